@@ -14,7 +14,8 @@ func main() {
 		Use:   "pulse",
 		Short: "Pulse - Lightweight Observability Platform",
 		Long: `Pulse CLI provides tools for working with the Pulse observability platform.
-It allows streaming logs, querying data, and launching the dashboard.`,
+It allows streaming logs, sending metrics and traces, querying data, and
+launching the dashboard.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			// If no subcommand is provided, show help
 			cmd.Help()
@@ -23,9 +24,11 @@ It allows streaming logs, querying data, and launching the dashboard.`,
 
 	// Add subcommands
 	rootCmd.AddCommand(cli.NewStreamCommand())
+	rootCmd.AddCommand(cli.NewSendCommand())
 	rootCmd.AddCommand(cli.NewQueryCommand())
 	rootCmd.AddCommand(cli.NewDashboardCommand())
 	rootCmd.AddCommand(cli.NewConfigCommand())
+	rootCmd.AddCommand(cli.NewDoctorCommand())
 
 	// Execute the command
 	if err := rootCmd.Execute(); err != nil {
@@ -3,27 +3,98 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/karansingh/pulse/pkg/api"
+	"github.com/karansingh/pulse/pkg/models"
 	"github.com/karansingh/pulse/pkg/processor"
 	"github.com/karansingh/pulse/pkg/storage"
 )
 
 var (
 	// Command-line flags
-	port          = flag.Int("port", 8080, "HTTP server port")
-	dbPath        = flag.String("db", "./pulse.db", "Path to SQLite database file")
-	dataDirectory = flag.String("data-dir", "./data", "Directory to store data files")
+	port              = flag.Int("port", 8080, "HTTP server port")
+	dbPath            = flag.String("db", "./pulse.db", "Path to SQLite database file")
+	postgresDSN       = flag.String("postgres-dsn", "", "Store data in Postgres at this DSN instead of SQLite")
+	inMemory          = flag.Bool("in-memory", false, "Keep telemetry in memory instead of SQLite")
+	snapshotFile      = flag.String("snapshot-file", "", "With -in-memory, restore telemetry from this file on startup and save it there on shutdown")
+	dataDirectory     = flag.String("data-dir", "./data", "Directory to store data files")
+	histBuckets       = flag.String("histogram-buckets", "", "Comma-separated default bucket boundaries for auto-generated histograms")
+	logRetention      = flag.Duration("retention-logs", 0, "How long to keep logs (0 keeps forever)")
+	metricRetention   = flag.Duration("retention-metrics", 0, "How long to keep metrics (0 keeps forever)")
+	traceRetention    = flag.Duration("retention-traces", 0, "How long to keep traces and spans after the trace ends (0 keeps forever)")
+	retentionEvery    = flag.Duration("retention-interval", time.Hour, "How often to apply retention")
+	correlateLogs     = flag.Duration("log-correlation-window", 0, "Attach orphan logs to active traces of the same service within this window (0 disables)")
+	rateLimit         = flag.Float64("rate-limit", 0, "Default per-service ingestion budget in records per second (0 disables)")
+	rateLimitBurst    = flag.Int("rate-limit-burst", 0, "Default per-service burst size (defaults to the rate)")
+	requestRate       = flag.Float64("request-rate-limit", 0, "Ingestion requests per second allowed for each API key, or each client IP without keys (0 disables)")
+	requestBurst      = flag.Int("request-rate-burst", 0, "Ingestion request burst size of each client (defaults to the rate)")
+	maxTagCount       = flag.Int("max-tags", 0, "Most tags kept on each log, metric or span; tags past the first in key order are dropped (0 is unlimited)")
+	queryWindow       = flag.Duration("default-query-window", time.Hour, "How far back queries without a time range look (0 disables the default)")
+	maxUnbounded      = flag.Int("max-unbounded-limit", 1000, "Largest limit accepted for queries without a time range")
+	servicesLimit     = flag.Int("services-limit", 100, "How many of the most recently active services /api/services lists by default")
+	maxServicesLimit  = flag.Int("max-services-limit", 1000, "Largest limit accepted by /api/services; larger limits are lowered to it")
+	maxBodySize       = flag.Int64("max-body-size", 1<<20, "Largest ingestion request body in bytes; larger bodies are rejected with 413")
+	lenientLogs       = flag.Bool("lenient-logs", false, "Accept off-schema JSON on /logs, storing unknown fields as tags")
+	logMessageField   = flag.String("log-message-fields", "message,msg", "Comma-separated fields holding the message of lenient JSON logs")
+	influxWrite       = flag.Bool("influx-write", false, "Accept metrics in InfluxDB line protocol on /write")
+	basePath          = flag.String("base-path", "", "Prefix of every route, e.g. /pulse when served behind a reverse proxy at a subpath")
+	dashboardDir      = flag.String("dashboard-dir", "", "Directory to serve the dashboard files from instead of the embedded ones")
+	slowQuery         = flag.Duration("slow-query-threshold", time.Second, "Log storage queries slower than this (0 disables)")
+	promFreshness     = flag.Duration("prometheus-freshness", time.Hour, "Only expose series seen within this window on /metrics/prometheus")
+	spanLogHead       = flag.Int("span-log-head", 0, "Cap span logs, keeping this many of the first logs of each span (0 with -span-log-tail 0 keeps all)")
+	spanLogTail       = flag.Int("span-log-tail", 0, "Cap span logs, keeping this many of the last logs of each span")
+	defaultEnv        = flag.String("default-env", "", "Env of logs, metrics and spans submitted without one")
+	defaultHost       = flag.String("default-host", "", "Host of logs, metrics and spans submitted without one")
+	traceSampleRate   = flag.Float64("trace-sample-rate", 1, "Share of traces to keep, from 0 to 1 (traces with errors are always kept)")
+	tailSampleRate    = flag.Float64("tail-sample-rate", 1, "Share of complete traces to keep, from 0 to 1, deciding once a trace's root span arrives (failed and slow traces are always kept)")
+	tailSampleSlow    = flag.Duration("tail-sample-latency", time.Second, "Traces slower than this are always kept by tail sampling (0 disables)")
+	tailSampleWait    = flag.Duration("tail-sample-timeout", processor.DefaultTailSamplingTimeout, "How long tail sampling waits for the root span of a trace before deciding on the spans received")
+	tailSamplePending = flag.Int("tail-sample-max-pending", processor.DefaultTailSamplingMaxPending, "Most traces tail sampling buffers while waiting for their root span; the oldest is decided early to make room")
+	sampleSeed        = flag.Int64("sample-seed", 0, "Seed mixed into the trace ID hash of head and tail sampling decisions (0 decides like any other instance)")
+	alertWebhook      = flag.String("alert-webhook", "", "POST alert notifications as JSON to this URL (alerts are logged otherwise)")
+	alertInterval     = flag.Duration("alert-interval", 15*time.Second, "How often alert rules are evaluated when no matching items arrive")
+	asyncWorkers      = flag.Int("async-workers", 0, "Write items to storage in the background with this many workers, answering ingestion requests once queued (0 writes synchronously)")
+	asyncQueueSize    = flag.Int("async-queue-size", 10000, "Items queued for background writes before ingestion blocks")
+	asyncDropFull     = flag.Bool("async-drop-when-full", false, "Drop items when the background write queue is full instead of blocking ingestion")
+	maxClockSkew      = flag.Duration("max-clock-skew", 0, "Reject logs, metrics and spans timestamped further than this ahead of the server clock (0 accepts any)")
+	rejectExpired     = flag.Bool("reject-expired", false, "Reject logs, metrics and spans timestamped before the retention of their signal")
+	dropRules         stringList
+	serviceLimits     stringList
+	apiKeys           stringList
+	alertRules        stringList
+	defaultTags       stringList
+	tagMappings       stringList
 )
 
+// stringList is a flag that can be given multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ";")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func main() {
 	// Parse command-line flags
+	flag.Var(&dropRules, "drop-rule", "Discard records matching [service:]key=value[,key=value] before storage (repeatable)")
+	flag.Var(&serviceLimits, "service-rate-limit", "Per-service ingestion budget as service=rate[:burst] (repeatable)")
+	flag.Var(&defaultTags, "default-tag", "Add key=value to logs, metrics and spans without the key (repeatable)")
+	flag.Var(&tagMappings, "tag-mapping", "Derive a tag from another as from>to[:value=mapped,...], e.g. region>cloud:eu-west-1=aws (repeatable)")
+	flag.Var(&alertRules, "alert-rule", "Alert when a rule like 'name: count(logs service=api level=ERROR) over 1m > 10' holds (repeatable)")
+	flag.Var(&apiKeys, "api-key", "Require this API key on ingestion and query routes (repeatable; PULSE_API_KEYS adds a comma-separated list)")
 	flag.Parse()
 
 	// Create data directory if it doesn't exist
@@ -33,19 +104,236 @@ func main() {
 
 	// Initialize storage
 	dbFilePath := filepath.Join(*dataDirectory, filepath.Base(*dbPath))
-	st, err := storage.NewSQLiteStorage(dbFilePath)
-	if err != nil {
-		log.Fatalf("Failed to initialize storage: %v", err)
+
+	// Run the retention job if any signal has a retention configured
+	var storageOpts []storage.SQLiteOption
+	retention := storage.RetentionPolicy{
+		Logs:    *logRetention,
+		Metrics: *metricRetention,
+		Traces:  *traceRetention,
+	}
+	if !retention.IsZero() {
+		storageOpts = append(storageOpts, storage.WithRetention(retention, *retentionEvery))
+		log.Printf("Retention enabled: logs=%s metrics=%s traces=%s", retention.Logs, retention.Metrics, retention.Traces)
+	}
+
+	// The storage is closed by the processor chain during shutdown
+	var st storage.Storage
+	if *postgresDSN != "" {
+		if !retention.IsZero() {
+			log.Printf("Retention is not supported with Postgres storage and will be ignored")
+		}
+		pg, err := storage.NewPostgresStorage(*postgresDSN)
+		if err != nil {
+			log.Fatalf("Failed to initialize storage: %v", err)
+		}
+		st = pg
+		log.Printf("Storage initialized in Postgres")
+	} else if *inMemory {
+		if !retention.IsZero() {
+			log.Printf("Retention is not supported with in-memory storage and will be ignored")
+		}
+		var memoryOpts []storage.InMemoryOption
+		if *snapshotFile != "" {
+			memoryOpts = append(memoryOpts, storage.WithSnapshotFile(*snapshotFile))
+		}
+		memory, err := storage.NewInMemoryStorage(memoryOpts...)
+		if err != nil {
+			log.Fatalf("Failed to initialize storage: %v", err)
+		}
+		st = memory
+		log.Printf("Storage initialized in memory")
+	} else {
+		sqlite, err := storage.NewSQLiteStorage(dbFilePath, storageOpts...)
+		if err != nil {
+			log.Fatalf("Failed to initialize storage: %v", err)
+		}
+		st = sqlite
+		log.Printf("Storage initialized at %s", dbFilePath)
 	}
-	defer st.Close()
-	log.Printf("Storage initialized at %s", dbFilePath)
 
-	// Initialize processor chain
-	proc := processor.NewStorageProcessor(st)
+	// Initialize processor chain. Stored items are published to the hub
+	// feeding the WebSocket streams.
+	hub := processor.NewHub()
+	var proc processor.Processor = processor.NewBroadcastProcessor(processor.NewStorageProcessor(st), hub)
+	var async *processor.AsyncProcessor
+	if *asyncWorkers > 0 {
+		async = processor.NewAsyncProcessor(proc, processor.AsyncConfig{
+			Workers:      *asyncWorkers,
+			QueueSize:    *asyncQueueSize,
+			DropWhenFull: *asyncDropFull,
+		})
+		proc = async
+		log.Printf("Writing to storage in the background with %d worker(s) and a queue of %d items", *asyncWorkers, *asyncQueueSize)
+	}
+	if *spanLogHead > 0 || *spanLogTail > 0 {
+		proc = processor.NewSpanLogLimitProcessor(proc, *spanLogHead, *spanLogTail)
+		log.Printf("Keeping the first %d and last %d logs of each span", *spanLogHead, *spanLogTail)
+	}
+	if *correlateLogs > 0 {
+		proc = processor.NewCorrelationProcessor(proc, *correlateLogs, 5*time.Minute)
+		log.Printf("Log correlation enabled with window %s", *correlateLogs)
+	}
+	if *defaultEnv != "" || *defaultHost != "" || len(defaultTags) > 0 || len(tagMappings) > 0 {
+		config := processor.EnrichmentConfig{Env: *defaultEnv, Host: *defaultHost}
+		for _, spec := range defaultTags {
+			key, value, ok := strings.Cut(spec, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				log.Fatalf("Invalid default tag %q, expected key=value", spec)
+			}
+			if config.Tags == nil {
+				config.Tags = make(map[string]string)
+			}
+			config.Tags[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		for _, spec := range tagMappings {
+			mapping, err := processor.ParseTagMapping(spec)
+			if err != nil {
+				log.Fatalf("Invalid tag mapping: %v", err)
+			}
+			config.Mappings = append(config.Mappings, mapping)
+		}
+		proc = processor.NewEnrichmentProcessor(proc, config)
+		log.Printf("Enriching items with env %q, host %q, %d default tag(s) and %d tag mapping(s)",
+			config.Env, config.Host, len(config.Tags), len(config.Mappings))
+	}
+	if len(dropRules) > 0 {
+		rules := make([]processor.DropRule, 0, len(dropRules))
+		for _, spec := range dropRules {
+			rule, err := processor.ParseDropRule(spec)
+			if err != nil {
+				log.Fatalf("Invalid drop rule: %v", err)
+			}
+			rules = append(rules, rule)
+		}
+		proc = processor.NewDropRuleProcessor(proc, rules)
+		log.Printf("Dropping records matching %d rule(s)", len(rules))
+	}
+	if *traceSampleRate < 1 {
+		sampling := processor.NewSamplingProcessor(proc, *traceSampleRate)
+		if *sampleSeed != 0 {
+			sampling.WithSource(rand.NewSource(*sampleSeed))
+		}
+		proc = sampling
+		log.Printf("Keeping %.0f%% of traces without errors", *traceSampleRate*100)
+	}
+	if *tailSampleRate < 1 {
+		policy := processor.TailSamplingPolicy{LatencyThreshold: *tailSampleSlow, Rate: *tailSampleRate}
+		sampling := processor.NewTailSamplingProcessor(proc, policy, *tailSampleWait, *tailSamplePending)
+		if *sampleSeed != 0 {
+			sampling.WithSource(rand.NewSource(*sampleSeed))
+		}
+		sampling.Start(*tailSampleWait / 4)
+		proc = sampling
+		log.Printf("Keeping %.0f%% of complete traces without errors or faster than %s", *tailSampleRate*100, *tailSampleSlow)
+	}
+	if len(alertRules) > 0 {
+		rules := make([]processor.AlertRule, 0, len(alertRules))
+		for _, spec := range alertRules {
+			rule, err := processor.ParseAlertRule(spec)
+			if err != nil {
+				log.Fatalf("Invalid alert rule: %v", err)
+			}
+			rules = append(rules, rule)
+		}
+		var notifier processor.Notifier = processor.NotifierFunc(func(alert processor.Alert) error {
+			log.Printf("Alert: %s", alert.Message)
+			return nil
+		})
+		if *alertWebhook != "" {
+			notifier = processor.NewWebhookNotifier(*alertWebhook)
+		}
+		alerts := processor.NewAlertProcessor(proc, rules, notifier)
+		if *alertInterval > 0 {
+			alerts.Start(*alertInterval)
+		}
+		proc = alerts
+		log.Printf("Evaluating %d alert rule(s)", len(rules))
+	}
+	if *rateLimit > 0 || len(serviceLimits) > 0 {
+		config := processor.RateLimitConfig{
+			Default:  processor.RateLimit{Rate: *rateLimit, Burst: *rateLimitBurst},
+			Services: make(map[string]processor.RateLimit),
+		}
+		for _, spec := range serviceLimits {
+			service, limit, err := processor.ParseServiceRateLimit(spec)
+			if err != nil {
+				log.Fatalf("Invalid service rate limit: %v", err)
+			}
+			config.Services[service] = limit
+		}
+		proc = processor.NewRateLimitProcessor(proc, config)
+		log.Printf("Rate limiting enabled: default %.0f/s, %d service override(s)", *rateLimit, len(config.Services))
+	}
 	log.Printf("Processor initialized")
 
+	// Collect server options
+	serverOpts := []api.ServerOption{api.WithHub(hub)}
+	if *maxTagCount > 0 {
+		models.SetMaxTags(*maxTagCount)
+		log.Printf("Keeping at most %d tags per item", *maxTagCount)
+	}
+
+	if *histBuckets != "" {
+		buckets, err := models.ParseHistogramBuckets(*histBuckets)
+		if err != nil {
+			log.Fatalf("Invalid histogram buckets: %v", err)
+		}
+		serverOpts = append(serverOpts, api.WithHistogramBuckets(buckets))
+		log.Printf("Using default histogram buckets: %v", buckets)
+	}
+
+	serverOpts = append(serverOpts, api.WithMaxUnboundedLimit(*maxUnbounded))
+	serverOpts = append(serverOpts, api.WithServiceListLimits(*servicesLimit, *maxServicesLimit))
+	serverOpts = append(serverOpts, api.WithDefaultQueryWindow(*queryWindow))
+	serverOpts = append(serverOpts, api.WithSlowQueryThreshold(*slowQuery))
+	serverOpts = append(serverOpts, api.WithPrometheusFreshness(*promFreshness))
+	serverOpts = append(serverOpts, api.WithDashboardDir(*dashboardDir))
+	serverOpts = append(serverOpts, api.WithBasePath(*basePath))
+	serverOpts = append(serverOpts, api.WithMaxBodySize(*maxBodySize))
+	if *maxClockSkew > 0 || *rejectExpired {
+		var maxAge storage.RetentionPolicy
+		if *rejectExpired {
+			maxAge = retention
+		}
+		serverOpts = append(serverOpts, api.WithTimestampBounds(*maxClockSkew, maxAge))
+		log.Printf("Rejecting timestamps more than %s ahead (0 is unbounded) or older than logs=%s metrics=%s traces=%s",
+			*maxClockSkew, maxAge.Logs, maxAge.Metrics, maxAge.Traces)
+	}
+	if *lenientLogs {
+		var fields []string
+		for _, field := range strings.Split(*logMessageField, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+		serverOpts = append(serverOpts, api.WithLenientLogParsing(fields...))
+		log.Printf("Lenient log parsing enabled, message fields: %v", fields)
+	}
+
+	if *influxWrite {
+		serverOpts = append(serverOpts, api.WithInfluxWrite())
+		log.Printf("InfluxDB line protocol ingestion enabled on /write")
+	}
+
+	// Keys from the environment stay out of the process list
+	for _, key := range strings.Split(os.Getenv("PULSE_API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			apiKeys = append(apiKeys, key)
+		}
+	}
+	if len(apiKeys) > 0 {
+		serverOpts = append(serverOpts, api.WithAPIKeys(apiKeys...))
+		log.Printf("API key authentication enabled with %d key(s)", len(apiKeys))
+	}
+
+	if *requestRate > 0 {
+		serverOpts = append(serverOpts, api.WithIngestRateLimit(processor.RateLimit{Rate: *requestRate, Burst: *requestBurst}))
+		log.Printf("Ingestion request rate limiting enabled: %.0f/s per client", *requestRate)
+	}
+
 	// Initialize API server
-	server := api.NewServer(proc, *port)
+	server := api.NewServer(proc, *port, serverOpts...)
 	log.Printf("API server initialized on port %d", *port)
 
 	// Set up signal handling for graceful shutdown
@@ -76,10 +364,44 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, 10*time.Second)
 	defer shutdownCancel()
 
-	// Gracefully shutdown the server
-	if err := server.Stop(shutdownCtx); err != nil {
-		log.Printf("Error during server shutdown: %v", err)
+	var flushers []flusher
+	if async != nil {
+		flushers = append(flushers, async)
+	}
+	if err := shutdown(shutdownCtx, server, proc, flushers...); err != nil {
+		log.Printf("Error during shutdown: %v", err)
 	}
 
 	log.Printf("Server shutdown complete")
 }
+
+// flusher is a processor buffering items that can be written downstream
+// within a deadline
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// shutdown stops the server in an order that loses no accepted data: first
+// ingestion stops and in-flight requests finish, then the flushers write their
+// queued items and the processor chain flushes any buffered writes, and
+// finally the storage underneath it closes.
+func shutdown(ctx context.Context, server *api.Server, proc processor.Processor, flushers ...flusher) error {
+	// Stop accepting requests and wait for in-flight ones
+	if err := server.Stop(ctx); err != nil {
+		log.Printf("Error during server shutdown: %v", err)
+	}
+
+	// Report queued writes that miss the deadline; closing still writes them
+	for _, f := range flushers {
+		if err := f.Flush(ctx); err != nil {
+			log.Printf("Error flushing queued writes: %v", err)
+		}
+	}
+
+	// Flush buffered writes; the storage processor closes the storage last
+	if err := proc.Close(); err != nil {
+		return fmt.Errorf("failed to close processor: %w", err)
+	}
+
+	return nil
+}
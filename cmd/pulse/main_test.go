@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/api"
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+// bufferingProcessor holds logs in memory until Close, standing in for an
+// asynchronous write-behind processor
+type bufferingProcessor struct {
+	processor.Processor
+
+	mu      sync.Mutex
+	pending []*models.LogEntry
+}
+
+func (p *bufferingProcessor) ProcessLog(log *models.LogEntry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = append(p.pending, log)
+	return nil
+}
+
+func (p *bufferingProcessor) Close() error {
+	p.mu.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+
+	for _, log := range pending {
+		if err := p.Processor.ProcessLog(log); err != nil {
+			return err
+		}
+	}
+	return p.Processor.Close()
+}
+
+func TestShutdown_FlushesBufferedData(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "pulse.db")
+
+	st, err := storage.NewSQLiteStorage(dbFile)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	proc := &bufferingProcessor{Processor: processor.NewStorageProcessor(st)}
+
+	server := api.NewServer(proc, 0)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+
+	// Ingest a few logs over HTTP
+	url := fmt.Sprintf("http://%s/logs", listener.Addr())
+	for i := 0; i < 3; i++ {
+		body := fmt.Sprintf(`{"message": "request %d", "level": "INFO", "service": "api"}`, i)
+		resp, err := http.Post(url, "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to post log: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+	}
+
+	// Nothing has reached storage yet
+	logs, err := st.QueryLogs(&models.QueryParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to query logs: %v", err)
+	}
+	if n := len(logs["logs"].([]map[string]interface{})); n != 0 {
+		t.Fatalf("expected logs to still be buffered, found %d stored", n)
+	}
+
+	// Simulate SIGTERM handling
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx, server, proc); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// Ingestion is stopped
+	if resp, err := http.Post(url, "application/json", bytes.NewBufferString(`{}`)); err == nil {
+		resp.Body.Close()
+		t.Error("expected server to stop accepting requests")
+	}
+
+	// The buffered logs survived the shutdown
+	reopened, err := storage.NewSQLiteStorage(dbFile)
+	if err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	defer reopened.Close()
+
+	logs, err = reopened.QueryLogs(&models.QueryParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to query logs: %v", err)
+	}
+	if n := len(logs["logs"].([]map[string]interface{})); n != 3 {
+		t.Errorf("expected 3 logs after shutdown, got %d", n)
+	}
+}
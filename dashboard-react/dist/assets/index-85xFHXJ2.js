@@ -181,5 +181,5 @@ Error generating stack: `+i.message+`
       animation: ${0} 1.4s linear infinite;
     `),LO)),FO=W("svg",{name:"MuiCircularProgress",slot:"Svg",overridesResolver:(e,t)=>t.svg})({display:"block"}),BO=W("circle",{name:"MuiCircularProgress",slot:"Circle",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.circle,t[`circle${z(n.variant)}`],n.disableShrink&&t.circleDisableShrink]}})(({ownerState:e,theme:t})=>b({stroke:"currentColor"},e.variant==="determinate"&&{transition:t.transitions.create("stroke-dashoffset")},e.variant==="indeterminate"&&{strokeDasharray:"80px, 200px",strokeDashoffset:0}),({ownerState:e})=>e.variant==="indeterminate"&&!e.disableShrink&&Au(Ug||(Ug=dc`
       animation: ${0} 1.4s ease-in-out infinite;
-    `),jO)),DO=m.forwardRef(function(t,n){const r=se({props:t,name:"MuiCircularProgress"}),{className:o,color:i="primary",disableShrink:a=!1,size:l=40,style:s,thickness:u=3.6,value:c=0,variant:d="indeterminate"}=r,h=q(r,NO),C=b({},r,{color:i,disableShrink:a,size:l,thickness:u,value:c,variant:d}),y=zO(C),x={},S={},f={};if(d==="determinate"){const g=2*Math.PI*((xr-u)/2);x.strokeDasharray=g.toFixed(3),f["aria-valuenow"]=Math.round(c),x.strokeDashoffset=`${((100-c)/100*g).toFixed(3)}px`,S.transform="rotate(-90deg)"}return p.jsx(AO,b({className:G(y.root,o),style:b({width:l,height:l},S,s),ownerState:C,ref:n,role:"progressbar"},f,h,{children:p.jsx(FO,{className:y.svg,ownerState:C,viewBox:`${xr/2} ${xr/2} ${xr} ${xr}`,children:p.jsx(BO,{className:y.circle,style:x,ownerState:C,cx:xr,cy:xr,r:(xr-u)/2,fill:"none",strokeWidth:u})})}))}),WO=["className","elementType","ownerState","externalForwardedProps","getSlotOwnerState","internalForwardedProps"],UO=["component","slots","slotProps"],HO=["component"];function Hg(e,t){const{className:n,elementType:r,ownerState:o,externalForwardedProps:i,getSlotOwnerState:a,internalForwardedProps:l}=t,s=q(t,WO),{component:u,slots:c={[e]:void 0},slotProps:d={[e]:void 0}}=i,h=q(i,UO),C=c[e]||r,y=Ex(d[e],o),x=Px(b({className:n},s,{externalForwardedProps:e==="root"?h:void 0,externalSlotProps:y})),{props:{component:S},internalRef:f}=x,g=q(x.props,HO),v=Ke(f,y==null?void 0:y.ref,t.ref),w=a?a(g):{},k=b({},o,w),E=e==="root"?S||u:S,P=Zo(C,b({},e==="root"&&!u&&!c[e]&&l,e!=="root"&&!c[e]&&l,g,E&&{as:E},{ref:v}),k);return Object.keys(w).forEach($=>{delete P[$]}),[C,P]}function VO(e){return le("MuiAlert",e)}const Vg=ie("MuiAlert",["root","action","icon","message","filled","colorSuccess","colorInfo","colorWarning","colorError","filledSuccess","filledInfo","filledWarning","filledError","outlined","outlinedSuccess","outlinedInfo","outlinedWarning","outlinedError","standard","standardSuccess","standardInfo","standardWarning","standardError"]);function KO(e){return le("MuiIconButton",e)}const YO=ie("MuiIconButton",["root","disabled","colorInherit","colorPrimary","colorSecondary","colorError","colorInfo","colorSuccess","colorWarning","edgeStart","edgeEnd","sizeSmall","sizeMedium","sizeLarge"]),GO=["edge","children","className","color","disabled","disableFocusRipple","size"],qO=e=>{const{classes:t,disabled:n,color:r,edge:o,size:i}=e,a={root:["root",n&&"disabled",r!=="default"&&`color${z(r)}`,o&&`edge${z(o)}`,`size${z(i)}`]};return ce(a,KO,t)},XO=W(Dr,{name:"MuiIconButton",slot:"Root",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.root,n.color!=="default"&&t[`color${z(n.color)}`],n.edge&&t[`edge${z(n.edge)}`],t[`size${z(n.size)}`]]}})(({theme:e,ownerState:t})=>b({textAlign:"center",flex:"0 0 auto",fontSize:e.typography.pxToRem(24),padding:8,borderRadius:"50%",overflow:"visible",color:(e.vars||e).palette.action.active,transition:e.transitions.create("background-color",{duration:e.transitions.duration.shortest})},!t.disableRipple&&{"&:hover":{backgroundColor:e.vars?`rgba(${e.vars.palette.action.activeChannel} / ${e.vars.palette.action.hoverOpacity})`:ke(e.palette.action.active,e.palette.action.hoverOpacity),"@media (hover: none)":{backgroundColor:"transparent"}}},t.edge==="start"&&{marginLeft:t.size==="small"?-3:-12},t.edge==="end"&&{marginRight:t.size==="small"?-3:-12}),({theme:e,ownerState:t})=>{var n;const r=(n=(e.vars||e).palette)==null?void 0:n[t.color];return b({},t.color==="inherit"&&{color:"inherit"},t.color!=="inherit"&&t.color!=="default"&&b({color:r==null?void 0:r.main},!t.disableRipple&&{"&:hover":b({},r&&{backgroundColor:e.vars?`rgba(${r.mainChannel} / ${e.vars.palette.action.hoverOpacity})`:ke(r.main,e.palette.action.hoverOpacity)},{"@media (hover: none)":{backgroundColor:"transparent"}})}),t.size==="small"&&{padding:5,fontSize:e.typography.pxToRem(18)},t.size==="large"&&{padding:12,fontSize:e.typography.pxToRem(28)},{[`&.${YO.disabled}`]:{backgroundColor:"transparent",color:(e.vars||e).palette.action.disabled}})}),hf=m.forwardRef(function(t,n){const r=se({props:t,name:"MuiIconButton"}),{edge:o=!1,children:i,className:a,color:l="default",disabled:s=!1,disableFocusRipple:u=!1,size:c="medium"}=r,d=q(r,GO),h=b({},r,{edge:o,color:l,disabled:s,disableFocusRipple:u,size:c}),C=qO(h);return p.jsx(XO,b({className:G(C.root,a),centerRipple:!0,focusRipple:!u,disabled:s,ref:n},d,{ownerState:h,children:i}))}),QO=Yt(p.jsx("path",{d:"M20,12A8,8 0 0,1 12,20A8,8 0 0,1 4,12A8,8 0 0,1 12,4C12.76,4 13.5,4.11 14.2, 4.31L15.77,2.74C14.61,2.26 13.34,2 12,2A10,10 0 0,0 2,12A10,10 0 0,0 12,22A10,10 0 0, 0 22,12M7.91,10.08L6.5,11.5L11,16L21,6L19.59,4.58L11,13.17L7.91,10.08Z"}),"SuccessOutlined"),JO=Yt(p.jsx("path",{d:"M12 5.99L19.53 19H4.47L12 5.99M12 2L1 21h22L12 2zm1 14h-2v2h2v-2zm0-6h-2v4h2v-4z"}),"ReportProblemOutlined"),ZO=Yt(p.jsx("path",{d:"M11 15h2v2h-2zm0-8h2v6h-2zm.99-5C6.47 2 2 6.48 2 12s4.47 10 9.99 10C17.52 22 22 17.52 22 12S17.52 2 11.99 2zM12 20c-4.42 0-8-3.58-8-8s3.58-8 8-8 8 3.58 8 8-3.58 8-8 8z"}),"ErrorOutline"),eI=Yt(p.jsx("path",{d:"M11,9H13V7H11M12,20C7.59,20 4,16.41 4,12C4,7.59 7.59,4 12,4C16.41,4 20,7.59 20, 12C20,16.41 16.41,20 12,20M12,2A10,10 0 0,0 2,12A10,10 0 0,0 12,22A10,10 0 0,0 22,12A10, 10 0 0,0 12,2M11,17H13V11H11V17Z"}),"InfoOutlined"),tI=Yt(p.jsx("path",{d:"M19 6.41L17.59 5 12 10.59 6.41 5 5 6.41 10.59 12 5 17.59 6.41 19 12 13.41 17.59 19 19 17.59 13.41 12z"}),"Close"),nI=["action","children","className","closeText","color","components","componentsProps","icon","iconMapping","onClose","role","severity","slotProps","slots","variant"],rI=e=>{const{variant:t,color:n,severity:r,classes:o}=e,i={root:["root",`color${z(n||r)}`,`${t}${z(n||r)}`,`${t}`],icon:["icon"],message:["message"],action:["action"]};return ce(i,VO,o)},oI=W(Dn,{name:"MuiAlert",slot:"Root",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.root,t[n.variant],t[`${n.variant}${z(n.color||n.severity)}`]]}})(({theme:e})=>{const t=e.palette.mode==="light"?bi:wi,n=e.palette.mode==="light"?wi:bi;return b({},e.typography.body2,{backgroundColor:"transparent",display:"flex",padding:"6px 16px",variants:[...Object.entries(e.palette).filter(([,r])=>r.main&&r.light).map(([r])=>({props:{colorSeverity:r,variant:"standard"},style:{color:e.vars?e.vars.palette.Alert[`${r}Color`]:t(e.palette[r].light,.6),backgroundColor:e.vars?e.vars.palette.Alert[`${r}StandardBg`]:n(e.palette[r].light,.9),[`& .${Vg.icon}`]:e.vars?{color:e.vars.palette.Alert[`${r}IconColor`]}:{color:e.palette[r].main}}})),...Object.entries(e.palette).filter(([,r])=>r.main&&r.light).map(([r])=>({props:{colorSeverity:r,variant:"outlined"},style:{color:e.vars?e.vars.palette.Alert[`${r}Color`]:t(e.palette[r].light,.6),border:`1px solid ${(e.vars||e).palette[r].light}`,[`& .${Vg.icon}`]:e.vars?{color:e.vars.palette.Alert[`${r}IconColor`]}:{color:e.palette[r].main}}})),...Object.entries(e.palette).filter(([,r])=>r.main&&r.dark).map(([r])=>({props:{colorSeverity:r,variant:"filled"},style:b({fontWeight:e.typography.fontWeightMedium},e.vars?{color:e.vars.palette.Alert[`${r}FilledColor`],backgroundColor:e.vars.palette.Alert[`${r}FilledBg`]}:{backgroundColor:e.palette.mode==="dark"?e.palette[r].dark:e.palette[r].main,color:e.palette.getContrastText(e.palette[r].main)})}))]})}),iI=W("div",{name:"MuiAlert",slot:"Icon",overridesResolver:(e,t)=>t.icon})({marginRight:12,padding:"7px 0",display:"flex",fontSize:22,opacity:.9}),aI=W("div",{name:"MuiAlert",slot:"Message",overridesResolver:(e,t)=>t.message})({padding:"8px 0",minWidth:0,overflow:"auto"}),Kg=W("div",{name:"MuiAlert",slot:"Action",overridesResolver:(e,t)=>t.action})({display:"flex",alignItems:"flex-start",padding:"4px 0 0 16px",marginLeft:"auto",marginRight:-8}),Yg={success:p.jsx(QO,{fontSize:"inherit"}),warning:p.jsx(JO,{fontSize:"inherit"}),error:p.jsx(ZO,{fontSize:"inherit"}),info:p.jsx(eI,{fontSize:"inherit"})},e1=m.forwardRef(function(t,n){const r=se({props:t,name:"MuiAlert"}),{action:o,children:i,className:a,closeText:l="Close",color:s,components:u={},componentsProps:c={},icon:d,iconMapping:h=Yg,onClose:C,role:y="alert",severity:x="success",slotProps:S={},slots:f={},variant:g="standard"}=r,v=q(r,nI),w=b({},r,{color:s,severity:x,variant:g,colorSeverity:s||x}),k=rI(w),E={slots:b({closeButton:u.CloseButton,closeIcon:u.CloseIcon},f),slotProps:b({},c,S)},[P,$]=Hg("closeButton",{elementType:hf,externalForwardedProps:E,ownerState:w}),[M,R]=Hg("closeIcon",{elementType:tI,externalForwardedProps:E,ownerState:w});return p.jsxs(oI,b({role:y,elevation:0,ownerState:w,className:G(k.root,a),ref:n},v,{children:[d!==!1?p.jsx(iI,{ownerState:w,className:k.icon,children:d||h[x]||Yg[x]}):null,p.jsx(aI,{ownerState:w,className:k.message,children:i}),o!=null?p.jsx(Kg,{ownerState:w,className:k.action,children:o}):null,o==null&&C?p.jsx(Kg,{ownerState:w,className:k.action,children:p.jsx(P,b({size:"small","aria-label":l,title:l,color:"inherit",onClick:C},$,{children:p.jsx(M,b({fontSize:"small"},R))}))}):null]}))});var Xp={};Object.defineProperty(Xp,"__esModule",{value:!0});var t1=Xp.default=void 0,lI=uI(m),sI=Ax;function n1(e){if(typeof WeakMap!="function")return null;var t=new WeakMap,n=new WeakMap;return(n1=function(r){return r?n:t})(e)}function uI(e,t){if(e&&e.__esModule)return e;if(e===null||typeof e!="object"&&typeof e!="function")return{default:e};var n=n1(t);if(n&&n.has(e))return n.get(e);var r={__proto__:null},o=Object.defineProperty&&Object.getOwnPropertyDescriptor;for(var i in e)if(i!=="default"&&Object.prototype.hasOwnProperty.call(e,i)){var a=o?Object.getOwnPropertyDescriptor(e,i):null;a&&(a.get||a.set)?Object.defineProperty(r,i,a):r[i]=e[i]}return r.default=e,n&&n.set(e,r),r}function cI(e){return Object.keys(e).length===0}function dI(e=null){const t=lI.useContext(sI.ThemeContext);return!t||cI(t)?e:t}t1=Xp.default=dI;var Ht="top",En="bottom",Rn="right",Vt="left",Qp="auto",Sl=[Ht,En,Rn,Vt],Si="start",nl="end",fI="clippingParents",r1="viewport",la="popper",pI="reference",Gg=Sl.reduce(function(e,t){return e.concat([t+"-"+Si,t+"-"+nl])},[]),o1=[].concat(Sl,[Qp]).reduce(function(e,t){return e.concat([t,t+"-"+Si,t+"-"+nl])},[]),hI="beforeRead",mI="read",gI="afterRead",vI="beforeMain",yI="main",xI="afterMain",bI="beforeWrite",wI="write",CI="afterWrite",SI=[hI,mI,gI,vI,yI,xI,bI,wI,CI];function er(e){return e?(e.nodeName||"").toLowerCase():null}function on(e){if(e==null)return window;if(e.toString()!=="[object Window]"){var t=e.ownerDocument;return t&&t.defaultView||window}return e}function xo(e){var t=on(e).Element;return e instanceof t||e instanceof Element}function wn(e){var t=on(e).HTMLElement;return e instanceof t||e instanceof HTMLElement}function Jp(e){if(typeof ShadowRoot>"u")return!1;var t=on(e).ShadowRoot;return e instanceof t||e instanceof ShadowRoot}function kI(e){var t=e.state;Object.keys(t.elements).forEach(function(n){var r=t.styles[n]||{},o=t.attributes[n]||{},i=t.elements[n];!wn(i)||!er(i)||(Object.assign(i.style,r),Object.keys(o).forEach(function(a){var l=o[a];l===!1?i.removeAttribute(a):i.setAttribute(a,l===!0?"":l)}))})}function PI(e){var t=e.state,n={popper:{position:t.options.strategy,left:"0",top:"0",margin:"0"},arrow:{position:"absolute"},reference:{}};return Object.assign(t.elements.popper.style,n.popper),t.styles=n,t.elements.arrow&&Object.assign(t.elements.arrow.style,n.arrow),function(){Object.keys(t.elements).forEach(function(r){var o=t.elements[r],i=t.attributes[r]||{},a=Object.keys(t.styles.hasOwnProperty(r)?t.styles[r]:n[r]),l=a.reduce(function(s,u){return s[u]="",s},{});!wn(o)||!er(o)||(Object.assign(o.style,l),Object.keys(i).forEach(function(s){o.removeAttribute(s)}))})}}const EI={name:"applyStyles",enabled:!0,phase:"write",fn:kI,effect:PI,requires:["computeStyles"]};function Jn(e){return e.split("-")[0]}var fo=Math.max,ou=Math.min,ki=Math.round;function mf(){var e=navigator.userAgentData;return e!=null&&e.brands&&Array.isArray(e.brands)?e.brands.map(function(t){return t.brand+"/"+t.version}).join(" "):navigator.userAgent}function i1(){return!/^((?!chrome|android).)*safari/i.test(mf())}function Pi(e,t,n){t===void 0&&(t=!1),n===void 0&&(n=!1);var r=e.getBoundingClientRect(),o=1,i=1;t&&wn(e)&&(o=e.offsetWidth>0&&ki(r.width)/e.offsetWidth||1,i=e.offsetHeight>0&&ki(r.height)/e.offsetHeight||1);var a=xo(e)?on(e):window,l=a.visualViewport,s=!i1()&&n,u=(r.left+(s&&l?l.offsetLeft:0))/o,c=(r.top+(s&&l?l.offsetTop:0))/i,d=r.width/o,h=r.height/i;return{width:d,height:h,top:c,right:u+d,bottom:c+h,left:u,x:u,y:c}}function Zp(e){var t=Pi(e),n=e.offsetWidth,r=e.offsetHeight;return Math.abs(t.width-n)<=1&&(n=t.width),Math.abs(t.height-r)<=1&&(r=t.height),{x:e.offsetLeft,y:e.offsetTop,width:n,height:r}}function a1(e,t){var n=t.getRootNode&&t.getRootNode();if(e.contains(t))return!0;if(n&&Jp(n)){var r=t;do{if(r&&e.isSameNode(r))return!0;r=r.parentNode||r.host}while(r)}return!1}function pr(e){return on(e).getComputedStyle(e)}function RI(e){return["table","td","th"].indexOf(er(e))>=0}function Vr(e){return((xo(e)?e.ownerDocument:e.document)||window.document).documentElement}function fc(e){return er(e)==="html"?e:e.assignedSlot||e.parentNode||(Jp(e)?e.host:null)||Vr(e)}function qg(e){return!wn(e)||pr(e).position==="fixed"?null:e.offsetParent}function $I(e){var t=/firefox/i.test(mf()),n=/Trident/i.test(mf());if(n&&wn(e)){var r=pr(e);if(r.position==="fixed")return null}var o=fc(e);for(Jp(o)&&(o=o.host);wn(o)&&["html","body"].indexOf(er(o))<0;){var i=pr(o);if(i.transform!=="none"||i.perspective!=="none"||i.contain==="paint"||["transform","perspective"].indexOf(i.willChange)!==-1||t&&i.willChange==="filter"||t&&i.filter&&i.filter!=="none")return o;o=o.parentNode}return null}function kl(e){for(var t=on(e),n=qg(e);n&&RI(n)&&pr(n).position==="static";)n=qg(n);return n&&(er(n)==="html"||er(n)==="body"&&pr(n).position==="static")?t:n||$I(e)||t}function eh(e){return["top","bottom"].indexOf(e)>=0?"x":"y"}function Ta(e,t,n){return fo(e,ou(t,n))}function TI(e,t,n){var r=Ta(e,t,n);return r>n?n:r}function l1(){return{top:0,right:0,bottom:0,left:0}}function s1(e){return Object.assign({},l1(),e)}function u1(e,t){return t.reduce(function(n,r){return n[r]=e,n},{})}var MI=function(t,n){return t=typeof t=="function"?t(Object.assign({},n.rects,{placement:n.placement})):t,s1(typeof t!="number"?t:u1(t,Sl))};function OI(e){var t,n=e.state,r=e.name,o=e.options,i=n.elements.arrow,a=n.modifiersData.popperOffsets,l=Jn(n.placement),s=eh(l),u=[Vt,Rn].indexOf(l)>=0,c=u?"height":"width";if(!(!i||!a)){var d=MI(o.padding,n),h=Zp(i),C=s==="y"?Ht:Vt,y=s==="y"?En:Rn,x=n.rects.reference[c]+n.rects.reference[s]-a[s]-n.rects.popper[c],S=a[s]-n.rects.reference[s],f=kl(i),g=f?s==="y"?f.clientHeight||0:f.clientWidth||0:0,v=x/2-S/2,w=d[C],k=g-h[c]-d[y],E=g/2-h[c]/2+v,P=Ta(w,E,k),$=s;n.modifiersData[r]=(t={},t[$]=P,t.centerOffset=P-E,t)}}function II(e){var t=e.state,n=e.options,r=n.element,o=r===void 0?"[data-popper-arrow]":r;o!=null&&(typeof o=="string"&&(o=t.elements.popper.querySelector(o),!o)||a1(t.elements.popper,o)&&(t.elements.arrow=o))}const _I={name:"arrow",enabled:!0,phase:"main",fn:OI,effect:II,requires:["popperOffsets"],requiresIfExists:["preventOverflow"]};function Ei(e){return e.split("-")[1]}var NI={top:"auto",right:"auto",bottom:"auto",left:"auto"};function LI(e,t){var n=e.x,r=e.y,o=t.devicePixelRatio||1;return{x:ki(n*o)/o||0,y:ki(r*o)/o||0}}function Xg(e){var t,n=e.popper,r=e.popperRect,o=e.placement,i=e.variation,a=e.offsets,l=e.position,s=e.gpuAcceleration,u=e.adaptive,c=e.roundOffsets,d=e.isFixed,h=a.x,C=h===void 0?0:h,y=a.y,x=y===void 0?0:y,S=typeof c=="function"?c({x:C,y:x}):{x:C,y:x};C=S.x,x=S.y;var f=a.hasOwnProperty("x"),g=a.hasOwnProperty("y"),v=Vt,w=Ht,k=window;if(u){var E=kl(n),P="clientHeight",$="clientWidth";if(E===on(n)&&(E=Vr(n),pr(E).position!=="static"&&l==="absolute"&&(P="scrollHeight",$="scrollWidth")),E=E,o===Ht||(o===Vt||o===Rn)&&i===nl){w=En;var M=d&&E===k&&k.visualViewport?k.visualViewport.height:E[P];x-=M-r.height,x*=s?1:-1}if(o===Vt||(o===Ht||o===En)&&i===nl){v=Rn;var R=d&&E===k&&k.visualViewport?k.visualViewport.width:E[$];C-=R-r.width,C*=s?1:-1}}var I=Object.assign({position:l},u&&NI),D=c===!0?LI({x:C,y:x},on(n)):{x:C,y:x};if(C=D.x,x=D.y,s){var _;return Object.assign({},I,(_={},_[w]=g?"0":"",_[v]=f?"0":"",_.transform=(k.devicePixelRatio||1)<=1?"translate("+C+"px, "+x+"px)":"translate3d("+C+"px, "+x+"px, 0)",_))}return Object.assign({},I,(t={},t[w]=g?x+"px":"",t[v]=f?C+"px":"",t.transform="",t))}function jI(e){var t=e.state,n=e.options,r=n.gpuAcceleration,o=r===void 0?!0:r,i=n.adaptive,a=i===void 0?!0:i,l=n.roundOffsets,s=l===void 0?!0:l,u={placement:Jn(t.placement),variation:Ei(t.placement),popper:t.elements.popper,popperRect:t.rects.popper,gpuAcceleration:o,isFixed:t.options.strategy==="fixed"};t.modifiersData.popperOffsets!=null&&(t.styles.popper=Object.assign({},t.styles.popper,Xg(Object.assign({},u,{offsets:t.modifiersData.popperOffsets,position:t.options.strategy,adaptive:a,roundOffsets:s})))),t.modifiersData.arrow!=null&&(t.styles.arrow=Object.assign({},t.styles.arrow,Xg(Object.assign({},u,{offsets:t.modifiersData.arrow,position:"absolute",adaptive:!1,roundOffsets:s})))),t.attributes.popper=Object.assign({},t.attributes.popper,{"data-popper-placement":t.placement})}const zI={name:"computeStyles",enabled:!0,phase:"beforeWrite",fn:jI,data:{}};var Jl={passive:!0};function AI(e){var t=e.state,n=e.instance,r=e.options,o=r.scroll,i=o===void 0?!0:o,a=r.resize,l=a===void 0?!0:a,s=on(t.elements.popper),u=[].concat(t.scrollParents.reference,t.scrollParents.popper);return i&&u.forEach(function(c){c.addEventListener("scroll",n.update,Jl)}),l&&s.addEventListener("resize",n.update,Jl),function(){i&&u.forEach(function(c){c.removeEventListener("scroll",n.update,Jl)}),l&&s.removeEventListener("resize",n.update,Jl)}}const FI={name:"eventListeners",enabled:!0,phase:"write",fn:function(){},effect:AI,data:{}};var BI={left:"right",right:"left",bottom:"top",top:"bottom"};function ks(e){return e.replace(/left|right|bottom|top/g,function(t){return BI[t]})}var DI={start:"end",end:"start"};function Qg(e){return e.replace(/start|end/g,function(t){return DI[t]})}function th(e){var t=on(e),n=t.pageXOffset,r=t.pageYOffset;return{scrollLeft:n,scrollTop:r}}function nh(e){return Pi(Vr(e)).left+th(e).scrollLeft}function WI(e,t){var n=on(e),r=Vr(e),o=n.visualViewport,i=r.clientWidth,a=r.clientHeight,l=0,s=0;if(o){i=o.width,a=o.height;var u=i1();(u||!u&&t==="fixed")&&(l=o.offsetLeft,s=o.offsetTop)}return{width:i,height:a,x:l+nh(e),y:s}}function UI(e){var t,n=Vr(e),r=th(e),o=(t=e.ownerDocument)==null?void 0:t.body,i=fo(n.scrollWidth,n.clientWidth,o?o.scrollWidth:0,o?o.clientWidth:0),a=fo(n.scrollHeight,n.clientHeight,o?o.scrollHeight:0,o?o.clientHeight:0),l=-r.scrollLeft+nh(e),s=-r.scrollTop;return pr(o||n).direction==="rtl"&&(l+=fo(n.clientWidth,o?o.clientWidth:0)-i),{width:i,height:a,x:l,y:s}}function rh(e){var t=pr(e),n=t.overflow,r=t.overflowX,o=t.overflowY;return/auto|scroll|overlay|hidden/.test(n+o+r)}function c1(e){return["html","body","#document"].indexOf(er(e))>=0?e.ownerDocument.body:wn(e)&&rh(e)?e:c1(fc(e))}function Ma(e,t){var n;t===void 0&&(t=[]);var r=c1(e),o=r===((n=e.ownerDocument)==null?void 0:n.body),i=on(r),a=o?[i].concat(i.visualViewport||[],rh(r)?r:[]):r,l=t.concat(a);return o?l:l.concat(Ma(fc(a)))}function gf(e){return Object.assign({},e,{left:e.x,top:e.y,right:e.x+e.width,bottom:e.y+e.height})}function HI(e,t){var n=Pi(e,!1,t==="fixed");return n.top=n.top+e.clientTop,n.left=n.left+e.clientLeft,n.bottom=n.top+e.clientHeight,n.right=n.left+e.clientWidth,n.width=e.clientWidth,n.height=e.clientHeight,n.x=n.left,n.y=n.top,n}function Jg(e,t,n){return t===r1?gf(WI(e,n)):xo(t)?HI(t,n):gf(UI(Vr(e)))}function VI(e){var t=Ma(fc(e)),n=["absolute","fixed"].indexOf(pr(e).position)>=0,r=n&&wn(e)?kl(e):e;return xo(r)?t.filter(function(o){return xo(o)&&a1(o,r)&&er(o)!=="body"}):[]}function KI(e,t,n,r){var o=t==="clippingParents"?VI(e):[].concat(t),i=[].concat(o,[n]),a=i[0],l=i.reduce(function(s,u){var c=Jg(e,u,r);return s.top=fo(c.top,s.top),s.right=ou(c.right,s.right),s.bottom=ou(c.bottom,s.bottom),s.left=fo(c.left,s.left),s},Jg(e,a,r));return l.width=l.right-l.left,l.height=l.bottom-l.top,l.x=l.left,l.y=l.top,l}function d1(e){var t=e.reference,n=e.element,r=e.placement,o=r?Jn(r):null,i=r?Ei(r):null,a=t.x+t.width/2-n.width/2,l=t.y+t.height/2-n.height/2,s;switch(o){case Ht:s={x:a,y:t.y-n.height};break;case En:s={x:a,y:t.y+t.height};break;case Rn:s={x:t.x+t.width,y:l};break;case Vt:s={x:t.x-n.width,y:l};break;default:s={x:t.x,y:t.y}}var u=o?eh(o):null;if(u!=null){var c=u==="y"?"height":"width";switch(i){case Si:s[u]=s[u]-(t[c]/2-n[c]/2);break;case nl:s[u]=s[u]+(t[c]/2-n[c]/2);break}}return s}function rl(e,t){t===void 0&&(t={});var n=t,r=n.placement,o=r===void 0?e.placement:r,i=n.strategy,a=i===void 0?e.strategy:i,l=n.boundary,s=l===void 0?fI:l,u=n.rootBoundary,c=u===void 0?r1:u,d=n.elementContext,h=d===void 0?la:d,C=n.altBoundary,y=C===void 0?!1:C,x=n.padding,S=x===void 0?0:x,f=s1(typeof S!="number"?S:u1(S,Sl)),g=h===la?pI:la,v=e.rects.popper,w=e.elements[y?g:h],k=KI(xo(w)?w:w.contextElement||Vr(e.elements.popper),s,c,a),E=Pi(e.elements.reference),P=d1({reference:E,element:v,placement:o}),$=gf(Object.assign({},v,P)),M=h===la?$:E,R={top:k.top-M.top+f.top,bottom:M.bottom-k.bottom+f.bottom,left:k.left-M.left+f.left,right:M.right-k.right+f.right},I=e.modifiersData.offset;if(h===la&&I){var D=I[o];Object.keys(R).forEach(function(_){var j=[Rn,En].indexOf(_)>=0?1:-1,F=[Ht,En].indexOf(_)>=0?"y":"x";R[_]+=D[F]*j})}return R}function YI(e,t){t===void 0&&(t={});var n=t,r=n.placement,o=n.boundary,i=n.rootBoundary,a=n.padding,l=n.flipVariations,s=n.allowedAutoPlacements,u=s===void 0?o1:s,c=Ei(r),d=c?l?Gg:Gg.filter(function(y){return Ei(y)===c}):Sl,h=d.filter(function(y){return u.indexOf(y)>=0});h.length===0&&(h=d);var C=h.reduce(function(y,x){return y[x]=rl(e,{placement:x,boundary:o,rootBoundary:i,padding:a})[Jn(x)],y},{});return Object.keys(C).sort(function(y,x){return C[y]-C[x]})}function GI(e){if(Jn(e)===Qp)return[];var t=ks(e);return[Qg(e),t,Qg(t)]}function qI(e){var t=e.state,n=e.options,r=e.name;if(!t.modifiersData[r]._skip){for(var o=n.mainAxis,i=o===void 0?!0:o,a=n.altAxis,l=a===void 0?!0:a,s=n.fallbackPlacements,u=n.padding,c=n.boundary,d=n.rootBoundary,h=n.altBoundary,C=n.flipVariations,y=C===void 0?!0:C,x=n.allowedAutoPlacements,S=t.options.placement,f=Jn(S),g=f===S,v=s||(g||!y?[ks(S)]:GI(S)),w=[S].concat(v).reduce(function(Q,he){return Q.concat(Jn(he)===Qp?YI(t,{placement:he,boundary:c,rootBoundary:d,padding:u,flipVariations:y,allowedAutoPlacements:x}):he)},[]),k=t.rects.reference,E=t.rects.popper,P=new Map,$=!0,M=w[0],R=0;R<w.length;R++){var I=w[R],D=Jn(I),_=Ei(I)===Si,j=[Ht,En].indexOf(D)>=0,F=j?"width":"height",A=rl(t,{placement:I,boundary:c,rootBoundary:d,altBoundary:h,padding:u}),B=j?_?Rn:Vt:_?En:Ht;k[F]>E[F]&&(B=ks(B));var T=ks(B),L=[];if(i&&L.push(A[D]<=0),l&&L.push(A[B]<=0,A[T]<=0),L.every(function(Q){return Q})){M=I,$=!1;break}P.set(I,L)}if($)for(var Y=y?3:1,te=function(he){var re=w.find(function(Se){var ye=P.get(Se);if(ye)return ye.slice(0,he).every(function(we){return we})});if(re)return M=re,"break"},ne=Y;ne>0;ne--){var me=te(ne);if(me==="break")break}t.placement!==M&&(t.modifiersData[r]._skip=!0,t.placement=M,t.reset=!0)}}const XI={name:"flip",enabled:!0,phase:"main",fn:qI,requiresIfExists:["offset"],data:{_skip:!1}};function Zg(e,t,n){return n===void 0&&(n={x:0,y:0}),{top:e.top-t.height-n.y,right:e.right-t.width+n.x,bottom:e.bottom-t.height+n.y,left:e.left-t.width-n.x}}function ev(e){return[Ht,Rn,En,Vt].some(function(t){return e[t]>=0})}function QI(e){var t=e.state,n=e.name,r=t.rects.reference,o=t.rects.popper,i=t.modifiersData.preventOverflow,a=rl(t,{elementContext:"reference"}),l=rl(t,{altBoundary:!0}),s=Zg(a,r),u=Zg(l,o,i),c=ev(s),d=ev(u);t.modifiersData[n]={referenceClippingOffsets:s,popperEscapeOffsets:u,isReferenceHidden:c,hasPopperEscaped:d},t.attributes.popper=Object.assign({},t.attributes.popper,{"data-popper-reference-hidden":c,"data-popper-escaped":d})}const JI={name:"hide",enabled:!0,phase:"main",requiresIfExists:["preventOverflow"],fn:QI};function ZI(e,t,n){var r=Jn(e),o=[Vt,Ht].indexOf(r)>=0?-1:1,i=typeof n=="function"?n(Object.assign({},t,{placement:e})):n,a=i[0],l=i[1];return a=a||0,l=(l||0)*o,[Vt,Rn].indexOf(r)>=0?{x:l,y:a}:{x:a,y:l}}function e_(e){var t=e.state,n=e.options,r=e.name,o=n.offset,i=o===void 0?[0,0]:o,a=o1.reduce(function(c,d){return c[d]=ZI(d,t.rects,i),c},{}),l=a[t.placement],s=l.x,u=l.y;t.modifiersData.popperOffsets!=null&&(t.modifiersData.popperOffsets.x+=s,t.modifiersData.popperOffsets.y+=u),t.modifiersData[r]=a}const t_={name:"offset",enabled:!0,phase:"main",requires:["popperOffsets"],fn:e_};function n_(e){var t=e.state,n=e.name;t.modifiersData[n]=d1({reference:t.rects.reference,element:t.rects.popper,placement:t.placement})}const r_={name:"popperOffsets",enabled:!0,phase:"read",fn:n_,data:{}};function o_(e){return e==="x"?"y":"x"}function i_(e){var t=e.state,n=e.options,r=e.name,o=n.mainAxis,i=o===void 0?!0:o,a=n.altAxis,l=a===void 0?!1:a,s=n.boundary,u=n.rootBoundary,c=n.altBoundary,d=n.padding,h=n.tether,C=h===void 0?!0:h,y=n.tetherOffset,x=y===void 0?0:y,S=rl(t,{boundary:s,rootBoundary:u,padding:d,altBoundary:c}),f=Jn(t.placement),g=Ei(t.placement),v=!g,w=eh(f),k=o_(w),E=t.modifiersData.popperOffsets,P=t.rects.reference,$=t.rects.popper,M=typeof x=="function"?x(Object.assign({},t.rects,{placement:t.placement})):x,R=typeof M=="number"?{mainAxis:M,altAxis:M}:Object.assign({mainAxis:0,altAxis:0},M),I=t.modifiersData.offset?t.modifiersData.offset[t.placement]:null,D={x:0,y:0};if(E){if(i){var _,j=w==="y"?Ht:Vt,F=w==="y"?En:Rn,A=w==="y"?"height":"width",B=E[w],T=B+S[j],L=B-S[F],Y=C?-$[A]/2:0,te=g===Si?P[A]:$[A],ne=g===Si?-$[A]:-P[A],me=t.elements.arrow,Q=C&&me?Zp(me):{width:0,height:0},he=t.modifiersData["arrow#persistent"]?t.modifiersData["arrow#persistent"].padding:l1(),re=he[j],Se=he[F],ye=Ta(0,P[A],Q[A]),we=v?P[A]/2-Y-ye-re-R.mainAxis:te-ye-re-R.mainAxis,Ae=v?-P[A]/2+Y+ye+Se+R.mainAxis:ne+ye+Se+R.mainAxis,de=t.elements.arrow&&kl(t.elements.arrow),Te=de?w==="y"?de.clientTop||0:de.clientLeft||0:0,oe=(_=I==null?void 0:I[w])!=null?_:0,ee=B+we-oe-Te,Z=B+Ae-oe,Ne=Ta(C?ou(T,ee):T,B,C?fo(L,Z):L);E[w]=Ne,D[w]=Ne-B}if(l){var Re,Ce=w==="x"?Ht:Vt,Qe=w==="x"?En:Rn,fe=E[k],xe=k==="y"?"height":"width",Ye=fe+S[Ce],Fe=fe-S[Qe],Pe=[Ht,Vt].indexOf(f)!==-1,rt=(Re=I==null?void 0:I[k])!=null?Re:0,ot=Pe?Ye:fe-P[xe]-$[xe]-rt+R.altAxis,Je=Pe?fe+P[xe]+$[xe]-rt-R.altAxis:Fe,N=C&&Pe?TI(ot,fe,Je):Ta(C?ot:Ye,fe,C?Je:Fe);E[k]=N,D[k]=N-fe}t.modifiersData[r]=D}}const a_={name:"preventOverflow",enabled:!0,phase:"main",fn:i_,requiresIfExists:["offset"]};function l_(e){return{scrollLeft:e.scrollLeft,scrollTop:e.scrollTop}}function s_(e){return e===on(e)||!wn(e)?th(e):l_(e)}function u_(e){var t=e.getBoundingClientRect(),n=ki(t.width)/e.offsetWidth||1,r=ki(t.height)/e.offsetHeight||1;return n!==1||r!==1}function c_(e,t,n){n===void 0&&(n=!1);var r=wn(t),o=wn(t)&&u_(t),i=Vr(t),a=Pi(e,o,n),l={scrollLeft:0,scrollTop:0},s={x:0,y:0};return(r||!r&&!n)&&((er(t)!=="body"||rh(i))&&(l=s_(t)),wn(t)?(s=Pi(t,!0),s.x+=t.clientLeft,s.y+=t.clientTop):i&&(s.x=nh(i))),{x:a.left+l.scrollLeft-s.x,y:a.top+l.scrollTop-s.y,width:a.width,height:a.height}}function d_(e){var t=new Map,n=new Set,r=[];e.forEach(function(i){t.set(i.name,i)});function o(i){n.add(i.name);var a=[].concat(i.requires||[],i.requiresIfExists||[]);a.forEach(function(l){if(!n.has(l)){var s=t.get(l);s&&o(s)}}),r.push(i)}return e.forEach(function(i){n.has(i.name)||o(i)}),r}function f_(e){var t=d_(e);return SI.reduce(function(n,r){return n.concat(t.filter(function(o){return o.phase===r}))},[])}function p_(e){var t;return function(){return t||(t=new Promise(function(n){Promise.resolve().then(function(){t=void 0,n(e())})})),t}}function h_(e){var t=e.reduce(function(n,r){var o=n[r.name];return n[r.name]=o?Object.assign({},o,r,{options:Object.assign({},o.options,r.options),data:Object.assign({},o.data,r.data)}):r,n},{});return Object.keys(t).map(function(n){return t[n]})}var tv={placement:"bottom",modifiers:[],strategy:"absolute"};function nv(){for(var e=arguments.length,t=new Array(e),n=0;n<e;n++)t[n]=arguments[n];return!t.some(function(r){return!(r&&typeof r.getBoundingClientRect=="function")})}function m_(e){e===void 0&&(e={});var t=e,n=t.defaultModifiers,r=n===void 0?[]:n,o=t.defaultOptions,i=o===void 0?tv:o;return function(l,s,u){u===void 0&&(u=i);var c={placement:"bottom",orderedModifiers:[],options:Object.assign({},tv,i),modifiersData:{},elements:{reference:l,popper:s},attributes:{},styles:{}},d=[],h=!1,C={state:c,setOptions:function(f){var g=typeof f=="function"?f(c.options):f;x(),c.options=Object.assign({},i,c.options,g),c.scrollParents={reference:xo(l)?Ma(l):l.contextElement?Ma(l.contextElement):[],popper:Ma(s)};var v=f_(h_([].concat(r,c.options.modifiers)));return c.orderedModifiers=v.filter(function(w){return w.enabled}),y(),C.update()},forceUpdate:function(){if(!h){var f=c.elements,g=f.reference,v=f.popper;if(nv(g,v)){c.rects={reference:c_(g,kl(v),c.options.strategy==="fixed"),popper:Zp(v)},c.reset=!1,c.placement=c.options.placement,c.orderedModifiers.forEach(function(R){return c.modifiersData[R.name]=Object.assign({},R.data)});for(var w=0;w<c.orderedModifiers.length;w++){if(c.reset===!0){c.reset=!1,w=-1;continue}var k=c.orderedModifiers[w],E=k.fn,P=k.options,$=P===void 0?{}:P,M=k.name;typeof E=="function"&&(c=E({state:c,options:$,name:M,instance:C})||c)}}}},update:p_(function(){return new Promise(function(S){C.forceUpdate(),S(c)})}),destroy:function(){x(),h=!0}};if(!nv(l,s))return C;C.setOptions(u).then(function(S){!h&&u.onFirstUpdate&&u.onFirstUpdate(S)});function y(){c.orderedModifiers.forEach(function(S){var f=S.name,g=S.options,v=g===void 0?{}:g,w=S.effect;if(typeof w=="function"){var k=w({state:c,name:f,instance:C,options:v}),E=function(){};d.push(k||E)}})}function x(){d.forEach(function(S){return S()}),d=[]}return C}}var g_=[FI,r_,zI,EI,t_,XI,a_,_I,JI],v_=m_({defaultModifiers:g_});function y_(e){return le("MuiPopper",e)}ie("MuiPopper",["root"]);const x_=["anchorEl","children","direction","disablePortal","modifiers","open","placement","popperOptions","popperRef","slotProps","slots","TransitionProps","ownerState"],b_=["anchorEl","children","container","direction","disablePortal","keepMounted","modifiers","open","placement","popperOptions","popperRef","style","transition","slotProps","slots"];function w_(e,t){if(t==="ltr")return e;switch(e){case"bottom-end":return"bottom-start";case"bottom-start":return"bottom-end";case"top-end":return"top-start";case"top-start":return"top-end";default:return e}}function vf(e){return typeof e=="function"?e():e}function C_(e){return e.nodeType!==void 0}const S_=e=>{const{classes:t}=e;return ce({root:["root"]},y_,t)},k_={},P_=m.forwardRef(function(t,n){var r;const{anchorEl:o,children:i,direction:a,disablePortal:l,modifiers:s,open:u,placement:c,popperOptions:d,popperRef:h,slotProps:C={},slots:y={},TransitionProps:x}=t,S=q(t,x_),f=m.useRef(null),g=Ke(f,n),v=m.useRef(null),w=Ke(v,h),k=m.useRef(w);rn(()=>{k.current=w},[w]),m.useImperativeHandle(h,()=>v.current,[]);const E=w_(c,a),[P,$]=m.useState(E),[M,R]=m.useState(vf(o));m.useEffect(()=>{v.current&&v.current.forceUpdate()}),m.useEffect(()=>{o&&R(vf(o))},[o]),rn(()=>{if(!M||!u)return;const F=T=>{$(T.placement)};let A=[{name:"preventOverflow",options:{altBoundary:l}},{name:"flip",options:{altBoundary:l}},{name:"onUpdate",enabled:!0,phase:"afterWrite",fn:({state:T})=>{F(T)}}];s!=null&&(A=A.concat(s)),d&&d.modifiers!=null&&(A=A.concat(d.modifiers));const B=v_(M,f.current,b({placement:E},d,{modifiers:A}));return k.current(B),()=>{B.destroy(),k.current(null)}},[M,l,s,u,d,E]);const I={placement:P};x!==null&&(I.TransitionProps=x);const D=S_(t),_=(r=y.root)!=null?r:"div",j=ft({elementType:_,externalSlotProps:C.root,externalForwardedProps:S,additionalProps:{role:"tooltip",ref:g},ownerState:t,className:D.root});return p.jsx(_,b({},j,{children:typeof i=="function"?i(I):i}))}),E_=m.forwardRef(function(t,n){const{anchorEl:r,children:o,container:i,direction:a="ltr",disablePortal:l=!1,keepMounted:s=!1,modifiers:u,open:c,placement:d="bottom",popperOptions:h=k_,popperRef:C,style:y,transition:x=!1,slotProps:S={},slots:f={}}=t,g=q(t,b_),[v,w]=m.useState(!0),k=()=>{w(!1)},E=()=>{w(!0)};if(!s&&!c&&(!x||v))return null;let P;if(i)P=i;else if(r){const R=vf(r);P=R&&C_(R)?Xe(R).body:Xe(null).body}const $=!c&&s&&(!x||v)?"none":void 0,M=x?{in:c,onEnter:k,onExited:E}:void 0;return p.jsx(Vx,{disablePortal:l,container:P,children:p.jsx(P_,b({anchorEl:r,direction:a,disablePortal:l,modifiers:u,ref:n,open:x?!v:c,placement:d,popperOptions:h,popperRef:C,slotProps:S,slots:f},g,{style:b({position:"fixed",top:0,left:0,display:$},y),TransitionProps:M,children:o}))})}),R_=["anchorEl","component","components","componentsProps","container","disablePortal","keepMounted","modifiers","open","placement","popperOptions","popperRef","transition","slots","slotProps"],$_=W(E_,{name:"MuiPopper",slot:"Root",overridesResolver:(e,t)=>t.root})({}),f1=m.forwardRef(function(t,n){var r;const o=t1(),i=se({props:t,name:"MuiPopper"}),{anchorEl:a,component:l,components:s,componentsProps:u,container:c,disablePortal:d,keepMounted:h,modifiers:C,open:y,placement:x,popperOptions:S,popperRef:f,transition:g,slots:v,slotProps:w}=i,k=q(i,R_),E=(r=v==null?void 0:v.root)!=null?r:s==null?void 0:s.Root,P=b({anchorEl:a,container:c,disablePortal:d,keepMounted:h,modifiers:C,open:y,placement:x,popperOptions:S,popperRef:f,transition:g},k);return p.jsx($_,b({as:l,direction:o==null?void 0:o.direction,slots:{root:E},slotProps:w??u},P,{ref:n}))});function T_(e){return le("MuiTooltip",e)}const Tr=ie("MuiTooltip",["popper","popperInteractive","popperArrow","popperClose","tooltip","tooltipArrow","touch","tooltipPlacementLeft","tooltipPlacementRight","tooltipPlacementTop","tooltipPlacementBottom","arrow"]),M_=["arrow","children","classes","components","componentsProps","describeChild","disableFocusListener","disableHoverListener","disableInteractive","disableTouchListener","enterDelay","enterNextDelay","enterTouchDelay","followCursor","id","leaveDelay","leaveTouchDelay","onClose","onOpen","open","placement","PopperComponent","PopperProps","slotProps","slots","title","TransitionComponent","TransitionProps"];function O_(e){return Math.round(e*1e5)/1e5}const I_=e=>{const{classes:t,disableInteractive:n,arrow:r,touch:o,placement:i}=e,a={popper:["popper",!n&&"popperInteractive",r&&"popperArrow"],tooltip:["tooltip",r&&"tooltipArrow",o&&"touch",`tooltipPlacement${z(i.split("-")[0])}`],arrow:["arrow"]};return ce(a,T_,t)},__=W(f1,{name:"MuiTooltip",slot:"Popper",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.popper,!n.disableInteractive&&t.popperInteractive,n.arrow&&t.popperArrow,!n.open&&t.popperClose]}})(({theme:e,ownerState:t,open:n})=>b({zIndex:(e.vars||e).zIndex.tooltip,pointerEvents:"none"},!t.disableInteractive&&{pointerEvents:"auto"},!n&&{pointerEvents:"none"},t.arrow&&{[`&[data-popper-placement*="bottom"] .${Tr.arrow}`]:{top:0,marginTop:"-0.71em","&::before":{transformOrigin:"0 100%"}},[`&[data-popper-placement*="top"] .${Tr.arrow}`]:{bottom:0,marginBottom:"-0.71em","&::before":{transformOrigin:"100% 0"}},[`&[data-popper-placement*="right"] .${Tr.arrow}`]:b({},t.isRtl?{right:0,marginRight:"-0.71em"}:{left:0,marginLeft:"-0.71em"},{height:"1em",width:"0.71em","&::before":{transformOrigin:"100% 100%"}}),[`&[data-popper-placement*="left"] .${Tr.arrow}`]:b({},t.isRtl?{left:0,marginLeft:"-0.71em"}:{right:0,marginRight:"-0.71em"},{height:"1em",width:"0.71em","&::before":{transformOrigin:"0 0"}})})),N_=W("div",{name:"MuiTooltip",slot:"Tooltip",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.tooltip,n.touch&&t.touch,n.arrow&&t.tooltipArrow,t[`tooltipPlacement${z(n.placement.split("-")[0])}`]]}})(({theme:e,ownerState:t})=>b({backgroundColor:e.vars?e.vars.palette.Tooltip.bg:ke(e.palette.grey[700],.92),borderRadius:(e.vars||e).shape.borderRadius,color:(e.vars||e).palette.common.white,fontFamily:e.typography.fontFamily,padding:"4px 8px",fontSize:e.typography.pxToRem(11),maxWidth:300,margin:2,wordWrap:"break-word",fontWeight:e.typography.fontWeightMedium},t.arrow&&{position:"relative",margin:0},t.touch&&{padding:"8px 16px",fontSize:e.typography.pxToRem(14),lineHeight:`${O_(16/14)}em`,fontWeight:e.typography.fontWeightRegular},{[`.${Tr.popper}[data-popper-placement*="left"] &`]:b({transformOrigin:"right center"},t.isRtl?b({marginLeft:"14px"},t.touch&&{marginLeft:"24px"}):b({marginRight:"14px"},t.touch&&{marginRight:"24px"})),[`.${Tr.popper}[data-popper-placement*="right"] &`]:b({transformOrigin:"left center"},t.isRtl?b({marginRight:"14px"},t.touch&&{marginRight:"24px"}):b({marginLeft:"14px"},t.touch&&{marginLeft:"24px"})),[`.${Tr.popper}[data-popper-placement*="top"] &`]:b({transformOrigin:"center bottom",marginBottom:"14px"},t.touch&&{marginBottom:"24px"}),[`.${Tr.popper}[data-popper-placement*="bottom"] &`]:b({transformOrigin:"center top",marginTop:"14px"},t.touch&&{marginTop:"24px"})})),L_=W("span",{name:"MuiTooltip",slot:"Arrow",overridesResolver:(e,t)=>t.arrow})(({theme:e})=>({overflow:"hidden",position:"absolute",width:"1em",height:"0.71em",boxSizing:"border-box",color:e.vars?e.vars.palette.Tooltip.bg:ke(e.palette.grey[700],.9),"&::before":{content:'""',margin:"auto",display:"block",width:"100%",height:"100%",backgroundColor:"currentColor",transform:"rotate(45deg)"}}));let Zl=!1;const rv=new xl;let sa={x:0,y:0};function es(e,t){return(n,...r)=>{t&&t(n,...r),e(n,...r)}}const rd=m.forwardRef(function(t,n){var r,o,i,a,l,s,u,c,d,h,C,y,x,S,f,g,v,w,k;const E=se({props:t,name:"MuiTooltip"}),{arrow:P=!1,children:$,components:M={},componentsProps:R={},describeChild:I=!1,disableFocusListener:D=!1,disableHoverListener:_=!1,disableInteractive:j=!1,disableTouchListener:F=!1,enterDelay:A=100,enterNextDelay:B=0,enterTouchDelay:T=700,followCursor:L=!1,id:Y,leaveDelay:te=0,leaveTouchDelay:ne=1500,onClose:me,onOpen:Q,open:he,placement:re="bottom",PopperComponent:Se,PopperProps:ye={},slotProps:we={},slots:Ae={},title:de,TransitionComponent:Te=el,TransitionProps:oe}=E,ee=q(E,M_),Z=m.isValidElement($)?$:p.jsx("span",{children:$}),Ne=ji(),Re=Ni(),[Ce,Qe]=m.useState(),[fe,xe]=m.useState(null),Ye=m.useRef(!1),Fe=j||L,Pe=io(),rt=io(),ot=io(),Je=io(),[N,O]=xi({controlled:he,default:!1,name:"Tooltip",state:"open"});let X=N;const pe=nc(Y),ge=m.useRef(),V=wt(()=>{ge.current!==void 0&&(document.body.style.WebkitUserSelect=ge.current,ge.current=void 0),Je.clear()});m.useEffect(()=>V,[V]);const ve=$e=>{rv.clear(),Zl=!0,O(!0),Q&&!X&&Q($e)},U=wt($e=>{rv.start(800+te,()=>{Zl=!1}),O(!1),me&&X&&me($e),Pe.start(Ne.transitions.duration.shortest,()=>{Ye.current=!1})}),K=$e=>{Ye.current&&$e.type!=="touchstart"||(Ce&&Ce.removeAttribute("title"),rt.clear(),ot.clear(),A||Zl&&B?rt.start(Zl?B:A,()=>{ve($e)}):ve($e))},ue=$e=>{rt.clear(),ot.start(te,()=>{U($e)})},{isFocusVisibleRef:be,onBlur:Ue,onFocus:dt,ref:_t}=bl(),[,un]=m.useState(!1),Nt=$e=>{Ue($e),be.current===!1&&(un(!1),ue($e))},nr=$e=>{Ce||Qe($e.currentTarget),dt($e),be.current===!0&&(un(!0),K($e))},Eo=$e=>{Ye.current=!0;const qt=Z.props;qt.onTouchStart&&qt.onTouchStart($e)},hc=$e=>{Eo($e),ot.clear(),Pe.clear(),V(),ge.current=document.body.style.WebkitUserSelect,document.body.style.WebkitUserSelect="none",Je.start(T,()=>{document.body.style.WebkitUserSelect=ge.current,K($e)})},Bi=$e=>{Z.props.onTouchEnd&&Z.props.onTouchEnd($e),V(),ot.start(ne,()=>{U($e)})};m.useEffect(()=>{if(!X)return;function $e(qt){(qt.key==="Escape"||qt.key==="Esc")&&U(qt)}return document.addEventListener("keydown",$e),()=>{document.removeEventListener("keydown",$e)}},[U,X]);const Di=Ke(ko(Z),_t,Qe,n);!de&&de!==0&&(X=!1);const Kr=m.useRef(),mc=$e=>{const qt=Z.props;qt.onMouseMove&&qt.onMouseMove($e),sa={x:$e.clientX,y:$e.clientY},Kr.current&&Kr.current.update()},Yr={},Wi=typeof de=="string";I?(Yr.title=!X&&Wi&&!_?de:null,Yr["aria-describedby"]=X?pe:null):(Yr["aria-label"]=Wi?de:null,Yr["aria-labelledby"]=X&&!Wi?pe:null);const Gt=b({},Yr,ee,Z.props,{className:G(ee.className,Z.props.className),onTouchStart:Eo,ref:Di},L?{onMouseMove:mc}:{}),Gr={};F||(Gt.onTouchStart=hc,Gt.onTouchEnd=Bi),_||(Gt.onMouseOver=es(K,Gt.onMouseOver),Gt.onMouseLeave=es(ue,Gt.onMouseLeave),Fe||(Gr.onMouseOver=K,Gr.onMouseLeave=ue)),D||(Gt.onFocus=es(nr,Gt.onFocus),Gt.onBlur=es(Nt,Gt.onBlur),Fe||(Gr.onFocus=nr,Gr.onBlur=Nt));const gc=m.useMemo(()=>{var $e;let qt=[{name:"arrow",enabled:!!fe,options:{element:fe,padding:4}}];return($e=ye.popperOptions)!=null&&$e.modifiers&&(qt=qt.concat(ye.popperOptions.modifiers)),b({},ye.popperOptions,{modifiers:qt})},[fe,ye]),rr=b({},E,{isRtl:Re,arrow:P,disableInteractive:Fe,placement:re,PopperComponentProp:Se,touch:Ye.current}),Ui=I_(rr),Ro=(r=(o=Ae.popper)!=null?o:M.Popper)!=null?r:__,$o=(i=(a=(l=Ae.transition)!=null?l:M.Transition)!=null?a:Te)!=null?i:el,Pl=(s=(u=Ae.tooltip)!=null?u:M.Tooltip)!=null?s:N_,Ze=(c=(d=Ae.arrow)!=null?d:M.Arrow)!=null?c:L_,gt=Zo(Ro,b({},ye,(h=we.popper)!=null?h:R.popper,{className:G(Ui.popper,ye==null?void 0:ye.className,(C=(y=we.popper)!=null?y:R.popper)==null?void 0:C.className)}),rr),Hi=Zo($o,b({},oe,(x=we.transition)!=null?x:R.transition),rr),To=Zo(Pl,b({},(S=we.tooltip)!=null?S:R.tooltip,{className:G(Ui.tooltip,(f=(g=we.tooltip)!=null?g:R.tooltip)==null?void 0:f.className)}),rr),On=Zo(Ze,b({},(v=we.arrow)!=null?v:R.arrow,{className:G(Ui.arrow,(w=(k=we.arrow)!=null?k:R.arrow)==null?void 0:w.className)}),rr);return p.jsxs(m.Fragment,{children:[m.cloneElement(Z,Gt),p.jsx(Ro,b({as:Se??f1,placement:re,anchorEl:L?{getBoundingClientRect:()=>({top:sa.y,left:sa.x,right:sa.x,bottom:sa.y,width:0,height:0})}:Ce,popperRef:Kr,open:Ce?X:!1,id:pe,transition:!0},Gr,gt,{popperOptions:gc,children:({TransitionProps:$e})=>p.jsx($o,b({timeout:Ne.transitions.duration.shorter},$e,Hi,{children:p.jsxs(Pl,b({},To,{children:[de,P?p.jsx(Ze,b({},On,{ref:xe})):null]}))}))}))]})});var oh={},j_=Po;Object.defineProperty(oh,"__esModule",{value:!0});var yf=oh.default=void 0,z_=j_(Cl()),A_=p;yf=oh.default=(0,z_.default)((0,A_.jsx)("path",{d:"M17.65 6.35C16.2 4.9 14.21 4 12 4c-4.42 0-7.99 3.58-7.99 8s3.57 8 7.99 8c3.73 0 6.84-2.55 7.73-6h-2.08c-.82 2.33-3.04 4-5.65 4-3.31 0-6-2.69-6-6s2.69-6 6-6c1.66 0 3.14.69 4.22 1.78L13 11h7V4z"}),"Refresh");var ih={},F_=Po;Object.defineProperty(ih,"__esModule",{value:!0});var p1=ih.default=void 0,B_=F_(Cl()),D_=p;p1=ih.default=(0,B_.default)((0,D_.jsx)("path",{d:"M15.5 14h-.79l-.28-.27C15.41 12.59 16 11.11 16 9.5 16 5.91 13.09 3 9.5 3S3 5.91 3 9.5 5.91 16 9.5 16c1.61 0 3.09-.59 4.23-1.57l.27.28v.79l5 4.99L20.49 19zm-6 0C7.01 14 5 11.99 5 9.5S7.01 5 9.5 5 14 7.01 14 9.5 11.99 14 9.5 14"}),"Search");var ah={},W_=Po;Object.defineProperty(ah,"__esModule",{value:!0});var h1=ah.default=void 0,U_=W_(Cl()),H_=p;h1=ah.default=(0,U_.default)((0,H_.jsx)("path",{d:"M19 6.41 17.59 5 12 10.59 6.41 5 5 6.41 10.59 12 5 17.59 6.41 19 12 13.41 17.59 19 19 17.59 13.41 12z"}),"Clear");const m1="",g1=async e=>{if(!e.ok){const t=e.headers.get("content-type");if(console.error(`API Response Error: ${e.status} ${e.statusText}`),console.error(`URL: ${e.url}`),console.error(`Content-Type: ${t}`),t&&t.includes("application/json"))try{const n=await e.json();throw console.error("Error response JSON:",n),new Error(n.message||`${e.status} ${e.statusText}`)}catch(n){console.error("Failed to parse error JSON:",n);try{const r=await e.text();throw console.error("Error response body:",r.substring(0,1e3)+(r.length>1e3?"...":"")),new Error(`API Error: ${e.status} ${e.statusText} - ${r.substring(0,100)}`)}catch{throw new Error(`API Error: ${e.status} ${e.statusText} (unable to read response body)`)}}else try{const n=await e.text();throw console.error("Non-JSON error response:",n.substring(0,1e3)+(n.length>1e3?"...":"")),new Error(`API returned non-JSON response (${e.status}): ${n.substring(0,100)}`)}catch{throw new Error(`API returned non-JSON response (${e.status}) - failed to read response body`)}}try{const t=await e.json();return console.log("Parsed API response:",t),t}catch(t){console.error("Error parsing JSON response:",t);const n=await e.text();throw console.error("Response body (failed JSON parsing):",n.substring(0,1e3)+(n.length>1e3?"...":"")),new Error("Invalid JSON response from server")}},V_=async(e={})=>{try{const t=new URLSearchParams;Object.entries(e).forEach(([a,l])=>{if(l!=null&&l!==""){const s=a.replace(/([A-Z])/g,"_$1").toLowerCase();console.log(`Converting parameter: ${a} → ${s}`);const u=l.toString();if((a==="offset"||a==="limit")&&u){const c=parseInt(u,10);!isNaN(c)&&c>=0&&(t.append(s,c.toString()),console.log(`Setting ${s}=${c.toString()}`))}else t.append(s,u)}});const n=`${m1}/api/logs?${t.toString()}`;console.log(`Fetching logs with URL: ${n}`);let r=0;const o=3;let i=null;for(;r<o;)try{r++,console.log(`Attempt ${r}/${o} to fetch logs`);const a=await fetch(n,{headers:{Accept:"application/json"},cache:"no-cache"}),l=await g1(a);return Array.isArray(l)?{logs:l,pagination:{total_items:l.length,total_pages:Math.ceil(l.length/(e.limit||50)),page_size:e.limit||50,offset:e.offset||0}}:l&&Array.isArray(l.logs)?(l.pagination||(l.pagination={total_items:l.logs.length,total_pages:Math.ceil(l.logs.length/(e.limit||50)),page_size:e.limit||50,offset:e.offset||0}),l):l&&!l.logs?(console.warn("API returned unexpected format:",l),{logs:Array.isArray(l)?l:[],pagination:{total_items:Array.isArray(l)?l.length:0,total_pages:1,page_size:e.limit||50,offset:e.offset||0}}):l}catch(a){if(i=a,r===o)throw i;console.warn(`Attempt ${r}/${o} failed, retrying in ${r*500}ms...`,a),await new Promise(l=>setTimeout(l,r*500))}}catch(t){throw console.error("Error in fetchLogs:",t),t}},K_=async()=>{try{const e=await fetch(`${m1}/api/services`);return g1(e)}catch(e){throw console.error("Error in fetchServices:",e),e}},Y_=(e={})=>{const[t,n]=m.useState([]),[r,o]=m.useState(!0),[i,a]=m.useState(null),[l,s]=m.useState(!1),[u,c]=m.useState(1),[d,h]=m.useState(50),[C,y]=m.useState(0),[x,S]=m.useState(1),f=m.useCallback(async()=>{if(!l)try{o(!0),a(null);let P;if(e.timeRange){const I=new Date;switch(e.timeRange){case"5m":P=new Date(I.getTime()-5*60*1e3);break;case"15m":P=new Date(I.getTime()-15*60*1e3);break;case"1h":P=new Date(I.getTime()-60*60*1e3);break;case"3h":P=new Date(I.getTime()-3*60*60*1e3);break;case"6h":P=new Date(I.getTime()-6*60*60*1e3);break;case"12h":P=new Date(I.getTime()-12*60*60*1e3);break;case"1d":P=new Date(I.getTime()-24*60*60*1e3);break;case"7d":P=new Date(I.getTime()-7*24*60*60*1e3);break;default:P=new Date(I.getTime()-60*60*1e3)}}console.log(`Fetching logs for page ${u}, pageSize ${d}`);const $=Math.max(0,(u-1)*d),M={limit:d,offset:String($),orderBy:"timestamp",orderDesc:!0,service:e.service||void 0,level:e.logLevel||void 0,search:e.logSearch||void 0,since:P?P.toISOString():void 0};console.log("API request options:",M);const R=await V_(M);console.log("API response data:",R),R&&R.logs?(n(R.logs),R.pagination?(y(R.pagination.total_items||0),S(Math.max(1,R.pagination.total_pages||1)),u>R.pagination.total_pages&&R.pagination.total_pages>0&&c(1)):(y(R.logs.length),S(Math.max(1,Math.ceil(R.logs.length/d))))):R&&Array.isArray(R)?(n(R),y(R.length),S(Math.max(1,Math.ceil(R.length/d)))):(console.warn("Unexpected API response format:",R),n([]),y(0),S(1))}catch(P){console.error("Error fetching logs:",P),a(`Failed to fetch logs: ${P.message}`),n([]),y(0),S(1)}finally{o(!1)}},[e,u,d,l]);m.useEffect(()=>{c(1)},[e]),m.useEffect(()=>{console.log(`Page changed to ${u}, fetching logs...`),f();let P;return l||(P=setInterval(()=>{console.log("Auto-refreshing logs..."),f()},3e4)),()=>{P&&clearInterval(P)}},[f,u,d,e,l]);const g=m.useCallback(()=>{console.log("Clearing logs"),n([]),y(0),S(1)},[]),v=m.useCallback(()=>{console.log("Toggling pause state"),s(P=>!P)},[]),w=m.useCallback(P=>{console.log(`Changing to page ${P}`),P>=1&&P<=x?(console.log(`Setting current page to ${P}`),c(P)):console.warn(`Invalid page number: ${P}. Must be between 1 and ${x}`)},[x]),k=m.useCallback(P=>{console.log(`Changing page size to ${P}`),h(P),c(1)},[]),E=m.useCallback(()=>{console.log("Manual refresh triggered"),f()},[f]);return{logs:t,loading:r,error:i,paused:l,pagination:{currentPage:u,pageSize:d,totalPages:x,totalItems:C},clearLogs:g,togglePause:v,changePage:w,changePageSize:k,refresh:E}};function Wn(e){const t=Object.prototype.toString.call(e);return e instanceof Date||typeof e=="object"&&t==="[object Date]"?new e.constructor(+e):typeof e=="number"||t==="[object Number]"||typeof e=="string"||t==="[object String]"?new Date(e):new Date(NaN)}function bo(e,t){return e instanceof Date?new e.constructor(t):new Date(t)}const v1=6048e5,G_=864e5;let q_={};function pc(){return q_}function ol(e,t){var l,s,u,c;const n=pc(),r=(t==null?void 0:t.weekStartsOn)??((s=(l=t==null?void 0:t.locale)==null?void 0:l.options)==null?void 0:s.weekStartsOn)??n.weekStartsOn??((c=(u=n.locale)==null?void 0:u.options)==null?void 0:c.weekStartsOn)??0,o=Wn(e),i=o.getDay(),a=(i<r?7:0)+i-r;return o.setDate(o.getDate()-a),o.setHours(0,0,0,0),o}function iu(e){return ol(e,{weekStartsOn:1})}function y1(e){const t=Wn(e),n=t.getFullYear(),r=bo(e,0);r.setFullYear(n+1,0,4),r.setHours(0,0,0,0);const o=iu(r),i=bo(e,0);i.setFullYear(n,0,4),i.setHours(0,0,0,0);const a=iu(i);return t.getTime()>=o.getTime()?n+1:t.getTime()>=a.getTime()?n:n-1}function ov(e){const t=Wn(e);return t.setHours(0,0,0,0),t}function iv(e){const t=Wn(e),n=new Date(Date.UTC(t.getFullYear(),t.getMonth(),t.getDate(),t.getHours(),t.getMinutes(),t.getSeconds(),t.getMilliseconds()));return n.setUTCFullYear(t.getFullYear()),+e-+n}function X_(e,t){const n=ov(e),r=ov(t),o=+n-iv(n),i=+r-iv(r);return Math.round((o-i)/G_)}function Q_(e){const t=y1(e),n=bo(e,0);return n.setFullYear(t,0,4),n.setHours(0,0,0,0),iu(n)}function J_(e){return e instanceof Date||typeof e=="object"&&Object.prototype.toString.call(e)==="[object Date]"}function Z_(e){if(!J_(e)&&typeof e!="number")return!1;const t=Wn(e);return!isNaN(Number(t))}function eN(e){const t=Wn(e),n=bo(e,0);return n.setFullYear(t.getFullYear(),0,1),n.setHours(0,0,0,0),n}const tN={lessThanXSeconds:{one:"less than a second",other:"less than {{count}} seconds"},xSeconds:{one:"1 second",other:"{{count}} seconds"},halfAMinute:"half a minute",lessThanXMinutes:{one:"less than a minute",other:"less than {{count}} minutes"},xMinutes:{one:"1 minute",other:"{{count}} minutes"},aboutXHours:{one:"about 1 hour",other:"about {{count}} hours"},xHours:{one:"1 hour",other:"{{count}} hours"},xDays:{one:"1 day",other:"{{count}} days"},aboutXWeeks:{one:"about 1 week",other:"about {{count}} weeks"},xWeeks:{one:"1 week",other:"{{count}} weeks"},aboutXMonths:{one:"about 1 month",other:"about {{count}} months"},xMonths:{one:"1 month",other:"{{count}} months"},aboutXYears:{one:"about 1 year",other:"about {{count}} years"},xYears:{one:"1 year",other:"{{count}} years"},overXYears:{one:"over 1 year",other:"over {{count}} years"},almostXYears:{one:"almost 1 year",other:"almost {{count}} years"}},nN=(e,t,n)=>{let r;const o=tN[e];return typeof o=="string"?r=o:t===1?r=o.one:r=o.other.replace("{{count}}",t.toString()),n!=null&&n.addSuffix?n.comparison&&n.comparison>0?"in "+r:r+" ago":r};function od(e){return(t={})=>{const n=t.width?String(t.width):e.defaultWidth;return e.formats[n]||e.formats[e.defaultWidth]}}const rN={full:"EEEE, MMMM do, y",long:"MMMM do, y",medium:"MMM d, y",short:"MM/dd/yyyy"},oN={full:"h:mm:ss a zzzz",long:"h:mm:ss a z",medium:"h:mm:ss a",short:"h:mm a"},iN={full:"{{date}} 'at' {{time}}",long:"{{date}} 'at' {{time}}",medium:"{{date}}, {{time}}",short:"{{date}}, {{time}}"},aN={date:od({formats:rN,defaultWidth:"full"}),time:od({formats:oN,defaultWidth:"full"}),dateTime:od({formats:iN,defaultWidth:"full"})},lN={lastWeek:"'last' eeee 'at' p",yesterday:"'yesterday at' p",today:"'today at' p",tomorrow:"'tomorrow at' p",nextWeek:"eeee 'at' p",other:"P"},sN=(e,t,n,r)=>lN[e];function ua(e){return(t,n)=>{const r=n!=null&&n.context?String(n.context):"standalone";let o;if(r==="formatting"&&e.formattingValues){const a=e.defaultFormattingWidth||e.defaultWidth,l=n!=null&&n.width?String(n.width):a;o=e.formattingValues[l]||e.formattingValues[a]}else{const a=e.defaultWidth,l=n!=null&&n.width?String(n.width):e.defaultWidth;o=e.values[l]||e.values[a]}const i=e.argumentCallback?e.argumentCallback(t):t;return o[i]}}const uN={narrow:["B","A"],abbreviated:["BC","AD"],wide:["Before Christ","Anno Domini"]},cN={narrow:["1","2","3","4"],abbreviated:["Q1","Q2","Q3","Q4"],wide:["1st quarter","2nd quarter","3rd quarter","4th quarter"]},dN={narrow:["J","F","M","A","M","J","J","A","S","O","N","D"],abbreviated:["Jan","Feb","Mar","Apr","May","Jun","Jul","Aug","Sep","Oct","Nov","Dec"],wide:["January","February","March","April","May","June","July","August","September","October","November","December"]},fN={narrow:["S","M","T","W","T","F","S"],short:["Su","Mo","Tu","We","Th","Fr","Sa"],abbreviated:["Sun","Mon","Tue","Wed","Thu","Fri","Sat"],wide:["Sunday","Monday","Tuesday","Wednesday","Thursday","Friday","Saturday"]},pN={narrow:{am:"a",pm:"p",midnight:"mi",noon:"n",morning:"morning",afternoon:"afternoon",evening:"evening",night:"night"},abbreviated:{am:"AM",pm:"PM",midnight:"midnight",noon:"noon",morning:"morning",afternoon:"afternoon",evening:"evening",night:"night"},wide:{am:"a.m.",pm:"p.m.",midnight:"midnight",noon:"noon",morning:"morning",afternoon:"afternoon",evening:"evening",night:"night"}},hN={narrow:{am:"a",pm:"p",midnight:"mi",noon:"n",morning:"in the morning",afternoon:"in the afternoon",evening:"in the evening",night:"at night"},abbreviated:{am:"AM",pm:"PM",midnight:"midnight",noon:"noon",morning:"in the morning",afternoon:"in the afternoon",evening:"in the evening",night:"at night"},wide:{am:"a.m.",pm:"p.m.",midnight:"midnight",noon:"noon",morning:"in the morning",afternoon:"in the afternoon",evening:"in the evening",night:"at night"}},mN=(e,t)=>{const n=Number(e),r=n%100;if(r>20||r<10)switch(r%10){case 1:return n+"st";case 2:return n+"nd";case 3:return n+"rd"}return n+"th"},gN={ordinalNumber:mN,era:ua({values:uN,defaultWidth:"wide"}),quarter:ua({values:cN,defaultWidth:"wide",argumentCallback:e=>e-1}),month:ua({values:dN,defaultWidth:"wide"}),day:ua({values:fN,defaultWidth:"wide"}),dayPeriod:ua({values:pN,defaultWidth:"wide",formattingValues:hN,defaultFormattingWidth:"wide"})};function ca(e){return(t,n={})=>{const r=n.width,o=r&&e.matchPatterns[r]||e.matchPatterns[e.defaultMatchWidth],i=t.match(o);if(!i)return null;const a=i[0],l=r&&e.parsePatterns[r]||e.parsePatterns[e.defaultParseWidth],s=Array.isArray(l)?yN(l,d=>d.test(a)):vN(l,d=>d.test(a));let u;u=e.valueCallback?e.valueCallback(s):s,u=n.valueCallback?n.valueCallback(u):u;const c=t.slice(a.length);return{value:u,rest:c}}}function vN(e,t){for(const n in e)if(Object.prototype.hasOwnProperty.call(e,n)&&t(e[n]))return n}function yN(e,t){for(let n=0;n<e.length;n++)if(t(e[n]))return n}function xN(e){return(t,n={})=>{const r=t.match(e.matchPattern);if(!r)return null;const o=r[0],i=t.match(e.parsePattern);if(!i)return null;let a=e.valueCallback?e.valueCallback(i[0]):i[0];a=n.valueCallback?n.valueCallback(a):a;const l=t.slice(o.length);return{value:a,rest:l}}}const bN=/^(\d+)(th|st|nd|rd)?/i,wN=/\d+/i,CN={narrow:/^(b|a)/i,abbreviated:/^(b\.?\s?c\.?|b\.?\s?c\.?\s?e\.?|a\.?\s?d\.?|c\.?\s?e\.?)/i,wide:/^(before christ|before common era|anno domini|common era)/i},SN={any:[/^b/i,/^(a|c)/i]},kN={narrow:/^[1234]/i,abbreviated:/^q[1234]/i,wide:/^[1234](th|st|nd|rd)? quarter/i},PN={any:[/1/i,/2/i,/3/i,/4/i]},EN={narrow:/^[jfmasond]/i,abbreviated:/^(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)/i,wide:/^(january|february|march|april|may|june|july|august|september|october|november|december)/i},RN={narrow:[/^j/i,/^f/i,/^m/i,/^a/i,/^m/i,/^j/i,/^j/i,/^a/i,/^s/i,/^o/i,/^n/i,/^d/i],any:[/^ja/i,/^f/i,/^mar/i,/^ap/i,/^may/i,/^jun/i,/^jul/i,/^au/i,/^s/i,/^o/i,/^n/i,/^d/i]},$N={narrow:/^[smtwf]/i,short:/^(su|mo|tu|we|th|fr|sa)/i,abbreviated:/^(sun|mon|tue|wed|thu|fri|sat)/i,wide:/^(sunday|monday|tuesday|wednesday|thursday|friday|saturday)/i},TN={narrow:[/^s/i,/^m/i,/^t/i,/^w/i,/^t/i,/^f/i,/^s/i],any:[/^su/i,/^m/i,/^tu/i,/^w/i,/^th/i,/^f/i,/^sa/i]},MN={narrow:/^(a|p|mi|n|(in the|at) (morning|afternoon|evening|night))/i,any:/^([ap]\.?\s?m\.?|midnight|noon|(in the|at) (morning|afternoon|evening|night))/i},ON={any:{am:/^a/i,pm:/^p/i,midnight:/^mi/i,noon:/^no/i,morning:/morning/i,afternoon:/afternoon/i,evening:/evening/i,night:/night/i}},IN={ordinalNumber:xN({matchPattern:bN,parsePattern:wN,valueCallback:e=>parseInt(e,10)}),era:ca({matchPatterns:CN,defaultMatchWidth:"wide",parsePatterns:SN,defaultParseWidth:"any"}),quarter:ca({matchPatterns:kN,defaultMatchWidth:"wide",parsePatterns:PN,defaultParseWidth:"any",valueCallback:e=>e+1}),month:ca({matchPatterns:EN,defaultMatchWidth:"wide",parsePatterns:RN,defaultParseWidth:"any"}),day:ca({matchPatterns:$N,defaultMatchWidth:"wide",parsePatterns:TN,defaultParseWidth:"any"}),dayPeriod:ca({matchPatterns:MN,defaultMatchWidth:"any",parsePatterns:ON,defaultParseWidth:"any"})},_N={code:"en-US",formatDistance:nN,formatLong:aN,formatRelative:sN,localize:gN,match:IN,options:{weekStartsOn:0,firstWeekContainsDate:1}};function NN(e){const t=Wn(e);return X_(t,eN(t))+1}function LN(e){const t=Wn(e),n=+iu(t)-+Q_(t);return Math.round(n/v1)+1}function x1(e,t){var c,d,h,C;const n=Wn(e),r=n.getFullYear(),o=pc(),i=(t==null?void 0:t.firstWeekContainsDate)??((d=(c=t==null?void 0:t.locale)==null?void 0:c.options)==null?void 0:d.firstWeekContainsDate)??o.firstWeekContainsDate??((C=(h=o.locale)==null?void 0:h.options)==null?void 0:C.firstWeekContainsDate)??1,a=bo(e,0);a.setFullYear(r+1,0,i),a.setHours(0,0,0,0);const l=ol(a,t),s=bo(e,0);s.setFullYear(r,0,i),s.setHours(0,0,0,0);const u=ol(s,t);return n.getTime()>=l.getTime()?r+1:n.getTime()>=u.getTime()?r:r-1}function jN(e,t){var l,s,u,c;const n=pc(),r=(t==null?void 0:t.firstWeekContainsDate)??((s=(l=t==null?void 0:t.locale)==null?void 0:l.options)==null?void 0:s.firstWeekContainsDate)??n.firstWeekContainsDate??((c=(u=n.locale)==null?void 0:u.options)==null?void 0:c.firstWeekContainsDate)??1,o=x1(e,t),i=bo(e,0);return i.setFullYear(o,0,r),i.setHours(0,0,0,0),ol(i,t)}function zN(e,t){const n=Wn(e),r=+ol(n,t)-+jN(n,t);return Math.round(r/v1)+1}function Le(e,t){const n=e<0?"-":"",r=Math.abs(e).toString().padStart(t,"0");return n+r}const br={y(e,t){const n=e.getFullYear(),r=n>0?n:1-n;return Le(t==="yy"?r%100:r,t.length)},M(e,t){const n=e.getMonth();return t==="M"?String(n+1):Le(n+1,2)},d(e,t){return Le(e.getDate(),t.length)},a(e,t){const n=e.getHours()/12>=1?"pm":"am";switch(t){case"a":case"aa":return n.toUpperCase();case"aaa":return n;case"aaaaa":return n[0];case"aaaa":default:return n==="am"?"a.m.":"p.m."}},h(e,t){return Le(e.getHours()%12||12,t.length)},H(e,t){return Le(e.getHours(),t.length)},m(e,t){return Le(e.getMinutes(),t.length)},s(e,t){return Le(e.getSeconds(),t.length)},S(e,t){const n=t.length,r=e.getMilliseconds(),o=Math.trunc(r*Math.pow(10,n-3));return Le(o,t.length)}},Ao={midnight:"midnight",noon:"noon",morning:"morning",afternoon:"afternoon",evening:"evening",night:"night"},av={G:function(e,t,n){const r=e.getFullYear()>0?1:0;switch(t){case"G":case"GG":case"GGG":return n.era(r,{width:"abbreviated"});case"GGGGG":return n.era(r,{width:"narrow"});case"GGGG":default:return n.era(r,{width:"wide"})}},y:function(e,t,n){if(t==="yo"){const r=e.getFullYear(),o=r>0?r:1-r;return n.ordinalNumber(o,{unit:"year"})}return br.y(e,t)},Y:function(e,t,n,r){const o=x1(e,r),i=o>0?o:1-o;if(t==="YY"){const a=i%100;return Le(a,2)}return t==="Yo"?n.ordinalNumber(i,{unit:"year"}):Le(i,t.length)},R:function(e,t){const n=y1(e);return Le(n,t.length)},u:function(e,t){const n=e.getFullYear();return Le(n,t.length)},Q:function(e,t,n){const r=Math.ceil((e.getMonth()+1)/3);switch(t){case"Q":return String(r);case"QQ":return Le(r,2);case"Qo":return n.ordinalNumber(r,{unit:"quarter"});case"QQQ":return n.quarter(r,{width:"abbreviated",context:"formatting"});case"QQQQQ":return n.quarter(r,{width:"narrow",context:"formatting"});case"QQQQ":default:return n.quarter(r,{width:"wide",context:"formatting"})}},q:function(e,t,n){const r=Math.ceil((e.getMonth()+1)/3);switch(t){case"q":return String(r);case"qq":return Le(r,2);case"qo":return n.ordinalNumber(r,{unit:"quarter"});case"qqq":return n.quarter(r,{width:"abbreviated",context:"standalone"});case"qqqqq":return n.quarter(r,{width:"narrow",context:"standalone"});case"qqqq":default:return n.quarter(r,{width:"wide",context:"standalone"})}},M:function(e,t,n){const r=e.getMonth();switch(t){case"M":case"MM":return br.M(e,t);case"Mo":return n.ordinalNumber(r+1,{unit:"month"});case"MMM":return n.month(r,{width:"abbreviated",context:"formatting"});case"MMMMM":return n.month(r,{width:"narrow",context:"formatting"});case"MMMM":default:return n.month(r,{width:"wide",context:"formatting"})}},L:function(e,t,n){const r=e.getMonth();switch(t){case"L":return String(r+1);case"LL":return Le(r+1,2);case"Lo":return n.ordinalNumber(r+1,{unit:"month"});case"LLL":return n.month(r,{width:"abbreviated",context:"standalone"});case"LLLLL":return n.month(r,{width:"narrow",context:"standalone"});case"LLLL":default:return n.month(r,{width:"wide",context:"standalone"})}},w:function(e,t,n,r){const o=zN(e,r);return t==="wo"?n.ordinalNumber(o,{unit:"week"}):Le(o,t.length)},I:function(e,t,n){const r=LN(e);return t==="Io"?n.ordinalNumber(r,{unit:"week"}):Le(r,t.length)},d:function(e,t,n){return t==="do"?n.ordinalNumber(e.getDate(),{unit:"date"}):br.d(e,t)},D:function(e,t,n){const r=NN(e);return t==="Do"?n.ordinalNumber(r,{unit:"dayOfYear"}):Le(r,t.length)},E:function(e,t,n){const r=e.getDay();switch(t){case"E":case"EE":case"EEE":return n.day(r,{width:"abbreviated",context:"formatting"});case"EEEEE":return n.day(r,{width:"narrow",context:"formatting"});case"EEEEEE":return n.day(r,{width:"short",context:"formatting"});case"EEEE":default:return n.day(r,{width:"wide",context:"formatting"})}},e:function(e,t,n,r){const o=e.getDay(),i=(o-r.weekStartsOn+8)%7||7;switch(t){case"e":return String(i);case"ee":return Le(i,2);case"eo":return n.ordinalNumber(i,{unit:"day"});case"eee":return n.day(o,{width:"abbreviated",context:"formatting"});case"eeeee":return n.day(o,{width:"narrow",context:"formatting"});case"eeeeee":return n.day(o,{width:"short",context:"formatting"});case"eeee":default:return n.day(o,{width:"wide",context:"formatting"})}},c:function(e,t,n,r){const o=e.getDay(),i=(o-r.weekStartsOn+8)%7||7;switch(t){case"c":return String(i);case"cc":return Le(i,t.length);case"co":return n.ordinalNumber(i,{unit:"day"});case"ccc":return n.day(o,{width:"abbreviated",context:"standalone"});case"ccccc":return n.day(o,{width:"narrow",context:"standalone"});case"cccccc":return n.day(o,{width:"short",context:"standalone"});case"cccc":default:return n.day(o,{width:"wide",context:"standalone"})}},i:function(e,t,n){const r=e.getDay(),o=r===0?7:r;switch(t){case"i":return String(o);case"ii":return Le(o,t.length);case"io":return n.ordinalNumber(o,{unit:"day"});case"iii":return n.day(r,{width:"abbreviated",context:"formatting"});case"iiiii":return n.day(r,{width:"narrow",context:"formatting"});case"iiiiii":return n.day(r,{width:"short",context:"formatting"});case"iiii":default:return n.day(r,{width:"wide",context:"formatting"})}},a:function(e,t,n){const o=e.getHours()/12>=1?"pm":"am";switch(t){case"a":case"aa":return n.dayPeriod(o,{width:"abbreviated",context:"formatting"});case"aaa":return n.dayPeriod(o,{width:"abbreviated",context:"formatting"}).toLowerCase();case"aaaaa":return n.dayPeriod(o,{width:"narrow",context:"formatting"});case"aaaa":default:return n.dayPeriod(o,{width:"wide",context:"formatting"})}},b:function(e,t,n){const r=e.getHours();let o;switch(r===12?o=Ao.noon:r===0?o=Ao.midnight:o=r/12>=1?"pm":"am",t){case"b":case"bb":return n.dayPeriod(o,{width:"abbreviated",context:"formatting"});case"bbb":return n.dayPeriod(o,{width:"abbreviated",context:"formatting"}).toLowerCase();case"bbbbb":return n.dayPeriod(o,{width:"narrow",context:"formatting"});case"bbbb":default:return n.dayPeriod(o,{width:"wide",context:"formatting"})}},B:function(e,t,n){const r=e.getHours();let o;switch(r>=17?o=Ao.evening:r>=12?o=Ao.afternoon:r>=4?o=Ao.morning:o=Ao.night,t){case"B":case"BB":case"BBB":return n.dayPeriod(o,{width:"abbreviated",context:"formatting"});case"BBBBB":return n.dayPeriod(o,{width:"narrow",context:"formatting"});case"BBBB":default:return n.dayPeriod(o,{width:"wide",context:"formatting"})}},h:function(e,t,n){if(t==="ho"){let r=e.getHours()%12;return r===0&&(r=12),n.ordinalNumber(r,{unit:"hour"})}return br.h(e,t)},H:function(e,t,n){return t==="Ho"?n.ordinalNumber(e.getHours(),{unit:"hour"}):br.H(e,t)},K:function(e,t,n){const r=e.getHours()%12;return t==="Ko"?n.ordinalNumber(r,{unit:"hour"}):Le(r,t.length)},k:function(e,t,n){let r=e.getHours();return r===0&&(r=24),t==="ko"?n.ordinalNumber(r,{unit:"hour"}):Le(r,t.length)},m:function(e,t,n){return t==="mo"?n.ordinalNumber(e.getMinutes(),{unit:"minute"}):br.m(e,t)},s:function(e,t,n){return t==="so"?n.ordinalNumber(e.getSeconds(),{unit:"second"}):br.s(e,t)},S:function(e,t){return br.S(e,t)},X:function(e,t,n){const r=e.getTimezoneOffset();if(r===0)return"Z";switch(t){case"X":return sv(r);case"XXXX":case"XX":return to(r);case"XXXXX":case"XXX":default:return to(r,":")}},x:function(e,t,n){const r=e.getTimezoneOffset();switch(t){case"x":return sv(r);case"xxxx":case"xx":return to(r);case"xxxxx":case"xxx":default:return to(r,":")}},O:function(e,t,n){const r=e.getTimezoneOffset();switch(t){case"O":case"OO":case"OOO":return"GMT"+lv(r,":");case"OOOO":default:return"GMT"+to(r,":")}},z:function(e,t,n){const r=e.getTimezoneOffset();switch(t){case"z":case"zz":case"zzz":return"GMT"+lv(r,":");case"zzzz":default:return"GMT"+to(r,":")}},t:function(e,t,n){const r=Math.trunc(e.getTime()/1e3);return Le(r,t.length)},T:function(e,t,n){const r=e.getTime();return Le(r,t.length)}};function lv(e,t=""){const n=e>0?"-":"+",r=Math.abs(e),o=Math.trunc(r/60),i=r%60;return i===0?n+String(o):n+String(o)+t+Le(i,2)}function sv(e,t){return e%60===0?(e>0?"-":"+")+Le(Math.abs(e)/60,2):to(e,t)}function to(e,t=""){const n=e>0?"-":"+",r=Math.abs(e),o=Le(Math.trunc(r/60),2),i=Le(r%60,2);return n+o+t+i}const uv=(e,t)=>{switch(e){case"P":return t.date({width:"short"});case"PP":return t.date({width:"medium"});case"PPP":return t.date({width:"long"});case"PPPP":default:return t.date({width:"full"})}},b1=(e,t)=>{switch(e){case"p":return t.time({width:"short"});case"pp":return t.time({width:"medium"});case"ppp":return t.time({width:"long"});case"pppp":default:return t.time({width:"full"})}},AN=(e,t)=>{const n=e.match(/(P+)(p+)?/)||[],r=n[1],o=n[2];if(!o)return uv(e,t);let i;switch(r){case"P":i=t.dateTime({width:"short"});break;case"PP":i=t.dateTime({width:"medium"});break;case"PPP":i=t.dateTime({width:"long"});break;case"PPPP":default:i=t.dateTime({width:"full"});break}return i.replace("{{date}}",uv(r,t)).replace("{{time}}",b1(o,t))},FN={p:b1,P:AN},BN=/^D+$/,DN=/^Y+$/,WN=["D","DD","YY","YYYY"];function UN(e){return BN.test(e)}function HN(e){return DN.test(e)}function VN(e,t,n){const r=KN(e,t,n);if(console.warn(r),WN.includes(e))throw new RangeError(r)}function KN(e,t,n){const r=e[0]==="Y"?"years":"days of the month";return`Use \`${e.toLowerCase()}\` instead of \`${e}\` (in \`${t}\`) for formatting ${r} to the input \`${n}\`; see: https://github.com/date-fns/date-fns/blob/master/docs/unicodeTokens.md`}const YN=/[yYQqMLwIdDecihHKkms]o|(\w)\1*|''|'(''|[^'])+('|$)|./g,GN=/P+p+|P+|p+|''|'(''|[^'])+('|$)|./g,qN=/^'([^]*?)'?$/,XN=/''/g,QN=/[a-zA-Z]/;function JN(e,t,n){var c,d,h,C;const r=pc(),o=r.locale??_N,i=r.firstWeekContainsDate??((d=(c=r.locale)==null?void 0:c.options)==null?void 0:d.firstWeekContainsDate)??1,a=r.weekStartsOn??((C=(h=r.locale)==null?void 0:h.options)==null?void 0:C.weekStartsOn)??0,l=Wn(e);if(!Z_(l))throw new RangeError("Invalid time value");let s=t.match(GN).map(y=>{const x=y[0];if(x==="p"||x==="P"){const S=FN[x];return S(y,o.formatLong)}return y}).join("").match(YN).map(y=>{if(y==="''")return{isToken:!1,value:"'"};const x=y[0];if(x==="'")return{isToken:!1,value:ZN(y)};if(av[x])return{isToken:!0,value:y};if(x.match(QN))throw new RangeError("Format string contains an unescaped latin alphabet character `"+x+"`");return{isToken:!1,value:y}});o.localize.preprocessor&&(s=o.localize.preprocessor(l,s));const u={firstWeekContainsDate:i,weekStartsOn:a,locale:o};return s.map(y=>{if(!y.isToken)return y.value;const x=y.value;(HN(x)||UN(x))&&VN(x,t,String(e));const S=av[x[0]];return S(l,x,o.localize,u)}).join("")}function ZN(e){const t=e.match(qN);return t?t[1].replace(XN,"'"):e}const eL=e=>{try{const t=new Date(e);return JN(t,"yyyy-MM-dd HH:mm:ss.SSS")}catch(t){return console.error("Error formatting timestamp:",t),e||"Unknown"}},tL=e=>!e||typeof e!="object"?"":Object.entries(e).map(([t,n])=>`${t}: ${n}`).join(`
+    `),jO)),DO=m.forwardRef(function(t,n){const r=se({props:t,name:"MuiCircularProgress"}),{className:o,color:i="primary",disableShrink:a=!1,size:l=40,style:s,thickness:u=3.6,value:c=0,variant:d="indeterminate"}=r,h=q(r,NO),C=b({},r,{color:i,disableShrink:a,size:l,thickness:u,value:c,variant:d}),y=zO(C),x={},S={},f={};if(d==="determinate"){const g=2*Math.PI*((xr-u)/2);x.strokeDasharray=g.toFixed(3),f["aria-valuenow"]=Math.round(c),x.strokeDashoffset=`${((100-c)/100*g).toFixed(3)}px`,S.transform="rotate(-90deg)"}return p.jsx(AO,b({className:G(y.root,o),style:b({width:l,height:l},S,s),ownerState:C,ref:n,role:"progressbar"},f,h,{children:p.jsx(FO,{className:y.svg,ownerState:C,viewBox:`${xr/2} ${xr/2} ${xr} ${xr}`,children:p.jsx(BO,{className:y.circle,style:x,ownerState:C,cx:xr,cy:xr,r:(xr-u)/2,fill:"none",strokeWidth:u})})}))}),WO=["className","elementType","ownerState","externalForwardedProps","getSlotOwnerState","internalForwardedProps"],UO=["component","slots","slotProps"],HO=["component"];function Hg(e,t){const{className:n,elementType:r,ownerState:o,externalForwardedProps:i,getSlotOwnerState:a,internalForwardedProps:l}=t,s=q(t,WO),{component:u,slots:c={[e]:void 0},slotProps:d={[e]:void 0}}=i,h=q(i,UO),C=c[e]||r,y=Ex(d[e],o),x=Px(b({className:n},s,{externalForwardedProps:e==="root"?h:void 0,externalSlotProps:y})),{props:{component:S},internalRef:f}=x,g=q(x.props,HO),v=Ke(f,y==null?void 0:y.ref,t.ref),w=a?a(g):{},k=b({},o,w),E=e==="root"?S||u:S,P=Zo(C,b({},e==="root"&&!u&&!c[e]&&l,e!=="root"&&!c[e]&&l,g,E&&{as:E},{ref:v}),k);return Object.keys(w).forEach($=>{delete P[$]}),[C,P]}function VO(e){return le("MuiAlert",e)}const Vg=ie("MuiAlert",["root","action","icon","message","filled","colorSuccess","colorInfo","colorWarning","colorError","filledSuccess","filledInfo","filledWarning","filledError","outlined","outlinedSuccess","outlinedInfo","outlinedWarning","outlinedError","standard","standardSuccess","standardInfo","standardWarning","standardError"]);function KO(e){return le("MuiIconButton",e)}const YO=ie("MuiIconButton",["root","disabled","colorInherit","colorPrimary","colorSecondary","colorError","colorInfo","colorSuccess","colorWarning","edgeStart","edgeEnd","sizeSmall","sizeMedium","sizeLarge"]),GO=["edge","children","className","color","disabled","disableFocusRipple","size"],qO=e=>{const{classes:t,disabled:n,color:r,edge:o,size:i}=e,a={root:["root",n&&"disabled",r!=="default"&&`color${z(r)}`,o&&`edge${z(o)}`,`size${z(i)}`]};return ce(a,KO,t)},XO=W(Dr,{name:"MuiIconButton",slot:"Root",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.root,n.color!=="default"&&t[`color${z(n.color)}`],n.edge&&t[`edge${z(n.edge)}`],t[`size${z(n.size)}`]]}})(({theme:e,ownerState:t})=>b({textAlign:"center",flex:"0 0 auto",fontSize:e.typography.pxToRem(24),padding:8,borderRadius:"50%",overflow:"visible",color:(e.vars||e).palette.action.active,transition:e.transitions.create("background-color",{duration:e.transitions.duration.shortest})},!t.disableRipple&&{"&:hover":{backgroundColor:e.vars?`rgba(${e.vars.palette.action.activeChannel} / ${e.vars.palette.action.hoverOpacity})`:ke(e.palette.action.active,e.palette.action.hoverOpacity),"@media (hover: none)":{backgroundColor:"transparent"}}},t.edge==="start"&&{marginLeft:t.size==="small"?-3:-12},t.edge==="end"&&{marginRight:t.size==="small"?-3:-12}),({theme:e,ownerState:t})=>{var n;const r=(n=(e.vars||e).palette)==null?void 0:n[t.color];return b({},t.color==="inherit"&&{color:"inherit"},t.color!=="inherit"&&t.color!=="default"&&b({color:r==null?void 0:r.main},!t.disableRipple&&{"&:hover":b({},r&&{backgroundColor:e.vars?`rgba(${r.mainChannel} / ${e.vars.palette.action.hoverOpacity})`:ke(r.main,e.palette.action.hoverOpacity)},{"@media (hover: none)":{backgroundColor:"transparent"}})}),t.size==="small"&&{padding:5,fontSize:e.typography.pxToRem(18)},t.size==="large"&&{padding:12,fontSize:e.typography.pxToRem(28)},{[`&.${YO.disabled}`]:{backgroundColor:"transparent",color:(e.vars||e).palette.action.disabled}})}),hf=m.forwardRef(function(t,n){const r=se({props:t,name:"MuiIconButton"}),{edge:o=!1,children:i,className:a,color:l="default",disabled:s=!1,disableFocusRipple:u=!1,size:c="medium"}=r,d=q(r,GO),h=b({},r,{edge:o,color:l,disabled:s,disableFocusRipple:u,size:c}),C=qO(h);return p.jsx(XO,b({className:G(C.root,a),centerRipple:!0,focusRipple:!u,disabled:s,ref:n},d,{ownerState:h,children:i}))}),QO=Yt(p.jsx("path",{d:"M20,12A8,8 0 0,1 12,20A8,8 0 0,1 4,12A8,8 0 0,1 12,4C12.76,4 13.5,4.11 14.2, 4.31L15.77,2.74C14.61,2.26 13.34,2 12,2A10,10 0 0,0 2,12A10,10 0 0,0 12,22A10,10 0 0, 0 22,12M7.91,10.08L6.5,11.5L11,16L21,6L19.59,4.58L11,13.17L7.91,10.08Z"}),"SuccessOutlined"),JO=Yt(p.jsx("path",{d:"M12 5.99L19.53 19H4.47L12 5.99M12 2L1 21h22L12 2zm1 14h-2v2h2v-2zm0-6h-2v4h2v-4z"}),"ReportProblemOutlined"),ZO=Yt(p.jsx("path",{d:"M11 15h2v2h-2zm0-8h2v6h-2zm.99-5C6.47 2 2 6.48 2 12s4.47 10 9.99 10C17.52 22 22 17.52 22 12S17.52 2 11.99 2zM12 20c-4.42 0-8-3.58-8-8s3.58-8 8-8 8 3.58 8 8-3.58 8-8 8z"}),"ErrorOutline"),eI=Yt(p.jsx("path",{d:"M11,9H13V7H11M12,20C7.59,20 4,16.41 4,12C4,7.59 7.59,4 12,4C16.41,4 20,7.59 20, 12C20,16.41 16.41,20 12,20M12,2A10,10 0 0,0 2,12A10,10 0 0,0 12,22A10,10 0 0,0 22,12A10, 10 0 0,0 12,2M11,17H13V11H11V17Z"}),"InfoOutlined"),tI=Yt(p.jsx("path",{d:"M19 6.41L17.59 5 12 10.59 6.41 5 5 6.41 10.59 12 5 17.59 6.41 19 12 13.41 17.59 19 19 17.59 13.41 12z"}),"Close"),nI=["action","children","className","closeText","color","components","componentsProps","icon","iconMapping","onClose","role","severity","slotProps","slots","variant"],rI=e=>{const{variant:t,color:n,severity:r,classes:o}=e,i={root:["root",`color${z(n||r)}`,`${t}${z(n||r)}`,`${t}`],icon:["icon"],message:["message"],action:["action"]};return ce(i,VO,o)},oI=W(Dn,{name:"MuiAlert",slot:"Root",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.root,t[n.variant],t[`${n.variant}${z(n.color||n.severity)}`]]}})(({theme:e})=>{const t=e.palette.mode==="light"?bi:wi,n=e.palette.mode==="light"?wi:bi;return b({},e.typography.body2,{backgroundColor:"transparent",display:"flex",padding:"6px 16px",variants:[...Object.entries(e.palette).filter(([,r])=>r.main&&r.light).map(([r])=>({props:{colorSeverity:r,variant:"standard"},style:{color:e.vars?e.vars.palette.Alert[`${r}Color`]:t(e.palette[r].light,.6),backgroundColor:e.vars?e.vars.palette.Alert[`${r}StandardBg`]:n(e.palette[r].light,.9),[`& .${Vg.icon}`]:e.vars?{color:e.vars.palette.Alert[`${r}IconColor`]}:{color:e.palette[r].main}}})),...Object.entries(e.palette).filter(([,r])=>r.main&&r.light).map(([r])=>({props:{colorSeverity:r,variant:"outlined"},style:{color:e.vars?e.vars.palette.Alert[`${r}Color`]:t(e.palette[r].light,.6),border:`1px solid ${(e.vars||e).palette[r].light}`,[`& .${Vg.icon}`]:e.vars?{color:e.vars.palette.Alert[`${r}IconColor`]}:{color:e.palette[r].main}}})),...Object.entries(e.palette).filter(([,r])=>r.main&&r.dark).map(([r])=>({props:{colorSeverity:r,variant:"filled"},style:b({fontWeight:e.typography.fontWeightMedium},e.vars?{color:e.vars.palette.Alert[`${r}FilledColor`],backgroundColor:e.vars.palette.Alert[`${r}FilledBg`]}:{backgroundColor:e.palette.mode==="dark"?e.palette[r].dark:e.palette[r].main,color:e.palette.getContrastText(e.palette[r].main)})}))]})}),iI=W("div",{name:"MuiAlert",slot:"Icon",overridesResolver:(e,t)=>t.icon})({marginRight:12,padding:"7px 0",display:"flex",fontSize:22,opacity:.9}),aI=W("div",{name:"MuiAlert",slot:"Message",overridesResolver:(e,t)=>t.message})({padding:"8px 0",minWidth:0,overflow:"auto"}),Kg=W("div",{name:"MuiAlert",slot:"Action",overridesResolver:(e,t)=>t.action})({display:"flex",alignItems:"flex-start",padding:"4px 0 0 16px",marginLeft:"auto",marginRight:-8}),Yg={success:p.jsx(QO,{fontSize:"inherit"}),warning:p.jsx(JO,{fontSize:"inherit"}),error:p.jsx(ZO,{fontSize:"inherit"}),info:p.jsx(eI,{fontSize:"inherit"})},e1=m.forwardRef(function(t,n){const r=se({props:t,name:"MuiAlert"}),{action:o,children:i,className:a,closeText:l="Close",color:s,components:u={},componentsProps:c={},icon:d,iconMapping:h=Yg,onClose:C,role:y="alert",severity:x="success",slotProps:S={},slots:f={},variant:g="standard"}=r,v=q(r,nI),w=b({},r,{color:s,severity:x,variant:g,colorSeverity:s||x}),k=rI(w),E={slots:b({closeButton:u.CloseButton,closeIcon:u.CloseIcon},f),slotProps:b({},c,S)},[P,$]=Hg("closeButton",{elementType:hf,externalForwardedProps:E,ownerState:w}),[M,R]=Hg("closeIcon",{elementType:tI,externalForwardedProps:E,ownerState:w});return p.jsxs(oI,b({role:y,elevation:0,ownerState:w,className:G(k.root,a),ref:n},v,{children:[d!==!1?p.jsx(iI,{ownerState:w,className:k.icon,children:d||h[x]||Yg[x]}):null,p.jsx(aI,{ownerState:w,className:k.message,children:i}),o!=null?p.jsx(Kg,{ownerState:w,className:k.action,children:o}):null,o==null&&C?p.jsx(Kg,{ownerState:w,className:k.action,children:p.jsx(P,b({size:"small","aria-label":l,title:l,color:"inherit",onClick:C},$,{children:p.jsx(M,b({fontSize:"small"},R))}))}):null]}))});var Xp={};Object.defineProperty(Xp,"__esModule",{value:!0});var t1=Xp.default=void 0,lI=uI(m),sI=Ax;function n1(e){if(typeof WeakMap!="function")return null;var t=new WeakMap,n=new WeakMap;return(n1=function(r){return r?n:t})(e)}function uI(e,t){if(e&&e.__esModule)return e;if(e===null||typeof e!="object"&&typeof e!="function")return{default:e};var n=n1(t);if(n&&n.has(e))return n.get(e);var r={__proto__:null},o=Object.defineProperty&&Object.getOwnPropertyDescriptor;for(var i in e)if(i!=="default"&&Object.prototype.hasOwnProperty.call(e,i)){var a=o?Object.getOwnPropertyDescriptor(e,i):null;a&&(a.get||a.set)?Object.defineProperty(r,i,a):r[i]=e[i]}return r.default=e,n&&n.set(e,r),r}function cI(e){return Object.keys(e).length===0}function dI(e=null){const t=lI.useContext(sI.ThemeContext);return!t||cI(t)?e:t}t1=Xp.default=dI;var Ht="top",En="bottom",Rn="right",Vt="left",Qp="auto",Sl=[Ht,En,Rn,Vt],Si="start",nl="end",fI="clippingParents",r1="viewport",la="popper",pI="reference",Gg=Sl.reduce(function(e,t){return e.concat([t+"-"+Si,t+"-"+nl])},[]),o1=[].concat(Sl,[Qp]).reduce(function(e,t){return e.concat([t,t+"-"+Si,t+"-"+nl])},[]),hI="beforeRead",mI="read",gI="afterRead",vI="beforeMain",yI="main",xI="afterMain",bI="beforeWrite",wI="write",CI="afterWrite",SI=[hI,mI,gI,vI,yI,xI,bI,wI,CI];function er(e){return e?(e.nodeName||"").toLowerCase():null}function on(e){if(e==null)return window;if(e.toString()!=="[object Window]"){var t=e.ownerDocument;return t&&t.defaultView||window}return e}function xo(e){var t=on(e).Element;return e instanceof t||e instanceof Element}function wn(e){var t=on(e).HTMLElement;return e instanceof t||e instanceof HTMLElement}function Jp(e){if(typeof ShadowRoot>"u")return!1;var t=on(e).ShadowRoot;return e instanceof t||e instanceof ShadowRoot}function kI(e){var t=e.state;Object.keys(t.elements).forEach(function(n){var r=t.styles[n]||{},o=t.attributes[n]||{},i=t.elements[n];!wn(i)||!er(i)||(Object.assign(i.style,r),Object.keys(o).forEach(function(a){var l=o[a];l===!1?i.removeAttribute(a):i.setAttribute(a,l===!0?"":l)}))})}function PI(e){var t=e.state,n={popper:{position:t.options.strategy,left:"0",top:"0",margin:"0"},arrow:{position:"absolute"},reference:{}};return Object.assign(t.elements.popper.style,n.popper),t.styles=n,t.elements.arrow&&Object.assign(t.elements.arrow.style,n.arrow),function(){Object.keys(t.elements).forEach(function(r){var o=t.elements[r],i=t.attributes[r]||{},a=Object.keys(t.styles.hasOwnProperty(r)?t.styles[r]:n[r]),l=a.reduce(function(s,u){return s[u]="",s},{});!wn(o)||!er(o)||(Object.assign(o.style,l),Object.keys(i).forEach(function(s){o.removeAttribute(s)}))})}}const EI={name:"applyStyles",enabled:!0,phase:"write",fn:kI,effect:PI,requires:["computeStyles"]};function Jn(e){return e.split("-")[0]}var fo=Math.max,ou=Math.min,ki=Math.round;function mf(){var e=navigator.userAgentData;return e!=null&&e.brands&&Array.isArray(e.brands)?e.brands.map(function(t){return t.brand+"/"+t.version}).join(" "):navigator.userAgent}function i1(){return!/^((?!chrome|android).)*safari/i.test(mf())}function Pi(e,t,n){t===void 0&&(t=!1),n===void 0&&(n=!1);var r=e.getBoundingClientRect(),o=1,i=1;t&&wn(e)&&(o=e.offsetWidth>0&&ki(r.width)/e.offsetWidth||1,i=e.offsetHeight>0&&ki(r.height)/e.offsetHeight||1);var a=xo(e)?on(e):window,l=a.visualViewport,s=!i1()&&n,u=(r.left+(s&&l?l.offsetLeft:0))/o,c=(r.top+(s&&l?l.offsetTop:0))/i,d=r.width/o,h=r.height/i;return{width:d,height:h,top:c,right:u+d,bottom:c+h,left:u,x:u,y:c}}function Zp(e){var t=Pi(e),n=e.offsetWidth,r=e.offsetHeight;return Math.abs(t.width-n)<=1&&(n=t.width),Math.abs(t.height-r)<=1&&(r=t.height),{x:e.offsetLeft,y:e.offsetTop,width:n,height:r}}function a1(e,t){var n=t.getRootNode&&t.getRootNode();if(e.contains(t))return!0;if(n&&Jp(n)){var r=t;do{if(r&&e.isSameNode(r))return!0;r=r.parentNode||r.host}while(r)}return!1}function pr(e){return on(e).getComputedStyle(e)}function RI(e){return["table","td","th"].indexOf(er(e))>=0}function Vr(e){return((xo(e)?e.ownerDocument:e.document)||window.document).documentElement}function fc(e){return er(e)==="html"?e:e.assignedSlot||e.parentNode||(Jp(e)?e.host:null)||Vr(e)}function qg(e){return!wn(e)||pr(e).position==="fixed"?null:e.offsetParent}function $I(e){var t=/firefox/i.test(mf()),n=/Trident/i.test(mf());if(n&&wn(e)){var r=pr(e);if(r.position==="fixed")return null}var o=fc(e);for(Jp(o)&&(o=o.host);wn(o)&&["html","body"].indexOf(er(o))<0;){var i=pr(o);if(i.transform!=="none"||i.perspective!=="none"||i.contain==="paint"||["transform","perspective"].indexOf(i.willChange)!==-1||t&&i.willChange==="filter"||t&&i.filter&&i.filter!=="none")return o;o=o.parentNode}return null}function kl(e){for(var t=on(e),n=qg(e);n&&RI(n)&&pr(n).position==="static";)n=qg(n);return n&&(er(n)==="html"||er(n)==="body"&&pr(n).position==="static")?t:n||$I(e)||t}function eh(e){return["top","bottom"].indexOf(e)>=0?"x":"y"}function Ta(e,t,n){return fo(e,ou(t,n))}function TI(e,t,n){var r=Ta(e,t,n);return r>n?n:r}function l1(){return{top:0,right:0,bottom:0,left:0}}function s1(e){return Object.assign({},l1(),e)}function u1(e,t){return t.reduce(function(n,r){return n[r]=e,n},{})}var MI=function(t,n){return t=typeof t=="function"?t(Object.assign({},n.rects,{placement:n.placement})):t,s1(typeof t!="number"?t:u1(t,Sl))};function OI(e){var t,n=e.state,r=e.name,o=e.options,i=n.elements.arrow,a=n.modifiersData.popperOffsets,l=Jn(n.placement),s=eh(l),u=[Vt,Rn].indexOf(l)>=0,c=u?"height":"width";if(!(!i||!a)){var d=MI(o.padding,n),h=Zp(i),C=s==="y"?Ht:Vt,y=s==="y"?En:Rn,x=n.rects.reference[c]+n.rects.reference[s]-a[s]-n.rects.popper[c],S=a[s]-n.rects.reference[s],f=kl(i),g=f?s==="y"?f.clientHeight||0:f.clientWidth||0:0,v=x/2-S/2,w=d[C],k=g-h[c]-d[y],E=g/2-h[c]/2+v,P=Ta(w,E,k),$=s;n.modifiersData[r]=(t={},t[$]=P,t.centerOffset=P-E,t)}}function II(e){var t=e.state,n=e.options,r=n.element,o=r===void 0?"[data-popper-arrow]":r;o!=null&&(typeof o=="string"&&(o=t.elements.popper.querySelector(o),!o)||a1(t.elements.popper,o)&&(t.elements.arrow=o))}const _I={name:"arrow",enabled:!0,phase:"main",fn:OI,effect:II,requires:["popperOffsets"],requiresIfExists:["preventOverflow"]};function Ei(e){return e.split("-")[1]}var NI={top:"auto",right:"auto",bottom:"auto",left:"auto"};function LI(e,t){var n=e.x,r=e.y,o=t.devicePixelRatio||1;return{x:ki(n*o)/o||0,y:ki(r*o)/o||0}}function Xg(e){var t,n=e.popper,r=e.popperRect,o=e.placement,i=e.variation,a=e.offsets,l=e.position,s=e.gpuAcceleration,u=e.adaptive,c=e.roundOffsets,d=e.isFixed,h=a.x,C=h===void 0?0:h,y=a.y,x=y===void 0?0:y,S=typeof c=="function"?c({x:C,y:x}):{x:C,y:x};C=S.x,x=S.y;var f=a.hasOwnProperty("x"),g=a.hasOwnProperty("y"),v=Vt,w=Ht,k=window;if(u){var E=kl(n),P="clientHeight",$="clientWidth";if(E===on(n)&&(E=Vr(n),pr(E).position!=="static"&&l==="absolute"&&(P="scrollHeight",$="scrollWidth")),E=E,o===Ht||(o===Vt||o===Rn)&&i===nl){w=En;var M=d&&E===k&&k.visualViewport?k.visualViewport.height:E[P];x-=M-r.height,x*=s?1:-1}if(o===Vt||(o===Ht||o===En)&&i===nl){v=Rn;var R=d&&E===k&&k.visualViewport?k.visualViewport.width:E[$];C-=R-r.width,C*=s?1:-1}}var I=Object.assign({position:l},u&&NI),D=c===!0?LI({x:C,y:x},on(n)):{x:C,y:x};if(C=D.x,x=D.y,s){var _;return Object.assign({},I,(_={},_[w]=g?"0":"",_[v]=f?"0":"",_.transform=(k.devicePixelRatio||1)<=1?"translate("+C+"px, "+x+"px)":"translate3d("+C+"px, "+x+"px, 0)",_))}return Object.assign({},I,(t={},t[w]=g?x+"px":"",t[v]=f?C+"px":"",t.transform="",t))}function jI(e){var t=e.state,n=e.options,r=n.gpuAcceleration,o=r===void 0?!0:r,i=n.adaptive,a=i===void 0?!0:i,l=n.roundOffsets,s=l===void 0?!0:l,u={placement:Jn(t.placement),variation:Ei(t.placement),popper:t.elements.popper,popperRect:t.rects.popper,gpuAcceleration:o,isFixed:t.options.strategy==="fixed"};t.modifiersData.popperOffsets!=null&&(t.styles.popper=Object.assign({},t.styles.popper,Xg(Object.assign({},u,{offsets:t.modifiersData.popperOffsets,position:t.options.strategy,adaptive:a,roundOffsets:s})))),t.modifiersData.arrow!=null&&(t.styles.arrow=Object.assign({},t.styles.arrow,Xg(Object.assign({},u,{offsets:t.modifiersData.arrow,position:"absolute",adaptive:!1,roundOffsets:s})))),t.attributes.popper=Object.assign({},t.attributes.popper,{"data-popper-placement":t.placement})}const zI={name:"computeStyles",enabled:!0,phase:"beforeWrite",fn:jI,data:{}};var Jl={passive:!0};function AI(e){var t=e.state,n=e.instance,r=e.options,o=r.scroll,i=o===void 0?!0:o,a=r.resize,l=a===void 0?!0:a,s=on(t.elements.popper),u=[].concat(t.scrollParents.reference,t.scrollParents.popper);return i&&u.forEach(function(c){c.addEventListener("scroll",n.update,Jl)}),l&&s.addEventListener("resize",n.update,Jl),function(){i&&u.forEach(function(c){c.removeEventListener("scroll",n.update,Jl)}),l&&s.removeEventListener("resize",n.update,Jl)}}const FI={name:"eventListeners",enabled:!0,phase:"write",fn:function(){},effect:AI,data:{}};var BI={left:"right",right:"left",bottom:"top",top:"bottom"};function ks(e){return e.replace(/left|right|bottom|top/g,function(t){return BI[t]})}var DI={start:"end",end:"start"};function Qg(e){return e.replace(/start|end/g,function(t){return DI[t]})}function th(e){var t=on(e),n=t.pageXOffset,r=t.pageYOffset;return{scrollLeft:n,scrollTop:r}}function nh(e){return Pi(Vr(e)).left+th(e).scrollLeft}function WI(e,t){var n=on(e),r=Vr(e),o=n.visualViewport,i=r.clientWidth,a=r.clientHeight,l=0,s=0;if(o){i=o.width,a=o.height;var u=i1();(u||!u&&t==="fixed")&&(l=o.offsetLeft,s=o.offsetTop)}return{width:i,height:a,x:l+nh(e),y:s}}function UI(e){var t,n=Vr(e),r=th(e),o=(t=e.ownerDocument)==null?void 0:t.body,i=fo(n.scrollWidth,n.clientWidth,o?o.scrollWidth:0,o?o.clientWidth:0),a=fo(n.scrollHeight,n.clientHeight,o?o.scrollHeight:0,o?o.clientHeight:0),l=-r.scrollLeft+nh(e),s=-r.scrollTop;return pr(o||n).direction==="rtl"&&(l+=fo(n.clientWidth,o?o.clientWidth:0)-i),{width:i,height:a,x:l,y:s}}function rh(e){var t=pr(e),n=t.overflow,r=t.overflowX,o=t.overflowY;return/auto|scroll|overlay|hidden/.test(n+o+r)}function c1(e){return["html","body","#document"].indexOf(er(e))>=0?e.ownerDocument.body:wn(e)&&rh(e)?e:c1(fc(e))}function Ma(e,t){var n;t===void 0&&(t=[]);var r=c1(e),o=r===((n=e.ownerDocument)==null?void 0:n.body),i=on(r),a=o?[i].concat(i.visualViewport||[],rh(r)?r:[]):r,l=t.concat(a);return o?l:l.concat(Ma(fc(a)))}function gf(e){return Object.assign({},e,{left:e.x,top:e.y,right:e.x+e.width,bottom:e.y+e.height})}function HI(e,t){var n=Pi(e,!1,t==="fixed");return n.top=n.top+e.clientTop,n.left=n.left+e.clientLeft,n.bottom=n.top+e.clientHeight,n.right=n.left+e.clientWidth,n.width=e.clientWidth,n.height=e.clientHeight,n.x=n.left,n.y=n.top,n}function Jg(e,t,n){return t===r1?gf(WI(e,n)):xo(t)?HI(t,n):gf(UI(Vr(e)))}function VI(e){var t=Ma(fc(e)),n=["absolute","fixed"].indexOf(pr(e).position)>=0,r=n&&wn(e)?kl(e):e;return xo(r)?t.filter(function(o){return xo(o)&&a1(o,r)&&er(o)!=="body"}):[]}function KI(e,t,n,r){var o=t==="clippingParents"?VI(e):[].concat(t),i=[].concat(o,[n]),a=i[0],l=i.reduce(function(s,u){var c=Jg(e,u,r);return s.top=fo(c.top,s.top),s.right=ou(c.right,s.right),s.bottom=ou(c.bottom,s.bottom),s.left=fo(c.left,s.left),s},Jg(e,a,r));return l.width=l.right-l.left,l.height=l.bottom-l.top,l.x=l.left,l.y=l.top,l}function d1(e){var t=e.reference,n=e.element,r=e.placement,o=r?Jn(r):null,i=r?Ei(r):null,a=t.x+t.width/2-n.width/2,l=t.y+t.height/2-n.height/2,s;switch(o){case Ht:s={x:a,y:t.y-n.height};break;case En:s={x:a,y:t.y+t.height};break;case Rn:s={x:t.x+t.width,y:l};break;case Vt:s={x:t.x-n.width,y:l};break;default:s={x:t.x,y:t.y}}var u=o?eh(o):null;if(u!=null){var c=u==="y"?"height":"width";switch(i){case Si:s[u]=s[u]-(t[c]/2-n[c]/2);break;case nl:s[u]=s[u]+(t[c]/2-n[c]/2);break}}return s}function rl(e,t){t===void 0&&(t={});var n=t,r=n.placement,o=r===void 0?e.placement:r,i=n.strategy,a=i===void 0?e.strategy:i,l=n.boundary,s=l===void 0?fI:l,u=n.rootBoundary,c=u===void 0?r1:u,d=n.elementContext,h=d===void 0?la:d,C=n.altBoundary,y=C===void 0?!1:C,x=n.padding,S=x===void 0?0:x,f=s1(typeof S!="number"?S:u1(S,Sl)),g=h===la?pI:la,v=e.rects.popper,w=e.elements[y?g:h],k=KI(xo(w)?w:w.contextElement||Vr(e.elements.popper),s,c,a),E=Pi(e.elements.reference),P=d1({reference:E,element:v,placement:o}),$=gf(Object.assign({},v,P)),M=h===la?$:E,R={top:k.top-M.top+f.top,bottom:M.bottom-k.bottom+f.bottom,left:k.left-M.left+f.left,right:M.right-k.right+f.right},I=e.modifiersData.offset;if(h===la&&I){var D=I[o];Object.keys(R).forEach(function(_){var j=[Rn,En].indexOf(_)>=0?1:-1,F=[Ht,En].indexOf(_)>=0?"y":"x";R[_]+=D[F]*j})}return R}function YI(e,t){t===void 0&&(t={});var n=t,r=n.placement,o=n.boundary,i=n.rootBoundary,a=n.padding,l=n.flipVariations,s=n.allowedAutoPlacements,u=s===void 0?o1:s,c=Ei(r),d=c?l?Gg:Gg.filter(function(y){return Ei(y)===c}):Sl,h=d.filter(function(y){return u.indexOf(y)>=0});h.length===0&&(h=d);var C=h.reduce(function(y,x){return y[x]=rl(e,{placement:x,boundary:o,rootBoundary:i,padding:a})[Jn(x)],y},{});return Object.keys(C).sort(function(y,x){return C[y]-C[x]})}function GI(e){if(Jn(e)===Qp)return[];var t=ks(e);return[Qg(e),t,Qg(t)]}function qI(e){var t=e.state,n=e.options,r=e.name;if(!t.modifiersData[r]._skip){for(var o=n.mainAxis,i=o===void 0?!0:o,a=n.altAxis,l=a===void 0?!0:a,s=n.fallbackPlacements,u=n.padding,c=n.boundary,d=n.rootBoundary,h=n.altBoundary,C=n.flipVariations,y=C===void 0?!0:C,x=n.allowedAutoPlacements,S=t.options.placement,f=Jn(S),g=f===S,v=s||(g||!y?[ks(S)]:GI(S)),w=[S].concat(v).reduce(function(Q,he){return Q.concat(Jn(he)===Qp?YI(t,{placement:he,boundary:c,rootBoundary:d,padding:u,flipVariations:y,allowedAutoPlacements:x}):he)},[]),k=t.rects.reference,E=t.rects.popper,P=new Map,$=!0,M=w[0],R=0;R<w.length;R++){var I=w[R],D=Jn(I),_=Ei(I)===Si,j=[Ht,En].indexOf(D)>=0,F=j?"width":"height",A=rl(t,{placement:I,boundary:c,rootBoundary:d,altBoundary:h,padding:u}),B=j?_?Rn:Vt:_?En:Ht;k[F]>E[F]&&(B=ks(B));var T=ks(B),L=[];if(i&&L.push(A[D]<=0),l&&L.push(A[B]<=0,A[T]<=0),L.every(function(Q){return Q})){M=I,$=!1;break}P.set(I,L)}if($)for(var Y=y?3:1,te=function(he){var re=w.find(function(Se){var ye=P.get(Se);if(ye)return ye.slice(0,he).every(function(we){return we})});if(re)return M=re,"break"},ne=Y;ne>0;ne--){var me=te(ne);if(me==="break")break}t.placement!==M&&(t.modifiersData[r]._skip=!0,t.placement=M,t.reset=!0)}}const XI={name:"flip",enabled:!0,phase:"main",fn:qI,requiresIfExists:["offset"],data:{_skip:!1}};function Zg(e,t,n){return n===void 0&&(n={x:0,y:0}),{top:e.top-t.height-n.y,right:e.right-t.width+n.x,bottom:e.bottom-t.height+n.y,left:e.left-t.width-n.x}}function ev(e){return[Ht,Rn,En,Vt].some(function(t){return e[t]>=0})}function QI(e){var t=e.state,n=e.name,r=t.rects.reference,o=t.rects.popper,i=t.modifiersData.preventOverflow,a=rl(t,{elementContext:"reference"}),l=rl(t,{altBoundary:!0}),s=Zg(a,r),u=Zg(l,o,i),c=ev(s),d=ev(u);t.modifiersData[n]={referenceClippingOffsets:s,popperEscapeOffsets:u,isReferenceHidden:c,hasPopperEscaped:d},t.attributes.popper=Object.assign({},t.attributes.popper,{"data-popper-reference-hidden":c,"data-popper-escaped":d})}const JI={name:"hide",enabled:!0,phase:"main",requiresIfExists:["preventOverflow"],fn:QI};function ZI(e,t,n){var r=Jn(e),o=[Vt,Ht].indexOf(r)>=0?-1:1,i=typeof n=="function"?n(Object.assign({},t,{placement:e})):n,a=i[0],l=i[1];return a=a||0,l=(l||0)*o,[Vt,Rn].indexOf(r)>=0?{x:l,y:a}:{x:a,y:l}}function e_(e){var t=e.state,n=e.options,r=e.name,o=n.offset,i=o===void 0?[0,0]:o,a=o1.reduce(function(c,d){return c[d]=ZI(d,t.rects,i),c},{}),l=a[t.placement],s=l.x,u=l.y;t.modifiersData.popperOffsets!=null&&(t.modifiersData.popperOffsets.x+=s,t.modifiersData.popperOffsets.y+=u),t.modifiersData[r]=a}const t_={name:"offset",enabled:!0,phase:"main",requires:["popperOffsets"],fn:e_};function n_(e){var t=e.state,n=e.name;t.modifiersData[n]=d1({reference:t.rects.reference,element:t.rects.popper,placement:t.placement})}const r_={name:"popperOffsets",enabled:!0,phase:"read",fn:n_,data:{}};function o_(e){return e==="x"?"y":"x"}function i_(e){var t=e.state,n=e.options,r=e.name,o=n.mainAxis,i=o===void 0?!0:o,a=n.altAxis,l=a===void 0?!1:a,s=n.boundary,u=n.rootBoundary,c=n.altBoundary,d=n.padding,h=n.tether,C=h===void 0?!0:h,y=n.tetherOffset,x=y===void 0?0:y,S=rl(t,{boundary:s,rootBoundary:u,padding:d,altBoundary:c}),f=Jn(t.placement),g=Ei(t.placement),v=!g,w=eh(f),k=o_(w),E=t.modifiersData.popperOffsets,P=t.rects.reference,$=t.rects.popper,M=typeof x=="function"?x(Object.assign({},t.rects,{placement:t.placement})):x,R=typeof M=="number"?{mainAxis:M,altAxis:M}:Object.assign({mainAxis:0,altAxis:0},M),I=t.modifiersData.offset?t.modifiersData.offset[t.placement]:null,D={x:0,y:0};if(E){if(i){var _,j=w==="y"?Ht:Vt,F=w==="y"?En:Rn,A=w==="y"?"height":"width",B=E[w],T=B+S[j],L=B-S[F],Y=C?-$[A]/2:0,te=g===Si?P[A]:$[A],ne=g===Si?-$[A]:-P[A],me=t.elements.arrow,Q=C&&me?Zp(me):{width:0,height:0},he=t.modifiersData["arrow#persistent"]?t.modifiersData["arrow#persistent"].padding:l1(),re=he[j],Se=he[F],ye=Ta(0,P[A],Q[A]),we=v?P[A]/2-Y-ye-re-R.mainAxis:te-ye-re-R.mainAxis,Ae=v?-P[A]/2+Y+ye+Se+R.mainAxis:ne+ye+Se+R.mainAxis,de=t.elements.arrow&&kl(t.elements.arrow),Te=de?w==="y"?de.clientTop||0:de.clientLeft||0:0,oe=(_=I==null?void 0:I[w])!=null?_:0,ee=B+we-oe-Te,Z=B+Ae-oe,Ne=Ta(C?ou(T,ee):T,B,C?fo(L,Z):L);E[w]=Ne,D[w]=Ne-B}if(l){var Re,Ce=w==="x"?Ht:Vt,Qe=w==="x"?En:Rn,fe=E[k],xe=k==="y"?"height":"width",Ye=fe+S[Ce],Fe=fe-S[Qe],Pe=[Ht,Vt].indexOf(f)!==-1,rt=(Re=I==null?void 0:I[k])!=null?Re:0,ot=Pe?Ye:fe-P[xe]-$[xe]-rt+R.altAxis,Je=Pe?fe+P[xe]+$[xe]-rt-R.altAxis:Fe,N=C&&Pe?TI(ot,fe,Je):Ta(C?ot:Ye,fe,C?Je:Fe);E[k]=N,D[k]=N-fe}t.modifiersData[r]=D}}const a_={name:"preventOverflow",enabled:!0,phase:"main",fn:i_,requiresIfExists:["offset"]};function l_(e){return{scrollLeft:e.scrollLeft,scrollTop:e.scrollTop}}function s_(e){return e===on(e)||!wn(e)?th(e):l_(e)}function u_(e){var t=e.getBoundingClientRect(),n=ki(t.width)/e.offsetWidth||1,r=ki(t.height)/e.offsetHeight||1;return n!==1||r!==1}function c_(e,t,n){n===void 0&&(n=!1);var r=wn(t),o=wn(t)&&u_(t),i=Vr(t),a=Pi(e,o,n),l={scrollLeft:0,scrollTop:0},s={x:0,y:0};return(r||!r&&!n)&&((er(t)!=="body"||rh(i))&&(l=s_(t)),wn(t)?(s=Pi(t,!0),s.x+=t.clientLeft,s.y+=t.clientTop):i&&(s.x=nh(i))),{x:a.left+l.scrollLeft-s.x,y:a.top+l.scrollTop-s.y,width:a.width,height:a.height}}function d_(e){var t=new Map,n=new Set,r=[];e.forEach(function(i){t.set(i.name,i)});function o(i){n.add(i.name);var a=[].concat(i.requires||[],i.requiresIfExists||[]);a.forEach(function(l){if(!n.has(l)){var s=t.get(l);s&&o(s)}}),r.push(i)}return e.forEach(function(i){n.has(i.name)||o(i)}),r}function f_(e){var t=d_(e);return SI.reduce(function(n,r){return n.concat(t.filter(function(o){return o.phase===r}))},[])}function p_(e){var t;return function(){return t||(t=new Promise(function(n){Promise.resolve().then(function(){t=void 0,n(e())})})),t}}function h_(e){var t=e.reduce(function(n,r){var o=n[r.name];return n[r.name]=o?Object.assign({},o,r,{options:Object.assign({},o.options,r.options),data:Object.assign({},o.data,r.data)}):r,n},{});return Object.keys(t).map(function(n){return t[n]})}var tv={placement:"bottom",modifiers:[],strategy:"absolute"};function nv(){for(var e=arguments.length,t=new Array(e),n=0;n<e;n++)t[n]=arguments[n];return!t.some(function(r){return!(r&&typeof r.getBoundingClientRect=="function")})}function m_(e){e===void 0&&(e={});var t=e,n=t.defaultModifiers,r=n===void 0?[]:n,o=t.defaultOptions,i=o===void 0?tv:o;return function(l,s,u){u===void 0&&(u=i);var c={placement:"bottom",orderedModifiers:[],options:Object.assign({},tv,i),modifiersData:{},elements:{reference:l,popper:s},attributes:{},styles:{}},d=[],h=!1,C={state:c,setOptions:function(f){var g=typeof f=="function"?f(c.options):f;x(),c.options=Object.assign({},i,c.options,g),c.scrollParents={reference:xo(l)?Ma(l):l.contextElement?Ma(l.contextElement):[],popper:Ma(s)};var v=f_(h_([].concat(r,c.options.modifiers)));return c.orderedModifiers=v.filter(function(w){return w.enabled}),y(),C.update()},forceUpdate:function(){if(!h){var f=c.elements,g=f.reference,v=f.popper;if(nv(g,v)){c.rects={reference:c_(g,kl(v),c.options.strategy==="fixed"),popper:Zp(v)},c.reset=!1,c.placement=c.options.placement,c.orderedModifiers.forEach(function(R){return c.modifiersData[R.name]=Object.assign({},R.data)});for(var w=0;w<c.orderedModifiers.length;w++){if(c.reset===!0){c.reset=!1,w=-1;continue}var k=c.orderedModifiers[w],E=k.fn,P=k.options,$=P===void 0?{}:P,M=k.name;typeof E=="function"&&(c=E({state:c,options:$,name:M,instance:C})||c)}}}},update:p_(function(){return new Promise(function(S){C.forceUpdate(),S(c)})}),destroy:function(){x(),h=!0}};if(!nv(l,s))return C;C.setOptions(u).then(function(S){!h&&u.onFirstUpdate&&u.onFirstUpdate(S)});function y(){c.orderedModifiers.forEach(function(S){var f=S.name,g=S.options,v=g===void 0?{}:g,w=S.effect;if(typeof w=="function"){var k=w({state:c,name:f,instance:C,options:v}),E=function(){};d.push(k||E)}})}function x(){d.forEach(function(S){return S()}),d=[]}return C}}var g_=[FI,r_,zI,EI,t_,XI,a_,_I,JI],v_=m_({defaultModifiers:g_});function y_(e){return le("MuiPopper",e)}ie("MuiPopper",["root"]);const x_=["anchorEl","children","direction","disablePortal","modifiers","open","placement","popperOptions","popperRef","slotProps","slots","TransitionProps","ownerState"],b_=["anchorEl","children","container","direction","disablePortal","keepMounted","modifiers","open","placement","popperOptions","popperRef","style","transition","slotProps","slots"];function w_(e,t){if(t==="ltr")return e;switch(e){case"bottom-end":return"bottom-start";case"bottom-start":return"bottom-end";case"top-end":return"top-start";case"top-start":return"top-end";default:return e}}function vf(e){return typeof e=="function"?e():e}function C_(e){return e.nodeType!==void 0}const S_=e=>{const{classes:t}=e;return ce({root:["root"]},y_,t)},k_={},P_=m.forwardRef(function(t,n){var r;const{anchorEl:o,children:i,direction:a,disablePortal:l,modifiers:s,open:u,placement:c,popperOptions:d,popperRef:h,slotProps:C={},slots:y={},TransitionProps:x}=t,S=q(t,x_),f=m.useRef(null),g=Ke(f,n),v=m.useRef(null),w=Ke(v,h),k=m.useRef(w);rn(()=>{k.current=w},[w]),m.useImperativeHandle(h,()=>v.current,[]);const E=w_(c,a),[P,$]=m.useState(E),[M,R]=m.useState(vf(o));m.useEffect(()=>{v.current&&v.current.forceUpdate()}),m.useEffect(()=>{o&&R(vf(o))},[o]),rn(()=>{if(!M||!u)return;const F=T=>{$(T.placement)};let A=[{name:"preventOverflow",options:{altBoundary:l}},{name:"flip",options:{altBoundary:l}},{name:"onUpdate",enabled:!0,phase:"afterWrite",fn:({state:T})=>{F(T)}}];s!=null&&(A=A.concat(s)),d&&d.modifiers!=null&&(A=A.concat(d.modifiers));const B=v_(M,f.current,b({placement:E},d,{modifiers:A}));return k.current(B),()=>{B.destroy(),k.current(null)}},[M,l,s,u,d,E]);const I={placement:P};x!==null&&(I.TransitionProps=x);const D=S_(t),_=(r=y.root)!=null?r:"div",j=ft({elementType:_,externalSlotProps:C.root,externalForwardedProps:S,additionalProps:{role:"tooltip",ref:g},ownerState:t,className:D.root});return p.jsx(_,b({},j,{children:typeof i=="function"?i(I):i}))}),E_=m.forwardRef(function(t,n){const{anchorEl:r,children:o,container:i,direction:a="ltr",disablePortal:l=!1,keepMounted:s=!1,modifiers:u,open:c,placement:d="bottom",popperOptions:h=k_,popperRef:C,style:y,transition:x=!1,slotProps:S={},slots:f={}}=t,g=q(t,b_),[v,w]=m.useState(!0),k=()=>{w(!1)},E=()=>{w(!0)};if(!s&&!c&&(!x||v))return null;let P;if(i)P=i;else if(r){const R=vf(r);P=R&&C_(R)?Xe(R).body:Xe(null).body}const $=!c&&s&&(!x||v)?"none":void 0,M=x?{in:c,onEnter:k,onExited:E}:void 0;return p.jsx(Vx,{disablePortal:l,container:P,children:p.jsx(P_,b({anchorEl:r,direction:a,disablePortal:l,modifiers:u,ref:n,open:x?!v:c,placement:d,popperOptions:h,popperRef:C,slotProps:S,slots:f},g,{style:b({position:"fixed",top:0,left:0,display:$},y),TransitionProps:M,children:o}))})}),R_=["anchorEl","component","components","componentsProps","container","disablePortal","keepMounted","modifiers","open","placement","popperOptions","popperRef","transition","slots","slotProps"],$_=W(E_,{name:"MuiPopper",slot:"Root",overridesResolver:(e,t)=>t.root})({}),f1=m.forwardRef(function(t,n){var r;const o=t1(),i=se({props:t,name:"MuiPopper"}),{anchorEl:a,component:l,components:s,componentsProps:u,container:c,disablePortal:d,keepMounted:h,modifiers:C,open:y,placement:x,popperOptions:S,popperRef:f,transition:g,slots:v,slotProps:w}=i,k=q(i,R_),E=(r=v==null?void 0:v.root)!=null?r:s==null?void 0:s.Root,P=b({anchorEl:a,container:c,disablePortal:d,keepMounted:h,modifiers:C,open:y,placement:x,popperOptions:S,popperRef:f,transition:g},k);return p.jsx($_,b({as:l,direction:o==null?void 0:o.direction,slots:{root:E},slotProps:w??u},P,{ref:n}))});function T_(e){return le("MuiTooltip",e)}const Tr=ie("MuiTooltip",["popper","popperInteractive","popperArrow","popperClose","tooltip","tooltipArrow","touch","tooltipPlacementLeft","tooltipPlacementRight","tooltipPlacementTop","tooltipPlacementBottom","arrow"]),M_=["arrow","children","classes","components","componentsProps","describeChild","disableFocusListener","disableHoverListener","disableInteractive","disableTouchListener","enterDelay","enterNextDelay","enterTouchDelay","followCursor","id","leaveDelay","leaveTouchDelay","onClose","onOpen","open","placement","PopperComponent","PopperProps","slotProps","slots","title","TransitionComponent","TransitionProps"];function O_(e){return Math.round(e*1e5)/1e5}const I_=e=>{const{classes:t,disableInteractive:n,arrow:r,touch:o,placement:i}=e,a={popper:["popper",!n&&"popperInteractive",r&&"popperArrow"],tooltip:["tooltip",r&&"tooltipArrow",o&&"touch",`tooltipPlacement${z(i.split("-")[0])}`],arrow:["arrow"]};return ce(a,T_,t)},__=W(f1,{name:"MuiTooltip",slot:"Popper",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.popper,!n.disableInteractive&&t.popperInteractive,n.arrow&&t.popperArrow,!n.open&&t.popperClose]}})(({theme:e,ownerState:t,open:n})=>b({zIndex:(e.vars||e).zIndex.tooltip,pointerEvents:"none"},!t.disableInteractive&&{pointerEvents:"auto"},!n&&{pointerEvents:"none"},t.arrow&&{[`&[data-popper-placement*="bottom"] .${Tr.arrow}`]:{top:0,marginTop:"-0.71em","&::before":{transformOrigin:"0 100%"}},[`&[data-popper-placement*="top"] .${Tr.arrow}`]:{bottom:0,marginBottom:"-0.71em","&::before":{transformOrigin:"100% 0"}},[`&[data-popper-placement*="right"] .${Tr.arrow}`]:b({},t.isRtl?{right:0,marginRight:"-0.71em"}:{left:0,marginLeft:"-0.71em"},{height:"1em",width:"0.71em","&::before":{transformOrigin:"100% 100%"}}),[`&[data-popper-placement*="left"] .${Tr.arrow}`]:b({},t.isRtl?{left:0,marginLeft:"-0.71em"}:{right:0,marginRight:"-0.71em"},{height:"1em",width:"0.71em","&::before":{transformOrigin:"0 0"}})})),N_=W("div",{name:"MuiTooltip",slot:"Tooltip",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.tooltip,n.touch&&t.touch,n.arrow&&t.tooltipArrow,t[`tooltipPlacement${z(n.placement.split("-")[0])}`]]}})(({theme:e,ownerState:t})=>b({backgroundColor:e.vars?e.vars.palette.Tooltip.bg:ke(e.palette.grey[700],.92),borderRadius:(e.vars||e).shape.borderRadius,color:(e.vars||e).palette.common.white,fontFamily:e.typography.fontFamily,padding:"4px 8px",fontSize:e.typography.pxToRem(11),maxWidth:300,margin:2,wordWrap:"break-word",fontWeight:e.typography.fontWeightMedium},t.arrow&&{position:"relative",margin:0},t.touch&&{padding:"8px 16px",fontSize:e.typography.pxToRem(14),lineHeight:`${O_(16/14)}em`,fontWeight:e.typography.fontWeightRegular},{[`.${Tr.popper}[data-popper-placement*="left"] &`]:b({transformOrigin:"right center"},t.isRtl?b({marginLeft:"14px"},t.touch&&{marginLeft:"24px"}):b({marginRight:"14px"},t.touch&&{marginRight:"24px"})),[`.${Tr.popper}[data-popper-placement*="right"] &`]:b({transformOrigin:"left center"},t.isRtl?b({marginRight:"14px"},t.touch&&{marginRight:"24px"}):b({marginLeft:"14px"},t.touch&&{marginLeft:"24px"})),[`.${Tr.popper}[data-popper-placement*="top"] &`]:b({transformOrigin:"center bottom",marginBottom:"14px"},t.touch&&{marginBottom:"24px"}),[`.${Tr.popper}[data-popper-placement*="bottom"] &`]:b({transformOrigin:"center top",marginTop:"14px"},t.touch&&{marginTop:"24px"})})),L_=W("span",{name:"MuiTooltip",slot:"Arrow",overridesResolver:(e,t)=>t.arrow})(({theme:e})=>({overflow:"hidden",position:"absolute",width:"1em",height:"0.71em",boxSizing:"border-box",color:e.vars?e.vars.palette.Tooltip.bg:ke(e.palette.grey[700],.9),"&::before":{content:'""',margin:"auto",display:"block",width:"100%",height:"100%",backgroundColor:"currentColor",transform:"rotate(45deg)"}}));let Zl=!1;const rv=new xl;let sa={x:0,y:0};function es(e,t){return(n,...r)=>{t&&t(n,...r),e(n,...r)}}const rd=m.forwardRef(function(t,n){var r,o,i,a,l,s,u,c,d,h,C,y,x,S,f,g,v,w,k;const E=se({props:t,name:"MuiTooltip"}),{arrow:P=!1,children:$,components:M={},componentsProps:R={},describeChild:I=!1,disableFocusListener:D=!1,disableHoverListener:_=!1,disableInteractive:j=!1,disableTouchListener:F=!1,enterDelay:A=100,enterNextDelay:B=0,enterTouchDelay:T=700,followCursor:L=!1,id:Y,leaveDelay:te=0,leaveTouchDelay:ne=1500,onClose:me,onOpen:Q,open:he,placement:re="bottom",PopperComponent:Se,PopperProps:ye={},slotProps:we={},slots:Ae={},title:de,TransitionComponent:Te=el,TransitionProps:oe}=E,ee=q(E,M_),Z=m.isValidElement($)?$:p.jsx("span",{children:$}),Ne=ji(),Re=Ni(),[Ce,Qe]=m.useState(),[fe,xe]=m.useState(null),Ye=m.useRef(!1),Fe=j||L,Pe=io(),rt=io(),ot=io(),Je=io(),[N,O]=xi({controlled:he,default:!1,name:"Tooltip",state:"open"});let X=N;const pe=nc(Y),ge=m.useRef(),V=wt(()=>{ge.current!==void 0&&(document.body.style.WebkitUserSelect=ge.current,ge.current=void 0),Je.clear()});m.useEffect(()=>V,[V]);const ve=$e=>{rv.clear(),Zl=!0,O(!0),Q&&!X&&Q($e)},U=wt($e=>{rv.start(800+te,()=>{Zl=!1}),O(!1),me&&X&&me($e),Pe.start(Ne.transitions.duration.shortest,()=>{Ye.current=!1})}),K=$e=>{Ye.current&&$e.type!=="touchstart"||(Ce&&Ce.removeAttribute("title"),rt.clear(),ot.clear(),A||Zl&&B?rt.start(Zl?B:A,()=>{ve($e)}):ve($e))},ue=$e=>{rt.clear(),ot.start(te,()=>{U($e)})},{isFocusVisibleRef:be,onBlur:Ue,onFocus:dt,ref:_t}=bl(),[,un]=m.useState(!1),Nt=$e=>{Ue($e),be.current===!1&&(un(!1),ue($e))},nr=$e=>{Ce||Qe($e.currentTarget),dt($e),be.current===!0&&(un(!0),K($e))},Eo=$e=>{Ye.current=!0;const qt=Z.props;qt.onTouchStart&&qt.onTouchStart($e)},hc=$e=>{Eo($e),ot.clear(),Pe.clear(),V(),ge.current=document.body.style.WebkitUserSelect,document.body.style.WebkitUserSelect="none",Je.start(T,()=>{document.body.style.WebkitUserSelect=ge.current,K($e)})},Bi=$e=>{Z.props.onTouchEnd&&Z.props.onTouchEnd($e),V(),ot.start(ne,()=>{U($e)})};m.useEffect(()=>{if(!X)return;function $e(qt){(qt.key==="Escape"||qt.key==="Esc")&&U(qt)}return document.addEventListener("keydown",$e),()=>{document.removeEventListener("keydown",$e)}},[U,X]);const Di=Ke(ko(Z),_t,Qe,n);!de&&de!==0&&(X=!1);const Kr=m.useRef(),mc=$e=>{const qt=Z.props;qt.onMouseMove&&qt.onMouseMove($e),sa={x:$e.clientX,y:$e.clientY},Kr.current&&Kr.current.update()},Yr={},Wi=typeof de=="string";I?(Yr.title=!X&&Wi&&!_?de:null,Yr["aria-describedby"]=X?pe:null):(Yr["aria-label"]=Wi?de:null,Yr["aria-labelledby"]=X&&!Wi?pe:null);const Gt=b({},Yr,ee,Z.props,{className:G(ee.className,Z.props.className),onTouchStart:Eo,ref:Di},L?{onMouseMove:mc}:{}),Gr={};F||(Gt.onTouchStart=hc,Gt.onTouchEnd=Bi),_||(Gt.onMouseOver=es(K,Gt.onMouseOver),Gt.onMouseLeave=es(ue,Gt.onMouseLeave),Fe||(Gr.onMouseOver=K,Gr.onMouseLeave=ue)),D||(Gt.onFocus=es(nr,Gt.onFocus),Gt.onBlur=es(Nt,Gt.onBlur),Fe||(Gr.onFocus=nr,Gr.onBlur=Nt));const gc=m.useMemo(()=>{var $e;let qt=[{name:"arrow",enabled:!!fe,options:{element:fe,padding:4}}];return($e=ye.popperOptions)!=null&&$e.modifiers&&(qt=qt.concat(ye.popperOptions.modifiers)),b({},ye.popperOptions,{modifiers:qt})},[fe,ye]),rr=b({},E,{isRtl:Re,arrow:P,disableInteractive:Fe,placement:re,PopperComponentProp:Se,touch:Ye.current}),Ui=I_(rr),Ro=(r=(o=Ae.popper)!=null?o:M.Popper)!=null?r:__,$o=(i=(a=(l=Ae.transition)!=null?l:M.Transition)!=null?a:Te)!=null?i:el,Pl=(s=(u=Ae.tooltip)!=null?u:M.Tooltip)!=null?s:N_,Ze=(c=(d=Ae.arrow)!=null?d:M.Arrow)!=null?c:L_,gt=Zo(Ro,b({},ye,(h=we.popper)!=null?h:R.popper,{className:G(Ui.popper,ye==null?void 0:ye.className,(C=(y=we.popper)!=null?y:R.popper)==null?void 0:C.className)}),rr),Hi=Zo($o,b({},oe,(x=we.transition)!=null?x:R.transition),rr),To=Zo(Pl,b({},(S=we.tooltip)!=null?S:R.tooltip,{className:G(Ui.tooltip,(f=(g=we.tooltip)!=null?g:R.tooltip)==null?void 0:f.className)}),rr),On=Zo(Ze,b({},(v=we.arrow)!=null?v:R.arrow,{className:G(Ui.arrow,(w=(k=we.arrow)!=null?k:R.arrow)==null?void 0:w.className)}),rr);return p.jsxs(m.Fragment,{children:[m.cloneElement(Z,Gt),p.jsx(Ro,b({as:Se??f1,placement:re,anchorEl:L?{getBoundingClientRect:()=>({top:sa.y,left:sa.x,right:sa.x,bottom:sa.y,width:0,height:0})}:Ce,popperRef:Kr,open:Ce?X:!1,id:pe,transition:!0},Gr,gt,{popperOptions:gc,children:({TransitionProps:$e})=>p.jsx($o,b({timeout:Ne.transitions.duration.shorter},$e,Hi,{children:p.jsxs(Pl,b({},To,{children:[de,P?p.jsx(Ze,b({},On,{ref:xe})):null]}))}))}))]})});var oh={},j_=Po;Object.defineProperty(oh,"__esModule",{value:!0});var yf=oh.default=void 0,z_=j_(Cl()),A_=p;yf=oh.default=(0,z_.default)((0,A_.jsx)("path",{d:"M17.65 6.35C16.2 4.9 14.21 4 12 4c-4.42 0-7.99 3.58-7.99 8s3.57 8 7.99 8c3.73 0 6.84-2.55 7.73-6h-2.08c-.82 2.33-3.04 4-5.65 4-3.31 0-6-2.69-6-6s2.69-6 6-6c1.66 0 3.14.69 4.22 1.78L13 11h7V4z"}),"Refresh");var ih={},F_=Po;Object.defineProperty(ih,"__esModule",{value:!0});var p1=ih.default=void 0,B_=F_(Cl()),D_=p;p1=ih.default=(0,B_.default)((0,D_.jsx)("path",{d:"M15.5 14h-.79l-.28-.27C15.41 12.59 16 11.11 16 9.5 16 5.91 13.09 3 9.5 3S3 5.91 3 9.5 5.91 16 9.5 16c1.61 0 3.09-.59 4.23-1.57l.27.28v.79l5 4.99L20.49 19zm-6 0C7.01 14 5 11.99 5 9.5S7.01 5 9.5 5 14 7.01 14 9.5 11.99 14 9.5 14"}),"Search");var ah={},W_=Po;Object.defineProperty(ah,"__esModule",{value:!0});var h1=ah.default=void 0,U_=W_(Cl()),H_=p;h1=ah.default=(0,U_.default)((0,H_.jsx)("path",{d:"M19 6.41 17.59 5 12 10.59 6.41 5 5 6.41 10.59 12 5 17.59 6.41 19 12 13.41 17.59 19 19 17.59 13.41 12z"}),"Clear");const m1=window.PULSE_BASE_PATH||"",g1=async e=>{if(!e.ok){const t=e.headers.get("content-type");if(console.error(`API Response Error: ${e.status} ${e.statusText}`),console.error(`URL: ${e.url}`),console.error(`Content-Type: ${t}`),t&&t.includes("application/json"))try{const n=await e.json();throw console.error("Error response JSON:",n),new Error(n.message||`${e.status} ${e.statusText}`)}catch(n){console.error("Failed to parse error JSON:",n);try{const r=await e.text();throw console.error("Error response body:",r.substring(0,1e3)+(r.length>1e3?"...":"")),new Error(`API Error: ${e.status} ${e.statusText} - ${r.substring(0,100)}`)}catch{throw new Error(`API Error: ${e.status} ${e.statusText} (unable to read response body)`)}}else try{const n=await e.text();throw console.error("Non-JSON error response:",n.substring(0,1e3)+(n.length>1e3?"...":"")),new Error(`API returned non-JSON response (${e.status}): ${n.substring(0,100)}`)}catch{throw new Error(`API returned non-JSON response (${e.status}) - failed to read response body`)}}try{const t=await e.json();return console.log("Parsed API response:",t),t}catch(t){console.error("Error parsing JSON response:",t);const n=await e.text();throw console.error("Response body (failed JSON parsing):",n.substring(0,1e3)+(n.length>1e3?"...":"")),new Error("Invalid JSON response from server")}},V_=async(e={})=>{try{const t=new URLSearchParams;Object.entries(e).forEach(([a,l])=>{if(l!=null&&l!==""){const s=a.replace(/([A-Z])/g,"_$1").toLowerCase();console.log(`Converting parameter: ${a} → ${s}`);const u=l.toString();if((a==="offset"||a==="limit")&&u){const c=parseInt(u,10);!isNaN(c)&&c>=0&&(t.append(s,c.toString()),console.log(`Setting ${s}=${c.toString()}`))}else t.append(s,u)}});const n=`${m1}/api/logs?${t.toString()}`;console.log(`Fetching logs with URL: ${n}`);let r=0;const o=3;let i=null;for(;r<o;)try{r++,console.log(`Attempt ${r}/${o} to fetch logs`);const a=await fetch(n,{headers:{Accept:"application/json"},cache:"no-cache"}),l=await g1(a);return Array.isArray(l)?{logs:l,pagination:{total_items:l.length,total_pages:Math.ceil(l.length/(e.limit||50)),page_size:e.limit||50,offset:e.offset||0}}:l&&Array.isArray(l.logs)?(l.pagination||(l.pagination={total_items:l.logs.length,total_pages:Math.ceil(l.logs.length/(e.limit||50)),page_size:e.limit||50,offset:e.offset||0}),l):l&&!l.logs?(console.warn("API returned unexpected format:",l),{logs:Array.isArray(l)?l:[],pagination:{total_items:Array.isArray(l)?l.length:0,total_pages:1,page_size:e.limit||50,offset:e.offset||0}}):l}catch(a){if(i=a,r===o)throw i;console.warn(`Attempt ${r}/${o} failed, retrying in ${r*500}ms...`,a),await new Promise(l=>setTimeout(l,r*500))}}catch(t){throw console.error("Error in fetchLogs:",t),t}},K_=async()=>{try{const e=await fetch(`${m1}/api/services`);return g1(e)}catch(e){throw console.error("Error in fetchServices:",e),e}},Y_=(e={})=>{const[t,n]=m.useState([]),[r,o]=m.useState(!0),[i,a]=m.useState(null),[l,s]=m.useState(!1),[u,c]=m.useState(1),[d,h]=m.useState(50),[C,y]=m.useState(0),[x,S]=m.useState(1),f=m.useCallback(async()=>{if(!l)try{o(!0),a(null);let P;if(e.timeRange){const I=new Date;switch(e.timeRange){case"5m":P=new Date(I.getTime()-5*60*1e3);break;case"15m":P=new Date(I.getTime()-15*60*1e3);break;case"1h":P=new Date(I.getTime()-60*60*1e3);break;case"3h":P=new Date(I.getTime()-3*60*60*1e3);break;case"6h":P=new Date(I.getTime()-6*60*60*1e3);break;case"12h":P=new Date(I.getTime()-12*60*60*1e3);break;case"1d":P=new Date(I.getTime()-24*60*60*1e3);break;case"7d":P=new Date(I.getTime()-7*24*60*60*1e3);break;default:P=new Date(I.getTime()-60*60*1e3)}}console.log(`Fetching logs for page ${u}, pageSize ${d}`);const $=Math.max(0,(u-1)*d),M={limit:d,offset:String($),orderBy:"timestamp",orderDesc:!0,service:e.service||void 0,level:e.logLevel||void 0,search:e.logSearch||void 0,since:P?P.toISOString():void 0};console.log("API request options:",M);const R=await V_(M);console.log("API response data:",R),R&&R.logs?(n(R.logs),R.pagination?(y(R.pagination.total_items||0),S(Math.max(1,R.pagination.total_pages||1)),u>R.pagination.total_pages&&R.pagination.total_pages>0&&c(1)):(y(R.logs.length),S(Math.max(1,Math.ceil(R.logs.length/d))))):R&&Array.isArray(R)?(n(R),y(R.length),S(Math.max(1,Math.ceil(R.length/d)))):(console.warn("Unexpected API response format:",R),n([]),y(0),S(1))}catch(P){console.error("Error fetching logs:",P),a(`Failed to fetch logs: ${P.message}`),n([]),y(0),S(1)}finally{o(!1)}},[e,u,d,l]);m.useEffect(()=>{c(1)},[e]),m.useEffect(()=>{console.log(`Page changed to ${u}, fetching logs...`),f();let P;return l||(P=setInterval(()=>{console.log("Auto-refreshing logs..."),f()},3e4)),()=>{P&&clearInterval(P)}},[f,u,d,e,l]);const g=m.useCallback(()=>{console.log("Clearing logs"),n([]),y(0),S(1)},[]),v=m.useCallback(()=>{console.log("Toggling pause state"),s(P=>!P)},[]),w=m.useCallback(P=>{console.log(`Changing to page ${P}`),P>=1&&P<=x?(console.log(`Setting current page to ${P}`),c(P)):console.warn(`Invalid page number: ${P}. Must be between 1 and ${x}`)},[x]),k=m.useCallback(P=>{console.log(`Changing page size to ${P}`),h(P),c(1)},[]),E=m.useCallback(()=>{console.log("Manual refresh triggered"),f()},[f]);return{logs:t,loading:r,error:i,paused:l,pagination:{currentPage:u,pageSize:d,totalPages:x,totalItems:C},clearLogs:g,togglePause:v,changePage:w,changePageSize:k,refresh:E}};function Wn(e){const t=Object.prototype.toString.call(e);return e instanceof Date||typeof e=="object"&&t==="[object Date]"?new e.constructor(+e):typeof e=="number"||t==="[object Number]"||typeof e=="string"||t==="[object String]"?new Date(e):new Date(NaN)}function bo(e,t){return e instanceof Date?new e.constructor(t):new Date(t)}const v1=6048e5,G_=864e5;let q_={};function pc(){return q_}function ol(e,t){var l,s,u,c;const n=pc(),r=(t==null?void 0:t.weekStartsOn)??((s=(l=t==null?void 0:t.locale)==null?void 0:l.options)==null?void 0:s.weekStartsOn)??n.weekStartsOn??((c=(u=n.locale)==null?void 0:u.options)==null?void 0:c.weekStartsOn)??0,o=Wn(e),i=o.getDay(),a=(i<r?7:0)+i-r;return o.setDate(o.getDate()-a),o.setHours(0,0,0,0),o}function iu(e){return ol(e,{weekStartsOn:1})}function y1(e){const t=Wn(e),n=t.getFullYear(),r=bo(e,0);r.setFullYear(n+1,0,4),r.setHours(0,0,0,0);const o=iu(r),i=bo(e,0);i.setFullYear(n,0,4),i.setHours(0,0,0,0);const a=iu(i);return t.getTime()>=o.getTime()?n+1:t.getTime()>=a.getTime()?n:n-1}function ov(e){const t=Wn(e);return t.setHours(0,0,0,0),t}function iv(e){const t=Wn(e),n=new Date(Date.UTC(t.getFullYear(),t.getMonth(),t.getDate(),t.getHours(),t.getMinutes(),t.getSeconds(),t.getMilliseconds()));return n.setUTCFullYear(t.getFullYear()),+e-+n}function X_(e,t){const n=ov(e),r=ov(t),o=+n-iv(n),i=+r-iv(r);return Math.round((o-i)/G_)}function Q_(e){const t=y1(e),n=bo(e,0);return n.setFullYear(t,0,4),n.setHours(0,0,0,0),iu(n)}function J_(e){return e instanceof Date||typeof e=="object"&&Object.prototype.toString.call(e)==="[object Date]"}function Z_(e){if(!J_(e)&&typeof e!="number")return!1;const t=Wn(e);return!isNaN(Number(t))}function eN(e){const t=Wn(e),n=bo(e,0);return n.setFullYear(t.getFullYear(),0,1),n.setHours(0,0,0,0),n}const tN={lessThanXSeconds:{one:"less than a second",other:"less than {{count}} seconds"},xSeconds:{one:"1 second",other:"{{count}} seconds"},halfAMinute:"half a minute",lessThanXMinutes:{one:"less than a minute",other:"less than {{count}} minutes"},xMinutes:{one:"1 minute",other:"{{count}} minutes"},aboutXHours:{one:"about 1 hour",other:"about {{count}} hours"},xHours:{one:"1 hour",other:"{{count}} hours"},xDays:{one:"1 day",other:"{{count}} days"},aboutXWeeks:{one:"about 1 week",other:"about {{count}} weeks"},xWeeks:{one:"1 week",other:"{{count}} weeks"},aboutXMonths:{one:"about 1 month",other:"about {{count}} months"},xMonths:{one:"1 month",other:"{{count}} months"},aboutXYears:{one:"about 1 year",other:"about {{count}} years"},xYears:{one:"1 year",other:"{{count}} years"},overXYears:{one:"over 1 year",other:"over {{count}} years"},almostXYears:{one:"almost 1 year",other:"almost {{count}} years"}},nN=(e,t,n)=>{let r;const o=tN[e];return typeof o=="string"?r=o:t===1?r=o.one:r=o.other.replace("{{count}}",t.toString()),n!=null&&n.addSuffix?n.comparison&&n.comparison>0?"in "+r:r+" ago":r};function od(e){return(t={})=>{const n=t.width?String(t.width):e.defaultWidth;return e.formats[n]||e.formats[e.defaultWidth]}}const rN={full:"EEEE, MMMM do, y",long:"MMMM do, y",medium:"MMM d, y",short:"MM/dd/yyyy"},oN={full:"h:mm:ss a zzzz",long:"h:mm:ss a z",medium:"h:mm:ss a",short:"h:mm a"},iN={full:"{{date}} 'at' {{time}}",long:"{{date}} 'at' {{time}}",medium:"{{date}}, {{time}}",short:"{{date}}, {{time}}"},aN={date:od({formats:rN,defaultWidth:"full"}),time:od({formats:oN,defaultWidth:"full"}),dateTime:od({formats:iN,defaultWidth:"full"})},lN={lastWeek:"'last' eeee 'at' p",yesterday:"'yesterday at' p",today:"'today at' p",tomorrow:"'tomorrow at' p",nextWeek:"eeee 'at' p",other:"P"},sN=(e,t,n,r)=>lN[e];function ua(e){return(t,n)=>{const r=n!=null&&n.context?String(n.context):"standalone";let o;if(r==="formatting"&&e.formattingValues){const a=e.defaultFormattingWidth||e.defaultWidth,l=n!=null&&n.width?String(n.width):a;o=e.formattingValues[l]||e.formattingValues[a]}else{const a=e.defaultWidth,l=n!=null&&n.width?String(n.width):e.defaultWidth;o=e.values[l]||e.values[a]}const i=e.argumentCallback?e.argumentCallback(t):t;return o[i]}}const uN={narrow:["B","A"],abbreviated:["BC","AD"],wide:["Before Christ","Anno Domini"]},cN={narrow:["1","2","3","4"],abbreviated:["Q1","Q2","Q3","Q4"],wide:["1st quarter","2nd quarter","3rd quarter","4th quarter"]},dN={narrow:["J","F","M","A","M","J","J","A","S","O","N","D"],abbreviated:["Jan","Feb","Mar","Apr","May","Jun","Jul","Aug","Sep","Oct","Nov","Dec"],wide:["January","February","March","April","May","June","July","August","September","October","November","December"]},fN={narrow:["S","M","T","W","T","F","S"],short:["Su","Mo","Tu","We","Th","Fr","Sa"],abbreviated:["Sun","Mon","Tue","Wed","Thu","Fri","Sat"],wide:["Sunday","Monday","Tuesday","Wednesday","Thursday","Friday","Saturday"]},pN={narrow:{am:"a",pm:"p",midnight:"mi",noon:"n",morning:"morning",afternoon:"afternoon",evening:"evening",night:"night"},abbreviated:{am:"AM",pm:"PM",midnight:"midnight",noon:"noon",morning:"morning",afternoon:"afternoon",evening:"evening",night:"night"},wide:{am:"a.m.",pm:"p.m.",midnight:"midnight",noon:"noon",morning:"morning",afternoon:"afternoon",evening:"evening",night:"night"}},hN={narrow:{am:"a",pm:"p",midnight:"mi",noon:"n",morning:"in the morning",afternoon:"in the afternoon",evening:"in the evening",night:"at night"},abbreviated:{am:"AM",pm:"PM",midnight:"midnight",noon:"noon",morning:"in the morning",afternoon:"in the afternoon",evening:"in the evening",night:"at night"},wide:{am:"a.m.",pm:"p.m.",midnight:"midnight",noon:"noon",morning:"in the morning",afternoon:"in the afternoon",evening:"in the evening",night:"at night"}},mN=(e,t)=>{const n=Number(e),r=n%100;if(r>20||r<10)switch(r%10){case 1:return n+"st";case 2:return n+"nd";case 3:return n+"rd"}return n+"th"},gN={ordinalNumber:mN,era:ua({values:uN,defaultWidth:"wide"}),quarter:ua({values:cN,defaultWidth:"wide",argumentCallback:e=>e-1}),month:ua({values:dN,defaultWidth:"wide"}),day:ua({values:fN,defaultWidth:"wide"}),dayPeriod:ua({values:pN,defaultWidth:"wide",formattingValues:hN,defaultFormattingWidth:"wide"})};function ca(e){return(t,n={})=>{const r=n.width,o=r&&e.matchPatterns[r]||e.matchPatterns[e.defaultMatchWidth],i=t.match(o);if(!i)return null;const a=i[0],l=r&&e.parsePatterns[r]||e.parsePatterns[e.defaultParseWidth],s=Array.isArray(l)?yN(l,d=>d.test(a)):vN(l,d=>d.test(a));let u;u=e.valueCallback?e.valueCallback(s):s,u=n.valueCallback?n.valueCallback(u):u;const c=t.slice(a.length);return{value:u,rest:c}}}function vN(e,t){for(const n in e)if(Object.prototype.hasOwnProperty.call(e,n)&&t(e[n]))return n}function yN(e,t){for(let n=0;n<e.length;n++)if(t(e[n]))return n}function xN(e){return(t,n={})=>{const r=t.match(e.matchPattern);if(!r)return null;const o=r[0],i=t.match(e.parsePattern);if(!i)return null;let a=e.valueCallback?e.valueCallback(i[0]):i[0];a=n.valueCallback?n.valueCallback(a):a;const l=t.slice(o.length);return{value:a,rest:l}}}const bN=/^(\d+)(th|st|nd|rd)?/i,wN=/\d+/i,CN={narrow:/^(b|a)/i,abbreviated:/^(b\.?\s?c\.?|b\.?\s?c\.?\s?e\.?|a\.?\s?d\.?|c\.?\s?e\.?)/i,wide:/^(before christ|before common era|anno domini|common era)/i},SN={any:[/^b/i,/^(a|c)/i]},kN={narrow:/^[1234]/i,abbreviated:/^q[1234]/i,wide:/^[1234](th|st|nd|rd)? quarter/i},PN={any:[/1/i,/2/i,/3/i,/4/i]},EN={narrow:/^[jfmasond]/i,abbreviated:/^(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)/i,wide:/^(january|february|march|april|may|june|july|august|september|october|november|december)/i},RN={narrow:[/^j/i,/^f/i,/^m/i,/^a/i,/^m/i,/^j/i,/^j/i,/^a/i,/^s/i,/^o/i,/^n/i,/^d/i],any:[/^ja/i,/^f/i,/^mar/i,/^ap/i,/^may/i,/^jun/i,/^jul/i,/^au/i,/^s/i,/^o/i,/^n/i,/^d/i]},$N={narrow:/^[smtwf]/i,short:/^(su|mo|tu|we|th|fr|sa)/i,abbreviated:/^(sun|mon|tue|wed|thu|fri|sat)/i,wide:/^(sunday|monday|tuesday|wednesday|thursday|friday|saturday)/i},TN={narrow:[/^s/i,/^m/i,/^t/i,/^w/i,/^t/i,/^f/i,/^s/i],any:[/^su/i,/^m/i,/^tu/i,/^w/i,/^th/i,/^f/i,/^sa/i]},MN={narrow:/^(a|p|mi|n|(in the|at) (morning|afternoon|evening|night))/i,any:/^([ap]\.?\s?m\.?|midnight|noon|(in the|at) (morning|afternoon|evening|night))/i},ON={any:{am:/^a/i,pm:/^p/i,midnight:/^mi/i,noon:/^no/i,morning:/morning/i,afternoon:/afternoon/i,evening:/evening/i,night:/night/i}},IN={ordinalNumber:xN({matchPattern:bN,parsePattern:wN,valueCallback:e=>parseInt(e,10)}),era:ca({matchPatterns:CN,defaultMatchWidth:"wide",parsePatterns:SN,defaultParseWidth:"any"}),quarter:ca({matchPatterns:kN,defaultMatchWidth:"wide",parsePatterns:PN,defaultParseWidth:"any",valueCallback:e=>e+1}),month:ca({matchPatterns:EN,defaultMatchWidth:"wide",parsePatterns:RN,defaultParseWidth:"any"}),day:ca({matchPatterns:$N,defaultMatchWidth:"wide",parsePatterns:TN,defaultParseWidth:"any"}),dayPeriod:ca({matchPatterns:MN,defaultMatchWidth:"any",parsePatterns:ON,defaultParseWidth:"any"})},_N={code:"en-US",formatDistance:nN,formatLong:aN,formatRelative:sN,localize:gN,match:IN,options:{weekStartsOn:0,firstWeekContainsDate:1}};function NN(e){const t=Wn(e);return X_(t,eN(t))+1}function LN(e){const t=Wn(e),n=+iu(t)-+Q_(t);return Math.round(n/v1)+1}function x1(e,t){var c,d,h,C;const n=Wn(e),r=n.getFullYear(),o=pc(),i=(t==null?void 0:t.firstWeekContainsDate)??((d=(c=t==null?void 0:t.locale)==null?void 0:c.options)==null?void 0:d.firstWeekContainsDate)??o.firstWeekContainsDate??((C=(h=o.locale)==null?void 0:h.options)==null?void 0:C.firstWeekContainsDate)??1,a=bo(e,0);a.setFullYear(r+1,0,i),a.setHours(0,0,0,0);const l=ol(a,t),s=bo(e,0);s.setFullYear(r,0,i),s.setHours(0,0,0,0);const u=ol(s,t);return n.getTime()>=l.getTime()?r+1:n.getTime()>=u.getTime()?r:r-1}function jN(e,t){var l,s,u,c;const n=pc(),r=(t==null?void 0:t.firstWeekContainsDate)??((s=(l=t==null?void 0:t.locale)==null?void 0:l.options)==null?void 0:s.firstWeekContainsDate)??n.firstWeekContainsDate??((c=(u=n.locale)==null?void 0:u.options)==null?void 0:c.firstWeekContainsDate)??1,o=x1(e,t),i=bo(e,0);return i.setFullYear(o,0,r),i.setHours(0,0,0,0),ol(i,t)}function zN(e,t){const n=Wn(e),r=+ol(n,t)-+jN(n,t);return Math.round(r/v1)+1}function Le(e,t){const n=e<0?"-":"",r=Math.abs(e).toString().padStart(t,"0");return n+r}const br={y(e,t){const n=e.getFullYear(),r=n>0?n:1-n;return Le(t==="yy"?r%100:r,t.length)},M(e,t){const n=e.getMonth();return t==="M"?String(n+1):Le(n+1,2)},d(e,t){return Le(e.getDate(),t.length)},a(e,t){const n=e.getHours()/12>=1?"pm":"am";switch(t){case"a":case"aa":return n.toUpperCase();case"aaa":return n;case"aaaaa":return n[0];case"aaaa":default:return n==="am"?"a.m.":"p.m."}},h(e,t){return Le(e.getHours()%12||12,t.length)},H(e,t){return Le(e.getHours(),t.length)},m(e,t){return Le(e.getMinutes(),t.length)},s(e,t){return Le(e.getSeconds(),t.length)},S(e,t){const n=t.length,r=e.getMilliseconds(),o=Math.trunc(r*Math.pow(10,n-3));return Le(o,t.length)}},Ao={midnight:"midnight",noon:"noon",morning:"morning",afternoon:"afternoon",evening:"evening",night:"night"},av={G:function(e,t,n){const r=e.getFullYear()>0?1:0;switch(t){case"G":case"GG":case"GGG":return n.era(r,{width:"abbreviated"});case"GGGGG":return n.era(r,{width:"narrow"});case"GGGG":default:return n.era(r,{width:"wide"})}},y:function(e,t,n){if(t==="yo"){const r=e.getFullYear(),o=r>0?r:1-r;return n.ordinalNumber(o,{unit:"year"})}return br.y(e,t)},Y:function(e,t,n,r){const o=x1(e,r),i=o>0?o:1-o;if(t==="YY"){const a=i%100;return Le(a,2)}return t==="Yo"?n.ordinalNumber(i,{unit:"year"}):Le(i,t.length)},R:function(e,t){const n=y1(e);return Le(n,t.length)},u:function(e,t){const n=e.getFullYear();return Le(n,t.length)},Q:function(e,t,n){const r=Math.ceil((e.getMonth()+1)/3);switch(t){case"Q":return String(r);case"QQ":return Le(r,2);case"Qo":return n.ordinalNumber(r,{unit:"quarter"});case"QQQ":return n.quarter(r,{width:"abbreviated",context:"formatting"});case"QQQQQ":return n.quarter(r,{width:"narrow",context:"formatting"});case"QQQQ":default:return n.quarter(r,{width:"wide",context:"formatting"})}},q:function(e,t,n){const r=Math.ceil((e.getMonth()+1)/3);switch(t){case"q":return String(r);case"qq":return Le(r,2);case"qo":return n.ordinalNumber(r,{unit:"quarter"});case"qqq":return n.quarter(r,{width:"abbreviated",context:"standalone"});case"qqqqq":return n.quarter(r,{width:"narrow",context:"standalone"});case"qqqq":default:return n.quarter(r,{width:"wide",context:"standalone"})}},M:function(e,t,n){const r=e.getMonth();switch(t){case"M":case"MM":return br.M(e,t);case"Mo":return n.ordinalNumber(r+1,{unit:"month"});case"MMM":return n.month(r,{width:"abbreviated",context:"formatting"});case"MMMMM":return n.month(r,{width:"narrow",context:"formatting"});case"MMMM":default:return n.month(r,{width:"wide",context:"formatting"})}},L:function(e,t,n){const r=e.getMonth();switch(t){case"L":return String(r+1);case"LL":return Le(r+1,2);case"Lo":return n.ordinalNumber(r+1,{unit:"month"});case"LLL":return n.month(r,{width:"abbreviated",context:"standalone"});case"LLLLL":return n.month(r,{width:"narrow",context:"standalone"});case"LLLL":default:return n.month(r,{width:"wide",context:"standalone"})}},w:function(e,t,n,r){const o=zN(e,r);return t==="wo"?n.ordinalNumber(o,{unit:"week"}):Le(o,t.length)},I:function(e,t,n){const r=LN(e);return t==="Io"?n.ordinalNumber(r,{unit:"week"}):Le(r,t.length)},d:function(e,t,n){return t==="do"?n.ordinalNumber(e.getDate(),{unit:"date"}):br.d(e,t)},D:function(e,t,n){const r=NN(e);return t==="Do"?n.ordinalNumber(r,{unit:"dayOfYear"}):Le(r,t.length)},E:function(e,t,n){const r=e.getDay();switch(t){case"E":case"EE":case"EEE":return n.day(r,{width:"abbreviated",context:"formatting"});case"EEEEE":return n.day(r,{width:"narrow",context:"formatting"});case"EEEEEE":return n.day(r,{width:"short",context:"formatting"});case"EEEE":default:return n.day(r,{width:"wide",context:"formatting"})}},e:function(e,t,n,r){const o=e.getDay(),i=(o-r.weekStartsOn+8)%7||7;switch(t){case"e":return String(i);case"ee":return Le(i,2);case"eo":return n.ordinalNumber(i,{unit:"day"});case"eee":return n.day(o,{width:"abbreviated",context:"formatting"});case"eeeee":return n.day(o,{width:"narrow",context:"formatting"});case"eeeeee":return n.day(o,{width:"short",context:"formatting"});case"eeee":default:return n.day(o,{width:"wide",context:"formatting"})}},c:function(e,t,n,r){const o=e.getDay(),i=(o-r.weekStartsOn+8)%7||7;switch(t){case"c":return String(i);case"cc":return Le(i,t.length);case"co":return n.ordinalNumber(i,{unit:"day"});case"ccc":return n.day(o,{width:"abbreviated",context:"standalone"});case"ccccc":return n.day(o,{width:"narrow",context:"standalone"});case"cccccc":return n.day(o,{width:"short",context:"standalone"});case"cccc":default:return n.day(o,{width:"wide",context:"standalone"})}},i:function(e,t,n){const r=e.getDay(),o=r===0?7:r;switch(t){case"i":return String(o);case"ii":return Le(o,t.length);case"io":return n.ordinalNumber(o,{unit:"day"});case"iii":return n.day(r,{width:"abbreviated",context:"formatting"});case"iiiii":return n.day(r,{width:"narrow",context:"formatting"});case"iiiiii":return n.day(r,{width:"short",context:"formatting"});case"iiii":default:return n.day(r,{width:"wide",context:"formatting"})}},a:function(e,t,n){const o=e.getHours()/12>=1?"pm":"am";switch(t){case"a":case"aa":return n.dayPeriod(o,{width:"abbreviated",context:"formatting"});case"aaa":return n.dayPeriod(o,{width:"abbreviated",context:"formatting"}).toLowerCase();case"aaaaa":return n.dayPeriod(o,{width:"narrow",context:"formatting"});case"aaaa":default:return n.dayPeriod(o,{width:"wide",context:"formatting"})}},b:function(e,t,n){const r=e.getHours();let o;switch(r===12?o=Ao.noon:r===0?o=Ao.midnight:o=r/12>=1?"pm":"am",t){case"b":case"bb":return n.dayPeriod(o,{width:"abbreviated",context:"formatting"});case"bbb":return n.dayPeriod(o,{width:"abbreviated",context:"formatting"}).toLowerCase();case"bbbbb":return n.dayPeriod(o,{width:"narrow",context:"formatting"});case"bbbb":default:return n.dayPeriod(o,{width:"wide",context:"formatting"})}},B:function(e,t,n){const r=e.getHours();let o;switch(r>=17?o=Ao.evening:r>=12?o=Ao.afternoon:r>=4?o=Ao.morning:o=Ao.night,t){case"B":case"BB":case"BBB":return n.dayPeriod(o,{width:"abbreviated",context:"formatting"});case"BBBBB":return n.dayPeriod(o,{width:"narrow",context:"formatting"});case"BBBB":default:return n.dayPeriod(o,{width:"wide",context:"formatting"})}},h:function(e,t,n){if(t==="ho"){let r=e.getHours()%12;return r===0&&(r=12),n.ordinalNumber(r,{unit:"hour"})}return br.h(e,t)},H:function(e,t,n){return t==="Ho"?n.ordinalNumber(e.getHours(),{unit:"hour"}):br.H(e,t)},K:function(e,t,n){const r=e.getHours()%12;return t==="Ko"?n.ordinalNumber(r,{unit:"hour"}):Le(r,t.length)},k:function(e,t,n){let r=e.getHours();return r===0&&(r=24),t==="ko"?n.ordinalNumber(r,{unit:"hour"}):Le(r,t.length)},m:function(e,t,n){return t==="mo"?n.ordinalNumber(e.getMinutes(),{unit:"minute"}):br.m(e,t)},s:function(e,t,n){return t==="so"?n.ordinalNumber(e.getSeconds(),{unit:"second"}):br.s(e,t)},S:function(e,t){return br.S(e,t)},X:function(e,t,n){const r=e.getTimezoneOffset();if(r===0)return"Z";switch(t){case"X":return sv(r);case"XXXX":case"XX":return to(r);case"XXXXX":case"XXX":default:return to(r,":")}},x:function(e,t,n){const r=e.getTimezoneOffset();switch(t){case"x":return sv(r);case"xxxx":case"xx":return to(r);case"xxxxx":case"xxx":default:return to(r,":")}},O:function(e,t,n){const r=e.getTimezoneOffset();switch(t){case"O":case"OO":case"OOO":return"GMT"+lv(r,":");case"OOOO":default:return"GMT"+to(r,":")}},z:function(e,t,n){const r=e.getTimezoneOffset();switch(t){case"z":case"zz":case"zzz":return"GMT"+lv(r,":");case"zzzz":default:return"GMT"+to(r,":")}},t:function(e,t,n){const r=Math.trunc(e.getTime()/1e3);return Le(r,t.length)},T:function(e,t,n){const r=e.getTime();return Le(r,t.length)}};function lv(e,t=""){const n=e>0?"-":"+",r=Math.abs(e),o=Math.trunc(r/60),i=r%60;return i===0?n+String(o):n+String(o)+t+Le(i,2)}function sv(e,t){return e%60===0?(e>0?"-":"+")+Le(Math.abs(e)/60,2):to(e,t)}function to(e,t=""){const n=e>0?"-":"+",r=Math.abs(e),o=Le(Math.trunc(r/60),2),i=Le(r%60,2);return n+o+t+i}const uv=(e,t)=>{switch(e){case"P":return t.date({width:"short"});case"PP":return t.date({width:"medium"});case"PPP":return t.date({width:"long"});case"PPPP":default:return t.date({width:"full"})}},b1=(e,t)=>{switch(e){case"p":return t.time({width:"short"});case"pp":return t.time({width:"medium"});case"ppp":return t.time({width:"long"});case"pppp":default:return t.time({width:"full"})}},AN=(e,t)=>{const n=e.match(/(P+)(p+)?/)||[],r=n[1],o=n[2];if(!o)return uv(e,t);let i;switch(r){case"P":i=t.dateTime({width:"short"});break;case"PP":i=t.dateTime({width:"medium"});break;case"PPP":i=t.dateTime({width:"long"});break;case"PPPP":default:i=t.dateTime({width:"full"});break}return i.replace("{{date}}",uv(r,t)).replace("{{time}}",b1(o,t))},FN={p:b1,P:AN},BN=/^D+$/,DN=/^Y+$/,WN=["D","DD","YY","YYYY"];function UN(e){return BN.test(e)}function HN(e){return DN.test(e)}function VN(e,t,n){const r=KN(e,t,n);if(console.warn(r),WN.includes(e))throw new RangeError(r)}function KN(e,t,n){const r=e[0]==="Y"?"years":"days of the month";return`Use \`${e.toLowerCase()}\` instead of \`${e}\` (in \`${t}\`) for formatting ${r} to the input \`${n}\`; see: https://github.com/date-fns/date-fns/blob/master/docs/unicodeTokens.md`}const YN=/[yYQqMLwIdDecihHKkms]o|(\w)\1*|''|'(''|[^'])+('|$)|./g,GN=/P+p+|P+|p+|''|'(''|[^'])+('|$)|./g,qN=/^'([^]*?)'?$/,XN=/''/g,QN=/[a-zA-Z]/;function JN(e,t,n){var c,d,h,C;const r=pc(),o=r.locale??_N,i=r.firstWeekContainsDate??((d=(c=r.locale)==null?void 0:c.options)==null?void 0:d.firstWeekContainsDate)??1,a=r.weekStartsOn??((C=(h=r.locale)==null?void 0:h.options)==null?void 0:C.weekStartsOn)??0,l=Wn(e);if(!Z_(l))throw new RangeError("Invalid time value");let s=t.match(GN).map(y=>{const x=y[0];if(x==="p"||x==="P"){const S=FN[x];return S(y,o.formatLong)}return y}).join("").match(YN).map(y=>{if(y==="''")return{isToken:!1,value:"'"};const x=y[0];if(x==="'")return{isToken:!1,value:ZN(y)};if(av[x])return{isToken:!0,value:y};if(x.match(QN))throw new RangeError("Format string contains an unescaped latin alphabet character `"+x+"`");return{isToken:!1,value:y}});o.localize.preprocessor&&(s=o.localize.preprocessor(l,s));const u={firstWeekContainsDate:i,weekStartsOn:a,locale:o};return s.map(y=>{if(!y.isToken)return y.value;const x=y.value;(HN(x)||UN(x))&&VN(x,t,String(e));const S=av[x[0]];return S(l,x,o.localize,u)}).join("")}function ZN(e){const t=e.match(qN);return t?t[1].replace(XN,"'"):e}const eL=e=>{try{const t=new Date(e);return JN(t,"yyyy-MM-dd HH:mm:ss.SSS")}catch(t){return console.error("Error formatting timestamp:",t),e||"Unknown"}},tL=e=>!e||typeof e!="object"?"":Object.entries(e).map(([t,n])=>`${t}: ${n}`).join(`
 `),nL=e=>{if(!e)return"";switch(String(e).toLowerCase()){case"debug":return"log-level-debug";case"info":return"log-level-info";case"warning":case"warn":return"log-level-warning";case"error":return"log-level-error";case"fatal":case"critical":return"log-level-fatal";default:return""}},rL=()=>{const[e,t]=m.useState({service:"",logLevel:"",logSearch:"",timeRange:"1h"}),[n,r]=m.useState([]),[o,i]=m.useState(!0),[a,l]=m.useState(""),{logs:s,loading:u,error:c,pagination:d,changePage:h,changePageSize:C,refresh:y}=Y_(e);m.useEffect(()=>{(async()=>{try{i(!0);const k=await K_();console.log("Available services:",k),Array.isArray(k)?r(k):k&&Array.isArray(k.services)?r(k.services):(console.warn("Unexpected services response format:",k),r([]))}catch(k){console.error("Error fetching services:",k),r([])}finally{i(!1)}})()},[]);const x=(w,k)=>{console.log(`Page changed in component to ${k}`),h(k)},S=w=>{const k=parseInt(w.target.value,10);console.log(`Page size changed in component to ${k}`),C(k)},f=w=>{const{name:k,value:E}=w.target;t(P=>({...P,[k]:E}))},g=w=>{w.preventDefault(),t(k=>({...k,logSearch:a}))},v=()=>{l(""),t(w=>({...w,logSearch:""}))};return p.jsxs(Dn,{elevation:2,sx:{p:2,mb:2},children:[p.jsxs(bt,{sx:{display:"flex",justifyContent:"space-between",alignItems:"center",mb:2},children:[p.jsx(De,{variant:"h6",component:"h2",children:"Logs"}),p.jsx(bt,{sx:{display:"flex",gap:1},children:p.jsx(rd,{title:"Refresh logs",children:u?p.jsx("span",{children:p.jsx($a,{variant:"outlined",color:"primary",startIcon:p.jsx(yf,{}),disabled:!0,children:"Refresh"})}):p.jsx($a,{variant:"outlined",color:"primary",startIcon:p.jsx(yf,{}),onClick:y,children:"Refresh"})})})]}),p.jsxs(bt,{sx:{display:"flex",gap:2,mb:2,flexWrap:"wrap"},children:[p.jsxs(jn,{size:"small",sx:{minWidth:120},children:[p.jsx(Gn,{id:"log-level-label",children:"Log Level"}),p.jsxs(vn,{labelId:"log-level-label",id:"log-level",name:"logLevel",value:e.logLevel,label:"Log Level",onChange:f,children:[p.jsx(ae,{value:"",children:"All Levels"}),p.jsx(ae,{value:"DEBUG",children:"Debug"}),p.jsx(ae,{value:"INFO",children:"Info"}),p.jsx(ae,{value:"WARNING",children:"Warning"}),p.jsx(ae,{value:"ERROR",children:"Error"}),p.jsx(ae,{value:"FATAL",children:"Fatal"})]})]}),p.jsxs(jn,{size:"small",sx:{minWidth:120},children:[p.jsx(Gn,{id:"service-label",children:"Service"}),p.jsxs(vn,{labelId:"service-label",id:"service",name:"service",value:e.service,label:"Service",onChange:f,disabled:o,children:[p.jsx(ae,{value:"",children:"All Services"}),o?p.jsx(ae,{disabled:!0,children:"Loading services..."}):n.length>0?n.map(w=>p.jsx(ae,{value:w.id||w.name||w,children:w.name||w.id||w},w.id||w.name||w)):p.jsx(ae,{disabled:!0,children:"No services found"})]})]}),p.jsxs(jn,{size:"small",sx:{minWidth:120},children:[p.jsx(Gn,{id:"time-range-label",children:"Time Range"}),p.jsxs(vn,{labelId:"time-range-label",id:"time-range",name:"timeRange",value:e.timeRange,label:"Time Range",onChange:f,children:[p.jsx(ae,{value:"5m",children:"Last 5 minutes"}),p.jsx(ae,{value:"15m",children:"Last 15 minutes"}),p.jsx(ae,{value:"1h",children:"Last 1 hour"}),p.jsx(ae,{value:"3h",children:"Last 3 hours"}),p.jsx(ae,{value:"6h",children:"Last 6 hours"}),p.jsx(ae,{value:"12h",children:"Last 12 hours"}),p.jsx(ae,{value:"1d",children:"Last 24 hours"}),p.jsx(ae,{value:"7d",children:"Last 7 days"})]})]}),p.jsxs("form",{onSubmit:g,style:{display:"flex",alignItems:"center"},children:[p.jsx(qp,{id:"search",name:"search",size:"small",label:"Search",variant:"outlined",value:a,onChange:w=>l(w.target.value),InputProps:{endAdornment:a?p.jsx(hf,{size:"small",onClick:v,children:p.jsx(h1,{})}):null}}),p.jsx(hf,{type:"submit",size:"small",children:p.jsx(p1,{})})]})]}),c&&p.jsx(e1,{severity:"error",sx:{mb:2},action:p.jsx($a,{color:"inherit",size:"small",onClick:y,children:"Retry"}),children:p.jsxs(De,{variant:"body2",component:"div",children:[c,c.includes("500")&&p.jsx(De,{variant:"caption",display:"block",sx:{mt:1},children:"This could be a server-side pagination issue. Try refreshing or changing your filters."})]})}),s.length>0&&p.jsxs(bt,{sx:{display:"flex",justifyContent:"space-between",alignItems:"center",mb:2},children:[p.jsxs(bt,{sx:{display:"flex",alignItems:"center"},children:[p.jsxs(De,{variant:"body2",component:"span",sx:{mr:2},children:["Showing ",(d.currentPage-1)*d.pageSize+1," - ",Math.min(d.currentPage*d.pageSize,d.totalItems)," of ",d.totalItems," logs"]}),p.jsx(jn,{size:"small",sx:{minWidth:80},children:p.jsxs(vn,{value:d.pageSize,onChange:S,displayEmpty:!0,children:[p.jsx(ae,{value:10,children:"10"}),p.jsx(ae,{value:25,children:"25"}),p.jsx(ae,{value:50,children:"50"}),p.jsx(ae,{value:100,children:"100"})]})})]}),p.jsx(zg,{count:d.totalPages,page:d.currentPage,onChange:x,color:"primary",size:"small",showFirstButton:!0,showLastButton:!0,disabled:u})]}),p.jsx(tO,{sx:{maxHeight:"calc(100vh - 300px)"},children:p.jsxs(FM,{stickyHeader:!0,size:"small",children:[p.jsx(lO,{children:p.jsxs(Ql,{children:[p.jsx(cn,{children:"Time"}),p.jsx(cn,{children:"Level"}),p.jsx(cn,{children:"Service"}),p.jsx(cn,{children:"Message"}),p.jsx(cn,{children:"Tags"})]})}),p.jsx(VM,{children:u?p.jsx(Ql,{children:p.jsx(cn,{colSpan:5,align:"center",children:p.jsx(DO,{size:24,sx:{my:2}})})}):s.length===0?p.jsx(Ql,{children:p.jsx(cn,{colSpan:5,align:"center",children:"No logs found. Try adjusting your filters."})}):s.map(w=>p.jsxs(Ql,{className:nL(w.level),children:[p.jsx(cn,{children:eL(w.timestamp)}),p.jsx(cn,{children:w.level}),p.jsx(cn,{children:w.service}),p.jsx(cn,{sx:{maxWidth:"400px",overflow:"hidden",textOverflow:"ellipsis",whiteSpace:"nowrap"},children:p.jsx(rd,{title:w.message,placement:"top",children:p.jsx("span",{children:w.message})})}),p.jsx(cn,{children:w.tags&&Object.keys(w.tags).length>0?p.jsx(rd,{title:tL(w.tags),placement:"left",children:p.jsxs(bt,{sx:{display:"flex",flexWrap:"wrap",gap:.5},children:[Object.entries(w.tags).slice(0,3).map(([k,E])=>p.jsx(Fg,{label:`${k}: ${E}`,size:"small",variant:"outlined"},k)),Object.keys(w.tags).length>3&&p.jsx(Fg,{label:`+${Object.keys(w.tags).length-3} more`,size:"small",variant:"outlined"})]})}):"-"})]},w.id))})]})}),s.length>0&&p.jsx(bt,{sx:{display:"flex",justifyContent:"center",mt:2},children:p.jsx(zg,{count:d.totalPages,page:d.currentPage,onChange:x,color:"primary",showFirstButton:!0,showLastButton:!0,disabled:u})})]})},oL=({filters:e})=>p.jsxs(Dn,{elevation:2,sx:{p:2,mb:2},children:[p.jsx(De,{variant:"h6",component:"h2",gutterBottom:!0,children:"Metrics"}),p.jsx(bt,{sx:{p:4,textAlign:"center"},children:p.jsx(De,{variant:"body1",children:"Metrics functionality will be implemented in a future update."})})]}),iL=({filters:e})=>p.jsxs(Dn,{elevation:2,sx:{p:2,mb:2},children:[p.jsx(De,{variant:"h6",component:"h2",gutterBottom:!0,children:"Traces"}),p.jsx(bt,{sx:{p:4,textAlign:"center"},children:p.jsx(De,{variant:"body1",children:"Traces functionality will be implemented in a future update."})})]});function aL(e,t,n=(r,o)=>r===o){return e.length===t.length&&e.every((r,o)=>n(r,t[o]))}const lL=2;function w1(e,t){return e-t}function cv(e,t){var n;const{index:r}=(n=e.reduce((o,i,a)=>{const l=Math.abs(t-i);return o===null||l<o.distance||l===o.distance?{distance:l,index:a}:o},null))!=null?n:{};return r}function ts(e,t){if(t.current!==void 0&&e.changedTouches){const n=e;for(let r=0;r<n.changedTouches.length;r+=1){const o=n.changedTouches[r];if(o.identifier===t.current)return{x:o.clientX,y:o.clientY}}return!1}return{x:e.clientX,y:e.clientY}}function au(e,t,n){return(e-t)*100/(n-t)}function sL(e,t,n){return(n-t)*e+t}function uL(e){if(Math.abs(e)<1){const n=e.toExponential().split("e-"),r=n[0].split(".")[1];return(r?r.length:0)+parseInt(n[1],10)}const t=e.toString().split(".")[1];return t?t.length:0}function cL(e,t,n){const r=Math.round((e-n)/t)*t+n;return Number(r.toFixed(uL(t)))}function dv({values:e,newValue:t,index:n}){const r=e.slice();return r[n]=t,r.sort(w1)}function ns({sliderRef:e,activeIndex:t,setActive:n}){var r,o;const i=Xe(e.current);if(!((r=e.current)!=null&&r.contains(i.activeElement))||Number(i==null||(o=i.activeElement)==null?void 0:o.getAttribute("data-index"))!==t){var a;(a=e.current)==null||a.querySelector(`[type="range"][data-index="${t}"]`).focus()}n&&n(t)}function rs(e,t){return typeof e=="number"&&typeof t=="number"?e===t:typeof e=="object"&&typeof t=="object"?aL(e,t):!1}const dL={horizontal:{offset:e=>({left:`${e}%`}),leap:e=>({width:`${e}%`})},"horizontal-reverse":{offset:e=>({right:`${e}%`}),leap:e=>({width:`${e}%`})},vertical:{offset:e=>({bottom:`${e}%`}),leap:e=>({height:`${e}%`})}},fL=e=>e;let os;function fv(){return os===void 0&&(typeof CSS<"u"&&typeof CSS.supports=="function"?os=CSS.supports("touch-action","none"):os=!0),os}function pL(e){const{"aria-labelledby":t,defaultValue:n,disabled:r=!1,disableSwap:o=!1,isRtl:i=!1,marks:a=!1,max:l=100,min:s=0,name:u,onChange:c,onChangeCommitted:d,orientation:h="horizontal",rootRef:C,scale:y=fL,step:x=1,shiftStep:S=10,tabIndex:f,value:g}=e,v=m.useRef(void 0),[w,k]=m.useState(-1),[E,P]=m.useState(-1),[$,M]=m.useState(!1),R=m.useRef(0),[I,D]=xi({controlled:g,default:n??s,name:"Slider"}),_=c&&((N,O,X)=>{const pe=N.nativeEvent||N,ge=new pe.constructor(pe.type,pe);Object.defineProperty(ge,"target",{writable:!0,value:{value:O,name:u}}),c(ge,O,X)}),j=Array.isArray(I);let F=j?I.slice().sort(w1):[I];F=F.map(N=>N==null?s:Fo(N,s,l));const A=a===!0&&x!==null?[...Array(Math.floor((l-s)/x)+1)].map((N,O)=>({value:s+x*O})):a||[],B=A.map(N=>N.value),{isFocusVisibleRef:T,onBlur:L,onFocus:Y,ref:te}=bl(),[ne,me]=m.useState(-1),Q=m.useRef(null),he=Ke(te,Q),re=Ke(C,he),Se=N=>O=>{var X;const pe=Number(O.currentTarget.getAttribute("data-index"));Y(O),T.current===!0&&me(pe),P(pe),N==null||(X=N.onFocus)==null||X.call(N,O)},ye=N=>O=>{var X;L(O),T.current===!1&&me(-1),P(-1),N==null||(X=N.onBlur)==null||X.call(N,O)},we=(N,O)=>{const X=Number(N.currentTarget.getAttribute("data-index")),pe=F[X],ge=B.indexOf(pe);let V=O;if(A&&x==null){const ve=B[B.length-1];V>ve?V=ve:V<B[0]?V=B[0]:V=V<pe?B[ge-1]:B[ge+1]}if(V=Fo(V,s,l),j){o&&(V=Fo(V,F[X-1]||-1/0,F[X+1]||1/0));const ve=V;V=dv({values:F,newValue:V,index:X});let U=X;o||(U=V.indexOf(ve)),ns({sliderRef:Q,activeIndex:U})}D(V),me(X),_&&!rs(V,I)&&_(N,V,X),d&&d(N,V)},Ae=N=>O=>{var X;if(x!==null){const pe=Number(O.currentTarget.getAttribute("data-index")),ge=F[pe];let V=null;(O.key==="ArrowLeft"||O.key==="ArrowDown")&&O.shiftKey||O.key==="PageDown"?V=Math.max(ge-S,s):((O.key==="ArrowRight"||O.key==="ArrowUp")&&O.shiftKey||O.key==="PageUp")&&(V=Math.min(ge+S,l)),V!==null&&(we(O,V),O.preventDefault())}N==null||(X=N.onKeyDown)==null||X.call(N,O)};rn(()=>{if(r&&Q.current.contains(document.activeElement)){var N;(N=document.activeElement)==null||N.blur()}},[r]),r&&w!==-1&&k(-1),r&&ne!==-1&&me(-1);const de=N=>O=>{var X;(X=N.onChange)==null||X.call(N,O),we(O,O.target.valueAsNumber)},Te=m.useRef(void 0);let oe=h;i&&h==="horizontal"&&(oe+="-reverse");const ee=({finger:N,move:O=!1})=>{const{current:X}=Q,{width:pe,height:ge,bottom:V,left:ve}=X.getBoundingClientRect();let U;oe.indexOf("vertical")===0?U=(V-N.y)/ge:U=(N.x-ve)/pe,oe.indexOf("-reverse")!==-1&&(U=1-U);let K;if(K=sL(U,s,l),x)K=cL(K,x,s);else{const be=cv(B,K);K=B[be]}K=Fo(K,s,l);let ue=0;if(j){O?ue=Te.current:ue=cv(F,K),o&&(K=Fo(K,F[ue-1]||-1/0,F[ue+1]||1/0));const be=K;K=dv({values:F,newValue:K,index:ue}),o&&O||(ue=K.indexOf(be),Te.current=ue)}return{newValue:K,activeIndex:ue}},Z=wt(N=>{const O=ts(N,v);if(!O)return;if(R.current+=1,N.type==="mousemove"&&N.buttons===0){Ne(N);return}const{newValue:X,activeIndex:pe}=ee({finger:O,move:!0});ns({sliderRef:Q,activeIndex:pe,setActive:k}),D(X),!$&&R.current>lL&&M(!0),_&&!rs(X,I)&&_(N,X,pe)}),Ne=wt(N=>{const O=ts(N,v);if(M(!1),!O)return;const{newValue:X}=ee({finger:O,move:!0});k(-1),N.type==="touchend"&&P(-1),d&&d(N,X),v.current=void 0,Ce()}),Re=wt(N=>{if(r)return;fv()||N.preventDefault();const O=N.changedTouches[0];O!=null&&(v.current=O.identifier);const X=ts(N,v);if(X!==!1){const{newValue:ge,activeIndex:V}=ee({finger:X});ns({sliderRef:Q,activeIndex:V,setActive:k}),D(ge),_&&!rs(ge,I)&&_(N,ge,V)}R.current=0;const pe=Xe(Q.current);pe.addEventListener("touchmove",Z,{passive:!0}),pe.addEventListener("touchend",Ne,{passive:!0})}),Ce=m.useCallback(()=>{const N=Xe(Q.current);N.removeEventListener("mousemove",Z),N.removeEventListener("mouseup",Ne),N.removeEventListener("touchmove",Z),N.removeEventListener("touchend",Ne)},[Ne,Z]);m.useEffect(()=>{const{current:N}=Q;return N.addEventListener("touchstart",Re,{passive:fv()}),()=>{N.removeEventListener("touchstart",Re),Ce()}},[Ce,Re]),m.useEffect(()=>{r&&Ce()},[r,Ce]);const Qe=N=>O=>{var X;if((X=N.onMouseDown)==null||X.call(N,O),r||O.defaultPrevented||O.button!==0)return;O.preventDefault();const pe=ts(O,v);if(pe!==!1){const{newValue:V,activeIndex:ve}=ee({finger:pe});ns({sliderRef:Q,activeIndex:ve,setActive:k}),D(V),_&&!rs(V,I)&&_(O,V,ve)}R.current=0;const ge=Xe(Q.current);ge.addEventListener("mousemove",Z,{passive:!0}),ge.addEventListener("mouseup",Ne)},fe=au(j?F[0]:s,s,l),xe=au(F[F.length-1],s,l)-fe,Ye=(N={})=>{const O=co(N),X={onMouseDown:Qe(O||{})},pe=b({},O,X);return b({},N,{ref:re},pe)},Fe=N=>O=>{var X;(X=N.onMouseOver)==null||X.call(N,O);const pe=Number(O.currentTarget.getAttribute("data-index"));P(pe)},Pe=N=>O=>{var X;(X=N.onMouseLeave)==null||X.call(N,O),P(-1)};return{active:w,axis:oe,axisProps:dL,dragging:$,focusedThumbIndex:ne,getHiddenInputProps:(N={})=>{var O;const X=co(N),pe={onChange:de(X||{}),onFocus:Se(X||{}),onBlur:ye(X||{}),onKeyDown:Ae(X||{})},ge=b({},X,pe);return b({tabIndex:f,"aria-labelledby":t,"aria-orientation":h,"aria-valuemax":y(l),"aria-valuemin":y(s),name:u,type:"range",min:e.min,max:e.max,step:e.step===null&&e.marks?"any":(O=e.step)!=null?O:void 0,disabled:r},N,ge,{style:b({},CP,{direction:i?"rtl":"ltr",width:"100%",height:"100%"})})},getRootProps:Ye,getThumbProps:(N={})=>{const O=co(N),X={onMouseOver:Fe(O||{}),onMouseLeave:Pe(O||{})};return b({},N,O,X)},marks:A,open:E,range:j,rootRef:re,trackLeap:xe,trackOffset:fe,values:F,getThumbStyle:N=>({pointerEvents:w!==-1&&w!==N?"none":void 0})}}const hL=e=>!e||!Ar(e);function mL(e){return le("MuiSlider",e)}const xn=ie("MuiSlider",["root","active","colorPrimary","colorSecondary","colorError","colorInfo","colorSuccess","colorWarning","disabled","dragging","focusVisible","mark","markActive","marked","markLabel","markLabelActive","rail","sizeSmall","thumb","thumbColorPrimary","thumbColorSecondary","thumbColorError","thumbColorSuccess","thumbColorInfo","thumbColorWarning","track","trackInverted","trackFalse","thumbSizeSmall","valueLabel","valueLabelOpen","valueLabelCircle","valueLabelLabel","vertical"]),gL=e=>{const{open:t}=e;return{offset:G(t&&xn.valueLabelOpen),circle:xn.valueLabelCircle,label:xn.valueLabelLabel}};function vL(e){const{children:t,className:n,value:r}=e,o=gL(e);return t?m.cloneElement(t,{className:G(t.props.className)},p.jsxs(m.Fragment,{children:[t.props.children,p.jsx("span",{className:G(o.offset,n),"aria-hidden":!0,children:p.jsx("span",{className:o.circle,children:p.jsx("span",{className:o.label,children:r})})})]})):null}const yL=["aria-label","aria-valuetext","aria-labelledby","component","components","componentsProps","color","classes","className","disableSwap","disabled","getAriaLabel","getAriaValueText","marks","max","min","name","onChange","onChangeCommitted","orientation","shiftStep","size","step","scale","slotProps","slots","tabIndex","track","value","valueLabelDisplay","valueLabelFormat"];function pv(e){return e}const xL=W("span",{name:"MuiSlider",slot:"Root",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.root,t[`color${z(n.color)}`],n.size!=="medium"&&t[`size${z(n.size)}`],n.marked&&t.marked,n.orientation==="vertical"&&t.vertical,n.track==="inverted"&&t.trackInverted,n.track===!1&&t.trackFalse]}})(({theme:e})=>{var t;return{borderRadius:12,boxSizing:"content-box",display:"inline-block",position:"relative",cursor:"pointer",touchAction:"none",WebkitTapHighlightColor:"transparent","@media print":{colorAdjust:"exact"},[`&.${xn.disabled}`]:{pointerEvents:"none",cursor:"default",color:(e.vars||e).palette.grey[400]},[`&.${xn.dragging}`]:{[`& .${xn.thumb}, & .${xn.track}`]:{transition:"none"}},variants:[...Object.keys(((t=e.vars)!=null?t:e).palette).filter(n=>{var r;return((r=e.vars)!=null?r:e).palette[n].main}).map(n=>({props:{color:n},style:{color:(e.vars||e).palette[n].main}})),{props:{orientation:"horizontal"},style:{height:4,width:"100%",padding:"13px 0","@media (pointer: coarse)":{padding:"20px 0"}}},{props:{orientation:"horizontal",size:"small"},style:{height:2}},{props:{orientation:"horizontal",marked:!0},style:{marginBottom:20}},{props:{orientation:"vertical"},style:{height:"100%",width:4,padding:"0 13px","@media (pointer: coarse)":{padding:"0 20px"}}},{props:{orientation:"vertical",size:"small"},style:{width:2}},{props:{orientation:"vertical",marked:!0},style:{marginRight:44}}]}}),bL=W("span",{name:"MuiSlider",slot:"Rail",overridesResolver:(e,t)=>t.rail})({display:"block",position:"absolute",borderRadius:"inherit",backgroundColor:"currentColor",opacity:.38,variants:[{props:{orientation:"horizontal"},style:{width:"100%",height:"inherit",top:"50%",transform:"translateY(-50%)"}},{props:{orientation:"vertical"},style:{height:"100%",width:"inherit",left:"50%",transform:"translateX(-50%)"}},{props:{track:"inverted"},style:{opacity:1}}]}),wL=W("span",{name:"MuiSlider",slot:"Track",overridesResolver:(e,t)=>t.track})(({theme:e})=>{var t;return{display:"block",position:"absolute",borderRadius:"inherit",border:"1px solid currentColor",backgroundColor:"currentColor",transition:e.transitions.create(["left","width","bottom","height"],{duration:e.transitions.duration.shortest}),variants:[{props:{size:"small"},style:{border:"none"}},{props:{orientation:"horizontal"},style:{height:"inherit",top:"50%",transform:"translateY(-50%)"}},{props:{orientation:"vertical"},style:{width:"inherit",left:"50%",transform:"translateX(-50%)"}},{props:{track:!1},style:{display:"none"}},...Object.keys(((t=e.vars)!=null?t:e).palette).filter(n=>{var r;return((r=e.vars)!=null?r:e).palette[n].main}).map(n=>({props:{color:n,track:"inverted"},style:b({},e.vars?{backgroundColor:e.vars.palette.Slider[`${n}Track`],borderColor:e.vars.palette.Slider[`${n}Track`]}:b({backgroundColor:wi(e.palette[n].main,.62),borderColor:wi(e.palette[n].main,.62)},e.applyStyles("dark",{backgroundColor:bi(e.palette[n].main,.5)}),e.applyStyles("dark",{borderColor:bi(e.palette[n].main,.5)})))}))]}}),CL=W("span",{name:"MuiSlider",slot:"Thumb",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.thumb,t[`thumbColor${z(n.color)}`],n.size!=="medium"&&t[`thumbSize${z(n.size)}`]]}})(({theme:e})=>{var t;return{position:"absolute",width:20,height:20,boxSizing:"border-box",borderRadius:"50%",outline:0,backgroundColor:"currentColor",display:"flex",alignItems:"center",justifyContent:"center",transition:e.transitions.create(["box-shadow","left","bottom"],{duration:e.transitions.duration.shortest}),"&::before":{position:"absolute",content:'""',borderRadius:"inherit",width:"100%",height:"100%",boxShadow:(e.vars||e).shadows[2]},"&::after":{position:"absolute",content:'""',borderRadius:"50%",width:42,height:42,top:"50%",left:"50%",transform:"translate(-50%, -50%)"},[`&.${xn.disabled}`]:{"&:hover":{boxShadow:"none"}},variants:[{props:{size:"small"},style:{width:12,height:12,"&::before":{boxShadow:"none"}}},{props:{orientation:"horizontal"},style:{top:"50%",transform:"translate(-50%, -50%)"}},{props:{orientation:"vertical"},style:{left:"50%",transform:"translate(-50%, 50%)"}},...Object.keys(((t=e.vars)!=null?t:e).palette).filter(n=>{var r;return((r=e.vars)!=null?r:e).palette[n].main}).map(n=>({props:{color:n},style:{[`&:hover, &.${xn.focusVisible}`]:b({},e.vars?{boxShadow:`0px 0px 0px 8px rgba(${e.vars.palette[n].mainChannel} / 0.16)`}:{boxShadow:`0px 0px 0px 8px ${ke(e.palette[n].main,.16)}`},{"@media (hover: none)":{boxShadow:"none"}}),[`&.${xn.active}`]:b({},e.vars?{boxShadow:`0px 0px 0px 14px rgba(${e.vars.palette[n].mainChannel} / 0.16)`}:{boxShadow:`0px 0px 0px 14px ${ke(e.palette[n].main,.16)}`})}}))]}}),SL=W(vL,{name:"MuiSlider",slot:"ValueLabel",overridesResolver:(e,t)=>t.valueLabel})(({theme:e})=>b({zIndex:1,whiteSpace:"nowrap"},e.typography.body2,{fontWeight:500,transition:e.transitions.create(["transform"],{duration:e.transitions.duration.shortest}),position:"absolute",backgroundColor:(e.vars||e).palette.grey[600],borderRadius:2,color:(e.vars||e).palette.common.white,display:"flex",alignItems:"center",justifyContent:"center",padding:"0.25rem 0.75rem",variants:[{props:{orientation:"horizontal"},style:{transform:"translateY(-100%) scale(0)",top:"-10px",transformOrigin:"bottom center","&::before":{position:"absolute",content:'""',width:8,height:8,transform:"translate(-50%, 50%) rotate(45deg)",backgroundColor:"inherit",bottom:0,left:"50%"},[`&.${xn.valueLabelOpen}`]:{transform:"translateY(-100%) scale(1)"}}},{props:{orientation:"vertical"},style:{transform:"translateY(-50%) scale(0)",right:"30px",top:"50%",transformOrigin:"right center","&::before":{position:"absolute",content:'""',width:8,height:8,transform:"translate(-50%, -50%) rotate(45deg)",backgroundColor:"inherit",right:-8,top:"50%"},[`&.${xn.valueLabelOpen}`]:{transform:"translateY(-50%) scale(1)"}}},{props:{size:"small"},style:{fontSize:e.typography.pxToRem(12),padding:"0.25rem 0.5rem"}},{props:{orientation:"vertical",size:"small"},style:{right:"20px"}}]})),kL=W("span",{name:"MuiSlider",slot:"Mark",shouldForwardProp:e=>oc(e)&&e!=="markActive",overridesResolver:(e,t)=>{const{markActive:n}=e;return[t.mark,n&&t.markActive]}})(({theme:e})=>({position:"absolute",width:2,height:2,borderRadius:1,backgroundColor:"currentColor",variants:[{props:{orientation:"horizontal"},style:{top:"50%",transform:"translate(-1px, -50%)"}},{props:{orientation:"vertical"},style:{left:"50%",transform:"translate(-50%, 1px)"}},{props:{markActive:!0},style:{backgroundColor:(e.vars||e).palette.background.paper,opacity:.8}}]})),PL=W("span",{name:"MuiSlider",slot:"MarkLabel",shouldForwardProp:e=>oc(e)&&e!=="markLabelActive",overridesResolver:(e,t)=>t.markLabel})(({theme:e})=>b({},e.typography.body2,{color:(e.vars||e).palette.text.secondary,position:"absolute",whiteSpace:"nowrap",variants:[{props:{orientation:"horizontal"},style:{top:30,transform:"translateX(-50%)","@media (pointer: coarse)":{top:40}}},{props:{orientation:"vertical"},style:{left:36,transform:"translateY(50%)","@media (pointer: coarse)":{left:44}}},{props:{markLabelActive:!0},style:{color:(e.vars||e).palette.text.primary}}]})),EL=e=>{const{disabled:t,dragging:n,marked:r,orientation:o,track:i,classes:a,color:l,size:s}=e,u={root:["root",t&&"disabled",n&&"dragging",r&&"marked",o==="vertical"&&"vertical",i==="inverted"&&"trackInverted",i===!1&&"trackFalse",l&&`color${z(l)}`,s&&`size${z(s)}`],rail:["rail"],track:["track"],mark:["mark"],markActive:["markActive"],markLabel:["markLabel"],markLabelActive:["markLabelActive"],valueLabel:["valueLabel"],thumb:["thumb",t&&"disabled",s&&`thumbSize${z(s)}`,l&&`thumbColor${z(l)}`],active:["active"],disabled:["disabled"],focusVisible:["focusVisible"]};return ce(u,mL,a)},RL=({children:e})=>e,hv=m.forwardRef(function(t,n){var r,o,i,a,l,s,u,c,d,h,C,y,x,S,f,g,v,w,k,E,P,$,M,R;const I=se({props:t,name:"MuiSlider"}),D=Ni(),{"aria-label":_,"aria-valuetext":j,"aria-labelledby":F,component:A="span",components:B={},componentsProps:T={},color:L="primary",classes:Y,className:te,disableSwap:ne=!1,disabled:me=!1,getAriaLabel:Q,getAriaValueText:he,marks:re=!1,max:Se=100,min:ye=0,orientation:we="horizontal",shiftStep:Ae=10,size:de="medium",step:Te=1,scale:oe=pv,slotProps:ee,slots:Z,track:Ne="normal",valueLabelDisplay:Re="off",valueLabelFormat:Ce=pv}=I,Qe=q(I,yL),fe=b({},I,{isRtl:D,max:Se,min:ye,classes:Y,disabled:me,disableSwap:ne,orientation:we,marks:re,color:L,size:de,step:Te,shiftStep:Ae,scale:oe,track:Ne,valueLabelDisplay:Re,valueLabelFormat:Ce}),{axisProps:xe,getRootProps:Ye,getHiddenInputProps:Fe,getThumbProps:Pe,open:rt,active:ot,axis:Je,focusedThumbIndex:N,range:O,dragging:X,marks:pe,values:ge,trackOffset:V,trackLeap:ve,getThumbStyle:U}=pL(b({},fe,{rootRef:n}));fe.marked=pe.length>0&&pe.some(Ze=>Ze.label),fe.dragging=X,fe.focusedThumbIndex=N;const K=EL(fe),ue=(r=(o=Z==null?void 0:Z.root)!=null?o:B.Root)!=null?r:xL,be=(i=(a=Z==null?void 0:Z.rail)!=null?a:B.Rail)!=null?i:bL,Ue=(l=(s=Z==null?void 0:Z.track)!=null?s:B.Track)!=null?l:wL,dt=(u=(c=Z==null?void 0:Z.thumb)!=null?c:B.Thumb)!=null?u:CL,_t=(d=(h=Z==null?void 0:Z.valueLabel)!=null?h:B.ValueLabel)!=null?d:SL,un=(C=(y=Z==null?void 0:Z.mark)!=null?y:B.Mark)!=null?C:kL,Nt=(x=(S=Z==null?void 0:Z.markLabel)!=null?S:B.MarkLabel)!=null?x:PL,nr=(f=(g=Z==null?void 0:Z.input)!=null?g:B.Input)!=null?f:"input",Eo=(v=ee==null?void 0:ee.root)!=null?v:T.root,hc=(w=ee==null?void 0:ee.rail)!=null?w:T.rail,Bi=(k=ee==null?void 0:ee.track)!=null?k:T.track,Di=(E=ee==null?void 0:ee.thumb)!=null?E:T.thumb,Kr=(P=ee==null?void 0:ee.valueLabel)!=null?P:T.valueLabel,mc=($=ee==null?void 0:ee.mark)!=null?$:T.mark,Yr=(M=ee==null?void 0:ee.markLabel)!=null?M:T.markLabel,Wi=(R=ee==null?void 0:ee.input)!=null?R:T.input,Gt=ft({elementType:ue,getSlotProps:Ye,externalSlotProps:Eo,externalForwardedProps:Qe,additionalProps:b({},hL(ue)&&{as:A}),ownerState:b({},fe,Eo==null?void 0:Eo.ownerState),className:[K.root,te]}),Gr=ft({elementType:be,externalSlotProps:hc,ownerState:fe,className:K.rail}),gc=ft({elementType:Ue,externalSlotProps:Bi,additionalProps:{style:b({},xe[Je].offset(V),xe[Je].leap(ve))},ownerState:b({},fe,Bi==null?void 0:Bi.ownerState),className:K.track}),rr=ft({elementType:dt,getSlotProps:Pe,externalSlotProps:Di,ownerState:b({},fe,Di==null?void 0:Di.ownerState),className:K.thumb}),Ui=ft({elementType:_t,externalSlotProps:Kr,ownerState:b({},fe,Kr==null?void 0:Kr.ownerState),className:K.valueLabel}),Ro=ft({elementType:un,externalSlotProps:mc,ownerState:fe,className:K.mark}),$o=ft({elementType:Nt,externalSlotProps:Yr,ownerState:fe,className:K.markLabel}),Pl=ft({elementType:nr,getSlotProps:Fe,externalSlotProps:Wi,ownerState:fe});return p.jsxs(ue,b({},Gt,{children:[p.jsx(be,b({},Gr)),p.jsx(Ue,b({},gc)),pe.filter(Ze=>Ze.value>=ye&&Ze.value<=Se).map((Ze,gt)=>{const Hi=au(Ze.value,ye,Se),To=xe[Je].offset(Hi);let On;return Ne===!1?On=ge.indexOf(Ze.value)!==-1:On=Ne==="normal"&&(O?Ze.value>=ge[0]&&Ze.value<=ge[ge.length-1]:Ze.value<=ge[0])||Ne==="inverted"&&(O?Ze.value<=ge[0]||Ze.value>=ge[ge.length-1]:Ze.value>=ge[0]),p.jsxs(m.Fragment,{children:[p.jsx(un,b({"data-index":gt},Ro,!Ar(un)&&{markActive:On},{style:b({},To,Ro.style),className:G(Ro.className,On&&K.markActive)})),Ze.label!=null?p.jsx(Nt,b({"aria-hidden":!0,"data-index":gt},$o,!Ar(Nt)&&{markLabelActive:On},{style:b({},To,$o.style),className:G(K.markLabel,$o.className,On&&K.markLabelActive),children:Ze.label})):null]},gt)}),ge.map((Ze,gt)=>{const Hi=au(Ze,ye,Se),To=xe[Je].offset(Hi),On=Re==="off"?RL:_t;return p.jsx(On,b({},!Ar(On)&&{valueLabelFormat:Ce,valueLabelDisplay:Re,value:typeof Ce=="function"?Ce(oe(Ze),gt):Ce,index:gt,open:rt===gt||ot===gt||Re==="on",disabled:me},Ui,{children:p.jsx(dt,b({"data-index":gt},rr,{className:G(K.thumb,rr.className,ot===gt&&K.active,N===gt&&K.focusVisible),style:b({},To,U(gt),rr.style),children:p.jsx(nr,b({"data-index":gt,"aria-label":Q?Q(gt):_,"aria-valuenow":oe(Ze),"aria-labelledby":F,"aria-valuetext":he?he(oe(Ze),gt):j,value:ge[gt]},Pl))}))}),gt)})]}))});function $L(e={}){const{autoHideDuration:t=null,disableWindowBlurListener:n=!1,onClose:r,open:o,resumeHideDuration:i}=e,a=io();m.useEffect(()=>{if(!o)return;function f(g){g.defaultPrevented||(g.key==="Escape"||g.key==="Esc")&&(r==null||r(g,"escapeKeyDown"))}return document.addEventListener("keydown",f),()=>{document.removeEventListener("keydown",f)}},[o,r]);const l=wt((f,g)=>{r==null||r(f,g)}),s=wt(f=>{!r||f==null||a.start(f,()=>{l(null,"timeout")})});m.useEffect(()=>(o&&s(t),a.clear),[o,t,s,a]);const u=f=>{r==null||r(f,"clickaway")},c=a.clear,d=m.useCallback(()=>{t!=null&&s(i??t*.5)},[t,i,s]),h=f=>g=>{const v=f.onBlur;v==null||v(g),d()},C=f=>g=>{const v=f.onFocus;v==null||v(g),c()},y=f=>g=>{const v=f.onMouseEnter;v==null||v(g),c()},x=f=>g=>{const v=f.onMouseLeave;v==null||v(g),d()};return m.useEffect(()=>{if(!n&&o)return window.addEventListener("focus",d),window.addEventListener("blur",c),()=>{window.removeEventListener("focus",d),window.removeEventListener("blur",c)}},[n,o,d,c]),{getRootProps:(f={})=>{const g=b({},co(e),co(f));return b({role:"presentation"},f,g,{onBlur:h(g),onFocus:C(g),onMouseEnter:y(g),onMouseLeave:x(g)})},onClickAway:u}}function mv(e){return e.substring(2).toLowerCase()}function TL(e,t){return t.documentElement.clientWidth<e.clientX||t.documentElement.clientHeight<e.clientY}function ML(e){const{children:t,disableReactTree:n=!1,mouseEvent:r="onClick",onClickAway:o,touchEvent:i="onTouchEnd"}=e,a=m.useRef(!1),l=m.useRef(null),s=m.useRef(!1),u=m.useRef(!1);m.useEffect(()=>(setTimeout(()=>{s.current=!0},0),()=>{s.current=!1}),[]);const c=Ke(ko(t),l),d=wt(y=>{const x=u.current;u.current=!1;const S=Xe(l.current);if(!s.current||!l.current||"clientX"in y&&TL(y,S))return;if(a.current){a.current=!1;return}let f;y.composedPath?f=y.composedPath().indexOf(l.current)>-1:f=!S.documentElement.contains(y.target)||l.current.contains(y.target),!f&&(n||!x)&&o(y)}),h=y=>x=>{u.current=!0;const S=t.props[y];S&&S(x)},C={ref:c};return i!==!1&&(C[i]=h(i)),m.useEffect(()=>{if(i!==!1){const y=mv(i),x=Xe(l.current),S=()=>{a.current=!0};return x.addEventListener(y,d),x.addEventListener("touchmove",S),()=>{x.removeEventListener(y,d),x.removeEventListener("touchmove",S)}}},[d,i]),r!==!1&&(C[r]=h(r)),m.useEffect(()=>{if(r!==!1){const y=mv(r),x=Xe(l.current);return x.addEventListener(y,d),()=>{x.removeEventListener(y,d)}}},[d,r]),p.jsx(m.Fragment,{children:m.cloneElement(t,C)})}function OL(e){return le("MuiSnackbarContent",e)}ie("MuiSnackbarContent",["root","message","action"]);const IL=["action","className","message","role"],_L=e=>{const{classes:t}=e;return ce({root:["root"],action:["action"],message:["message"]},OL,t)},NL=W(Dn,{name:"MuiSnackbarContent",slot:"Root",overridesResolver:(e,t)=>t.root})(({theme:e})=>{const t=e.palette.mode==="light"?.8:.98,n=zP(e.palette.background.default,t);return b({},e.typography.body2,{color:e.vars?e.vars.palette.SnackbarContent.color:e.palette.getContrastText(n),backgroundColor:e.vars?e.vars.palette.SnackbarContent.bg:n,display:"flex",alignItems:"center",flexWrap:"wrap",padding:"6px 16px",borderRadius:(e.vars||e).shape.borderRadius,flexGrow:1,[e.breakpoints.up("sm")]:{flexGrow:"initial",minWidth:288}})}),LL=W("div",{name:"MuiSnackbarContent",slot:"Message",overridesResolver:(e,t)=>t.message})({padding:"8px 0"}),jL=W("div",{name:"MuiSnackbarContent",slot:"Action",overridesResolver:(e,t)=>t.action})({display:"flex",alignItems:"center",marginLeft:"auto",paddingLeft:16,marginRight:-8}),zL=m.forwardRef(function(t,n){const r=se({props:t,name:"MuiSnackbarContent"}),{action:o,className:i,message:a,role:l="alert"}=r,s=q(r,IL),u=r,c=_L(u);return p.jsxs(NL,b({role:l,square:!0,elevation:6,className:G(c.root,i),ownerState:u,ref:n},s,{children:[p.jsx(LL,{className:c.message,ownerState:u,children:a}),o?p.jsx(jL,{className:c.action,ownerState:u,children:o}):null]}))});function AL(e){return le("MuiSnackbar",e)}ie("MuiSnackbar",["root","anchorOriginTopCenter","anchorOriginBottomCenter","anchorOriginTopRight","anchorOriginBottomRight","anchorOriginTopLeft","anchorOriginBottomLeft"]);const FL=["onEnter","onExited"],BL=["action","anchorOrigin","autoHideDuration","children","className","ClickAwayListenerProps","ContentProps","disableWindowBlurListener","message","onBlur","onClose","onFocus","onMouseEnter","onMouseLeave","open","resumeHideDuration","TransitionComponent","transitionDuration","TransitionProps"],DL=e=>{const{classes:t,anchorOrigin:n}=e,r={root:["root",`anchorOrigin${z(n.vertical)}${z(n.horizontal)}`]};return ce(r,AL,t)},gv=W("div",{name:"MuiSnackbar",slot:"Root",overridesResolver:(e,t)=>{const{ownerState:n}=e;return[t.root,t[`anchorOrigin${z(n.anchorOrigin.vertical)}${z(n.anchorOrigin.horizontal)}`]]}})(({theme:e,ownerState:t})=>{const n={left:"50%",right:"auto",transform:"translateX(-50%)"};return b({zIndex:(e.vars||e).zIndex.snackbar,position:"fixed",display:"flex",left:8,right:8,justifyContent:"center",alignItems:"center"},t.anchorOrigin.vertical==="top"?{top:8}:{bottom:8},t.anchorOrigin.horizontal==="left"&&{justifyContent:"flex-start"},t.anchorOrigin.horizontal==="right"&&{justifyContent:"flex-end"},{[e.breakpoints.up("sm")]:b({},t.anchorOrigin.vertical==="top"?{top:24}:{bottom:24},t.anchorOrigin.horizontal==="center"&&n,t.anchorOrigin.horizontal==="left"&&{left:24,right:"auto"},t.anchorOrigin.horizontal==="right"&&{right:24,left:"auto"})})}),WL=m.forwardRef(function(t,n){const r=se({props:t,name:"MuiSnackbar"}),o=ji(),i={enter:o.transitions.duration.enteringScreen,exit:o.transitions.duration.leavingScreen},{action:a,anchorOrigin:{vertical:l,horizontal:s}={vertical:"bottom",horizontal:"left"},autoHideDuration:u=null,children:c,className:d,ClickAwayListenerProps:h,ContentProps:C,disableWindowBlurListener:y=!1,message:x,open:S,TransitionComponent:f=el,transitionDuration:g=i,TransitionProps:{onEnter:v,onExited:w}={}}=r,k=q(r.TransitionProps,FL),E=q(r,BL),P=b({},r,{anchorOrigin:{vertical:l,horizontal:s},autoHideDuration:u,disableWindowBlurListener:y,TransitionComponent:f,transitionDuration:g}),$=DL(P),{getRootProps:M,onClickAway:R}=$L(b({},P)),[I,D]=m.useState(!0),_=ft({elementType:gv,getSlotProps:M,externalForwardedProps:E,ownerState:P,additionalProps:{ref:n},className:[$.root,d]}),j=A=>{D(!0),w&&w(A)},F=(A,B)=>{D(!1),v&&v(A,B)};return!S&&I?null:p.jsx(ML,b({onClickAway:R},h,{children:p.jsx(gv,b({},_,{children:p.jsx(f,b({appear:!0,in:S,timeout:g,direction:l==="top"?"down":"up",onEnter:F,onExited:j},k,{children:c||p.jsx(zL,b({message:x,action:a},C))}))}))}))});var lh={},UL=Po;Object.defineProperty(lh,"__esModule",{value:!0});var C1=lh.default=void 0,HL=UL(Cl()),VL=p;C1=lh.default=(0,HL.default)((0,VL.jsx)("path",{d:"M17 3H5c-1.11 0-2 .9-2 2v14c0 1.1.89 2 2 2h14c1.1 0 2-.9 2-2V7zm-5 16c-1.66 0-3-1.34-3-3s1.34-3 3-3 3 1.34 3 3-1.34 3-3 3m3-10H5V5h10z"}),"Save");const KL=()=>{const[e,t]=m.useState({serverUrl:window.location.origin,refreshRate:1,maxItems:1e3,theme:"light"}),[n,r]=m.useState({open:!1,message:"",severity:"success"}),o=s=>{const{name:u,value:c}=s.target;t({...e,[u]:c})},i=s=>(u,c)=>{t({...e,[s]:c})},a=()=>{localStorage.setItem("pulseSettings",JSON.stringify(e)),r({open:!0,message:"Settings saved successfully!",severity:"success"})},l=()=>{r({...n,open:!1})};return p.jsxs(p.Fragment,{children:[p.jsxs(Dn,{elevation:2,sx:{p:2,mb:2},children:[p.jsx(De,{variant:"h6",component:"h2",gutterBottom:!0,children:"Dashboard Settings"}),p.jsxs(bt,{component:"form",sx:{mt:3},children:[p.jsx(qp,{fullWidth:!0,margin:"normal",id:"server-url",name:"serverUrl",label:"Server URL",value:e.serverUrl,onChange:o,helperText:"The URL of your Pulse server"}),p.jsxs(De,{id:"refresh-rate-slider",gutterBottom:!0,sx:{mt:2},children:["Refresh Rate: ",e.refreshRate," second",e.refreshRate!==1?"s":""]}),p.jsx(hv,{value:e.refreshRate,onChange:i("refreshRate"),"aria-labelledby":"refresh-rate-slider",valueLabelDisplay:"auto",step:1,marks:!0,min:1,max:60,sx:{mt:1,mb:3}}),p.jsxs(De,{id:"max-items-slider",gutterBottom:!0,children:["Maximum Items to Display: ",e.maxItems]}),p.jsx(hv,{value:e.maxItems,onChange:i("maxItems"),"aria-labelledby":"max-items-slider",valueLabelDisplay:"auto",step:100,marks:!0,min:100,max:5e3,sx:{mt:1,mb:3}}),p.jsxs(jn,{fullWidth:!0,margin:"normal",children:[p.jsx(Gn,{id:"theme-select-label",children:"Theme"}),p.jsxs(vn,{labelId:"theme-select-label",id:"theme",name:"theme",value:e.theme,label:"Theme",onChange:o,children:[p.jsx(ae,{value:"light",children:"Light"}),p.jsx(ae,{value:"dark",children:"Dark"}),p.jsx(ae,{value:"system",children:"System Default"})]})]}),p.jsx(bt,{sx:{mt:3,display:"flex",justifyContent:"flex-end"},children:p.jsx($a,{variant:"contained",color:"primary",startIcon:p.jsx(C1,{}),onClick:a,children:"Save Settings"})})]})]}),p.jsx(WL,{open:n.open,autoHideDuration:6e3,onClose:l,children:p.jsx(e1,{onClose:l,severity:n.severity,sx:{width:"100%"},children:n.message})})]})},YL=zp({palette:{primary:{main:"#3498db"},secondary:{main:"#2c3e50"},background:{default:"#f5f7fa"}}});function GL(){const[e,t]=m.useState({service:"",timeRange:"1h",logLevel:"",logSearch:""}),n=r=>{t({...e,...r})};return p.jsxs(FE,{theme:YL,children:[p.jsx(UE,{}),p.jsxs(bt,{sx:{display:"flex",flexDirection:"column",minHeight:"100vh"},children:[p.jsx(mR,{}),p.jsxs(bt,{sx:{display:"flex",flex:1,p:2},children:[p.jsx(rM,{filters:e,onFilterChange:n}),p.jsx(bt,{component:"main",sx:{flex:1,ml:2},children:p.jsxs(FC,{children:[p.jsx(Jr,{path:"/",element:p.jsx(zC,{to:"/overview",replace:!0})}),p.jsx(Jr,{path:"/overview",element:p.jsx(NM,{})}),p.jsx(Jr,{path:"/logs",element:p.jsx(rL,{filters:e})}),p.jsx(Jr,{path:"/metrics",element:p.jsx(oL,{filters:e})}),p.jsx(Jr,{path:"/traces",element:p.jsx(iL,{filters:e})}),p.jsx(Jr,{path:"/settings",element:p.jsx(KL,{})})]})})]}),p.jsx(fM,{})]})]})}const qL=document.getElementById("root"),XL=M0(qL);XL.render(p.jsx(zn.StrictMode,{children:p.jsx(WC,{children:p.jsx(GL,{})})}));
@@ -0,0 +1,21 @@
+// Package dashboard bundles the built dashboard into the Pulse binary.
+// Run npm run build in this directory to refresh dist before building Pulse.
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed dist
+var dist embed.FS
+
+// Files returns the built dashboard files, with index.html at the root
+func Files() fs.FS {
+	files, err := fs.Sub(dist, "dist")
+	if err != nil {
+		// dist is embedded above, so this cannot happen
+		panic(err)
+	}
+	return files
+}
@@ -2,8 +2,10 @@
  * API Client for interacting with the Pulse backend
  */
 
-// Base URL for API requests - we're using the proxy defined in vite.config.js
-const API_BASE = '';  // Empty base means same-origin requests
+// Base URL for API requests - the server injects its base path when Pulse runs
+// behind a reverse proxy; in development we're using the proxy defined in
+// vite.config.js
+const API_BASE = window.PULSE_BASE_PATH || '';  // Empty base means same-origin requests
 
 /**
  * Handles API errors and formats them consistently
@@ -5,6 +5,8 @@ import { resolve } from 'path';
 // https://vitejs.dev/config/
 export default defineConfig({
   plugins: [react()],
+  // Relative asset URLs, so the dashboard works under any server base path
+  base: './',
   server: {
     port: 3000,
     open: true,
@@ -191,6 +191,28 @@ func extractPulseTraceContext(r *http.Request) *TraceContext {
 	}
 }
 
+// ApplySampling records the sampling decision of the trace context on the
+// spans of its trace that carry none, so that processors such as the
+// SamplingProcessor respect decisions made upstream
+func (c *TraceContext) ApplySampling(spans ...*models.Span) {
+	if c == nil || c.TraceID == "" {
+		return
+	}
+
+	priority := "0"
+	if c.Sampled {
+		priority = "1"
+	}
+	for _, span := range spans {
+		if span.TraceID != c.TraceID {
+			continue
+		}
+		if _, ok := span.Tags[models.SamplingPriorityTag]; !ok {
+			span.AddTag(models.SamplingPriorityTag, priority)
+		}
+	}
+}
+
 // InjectTraceContext injects trace context into HTTP headers
 // It injects in multiple formats for maximum compatibility
 func InjectTraceContext(r *http.Request, ctx *TraceContext) {
@@ -1,8 +1,7 @@
 package api
 
 import (
-	"crypto/rand"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,8 +9,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
 )
 
 // QueryParams represents the parameters for querying data
@@ -37,23 +37,29 @@ type WSMessage struct {
 
 // generateID generates a unique ID for entries
 func generateID() string {
-	return fmt.Sprintf("%d-%s", time.Now().UnixNano(), strings.ReplaceAll(uuidMini(), "-", ""))
+	return models.GenerateID()
 }
 
-// uuidMini generates a simplified UUID
-func uuidMini() string {
-	b := make([]byte, 4)
-	_, err := rand.Read(b)
-	if err != nil {
-		log.Printf("Warning: failed to generate UUID: %v", err)
-		// Fallback to less secure method
-		return fmt.Sprintf("%x", time.Now().UnixNano())
-	}
-	return fmt.Sprintf("%x", b)
+// parseQueryParams extracts query parameters from an HTTP request. Without
+// time_range or since the query covers the default query window, unless
+// all_time=true asks for no lower bound. A time_range that isn't a positive
+// duration is an error rather than being ignored, which would widen the
+// query to the default window or to all time.
+func (s *Server) parseQueryParams(r *http.Request) (*models.QueryParams, error) {
+	return s.parseQuery(r, s.defaultQueryWindow)
+}
+
+// parseExportQueryParams extracts the query parameters of an export. Exports
+// are streamed backups, so without time_range or since they cover all time
+// rather than the default query window.
+func (s *Server) parseExportQueryParams(r *http.Request) (*models.QueryParams, error) {
+	return s.parseQuery(r, 0)
 }
 
-// parseQueryParams extracts query parameters from an HTTP request
-func parseQueryParams(r *http.Request) *models.QueryParams {
+// parseQuery extracts query parameters from an HTTP request, defaulting to
+// defaultWindow without a time range; a zero defaultWindow sets no lower
+// bound
+func (s *Server) parseQuery(r *http.Request, defaultWindow time.Duration) (*models.QueryParams, error) {
 	log.Printf("Parsing query parameters from request: %s", r.URL.String())
 
 	// Parse query parameters
@@ -68,6 +74,13 @@ func parseQueryParams(r *http.Request) *models.QueryParams {
 		log.Printf("Filtering by service: %s", service)
 	}
 
+	// Get metric name filter (for metrics)
+	name := r.URL.Query().Get("name")
+	if name != "" {
+		query.Name = name
+		log.Printf("Filtering by name: %s", name)
+	}
+
 	// Get level filter (for logs)
 	level := r.URL.Query().Get("level")
 	if level != "" {
@@ -75,6 +88,15 @@ func parseQueryParams(r *http.Request) *models.QueryParams {
 		log.Printf("Filtering by level: %s", level)
 	}
 
+	// Get minimum level filter (for logs); WARN is accepted for WARNING
+	if minLevel := strings.ToUpper(r.URL.Query().Get("min_level")); minLevel != "" {
+		if minLevel == "WARN" {
+			minLevel = string(models.LogLevelWarning)
+		}
+		query.MinLevel = minLevel
+		log.Printf("Filtering by minimum level: %s", minLevel)
+	}
+
 	// Get trace ID filter
 	traceID := r.URL.Query().Get("trace_id")
 	if traceID != "" {
@@ -82,6 +104,18 @@ func parseQueryParams(r *http.Request) *models.QueryParams {
 		log.Printf("Filtering by trace ID: %s", traceID)
 	}
 
+	// Get span ID filter (for spans)
+	if spanID := r.URL.Query().Get("span_id"); spanID != "" {
+		query.SpanID = spanID
+	}
+
+	// Get span name filter (for traces)
+	containsSpan := r.URL.Query().Get("contains_span")
+	if containsSpan != "" {
+		query.ContainsSpan = containsSpan
+		log.Printf("Filtering by traces containing span: %s", containsSpan)
+	}
+
 	// Get search filter
 	search := r.URL.Query().Get("search")
 	if search != "" {
@@ -99,6 +133,16 @@ func parseQueryParams(r *http.Request) *models.QueryParams {
 		}
 	}
 
+	// Get duration range in milliseconds (for traces and spans)
+	if minDuration, err := strconv.ParseInt(r.URL.Query().Get("min_duration_ms"), 10, 64); err == nil && minDuration >= 0 {
+		query.MinDuration = minDuration
+		log.Printf("Filtering by minimum duration: %dms", minDuration)
+	}
+	if maxDuration, err := strconv.ParseInt(r.URL.Query().Get("max_duration_ms"), 10, 64); err == nil && maxDuration >= 0 {
+		query.MaxDuration = maxDuration
+		log.Printf("Filtering by maximum duration: %dms", maxDuration)
+	}
+
 	// Get time range
 	timeRange := r.URL.Query().Get("time_range")
 	if timeRange != "" {
@@ -106,16 +150,17 @@ func parseQueryParams(r *http.Request) *models.QueryParams {
 
 		// Parse the time range (e.g., "1h", "30m", "1d")
 		duration, err := parseDuration(timeRange)
-		if err == nil {
-			query.Since = time.Now().Add(-duration)
-			log.Printf("Calculated since time: %s", query.Since)
-		} else {
-			log.Printf("Error parsing time range: %v", err)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("Invalid time_range %q: must be a positive duration such as 30m, 1h or 7d", timeRange)
 		}
-	} else {
-		// Default to last hour if no time range specified
-		query.Since = time.Now().Add(-1 * time.Hour)
-		log.Printf("Using default time range (1h), since: %s", query.Since)
+		query.Since = time.Now().Add(-duration)
+		log.Printf("Calculated since time: %s", query.Since)
+	} else if r.URL.Query().Get("all_time") == "true" {
+		log.Printf("Querying all time without a default time range")
+	} else if defaultWindow > 0 {
+		// Default to the configured window if no time range specified
+		query.Since = time.Now().Add(-defaultWindow)
+		log.Printf("Using default time range (%s), since: %s", defaultWindow, query.Since)
 	}
 
 	// Get explicit since time
@@ -168,6 +213,22 @@ func parseQueryParams(r *http.Request) *models.QueryParams {
 		}
 	}
 
+	// Get cursor for pagination (for logs)
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		query.Cursor = cursor
+		log.Printf("Using cursor: %s", cursor)
+	}
+
+	// Get tag keys that must be present or absent
+	if hasTags := r.URL.Query()["has_tag"]; len(hasTags) > 0 {
+		query.HasTags = hasTags
+		log.Printf("Filtering by tags present: %v", hasTags)
+	}
+	if missingTags := r.URL.Query()["missing_tag"]; len(missingTags) > 0 {
+		query.MissingTags = missingTags
+		log.Printf("Filtering by tags missing: %v", missingTags)
+	}
+
 	// Parse additional filters
 	for key, values := range r.URL.Query() {
 		if strings.HasPrefix(key, "filter.") && len(values) > 0 {
@@ -181,7 +242,20 @@ func parseQueryParams(r *http.Request) *models.QueryParams {
 	}
 
 	log.Printf("Final query parameters: %+v", query)
-	return query
+	return query, nil
+}
+
+// checkQueryCost rejects queries that are likely to scan whole tables: those
+// with no time bound, trace ID or span ID and a limit that is unset or above the
+// configured maximum.
+func (s *Server) checkQueryCost(query *models.QueryParams) error {
+	if !query.Since.IsZero() || !query.Until.IsZero() || query.TraceID != "" || query.SpanID != "" {
+		return nil
+	}
+	if query.Limit > 0 && query.Limit <= s.maxUnboundedLimit {
+		return nil
+	}
+	return fmt.Errorf("query is unbounded: add a time range (time_range, since or until) or a limit of at most %d", s.maxUnboundedLimit)
 }
 
 // parseDuration parses duration strings like "5m", "1h", "7d"
@@ -199,7 +273,9 @@ func parseDuration(s string) (time.Duration, error) {
 	return time.ParseDuration(s)
 }
 
-// apiServicesHandler returns a handler for querying available services
+// apiServicesHandler returns a handler for querying available services, the
+// most recently active first. Without a limit the configured number of
+// services is listed, and limits above the configured maximum are lowered.
 func (s *Server) apiServicesHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -207,17 +283,92 @@ func (s *Server) apiServicesHandler() http.HandlerFunc {
 			return
 		}
 
+		// List the most recently active services first, at most the
+		// configured maximum
+		limit := s.serviceListLimit
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			var err error
+			if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 {
+				http.Error(w, "Invalid limit: must be a positive integer", http.StatusBadRequest)
+				return
+			}
+		}
+		if limit > s.maxServiceListLimit {
+			limit = s.maxServiceListLimit
+		}
+
 		// Query available services from storage
-		services, err := s.processor.GetServices()
+		start := time.Now()
+		services, err := s.processor.GetActiveServices(limit)
+		s.observeQuery("services", start)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error querying services: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		// Send response
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(services)
+		writeResponse(w, r, http.StatusOK, services)
+	}
+}
+
+// apiServiceHandler returns a handler for /api/services/{name}. DELETE
+// purges all logs, metrics and spans stored for the service.
+func (s *Server) apiServiceHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		service := strings.TrimPrefix(r.URL.Path, "/api/services/")
+		if service == "" || strings.Contains(service, "/") {
+			http.Error(w, "Service name is required", http.StatusBadRequest)
+			return
+		}
+
+		start := time.Now()
+		deleted, err := s.processor.DeleteByService(service)
+		s.observeQuery("delete service", start)
+		if err != nil {
+			log.Printf("Error deleting service %s: %v", service, err)
+			http.Error(w, fmt.Sprintf("Error deleting service: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Deleted %d rows of service %s", deleted, service)
+
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"status":  "ok",
+			"service": service,
+			"deleted": deleted,
+		})
+	}
+}
+
+// clearHandler returns a handler that deletes all stored telemetry
+func (s *Server) clearHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		start := time.Now()
+		deleted, err := s.processor.ClearAll()
+		s.observeQuery("clear", start)
+		if err != nil {
+			log.Printf("Error clearing data: %v", err)
+			http.Error(w, fmt.Sprintf("Error clearing data: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Cleared %d rows of stored data", deleted)
+
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"status":  "ok",
+			"deleted": deleted,
+			"message": fmt.Sprintf("Cleared %d rows", deleted),
+		})
 	}
 }
 
@@ -230,19 +381,23 @@ func (s *Server) apiStatsHandler() http.HandlerFunc {
 		}
 
 		// Parse query parameters
-		query := parseQueryParams(r)
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Query stats from storage
+		start := time.Now()
 		stats, err := s.processor.GetStats(query)
+		s.observeQuery("stats", start)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error querying stats: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		// Send response
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(stats)
+		writeResponse(w, r, http.StatusOK, stats)
 	}
 }
 
@@ -255,19 +410,70 @@ func (s *Server) apiMetricsHandler() http.HandlerFunc {
 		}
 
 		// Parse query parameters
-		query := parseQueryParams(r)
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Reject queries that would scan everything
+		if err := s.checkQueryCost(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Query metrics from storage
+		start := time.Now()
 		metrics, err := s.processor.QueryMetrics(query)
+		s.observeQuery("metrics", start)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error querying metrics: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		// Send response
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(metrics)
+		writeResponse(w, r, http.StatusOK, metrics)
+	}
+}
+
+// apiHistogramsHandler returns a handler for querying histogram metrics with
+// their buckets, sum, count and percentiles, oldest first
+func (s *Server) apiHistogramsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Parse query parameters
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Reject queries that would scan everything
+		if err := s.checkQueryCost(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Query histograms from storage
+		start := time.Now()
+		histograms, err := s.processor.QueryHistograms(query)
+		s.observeQuery("histograms", start)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error querying histograms: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if histograms == nil {
+			histograms = []*models.HistogramMetric{}
+		}
+
+		// Send response
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"histograms": histograms,
+		})
 	}
 }
 
@@ -280,24 +486,36 @@ func (s *Server) apiTracesHandler() http.HandlerFunc {
 		}
 
 		// Parse query parameters
-		query := parseQueryParams(r)
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Reject queries that would scan everything
+		if err := s.checkQueryCost(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Query traces from storage
+		start := time.Now()
 		traces, err := s.processor.QueryTraces(query)
+		s.observeQuery("traces", start)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error querying traces: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		// Send response
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(traces)
+		writeResponse(w, r, http.StatusOK, traces)
 	}
 }
 
-// apiSpansHandler returns a handler for querying spans
-func (s *Server) apiSpansHandler() http.HandlerFunc {
+// apiTraceIDsHandler returns a handler listing just the IDs and root start
+// times of the traces in a window, for paging through traces cheaply and
+// fetching each one from /api/traces/{id}
+func (s *Server) apiTraceIDsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -305,292 +523,447 @@ func (s *Server) apiSpansHandler() http.HandlerFunc {
 		}
 
 		// Parse query parameters
-		query := parseQueryParams(r)
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		// Query spans from storage
-		spans, err := s.processor.QuerySpans(query)
+		// Reject queries that would scan everything
+		if err := s.checkQueryCost(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Query trace IDs from storage
+		start := time.Now()
+		ids, err := s.processor.QueryTraceIDs(query)
+		s.observeQuery("trace IDs", start)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error querying spans: %v", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Error querying trace IDs: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		// Send response
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(spans)
+		writeResponse(w, r, http.StatusOK, ids)
 	}
 }
 
-// wsLogsHandler handles WebSocket connections for real-time log updates
-func (s *Server) wsLogsHandler() http.HandlerFunc {
+// defaultSlowTracePercentile is the percentile used by
+// /api/traces/slow-percentile when none is given
+const defaultSlowTracePercentile = 95.0
+
+// apiSlowTracesHandler returns a handler for querying the traces slower than
+// a percentile of the matching traces' durations
+func (s *Server) apiSlowTracesHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Upgrade HTTP connection to WebSocket
-		conn, err := s.wsUpgrader.Upgrade(w, r, nil)
-		if err != nil {
-			log.Printf("Error upgrading to WebSocket: %v", err)
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		// Register connection
-		s.connLock.Lock()
-		s.activeConns[conn] = true
-		s.connLock.Unlock()
-
-		// Schedule cleanup when connection closes
-		defer func() {
-			conn.Close()
-			s.connLock.Lock()
-			delete(s.activeConns, conn)
-			s.connLock.Unlock()
-		}()
+		percentile := defaultSlowTracePercentile
+		if value := r.URL.Query().Get("percentile"); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 || parsed >= 100 {
+				http.Error(w, "percentile must be a number between 0 and 100", http.StatusBadRequest)
+				return
+			}
+			percentile = parsed
+		}
 
 		// Parse query parameters
-		query := parseQueryParams(r)
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		// Start real-time log streaming
-		s.streamLogs(conn, query)
+		// Reject queries that would scan everything
+		if err := s.checkQueryCost(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Query slow traces from storage
+		start := time.Now()
+		traces, err := s.processor.QuerySlowTraces(query, percentile)
+		s.observeQuery("slow_traces", start)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error querying slow traces: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// Send response
+		writeResponse(w, r, http.StatusOK, traces)
 	}
 }
 
-// wsMetricsHandler handles WebSocket connections for real-time metric updates
-func (s *Server) wsMetricsHandler() http.HandlerFunc {
+// defaultTailSamplingRate is the share of ordinary traces the tail sampling
+// report keeps when no rate is given
+const defaultTailSamplingRate = 0.1
+
+// traceSamplePageSize is how many trace IDs the tail sampling report reads
+// at a time
+const traceSamplePageSize = 500
+
+// apiTailSamplingReportHandler returns a handler reporting how many of the
+// traces in a window tail-based sampling would keep: every failed trace,
+// those above a latency percentile and a share of the rest. Nothing is
+// dropped; the report only sizes the impact of the policy.
+func (s *Server) apiTailSamplingReportHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Upgrade HTTP connection to WebSocket
-		conn, err := s.wsUpgrader.Upgrade(w, r, nil)
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		percentile := processor.DefaultTailSamplingPercentile
+		if value := r.URL.Query().Get("percentile"); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 || parsed >= 100 {
+				http.Error(w, "percentile must be a number between 0 and 100", http.StatusBadRequest)
+				return
+			}
+			percentile = parsed
+		}
+		rate := defaultTailSamplingRate
+		if value := r.URL.Query().Get("rate"); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				http.Error(w, "rate must be a number between 0 and 1", http.StatusBadRequest)
+				return
+			}
+			rate = parsed
+		}
+
+		// Every trace of the window is read, so a window is required
+		query, err := s.parseQueryParams(r)
 		if err != nil {
-			log.Printf("Error upgrading to WebSocket: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if query.Since.IsZero() && query.Until.IsZero() {
+			http.Error(w, "the sampling report needs a time range (time_range, since or until)", http.StatusBadRequest)
+			return
+		}
+		if query.Until.IsZero() {
+			// Pin the window so that new traces don't shift the pages
+			query.Until = time.Now()
+		}
 
-		// Register connection
-		s.connLock.Lock()
-		s.activeConns[conn] = true
-		s.connLock.Unlock()
+		start := time.Now()
+		samples, err := s.traceSamples(query)
+		s.observeQuery("tail_sampling_report", start)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error querying traces: %v", err), http.StatusInternalServerError)
+			return
+		}
 
-		// Schedule cleanup when connection closes
-		defer func() {
-			conn.Close()
-			s.connLock.Lock()
-			delete(s.activeConns, conn)
-			s.connLock.Unlock()
-		}()
+		writeResponse(w, r, http.StatusOK, processor.ReportTailSampling(samples, percentile, rate))
+	}
+}
 
-		// Parse query parameters
-		query := parseQueryParams(r)
+// traceSamples reads the duration and failure of every trace matching the
+// service and time range of query, paging through them. Storage aggregates
+// them over the spans of each page, so no trace is loaded.
+func (s *Server) traceSamples(query *models.QueryParams) ([]processor.TraceSample, error) {
+	page := *query
+	page.Limit = traceSamplePageSize
+	page.Offset = 0
 
-		// Start real-time metric streaming
-		s.streamMetrics(conn, query)
+	var samples []processor.TraceSample
+	for {
+		result, err := s.processor.QueryTraceSamples(&page)
+		if err != nil {
+			return nil, err
+		}
+		records, _ := result["traces"].([]map[string]interface{})
+		for _, record := range records {
+			id, _ := record["id"].(string)
+			duration, _ := record["duration"].(int64)
+			failed, _ := record["error"].(bool)
+			samples = append(samples, processor.TraceSample{
+				ID:         id,
+				DurationMs: duration,
+				Error:      failed,
+			})
+		}
+		if len(records) < page.Limit {
+			return samples, nil
+		}
+		page.Offset += page.Limit
 	}
 }
 
-// wsTracesHandler handles WebSocket connections for real-time trace updates
-func (s *Server) wsTracesHandler() http.HandlerFunc {
+// maxAnalyzedTraceSpans caps the spans of a trace loaded in memory for its
+// detail or analysis
+const maxAnalyzedTraceSpans = 10000
+
+// apiTraceHandler returns a handler for the routes of a single trace under
+// /api/traces/{id}. GET /api/traces/{id} returns the trace with all of its
+// spans, parents before their children, and GET /api/traces/{id}/issues
+// reports likely performance problems in the trace, such as n+1 query
+// patterns. Traces of more than maxAnalyzedTraceSpans spans aren't loaded;
+// their spans can be paged through /api/spans?trace_id={id} instead.
+func (s *Server) apiTraceHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Upgrade HTTP connection to WebSocket
-		conn, err := s.wsUpgrader.Upgrade(w, r, nil)
-		if err != nil {
-			log.Printf("Error upgrading to WebSocket: %v", err)
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		// Register connection
-		s.connLock.Lock()
-		s.activeConns[conn] = true
-		s.connLock.Unlock()
+		traceID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/traces/"), "/")
+		if traceID == "" || (action != "" && action != "issues") {
+			http.NotFound(w, r)
+			return
+		}
 
-		// Schedule cleanup when connection closes
-		defer func() {
-			conn.Close()
-			s.connLock.Lock()
-			delete(s.activeConns, conn)
-			s.connLock.Unlock()
-		}()
+		opts := processor.TraceIssueOptions{}
+		if value := r.URL.Query().Get("min_repeats"); value != "" && action == "issues" {
+			repeats, err := strconv.Atoi(value)
+			if err != nil || repeats < 2 {
+				http.Error(w, "min_repeats must be an integer of at least 2", http.StatusBadRequest)
+				return
+			}
+			opts.NPlusOneThreshold = repeats
+		}
 
-		// Parse query parameters
-		query := parseQueryParams(r)
+		// Count the spans of the trace before loading them all
+		start := time.Now()
+		result, err := s.processor.QuerySpans(&models.QueryParams{TraceID: traceID, Limit: 1})
+		var trace *models.Trace
+		if err == nil {
+			pagination, _ := result["pagination"].(map[string]interface{})
+			if spans, _ := pagination["total_items"].(int); spans > maxAnalyzedTraceSpans {
+				http.Error(w, fmt.Sprintf("Trace has %d spans, more than the %d that can be loaded at once: page through /api/spans?trace_id=%s instead", spans, maxAnalyzedTraceSpans, traceID), http.StatusUnprocessableEntity)
+				return
+			}
+			trace, err = s.processor.GetTrace(traceID)
+		}
+		if action == "issues" {
+			s.observeQuery("trace_issues", start)
+		} else {
+			s.observeQuery("trace", start)
+		}
+		if errors.Is(err, storage.ErrTraceNotFound) {
+			http.Error(w, "Trace not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error loading trace: %v", err), http.StatusInternalServerError)
+			return
+		}
 
-		// Start real-time trace streaming
-		s.streamTraces(conn, query)
+		// Send response
+		if action == "" {
+			writeResponse(w, r, http.StatusOK, trace)
+			return
+		}
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"trace_id": traceID,
+			"spans":    len(trace.Spans),
+			"issues":   processor.DetectTraceIssues(trace.Spans, opts),
+		})
 	}
 }
 
-// streamLogs streams logs to a WebSocket connection
-func (s *Server) streamLogs(conn *websocket.Conn, query *models.QueryParams) {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	log.Printf("Starting log streaming with query: %+v", query)
+// apiSpanHandler returns a handler for the chain of ancestors of one span,
+// its parent first and the root last: /api/spans/{id}/ancestors
+func (s *Server) apiSpanHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-	// Read control messages from client
-	go func() {
-		for {
-			_, _, err := conn.ReadMessage()
-			if err != nil {
-				return // Connection closed or error
-			}
+		spanID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/spans/"), "/")
+		if spanID == "" || action != "ancestors" {
+			http.NotFound(w, r)
+			return
 		}
-	}()
 
-	// Initial query
-	logs, err := s.processor.QueryLogs(query)
-	if err == nil {
-		log.Printf("Initial query returned %d logs", len(logs))
-		message := WSMessage{
-			Type:    "logs",
-			Payload: logs,
+		start := time.Now()
+		ancestors, err := s.processor.GetSpanAncestors(spanID)
+		s.observeQuery("span_ancestors", start)
+		if errors.Is(err, storage.ErrSpanNotFound) {
+			http.Error(w, "Span not found", http.StatusNotFound)
+			return
 		}
-		if err := conn.WriteJSON(message); err != nil {
-			log.Printf("Error sending initial logs: %v", err)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error loading span ancestors: %v", err), http.StatusInternalServerError)
 			return
 		}
-	} else {
-		log.Printf("Error in initial logs query: %v", err)
+
+		// Send response
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"span_id":   spanID,
+			"ancestors": ancestors,
+		})
 	}
+}
 
-	// Send updates
-	for {
-		select {
-		case <-ticker.C:
-			// Update since time to get only new logs
-			query.Since = time.Now().Add(-2 * time.Second)
-			query.Until = time.Now()
+// apiSpansHandler returns a handler for querying spans
+func (s *Server) apiSpansHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-			logs, err := s.processor.QueryLogs(query)
-			if err != nil {
-				log.Printf("Error streaming logs: %v", err)
-				continue
-			}
+		// Parse query parameters
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-			log.Printf("Found %d new logs", len(logs))
-
-			if len(logs) > 0 {
-				message := WSMessage{
-					Type:    "logs",
-					Payload: logs,
-				}
-				if err := conn.WriteJSON(message); err != nil {
-					log.Printf("Error sending logs: %v", err)
-					return
-				}
-				log.Printf("Sent %d logs to client", len(logs))
-			}
+		// Reject queries that would scan everything
+		if err := s.checkQueryCost(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
+
+		// Query spans from storage
+		start := time.Now()
+		spans, err := s.processor.QuerySpans(query)
+		s.observeQuery("spans", start)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error querying spans: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// Send response
+		writeResponse(w, r, http.StatusOK, spans)
 	}
 }
 
-// streamMetrics streams metrics to a WebSocket connection
-func (s *Server) streamMetrics(conn *websocket.Conn, query *models.QueryParams) {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+// apiSpanErrorsByTypeHandler returns a handler for counting span errors by error.type
+func (s *Server) apiSpanErrorsByTypeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-	log.Printf("Starting metrics streaming with query: %+v", query)
+		// Parse query parameters
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-	// Read control messages from client
-	go func() {
-		for {
-			_, _, err := conn.ReadMessage()
-			if err != nil {
-				return // Connection closed or error
-			}
+		// Count span errors from storage
+		start := time.Now()
+		counts, err := s.processor.CountSpanErrorsByType(query)
+		s.observeQuery("span_errors_by_type", start)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error counting span errors: %v", err), http.StatusInternalServerError)
+			return
 		}
-	}()
 
-	// Initial query
-	metrics, err := s.processor.QueryMetrics(query)
-	if err == nil {
-		log.Printf("Initial query returned %d metrics", len(metrics))
-		message := WSMessage{
-			Type:    "metrics",
-			Payload: metrics,
+		// Send response
+		writeResponse(w, r, http.StatusOK, counts)
+	}
+}
+
+// wsLogsHandler handles WebSocket connections for real-time log updates
+func (s *Server) wsLogsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Upgrade HTTP connection to WebSocket
+		conn, err := s.wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("Error upgrading to WebSocket: %v", err)
+			return
 		}
-		if err := conn.WriteJSON(message); err != nil {
-			log.Printf("Error sending initial metrics: %v", err)
+
+		// Register connection and schedule cleanup when it closes
+		ctx, untrack := s.trackConn("logs", conn)
+		defer untrack()
+
+		// Parse query parameters
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-	} else {
-		log.Printf("Error in initial metrics query: %v", err)
+
+		// Start real-time log streaming
+		s.streamLogs(ctx, conn, query)
 	}
+}
 
-	// Send updates
-	for {
-		select {
-		case <-ticker.C:
-			// Update since time to get only new metrics
-			query.Since = time.Now().Add(-2 * time.Second)
-			query.Until = time.Now()
+// wsMetricsHandler handles WebSocket connections for real-time metric updates
+func (s *Server) wsMetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Upgrade HTTP connection to WebSocket
+		conn, err := s.wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("Error upgrading to WebSocket: %v", err)
+			return
+		}
 
-			metrics, err := s.processor.QueryMetrics(query)
-			if err != nil {
-				log.Printf("Error streaming metrics: %v", err)
-				continue
-			}
+		// Register connection and schedule cleanup when it closes
+		ctx, untrack := s.trackConn("metrics", conn)
+		defer untrack()
 
-			log.Printf("Found %d new metrics", len(metrics))
-
-			if len(metrics) > 0 {
-				message := WSMessage{
-					Type:    "metrics",
-					Payload: metrics,
-				}
-				if err := conn.WriteJSON(message); err != nil {
-					log.Printf("Error sending metrics: %v", err)
-					return
-				}
-				log.Printf("Sent %d metrics to client", len(metrics))
-			}
+		// Parse query parameters
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
+
+		// Start real-time metric streaming
+		s.streamMetrics(ctx, conn, query)
 	}
 }
 
-// streamTraces streams traces to a WebSocket connection
-func (s *Server) streamTraces(conn *websocket.Conn, query *models.QueryParams) {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	// Read control messages from client
-	go func() {
-		for {
-			_, _, err := conn.ReadMessage()
-			if err != nil {
-				return // Connection closed or error
-			}
+// wsTracesHandler handles WebSocket connections for real-time trace updates
+func (s *Server) wsTracesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Upgrade HTTP connection to WebSocket
+		conn, err := s.wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("Error upgrading to WebSocket: %v", err)
+			return
 		}
-	}()
 
-	// Initial query
-	traces, err := s.processor.QueryTraces(query)
-	if err == nil {
-		message := WSMessage{
-			Type:    "traces",
-			Payload: traces,
+		// Register connection and schedule cleanup when it closes
+		ctx, untrack := s.trackConn("traces", conn)
+		defer untrack()
+
+		// Parse query parameters
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		conn.WriteJSON(message)
-	}
 
-	// Send updates
-	for {
-		select {
-		case <-ticker.C:
-			// Update since time to get only new traces
-			query.Since = time.Now().Add(-2 * time.Second)
-			query.Until = time.Now()
+		// Start real-time trace streaming
+		s.streamTraces(ctx, conn, query)
+	}
+}
 
-			traces, err := s.processor.QueryTraces(query)
-			if err != nil {
-				log.Printf("Error streaming traces: %v", err)
-				continue
-			}
+// apiConnectionsHandler returns a handler reporting the open WebSocket
+// connections of each stream kind and their total
+func (s *Server) apiConnectionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-			if len(traces) > 0 {
-				message := WSMessage{
-					Type:    "traces",
-					Payload: traces,
-				}
-				if err := conn.WriteJSON(message); err != nil {
-					log.Printf("Error sending traces: %v", err)
-					return
-				}
-			}
+		counts := s.connectionCounts()
+		total := 0
+		for _, count := range counts {
+			total += count
 		}
+
+		// Send response
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"connections": counts,
+			"total":       total,
+		})
 	}
 }
@@ -0,0 +1,668 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+func TestQueryCostGuard(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0, WithMaxUnboundedLimit(500))
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"default window is bounded", "/api/logs", http.StatusOK},
+		{"explicit since with huge limit", "/api/logs?since=2024-01-01T00:00:00Z&limit=100000", http.StatusOK},
+		{"unbounded with small limit", "/api/logs?all_time=true&limit=100", http.StatusOK},
+		{"unbounded with huge limit", "/api/logs?all_time=true&limit=100000", http.StatusBadRequest},
+		{"unbounded by trace ID", "/api/logs?all_time=true&trace_id=abc&limit=100000", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		server.apiLogsHandler()(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, rec.Code, rec.Body.String())
+		}
+		if tt.status == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "time range") {
+			t.Errorf("%s: expected guidance in error, got %q", tt.name, rec.Body.String())
+		}
+	}
+}
+
+// deleteProcessor records the services deleted through it
+type deleteProcessor struct {
+	stubProcessor
+	deleted []string
+}
+
+func (p *deleteProcessor) DeleteByService(service string) (int64, error) {
+	p.deleted = append(p.deleted, service)
+	return 42, nil
+}
+
+func TestAPIServicesHandler_Limit(t *testing.T) {
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	now := time.Now().UTC()
+	for i, service := range []string{"api", "billing", "checkout", "worker"} {
+		entry := models.NewLogEntry(service, "hello", models.LogLevelInfo)
+		entry.Timestamp = now.Add(time.Duration(i-10) * time.Minute)
+		store.SaveLog(entry)
+	}
+
+	for path, expected := range map[string][]string{
+		"/api/services":         {"worker", "checkout", "billing", "api"},
+		"/api/services?limit=2": {"worker", "checkout"},
+		"/api/services?limit=9": {"worker", "checkout", "billing", "api"},
+	} {
+		rec := httptest.NewRecorder()
+		server.apiServicesHandler()(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+		var services []string
+		if err := json.NewDecoder(rec.Body).Decode(&services); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", path, err)
+		}
+		if !reflect.DeepEqual(services, expected) {
+			t.Errorf("%s: expected %v, got %v", path, expected, services)
+		}
+	}
+
+	for _, limit := range []string{"0", "-1", "many"} {
+		rec := httptest.NewRecorder()
+		server.apiServicesHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/services?limit="+limit, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("limit %s: expected status 400, got %d", limit, rec.Code)
+		}
+	}
+
+	// The default and maximum are capped by configuration
+	server = NewServer(processor.NewStorageProcessor(store), 0, WithServiceListLimits(2, 3))
+	for path, expected := range map[string][]string{
+		"/api/services":         {"worker", "checkout"},
+		"/api/services?limit=9": {"worker", "checkout", "billing"},
+	} {
+		rec := httptest.NewRecorder()
+		server.apiServicesHandler()(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var services []string
+		if err := json.NewDecoder(rec.Body).Decode(&services); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", path, err)
+		}
+		if !reflect.DeepEqual(services, expected) {
+			t.Errorf("%s: expected %v, got %v", path, expected, services)
+		}
+	}
+}
+
+func TestAPISpanHandler_Ancestors(t *testing.T) {
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	root := models.NewSpan("GET /checkout", "api", "trace-1")
+	charge := models.NewSpan("charge", "payments", "trace-1").SetParent(root.ID)
+	query := models.NewSpan("INSERT payments", "db", "trace-1").SetParent(charge.ID)
+	for _, span := range []*models.Span{query, charge, root} {
+		store.SaveSpan(span)
+	}
+
+	rec := httptest.NewRecorder()
+	server.routes["/api/spans/"](rec, httptest.NewRequest(http.MethodGet, "/api/spans/"+query.ID+"/ancestors", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		SpanID    string         `json:"span_id"`
+		Ancestors []*models.Span `json:"ancestors"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.SpanID != query.ID {
+		t.Errorf("expected span_id %s, got %s", query.ID, response.SpanID)
+	}
+	if len(response.Ancestors) != 2 || response.Ancestors[0].ID != charge.ID || response.Ancestors[1].ID != root.ID {
+		t.Errorf("expected ancestors charge, root, got %d ancestors", len(response.Ancestors))
+	}
+
+	for path, expected := range map[string]int{
+		"/api/spans/missing/ancestors":       http.StatusNotFound,
+		"/api/spans/" + query.ID:             http.StatusNotFound,
+		"/api/spans/" + query.ID + "/parent": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		server.routes["/api/spans/"](rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != expected {
+			t.Errorf("%s: expected status %d, got %d", path, expected, rec.Code)
+		}
+	}
+}
+
+func TestAPIServiceHandler_Delete(t *testing.T) {
+	proc := &deleteProcessor{}
+	server := NewServer(proc, 0)
+
+	rec := httptest.NewRecorder()
+	server.apiServiceHandler()(rec, httptest.NewRequest(http.MethodDelete, "/api/services/legacy-billing", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["service"] != "legacy-billing" || resp["deleted"] != float64(42) {
+		t.Errorf("unexpected response: %v", resp)
+	}
+	if len(proc.deleted) != 1 || proc.deleted[0] != "legacy-billing" {
+		t.Errorf("expected legacy-billing to be deleted, got %v", proc.deleted)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/api/services/legacy-billing", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/api/services/", http.StatusBadRequest},
+		{http.MethodDelete, "/api/services/a/b", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		server.apiServiceHandler()(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, rec.Code)
+		}
+	}
+	if len(proc.deleted) != 1 {
+		t.Errorf("expected no further deletes, got %v", proc.deleted)
+	}
+}
+
+// clearProcessor reports a fixed number of cleared rows
+type clearProcessor struct {
+	stubProcessor
+	cleared int
+}
+
+func (p *clearProcessor) ClearAll() (int64, error) {
+	p.cleared++
+	return 17, nil
+}
+
+func TestClearHandler(t *testing.T) {
+	proc := &clearProcessor{}
+	server := NewServer(proc, 0)
+
+	rec := httptest.NewRecorder()
+	server.clearHandler()(rec, httptest.NewRequest(http.MethodPost, "/api/clear", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["deleted"] != float64(17) || resp["message"] != "Cleared 17 rows" {
+		t.Errorf("unexpected response: %v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	server.clearHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/clear", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for GET, got %d", rec.Code)
+	}
+	if proc.cleared != 1 {
+		t.Errorf("expected storage to be cleared once, got %d", proc.cleared)
+	}
+}
+
+// parseQuery parses the query parameters of a request, failing the test if
+// they are invalid
+func parseQuery(t *testing.T, server *Server, r *http.Request) *models.QueryParams {
+	t.Helper()
+
+	query, err := server.parseQueryParams(r)
+	if err != nil {
+		t.Fatalf("failed to parse query parameters: %v", err)
+	}
+	return query
+}
+
+func TestParseQueryParams_MinLevel(t *testing.T) {
+	tests := []struct {
+		url      string
+		minLevel string
+	}{
+		{"/api/logs?min_level=error", "ERROR"},
+		{"/api/logs?min_level=warn", "WARNING"},
+		{"/api/logs?min_level=WARNING", "WARNING"},
+		{"/api/logs", ""},
+	}
+
+	server := NewServer(&stubProcessor{}, 0)
+	for _, tt := range tests {
+		query := parseQuery(t, server, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if query.MinLevel != tt.minLevel {
+			t.Errorf("%s: expected min level %q, got %q", tt.url, tt.minLevel, query.MinLevel)
+		}
+	}
+}
+
+func TestParseQueryParams_Duration(t *testing.T) {
+	tests := []struct {
+		url      string
+		min, max int64
+	}{
+		{"/api/traces?min_duration_ms=500", 500, 0},
+		{"/api/spans?min_duration_ms=10&max_duration_ms=250", 10, 250},
+		{"/api/spans?min_duration_ms=-5&max_duration_ms=slow", 0, 0},
+		{"/api/traces", 0, 0},
+	}
+
+	server := NewServer(&stubProcessor{}, 0)
+	for _, tt := range tests {
+		query := parseQuery(t, server, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if query.MinDuration != tt.min || query.MaxDuration != tt.max {
+			t.Errorf("%s: expected durations %d-%d, got %d-%d", tt.url, tt.min, tt.max, query.MinDuration, query.MaxDuration)
+		}
+	}
+}
+
+func TestParseQueryParams_TagPresence(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+	query := parseQuery(t, server, httptest.NewRequest(http.MethodGet, "/api/logs?has_tag=region&has_tag=zone&missing_tag=user.id", nil))
+
+	if !reflect.DeepEqual(query.HasTags, []string{"region", "zone"}) {
+		t.Errorf("expected has tags [region zone], got %v", query.HasTags)
+	}
+	if !reflect.DeepEqual(query.MissingTags, []string{"user.id"}) {
+		t.Errorf("expected missing tags [user.id], got %v", query.MissingTags)
+	}
+}
+
+func TestParseQueryParams_DefaultWindow(t *testing.T) {
+	request := func(url string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, url, nil)
+	}
+	near := func(since time.Time, window time.Duration) bool {
+		return time.Since(since.Add(window)).Abs() < time.Minute
+	}
+
+	server := NewServer(&stubProcessor{}, 0)
+	if query := parseQuery(t, server, request("/api/logs")); !near(query.Since, time.Hour) {
+		t.Errorf("expected the default 1h window, got since %v", query.Since)
+	}
+	if query := parseQuery(t, server, request("/api/logs?all_time=true")); !query.Since.IsZero() || !query.Until.IsZero() {
+		t.Errorf("expected all_time to remove the default window, got since %v", query.Since)
+	}
+
+	// Explicit bounds still apply with all_time
+	if query := parseQuery(t, server, request("/api/logs?all_time=true&time_range=30m")); !near(query.Since, 30*time.Minute) {
+		t.Errorf("expected the 30m time range, got since %v", query.Since)
+	}
+	if query := parseQuery(t, server, request("/api/logs?all_time=true&since=2024-01-01T00:00:00Z")); !query.Since.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the explicit since, got %v", query.Since)
+	}
+
+	server = NewServer(&stubProcessor{}, 0, WithDefaultQueryWindow(24*time.Hour))
+	if query := parseQuery(t, server, request("/api/logs")); !near(query.Since, 24*time.Hour) {
+		t.Errorf("expected the configured 24h window, got since %v", query.Since)
+	}
+
+	server = NewServer(&stubProcessor{}, 0, WithDefaultQueryWindow(0))
+	if query := parseQuery(t, server, request("/api/logs")); !query.Since.IsZero() {
+		t.Errorf("expected no default window, got since %v", query.Since)
+	}
+}
+
+func TestParseQueryParams_InvalidTimeRange(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+	for _, timeRange := range []string{"forever", "-1h", "0s", "xd"} {
+		if _, err := server.parseQueryParams(httptest.NewRequest(http.MethodGet, "/api/logs?time_range="+timeRange, nil)); err == nil {
+			t.Errorf("%s: expected an error, got none", timeRange)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	server.apiLogsHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/logs?time_range=forever", nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "time_range") {
+		t.Errorf("expected status 400 naming time_range, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestParseQueryParams_Until(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+
+	// Without until queries are open-ended, also when it doesn't parse
+	for _, url := range []string{"/api/logs", "/api/logs?time_range=2h", "/api/logs?until=yesterday"} {
+		if query := parseQuery(t, server, httptest.NewRequest(http.MethodGet, url, nil)); !query.Until.IsZero() {
+			t.Errorf("%s: expected no upper bound, got %v", url, query.Until)
+		}
+	}
+
+	query := parseQuery(t, server, httptest.NewRequest(http.MethodGet, "/api/logs?since=2024-01-01T00:00:00Z&until=2024-01-02T00:00:00Z", nil))
+	if !query.Since.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !query.Until.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the explicit bounds, got %v to %v", query.Since, query.Until)
+	}
+}
+
+func TestAPILogsHandler_AllTime(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+
+	// Without a lower bound the unbounded query limit applies
+	for url, status := range map[string]int{
+		"/api/logs":                          http.StatusOK,
+		"/api/logs?all_time=true&limit=500":  http.StatusOK,
+		"/api/logs?all_time=true&limit=5000": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		server.routes["/api/logs"](rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != status {
+			t.Errorf("%s: expected status %d, got %d", url, status, rec.Code)
+		}
+	}
+}
+
+func TestAPIHistogramsHandler(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	latency, err := models.NewHistogramMetric("http.latency", "api", []float64{10, 100})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	latency.AddTag("endpoint", "/users")
+	for _, value := range []float64{5, 50, 500} {
+		latency.Observe(value)
+	}
+	latency.Percentile[99] = 500
+	other, err := models.NewHistogramMetric("db.latency", "api", []float64{1})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	for _, histogram := range []*models.HistogramMetric{latency, other} {
+		if err := store.SaveHistogramMetric(histogram); err != nil {
+			t.Fatalf("failed to save histogram: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	server.apiHistogramsHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/histograms?name=http.latency&service=api", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Histograms []models.HistogramMetric `json:"histograms"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Histograms) != 1 {
+		t.Fatalf("expected 1 histogram, got %d", len(resp.Histograms))
+	}
+	histogram := resp.Histograms[0]
+	if histogram.ID != latency.ID || histogram.Tags["endpoint"] != "/users" {
+		t.Errorf("expected the http.latency histogram, got %+v", histogram)
+	}
+	if histogram.Count != 3 || histogram.Sum != 555 || histogram.Percentile[99] != 500 {
+		t.Errorf("expected count 3, sum 555 and p99 500, got %d, %v and %v", histogram.Count, histogram.Sum, histogram.Percentile)
+	}
+	if len(histogram.Buckets) != len(latency.Buckets) || histogram.Buckets[1].Count != 2 {
+		t.Errorf("expected the stored buckets %+v, got %+v", latency.Buckets, histogram.Buckets)
+	}
+
+	// No match is an empty list rather than null
+	rec = httptest.NewRecorder()
+	server.apiHistogramsHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/histograms?name=missing", nil))
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"histograms":[]}` {
+		t.Errorf("expected an empty list, got %s", body)
+	}
+}
+
+// waitForConnections polls /api/connections until it reports the expected
+// counts, failing the test after a few seconds
+func waitForConnections(t *testing.T, server *Server, expected map[string]int) {
+	t.Helper()
+
+	var body struct {
+		Connections map[string]int `json:"connections"`
+		Total       int            `json:"total"`
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := httptest.NewRecorder()
+		server.routes["/api/connections"](rec, httptest.NewRequest(http.MethodGet, "/api/connections", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		total := 0
+		for _, count := range expected {
+			total += count
+		}
+		if reflect.DeepEqual(body.Connections, expected) && body.Total == total {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected connections %v, got %v (total %d)", expected, body.Connections, body.Total)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAPIConnectionsHandler(t *testing.T) {
+	server := NewServer(&tracesProcessor{}, 0)
+	mux := http.NewServeMux()
+	for _, kind := range []string{"logs", "traces"} {
+		mux.HandleFunc("/ws/"+kind, server.routes["/ws/"+kind])
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	waitForConnections(t, server, map[string]int{"logs": 0, "metrics": 0, "traces": 0})
+
+	dial := func(kind string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/"+kind, nil)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	first := dial("logs")
+	dial("logs")
+	dial("traces")
+	waitForConnections(t, server, map[string]int{"logs": 2, "metrics": 0, "traces": 1})
+
+	// Closed connections are no longer counted
+	first.Close()
+	waitForConnections(t, server, map[string]int{"logs": 1, "metrics": 0, "traces": 1})
+
+	rec := httptest.NewRecorder()
+	server.routes["/api/connections"](rec, httptest.NewRequest(http.MethodPost, "/api/connections", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", rec.Code)
+	}
+}
+
+func TestAPIStatsHandler(t *testing.T) {
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	store.SaveLog(models.NewLogEntry("api", "boom", models.LogLevelError))
+	store.SaveMetric(models.NewMetric("http.requests", 1, models.MetricTypeCounter, "api"))
+	span := models.NewSpan("GET /", "api", "trace-1")
+	span.Duration = 40
+	store.SaveSpan(span)
+
+	rec := httptest.NewRecorder()
+	server.apiStatsHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp map[string]map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp) != 3 {
+		t.Errorf("expected logs, metrics and traces only, got %v", resp)
+	}
+	byLevel, _ := resp["logs"]["by_level"].(map[string]interface{})
+	if resp["logs"]["total"] != 1.0 || byLevel["ERROR"] != 1.0 || byLevel["INFO"] != 0.0 {
+		t.Errorf("unexpected logs: %v", resp["logs"])
+	}
+	byType, _ := resp["metrics"]["by_type"].(map[string]interface{})
+	if resp["metrics"]["total"] != 1.0 || byType["counter"] != 1.0 {
+		t.Errorf("unexpected metrics: %v", resp["metrics"])
+	}
+	if resp["traces"]["total"] != 1.0 || resp["traces"]["avg_duration_ms"] != 40.0 {
+		t.Errorf("unexpected traces: %v", resp["traces"])
+	}
+}
+
+func TestAPITraceIDsHandler(t *testing.T) {
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, id := range []string{"trace-1", "trace-2", "trace-3"} {
+		span := models.NewSpan("GET /", "api", id)
+		span.StartTime = now.Add(time.Duration(i-10) * time.Minute)
+		store.SaveSpan(span)
+	}
+
+	rec := httptest.NewRecorder()
+	server.apiTraceIDsHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/traces/ids?limit=2&offset=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Traces []struct {
+			ID        string    `json:"id"`
+			StartTime time.Time `json:"start_time"`
+		} `json:"traces"`
+		Pagination map[string]interface{} `json:"pagination"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Traces) != 2 || resp.Traces[0].ID != "trace-2" || resp.Traces[1].ID != "trace-1" {
+		t.Fatalf("expected trace-2 and trace-1, got %+v", resp.Traces)
+	}
+	if !resp.Traces[1].StartTime.Equal(now.Add(-10 * time.Minute)) {
+		t.Errorf("expected the root start time, got %v", resp.Traces[1].StartTime)
+	}
+	if resp.Pagination["total_items"] != 3.0 {
+		t.Errorf("expected 3 traces in total, got %v", resp.Pagination["total_items"])
+	}
+}
+
+func TestAPITailSamplingReportHandler(t *testing.T) {
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	// 10 traces of 10ms to 100ms in the last hour, the 20ms one failing in
+	// a child span, and one trace outside the window
+	now := time.Now().UTC()
+	for i := 1; i <= 10; i++ {
+		id := fmt.Sprintf("trace-%d", i)
+		root := models.NewSpan("GET /", "api", id)
+		root.StartTime = now.Add(-time.Duration(i) * time.Minute)
+		root.Duration = int64(i * 10)
+		store.SaveSpan(root)
+		if i == 2 {
+			child := models.NewSpan("charge", "payments", id).SetParent(root.ID)
+			child.StartTime = root.StartTime
+			child.Duration = 5
+			child.Status = models.SpanStatusError
+			store.SaveSpan(child)
+		}
+	}
+	old := models.NewSpan("GET /", "api", "trace-old")
+	old.StartTime = now.Add(-2 * time.Hour)
+	old.Duration = 1000
+	store.SaveSpan(old)
+
+	rec := httptest.NewRecorder()
+	server.routes["/api/traces/sampling-report"](rec, httptest.NewRequest(http.MethodGet, "/api/traces/sampling-report?time_range=1h&percentile=80&rate=0.5", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var report processor.TailSamplingReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// The 80th percentile is 80ms, so the 90ms and 100ms traces are slow
+	expected := processor.TailSamplingReport{
+		Traces: 10, ErrorTraces: 1, SlowTraces: 2, SampledTraces: 7,
+		Percentile: 80, ThresholdMs: 80, Rate: 0.5,
+		KeepAll: 10, HeadKept: 5.5, TailKept: 6.5,
+	}
+	if report != expected {
+		t.Errorf("expected %+v, got %+v", expected, report)
+	}
+
+	for _, url := range []string{
+		"/api/traces/sampling-report?time_range=1h&rate=2",
+		"/api/traces/sampling-report?time_range=1h&percentile=100",
+	} {
+		rec := httptest.NewRecorder()
+		server.routes["/api/traces/sampling-report"](rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", url, rec.Code)
+		}
+	}
+
+	unbounded := NewServer(processor.NewStorageProcessor(store), 0, WithDefaultQueryWindow(0))
+	rec = httptest.NewRecorder()
+	unbounded.routes["/api/traces/sampling-report"](rec, httptest.NewRequest(http.MethodGet, "/api/traces/sampling-report", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a time range, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// responseEncoder writes query responses in one encoding
+type responseEncoder interface {
+	ContentType() string
+	Encode(w io.Writer, v interface{}) error
+}
+
+// jsonEncoder encodes responses as JSON, the default
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string { return "application/json" }
+
+func (jsonEncoder) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// msgpackEncoder encodes responses as MessagePack. Values are first encoded
+// as JSON so that both encodings carry the same fields under the same names:
+// timestamps are RFC 3339 strings and whole numbers are integers.
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) ContentType() string { return "application/msgpack" }
+
+func (msgpackEncoder) Encode(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := appendMsgpack(&buf, value); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// negotiateEncoder picks the response encoding from the Accept header:
+// MessagePack if application/msgpack (or application/x-msgpack) has a
+// higher quality than JSON, and JSON otherwise
+func negotiateEncoder(r *http.Request) responseEncoder {
+	msgpackQ, jsonQ := -1.0, -1.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case "application/msgpack", "application/x-msgpack":
+			msgpackQ = math.Max(msgpackQ, q)
+		case "application/json":
+			jsonQ = math.Max(jsonQ, q)
+		}
+	}
+	if msgpackQ > 0 && msgpackQ > jsonQ {
+		return msgpackEncoder{}
+	}
+	return jsonEncoder{}
+}
+
+// writeResponse writes v with status in the encoding negotiated for r
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	encoder := negotiateEncoder(r)
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	if err := encoder.Encode(w, v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// appendMsgpack appends the MessagePack encoding of a value decoded from
+// JSON with UseNumber to buf
+func appendMsgpack(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			appendMsgpackInt(buf, n)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid number %q: %w", v, err)
+		}
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		appendMsgpackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		appendMsgpackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := appendMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		appendMsgpackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			appendMsgpack(buf, key)
+			if err := appendMsgpack(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %T as msgpack", value)
+	}
+	return nil
+}
+
+// appendMsgpackInt appends an integer in its most compact form
+func appendMsgpackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n < 128:
+		buf.WriteByte(byte(n))
+	case n < 0 && n >= -32:
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(n))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// appendMsgpackHeader appends the header of a string, array or map of n
+// elements: the fix format below fixMax, else the 8 (if any), 16 or 32 bit one
+func appendMsgpackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, format8, format16, format32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case format8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(format8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(format16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(format32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+// decodeMsgpack decodes the MessagePack formats msgpackEncoder writes into
+// the values encoding/json decodes, with every number a float64
+func decodeMsgpack(r *bytes.Reader) (interface{}, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	readLen := func(size int) (int, error) {
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return 0, err
+		}
+		switch size {
+		case 1:
+			return int(buf[0]), nil
+		case 2:
+			return int(binary.BigEndian.Uint16(buf)), nil
+		default:
+			return int(binary.BigEndian.Uint32(buf)), nil
+		}
+	}
+	readString := func(n int) (interface{}, error) {
+		buf := make([]byte, n)
+		_, err := io.ReadFull(r, buf)
+		return string(buf), err
+	}
+	readArray := func(n int) (interface{}, error) {
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = decodeMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	readMap := func(n int) (interface{}, error) {
+		entries := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key, err := decodeMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+			if entries[key.(string)], err = decodeMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+	}
+	readInt := func(v interface{}) (interface{}, error) {
+		if err := binary.Read(r, binary.BigEndian, v); err != nil {
+			return nil, err
+		}
+		return float64(reflect.ValueOf(v).Elem().Int()), nil
+	}
+
+	switch {
+	case b <= 0x7f:
+		return float64(b), nil
+	case b >= 0xe0:
+		return float64(int8(b)), nil
+	case b&0xe0 == 0xa0:
+		return readString(int(b & 0x1f))
+	case b&0xf0 == 0x90:
+		return readArray(int(b & 0x0f))
+	case b&0xf0 == 0x80:
+		return readMap(int(b & 0x0f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcb:
+		var bits uint64
+		err := binary.Read(r, binary.BigEndian, &bits)
+		return math.Float64frombits(bits), err
+	case 0xd0:
+		return readInt(new(int8))
+	case 0xd1:
+		return readInt(new(int16))
+	case 0xd2:
+		return readInt(new(int32))
+	case 0xd3:
+		return readInt(new(int64))
+	case 0xd9, 0xda, 0xdb:
+		n, err := readLen(map[byte]int{0xd9: 1, 0xda: 2, 0xdb: 4}[b])
+		if err != nil {
+			return nil, err
+		}
+		return readString(n)
+	case 0xdc, 0xdd:
+		n, err := readLen(map[byte]int{0xdc: 2, 0xdd: 4}[b])
+		if err != nil {
+			return nil, err
+		}
+		return readArray(n)
+	case 0xde, 0xdf:
+		n, err := readLen(map[byte]int{0xde: 2, 0xdf: 4}[b])
+		if err != nil {
+			return nil, err
+		}
+		return readMap(n)
+	}
+	return nil, fmt.Errorf("unexpected msgpack format 0x%x", b)
+}
+
+func TestNegotiateEncoder(t *testing.T) {
+	for accept, expected := range map[string]string{
+		"":                                      "application/json",
+		"*/*":                                   "application/json",
+		"application/json":                      "application/json",
+		"application/msgpack":                   "application/msgpack",
+		"application/x-msgpack, */*;q=0.1":      "application/msgpack",
+		"application/json, application/msgpack": "application/json",
+		"application/json, application/msgpack;q=0.5": "application/json",
+		"application/msgpack;q=0":                     "application/json",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/api/logs", nil)
+		r.Header.Set("Accept", accept)
+		if got := negotiateEncoder(r).ContentType(); got != expected {
+			t.Errorf("expected %s for Accept %q, got %s", expected, accept, got)
+		}
+	}
+}
+
+func TestMsgpackEncoder_RoundTrip(t *testing.T) {
+	values := []interface{}{
+		map[string]interface{}{
+			"small": 7, "negative": -5, "int8": -100, "int16": 3000, "int32": -70000, "int64": int64(1) << 40,
+			"float": 0.25, "bool": true, "null": nil, "empty": "", "list": []int{1, 2, 3},
+		},
+		map[string]string{"long": string(bytes.Repeat([]byte("x"), 300))},
+		make([]int, 20),
+	}
+	for _, value := range values {
+		assertSameEncoding(t, value)
+	}
+}
+
+func TestAPIHandlers_Msgpack(t *testing.T) {
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	for i := 0; i < 20; i++ {
+		entry := models.NewLogEntry("api", fmt.Sprintf("request %d", i), models.LogLevelWarning)
+		entry.AddTag("endpoint", "/checkout")
+		store.SaveLog(entry)
+	}
+	trace, root := models.NewTrace("GET /checkout", "api")
+	root.Duration = 42
+	trace.Spans = append(trace.Spans, models.NewSpan("charge", "payments", trace.ID).SetParent(root.ID))
+	store.SaveTrace(trace)
+
+	for _, path := range []string{"/api/logs?time_range=1h", "/api/traces?time_range=1h", "/api/stats"} {
+		responses := make(map[string]interface{})
+		for _, accept := range []string{"application/json", "application/msgpack"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Accept", accept)
+			rec := httptest.NewRecorder()
+			server.routes[req.URL.Path](rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: expected status 200, got %d: %s", path, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != accept {
+				t.Errorf("%s: expected Content-Type %s, got %s", path, accept, got)
+			}
+
+			var decoded interface{}
+			if accept == "application/json" {
+				err = json.Unmarshal(rec.Body.Bytes(), &decoded)
+			} else {
+				decoded, err = decodeMsgpack(bytes.NewReader(rec.Body.Bytes()))
+			}
+			if err != nil {
+				t.Fatalf("%s: failed to decode %s: %v", path, accept, err)
+			}
+			responses[accept] = decoded
+		}
+		if !reflect.DeepEqual(responses["application/json"], responses["application/msgpack"]) {
+			t.Errorf("%s: expected both encodings to carry the same data, got %v and %v",
+				path, responses["application/json"], responses["application/msgpack"])
+		}
+	}
+}
+
+// assertSameEncoding checks that value decodes to the same data from JSON
+// and from MessagePack
+func assertSameEncoding(t *testing.T, value interface{}) {
+	t.Helper()
+
+	var jsonBuf, msgpackBuf bytes.Buffer
+	if err := (jsonEncoder{}).Encode(&jsonBuf, value); err != nil {
+		t.Fatalf("failed to encode JSON: %v", err)
+	}
+	if err := (msgpackEncoder{}).Encode(&msgpackBuf, value); err != nil {
+		t.Fatalf("failed to encode msgpack: %v", err)
+	}
+
+	var fromJSON interface{}
+	if err := json.Unmarshal(jsonBuf.Bytes(), &fromJSON); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	reader := bytes.NewReader(msgpackBuf.Bytes())
+	fromMsgpack, err := decodeMsgpack(reader)
+	if err != nil {
+		t.Fatalf("failed to decode msgpack: %v", err)
+	}
+	if reader.Len() != 0 {
+		t.Errorf("expected a single msgpack value, %d bytes left", reader.Len())
+	}
+	if !reflect.DeepEqual(fromJSON, fromMsgpack) {
+		t.Errorf("expected %v from msgpack, got %v", fromJSON, fromMsgpack)
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// exportFlushEvery is how many NDJSON lines are buffered between flushes
+const exportFlushEvery = 500
+
+// ExportError is the last line of an export that failed after it started, so
+// that a truncated export can't pass for a complete one
+type ExportError struct {
+	Error string `json:"error"`
+}
+
+// exportHandler returns a handler that streams the logs, metrics or spans
+// matching the query as newline-delimited JSON, one item per line and oldest
+// first, for backups: /api/export?type=logs&since=2024-01-01T00:00:00Z.
+// Items are written as they are read from storage so the full result set is
+// never held in memory, and without a time range everything is exported
+// rather than the default query window. If storage fails once items have
+// been sent, the export ends with an ExportError line instead.
+func (s *Server) exportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query, err := s.parseExportQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Exports are unbounded unless a limit is asked for explicitly
+		if r.URL.Query().Get("limit") == "" {
+			query.Limit = 0
+		}
+
+		kind := r.URL.Query().Get("type")
+		var stream func(write func(item interface{}) error) error
+		switch kind {
+		case "logs":
+			stream = func(write func(item interface{}) error) error {
+				return s.processor.StreamLogs(query, func(log *models.LogEntry) error { return write(log) })
+			}
+		case "metrics":
+			stream = func(write func(item interface{}) error) error {
+				return s.processor.StreamMetrics(query, func(metric *models.Metric) error { return write(metric) })
+			}
+		case "spans":
+			stream = func(write func(item interface{}) error) error {
+				return s.processor.StreamSpans(query, func(span *models.Span) error { return write(span) })
+			}
+		default:
+			http.Error(w, fmt.Sprintf("Unsupported export type %q: must be logs, metrics or spans", kind), http.StatusBadRequest)
+			return
+		}
+
+		flusher, _ := w.(http.Flusher)
+		bw := bufio.NewWriter(w)
+		encoder := json.NewEncoder(bw)
+		started := false
+		items := 0
+
+		writeHeader := func() {
+			started = true
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ndjson"`, kind))
+			w.WriteHeader(http.StatusOK)
+		}
+
+		err = stream(func(item interface{}) error {
+			if !started {
+				writeHeader()
+			}
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+
+			items++
+			if items%exportFlushEvery == 0 {
+				if err := bw.Flush(); err != nil {
+					return err
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			if !started {
+				http.Error(w, fmt.Sprintf("Error exporting %s: %v", kind, err), http.StatusInternalServerError)
+				return
+			}
+			// Headers are already sent, so the error is reported in the stream
+			log.Printf("Error exporting %s after %d items: %v", kind, items, err)
+			encoder.Encode(ExportError{Error: fmt.Sprintf("export of %s failed after %d items: %v", kind, items, err)})
+		}
+
+		if !started {
+			writeHeader()
+		}
+		bw.Flush()
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+func TestExportHandler(t *testing.T) {
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for message, minutes := range map[string]int{"third": 3, "first": 1, "second": 2, "too old": -60} {
+		entry := models.NewLogEntry("checkout", message, models.LogLevelInfo)
+		entry.Timestamp = base.Add(time.Duration(minutes) * time.Minute)
+		store.SaveLog(entry)
+	}
+	metric := models.NewMetric("queue.depth", 7, models.MetricTypeGauge, "worker")
+	metric.Timestamp = base.Add(time.Minute)
+	store.SaveMetric(metric)
+	span := models.NewSpan("charge", "payments", "trace-1")
+	span.StartTime = base.Add(time.Minute)
+	store.SaveSpan(span)
+
+	since := base.Format(time.RFC3339)
+	for kind, expected := range map[string]int{"logs": 3, "metrics": 1, "spans": 1} {
+		rec := httptest.NewRecorder()
+		server.exportHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/export?type="+kind+"&since="+since, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", kind, rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("%s: expected content type application/x-ndjson, got %s", kind, ct)
+		}
+
+		var lines []map[string]interface{}
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var line map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatalf("%s: expected a JSON object per line, got %q: %v", kind, scanner.Text(), err)
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) != expected {
+			t.Fatalf("%s: expected %d lines, got %d", kind, expected, len(lines))
+		}
+
+		if kind == "logs" {
+			for i, message := range []string{"first", "second", "third"} {
+				if lines[i]["message"] != message {
+					t.Errorf("expected logs oldest first, got %v at line %d", lines[i]["message"], i)
+				}
+			}
+		}
+	}
+
+	// Without a time range every log is exported, however old, rather than
+	// the default query window
+	for _, path := range []string{"/api/export?type=logs", "/api/export?type=logs&all_time=true"} {
+		rec := httptest.NewRecorder()
+		server.exportHandler()(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+		if lines := strings.Count(rec.Body.String(), "\n"); lines != 4 {
+			t.Errorf("%s: expected all 4 logs, got %d lines", path, lines)
+		}
+	}
+
+	for _, path := range []string{
+		"/api/export?since=" + since,
+		"/api/export?type=histograms&since=" + since,
+	} {
+		rec := httptest.NewRecorder()
+		server.exportHandler()(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, rec.Code)
+		}
+	}
+}
+
+// brokenExportProcessor streams a few logs and then fails, like storage
+// going away in the middle of an export
+type brokenExportProcessor struct {
+	stubProcessor
+}
+
+func (p *brokenExportProcessor) StreamLogs(query *models.QueryParams, fn func(log *models.LogEntry) error) error {
+	for i := 0; i < 2; i++ {
+		entry := models.NewLogEntry("checkout", "order placed", models.LogLevelInfo)
+		entry.ID = fmt.Sprintf("log-%d", i)
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return errors.New("database is locked")
+}
+
+func TestExportHandler_FailsAfterStart(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewServer(&brokenExportProcessor{}, 0).exportHandler()(rec,
+		httptest.NewRequest(http.MethodGet, "/api/export?type=logs&since=2024-01-01T00:00:00Z", nil))
+
+	// The export ends with an error line after the logs already sent
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 2 logs and an error line, got %d lines", len(lines))
+	}
+	var last ExportError
+	if err := json.Unmarshal([]byte(lines[2]), &last); err != nil || !strings.Contains(last.Error, "database is locked") {
+		t.Fatalf("expected an error line, got %q", lines[2])
+	}
+
+	// Importing the truncated export reports it as incomplete
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+	importRec := httptest.NewRecorder()
+	server.importHandler()(importRec, httptest.NewRequest(http.MethodPost, "/api/import?type=logs", strings.NewReader(rec.Body.String())))
+
+	var response ImportResponse
+	if err := json.NewDecoder(importRec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Inserted != 2 || response.Failed != 1 {
+		t.Fatalf("expected 2 inserted and 1 failed, got %+v", response)
+	}
+	if failure := response.Failures[0]; failure.Line != 3 || !strings.Contains(failure.Error, "export is incomplete") {
+		t.Errorf("expected line 3 to report the incomplete export, got %+v", failure)
+	}
+}
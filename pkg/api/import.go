@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// defaultImportBatchSize is how many lines an import writes per transaction
+// unless batch_size asks otherwise
+const defaultImportBatchSize = 1000
+
+// maxImportBatchSize caps the batch_size of an import
+const maxImportBatchSize = 10000
+
+// maxImportFailures caps the failed lines listed in an import summary; the
+// failed count covers them all
+const maxImportFailures = 100
+
+// ImportFailure is a line of an import that wasn't inserted
+type ImportFailure struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportResponse summarizes an import
+type ImportResponse struct {
+	Type     string          `json:"type"`
+	Inserted int             `json:"inserted"`
+	Failed   int             `json:"failed"`
+	Failures []ImportFailure `json:"failures"`
+}
+
+// importedLog is a log of an import batch with the line it was read from
+type importedLog struct {
+	line int
+	log  *models.LogEntry
+}
+
+// importHandler returns a handler that bulk-loads newline-delimited JSON, as
+// written by /api/export, one item per line: /api/import?type=logs. Lines are
+// read as they arrive and written in batches of batch_size lines, each in one
+// transaction, so neither the request nor a transaction grows with the size
+// of the import. Lines that can't be decoded, or whose timestamp is out of
+// the bounds set by WithTimestampBounds, are reported in the summary by
+// line number while the rest of the import goes on. A batch that can't be
+// processed, for instance because it holds a duplicate ID or is over the
+// rate limit, is rolled back and each of its lines is reported with the
+// batch's error; it isn't retried line by line, which would charge the rate
+// limit and count dropped logs again. Importing again with a smaller
+// batch_size narrows the failures down. The error line ending an export that
+// failed part way through is reported as a failure, as the import is then
+// incomplete.
+func (s *Server) importHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		kind := r.URL.Query().Get("type")
+		if kind != "logs" {
+			http.Error(w, fmt.Sprintf("Unsupported import type %q: must be logs", kind), http.StatusBadRequest)
+			return
+		}
+
+		batchSize := defaultImportBatchSize
+		if value := r.URL.Query().Get("batch_size"); value != "" {
+			size, err := strconv.Atoi(value)
+			if err != nil || size <= 0 || size > maxImportBatchSize {
+				http.Error(w, fmt.Sprintf("Invalid batch_size: must be an integer between 1 and %d", maxImportBatchSize), http.StatusBadRequest)
+				return
+			}
+			batchSize = size
+		}
+
+		response := ImportResponse{Type: kind, Failures: []ImportFailure{}}
+		fail := func(line int, err error) {
+			response.Failed++
+			if len(response.Failures) < maxImportFailures {
+				response.Failures = append(response.Failures, ImportFailure{Line: line, Error: err.Error()})
+			}
+		}
+
+		batch := make([]importedLog, 0, batchSize)
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			logs := make([]*models.LogEntry, len(batch))
+			for i, item := range batch {
+				logs[i] = item.log
+			}
+
+			if err := s.processor.ProcessLogs(logs); err != nil {
+				for _, item := range batch {
+					fail(item.line, fmt.Errorf("batch failed: %w", err))
+				}
+			} else {
+				response.Inserted += len(batch)
+			}
+			batch = batch[:0]
+		}
+
+		// Each line is bounded by the body limit of single submissions
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), int(s.maxBodySize))
+		line := 0
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+
+			if message, ok := exportErrorLine(scanner.Bytes()); ok {
+				fail(line, fmt.Errorf("export is incomplete: %s", message))
+				continue
+			}
+
+			entry, err := decodeImportedLog(scanner.Bytes())
+			if err == nil {
+				err = s.checkTimestamp("timestamp", entry.Timestamp, s.maxTimestampAge.Logs)
+			}
+			if err != nil {
+				fail(line, err)
+				continue
+			}
+			batch = append(batch, importedLog{line: line, log: entry})
+			if len(batch) == batchSize {
+				flush()
+			}
+		}
+		flush()
+
+		// The rest of the body can't be read past a line that is too long,
+		// or past the decompressed limit of a gzip body
+		if err := scanner.Err(); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.Is(err, bufio.ErrTooLong) {
+				err = fmt.Errorf("line exceeds the limit of %d bytes", s.maxBodySize)
+			} else if errors.As(err, &tooLarge) {
+				err = fmt.Errorf("decompressed body exceeds the limit of %d bytes", tooLarge.Limit)
+			}
+			fail(line+1, fmt.Errorf("import stopped: %w", err))
+		}
+
+		writeResponse(w, r, http.StatusOK, response)
+	}
+}
+
+// exportErrorLine returns the message of an ExportError line written by
+// /api/export, an object holding nothing but an error
+func exportErrorLine(data []byte) (string, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || len(fields) != 1 {
+		return "", false
+	}
+	var exportErr ExportError
+	if _, ok := fields["error"]; !ok || json.Unmarshal(data, &exportErr) != nil {
+		return "", false
+	}
+	return exportErr.Error, true
+}
+
+// decodeImportedLog decodes and validates a log line of an import. Logs
+// without an ID or timestamp are given one, like on ingestion.
+func decodeImportedLog(data []byte) (*models.LogEntry, error) {
+	var entry models.LogEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if entry.Message == "" {
+		return nil, fmt.Errorf("message is required")
+	}
+	if entry.Service == "" {
+		return nil, fmt.Errorf("service is required")
+	}
+
+	if entry.ID == "" {
+		entry.ID = generateID()
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	if entry.Level == "" {
+		entry.Level = models.LogLevelInfo
+	}
+	return &entry, nil
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+func TestImportHandler(t *testing.T) {
+	source, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	target, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer target.Close()
+
+	// Export five logs from one instance
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		entry := models.NewLogEntry("checkout", "order placed", models.LogLevelInfo)
+		entry.ID = fmt.Sprintf("log-%d", i)
+		entry.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		source.SaveLog(entry)
+	}
+	rec := httptest.NewRecorder()
+	NewServer(processor.NewStorageProcessor(source), 0).exportHandler()(rec,
+		httptest.NewRequest(http.MethodGet, "/api/export?type=logs&since="+base.Format(time.RFC3339), nil))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 exported lines, got %d", len(lines))
+	}
+
+	// Import them into another, along with broken lines and a duplicate of
+	// the first log, in batches of two; the duplicate fails its batch
+	body := strings.Join([]string{
+		lines[0],
+		lines[1],
+		`{"service": "checkout"`,
+		lines[2],
+		"",
+		`{"service": "checkout", "level": "INFO"}`,
+		lines[3],
+		lines[0],
+		lines[4],
+	}, "\n")
+	server := NewServer(processor.NewStorageProcessor(target), 0)
+	rec = httptest.NewRecorder()
+	server.routes["/api/import"](rec, httptest.NewRequest(http.MethodPost, "/api/import?type=logs&batch_size=2", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response ImportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Inserted != 4 || response.Failed != 4 {
+		t.Errorf("expected 4 inserted and 4 failed, got %d and %d", response.Inserted, response.Failed)
+	}
+	var failedLines []int
+	for _, failure := range response.Failures {
+		failedLines = append(failedLines, failure.Line)
+	}
+	if len(failedLines) != 4 || failedLines[0] != 3 || failedLines[1] != 6 || failedLines[2] != 8 || failedLines[3] != 9 {
+		t.Errorf("expected lines 3, 6, 8 and 9 to fail, got %v", failedLines)
+	}
+
+	result, err := target.QueryLogs(&models.QueryParams{Service: "checkout", Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to query logs: %v", err)
+	}
+	if pagination, _ := result["pagination"].(map[string]interface{}); pagination["total_items"] != 4 {
+		t.Errorf("expected 4 imported logs, got %v", pagination["total_items"])
+	}
+
+	for path, expected := range map[string]int{
+		"/api/import?type=spans":               http.StatusBadRequest,
+		"/api/import?type=logs&batch_size=0":   http.StatusBadRequest,
+		"/api/import?type=logs&batch_size=abc": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		server.routes["/api/import"](rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(lines[0])))
+		if rec.Code != expected {
+			t.Errorf("%s: expected status %d, got %d", path, expected, rec.Code)
+		}
+	}
+}
+
+func TestImportHandler_FailedBatches(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	rules := []processor.DropRule{{Service: "noise"}}
+	dropping := processor.NewDropRuleProcessor(processor.NewStorageProcessor(store), rules)
+	limited := processor.NewRateLimitProcessor(dropping, processor.RateLimitConfig{Default: processor.RateLimit{Rate: 0.001, Burst: 4}})
+	server := NewServer(limited, 0)
+
+	// The second batch fails on a duplicate ID and the third on the rate
+	// limit; neither is retried line by line
+	body := strings.Join([]string{
+		`{"id": "log-1", "service": "api", "message": "one"}`,
+		`{"id": "log-2", "service": "api", "message": "two"}`,
+		`{"id": "log-1", "service": "api", "message": "again"}`,
+		`{"id": "log-3", "service": "noise", "message": "dropped"}`,
+		`{"id": "log-4", "service": "api", "message": "four"}`,
+		`{"id": "log-5", "service": "api", "message": "five"}`,
+	}, "\n")
+	rec := httptest.NewRecorder()
+	server.routes["/api/import"](rec, httptest.NewRequest(http.MethodPost, "/api/import?type=logs&batch_size=2", strings.NewReader(body)))
+
+	var response ImportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Inserted != 2 || response.Failed != 4 {
+		t.Fatalf("expected 2 inserted and 4 failed, got %d and %d", response.Inserted, response.Failed)
+	}
+	for _, failure := range response.Failures[2:] {
+		if !strings.Contains(failure.Error, processor.ErrRateLimited.Error()) {
+			t.Errorf("expected line %d to fail on the rate limit, got %s", failure.Line, failure.Error)
+		}
+	}
+	if dropping.Dropped() != 1 {
+		t.Errorf("expected the dropped log to be counted once, got %d", dropping.Dropped())
+	}
+}
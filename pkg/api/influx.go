@@ -0,0 +1,280 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// influxDefaultService is used for lines without a service tag when the write
+// names no database
+const influxDefaultService = "default"
+
+// influxPrecisions maps the precision parameter of /write to the unit of the
+// line timestamps. Timestamps are in nanoseconds by default.
+var influxPrecisions = map[string]time.Duration{
+	"":   time.Nanosecond,
+	"n":  time.Nanosecond,
+	"ns": time.Nanosecond,
+	"u":  time.Microsecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// influxWriteHandler returns a handler for the InfluxDB v1 /write endpoint.
+// The body holds metrics in line protocol, each numeric field of a line
+// becoming a gauge named measurement.field. Like InfluxDB it responds with
+// 204 once the whole body has been written and rejects it if any line is
+// malformed.
+func (s *Server) influxWriteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		precision, ok := influxPrecisions[r.URL.Query().Get("precision")]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Invalid precision %q", r.URL.Query().Get("precision")), http.StatusBadRequest)
+			return
+		}
+
+		// Read the request body
+		body, ok := readBody(w, r, s.batchBodyLimit())
+		if !ok {
+			return
+		}
+
+		service := r.URL.Query().Get("db")
+		if service == "" {
+			service = influxDefaultService
+		}
+
+		metrics, err := parseInfluxLineProtocol(string(body), precision, service, time.Now().UTC())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid line protocol: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		// Reject the write before processing any metric
+		for _, metric := range metrics {
+			if err := s.checkTimestamp(metric.Name+" timestamp", metric.Timestamp, s.maxTimestampAge.Metrics); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if len(metrics) > 0 {
+			if err := s.processor.ProcessMetrics(metrics); err != nil {
+				log.Printf("Error processing line protocol metrics: %v", err)
+				http.Error(w, "Error processing metrics", processErrorStatus(err))
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// parseInfluxLineProtocol parses metrics in InfluxDB line protocol:
+//
+//	measurement[,tag=value...] field=value[,field=value...] [timestamp]
+//
+// Every numeric or boolean field becomes a gauge named measurement.field
+// carrying the line's tags; string fields are skipped. The service tag names
+// the service, defaulting to service. Timestamps are counted in precision
+// units since the epoch and default to now. Blank lines and # comments are
+// ignored.
+// See: https://docs.influxdata.com/influxdb/v1/write_protocols/line_protocol_reference/
+func parseInfluxLineProtocol(input string, precision time.Duration, service string, now time.Time) ([]*models.Metric, error) {
+	var metrics []*models.Metric
+
+	for i, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lineMetrics, err := parseInfluxLine(line, precision, service, now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		metrics = append(metrics, lineMetrics...)
+	}
+
+	return metrics, nil
+}
+
+// parseInfluxLine parses a single non-empty line of line protocol
+func parseInfluxLine(line string, precision time.Duration, service string, now time.Time) ([]*models.Metric, error) {
+	// Quotes are only special in field values, so the key ends at the first
+	// unescaped space
+	keyEnd := -1
+	for i := 0; i < len(line) && keyEnd < 0; i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case ' ':
+			keyEnd = i
+		}
+	}
+	if keyEnd < 0 {
+		return nil, fmt.Errorf("expected measurement, fields and an optional timestamp")
+	}
+	sections := append([]string{line[:keyEnd]}, splitInfluxUnescaped(line[keyEnd+1:], ' ', true)...)
+	if len(sections) > 3 {
+		return nil, fmt.Errorf("expected measurement, fields and an optional timestamp")
+	}
+
+	// Measurement and tags
+	key := splitInfluxUnescaped(sections[0], ',', false)
+	measurement := unescapeInflux(key[0])
+	if measurement == "" {
+		return nil, fmt.Errorf("missing measurement")
+	}
+
+	tags := make(map[string]string, len(key)-1)
+	for _, tag := range key[1:] {
+		name, value, ok := splitInfluxPair(tag)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+		tags[name] = value
+	}
+	if svc, ok := tags["service"]; ok {
+		service = svc
+		delete(tags, "service") // Remove to avoid duplication
+	}
+
+	// Timestamp
+	timestamp := now
+	if len(sections) == 3 {
+		ts, err := strconv.ParseInt(sections[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", sections[2])
+		}
+		timestamp = time.Unix(0, ts*int64(precision)).UTC()
+	}
+
+	// One metric per numeric field
+	var metrics []*models.Metric
+	for _, field := range splitInfluxUnescaped(sections[1], ',', true) {
+		name, raw, ok := splitInfluxPair(field)
+		if !ok || name == "" || raw == "" {
+			return nil, fmt.Errorf("invalid field %q", field)
+		}
+
+		value, numeric, err := parseInfluxFieldValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+		if !numeric {
+			continue
+		}
+
+		metric := models.NewMetric(measurement+"."+name, value, models.MetricTypeGauge, service)
+		metric.Timestamp = timestamp
+		metric.AddTags(tags)
+		metrics = append(metrics, metric)
+	}
+
+	return metrics, nil
+}
+
+// parseInfluxFieldValue parses a field value: a float, an integer with an i
+// or u suffix, or a boolean, which counts as 1 or 0. String values are
+// valid but not numeric.
+func parseInfluxFieldValue(raw string) (float64, bool, error) {
+	if strings.HasPrefix(raw, `"`) {
+		if len(raw) < 2 || !strings.HasSuffix(raw, `"`) {
+			return 0, false, fmt.Errorf("unterminated string %s", raw)
+		}
+		return 0, false, nil
+	}
+
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return 1, true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return 0, true, nil
+	}
+
+	switch raw[len(raw)-1] {
+	case 'i':
+		value, err := strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid integer %s", raw)
+		}
+		return float64(value), true, nil
+	case 'u':
+		value, err := strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid unsigned integer %s", raw)
+		}
+		return float64(value), true, nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid number %s", raw)
+	}
+	return value, true, nil
+}
+
+// splitInfluxPair splits a key=value element at its first unescaped equals
+// sign, unescaping both sides. Quoted string values are returned as is.
+func splitInfluxPair(element string) (string, string, bool) {
+	for i := 0; i < len(element); i++ {
+		switch element[i] {
+		case '\\':
+			i++
+		case '=':
+			value := element[i+1:]
+			if !strings.HasPrefix(value, `"`) {
+				value = unescapeInflux(value)
+			}
+			return unescapeInflux(element[:i]), value, true
+		}
+	}
+	return "", "", false
+}
+
+// splitInfluxUnescaped splits s at each occurrence of sep that is not escaped
+// with a backslash. With fields set, s holds field values and occurrences
+// inside double quoted strings are kept as well.
+func splitInfluxUnescaped(s string, sep byte, fields bool) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = fields && !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// influxUnescaper removes the escaping of commas, spaces and equals signs in
+// measurements, tag keys and values, and field keys
+var influxUnescaper = strings.NewReplacer(`\,`, ",", `\ `, " ", `\=`, "=")
+
+// unescapeInflux returns s with its escape sequences removed
+func unescapeInflux(s string) string {
+	return influxUnescaper.Replace(s)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInfluxLineProtocol(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	input := `# edge agent sample
+cpu,host=edge-1,region=eu-west,service=agent usage_user=12.5,usage_system=3i,healthy=true 1700000000000000000
+
+disk,host=edge\ 2,path=/var\,log used=4096u,label="root disk, primary",full=F
+uptime seconds=-1.5e3`
+
+	metrics, err := parseInfluxLineProtocol(input, time.Nanosecond, "default", now)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(metrics) != 6 {
+		t.Fatalf("expected 6 metrics, got %d", len(metrics))
+	}
+
+	expected := []struct {
+		name    string
+		value   float64
+		service string
+	}{
+		{"cpu.usage_user", 12.5, "agent"},
+		{"cpu.usage_system", 3, "agent"},
+		{"cpu.healthy", 1, "agent"},
+		{"disk.used", 4096, "default"},
+		{"disk.full", 0, "default"},
+		{"uptime.seconds", -1500, "default"},
+	}
+	for i, want := range expected {
+		metric := metrics[i]
+		if metric.Name != want.name || metric.Value != want.value || metric.Service != want.service {
+			t.Errorf("metric %d: expected %s=%v of %s, got %s=%v of %s", i, want.name, want.value, want.service, metric.Name, metric.Value, metric.Service)
+		}
+	}
+
+	// Tags are shared by the fields of a line, without the service tag
+	cpuTags := map[string]string{"host": "edge-1", "region": "eu-west"}
+	for _, metric := range metrics[:3] {
+		if !reflect.DeepEqual(metric.Tags, cpuTags) {
+			t.Errorf("expected tags %v on %s, got %v", cpuTags, metric.Name, metric.Tags)
+		}
+		if !metric.Timestamp.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("expected the line timestamp on %s, got %v", metric.Name, metric.Timestamp)
+		}
+	}
+	diskTags := map[string]string{"host": "edge 2", "path": "/var,log"}
+	if !reflect.DeepEqual(metrics[3].Tags, diskTags) {
+		t.Errorf("expected unescaped tags %v, got %v", diskTags, metrics[3].Tags)
+	}
+	if !metrics[3].Timestamp.Equal(now) {
+		t.Errorf("expected lines without a timestamp to use now, got %v", metrics[3].Timestamp)
+	}
+}
+
+func TestParseInfluxLineProtocol_Precision(t *testing.T) {
+	metrics, err := parseInfluxLineProtocol("cpu usage=1 1700000000", time.Second, "default", time.Now())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(metrics) != 1 || !metrics[0].Timestamp.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected a timestamp in seconds, got %+v", metrics)
+	}
+}
+
+func TestParseInfluxLineProtocol_Invalid(t *testing.T) {
+	for _, line := range []string{
+		"cpu",
+		"cpu,host usage=1",
+		"cpu usage",
+		"cpu usage=abc",
+		"cpu usage=1i2i",
+		`cpu label="unterminated`,
+		"cpu usage=1 yesterday",
+		"cpu usage=1 1700000000 extra",
+	} {
+		if _, err := parseInfluxLineProtocol("ok value=1\n"+line, time.Nanosecond, "default", time.Now()); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+			t.Errorf("expected an error on line 2 for %q, got %v", line, err)
+		}
+	}
+}
+
+func TestInfluxWriteHandler(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0, WithInfluxWrite())
+
+	body := "cpu,host=edge-1 usage_user=12.5,usage_system=3.1 1700000000000\nmem,host=edge-1 used=512i 1700000000000"
+	req := httptest.NewRequest(http.MethodPost, "/write?db=edge-agent&precision=ms", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.routes["/write"](rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(proc.metrics) != 3 || proc.batches != 1 {
+		t.Fatalf("expected 3 metrics in one batch, got %d in %d", len(proc.metrics), proc.batches)
+	}
+	for _, metric := range proc.metrics {
+		if metric.Service != "edge-agent" || metric.Tags["host"] != "edge-1" || !metric.Timestamp.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("unexpected metric: %+v", metric)
+		}
+	}
+
+	// Malformed bodies and unknown precisions are rejected without writing
+	for _, target := range []string{"/write", "/write?precision=d"} {
+		rec := httptest.NewRecorder()
+		server.routes["/write"](rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader("cpu usage=1\ncpu usage")))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", target, rec.Code)
+		}
+	}
+	if len(proc.metrics) != 3 {
+		t.Errorf("expected no metrics from rejected writes, got %d", len(proc.metrics))
+	}
+}
+
+func TestInfluxWriteHandler_Disabled(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+	if _, ok := server.routes["/write"]; ok {
+		t.Error("expected /write to be served only with WithInfluxWrite")
+	}
+}
@@ -1,9 +1,9 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"time"
@@ -43,17 +43,20 @@ func (s *Server) logsHandler() http.HandlerFunc {
 		}
 
 		// Read the request body
-		body, err := io.ReadAll(io.LimitReader(r.Body, 1048576)) // 1MB limit
-		if err != nil {
-			log.Printf("Error reading request body: %v", err)
-			http.Error(w, "Error reading request", http.StatusBadRequest)
+		body, ok := readBody(w, r, s.maxBodySize)
+		if !ok {
 			return
 		}
-		defer r.Body.Close()
 
-		// Parse the request
+		// Parse the request, accepting off-schema JSON in lenient mode
 		var logReq LogRequest
-		if err := json.Unmarshal(body, &logReq); err != nil {
+		var err error
+		if s.lenientLogs {
+			logReq, err = parseLenientLogRequest(body, s.logMessageFields)
+		} else {
+			err = json.Unmarshal(body, &logReq)
+		}
+		if err != nil {
 			log.Printf("Error parsing JSON: %v", err)
 			http.Error(w, "Invalid JSON format", http.StatusBadRequest)
 			return
@@ -109,9 +112,7 @@ func (s *Server) logsHandler() http.HandlerFunc {
 
 		// Add optional fields
 		if logReq.Tags != nil {
-			for k, v := range logReq.Tags {
-				logEntry.AddTag(k, v)
-			}
+			logEntry.AddTags(logReq.Tags)
 		}
 		if logReq.Env != "" {
 			logEntry.WithEnv(logReq.Env)
@@ -133,11 +134,15 @@ func (s *Server) logsHandler() http.HandlerFunc {
 				logEntry.Timestamp = ts
 			}
 		}
+		if err := s.checkTimestamp("timestamp", logEntry.Timestamp, s.maxTimestampAge.Logs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Process the log entry
 		if err := s.processor.ProcessLog(logEntry); err != nil {
 			log.Printf("Error processing log: %v", err)
-			http.Error(w, "Error processing log", http.StatusInternalServerError)
+			http.Error(w, "Error processing log", processErrorStatus(err))
 			return
 		}
 
@@ -155,6 +160,74 @@ func (s *Server) logsHandler() http.HandlerFunc {
 	}
 }
 
+// parseLenientLogRequest parses an arbitrary JSON object into a LogRequest.
+// The message is taken from the first of messageFields that is set, known
+// fields are mapped as usual and every other top-level key is captured as a
+// tag. Values that aren't strings are rendered as JSON.
+func parseLenientLogRequest(body []byte, messageFields []string) (LogRequest, error) {
+	var logReq LogRequest
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return logReq, err
+	}
+
+	for _, field := range messageFields {
+		if message := lenientString(fields[field]); message != "" {
+			logReq.Message = message
+			delete(fields, field)
+			break
+		}
+	}
+
+	// Explicit tags take precedence over captured top-level keys
+	explicitTags, _ := fields["tags"].(map[string]interface{})
+	delete(fields, "tags")
+
+	known := map[string]*string{
+		"level":     &logReq.Level,
+		"service":   &logReq.Service,
+		"timestamp": &logReq.Timestamp,
+		"trace_id":  &logReq.TraceID,
+		"span_id":   &logReq.SpanID,
+		"env":       &logReq.Env,
+		"host":      &logReq.Host,
+		"source":    &logReq.Source,
+	}
+
+	logReq.Tags = make(map[string]string)
+	for k, v := range fields {
+		if target, ok := known[k]; ok {
+			*target = lenientString(v)
+			continue
+		}
+		logReq.Tags[k] = lenientString(v)
+	}
+	for k, v := range explicitTags {
+		logReq.Tags[k] = lenientString(v)
+	}
+
+	return logReq, nil
+}
+
+// lenientString renders a decoded JSON value as a string
+func lenientString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	default:
+		b, _ := json.Marshal(value)
+		return string(b)
+	}
+}
+
 // logsBatchHandler returns a handler for batch log ingestion
 func (s *Server) logsBatchHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -164,24 +237,36 @@ func (s *Server) logsBatchHandler() http.HandlerFunc {
 		}
 
 		// Read and decode the request body
+		body, ok := readBody(w, r, s.maxBodySize)
+		if !ok {
+			return
+		}
 		var logs []models.LogEntry
-		if err := json.NewDecoder(r.Body).Decode(&logs); err != nil {
+		if err := json.Unmarshal(body, &logs); err != nil {
 			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
 			return
 		}
 
-		// Process each log entry
+		// Reject the batch before processing any entry
+		for i := range logs {
+			if err := s.checkTimestamp(fmt.Sprintf("log %d timestamp", i), logs[i].Timestamp, s.maxTimestampAge.Logs); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Process the entries as one batch, written in a single transaction
+		batch := make([]*models.LogEntry, len(logs))
 		for i := range logs {
 			// Generate ID if not provided
 			if logs[i].ID == "" {
 				logs[i].ID = generateID()
 			}
-
-			// Process the log entry
-			if err := s.processor.ProcessLog(&logs[i]); err != nil {
-				http.Error(w, fmt.Sprintf("Error processing log: %v", err), http.StatusInternalServerError)
-				return
-			}
+			batch[i] = &logs[i]
+		}
+		if err := s.processor.ProcessLogs(batch); err != nil {
+			http.Error(w, fmt.Sprintf("Error processing logs: %v", err), processErrorStatus(err))
+			return
 		}
 
 		// Send success response
@@ -203,18 +288,137 @@ func (s *Server) apiLogsHandler() http.HandlerFunc {
 		}
 
 		// Parse query parameters
-		query := parseQueryParams(r)
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Reject queries that would scan everything
+		if err := s.checkQueryCost(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Cursors continue the default order from a previous page
+		if query.Cursor != "" {
+			if query.OrderBy != "" {
+				http.Error(w, "cursor cannot be combined with order_by", http.StatusBadRequest)
+				return
+			}
+			if _, err := models.ParseLogCursor(query.Cursor); err != nil {
+				http.Error(w, "Invalid cursor", http.StatusBadRequest)
+				return
+			}
+		}
 
 		// Query logs from storage (add this to the processor interface)
+		start := time.Now()
 		logs, err := s.processor.QueryLogs(query)
+		s.observeQuery("logs", start)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error querying logs: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		// Send response
+		writeResponse(w, r, http.StatusOK, logs)
+	}
+}
+
+// LogTagsRequest is the body of a bulk log tag update: Tags are merged into
+// the tags of every log matching Filter, overwriting values of the same keys
+type LogTagsRequest struct {
+	Filter LogTagsFilter     `json:"filter"`
+	Tags   map[string]string `json:"tags"`
+}
+
+// LogTagsFilter selects the logs of a bulk tag update. At least one field
+// must be set so that an update never tags every log by accident.
+type LogTagsFilter struct {
+	Service string            `json:"service,omitempty"`
+	Level   string            `json:"level,omitempty"`
+	TraceID string            `json:"trace_id,omitempty"`
+	Search  string            `json:"search,omitempty"`
+	Since   time.Time         `json:"since,omitempty"`
+	Until   time.Time         `json:"until,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"` // Tag values the logs must have
+}
+
+// query returns the query selecting the logs of the filter, or false if the
+// filter is empty
+func (f LogTagsFilter) query() (*models.QueryParams, bool) {
+	query := &models.QueryParams{
+		Service: f.Service,
+		Level:   f.Level,
+		TraceID: f.TraceID,
+		Search:  f.Search,
+		Since:   f.Since,
+		Until:   f.Until,
+		Filters: f.Tags,
+	}
+	empty := query.Service == "" && query.Level == "" && query.TraceID == "" && query.Search == "" &&
+		query.Since.IsZero() && query.Until.IsZero() && len(query.Filters) == 0
+	return query, !empty
+}
+
+// apiLogTagsHandler returns a handler merging tags into the stored logs
+// matching a filter. As an administrative operation it is only available
+// when API keys are configured.
+func (s *Server) apiLogTagsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if len(s.apiKeys) == 0 {
+			http.Error(w, "Bulk tag updates require API keys to be configured", http.StatusForbidden)
+			return
+		}
+
+		body, ok := readBody(w, r, s.maxBodySize)
+		if !ok {
+			return
+		}
+		var req LogTagsRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+
+		query, ok := req.Filter.query()
+		if !ok {
+			http.Error(w, "A filter is required", http.StatusBadRequest)
+			return
+		}
+		if len(req.Tags) == 0 {
+			http.Error(w, "At least one tag is required", http.StatusBadRequest)
+			return
+		}
+		for key := range req.Tags {
+			if key == "" {
+				http.Error(w, "Tag keys cannot be empty", http.StatusBadRequest)
+				return
+			}
+		}
+
+		start := time.Now()
+		updated, err := s.processor.UpdateLogTags(query, req.Tags)
+		s.observeQuery("log tags", start)
+		if err != nil {
+			log.Printf("Error updating log tags: %v", err)
+			http.Error(w, fmt.Sprintf("Error updating log tags: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Tagged %d logs with %v", updated, req.Tags)
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(logs)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "ok",
+			"updated": updated,
+		})
 	}
 }
@@ -0,0 +1,296 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+func postLog(server *Server, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/logs", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.logsHandler()(rec, req)
+	return rec
+}
+
+func TestLogsHandler_Lenient(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0, WithLenientLogParsing())
+
+	// A typical structured logger line: msg instead of message, extra fields
+	// at the top level and non-string values
+	body := `{"msg": "payment declined", "level": "warn", "service": "billing",
+		"user_id": 42, "amount": 19.99, "retry": false, "card": {"brand": "visa"},
+		"tags": {"region": "eu"}, "region": "us"}`
+	if rec := postLog(server, body); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(proc.logs) != 1 {
+		t.Fatalf("expected 1 log, got %d", len(proc.logs))
+	}
+	entry := proc.logs[0]
+	if entry.Message != "payment declined" || entry.Service != "billing" || entry.Level != models.LogLevelWarning {
+		t.Errorf("unexpected log entry: %+v", entry)
+	}
+
+	expected := map[string]string{
+		"user_id": "42",
+		"amount":  "19.99",
+		"retry":   "false",
+		"card":    `{"brand":"visa"}`,
+		"region":  "eu", // explicit tags win over top-level keys
+	}
+	for k, v := range expected {
+		if entry.Tags[k] != v {
+			t.Errorf("expected tag %s=%s, got %q", k, v, entry.Tags[k])
+		}
+	}
+	for _, k := range []string{"msg", "level", "service"} {
+		if _, ok := entry.Tags[k]; ok {
+			t.Errorf("expected known field %s not to be captured as a tag", k)
+		}
+	}
+
+	// Messages and services are still required
+	if rec := postLog(server, `{"text": "no message field", "service": "billing"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a message, got %d", rec.Code)
+	}
+	if rec := postLog(server, `["not", "an", "object"]`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a non-object body, got %d", rec.Code)
+	}
+}
+
+func TestLogsHandler_LenientMessageFields(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0, WithLenientLogParsing("event", "text"))
+
+	if rec := postLog(server, `{"text": "fallback", "event": "user signed up", "service": "auth"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	entry := proc.logs[0]
+	if entry.Message != "user signed up" {
+		t.Errorf("expected message from the first configured field, got %q", entry.Message)
+	}
+	if entry.Tags["text"] != "fallback" {
+		t.Errorf("expected unused message field to be kept as a tag, got %v", entry.Tags)
+	}
+
+	// The default fields are replaced, not extended
+	if rec := postLog(server, `{"message": "hello", "service": "auth"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 when only the default field is set, got %d", rec.Code)
+	}
+}
+
+func TestLogsHandler_StrictByDefault(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	if rec := postLog(server, `{"msg": "hello", "service": "auth"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for msg without lenient parsing, got %d", rec.Code)
+	}
+	if rec := postLog(server, `{"message": "hello", "service": "auth", "tags": {"attempt": 3}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for non-string tags without lenient parsing, got %d", rec.Code)
+	}
+	if len(proc.logs) != 0 {
+		t.Errorf("expected no logs to be processed, got %d", len(proc.logs))
+	}
+}
+
+func TestAPILogsHandler_Cursor(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	saveLogs := func(prefix string, n int, at time.Time) {
+		for i := 0; i < n; i++ {
+			entry := models.NewLogEntry("api", "message", models.LogLevelInfo)
+			entry.ID = fmt.Sprintf("%s-%d", prefix, i)
+			entry.Timestamp = at.Add(time.Duration(i) * time.Second)
+			if err := store.SaveLog(entry); err != nil {
+				t.Fatalf("failed to save log: %v", err)
+			}
+		}
+	}
+	getLogs := func(rawQuery string) ([]string, string) {
+		rec := httptest.NewRecorder()
+		server.apiLogsHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/logs?"+rawQuery, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Logs       []map[string]interface{} `json:"logs"`
+			Pagination struct {
+				NextCursor string `json:"next_cursor"`
+			} `json:"pagination"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		var ids []string
+		for _, log := range resp.Logs {
+			ids = append(ids, log["id"].(string))
+		}
+		return ids, resp.Pagination.NextCursor
+	}
+
+	start := time.Now().UTC().Add(-10 * time.Minute)
+	saveLogs("old", 3, start)
+
+	first, cursor := getLogs("limit=2")
+	if len(first) != 2 || cursor == "" {
+		t.Fatalf("expected a full first page with a cursor, got %v %q", first, cursor)
+	}
+
+	saveLogs("new", 2, start.Add(time.Minute))
+
+	second, cursor := getLogs("limit=2&cursor=" + url.QueryEscape(cursor))
+	if len(second) != 1 || second[0] != "old-0" || cursor != "" {
+		t.Errorf("expected only old-0 after the cursor and no next cursor, got %v %q", second, cursor)
+	}
+
+	for _, rawQuery := range []string{"cursor=bogus", "cursor=" + models.LogCursor{Timestamp: start, ID: "old-1"}.Encode() + "&order_by=message"} {
+		rec := httptest.NewRecorder()
+		server.apiLogsHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/logs?"+rawQuery, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", rawQuery, rec.Code)
+		}
+	}
+}
+
+func TestAPILogTagsHandler(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	baseURL := startTestServer(t, NewServer(processor.NewStorageProcessor(store), 0, WithAPIKeys("secret")))
+
+	for i, entry := range []*models.LogEntry{
+		models.NewLogEntry("api", "timeout", models.LogLevelError),
+		models.NewLogEntry("api", "refused", models.LogLevelError),
+		models.NewLogEntry("api", "ok", models.LogLevelInfo),
+		models.NewLogEntry("billing", "declined", models.LogLevelError),
+	} {
+		entry.ID = fmt.Sprintf("log-%d", i)
+		entry.AddTag("region", "eu")
+		if err := store.SaveLog(entry); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	patch := func(key, body string) (int, string) {
+		req, _ := http.NewRequest(http.MethodPatch, baseURL+"/api/logs/tags", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var b strings.Builder
+		io.Copy(&b, resp.Body)
+		return resp.StatusCode, b.String()
+	}
+
+	body := `{"filter": {"service": "api", "level": "ERROR"}, "tags": {"incident": "INC-123", "region": "us"}}`
+	if status, _ := patch("", body); status != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without a key, got %d", status)
+	}
+	for _, invalid := range []string{
+		`{"filter": {}, "tags": {"incident": "INC-123"}}`,
+		`{"filter": {"service": "api"}, "tags": {}}`,
+		`{"filter": {"service": "api"}, "tags": {"": "INC-123"}}`,
+		`{"filter": `,
+	} {
+		if status, _ := patch("secret", invalid); status != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", invalid, status)
+		}
+	}
+
+	status, resp := patch("secret", body)
+	if status != http.StatusOK || !strings.Contains(resp, `"updated":2`) {
+		t.Fatalf("expected 2 logs updated, got %d: %s", status, resp)
+	}
+
+	// Only the filtered logs change, keeping their other tags
+	result, err := store.QueryLogs(&models.QueryParams{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for _, entry := range result["logs"].([]map[string]interface{}) {
+		tags := entry["tags"].(map[string]string)
+		switch entry["id"] {
+		case "log-0", "log-1":
+			if tags["incident"] != "INC-123" || tags["region"] != "us" {
+				t.Errorf("expected %s to be tagged, got %v", entry["id"], tags)
+			}
+		default:
+			if tags["incident"] != "" || tags["region"] != "eu" {
+				t.Errorf("expected %s to be unchanged, got %v", entry["id"], tags)
+			}
+		}
+	}
+}
+
+func TestAPILogTagsHandler_RequiresAPIKeys(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+
+	rec := httptest.NewRecorder()
+	body := `{"filter": {"service": "api"}, "tags": {"incident": "INC-123"}}`
+	server.apiLogTagsHandler()(rec, httptest.NewRequest(http.MethodPatch, "/api/logs/tags", strings.NewReader(body)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 without API keys, got %d", rec.Code)
+	}
+}
+
+func TestLogsBatchHandler_RateLimit(t *testing.T) {
+	proc := &stubProcessor{}
+	limited := processor.NewRateLimitProcessor(proc, processor.RateLimitConfig{Default: processor.RateLimit{Rate: 0.001, Burst: 3}})
+	server := NewServer(limited, 0)
+
+	post := func(services ...string) int {
+		var logs []string
+		for _, service := range services {
+			logs = append(logs, fmt.Sprintf(`{"message": "hello", "service": %q}`, service))
+		}
+		rec := httptest.NewRecorder()
+		server.routes["/logs/batch"](rec, httptest.NewRequest(http.MethodPost, "/logs/batch", strings.NewReader("["+strings.Join(logs, ",")+"]")))
+		return rec.Code
+	}
+
+	// A batch larger than the burst is accepted while the bucket is full
+	if status := post("api", "api", "api", "api", "api"); status != http.StatusOK {
+		t.Errorf("expected status 200 for a batch over the burst, got %d", status)
+	}
+	if status := post("api"); status != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 once the budget is spent, got %d", status)
+	}
+
+	// A rejected batch doesn't spend the budget of its other services
+	if status := post("worker", "worker", "api"); status != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 for a batch with a service over budget, got %d", status)
+	}
+	if status := post("worker", "worker", "worker"); status != http.StatusOK {
+		t.Errorf("expected status 200 for a service within budget, got %d", status)
+	}
+	if len(proc.logs) != 8 {
+		t.Errorf("expected 8 logs processed, got %d", len(proc.logs))
+	}
+}
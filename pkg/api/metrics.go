@@ -1,16 +1,22 @@
 package api
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
 )
 
 // MetricRequest represents the expected request format for submitting metrics
@@ -29,7 +35,18 @@ type MetricRequest struct {
 // HistogramMetricRequest extends MetricRequest with histogram-specific fields
 type HistogramMetricRequest struct {
 	MetricRequest
-	Buckets []float64 `json:"buckets,omitempty"` // Bucket boundaries for histogram
+	Buckets      []float64 `json:"buckets,omitempty"`      // Bucket boundaries for histogram
+	Observations []float64 `json:"observations,omitempty"` // Values to observe; Value is observed when empty
+}
+
+// HistogramSubmitRequest represents a pre-aggregated histogram, such as one
+// exported by a Prometheus client library. Bucket counts are cumulative and
+// Count also includes observations above the largest bound (the +Inf bucket).
+type HistogramSubmitRequest struct {
+	MetricRequest
+	Buckets []models.HistogramBucket `json:"buckets"` // Cumulative bucket counts by upper bound
+	Sum     float64                  `json:"sum"`     // Sum of all observed values
+	Count   uint64                   `json:"count"`   // Total number of observations
 }
 
 // MetricResponse represents the API response for metric submission
@@ -40,6 +57,14 @@ type MetricResponse struct {
 	TraceID string `json:"trace_id,omitempty"`
 }
 
+// MetricBatchResponse represents the API response for batch metric submission
+type MetricBatchResponse struct {
+	Status    string           `json:"status"`
+	Processed int              `json:"processed"`
+	Failed    int              `json:"failed"`
+	Results   []MetricResponse `json:"results"`
+}
+
 // metricsHandler returns a handler for metric ingestion and fetching
 func (s *Server) metricsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -57,13 +82,10 @@ func (s *Server) metricsHandler() http.HandlerFunc {
 // handleMetricPost processes POST requests to /metrics for submitting metrics
 func (s *Server) handleMetricPost(w http.ResponseWriter, r *http.Request) {
 	// Read the request body
-	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576)) // 1MB limit
-	if err != nil {
-		log.Printf("Error reading request body: %v", err)
-		http.Error(w, "Error reading request", http.StatusBadRequest)
+	body, ok := readBody(w, r, s.maxBodySize)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	// Extract trace context from headers (to be used if not in the request body)
 	traceCtx := ExtractTraceContext(r)
@@ -91,30 +113,14 @@ func (s *Server) handleJSONMetric(w http.ResponseWriter, body []byte, traceCtx *
 		return
 	}
 
-	// Validate required fields
-	if metricReq.Name == "" {
-		http.Error(w, "Metric name is required", http.StatusBadRequest)
-		return
-	}
-	if metricReq.Service == "" {
-		http.Error(w, "Service name is required", http.StatusBadRequest)
-		return
-	}
-
 	// Apply trace context from headers if not in request
 	if metricReq.TraceID == "" && traceCtx != nil {
 		metricReq.TraceID = traceCtx.TraceID
 	}
 
-	// Determine metric type
-	var metricType models.MetricType
-	switch strings.ToLower(metricReq.Type) {
-	case "counter", "c":
-		metricType = models.MetricTypeCounter
-	case "gauge", "g":
-		metricType = models.MetricTypeGauge
-	case "histogram", "h":
-		// If it's a histogram, we need to check if we have bucket information
+	metric, err := s.metricFromRequest(metricReq)
+	if errors.Is(err, errHistogramMetric) {
+		// Histograms also carry their buckets or observations
 		var histogramReq HistogramMetricRequest
 		if err := json.Unmarshal(body, &histogramReq); err != nil {
 			log.Printf("Error parsing histogram metric: %v", err)
@@ -128,12 +134,16 @@ func (s *Server) handleJSONMetric(w http.ResponseWriter, body []byte, traceCtx *
 		}
 
 		// Create and save histogram metric
-		histMetric := s.createHistogramMetric(histogramReq)
+		histMetric, err := s.createHistogramMetric(histogramReq)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		// Access the embedded Metric field for processing
-		if err := s.processor.ProcessMetric(&histMetric.Metric); err != nil {
+		// Process the histogram with its buckets
+		if err := s.processor.ProcessHistogram(histMetric); err != nil {
 			log.Printf("Error processing histogram metric: %v", err)
-			http.Error(w, "Error processing metric", http.StatusInternalServerError)
+			http.Error(w, "Error processing metric", processErrorStatus(err))
 			return
 		}
 
@@ -149,18 +159,16 @@ func (s *Server) handleJSONMetric(w http.ResponseWriter, body []byte, traceCtx *
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(response)
 		return
-	default:
-		// Default to gauge if not specified
-		metricType = models.MetricTypeGauge
 	}
-
-	// Create a metric entry
-	metric := s.createMetric(metricReq, metricType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Process the metric
 	if err := s.processor.ProcessMetric(metric); err != nil {
 		log.Printf("Error processing metric: %v", err)
-		http.Error(w, "Error processing metric", http.StatusInternalServerError)
+		http.Error(w, "Error processing metric", processErrorStatus(err))
 		return
 	}
 
@@ -183,9 +191,7 @@ func (s *Server) createMetric(req MetricRequest, metricType models.MetricType) *
 
 	// Add optional fields
 	if req.Tags != nil {
-		for k, v := range req.Tags {
-			metric.AddTag(k, v)
-		}
+		metric.AddTags(req.Tags)
 	}
 	if req.TraceID != "" {
 		metric.WithTrace(req.TraceID)
@@ -211,21 +217,23 @@ func (s *Server) createMetric(req MetricRequest, metricType models.MetricType) *
 	return metric
 }
 
-// createHistogramMetric creates a new histogram metric from the request
-func (s *Server) createHistogramMetric(req HistogramMetricRequest) *models.HistogramMetric {
-	// Use default buckets if none provided
+// createHistogramMetric creates a new histogram metric from the request,
+// failing if its bucket bounds are invalid
+func (s *Server) createHistogramMetric(req HistogramMetricRequest) (*models.HistogramMetric, error) {
+	// Use the configured default buckets if none provided
 	buckets := req.Buckets
 	if len(buckets) == 0 {
-		buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+		buckets = s.histogramBuckets
 	}
 
-	histMetric := models.NewHistogramMetric(req.Name, req.Service, buckets)
+	histMetric, err := models.NewHistogramMetric(req.Name, req.Service, buckets)
+	if err != nil {
+		return nil, err
+	}
 
 	// Add optional fields
 	if req.Tags != nil {
-		for k, v := range req.Tags {
-			histMetric.AddTag(k, v)
-		}
+		histMetric.AddTags(req.Tags)
 	}
 	if req.TraceID != "" {
 		histMetric.WithTrace(req.TraceID)
@@ -247,11 +255,251 @@ func (s *Server) createHistogramMetric(req HistogramMetricRequest) *models.Histo
 			histMetric.Timestamp = ts
 		}
 	}
+	if err := s.checkTimestamp("timestamp", histMetric.Timestamp, s.maxTimestampAge.Metrics); err != nil {
+		return nil, err
+	}
 
-	// Record the observation
-	histMetric.Observe(req.Value)
+	// Record the observations
+	if len(req.Observations) == 0 {
+		histMetric.Observe(req.Value)
+	}
+	for _, value := range req.Observations {
+		histMetric.Observe(value)
+	}
 
-	return histMetric
+	return histMetric, nil
+}
+
+// histogramSubmitHandler returns a handler for pre-aggregated histogram submission
+func (s *Server) histogramSubmitHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Read the request body
+		body, ok := readBody(w, r, s.maxBodySize)
+		if !ok {
+			return
+		}
+
+		var req HistogramSubmitRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			log.Printf("Error parsing histogram: %v", err)
+			http.Error(w, "Invalid histogram format", http.StatusBadRequest)
+			return
+		}
+
+		// Validate required fields
+		if req.Name == "" {
+			http.Error(w, "Metric name is required", http.StatusBadRequest)
+			return
+		}
+		if req.Service == "" {
+			http.Error(w, "Service name is required", http.StatusBadRequest)
+			return
+		}
+
+		// Apply trace context from headers if not in request
+		if traceCtx := ExtractTraceContext(r); req.TraceID == "" && traceCtx != nil {
+			req.TraceID = traceCtx.TraceID
+		}
+
+		histMetric := s.createSubmittedHistogram(req)
+		if err := s.checkTimestamp("timestamp", histMetric.Timestamp, s.maxTimestampAge.Metrics); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := histMetric.ValidateBuckets(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.processor.ProcessHistogram(histMetric); err != nil {
+			log.Printf("Error processing histogram metric: %v", err)
+			http.Error(w, "Error processing metric", processErrorStatus(err))
+			return
+		}
+
+		response := MetricResponse{
+			Status:  "ok",
+			ID:      histMetric.ID,
+			Message: "Histogram metric received and processed",
+			TraceID: histMetric.TraceID,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// createSubmittedHistogram creates a histogram metric from pre-aggregated bucket counts
+func (s *Server) createSubmittedHistogram(req HistogramSubmitRequest) *models.HistogramMetric {
+	return &models.HistogramMetric{
+		Metric:     *s.createMetric(req.MetricRequest, models.MetricTypeHistogram),
+		Buckets:    req.Buckets,
+		Sum:        req.Sum,
+		Count:      req.Count,
+		Percentile: make(map[float64]float64),
+	}
+}
+
+// maxMetricBatchBody caps the request body of a metric batch. Batches are
+// expected to be much larger than single submissions.
+const maxMetricBatchBody = 10 << 20 // 10MB
+
+// metricsBatchHandler returns a handler for submitting many metrics at once.
+// Each metric is validated independently and reported with its own status;
+// the valid ones are persisted together in a single write.
+func (s *Server) metricsBatchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Read the request body
+		body, ok := readBody(w, r, s.batchBodyLimit())
+		if !ok {
+			return
+		}
+
+		var metricReqs []MetricRequest
+		if err := json.Unmarshal(body, &metricReqs); err != nil {
+			log.Printf("Error parsing JSON: %v", err)
+			http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+
+		if len(metricReqs) == 0 {
+			http.Error(w, "At least one metric is required", http.StatusBadRequest)
+			return
+		}
+
+		traceCtx := ExtractTraceContext(r)
+
+		response := MetricBatchResponse{
+			Status:  "ok",
+			Results: make([]MetricResponse, len(metricReqs)),
+		}
+
+		// Validate and convert each metric, remembering which results belong
+		// to the metrics that will be written
+		metrics := make([]*models.Metric, 0, len(metricReqs))
+		pending := make([]int, 0, len(metricReqs))
+		for i, metricReq := range metricReqs {
+			result := &response.Results[i]
+
+			if metricReq.TraceID == "" && traceCtx != nil {
+				metricReq.TraceID = traceCtx.TraceID
+			}
+
+			metric, err := s.metricFromRequest(metricReq)
+			if err != nil {
+				result.Status, result.Message = "error", err.Error()
+				response.Failed++
+				continue
+			}
+
+			// Assign IDs up front so that metrics of the same batch never collide
+			metric.ID = generateID()
+
+			result.ID = metric.ID
+			result.TraceID = metric.TraceID
+			metrics = append(metrics, metric)
+			pending = append(pending, i)
+		}
+
+		if len(metrics) > 0 {
+			if err := s.processor.ProcessMetrics(metrics); err != nil {
+				log.Printf("Error processing metric batch: %v", err)
+				message := "Error processing metric"
+				if errors.Is(err, processor.ErrRateLimited) {
+					message = err.Error()
+				}
+				for _, i := range pending {
+					response.Results[i].Status = "error"
+					response.Results[i].Message = message
+				}
+				response.Failed += len(pending)
+			} else {
+				for _, i := range pending {
+					response.Results[i].Status = "ok"
+				}
+				response.Processed = len(pending)
+			}
+		}
+
+		if response.Failed > 0 {
+			response.Status = "partial"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// validateMetricType checks a metric type submitted as a single value,
+// other than the ones handled explicitly
+func validateMetricType(metricType string) error {
+	switch t := models.MetricType(strings.ToLower(metricType)); {
+	case !t.IsValid():
+		return fmt.Errorf("unknown metric type %q: must be one of counter, gauge, histogram, summary or info", metricType)
+	case t == models.MetricTypeExponentialHistogram:
+		return fmt.Errorf("exponential histograms cannot be submitted as single values")
+	}
+	return nil
+}
+
+// errHistogramMetric is returned by metricFromRequest for histograms, which
+// carry buckets besides the fields of a MetricRequest
+var errHistogramMetric = errors.New("histogram metrics must be submitted to /metrics/histogram")
+
+// metricFromRequest validates a submitted metric and converts it. Metrics
+// without a type are gauges and info metrics take the value 1.
+func (s *Server) metricFromRequest(req MetricRequest) (*models.Metric, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("metric name is required")
+	}
+	if req.Service == "" {
+		return nil, fmt.Errorf("service name is required")
+	}
+
+	var metricType models.MetricType
+	switch strings.ToLower(req.Type) {
+	case "counter", "c":
+		metricType = models.MetricTypeCounter
+	case "gauge", "g", "":
+		metricType = models.MetricTypeGauge
+	case "info", "i":
+		if req.Value != 0 && req.Value != 1 {
+			return nil, fmt.Errorf("info metrics must have value 1")
+		}
+		req.Value = 1
+		metricType = models.MetricTypeInfo
+	case "histogram", "h":
+		return nil, errHistogramMetric
+	default:
+		if err := validateMetricType(req.Type); err != nil {
+			return nil, err
+		}
+		metricType = models.MetricType(strings.ToLower(req.Type))
+	}
+
+	metric := s.createMetric(req, metricType)
+	if err := s.checkTimestamp("timestamp", metric.Timestamp, s.maxTimestampAge.Metrics); err != nil {
+		return nil, err
+	}
+	if metricType == models.MetricTypeInfo {
+		if err := metric.ValidateInfo(); err != nil {
+			return nil, err
+		}
+	}
+
+	return metric, nil
 }
 
 // handlePrometheusMetric processes metrics in Prometheus format
@@ -274,7 +522,7 @@ func (s *Server) handlePrometheusMetric(w http.ResponseWriter, body []byte, trac
 	for _, metric := range metrics {
 		if err := s.processor.ProcessMetric(metric); err != nil {
 			log.Printf("Error processing Prometheus metric: %v", err)
-			http.Error(w, "Error processing metrics", http.StatusInternalServerError)
+			http.Error(w, "Error processing metrics", processErrorStatus(err))
 			return
 		}
 	}
@@ -376,6 +624,8 @@ func parsePrometheusFormat(input string) []*models.Metric {
 			metricType = models.MetricTypeGauge
 		case "summary":
 			metricType = models.MetricTypeSummary
+		case "info":
+			metricType = models.MetricTypeInfo
 		default:
 			metricType = models.MetricTypeGauge
 		}
@@ -391,9 +641,7 @@ func parsePrometheusFormat(input string) []*models.Metric {
 		metric := models.NewMetric(metricName, metricValue, metricType, service)
 
 		// Add labels as tags
-		for k, v := range metricLabels {
-			metric.AddTag(k, v)
-		}
+		metric.AddTags(metricLabels)
 
 		metrics = append(metrics, metric)
 	}
@@ -401,32 +649,490 @@ func parsePrometheusFormat(input string) []*models.Metric {
 	return metrics
 }
 
-// handleMetricGet processes GET requests to /metrics for scraping metrics
+// defaultPrometheusFreshness is how recently a series must have been seen to
+// be exposed to Prometheus scrapes unless configured otherwise
+const defaultPrometheusFreshness = time.Hour
+
+// prometheusHandler returns a handler exposing stored metrics for Prometheus to scrape
+func (s *Server) prometheusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleMetricGet(w, r)
+	}
+}
+
+// handleMetricGet processes GET requests for scraping metrics. Counters and
+// gauges are exposed with the latest value of each series, histograms with
+// the buckets of their latest snapshot. Series without a sample within the
+// freshness window are omitted, so that series which stopped reporting go
+// stale in Prometheus instead of being exposed with their last value forever.
 func (s *Server) handleMetricGet(w http.ResponseWriter, _ *http.Request) {
-	// Retrieve metrics from storage
-	// TODO: Implement proper metric retrieval from storage
-	// For now, we'll return a sample Prometheus format output
+	query := &models.QueryParams{Since: s.now().UTC().Add(-s.prometheusFreshness)}
+
+	// Keep the latest sample of each series
+	latest := make(map[string]*models.Metric)
+	start := time.Now()
+	err := s.processor.StreamMetrics(query, func(metric *models.Metric) error {
+		// Histogram samples are exported from their bucket data below
+		if metric.Type == models.MetricTypeHistogram || metric.Type == models.MetricTypeExponentialHistogram {
+			return nil
+		}
+		key := prometheusSeriesKey(metric.Name, metric.Service, metric.Tags)
+		if prev, ok := latest[key]; !ok || !metric.Timestamp.Before(prev.Timestamp) {
+			latest[key] = metric
+		}
+		return nil
+	})
+	s.observeQuery("prometheus metrics", start)
+	if err != nil {
+		log.Printf("Error querying metrics: %v", err)
+		http.Error(w, "Error querying metrics", http.StatusInternalServerError)
+		return
+	}
 
-	now := time.Now().Unix()
+	latestHistograms := make(map[string]*models.HistogramMetric)
+	start = time.Now()
+	histograms, err := s.processor.QueryHistograms(query)
+	s.observeQuery("prometheus histograms", start)
+	if err != nil {
+		log.Printf("Error querying histograms: %v", err)
+		http.Error(w, "Error querying histograms", http.StatusInternalServerError)
+		return
+	}
+	for _, histogram := range histograms {
+		key := prometheusSeriesKey(histogram.Name, histogram.Service, histogram.Tags)
+		if prev, ok := latestHistograms[key]; !ok || !histogram.Timestamp.Before(prev.Timestamp) {
+			latestHistograms[key] = histogram
+		}
+	}
+
+	// Sort series so scrapes are stable
+	keys := make([]string, 0, len(latest))
+	for key := range latest {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	aggregations := make([]storage.MetricAggregation, 0, len(keys))
+	for _, key := range keys {
+		metric := latest[key]
+		aggregations = append(aggregations, storage.MetricAggregation{
+			Name:       metric.Name,
+			Type:       metric.Type,
+			TimeSeries: []storage.MetricTimeSeriesPoint{{Timestamp: metric.Timestamp, Value: metric.Value, Count: 1}},
+			Labels:     prometheusSeriesLabels(metric.Service, metric.Tags),
+		})
+	}
+
+	keys = keys[:0]
+	for key := range latestHistograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	exported := make([]*models.HistogramMetric, 0, len(keys))
+	for _, key := range keys {
+		histogram := *latestHistograms[key]
+		histogram.Tags = prometheusSeriesLabels(histogram.Service, histogram.Tags)
+		exported = append(exported, &histogram)
+	}
 
-	// Set Prometheus content type
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, storage.ExportPrometheusFormat(aggregations))
+	io.WriteString(w, storage.ExportPrometheusHistograms(exported))
+}
+
+// prometheusSeriesLabels returns the labels of a series: its tags plus the service
+func prometheusSeriesLabels(service string, tags map[string]string) map[string]string {
+	labels := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		labels[k] = v
+	}
+	labels["service"] = service
+	return labels
+}
+
+// prometheusSeriesKey identifies a series by its name, service and tags
+func prometheusSeriesKey(name, service string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteString("\x00")
+	b.WriteString(service)
+	for _, k := range keys {
+		b.WriteString("\x00")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(tags[k])
+	}
+	return b.String()
+}
+
+// maxBatchQueries caps the number of queries accepted in a single batch request
+const maxBatchQueries = 50
+
+// batchQueryWorkers bounds how many queries of a batch are evaluated concurrently
+const batchQueryWorkers = 4
+
+// MetricQueryRequest represents a single aggregated metric query
+type MetricQueryRequest struct {
+	Name          string            `json:"name"`                     // Metric name
+	Service       string            `json:"service,omitempty"`        // Service name
+	Tags          map[string]string `json:"tags,omitempty"`           // Tags to filter by
+	From          string            `json:"from,omitempty"`           // Start time in RFC3339 format
+	To            string            `json:"to,omitempty"`             // End time in RFC3339 format
+	Resolution    string            `json:"resolution,omitempty"`     // Bucket size (e.g., "1m", "5m")
+	Aggregation   string            `json:"aggregation,omitempty"`    // Aggregation function (avg, sum, ...)
+	IncludeLabels []string          `json:"include_labels,omitempty"` // Labels to group by
+}
+
+// MetricQueryResult holds the outcome of one query in a batch
+type MetricQueryResult struct {
+	Index  int                         `json:"index"`
+	Series []storage.MetricAggregation `json:"series,omitempty"`
+	Error  string                      `json:"error,omitempty"`
+}
+
+// toMetricQuery converts the request into a storage query
+func (req MetricQueryRequest) toMetricQuery() (storage.MetricQuery, error) {
+	query := storage.MetricQuery{
+		Name:          req.Name,
+		Service:       req.Service,
+		Tags:          req.Tags,
+		Resolution:    req.Resolution,
+		Aggregation:   req.Aggregation,
+		IncludeLabels: req.IncludeLabels,
+	}
+
+	if req.Name == "" {
+		return query, fmt.Errorf("metric name is required")
+	}
+
+	if req.From != "" {
+		from, err := time.Parse(time.RFC3339, req.From)
+		if err != nil {
+			return query, fmt.Errorf("invalid from time: %w", err)
+		}
+		query.From = from
+	}
+
+	if req.To != "" {
+		to, err := time.Parse(time.RFC3339, req.To)
+		if err != nil {
+			return query, fmt.Errorf("invalid to time: %w", err)
+		}
+		query.To = to
+	}
+
+	return query, nil
+}
+
+// metricsBatchQueryHandler returns a handler that evaluates several aggregated
+// metric queries in one request
+func (s *Server) metricsBatchQueryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, ok := readBody(w, r, s.maxBodySize)
+		if !ok {
+			return
+		}
+		var requests []MetricQueryRequest
+		if err := json.Unmarshal(body, &requests); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if len(requests) == 0 {
+			http.Error(w, "At least one query is required", http.StatusBadRequest)
+			return
+		}
+		if len(requests) > maxBatchQueries {
+			http.Error(w, fmt.Sprintf("Too many queries (max %d)", maxBatchQueries), http.StatusBadRequest)
+			return
+		}
+
+		results := make([]MetricQueryResult, len(requests))
+		jobs := make(chan int)
+
+		var wg sync.WaitGroup
+		workers := batchQueryWorkers
+		if len(requests) < workers {
+			workers = len(requests)
+		}
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for idx := range jobs {
+					results[idx] = s.runMetricQuery(idx, requests[idx])
+				}
+			}()
+		}
+
+		for i := range requests {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"results": results,
+		})
+	}
+}
+
+// runMetricQuery evaluates a single query of a batch
+func (s *Server) runMetricQuery(idx int, req MetricQueryRequest) MetricQueryResult {
+	result := MetricQueryResult{Index: idx}
+
+	query, err := req.toMetricQuery()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	series, err := s.processor.AggregateMetrics(query)
+	s.observeQuery("aggregate_metrics", start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Series = series
+	return result
+}
+
+// metricsExportFlushEvery is how many CSV rows are buffered between flushes
+const metricsExportFlushEvery = 500
+
+// metricsExportHandler returns a handler that streams raw metric samples as
+// CSV for offline analysis. Rows are written as they are read from storage so
+// the full result set is never held in memory, and without a time range
+// every sample is exported.
+func (s *Server) metricsExportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" {
+			http.Error(w, fmt.Sprintf("Unsupported export format: %s", format), http.StatusBadRequest)
+			return
+		}
+
+		query, err := s.parseExportQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Exports are unbounded unless a limit is asked for explicitly
+		if r.URL.Query().Get("limit") == "" {
+			query.Limit = 0
+		}
+
+		flusher, _ := w.(http.Flusher)
+		cw := csv.NewWriter(w)
+		started := false
+		rows := 0
+
+		writeHeader := func() error {
+			started = true
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", `attachment; filename="metrics.csv"`)
+			w.WriteHeader(http.StatusOK)
+			return cw.Write([]string{"timestamp", "value", "tags"})
+		}
+
+		err = s.processor.StreamMetrics(query, func(metric *models.Metric) error {
+			if !started {
+				if err := writeHeader(); err != nil {
+					return err
+				}
+			}
+
+			tags := "{}"
+			if len(metric.Tags) > 0 {
+				tagsJSON, err := json.Marshal(metric.Tags)
+				if err != nil {
+					return fmt.Errorf("failed to marshal tags: %w", err)
+				}
+				tags = string(tagsJSON)
+			}
+
+			if err := cw.Write([]string{
+				metric.Timestamp.UTC().Format(time.RFC3339Nano),
+				strconv.FormatFloat(metric.Value, 'g', -1, 64),
+				tags,
+			}); err != nil {
+				return err
+			}
+
+			rows++
+			if rows%metricsExportFlushEvery == 0 {
+				cw.Flush()
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return cw.Error()
+		})
+
+		if err != nil {
+			if !started {
+				http.Error(w, fmt.Sprintf("Error exporting metrics: %v", err), http.StatusInternalServerError)
+				return
+			}
+			// Headers are already sent; all we can do is stop the stream
+			log.Printf("Error exporting metrics after %d rows: %v", rows, err)
+		}
+
+		if !started {
+			writeHeader()
+		}
+		cw.Flush()
+	}
+}
+
+// apdexHandler returns a handler computing the Apdex score per service of a
+// latency metric over the query window. The target is in the unit of the
+// metric's samples, e.g. /api/apdex?metric=http.request.duration&target=200
+// for a duration recorded in milliseconds.
+func (s *Server) apdexHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		metric := r.URL.Query().Get("metric")
+		if metric == "" {
+			http.Error(w, "metric is required", http.StatusBadRequest)
+			return
+		}
+		target, err := strconv.ParseFloat(r.URL.Query().Get("target"), 64)
+		if err != nil || target <= 0 {
+			http.Error(w, "target must be a positive number", http.StatusBadRequest)
+			return
+		}
+
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query.Name = metric
+		// Every sample in the window counts unless a limit is asked for explicitly
+		if r.URL.Query().Get("limit") == "" {
+			query.Limit = 0
+		}
+
+		// Reject queries that would scan everything
+		if err := s.checkQueryCost(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		calculator := storage.NewApdexCalculator(target)
+		start := time.Now()
+		err = s.processor.StreamMetrics(query, func(m *models.Metric) error {
+			calculator.Add(m.Service, m.Value)
+			return nil
+		})
+		s.observeQuery("apdex", start)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error querying metrics: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"metric":   metric,
+			"target":   target,
+			"services": calculator.Scores(),
+		})
+	}
+}
+
+// defaultGroupPercentiles are computed by /api/metrics/percentiles when no
+// percentiles are given
+var defaultGroupPercentiles = []float64{50, 95, 99}
+
+// metricPercentilesHandler returns a handler computing percentiles of a
+// histogram metric per value of a tag, merging the histograms of each group
+func (s *Server) metricPercentilesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-	// Write sample metrics in Prometheus format
-	fmt.Fprintf(w, "# HELP process_cpu_seconds_total Total user and system CPU time spent in seconds.\n")
-	fmt.Fprintf(w, "# TYPE process_cpu_seconds_total counter\n")
-	fmt.Fprintf(w, "process_cpu_seconds_total %v\n\n", float64(now%100)*0.01)
-
-	fmt.Fprintf(w, "# HELP process_resident_memory_bytes Resident memory size in bytes.\n")
-	fmt.Fprintf(w, "# TYPE process_resident_memory_bytes gauge\n")
-	fmt.Fprintf(w, "process_resident_memory_bytes %v\n\n", 1024*1024*float64(now%100))
-
-	fmt.Fprintf(w, "# HELP http_request_duration_seconds HTTP request duration in seconds.\n")
-	fmt.Fprintf(w, "# TYPE http_request_duration_seconds histogram\n")
-	fmt.Fprintf(w, "http_request_duration_seconds_bucket{le=\"0.1\"} %v\n", now%50)
-	fmt.Fprintf(w, "http_request_duration_seconds_bucket{le=\"0.5\"} %v\n", now%100)
-	fmt.Fprintf(w, "http_request_duration_seconds_bucket{le=\"1\"} %v\n", now%200)
-	fmt.Fprintf(w, "http_request_duration_seconds_bucket{le=\"+Inf\"} %v\n", now%300)
-	fmt.Fprintf(w, "http_request_duration_seconds_sum %v\n", float64(now%1000)*0.01)
-	fmt.Fprintf(w, "http_request_duration_seconds_count %v\n", now%300)
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		groupBy := r.URL.Query().Get("group_by")
+
+		percentiles := defaultGroupPercentiles
+		if value := r.URL.Query().Get("percentiles"); value != "" {
+			percentiles = nil
+			for _, field := range strings.Split(value, ",") {
+				percentile, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+				if err != nil || percentile < 0 || percentile > 100 {
+					http.Error(w, fmt.Sprintf("Invalid percentile %q: must be a number between 0 and 100", field), http.StatusBadRequest)
+					return
+				}
+				percentiles = append(percentiles, percentile)
+			}
+		}
+
+		query, err := s.parseQueryParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query.Name = name
+		// Every histogram in the window counts unless a limit is asked for explicitly
+		if r.URL.Query().Get("limit") == "" {
+			query.Limit = 0
+		}
+
+		// Reject queries that would scan everything
+		if err := s.checkQueryCost(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		start := time.Now()
+		histograms, err := s.processor.QueryHistograms(query)
+		s.observeQuery("metric percentiles", start)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error querying histograms: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, map[string]interface{}{
+			"name":     name,
+			"group_by": groupBy,
+			"groups":   storage.HistogramPercentilesByGroup(histograms, groupBy, percentiles),
+		})
+	}
 }
@@ -0,0 +1,762 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+func TestCreateHistogramMetric_ConfiguredBuckets(t *testing.T) {
+	buckets := []float64{50, 100, 250, 500}
+	server := NewServer(nil, 0, WithHistogramBuckets(buckets))
+
+	req := HistogramMetricRequest{
+		MetricRequest: MetricRequest{
+			Name:    "http.request.duration",
+			Value:   120,
+			Type:    "histogram",
+			Service: "api",
+		},
+	}
+
+	histogram, err := server.createHistogramMetric(req)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(histogram.Buckets) != len(buckets) {
+		t.Fatalf("expected %d buckets, got %d", len(buckets), len(histogram.Buckets))
+	}
+	for i, bound := range buckets {
+		if histogram.Buckets[i].UpperBound != bound {
+			t.Errorf("expected bucket[%d] to have upper bound %f, got %f", i, bound, histogram.Buckets[i].UpperBound)
+		}
+	}
+
+	// The observation should only land in buckets >= 120
+	expectedCounts := []uint64{0, 0, 1, 1}
+	for i, count := range expectedCounts {
+		if histogram.Buckets[i].Count != count {
+			t.Errorf("expected bucket[%d] count %d, got %d", i, count, histogram.Buckets[i].Count)
+		}
+	}
+}
+
+func TestCreateHistogramMetric_ExplicitBucketsWin(t *testing.T) {
+	server := NewServer(nil, 0, WithHistogramBuckets([]float64{50, 100}))
+
+	req := HistogramMetricRequest{
+		MetricRequest: MetricRequest{Name: "latency", Value: 1, Service: "api"},
+		Buckets:       []float64{1, 2, 3},
+	}
+
+	histogram, err := server.createHistogramMetric(req)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(histogram.Buckets) != 3 {
+		t.Errorf("expected explicit buckets to be used, got %d buckets", len(histogram.Buckets))
+	}
+}
+
+func TestMetricsHandler_HistogramPersisted(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	body := `{"name":"latency","type":"histogram","service":"api","buckets":[10,1,5],"observations":[0.5,3,7,20]}`
+	req := httptest.NewRequest(http.MethodPost, "/metrics", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.metricsHandler()(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	server.apiHistogramsHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/histograms?name=latency&time_range=1h", nil))
+	var resp struct {
+		Histograms []*models.HistogramMetric `json:"histograms"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Histograms) != 1 {
+		t.Fatalf("expected the histogram to be stored, got %d histograms", len(resp.Histograms))
+	}
+	histogram := resp.Histograms[0]
+	if histogram.Count != 4 || histogram.Sum != 30.5 {
+		t.Errorf("expected 4 observations summing to 30.5, got %d summing to %v", histogram.Count, histogram.Sum)
+	}
+	expected := []models.HistogramBucket{{UpperBound: 1, Count: 1}, {UpperBound: 5, Count: 2}, {UpperBound: 10, Count: 3}}
+	if len(histogram.Buckets) != len(expected) {
+		t.Fatalf("expected %d buckets, got %v", len(expected), histogram.Buckets)
+	}
+	for i, bucket := range expected {
+		if histogram.Buckets[i] != bucket {
+			t.Errorf("expected bucket %d to be %+v, got %+v", i, bucket, histogram.Buckets[i])
+		}
+	}
+}
+
+func TestMetricsHandler_InvalidHistogramBuckets(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+
+	body := `{"name":"latency","value":1,"type":"histogram","service":"api","buckets":[1,2,2]}`
+	rec := httptest.NewRecorder()
+	server.metricsHandler()(rec, httptest.NewRequest(http.MethodPost, "/metrics", strings.NewReader(body)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "duplicate bucket boundary 2") {
+		t.Errorf("expected the duplicate bound to be reported, got %q", rec.Body.String())
+	}
+}
+
+func TestMetricsBatchQueryHandler(t *testing.T) {
+	var calls int32
+	proc := &stubProcessor{
+		aggregateFn: func(query storage.MetricQuery) ([]storage.MetricAggregation, error) {
+			atomic.AddInt32(&calls, 1)
+			if query.Name == "broken" {
+				return nil, errors.New("boom")
+			}
+			return []storage.MetricAggregation{{
+				Name: query.Name,
+				Type: models.MetricTypeGauge,
+				TimeSeries: []storage.MetricTimeSeriesPoint{
+					{Timestamp: query.From, Value: float64(len(query.Name)), Count: 1},
+				},
+			}}, nil
+		},
+	}
+	server := NewServer(proc, 0)
+
+	body := `[
+		{"name": "cpu", "service": "api", "from": "2024-01-01T00:00:00Z", "aggregation": "avg"},
+		{"name": "broken"},
+		{"name": "memory", "aggregation": "max"},
+		{"name": "cpu", "from": "not-a-time"}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/api/metrics/batch-query", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.metricsBatchQueryHandler()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Results []MetricQueryResult `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(resp.Results))
+	}
+
+	for i, result := range resp.Results {
+		if result.Index != i {
+			t.Errorf("expected result %d to have index %d, got %d", i, i, result.Index)
+		}
+	}
+
+	if resp.Results[0].Error != "" || len(resp.Results[0].Series) != 1 || resp.Results[0].Series[0].Name != "cpu" {
+		t.Errorf("unexpected result for cpu query: %+v", resp.Results[0])
+	}
+	if resp.Results[1].Error != "boom" {
+		t.Errorf("expected error for broken query, got %+v", resp.Results[1])
+	}
+	if resp.Results[2].Error != "" || resp.Results[2].Series[0].TimeSeries[0].Value != 6 {
+		t.Errorf("unexpected result for memory query: %+v", resp.Results[2])
+	}
+	if resp.Results[3].Error == "" {
+		t.Errorf("expected error for invalid time, got %+v", resp.Results[3])
+	}
+
+	// The invalid query should never reach storage
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("expected 3 aggregate calls, got %d", calls)
+	}
+}
+
+func TestMetricsBatchQueryHandler_Validation(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+
+	testCases := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"invalid json", http.MethodPost, "{", http.StatusBadRequest},
+		{"empty batch", http.MethodPost, "[]", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/metrics/batch-query", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			server.metricsBatchQueryHandler()(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, rec.Code)
+			}
+		})
+	}
+}
+
+func TestMetricsExportHandler_CSV(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var gotQuery *models.QueryParams
+	proc := &stubProcessor{
+		streamFn: func(query *models.QueryParams, fn func(metric *models.Metric) error) error {
+			gotQuery = query
+			samples := []*models.Metric{
+				{Name: "api.latency", Value: 12.5, Timestamp: base, Tags: map[string]string{"endpoint": "/users"}},
+				{Name: "api.latency", Value: 40, Timestamp: base.Add(time.Second)},
+			}
+			for _, m := range samples {
+				if err := fn(m); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	server := NewServer(proc, 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/export?name=api.latency&service=payment-api&format=csv", nil)
+	rec := httptest.NewRecorder()
+	server.metricsExportHandler()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expected content type text/csv, got %s", ct)
+	}
+
+	// Filters are passed through and the default limit is lifted
+	if gotQuery.Name != "api.latency" || gotQuery.Service != "payment-api" {
+		t.Errorf("unexpected query filters: name=%q service=%q", gotQuery.Name, gotQuery.Service)
+	}
+	if gotQuery.Limit != 0 {
+		t.Errorf("expected unbounded export, got limit %d", gotQuery.Limit)
+	}
+	if !gotQuery.Since.IsZero() {
+		t.Errorf("expected no default time window for exports, got since %s", gotQuery.Since)
+	}
+
+	expected := "timestamp,value,tags\n" +
+		"2024-01-02T03:04:05Z,12.5,\"{\"\"endpoint\"\":\"\"/users\"\"}\"\n" +
+		"2024-01-02T03:04:06Z,40,{}\n"
+	if rec.Body.String() != expected {
+		t.Errorf("unexpected CSV body:\n%s", rec.Body.String())
+	}
+}
+
+func TestMetricsExportHandler_Errors(t *testing.T) {
+	proc := &stubProcessor{
+		streamFn: func(query *models.QueryParams, fn func(metric *models.Metric) error) error {
+			return errors.New("database is locked")
+		},
+	}
+	server := NewServer(proc, 0)
+
+	// Unsupported formats are rejected
+	rec := httptest.NewRecorder()
+	server.metricsExportHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/metrics/export?format=xml", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unsupported format, got %d", rec.Code)
+	}
+
+	// Errors before the first row still produce a proper status
+	rec = httptest.NewRecorder()
+	server.metricsExportHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/metrics/export", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+}
+
+func TestHandleJSONMetric_Info(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	// Value defaults to 1 and tags are kept as the payload
+	body := `{"name":"build_info","type":"info","service":"api","tags":{"version":"1.2.3"}}`
+	rec := httptest.NewRecorder()
+	server.handleJSONMetric(rec, []byte(body), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(proc.metrics) != 1 {
+		t.Fatalf("expected 1 processed metric, got %d", len(proc.metrics))
+	}
+	if m := proc.metrics[0]; m.Type != models.MetricTypeInfo || m.Value != 1 || m.Tags["version"] != "1.2.3" {
+		t.Errorf("unexpected info metric: %+v", m)
+	}
+
+	// Info metrics without tags or with another value are rejected
+	for _, body := range []string{
+		`{"name":"build_info","type":"info","service":"api"}`,
+		`{"name":"build_info","type":"info","service":"api","value":3,"tags":{"version":"1.2.3"}}`,
+	} {
+		rec := httptest.NewRecorder()
+		server.handleJSONMetric(rec, []byte(body), nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestHandleJSONMetric_Types(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	for _, c := range []struct {
+		metricType string
+		expected   models.MetricType
+	}{
+		{"", models.MetricTypeGauge},
+		{"Summary", models.MetricTypeSummary},
+		{"c", models.MetricTypeCounter},
+	} {
+		body := `{"name":"requests","value":2,"type":"` + c.metricType + `","service":"api"}`
+		rec := httptest.NewRecorder()
+		server.handleJSONMetric(rec, []byte(body), nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200 for type %q, got %d: %s", c.metricType, rec.Code, rec.Body.String())
+		}
+		if m := proc.metrics[len(proc.metrics)-1]; m.Type != c.expected {
+			t.Errorf("expected type %q to be stored as %s, got %s", c.metricType, c.expected, m.Type)
+		}
+	}
+
+	// Unknown types are rejected rather than stored
+	processed := len(proc.metrics)
+	for _, metricType := range []string{"guage", "exponential_histogram"} {
+		body := `{"name":"requests","value":2,"type":"` + metricType + `","service":"api"}`
+		rec := httptest.NewRecorder()
+		server.handleJSONMetric(rec, []byte(body), nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for type %q, got %d", metricType, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	server.handleJSONMetric(rec, []byte(`{"name":"requests","type":"guage","service":"api"}`), nil)
+	if !strings.Contains(rec.Body.String(), `unknown metric type "guage"`) {
+		t.Errorf("expected the unknown type to be reported, got %q", rec.Body.String())
+	}
+	if len(proc.metrics) != processed {
+		t.Errorf("expected no metric of an unknown type to be processed, got %d", len(proc.metrics)-processed)
+	}
+}
+
+func TestHistogramSubmitHandler(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	body := `{"name":"http.duration","service":"api","tags":{"route":"/users"},
+		"buckets":[{"upper_bound":0.1,"count":4},{"upper_bound":0.5,"count":7},{"upper_bound":1,"count":7}],
+		"sum":1.75,"count":8}`
+	req := httptest.NewRequest(http.MethodPost, "/metrics/histogram", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.histogramSubmitHandler()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(proc.histograms) != 1 {
+		t.Fatalf("expected 1 processed histogram, got %d", len(proc.histograms))
+	}
+
+	h := proc.histograms[0]
+	if h.Type != models.MetricTypeHistogram || h.Tags["route"] != "/users" {
+		t.Errorf("unexpected histogram metadata: %+v", h.Metric)
+	}
+	if h.Sum != 1.75 || h.Count != 8 {
+		t.Errorf("expected sum 1.75 and count 8, got %v and %d", h.Sum, h.Count)
+	}
+	if len(h.Buckets) != 3 || h.Buckets[1] != (models.HistogramBucket{UpperBound: 0.5, Count: 7}) {
+		t.Errorf("unexpected buckets: %v", h.Buckets)
+	}
+
+	// Malformed histograms are rejected before reaching the processor
+	for _, body := range []string{
+		`{"name":"http.duration","service":"api","count":1}`,
+		`{"name":"http.duration","service":"api","buckets":[{"upper_bound":1,"count":1},{"upper_bound":0.5,"count":2}],"count":2}`,
+		`{"name":"http.duration","service":"api","buckets":[{"upper_bound":0.5,"count":3},{"upper_bound":1,"count":2}],"count":3}`,
+		`{"name":"http.duration","service":"api","buckets":[{"upper_bound":0.5,"count":3}],"count":2}`,
+		`{"service":"api","buckets":[{"upper_bound":0.5,"count":1}],"count":1}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/metrics/histogram", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		server.histogramSubmitHandler()(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+	if len(proc.histograms) != 1 {
+		t.Errorf("expected invalid histograms to be dropped, got %d processed", len(proc.histograms))
+	}
+}
+
+func TestMetricsBatchHandler(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	body := `[
+		{"name":"http.requests","value":3,"type":"counter","service":"api","tags":{"route":"/users"}},
+		{"name":"cpu","value":0.5,"service":"worker"},
+		{"name":"","value":1,"service":"api"},
+		{"name":"build_info","type":"info","service":"api","tags":{"version":"1.2.3"}},
+		{"name":"latency","value":0.2,"type":"histogram","service":"api"},
+		{"name":"queue.depth","value":4,"type":"gauge"}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/metrics/batch", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.metricsBatchHandler()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp MetricBatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "partial" || resp.Processed != 3 || resp.Failed != 3 {
+		t.Errorf("expected partial with 3 processed and 3 failed, got %s with %d and %d",
+			resp.Status, resp.Processed, resp.Failed)
+	}
+
+	expected := []string{"ok", "ok", "error", "ok", "error", "error"}
+	if len(resp.Results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(resp.Results))
+	}
+	for i, status := range expected {
+		if resp.Results[i].Status != status {
+			t.Errorf("expected result %d to be %s, got %s (%s)", i, status, resp.Results[i].Status, resp.Results[i].Message)
+		}
+	}
+	if resp.Results[0].ID == "" || resp.Results[0].ID == resp.Results[1].ID {
+		t.Errorf("expected distinct IDs for accepted metrics, got %q and %q", resp.Results[0].ID, resp.Results[1].ID)
+	}
+
+	// Valid metrics are handed over in a single batch
+	if proc.batches != 1 || len(proc.metrics) != 3 {
+		t.Fatalf("expected 3 metrics in 1 batch, got %d in %d", len(proc.metrics), proc.batches)
+	}
+	if proc.metrics[0].Type != models.MetricTypeCounter || proc.metrics[0].Tags["route"] != "/users" {
+		t.Errorf("unexpected first metric: %+v", proc.metrics[0])
+	}
+	if proc.metrics[1].Type != models.MetricTypeGauge {
+		t.Errorf("expected untyped metric to default to gauge, got %s", proc.metrics[1].Type)
+	}
+	if proc.metrics[2].Type != models.MetricTypeInfo || proc.metrics[2].Value != 1 {
+		t.Errorf("expected info metric with value 1, got %+v", proc.metrics[2])
+	}
+
+	// An empty batch or a non-array body is rejected outright
+	for _, body := range []string{`[]`, `{"name":"cpu","service":"api"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/metrics/batch", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		server.metricsBatchHandler()(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestMetricsBatchHandler_SameRulesAsSingle(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+
+	for _, body := range []string{
+		`{"name":"","value":1,"service":"api"}`,
+		`{"name":"cpu","value":1}`,
+		`{"name":"cpu","value":1,"type":"guage","service":"api"}`,
+		`{"name":"build_info","value":2,"type":"info","service":"api","tags":{"version":"1"}}`,
+	} {
+		rec := httptest.NewRecorder()
+		server.handleJSONMetric(rec, []byte(body), nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, rec.Code)
+			continue
+		}
+
+		batch := httptest.NewRecorder()
+		server.metricsBatchHandler()(batch, httptest.NewRequest(http.MethodPost, "/metrics/batch", strings.NewReader("["+body+"]")))
+		var resp MetricBatchResponse
+		if err := json.NewDecoder(batch.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if message := strings.TrimSpace(rec.Body.String()); resp.Results[0].Message != message {
+			t.Errorf("%s: expected the batch to report %q, got %q", body, message, resp.Results[0].Message)
+		}
+	}
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+	now := time.Now().UTC()
+
+	// Only the latest sample of each series is exposed
+	for i, value := range []float64{10, 12} {
+		metric := models.NewMetric("http.requests", value, models.MetricTypeCounter, "api")
+		metric.Timestamp = now.Add(time.Duration(i-2) * time.Minute)
+		metric.AddTag("path", `/search?q="x"`)
+		if err := store.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+	}
+	gauge := models.NewMetric("queue.depth", 3, models.MetricTypeGauge, "worker")
+	gauge.Timestamp = now.Add(-time.Minute)
+	if err := store.SaveMetric(gauge); err != nil {
+		t.Fatalf("failed to save metric: %v", err)
+	}
+
+	// Samples outside the scrape window are left out
+	stale := models.NewMetric("queue.depth", 99, models.MetricTypeGauge, "legacy")
+	stale.Timestamp = now.Add(-2 * defaultPrometheusFreshness)
+	if err := store.SaveMetric(stale); err != nil {
+		t.Fatalf("failed to save metric: %v", err)
+	}
+
+	histogram, err := models.NewHistogramMetric("request.duration", "api", []float64{0.1, 1})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	histogram.Timestamp = now.Add(-time.Minute)
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	if err := store.SaveHistogramMetric(histogram); err != nil {
+		t.Fatalf("failed to save histogram: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.prometheusHandler()(rec, httptest.NewRequest(http.MethodGet, "/metrics/prometheus", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected text/plain content type, got %s", ct)
+	}
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE http_requests counter",
+		`http_requests{path="/search?q=\"x\"",service="api"} 12 `,
+		"# TYPE queue_depth gauge",
+		`queue_depth{service="worker"} 3 `,
+		"# TYPE request_duration histogram",
+		`request_duration_bucket{le="0.1",service="api"} 1 `,
+		`request_duration_bucket{le="+Inf",service="api"} 2 `,
+		`request_duration_sum{service="api"} 0.55 `,
+		`request_duration_count{service="api"} 2 `,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected output to contain %q, got:\n%s", line, body)
+		}
+	}
+	for _, unwanted := range []string{"} 10 ", "legacy"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("expected output not to contain %q, got:\n%s", unwanted, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	server.prometheusHandler()(rec, httptest.NewRequest(http.MethodPost, "/metrics/prometheus", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for POST, got %d", rec.Code)
+	}
+}
+
+func TestPrometheusHandler_Freshness(t *testing.T) {
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	server := NewServer(processor.NewStorageProcessor(store), 0,
+		WithPrometheusFreshness(10*time.Minute), WithClock(func() time.Time { return now }))
+
+	// One series per service, last seen at increasing ages; stale reported
+	// recently before going quiet
+	for service, age := range map[string]time.Duration{
+		"fresh":    time.Minute,
+		"boundary": 10 * time.Minute,
+		"stale":    11 * time.Minute,
+		"gone":     3 * time.Hour,
+	} {
+		for _, offset := range []time.Duration{0, time.Hour} {
+			metric := models.NewMetric("queue.depth", 1, models.MetricTypeGauge, service)
+			metric.Timestamp = now.Add(-age - offset)
+			store.SaveMetric(metric)
+		}
+	}
+	histogram, err := models.NewHistogramMetric("request.duration", "stale", []float64{0.1, 1})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	histogram.Timestamp = now.Add(-time.Hour)
+	histogram.Observe(0.5)
+	store.SaveHistogramMetric(histogram)
+
+	rec := httptest.NewRecorder()
+	server.prometheusHandler()(rec, httptest.NewRequest(http.MethodGet, "/metrics/prometheus", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	body := rec.Body.String()
+	for _, service := range []string{"fresh", "boundary"} {
+		if !strings.Contains(body, fmt.Sprintf(`queue_depth{service=%q} 1 `, service)) {
+			t.Errorf("expected the %s series to be exposed, got:\n%s", service, body)
+		}
+	}
+	for _, unwanted := range []string{`"stale"`, `"gone"`, "request_duration"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("expected output not to contain %s, got:\n%s", unwanted, body)
+		}
+	}
+}
+
+func TestApdexHandler(t *testing.T) {
+	var gotQuery *models.QueryParams
+	proc := &stubProcessor{
+		streamFn: func(query *models.QueryParams, fn func(metric *models.Metric) error) error {
+			gotQuery = query
+			// 2 satisfied, 1 tolerating and 1 frustrated against a 100ms target
+			for _, latency := range []float64{40, 100, 300, 500} {
+				if err := fn(&models.Metric{Name: "http.request.duration", Service: "checkout", Value: latency}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	server := NewServer(proc, 0)
+
+	rec := httptest.NewRecorder()
+	server.apdexHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/apdex?metric=http.request.duration&target=100&service=checkout", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if gotQuery.Name != "http.request.duration" || gotQuery.Service != "checkout" || gotQuery.Since.IsZero() {
+		t.Errorf("expected a windowed query for the metric, got %+v", gotQuery)
+	}
+
+	var resp struct {
+		Target   float64              `json:"target"`
+		Services []storage.ApdexScore `json:"services"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Target != 100 || len(resp.Services) != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if score := resp.Services[0]; score.Service != "checkout" || score.Score != 0.625 {
+		t.Errorf("expected checkout to score 0.625, got %+v", score)
+	}
+
+	for _, url := range []string{
+		"/api/apdex?target=100",
+		"/api/apdex?metric=http.request.duration",
+		"/api/apdex?metric=http.request.duration&target=-5",
+	} {
+		rec := httptest.NewRecorder()
+		server.apdexHandler()(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", url, rec.Code)
+		}
+	}
+}
+
+func TestMetricPercentilesHandler(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	// Two /orders histograms whose merged p99 is only visible together
+	for endpoint, samples := range map[string][][]float64{
+		"/orders": {{20, 20, 20, 20, 20, 20, 20, 20, 20, 20}, {20, 20, 20, 20, 20, 20, 20, 20, 900, 900}},
+		"/health": {{1, 1, 1}},
+	} {
+		for _, values := range samples {
+			histogram, err := models.NewHistogramMetric("http.latency", "api", []float64{5, 50, 500, 1000})
+			if err != nil {
+				t.Fatalf("failed to create histogram: %v", err)
+			}
+			histogram.AddTag("endpoint", endpoint)
+			for _, value := range values {
+				histogram.Observe(value)
+			}
+			if err := store.SaveHistogramMetric(histogram); err != nil {
+				t.Fatalf("failed to save histogram: %v", err)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	server.metricPercentilesHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/metrics/percentiles?name=http.latency&group_by=endpoint&percentiles=99", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		GroupBy string                     `json:"group_by"`
+		Groups  []storage.GroupPercentiles `json:"groups"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.GroupBy != "endpoint" || len(resp.Groups) != 2 {
+		t.Fatalf("expected 2 endpoint groups, got %+v", resp)
+	}
+	if health := resp.Groups[0]; health.Group != "/health" || health.Percentiles["99"] != 5 {
+		t.Errorf("expected /health p99 of 5, got %+v", health)
+	}
+	if orders := resp.Groups[1]; orders.Group != "/orders" || orders.Count != 20 || orders.Percentiles["99"] != 1000 {
+		t.Errorf("expected /orders p99 of 1000 over 20 observations, got %+v", orders)
+	}
+
+	for _, url := range []string{
+		"/api/metrics/percentiles?group_by=endpoint",
+		"/api/metrics/percentiles?name=http.latency&percentiles=99,101",
+		"/api/metrics/percentiles?name=http.latency&percentiles=p99",
+	} {
+		rec := httptest.NewRecorder()
+		server.metricPercentilesHandler()(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", url, rec.Code)
+		}
+	}
+}
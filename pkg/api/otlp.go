@@ -0,0 +1,364 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpMaxBody caps the size of an OTLP export request after decompression
+const otlpMaxBody = 10 << 20 // 10MB
+
+// otlpUnknownService is used for spans whose resource has no service.name,
+// matching the OpenTelemetry SDK default
+const otlpUnknownService = "unknown_service"
+
+// otlpTracesHandler returns a handler for the OTLP/HTTP trace exporter. It
+// accepts ExportTraceServiceRequest payloads encoded as protobuf or JSON,
+// groups the spans into traces by trace ID and processes each trace.
+func (s *Server) otlpTracesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		defer r.Body.Close()
+
+		// Exporters may compress the payload
+		reader := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "Invalid gzip body", http.StatusBadRequest)
+				return
+			}
+			defer gz.Close()
+			reader = gz
+		}
+
+		// Read the request body
+		body, ok := readLimited(w, reader, otlpMaxBody)
+		if !ok {
+			return
+		}
+
+		// Decode according to the content type
+		contentType := r.Header.Get("Content-Type")
+		isProtobuf := isOTLPProtobuf(contentType)
+		if !isProtobuf && !strings.Contains(contentType, "application/json") {
+			http.Error(w, fmt.Sprintf("Unsupported content type: %s", contentType), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		traces, err := decodeOTLPTraces(body, isProtobuf)
+		if err != nil {
+			log.Printf("Error parsing OTLP traces: %v", err)
+			http.Error(w, fmt.Sprintf("Invalid OTLP payload: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		// Reject the payload before processing any trace
+		for _, trace := range traces {
+			for _, span := range trace.Spans {
+				if err := s.checkSpanTimestamps(span); err != nil {
+					http.Error(w, fmt.Sprintf("span %s: %v", span.ID, err), http.StatusBadRequest)
+					return
+				}
+			}
+		}
+
+		// Process each trace
+		for _, trace := range traces {
+			if err := s.processor.ProcessTrace(trace); err != nil {
+				log.Printf("Error saving trace %s: %v", trace.ID, err)
+				http.Error(w, "Error processing trace", processErrorStatus(err))
+				return
+			}
+		}
+
+		// Respond with an empty ExportTraceServiceResponse in the request's encoding
+		if isProtobuf {
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{}"))
+	}
+}
+
+// isOTLPProtobuf reports whether contentType is the OTLP protobuf encoding
+func isOTLPProtobuf(contentType string) bool {
+	return strings.Contains(contentType, "application/x-protobuf")
+}
+
+// decodeOTLPTraces decodes OTLP trace data encoded as protobuf or, if
+// isProtobuf is false, as OTLP/JSON, and maps its spans to traces
+func decodeOTLPTraces(body []byte, isProtobuf bool) ([]*models.Trace, error) {
+	var data tracepb.TracesData
+	var err error
+	if isProtobuf {
+		err = proto.Unmarshal(body, &data)
+	} else {
+		err = unmarshalOTLPJSON(body, &data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return otlpToTraces(&data), nil
+}
+
+// unmarshalOTLPJSON decodes the OTLP/JSON encoding of trace data. OTLP/JSON
+// differs from the standard protobuf JSON mapping in that trace and span IDs
+// are hex rather than base64 encoded, so the IDs are converted before the
+// payload is handed to protojson.
+func unmarshalOTLPJSON(body []byte, data *tracepb.TracesData) error {
+	// Keep numbers as written so nanosecond timestamps don't lose precision
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	for _, resourceSpans := range otlpList(raw, "resourceSpans", "resource_spans") {
+		for _, scopeSpans := range otlpList(resourceSpans, "scopeSpans", "scope_spans") {
+			for _, span := range otlpList(scopeSpans, "spans") {
+				if err := hexIDsToBase64(span, "traceId", "trace_id", "spanId", "span_id", "parentSpanId", "parent_span_id"); err != nil {
+					return err
+				}
+				for _, link := range otlpList(span, "links") {
+					if err := hexIDsToBase64(link, "traceId", "trace_id", "spanId", "span_id"); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	converted, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(converted, data)
+}
+
+// otlpList returns the array stored under the first of keys present in obj
+func otlpList(obj interface{}, keys ...string) []interface{} {
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for _, key := range keys {
+		if list, ok := m[key].([]interface{}); ok {
+			return list
+		}
+	}
+	return nil
+}
+
+// hexIDsToBase64 re-encodes the hex IDs stored under keys of obj as base64
+func hexIDsToBase64(obj interface{}, keys ...string) error {
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for _, key := range keys {
+		id, ok := m[key].(string)
+		if !ok || id == "" {
+			continue
+		}
+		b, err := hex.DecodeString(id)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", key, id, err)
+		}
+		m[key] = base64.StdEncoding.EncodeToString(b)
+	}
+	return nil
+}
+
+// otlpToTraces maps OTLP spans to traces, grouping spans by trace ID in the
+// order the traces first appear. The root of each trace is its span without
+// a parent, and is left unset if the payload holds only other spans of the
+// trace.
+func otlpToTraces(data *tracepb.TracesData) []*models.Trace {
+	var traces []*models.Trace
+	byID := make(map[string]*models.Trace)
+
+	for _, resourceSpans := range data.GetResourceSpans() {
+		resource := otlpAttributes(resourceSpans.GetResource().GetAttributes())
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			for _, otlpSpan := range scopeSpans.GetSpans() {
+				span := otlpToSpan(otlpSpan, resource)
+
+				trace, ok := byID[span.TraceID]
+				if !ok {
+					trace = &models.Trace{ID: span.TraceID}
+					byID[span.TraceID] = trace
+					traces = append(traces, trace)
+				}
+				trace.Spans = append(trace.Spans, span)
+			}
+		}
+	}
+
+	for _, trace := range traces {
+		completeBatchTrace(trace)
+	}
+
+	return traces
+}
+
+// otlpToSpan converts an OTLP span into a Span. Resource attributes provide
+// the service, environment and host and are added to the tags, with span
+// attributes taking precedence.
+func otlpToSpan(otlpSpan *tracepb.Span, resource map[string]string) *models.Span {
+	span := &models.Span{
+		ID:        hex.EncodeToString(otlpSpan.GetSpanId()),
+		TraceID:   hex.EncodeToString(otlpSpan.GetTraceId()),
+		ParentID:  hex.EncodeToString(otlpSpan.GetParentSpanId()),
+		Name:      otlpSpan.GetName(),
+		Service:   resource["service.name"],
+		StartTime: time.Unix(0, int64(otlpSpan.GetStartTimeUnixNano())).UTC(),
+		Status:    models.SpanStatusOK,
+		Tags:      make(map[string]string),
+		Env:       resource["deployment.environment"],
+		Host:      resource["host.name"],
+	}
+
+	// Fill in IDs that the payload left out
+	if span.ID == "" {
+		span.ID = models.GenerateID()
+	}
+	if span.TraceID == "" {
+		span.TraceID = models.GenerateID()
+	}
+	if span.Service == "" {
+		span.Service = otlpUnknownService
+	}
+
+	for k, v := range resource {
+		if k != "service.name" {
+			span.Tags[k] = v
+		}
+	}
+	for k, v := range otlpAttributes(otlpSpan.GetAttributes()) {
+		span.Tags[k] = v
+	}
+
+	if kind := otlpSpan.GetKind(); kind != tracepb.Span_SPAN_KIND_UNSPECIFIED {
+		span.Tags["span.kind"] = strings.ToLower(strings.TrimPrefix(kind.String(), "SPAN_KIND_"))
+	}
+
+	if end := otlpSpan.GetEndTimeUnixNano(); end > 0 {
+		span.EndTime = time.Unix(0, int64(end)).UTC()
+		span.Duration = span.EndTime.Sub(span.StartTime).Milliseconds()
+		span.IsFinished = true
+	}
+
+	if status := otlpSpan.GetStatus(); status != nil {
+		if status.GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+			span.Status = models.SpanStatusError
+		}
+		if status.GetMessage() != "" {
+			span.Tags["otel.status_description"] = status.GetMessage()
+		}
+	}
+
+	// Span events become span logs
+	for _, event := range otlpSpan.GetEvents() {
+		fields := otlpAttributes(event.GetAttributes())
+		fields["event"] = event.GetName()
+		span.Logs = append(span.Logs, models.SpanLog{
+			Timestamp: time.Unix(0, int64(event.GetTimeUnixNano())).UTC(),
+			Fields:    fields,
+		})
+	}
+
+	for _, link := range otlpSpan.GetLinks() {
+		var attributes map[string]string
+		if len(link.GetAttributes()) > 0 {
+			attributes = otlpAttributes(link.GetAttributes())
+		}
+		span.AddLink(hex.EncodeToString(link.GetTraceId()), hex.EncodeToString(link.GetSpanId()), attributes)
+	}
+
+	return span
+}
+
+// otlpAttributes flattens OTLP attributes into string tags
+func otlpAttributes(attrs []*commonpb.KeyValue) map[string]string {
+	tags := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		tags[attr.GetKey()] = otlpValueString(attr.GetValue())
+	}
+	return tags
+}
+
+// otlpValueString renders an attribute value as a string. Arrays and
+// key-value lists are rendered as JSON.
+func otlpValueString(v *commonpb.AnyValue) string {
+	if s, ok := v.GetValue().(*commonpb.AnyValue_StringValue); ok {
+		return s.StringValue
+	}
+	switch value := otlpValue(v).(type) {
+	case nil:
+		return ""
+	case bool:
+		return strconv.FormatBool(value)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	default:
+		b, _ := json.Marshal(value)
+		return string(b)
+	}
+}
+
+// otlpValue converts an attribute value to its Go equivalent
+func otlpValue(v *commonpb.AnyValue) interface{} {
+	switch value := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return value.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return value.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return value.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(value.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]interface{}, 0, len(value.ArrayValue.GetValues()))
+		for _, item := range value.ArrayValue.GetValues() {
+			values = append(values, otlpValue(item))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]interface{}, len(value.KvlistValue.GetValues()))
+		for _, kv := range value.KvlistValue.GetValues() {
+			values[kv.GetKey()] = otlpValue(kv.GetValue())
+		}
+		return values
+	default:
+		return nil
+	}
+}
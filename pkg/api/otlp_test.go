@@ -0,0 +1,270 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTLPTracesHandler_JSON(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	// Two traces from one resource; the child is listed before its parent
+	body := `{"resourceSpans": [{
+		"resource": {"attributes": [
+			{"key": "service.name", "value": {"stringValue": "checkout"}},
+			{"key": "deployment.environment", "value": {"stringValue": "prod"}},
+			{"key": "host.name", "value": {"stringValue": "web-1"}}
+		]},
+		"scopeSpans": [{"spans": [
+			{
+				"traceId": "5b8efff798038103d269b633813fc60c",
+				"spanId": "eee19b7ec3c1b174",
+				"parentSpanId": "eee19b7ec3c1b173",
+				"name": "charge card",
+				"kind": 3,
+				"startTimeUnixNano": "1700000000100000000",
+				"endTimeUnixNano": "1700000000150000000",
+				"attributes": [
+					{"key": "http.status_code", "value": {"intValue": "502"}},
+					{"key": "retry", "value": {"boolValue": true}}
+				],
+				"status": {"code": 2, "message": "upstream failed"}
+			},
+			{
+				"traceId": "5b8efff798038103d269b633813fc60c",
+				"spanId": "eee19b7ec3c1b173",
+				"name": "POST /checkout",
+				"kind": "SPAN_KIND_SERVER",
+				"startTimeUnixNano": "1700000000000000000",
+				"endTimeUnixNano": "1700000000200000000",
+				"links": [{"traceId": "0af7651916cd43dd8448eb211c80319c", "spanId": "b7ad6b7169203331",
+					"attributes": [{"key": "link.reason", "value": {"stringValue": "retry"}}]}],
+				"events": [{"timeUnixNano": "1700000000120000000", "name": "cache miss",
+					"attributes": [{"key": "key", "value": {"stringValue": "cart:42"}}]}]
+			},
+			{
+				"traceId": "0af7651916cd43dd8448eb211c80319c",
+				"spanId": "b7ad6b7169203331",
+				"name": "GET /health",
+				"startTimeUnixNano": "1700000001000000000",
+				"endTimeUnixNano": "1700000001001000000"
+			}
+		]}]
+	}]}`
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.otlpTracesHandler()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(proc.traces) != 2 {
+		t.Fatalf("expected 2 traces, got %d", len(proc.traces))
+	}
+
+	trace := proc.traces[0]
+	if trace.ID != "5b8efff798038103d269b633813fc60c" || len(trace.Spans) != 2 {
+		t.Fatalf("expected trace 5b8e... with 2 spans, got %s with %d", trace.ID, len(trace.Spans))
+	}
+	if trace.Root == nil || trace.Root.ID != "eee19b7ec3c1b173" {
+		t.Errorf("expected the span without a parent to be the root, got %+v", trace.Root)
+	}
+
+	child := trace.Spans[0]
+	if child.ParentID != "eee19b7ec3c1b173" || child.Name != "charge card" {
+		t.Errorf("unexpected child span: %+v", child)
+	}
+	if child.Service != "checkout" || child.Env != "prod" || child.Host != "web-1" {
+		t.Errorf("expected resource attributes to set service, env and host, got %s/%s/%s",
+			child.Service, child.Env, child.Host)
+	}
+	if child.Status != models.SpanStatusError || child.Tags["otel.status_description"] != "upstream failed" {
+		t.Errorf("expected error status with description, got %s and %v", child.Status, child.Tags)
+	}
+	if child.Tags["http.status_code"] != "502" || child.Tags["retry"] != "true" || child.Tags["span.kind"] != "client" {
+		t.Errorf("unexpected span tags: %v", child.Tags)
+	}
+	if _, ok := child.Tags["service.name"]; ok {
+		t.Errorf("expected service.name not to be copied into tags")
+	}
+
+	wantStart := time.Unix(0, 1700000000100000000).UTC()
+	if !child.StartTime.Equal(wantStart) || child.Duration != 50 || !child.IsFinished {
+		t.Errorf("expected start %v and duration 50ms, got %v and %d", wantStart, child.StartTime, child.Duration)
+	}
+
+	root := trace.Spans[1]
+	if root.Status != models.SpanStatusOK || root.Tags["span.kind"] != "server" {
+		t.Errorf("unexpected root span: %+v", root)
+	}
+	if len(root.Logs) != 1 || root.Logs[0].Fields["event"] != "cache miss" || root.Logs[0].Fields["key"] != "cart:42" {
+		t.Errorf("expected span event as a log, got %+v", root.Logs)
+	}
+	if len(root.Links) != 1 || root.Links[0].TraceID != "0af7651916cd43dd8448eb211c80319c" ||
+		root.Links[0].SpanID != "b7ad6b7169203331" || root.Links[0].Attributes["link.reason"] != "retry" {
+		t.Errorf("expected hex encoded span link, got %+v", root.Links)
+	}
+
+	if rec.Body.String() != "{}" {
+		t.Errorf("expected empty JSON response, got %s", rec.Body.String())
+	}
+}
+
+func TestOTLPTracesHandler_Protobuf(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	traceID := []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}
+	data := &tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "inventory"}}},
+		}},
+		ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{
+			TraceId:           traceID,
+			SpanId:            []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			Name:              "reserve",
+			StartTimeUnixNano: 1700000000000000000,
+			EndTimeUnixNano:   1700000000025000000,
+			Attributes: []*commonpb.KeyValue{
+				{Key: "items", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+					Values: []*commonpb.AnyValue{
+						{Value: &commonpb.AnyValue_IntValue{IntValue: 1}},
+						{Value: &commonpb.AnyValue_IntValue{IntValue: 2}},
+					},
+				}}}},
+				{Key: "ratio", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 0.5}}},
+			},
+		}}}},
+	}}}
+	payload, err := proto.Marshal(data)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+
+	// Exporters commonly gzip the payload
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(payload)
+	gz.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", &compressed)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	server.otlpTracesHandler()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-protobuf" {
+		t.Errorf("expected protobuf response, got %s", ct)
+	}
+	if len(proc.traces) != 1 || len(proc.traces[0].Spans) != 1 {
+		t.Fatalf("expected 1 trace with 1 span, got %d traces", len(proc.traces))
+	}
+
+	span := proc.traces[0].Spans[0]
+	if span.TraceID != "5b8efff798038103d269b633813fc60c" || span.ID != "0102030405060708" {
+		t.Errorf("expected hex encoded IDs, got trace %s span %s", span.TraceID, span.ID)
+	}
+	if span.Service != "inventory" || span.Duration != 25 {
+		t.Errorf("expected inventory span of 25ms, got %s of %dms", span.Service, span.Duration)
+	}
+	if span.Tags["items"] != "[1,2]" || span.Tags["ratio"] != "0.5" {
+		t.Errorf("unexpected span tags: %v", span.Tags)
+	}
+	if proc.traces[0].Root != span {
+		t.Errorf("expected the only span to be the root")
+	}
+}
+
+func TestOTLPTracesHandler_Errors(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"unsupported content type", "text/plain", `{}`, http.StatusUnsupportedMediaType},
+		{"invalid JSON", "application/json", `{"resourceSpans": [`, http.StatusBadRequest},
+		{"invalid hex ID", "application/json",
+			`{"resourceSpans": [{"scopeSpans": [{"spans": [{"traceId": "not-hex", "spanId": "01"}]}]}]}`, http.StatusBadRequest},
+		{"invalid protobuf", "application/x-protobuf", "\xff\xff\xff", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/traces", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		server.otlpTracesHandler()(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, rec.Code)
+		}
+	}
+	if len(proc.traces) != 0 {
+		t.Errorf("expected no traces to be processed, got %d", len(proc.traces))
+	}
+}
+
+func TestOTLPTracesHandler_SplitTrace(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	post := func(body string) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/traces", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.otlpTracesHandler()(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	// An exporter flushes an asynchronous child outliving its parent in one
+	// batch and the parent in another
+	post(`{"resourceSpans": [{"scopeSpans": [{"spans": [{
+		"traceId": "5b8efff798038103d269b633813fc60c", "spanId": "eee19b7ec3c1b174", "parentSpanId": "eee19b7ec3c1b173",
+		"name": "charge card", "startTimeUnixNano": "1700000000100000000", "endTimeUnixNano": "1700000000250000000",
+		"status": {"code": 2}
+	}]}]}]}`)
+	post(`{"resourceSpans": [{"scopeSpans": [{"spans": [{
+		"traceId": "5b8efff798038103d269b633813fc60c", "spanId": "eee19b7ec3c1b173",
+		"name": "POST /checkout", "startTimeUnixNano": "1700000000000000000", "endTimeUnixNano": "1700000000200000000"
+	}]}]}]}`)
+
+	result, err := store.QueryTraces(&models.QueryParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to query traces: %v", err)
+	}
+	traces := result["traces"].([]map[string]interface{})
+	if len(traces) != 1 {
+		t.Fatalf("expected 1 trace, got %v", traces)
+	}
+	if traces[0]["name"] != "POST /checkout" || traces[0]["duration_ms"] != int64(250) {
+		t.Errorf("expected the trace of POST /checkout taking 250ms, got %v", traces[0])
+	}
+}
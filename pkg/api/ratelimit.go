@@ -0,0 +1,137 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/processor"
+)
+
+// ingestionRoutes are the routes whose POST requests are rate limited by
+// WithIngestRateLimit
+var ingestionRoutes = map[string]bool{
+	"/logs":              true,
+	"/logs/batch":        true,
+	"/metrics":           true,
+	"/metrics/batch":     true,
+	"/metrics/histogram": true,
+	"/traces":            true,
+	"/spans":             true,
+	"/spans/batch":       true,
+	"/v1/traces":         true,
+	"/write":             true,
+}
+
+// maxRateLimitedClients is how many clients the request limiter tracks
+// before it forgets the idle ones
+const maxRateLimitedClients = 10000
+
+// requestLimiter keeps a token bucket of requests per client
+type requestLimiter struct {
+	limit processor.RateLimit
+	now   func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]*limitedClient
+}
+
+// limitedClient is the bucket of a client and when it was last used
+type limitedClient struct {
+	bucket *processor.TokenBucket
+	seen   time.Time
+}
+
+// newRequestLimiter creates a limiter giving every client the budget limit
+func newRequestLimiter(limit processor.RateLimit, now func() time.Time) *requestLimiter {
+	return &requestLimiter{
+		limit:   limit,
+		now:     now,
+		clients: make(map[string]*limitedClient),
+	}
+}
+
+// allow takes a request from the client's budget. If the budget is spent it
+// returns false and how long the client should wait before retrying.
+func (l *requestLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	c, ok := l.clients[client]
+	if !ok {
+		if len(l.clients) >= maxRateLimitedClients {
+			l.forgetIdle(now)
+		}
+		c = &limitedClient{bucket: processor.NewTokenBucket(l.limit, now)}
+		l.clients[client] = c
+	}
+	c.seen = now
+
+	if c.bucket.Take(1, now) {
+		return true, 0
+	}
+	return false, c.bucket.RetryAfter(1)
+}
+
+// forgetIdle drops the clients idle long enough for their bucket to refill,
+// as a new bucket for them would be the same
+func (l *requestLimiter) forgetIdle(now time.Time) {
+	burst := float64(l.limit.Burst)
+	if burst < 1 {
+		burst = l.limit.Rate
+	}
+	refill := time.Duration(burst / l.limit.Rate * float64(time.Second))
+
+	for client, c := range l.clients {
+		if now.Sub(c.seen) >= refill {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// rateLimitMiddleware limits POST requests to the ingestion route at path,
+// answering requests over budget with 429 and a Retry-After header
+func (s *Server) rateLimitMiddleware(path string, next http.HandlerFunc) http.HandlerFunc {
+	if s.ingestLimiter == nil || !ingestionRoutes[path] {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+
+		if ok, retryAfter := s.ingestLimiter.allow(s.rateLimitClient(r)); !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rateLimitClient identifies the client of a request by its API key. Without
+// API authentication the key is unverified and easy to rotate, so clients
+// are identified by their remote IP instead.
+func (s *Server) rateLimitClient(r *http.Request) string {
+	if len(s.apiKeys) > 0 {
+		if key := requestAPIKey(r); key != "" {
+			return "key:" + key
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
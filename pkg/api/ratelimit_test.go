@@ -0,0 +1,131 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/processor"
+)
+
+// fakeClock is a clock that only moves when advanced
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// ingest posts a log to handler from remoteAddr with the given API key
+func ingest(handler http.HandlerFunc, remoteAddr, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/logs", strings.NewReader(`{"message": "hello", "service": "api"}`))
+	req.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	server := NewServer(&stubProcessor{}, 0,
+		WithIngestRateLimit(processor.RateLimit{Rate: 0.5, Burst: 2}),
+		WithClock(clock.Now))
+	handler := server.rateLimitMiddleware("/logs", server.logsHandler())
+
+	for i := 0; i < 2; i++ {
+		if rec := ingest(handler, "10.0.0.1:5000", ""); rec.Code != http.StatusOK {
+			t.Fatalf("expected request %d within the burst to pass, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := ingest(handler, "10.0.0.1:5001", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the burst is spent, got %d", rec.Code)
+	}
+	if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "2" {
+		t.Errorf("expected Retry-After 2, got %q", retryAfter)
+	}
+
+	// Other clients have their own budget
+	if rec := ingest(handler, "10.0.0.2:5000", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected another IP to pass, got %d", rec.Code)
+	}
+
+	// The budget refills with time
+	clock.Advance(2 * time.Second)
+	if rec := ingest(handler, "10.0.0.1:5000", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected a request to pass after the refill, got %d", rec.Code)
+	}
+	if rec := ingest(handler, "10.0.0.1:5000", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected only one token to refill, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitMiddleware_APIKeys(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limit := WithIngestRateLimit(processor.RateLimit{Rate: 1})
+
+	// With authentication, clients sharing an IP are limited by key
+	server := NewServer(&stubProcessor{}, 0, limit, WithClock(clock.Now), WithAPIKeys("key-1", "key-2"))
+	handler := server.rateLimitMiddleware("/logs", server.logsHandler())
+
+	if rec := ingest(handler, "10.0.0.1:5000", "key-1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the first request to pass, got %d", rec.Code)
+	}
+	if rec := ingest(handler, "10.0.0.1:5000", "key-1"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the key's second request to be limited, got %d", rec.Code)
+	}
+	if rec := ingest(handler, "10.0.0.1:5000", "key-2"); rec.Code != http.StatusOK {
+		t.Errorf("expected another key on the same IP to pass, got %d", rec.Code)
+	}
+
+	// Without authentication, keys are not trusted to tell clients apart
+	server = NewServer(&stubProcessor{}, 0, limit, WithClock(clock.Now))
+	handler = server.rateLimitMiddleware("/logs", server.logsHandler())
+
+	ingest(handler, "10.0.0.1:5000", "key-1")
+	if rec := ingest(handler, "10.0.0.1:5000", "key-2"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected unverified keys to share the IP budget, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitMiddleware_Routes(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0, WithIngestRateLimit(processor.RateLimit{Rate: 1}))
+	baseURL := startTestServer(t, server)
+
+	statuses := func(method, path string) []int {
+		var codes []int
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequest(method, baseURL+path, strings.NewReader(`{"message": "hello", "service": "api"}`))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			codes = append(codes, resp.StatusCode)
+		}
+		return codes
+	}
+
+	if codes := statuses(http.MethodPost, "/logs"); codes[1] != http.StatusTooManyRequests {
+		t.Errorf("expected ingestion to be limited, got %v", codes)
+	}
+	// Queries and other methods on ingestion routes are not limited
+	for _, path := range []string{"/api/logs", "/logs"} {
+		for _, code := range statuses(http.MethodGet, path) {
+			if code == http.StatusTooManyRequests {
+				t.Errorf("expected GET %s not to be limited", path)
+			}
+		}
+	}
+}
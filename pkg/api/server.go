@@ -1,36 +1,339 @@
 package api
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	dashboard "github.com/karansingh/pulse/dashboard-react"
+	"github.com/karansingh/pulse/pkg/models"
 	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
 )
 
 // Server represents the HTTP API server
 type Server struct {
 	server      *http.Server
+	serverLock  sync.Mutex
 	processor   processor.Processor
 	port        int
 	routes      map[string]http.HandlerFunc
 	wsUpgrader  websocket.Upgrader
-	activeConns map[*websocket.Conn]bool
+	activeConns map[string]map[*websocket.Conn]context.CancelFunc // Open connections by stream kind, with the function stopping their stream
+	connsClosed bool                                              // Set by Stop; later connections are stopped at once
 	connLock    sync.Mutex
+
+	// histogramBuckets are the default bucket boundaries for histograms
+	// submitted without explicit buckets
+	histogramBuckets []float64
+
+	// maxUnboundedLimit is the largest limit accepted for queries without
+	// a time range
+	maxUnboundedLimit int
+
+	// defaultQueryWindow is how far back queries without a time range look;
+	// zero leaves them without a lower bound
+	defaultQueryWindow time.Duration
+
+	// serviceListLimit is how many services /api/services lists without a
+	// limit, and maxServiceListLimit the largest limit it accepts
+	serviceListLimit    int
+	maxServiceListLimit int
+
+	// slowQueryThreshold is how long a storage query may take before it is
+	// logged as slow; zero disables the check
+	slowQueryThreshold time.Duration
+
+	// lenientLogs accepts off-schema JSON on /logs, taking the message from
+	// the first of logMessageFields and capturing unknown keys as tags
+	lenientLogs      bool
+	logMessageFields []string
+
+	// influxWrite serves the InfluxDB line protocol endpoint /write
+	influxWrite bool
+
+	// hub publishes processed items to the streaming WebSocket connections
+	hub *processor.Hub
+
+	// apiKeys are the keys accepted by authMiddleware; empty disables auth
+	apiKeys map[string]bool
+
+	// ingestLimit is the request budget of each client on the ingestion
+	// routes, enforced by ingestLimiter; a zero rate disables it
+	ingestLimit   processor.RateLimit
+	ingestLimiter *requestLimiter
+
+	// maxClockSkew is how far ahead of the server clock submitted
+	// timestamps may be, and maxTimestampAge how old they may be for each
+	// signal; zero leaves a bound unchecked
+	maxClockSkew    time.Duration
+	maxTimestampAge storage.RetentionPolicy
+
+	// maxBodySize is the largest request body accepted by the ingestion
+	// endpoints
+	maxBodySize int64
+
+	// basePath prefixes every route, e.g. /pulse when served behind a
+	// reverse proxy at a subpath; empty serves them at the root
+	basePath string
+
+	// prometheusFreshness is how recently a series must have been seen to be
+	// exposed on /metrics/prometheus
+	prometheusFreshness time.Duration
+
+	// dashboardDir, when set, holds the dashboard files served under
+	// /dashboard in place of the ones embedded in the binary
+	dashboardDir string
+
+	// readyLock guards the result of the last readiness check, reused for
+	// readyCacheTTL
+	readyLock    sync.Mutex
+	readyChecked time.Time
+	readyErr     error
+
+	// now is the server clock
+	now func() time.Time
+}
+
+// readyCacheTTL is how long /ready reuses the result of pinging the storage,
+// so that frequent probes don't each hit the database
+const readyCacheTTL = 2 * time.Second
+
+// defaultMaxBodySize is the default cap on ingestion request bodies
+const defaultMaxBodySize = 1 << 20 // 1MB
+
+// defaultMaxUnboundedLimit is the default cap on rows for queries without a time range
+const defaultMaxUnboundedLimit = 1000
+
+// defaultQueryWindowDuration is how far back queries without a time range look by default
+const defaultQueryWindowDuration = time.Hour
+
+// defaultServiceListLimit and defaultMaxServiceListLimit are the default and
+// largest number of services /api/services lists by default
+const (
+	defaultServiceListLimit    = 100
+	defaultMaxServiceListLimit = 1000
+)
+
+// defaultLogMessageFields are the fields holding the message of lenient JSON logs
+var defaultLogMessageFields = []string{"message", "msg"}
+
+// defaultSlowQueryThreshold is the default duration after which a storage query is logged as slow
+const defaultSlowQueryThreshold = time.Second
+
+// ServerOption configures optional Server behavior
+type ServerOption func(*Server)
+
+// WithHistogramBuckets overrides the default bucket boundaries used for
+// auto-generated histograms
+func WithHistogramBuckets(buckets []float64) ServerOption {
+	return func(s *Server) {
+		if len(buckets) > 0 {
+			s.histogramBuckets = buckets
+		}
+	}
+}
+
+// WithMaxUnboundedLimit sets the largest limit accepted for queries that have
+// no time range. Larger or unlimited queries are rejected with 400.
+func WithMaxUnboundedLimit(limit int) ServerOption {
+	return func(s *Server) {
+		if limit > 0 {
+			s.maxUnboundedLimit = limit
+		}
+	}
+}
+
+// WithDefaultQueryWindow sets how far back queries without a time_range,
+// since or all_time=true parameter look. Zero removes the implicit lower
+// bound, leaving such queries to the unbounded query limit.
+func WithDefaultQueryWindow(window time.Duration) ServerOption {
+	return func(s *Server) {
+		if window >= 0 {
+			s.defaultQueryWindow = window
+		}
+	}
+}
+
+// WithServiceListLimits sets how many of the most recently active services
+// /api/services lists without a limit, and the largest limit it accepts;
+// larger limits are lowered to max
+func WithServiceListLimits(defaultLimit, max int) ServerOption {
+	return func(s *Server) {
+		if max > 0 {
+			s.maxServiceListLimit = max
+		}
+		if defaultLimit > 0 {
+			s.serviceListLimit = defaultLimit
+		}
+		if s.serviceListLimit > s.maxServiceListLimit {
+			s.serviceListLimit = s.maxServiceListLimit
+		}
+	}
+}
+
+// WithSlowQueryThreshold sets how long a storage query may take before a
+// warning with the query type and duration is logged. Zero disables it.
+func WithSlowQueryThreshold(threshold time.Duration) ServerOption {
+	return func(s *Server) {
+		if threshold >= 0 {
+			s.slowQueryThreshold = threshold
+		}
+	}
+}
+
+// WithLenientLogParsing makes /logs accept arbitrary JSON objects. The
+// message is read from the first of messageFields that is set (message or
+// msg by default) and unknown top-level keys are stored as tags.
+func WithLenientLogParsing(messageFields ...string) ServerOption {
+	return func(s *Server) {
+		s.lenientLogs = true
+		if len(messageFields) > 0 {
+			s.logMessageFields = messageFields
+		}
+	}
+}
+
+// WithMaxBodySize sets the largest request body, in bytes, accepted by the
+// ingestion endpoints (1MB by default). Larger bodies are rejected with 413.
+// Metric batches and InfluxDB writes accept at least 10MB, and gzip bodies
+// are capped at 10MB once decompressed.
+func WithMaxBodySize(size int64) ServerOption {
+	return func(s *Server) {
+		if size > 0 {
+			s.maxBodySize = size
+		}
+	}
+}
+
+// WithPrometheusFreshness sets how recently a series must have reported to
+// be exposed on /metrics/prometheus (an hour by default). Older series are
+// left out of scrapes.
+func WithPrometheusFreshness(window time.Duration) ServerOption {
+	return func(s *Server) {
+		if window > 0 {
+			s.prometheusFreshness = window
+		}
+	}
+}
+
+// WithDashboardDir serves the dashboard files from dir instead of the ones
+// embedded in the binary, e.g. dashboard-react/dist while developing it
+func WithDashboardDir(dir string) ServerOption {
+	return func(s *Server) {
+		s.dashboardDir = dir
+	}
+}
+
+// WithBasePath serves every route under prefix instead of the root, for
+// deployments behind a reverse proxy at a subpath. The dashboard is told the
+// prefix so that its API requests carry it too.
+func WithBasePath(prefix string) ServerOption {
+	return func(s *Server) {
+		s.basePath = strings.TrimRight(prefix, "/")
+		if s.basePath != "" && !strings.HasPrefix(s.basePath, "/") {
+			s.basePath = "/" + s.basePath
+		}
+	}
+}
+
+// WithInfluxWrite enables the InfluxDB v1 compatible /write endpoint, which
+// ingests metrics in line protocol
+func WithInfluxWrite() ServerOption {
+	return func(s *Server) {
+		s.influxWrite = true
+	}
+}
+
+// WithHub streams the items published to hub over the WebSocket endpoints.
+// The hub should be fed by a BroadcastProcessor in the processing chain so
+// that items from every ingestion path are streamed. Without it the server
+// only streams items ingested through its own endpoints.
+func WithHub(hub *processor.Hub) ServerOption {
+	return func(s *Server) {
+		s.hub = hub
+	}
+}
+
+// WithAPIKeys requires requests to present one of keys as a bearer token, in
+// the X-API-Key header, the api_key query parameter or the dashboard's
+// cookie. Without keys the API is open.
+func WithAPIKeys(keys ...string) ServerOption {
+	return func(s *Server) {
+		for _, key := range keys {
+			if key != "" {
+				if s.apiKeys == nil {
+					s.apiKeys = make(map[string]bool)
+				}
+				s.apiKeys[key] = true
+			}
+		}
+	}
+}
+
+// WithIngestRateLimit limits the POST requests each client can make to the
+// ingestion endpoints. Clients are identified by API key when keys are
+// configured and by remote IP otherwise. Requests over budget get 429 with
+// a Retry-After header.
+func WithIngestRateLimit(limit processor.RateLimit) ServerOption {
+	return func(s *Server) {
+		s.ingestLimit = limit
+	}
+}
+
+// WithTimestampBounds rejects submitted logs, metrics and spans timestamped
+// more than maxSkew ahead of the server clock, or older than the maxAge of
+// their signal (typically the retention policy). Zero durations leave a
+// bound unchecked, which is the default.
+func WithTimestampBounds(maxSkew time.Duration, maxAge storage.RetentionPolicy) ServerOption {
+	return func(s *Server) {
+		s.maxClockSkew = maxSkew
+		s.maxTimestampAge = maxAge
+	}
+}
+
+// WithClock replaces the clock the server uses for rate limiting, timestamp
+// bounds and caching readiness checks
+func WithClock(now func() time.Time) ServerOption {
+	return func(s *Server) {
+		if now != nil {
+			s.now = now
+		}
+	}
 }
 
 // NewServer creates a new HTTP API server
-func NewServer(processor processor.Processor, port int) *Server {
+func NewServer(proc processor.Processor, port int, opts ...ServerOption) *Server {
 	s := &Server{
-		processor:   processor,
-		port:        port,
-		routes:      make(map[string]http.HandlerFunc),
-		activeConns: make(map[*websocket.Conn]bool),
+		processor:           proc,
+		port:                port,
+		routes:              make(map[string]http.HandlerFunc),
+		activeConns:         make(map[string]map[*websocket.Conn]context.CancelFunc),
+		histogramBuckets:    models.DefaultHistogramBuckets,
+		maxUnboundedLimit:   defaultMaxUnboundedLimit,
+		defaultQueryWindow:  defaultQueryWindowDuration,
+		serviceListLimit:    defaultServiceListLimit,
+		maxServiceListLimit: defaultMaxServiceListLimit,
+		slowQueryThreshold:  defaultSlowQueryThreshold,
+		logMessageFields:    defaultLogMessageFields,
+		maxBodySize:         defaultMaxBodySize,
+		prometheusFreshness: defaultPrometheusFreshness,
+		now:                 time.Now,
 		wsUpgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -40,6 +343,25 @@ func NewServer(processor processor.Processor, port int) *Server {
 		},
 	}
 
+	// Apply options
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.ingestLimit.Rate > 0 {
+		s.ingestLimiter = newRequestLimiter(s.ingestLimit, s.now)
+	}
+
+	// Publish the items ingested through the API when no hub is shared
+	if s.hub == nil {
+		s.hub = processor.NewHub()
+		s.processor = processor.NewBroadcastProcessor(s.processor, s.hub)
+	}
+
+	// Every ingestion route shares the tag limit, whether or not its items
+	// were tagged through AddTag
+	s.processor = processor.NewTagLimitProcessor(s.processor)
+
 	// Register routes
 	s.setupRoutes()
 
@@ -50,6 +372,7 @@ func NewServer(processor processor.Processor, port int) *Server {
 func (s *Server) setupRoutes() {
 	// Health check endpoint
 	s.routes["/health"] = s.handleHealth()
+	s.routes["/ready"] = s.handleReady()
 
 	// Log ingestion endpoints
 	s.routes["/logs"] = s.logsHandler()
@@ -57,64 +380,279 @@ func (s *Server) setupRoutes() {
 
 	// Metric ingestion endpoints
 	s.routes["/metrics"] = s.metricsHandler()
+	s.routes["/metrics/batch"] = s.metricsBatchHandler()
+	s.routes["/metrics/histogram"] = s.histogramSubmitHandler()
+	s.routes["/metrics/prometheus"] = s.prometheusHandler()
+	if s.influxWrite {
+		s.routes["/write"] = s.influxWriteHandler()
+	}
 
 	// Trace ingestion endpoints
 	s.routes["/traces"] = s.tracesHandler()
 	s.routes["/spans"] = s.spansHandler()
+	s.routes["/spans/batch"] = s.spansBatchHandler()
+
+	// OpenTelemetry (OTLP/HTTP) ingestion endpoints
+	s.routes["/v1/traces"] = s.otlpTracesHandler()
 
 	// Dashboard API endpoints
 	s.routes["/api/logs"] = s.apiLogsHandler()
+	s.routes["/api/logs/tags"] = s.apiLogTagsHandler()
 	s.routes["/api/metrics"] = s.apiMetricsHandler()
+	s.routes["/api/histograms"] = s.apiHistogramsHandler()
+	s.routes["/api/metrics/batch-query"] = s.metricsBatchQueryHandler()
+	s.routes["/api/metrics/export"] = s.metricsExportHandler()
+	s.routes["/api/apdex"] = s.apdexHandler()
+	s.routes["/api/metrics/percentiles"] = s.metricPercentilesHandler()
 	s.routes["/api/traces"] = s.apiTracesHandler()
+	s.routes["/api/traces/slow-percentile"] = s.apiSlowTracesHandler()
+	s.routes["/api/traces/ids"] = s.apiTraceIDsHandler()
+	s.routes["/api/traces/sampling-report"] = s.apiTailSamplingReportHandler()
+	s.routes["/api/traces/"] = s.apiTraceHandler()
 	s.routes["/api/spans"] = s.apiSpansHandler()
+	s.routes["/api/spans/"] = s.apiSpanHandler()
+	s.routes["/api/export"] = s.exportHandler()
+	s.routes["/api/import"] = s.importHandler()
+	s.routes["/api/spans/errors/by-type"] = s.apiSpanErrorsByTypeHandler()
 	s.routes["/api/services"] = s.apiServicesHandler()
+	s.routes["/api/services/"] = s.apiServiceHandler()
 	s.routes["/api/stats"] = s.apiStatsHandler()
+	s.routes["/api/clear"] = s.clearHandler()
+	s.routes["/api/connections"] = s.apiConnectionsHandler()
 
 	// WebSocket endpoints
 	s.routes["/ws/logs"] = s.wsLogsHandler()
 	s.routes["/ws/metrics"] = s.wsMetricsHandler()
 	s.routes["/ws/traces"] = s.wsTracesHandler()
 
-	// Add improved static file handler for dashboard
-	// This will handle both /dashboard and /dashboard/ correctly
-	dashboardHandler := http.StripPrefix("/dashboard", http.FileServer(http.Dir("./dashboard")))
-	s.routes["/dashboard"] = func(w http.ResponseWriter, r *http.Request) {
-		// If path is exactly /dashboard, redirect to /dashboard/ to ensure relative paths work correctly
-		if r.URL.Path == "/dashboard" {
-			http.Redirect(w, r, "/dashboard/", http.StatusMovedPermanently)
-			return
+	// Static dashboard files, handling both /dashboard and /dashboard/
+	dashboard := s.dashboardHandler()
+	s.routes["/dashboard"] = dashboard
+	s.routes["/dashboard/"] = dashboard
+}
+
+// dashboardHandler returns a handler serving the dashboard files embedded in
+// the binary, or those in the dashboard directory if one is set. If that
+// directory is missing, every dashboard path answers 404 with a page
+// explaining how to provide it.
+func (s *Server) dashboardHandler() http.HandlerFunc {
+	files := dashboard.Files()
+	if s.dashboardDir != "" {
+		if info, err := os.Stat(s.dashboardDir); err != nil || !info.IsDir() {
+			log.Printf("Dashboard directory %s not found; /dashboard will explain how to build it", s.dashboardDir)
+			page := fmt.Sprintf(dashboardMissingPage, html.EscapeString(s.dashboardDir))
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, page)
+			}
 		}
-		dashboardHandler.ServeHTTP(w, r)
+		files = os.DirFS(s.dashboardDir)
 	}
 
-	// Handle dashboard's root path to ensure it loads index.html
-	s.routes["/dashboard/"] = func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/dashboard/" {
-			http.ServeFile(w, r, "./dashboard/index.html")
-			return
+	fileServer := http.StripPrefix("/dashboard", http.FileServer(http.FS(files)))
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dashboard":
+			// Redirect to /dashboard/ so that relative paths work
+			http.Redirect(w, r, s.basePath+"/dashboard/", http.StatusMovedPermanently)
+		case "/dashboard/":
+			if key := r.URL.Query().Get("api_key"); key != "" && len(s.apiKeys) > 0 {
+				s.dashboardLogin(w, r, key)
+				return
+			}
+			index, err := fs.ReadFile(files, "index.html")
+			if err != nil {
+				http.Error(w, "Dashboard index.html not found", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(injectBasePath(index, s.basePath))
+		default:
+			fileServer.ServeHTTP(w, r)
 		}
-		dashboardHandler.ServeHTTP(w, r)
 	}
 }
 
+// dashboardLogin answers /dashboard/?api_key=... by storing a valid key in
+// a cookie, which the browser then sends with the dashboard's API requests
+// and WebSocket handshakes, and redirecting to the dashboard without the key
+// in its URL
+func (s *Server) dashboardLogin(w http.ResponseWriter, r *http.Request, key string) {
+	if !s.validAPIKey(key) {
+		http.Error(w, "Unauthorized: invalid API key", http.StatusUnauthorized)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     apiKeyCookie,
+		Value:    key,
+		Path:     s.basePath + "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, s.basePath+"/dashboard/", http.StatusSeeOther)
+}
+
+// injectBasePath adds a script setting window.PULSE_BASE_PATH to the
+// dashboard's index.html, which the dashboard prefixes its API requests with
+func injectBasePath(index []byte, basePath string) []byte {
+	encoded, _ := json.Marshal(basePath) // escapes <, > and &, so it can't close the script
+	script := []byte("<script>window.PULSE_BASE_PATH = " + string(encoded) + ";</script>\n")
+
+	at := bytes.Index(index, []byte("</head>"))
+	if at < 0 {
+		return append(script, index...)
+	}
+	injected := make([]byte, 0, len(index)+len(script))
+	injected = append(injected, index[:at]...)
+	injected = append(injected, script...)
+	return append(injected, index[at:]...)
+}
+
+// dashboardMissingPage is served in place of the dashboard when the
+// directory overriding it, the format argument, does not exist
+const dashboardMissingPage = `<!DOCTYPE html>
+<html>
+<head><title>Pulse dashboard not found</title></head>
+<body>
+<h1>Pulse dashboard not found</h1>
+<p>The dashboard directory <code>%s</code> does not exist. The API is running,
+but there are no dashboard files to serve.</p>
+<p>Build the dashboard with <code>npm install &amp;&amp; npm run build</code> in
+<code>dashboard-react</code> and start Pulse with
+<code>-dashboard-dir dashboard-react/dist</code>, or drop
+<code>-dashboard-dir</code> to serve the dashboard embedded in the binary.</p>
+</body>
+</html>
+`
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", s.port, err)
+	}
+
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on the given listener until Stop is called
+func (s *Server) Serve(listener net.Listener) error {
+	// Create the server
+	s.serverLock.Lock()
+	s.server = &http.Server{
+		Addr:    listener.Addr().String(),
+		Handler: s.handler(),
+	}
+	server := s.server
+	s.serverLock.Unlock()
+
+	// Start the server
+	log.Printf("Starting API server on %s", listener.Addr())
+	return server.Serve(listener)
+}
+
+// handler returns the mux serving every route under the base path. The
+// prefix is stripped before the route handlers run, so they only ever see
+// root paths.
+func (s *Server) handler() http.Handler {
 	mux := http.NewServeMux()
 
 	// Register all routes with the mux
 	for path, handler := range s.routes {
-		mux.HandleFunc(path, corsMiddleware(handler))
+		var h http.Handler = corsMiddleware(s.gzipMiddleware(s.authMiddleware(path, s.rateLimitMiddleware(path, handler))))
+		if s.basePath != "" {
+			h = http.StripPrefix(s.basePath, h)
+		}
+		mux.Handle(s.basePath+path, h)
 	}
+	return mux
+}
 
-	// Create the server
-	s.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.port),
-		Handler: mux,
+// readBody reads the request body, rejecting bodies over limit bytes with
+// 413 rather than truncating them. ok is false once an error response has
+// been written.
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) (body []byte, ok bool) {
+	defer r.Body.Close()
+	return readLimited(w, r.Body, limit)
+}
+
+// readLimited reads body like readBody. Bodies cut short by the decompressed
+// limit of gzipMiddleware are rejected with 413 as well.
+func readLimited(w http.ResponseWriter, body io.Reader, limit int64) ([]byte, bool) {
+	// Read one byte past the limit to tell a full body from a truncated one
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		limit = tooLarge.Limit
+	} else if err != nil {
+		log.Printf("Error reading request body: %v", err)
+		http.Error(w, "Error reading request", http.StatusBadRequest)
+		return nil, false
+	}
+	if err != nil || int64(len(data)) > limit {
+		http.Error(w, fmt.Sprintf("Request body exceeds the limit of %d bytes", limit), http.StatusRequestEntityTooLarge)
+		return nil, false
 	}
+	return data, true
+}
 
-	// Start the server
-	log.Printf("Starting API server on port %d", s.port)
-	return s.server.ListenAndServe()
+// batchBodyLimit is the body limit of the metric batch endpoints, which are
+// expected to be much larger than single submissions
+func (s *Server) batchBodyLimit() int64 {
+	if s.maxBodySize > maxMetricBatchBody {
+		return s.maxBodySize
+	}
+	return maxMetricBatchBody
+}
+
+// processErrorStatus maps an error returned by the processor to an HTTP status
+func processErrorStatus(err error) int {
+	if errors.Is(err, processor.ErrRateLimited) {
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, processor.ErrProcessorClosed) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// checkTimestamp returns an error naming field if ts is out of the
+// configured bounds, given the maxAge of its signal. Zero timestamps are
+// left to the defaults of the models.
+func (s *Server) checkTimestamp(field string, ts time.Time, maxAge time.Duration) error {
+	if ts.IsZero() {
+		return nil
+	}
+	now := s.now()
+	if s.maxClockSkew > 0 && ts.After(now.Add(s.maxClockSkew)) {
+		return fmt.Errorf("%s %s is more than %s in the future", field, ts.Format(time.RFC3339Nano), s.maxClockSkew)
+	}
+	if maxAge > 0 && ts.Before(now.Add(-maxAge)) {
+		return fmt.Errorf("%s %s is older than the %s retention", field, ts.Format(time.RFC3339Nano), maxAge)
+	}
+	return nil
+}
+
+// checkSpanTimestamps returns an error if the start or end time of span is
+// out of the configured bounds
+func (s *Server) checkSpanTimestamps(span *models.Span) error {
+	if err := s.checkTimestamp("start_time", span.StartTime, s.maxTimestampAge.Traces); err != nil {
+		return err
+	}
+	return s.checkTimestamp("end_time", span.EndTime, s.maxTimestampAge.Traces)
+}
+
+// observeQuery logs a warning if the storage query of the given type, started
+// at start, took longer than the slow query threshold
+func (s *Server) observeQuery(queryType string, start time.Time) {
+	if s.slowQueryThreshold <= 0 {
+		return
+	}
+	if elapsed := time.Since(start); elapsed > s.slowQueryThreshold {
+		log.Printf("WARN: slow %s query took %v (threshold %v)", queryType, elapsed, s.slowQueryThreshold)
+	}
 }
 
 // corsMiddleware adds CORS headers to responses
@@ -122,8 +660,8 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, PATCH, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 
 		// Handle preflight requests
 		if r.Method == http.MethodOptions {
@@ -136,18 +674,233 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// gzipMiddleware decompresses gzip request bodies and compresses JSON
+// responses for clients accepting gzip. Decompressed bodies are capped at the
+// largest body limit of the handlers, the batch limit, so that a small
+// compressed body can't expand without bound; reading past it fails with an
+// *http.MaxBytesError, which readBody answers with 413. Handlers apply their
+// own, usually smaller, limits on top.
+func (s *Server) gzipMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	limit := s.batchBodyLimit()
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "Invalid gzip body", http.StatusBadRequest)
+				return
+			}
+			defer gz.Close()
+
+			// Handlers see the decompressed body
+			r.Body = http.MaxBytesReader(w, struct {
+				io.Reader
+				io.Closer
+			}{gz, r.Body}, limit)
+			r.Header.Del("Content-Encoding")
+			r.ContentLength = -1
+		}
+
+		// WebSocket upgrades hijack the connection and can't be compressed
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Header.Get("Upgrade") != "" {
+			next(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.Close()
+		w.Header().Add("Vary", "Accept-Encoding")
+		next(gw, r)
+	}
+}
+
+// gzipResponseWriter compresses the response if it is JSON, deciding when
+// the header is written
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+// WriteHeader starts compressing JSON responses with a body
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	header := w.Header()
+	if strings.HasPrefix(header.Get("Content-Type"), "application/json") &&
+		status != http.StatusNoContent && status != http.StatusNotModified {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the body, compressed if the response is JSON
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush sends the data compressed so far to the client
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close finishes the compressed stream
+func (w *gzipResponseWriter) Close() error {
+	if w.gz == nil {
+		return nil
+	}
+	return w.gz.Close()
+}
+
+// openRoutes are served without an API key: the health and readiness checks
+// used by load balancers and the static dashboard files, which hold no
+// telemetry
+var openRoutes = map[string]bool{
+	"/health":     true,
+	"/ready":      true,
+	"/dashboard":  true,
+	"/dashboard/": true,
+}
+
+// authMiddleware rejects requests to the route at path that don't present a
+// configured API key, unless no keys are configured or the route is open
+func (s *Server) authMiddleware(path string, next http.HandlerFunc) http.HandlerFunc {
+	if len(s.apiKeys) == 0 || openRoutes[path] {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.validAPIKey(requestAPIKey(r)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pulse"`)
+			http.Error(w, "Unauthorized: a valid API key is required", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// apiKeyCookie is the cookie the dashboard keeps its API key in
+const apiKeyCookie = "pulse_api_key"
+
+// requestAPIKey returns the API key sent as a bearer token or in the
+// X-API-Key header. Browsers can't set headers on WebSocket handshakes, so
+// the api_key query parameter and the dashboard's cookie are accepted too.
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	auth := r.Header.Get("Authorization")
+	if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(auth[len("Bearer "):])
+	}
+	if key := r.URL.Query().Get("api_key"); key != "" {
+		return key
+	}
+	if cookie, err := r.Cookie(apiKeyCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// validAPIKey reports whether key is one of the configured keys. Every key
+// is compared in constant time so the response time doesn't leak them.
+func (s *Server) validAPIKey(key string) bool {
+	valid := false
+	for candidate := range s.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// wsCloseTimeout bounds how long Stop waits to send the close frame of each
+// WebSocket connection
+const wsCloseTimeout = time.Second
+
+// trackConn registers a WebSocket connection of the given stream kind. It
+// returns the context of the connection's stream, cancelled when the server
+// stops, and a function that closes and unregisters the connection.
+func (s *Server) trackConn(kind string, conn *websocket.Conn) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.connLock.Lock()
+	if s.connsClosed {
+		cancel()
+	} else {
+		if s.activeConns[kind] == nil {
+			s.activeConns[kind] = make(map[*websocket.Conn]context.CancelFunc)
+		}
+		s.activeConns[kind][conn] = cancel
+	}
+	s.connLock.Unlock()
+
+	return ctx, func() {
+		cancel()
+		conn.Close()
+		s.connLock.Lock()
+		delete(s.activeConns[kind], conn)
+		s.connLock.Unlock()
+	}
+}
+
+// connectionCounts returns the number of open WebSocket connections of each
+// stream kind
+func (s *Server) connectionCounts() map[string]int {
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
+	counts := map[string]int{"logs": 0, "metrics": 0, "traces": 0}
+	for kind, conns := range s.activeConns {
+		counts[kind] = len(conns)
+	}
+	return counts
+}
+
 // Stop gracefully shuts down the HTTP server
 func (s *Server) Stop(ctx context.Context) error {
 	log.Printf("Shutting down API server")
 
-	// Close all WebSocket connections
+	// Stop the WebSocket streams and tell their clients the server is going
+	// away, so that dashboards see a clean close rather than an error. The
+	// HTTP server doesn't track hijacked connections, so Shutdown won't.
 	s.connLock.Lock()
-	for conn := range s.activeConns {
-		conn.Close()
+	s.connsClosed = true
+	for _, conns := range s.activeConns {
+		for conn, cancel := range conns {
+			cancel()
+			message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+			if err := conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(wsCloseTimeout)); err != nil {
+				log.Printf("Error sending WebSocket close frame: %v", err)
+			}
+			conn.Close()
+		}
 	}
 	s.connLock.Unlock()
 
-	return s.server.Shutdown(ctx)
+	s.serverLock.Lock()
+	server := s.server
+	s.serverLock.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
 }
 
 // handleHealth returns a health check handler
@@ -169,3 +922,47 @@ func (s *Server) handleHealth() http.HandlerFunc {
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// handleReady returns a readiness check handler. Unlike /health, which only
+// reports that the server is up, it answers 503 while the storage can't be
+// reached.
+func (s *Server) handleReady() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := http.StatusOK
+		response := map[string]interface{}{
+			"status": "ready",
+			"time":   s.now().UTC(),
+		}
+		if err := s.checkReady(); err != nil {
+			status = http.StatusServiceUnavailable
+			response["status"] = "unavailable"
+			response["error"] = err.Error()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// checkReady pings the storage, or returns the result of the last ping if it
+// is more recent than readyCacheTTL. Concurrent checks wait for a single ping.
+func (s *Server) checkReady() error {
+	s.readyLock.Lock()
+	defer s.readyLock.Unlock()
+
+	now := s.now()
+	if s.readyChecked.IsZero() || now.Sub(s.readyChecked) >= readyCacheTTL {
+		s.readyErr = s.processor.Ping()
+		s.readyChecked = now
+		if s.readyErr != nil {
+			log.Printf("Readiness check failed: %v", s.readyErr)
+		}
+	}
+	return s.readyErr
+}
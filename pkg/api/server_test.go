@@ -0,0 +1,744 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+// stubProcessor is a test double for processor.Processor. It records ingested
+// items and lets tests override query behavior. Methods that are not
+// overridden panic via the nil embedded Processor.
+type stubProcessor struct {
+	processor.Processor
+
+	mu         sync.Mutex
+	logs       []*models.LogEntry
+	metrics    []*models.Metric
+	batches    int
+	histograms []*models.HistogramMetric
+	spans      []*models.Span
+	traces     []*models.Trace
+
+	aggregateFn func(query storage.MetricQuery) ([]storage.MetricAggregation, error)
+	streamFn    func(query *models.QueryParams, fn func(metric *models.Metric) error) error
+}
+
+func (p *stubProcessor) ProcessLog(log *models.LogEntry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.logs = append(p.logs, log)
+	return nil
+}
+
+func (p *stubProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.logs = append(p.logs, logs...)
+	return nil
+}
+
+func (p *stubProcessor) ProcessMetric(metric *models.Metric) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.metrics = append(p.metrics, metric)
+	return nil
+}
+
+func (p *stubProcessor) ProcessMetrics(metrics []*models.Metric) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.metrics = append(p.metrics, metrics...)
+	p.batches++
+	return nil
+}
+
+func (p *stubProcessor) ProcessHistogram(histogram *models.HistogramMetric) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.histograms = append(p.histograms, histogram)
+	return nil
+}
+
+func (p *stubProcessor) Ping() error {
+	return nil
+}
+
+func (p *stubProcessor) ProcessSpan(span *models.Span) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spans = append(p.spans, span)
+	return nil
+}
+
+func (p *stubProcessor) ProcessTrace(trace *models.Trace) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.traces = append(p.traces, trace)
+	return nil
+}
+
+func (p *stubProcessor) AggregateMetrics(query storage.MetricQuery) ([]storage.MetricAggregation, error) {
+	return p.aggregateFn(query)
+}
+
+func (p *stubProcessor) StreamMetrics(query *models.QueryParams, fn func(metric *models.Metric) error) error {
+	return p.streamFn(query, fn)
+}
+
+func (p *stubProcessor) QueryLogs(query *models.QueryParams) (map[string]interface{}, error) {
+	return map[string]interface{}{"logs": []map[string]interface{}{}}, nil
+}
+
+func (p *stubProcessor) Close() error {
+	return nil
+}
+
+func TestProcessErrorStatus(t *testing.T) {
+	if status := processErrorStatus(fmt.Errorf("wrapped: %w", processor.ErrRateLimited)); status != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for rate limited errors, got %d", status)
+	}
+	if status := processErrorStatus(processor.ErrProcessorClosed); status != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 once the processor is closed, got %d", status)
+	}
+	if status := processErrorStatus(errors.New("disk full")); status != http.StatusInternalServerError {
+		t.Errorf("expected 500 for other errors, got %d", status)
+	}
+}
+
+// slowProcessor delays log queries to simulate a slow storage backend
+type slowProcessor struct {
+	stubProcessor
+	delay time.Duration
+}
+
+func (p *slowProcessor) QueryLogs(query *models.QueryParams) (map[string]interface{}, error) {
+	time.Sleep(p.delay)
+	return p.stubProcessor.QueryLogs(query)
+}
+
+func TestSlowQueryLogging(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	query := func(threshold time.Duration) string {
+		buf.Reset()
+		server := NewServer(&slowProcessor{delay: 20 * time.Millisecond}, 0, WithSlowQueryThreshold(threshold))
+		req := httptest.NewRequest(http.MethodGet, "/api/logs?service=api", nil)
+		rec := httptest.NewRecorder()
+		server.apiLogsHandler()(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return buf.String()
+	}
+
+	if output := query(5 * time.Millisecond); !strings.Contains(output, "WARN: slow logs query took") {
+		t.Errorf("expected slow query warning, got log output: %s", output)
+	}
+	if output := query(time.Hour); strings.Contains(output, "slow") {
+		t.Errorf("expected no warning under the threshold, got log output: %s", output)
+	}
+	if output := query(0); strings.Contains(output, "slow") {
+		t.Errorf("expected no warning when disabled, got log output: %s", output)
+	}
+}
+
+// startTestServer serves server on a local port until the test ends and
+// returns its base URL
+func startTestServer(t *testing.T, server *Server) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	})
+	return "http://" + listener.Addr().String()
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	proc := &stubProcessor{}
+	baseURL := startTestServer(t, NewServer(proc, 0, WithAPIKeys("secret-1", "secret-2")))
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		status  int
+	}{
+		{"health is open", http.MethodGet, "/health", nil, http.StatusOK},
+		{"readiness is open", http.MethodGet, "/ready", nil, http.StatusOK},
+		{"query without key", http.MethodGet, "/api/logs", nil, http.StatusUnauthorized},
+		{"query with X-API-Key", http.MethodGet, "/api/logs", map[string]string{"X-API-Key": "secret-2"}, http.StatusOK},
+		{"query with bearer token", http.MethodGet, "/api/logs", map[string]string{"Authorization": "Bearer secret-1"}, http.StatusOK},
+		{"query with wrong key", http.MethodGet, "/api/logs", map[string]string{"Authorization": "Bearer secret-3"}, http.StatusUnauthorized},
+		{"basic auth is not a key", http.MethodGet, "/api/logs", map[string]string{"Authorization": "Basic secret-1"}, http.StatusUnauthorized},
+		{"ingestion without key", http.MethodPost, "/logs", nil, http.StatusUnauthorized},
+		{"delete without key", http.MethodDelete, "/api/clear", nil, http.StatusUnauthorized},
+		{"CORS preflight", http.MethodOptions, "/api/logs", nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, baseURL+tt.path, strings.NewReader(`{"message": "hello", "service": "api"}`))
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, resp.StatusCode)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected a WWW-Authenticate header", tt.name)
+		}
+	}
+
+	if len(proc.logs) != 0 {
+		t.Errorf("expected unauthenticated logs to be rejected, got %d", len(proc.logs))
+	}
+}
+
+func TestAuthMiddleware_NoKeys(t *testing.T) {
+	proc := &stubProcessor{}
+	baseURL := startTestServer(t, NewServer(proc, 0, WithAPIKeys("")))
+
+	resp, err := http.Post(baseURL+"/logs", "application/json", strings.NewReader(`{"message": "hello", "service": "api"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the API to stay open without keys, got %d", resp.StatusCode)
+	}
+}
+
+func TestAuthMiddleware_DashboardAndWebSockets(t *testing.T) {
+	baseURL := startTestServer(t, NewServer(&stubProcessor{}, 0, WithAPIKeys("secret")))
+	wsURL := "ws" + strings.TrimPrefix(baseURL, "http") + "/ws/logs"
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	get := func(path string) *http.Response {
+		resp, err := client.Get(baseURL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := get("/dashboard/?api_key=wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status 401 for a wrong key, got %d", resp.StatusCode)
+	}
+	if resp := get("/api/logs"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status 401 before logging in, got %d", resp.StatusCode)
+	}
+
+	// Logging in stores the key in a cookie and drops it from the URL
+	resp := get("/dashboard/?api_key=secret")
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.RawQuery != "" {
+		t.Errorf("expected a redirect to the dashboard without the key, got %d for %s", resp.StatusCode, resp.Request.URL)
+	}
+	if resp := get("/api/logs"); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the dashboard's cookie to authenticate, got %d", resp.StatusCode)
+	}
+
+	// WebSocket handshakes authenticate with the query parameter or cookie
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected a WebSocket without a key to be rejected")
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?api_key=secret", nil)
+	if err != nil {
+		t.Fatalf("expected the api_key parameter to authenticate: %v", err)
+	}
+	conn.Close()
+	dialer := &websocket.Dialer{Jar: jar}
+	conn, _, err = dialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("expected the dashboard's cookie to authenticate: %v", err)
+	}
+	conn.Close()
+}
+
+// gzipped compresses data
+func gzipped(t *testing.T, data []byte) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	gz.Close()
+	return &buf
+}
+
+func TestGzipMiddleware(t *testing.T) {
+	proc := &stubProcessor{}
+	baseURL := startTestServer(t, NewServer(proc, 0))
+
+	// Compressed request bodies are decompressed before the handler parses them
+	req, _ := http.NewRequest(http.MethodPost, baseURL+"/logs", gzipped(t, []byte(`{"message": "compressed", "service": "api"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if len(proc.logs) != 1 || proc.logs[0].Message != "compressed" {
+		t.Errorf("expected the decompressed log to be processed, got %v", proc.logs)
+	}
+
+	// Bodies that aren't gzip are rejected
+	req, _ = http.NewRequest(http.MethodPost, baseURL+"/logs", strings.NewReader(`{"message": "plain"}`))
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid gzip body, got %d", resp.StatusCode)
+	}
+
+	// JSON responses are compressed for clients accepting gzip. Setting the
+	// header stops the client from decompressing transparently.
+	req, _ = http.NewRequest(http.MethodGet, baseURL+"/health", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip response, got headers %v", resp.Header)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("expected a valid gzip body: %v", err)
+	}
+	var health map[string]interface{}
+	if err := json.NewDecoder(gz).Decode(&health); err != nil {
+		t.Fatalf("expected a JSON body: %v", err)
+	}
+	if health["status"] == nil {
+		t.Errorf("unexpected health response: %v", health)
+	}
+}
+
+func TestGzipMiddleware_DecompressedLimit(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+	limit := server.batchBodyLimit()
+	handler := server.gzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if body, ok := readBody(w, r, 2*limit); ok {
+			w.Write([]byte(fmt.Sprint(len(body))))
+		}
+	})
+	post := func(size int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/logs", gzipped(t, make([]byte, size)))
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	// A few kilobytes that expand past the limit are rejected, not cut short
+	if rec := post(limit + 1<<20); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(limit); rec.Code != http.StatusOK || rec.Body.String() != fmt.Sprint(limit) {
+		t.Errorf("expected a body at the limit to be read whole, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTimestampBounds(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0, WithClock(func() time.Time { return now }), WithInfluxWrite(),
+		WithTimestampBounds(5*time.Minute, storage.RetentionPolicy{Logs: 24 * time.Hour, Traces: time.Hour}))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.routes[strings.Split(path, "?")[0]](rec, req)
+		return rec
+	}
+	stamp := func(d time.Duration) string {
+		return now.Add(d).Format(time.RFC3339)
+	}
+	nanos := func(d time.Duration) string {
+		return fmt.Sprint(now.Add(d).UnixNano())
+	}
+	otlp := func(start, end time.Duration) string {
+		return `{"resourceSpans": [{"scopeSpans": [{"spans": [{"traceId": "5b8efff798038103d269b633813fc60c",
+			"spanId": "eee19b7ec3c1b173", "name": "GET /", "startTimeUnixNano": "` + nanos(start) + `",
+			"endTimeUnixNano": "` + nanos(end) + `"}]}]}]}`
+	}
+
+	for _, c := range []struct {
+		path, body string
+		status     int
+	}{
+		{"/logs", `{"message":"ok","service":"api","timestamp":"` + stamp(time.Minute) + `"}`, http.StatusOK},
+		{"/logs", `{"message":"late","service":"api","timestamp":"` + stamp(-23*time.Hour) + `"}`, http.StatusOK},
+		{"/logs", `{"message":"ahead","service":"api","timestamp":"` + stamp(time.Hour) + `"}`, http.StatusBadRequest},
+		{"/logs", `{"message":"expired","service":"api","timestamp":"` + stamp(-25*time.Hour) + `"}`, http.StatusBadRequest},
+		{"/logs/batch", `[{"message":"ok","service":"api"},{"message":"ahead","service":"api","timestamp":"` + stamp(time.Hour) + `"}]`, http.StatusBadRequest},
+		// Metrics have no retention configured, so only the skew is checked
+		{"/metrics", `{"name":"up","value":1,"service":"api","timestamp":"` + stamp(-1000*time.Hour) + `"}`, http.StatusOK},
+		{"/metrics", `{"name":"up","value":1,"service":"api","timestamp":"` + stamp(time.Hour) + `"}`, http.StatusBadRequest},
+		{"/metrics", `{"name":"latency","value":1,"type":"histogram","service":"api","timestamp":"` + stamp(time.Hour) + `"}`, http.StatusBadRequest},
+		{"/spans", `{"name":"GET /","service":"api","start_time":"` + stamp(-2*time.Hour) + `"}`, http.StatusBadRequest},
+		{"/spans", `{"name":"GET /","service":"api","start_time":"` + stamp(-time.Minute) + `","end_time":"` + stamp(time.Hour) + `"}`, http.StatusBadRequest},
+		{"/spans", `{"name":"GET /","service":"api","start_time":"` + stamp(-time.Minute) + `","end_time":"` + stamp(0) + `"}`, http.StatusOK},
+		{"/write", "cpu usage=1 " + nanos(-1000*time.Hour), http.StatusNoContent},
+		{"/write", "cpu usage=1 " + nanos(time.Hour), http.StatusBadRequest},
+		{"/v1/traces", otlp(-time.Minute, 0), http.StatusOK},
+		{"/v1/traces", otlp(-2*time.Hour, -2*time.Hour+time.Second), http.StatusBadRequest},
+		{"/v1/traces", otlp(-time.Minute, time.Hour), http.StatusBadRequest},
+	} {
+		rec := post(c.path, c.body)
+		if rec.Code != c.status {
+			t.Errorf("%s %s: expected status %d, got %d: %s", c.path, c.body, c.status, rec.Code, rec.Body.String())
+		}
+	}
+	if len(proc.logs) != 2 {
+		t.Errorf("expected no log of a rejected batch to be processed, got %d logs", len(proc.logs))
+	}
+	if len(proc.metrics) != 2 || len(proc.traces) != 1 {
+		t.Errorf("expected no metric or trace of a rejected write to be processed, got %d metrics and %d traces", len(proc.metrics), len(proc.traces))
+	}
+
+	// Imported lines out of bounds are reported like lines that can't be decoded
+	rec := post("/api/import?type=logs", `{"message":"ok","service":"api","timestamp":"`+stamp(-time.Hour)+`"}`+"\n"+
+		`{"message":"expired","service":"api","timestamp":"`+stamp(-25*time.Hour)+`"}`)
+	var response ImportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Inserted != 1 || response.Failed != 1 || response.Failures[0].Line != 2 {
+		t.Errorf("expected the expired line to fail, got %+v", response)
+	}
+
+	rec = post("/logs", `{"message":"ahead","service":"api","timestamp":"`+stamp(time.Hour)+`"}`)
+	if !strings.Contains(rec.Body.String(), "timestamp 2024-06-01T13:00:00Z is more than 5m0s in the future") {
+		t.Errorf("expected the offending timestamp in the error, got %q", rec.Body.String())
+	}
+
+	// Without bounds any timestamp is accepted
+	lenient := NewServer(&stubProcessor{}, 0)
+	req := httptest.NewRequest(http.MethodPost, "/logs", strings.NewReader(`{"message":"ahead","service":"api","timestamp":"2100-01-01T00:00:00Z"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	lenient.routes["/logs"](rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected a server without bounds to accept future timestamps, got %d", rec.Code)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	store := storage.NewMockStorage()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := NewServer(processor.NewStorageProcessor(store), 0, WithClock(func() time.Time { return now }))
+
+	ready := func() (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		server.routes["/ready"](rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		var response map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rec.Code, response
+	}
+
+	if code, response := ready(); code != http.StatusOK || response["status"] != "ready" {
+		t.Errorf("expected 200 ready, got %d %v", code, response)
+	}
+
+	store.Close()
+	if code, _ := ready(); code != http.StatusOK {
+		t.Errorf("expected the cached result within %s, got %d", readyCacheTTL, code)
+	}
+
+	now = now.Add(readyCacheTTL)
+	code, response := ready()
+	if code != http.StatusServiceUnavailable || response["status"] != "unavailable" || response["error"] != storage.ErrStorageClosed.Error() {
+		t.Errorf("expected 503 once the storage is down, got %d %v", code, response)
+	}
+
+	rec := httptest.NewRecorder()
+	server.routes["/health"](rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected /health to stay up while the storage is down, got %d", rec.Code)
+	}
+}
+
+func TestDashboardHandler(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>Pulse</h1>"), 0o644)
+	os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log('pulse')"), 0o644)
+	server := NewServer(&stubProcessor{}, 0, WithDashboardDir(dir))
+
+	for path, expected := range map[string]string{
+		"/dashboard/":       "<script>window.PULSE_BASE_PATH = \"\";</script>\n<h1>Pulse</h1>",
+		"/dashboard/app.js": "console.log('pulse')",
+	} {
+		rec := httptest.NewRecorder()
+		server.routes["/dashboard/"](rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != expected {
+			t.Errorf("%s: expected %q, got %d %q", path, expected, rec.Code, rec.Body.String())
+		}
+	}
+	rec := httptest.NewRecorder()
+	server.routes["/dashboard"](rec, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/dashboard/" {
+		t.Errorf("expected a redirect to /dashboard/, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestDashboardHandler_Embedded(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+
+	rec := httptest.NewRecorder()
+	server.routes["/dashboard/"](rec, httptest.NewRequest(http.MethodGet, "/dashboard/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected an HTML page, got %s", ct)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "<title>Pulse Dashboard</title>") {
+		t.Errorf("expected the embedded index.html, got %q", body)
+	}
+}
+
+func TestDashboardHandler_MissingDirectory(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "dashboard")
+	server := NewServer(&stubProcessor{}, 0, WithDashboardDir(missing))
+
+	for _, path := range []string{"/dashboard", "/dashboard/", "/dashboard/assets/app.js"} {
+		route := "/dashboard/"
+		if path == "/dashboard" {
+			route = path
+		}
+		rec := httptest.NewRecorder()
+		server.routes[route](rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", path, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+			t.Errorf("%s: expected an HTML page, got %s", path, ct)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, missing) || !strings.Contains(body, "-dashboard-dir") {
+			t.Errorf("%s: expected the page to name the directory and the flag, got %q", path, body)
+		}
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0, WithBasePath("pulse/"))
+	handler := server.handler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	for _, path := range []string{"/pulse/health", "/pulse/api/connections", "/pulse/dashboard/"} {
+		if rec := get(path); rec.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+	for _, path := range []string{"/health", "/api/connections", "/dashboard/"} {
+		if rec := get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404 outside the base path, got %d", path, rec.Code)
+		}
+	}
+
+	rec := get("/pulse/dashboard")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/pulse/dashboard/" {
+		t.Errorf("expected a redirect to /pulse/dashboard/, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	body := get("/pulse/dashboard/").Body.String()
+	if !strings.Contains(body, `<script>window.PULSE_BASE_PATH = "/pulse";</script>`+"\n</head>") {
+		t.Errorf("expected the base path injected into the dashboard's head, got %q", body)
+	}
+}
+
+func TestInjectBasePath(t *testing.T) {
+	injected := string(injectBasePath([]byte("<head></head>"), "/a</script>"))
+	expected := `<head><script>window.PULSE_BASE_PATH = "/a\u003c/script\u003e";</script>` + "\n</head>"
+	if injected != expected {
+		t.Errorf("expected %q, got %q", expected, injected)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0, WithMaxBodySize(256))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.routes[path](rec, req)
+		return rec
+	}
+	padding := strings.Repeat("x", 300)
+
+	for path, body := range map[string]string{
+		"/logs":       `{"message":"` + padding + `","service":"api"}`,
+		"/logs/batch": `[{"message":"` + padding + `","service":"api"}]`,
+		"/metrics":    `{"name":"` + padding + `","value":1,"service":"api"}`,
+		"/traces":     `{"spans":[{"name":"` + padding + `","service":"api"}]}`,
+		"/spans":      `{"name":"` + padding + `","service":"api"}`,
+	} {
+		rec := post(path, body)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: expected status 413, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), "limit of 256 bytes") {
+			t.Errorf("%s: expected the limit in the error, got %q", path, rec.Body.String())
+		}
+	}
+
+	// A body of exactly the limit is accepted
+	body := `{"message":"","service":"api"}`
+	body = `{"message":"` + strings.Repeat("x", 256-len(body)) + `","service":"api"}`
+	if rec := post("/logs", body); rec.Code != http.StatusOK {
+		t.Errorf("expected a body at the limit to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(proc.logs) != 1 {
+		t.Errorf("expected only the log within the limit to be processed, got %d", len(proc.logs))
+	}
+
+	// Metric batches keep their larger limit
+	if rec := post("/metrics/batch", `[{"name":"`+padding+`","value":1,"service":"api"}]`); rec.Code != http.StatusOK {
+		t.Errorf("expected the metric batch to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestIngest_MaxTags(t *testing.T) {
+	defer models.SetMaxTags(2)()
+
+	// Every route keeps the first two tags in key order, a and b
+	tags := `{"d": "1", "c": "1", "b": "1", "a": "1"}`
+	attributes := `[{"key": "d", "value": {"stringValue": "1"}}, {"key": "c", "value": {"stringValue": "1"}},
+		{"key": "b", "value": {"stringValue": "1"}}, {"key": "a", "value": {"stringValue": "1"}}]`
+	requests := []struct {
+		path        string
+		contentType string
+		body        string
+	}{
+		{"/logs", "application/json", `{"message": "hello", "service": "api", "tags": ` + tags + `}`},
+		{"/logs/batch", "application/json", `[{"message": "hello", "service": "api", "tags": ` + tags + `}]`},
+		{"/metrics", "application/json", `{"name": "requests", "value": 1, "service": "api", "tags": ` + tags + `}`},
+		{"/metrics", "text/plain", `requests_total{d="1",c="1",b="1",a="1"} 5`},
+		{"/metrics/batch", "application/json", `[{"name": "requests", "value": 1, "service": "api", "tags": ` + tags + `}]`},
+		{"/metrics/histogram", "application/json", `{"name": "latency", "service": "api", "tags": ` + tags + `,
+			"buckets": [{"upper_bound": 10, "count": 1}], "sum": 5, "count": 1}`},
+		{"/write", "text/plain", `cpu,d=1,c=1,b=1,a=1 usage=1`},
+		{"/traces", "application/json", `{"spans": [{"name": "GET /", "service": "api", "tags": ` + tags + `}]}`},
+		{"/spans", "application/json", `{"name": "GET /", "service": "api", "tags": ` + tags + `}`},
+		{"/spans/batch", "application/json", `[{"name": "GET /", "service": "api", "tags": ` + tags + `}]`},
+		{"/v1/traces", "application/json", `{"resourceSpans": [{"scopeSpans": [{"spans": [{
+			"traceId": "5b8efff798038103d269b633813fc60c", "spanId": "eee19b7ec3c1b173", "name": "GET /",
+			"startTimeUnixNano": "1700000000000000000", "endTimeUnixNano": "1700000000200000000",
+			"attributes": ` + attributes + `}]}]}]}`},
+		{"/api/import?type=logs", "application/x-ndjson", `{"message": "hello", "service": "api", "tags": ` + tags + `}`},
+	}
+
+	for _, tt := range requests {
+		proc := &stubProcessor{}
+		server := NewServer(proc, 0, WithInfluxWrite())
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		server.routes[strings.Split(tt.path, "?")[0]](rec, req)
+		if rec.Code >= 300 {
+			t.Fatalf("%s: expected success, got %d: %s", tt.path, rec.Code, rec.Body.String())
+		}
+
+		var ingested []map[string]string
+		for _, log := range proc.logs {
+			ingested = append(ingested, log.Tags)
+		}
+		for _, metric := range proc.metrics {
+			ingested = append(ingested, metric.Tags)
+		}
+		for _, histogram := range proc.histograms {
+			ingested = append(ingested, histogram.Tags)
+		}
+		for _, span := range proc.spans {
+			ingested = append(ingested, span.Tags)
+		}
+		for _, trace := range proc.traces {
+			for _, span := range trace.Spans {
+				ingested = append(ingested, span.Tags)
+			}
+		}
+		if len(ingested) == 0 {
+			t.Errorf("%s: expected an ingested item", tt.path)
+		}
+		for _, tags := range ingested {
+			if len(tags) != 2 || tags["a"] != "1" || tags["b"] != "1" {
+				t.Errorf("%s: expected only tags a and b, got %v", tt.path, tags)
+			}
+		}
+	}
+
+	// Bulk tag updates keep stored logs within the limit too
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	entry := models.NewLogEntry("api", "hello", models.LogLevelInfo).AddTag("c", "1")
+	store.SaveLog(entry)
+	server := NewServer(processor.NewStorageProcessor(store), 0, WithAPIKeys("secret"))
+	rec := httptest.NewRecorder()
+	server.routes["/api/logs/tags"](rec, httptest.NewRequest(http.MethodPatch, "/api/logs/tags",
+		strings.NewReader(`{"filter": {"service": "api"}, "tags": {"d": "2", "c": "2", "b": "2"}}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	result, err := store.QueryLogs(&models.QueryParams{Service: "api"})
+	if err != nil {
+		t.Fatalf("failed to query logs: %v", err)
+	}
+	logs := result["logs"].([]map[string]interface{})
+	if tags := logs[0]["tags"].(map[string]string); len(tags) != 2 || tags["b"] != "2" || tags["c"] != "2" {
+		t.Errorf("expected the existing tag to be updated and the first new one added, got %v", tags)
+	}
+}
@@ -0,0 +1,393 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+// streamBufferSize is how many published items a streaming connection queues
+// before it starts missing them
+const streamBufferSize = 256
+
+// streamFilter is the payload of a "filter" control message. Omitted fields
+// keep their current value; empty strings clear the filter.
+type streamFilter struct {
+	Service *string `json:"service"`
+	Level   *string `json:"level"`
+	Search  *string `json:"search"`
+}
+
+// streamQuery is the query of a streaming connection. It is shared between
+// the goroutine reading control messages and the loop sending updates.
+type streamQuery struct {
+	mu    sync.Mutex
+	query models.QueryParams
+}
+
+// newStreamQuery creates a stream query starting from a copy of query
+func newStreamQuery(query *models.QueryParams) *streamQuery {
+	return &streamQuery{query: *query}
+}
+
+// applyFilter updates the query with the fields set in filter
+func (q *streamQuery) applyFilter(filter streamFilter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if filter.Service != nil {
+		q.query.Service = *filter.Service
+	}
+	if filter.Level != nil {
+		q.query.Level = *filter.Level
+	}
+	if filter.Search != nil {
+		q.query.Search = *filter.Search
+	}
+}
+
+// snapshot returns a copy of the current query
+func (q *streamQuery) snapshot() *models.QueryParams {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	query := q.query
+	return &query
+}
+
+// readControlMessages reads messages from the client until the connection
+// closes, applying filter updates to the stream's query. It calls cancel
+// when the connection is gone, so that the stream stops writing to it.
+func readControlMessages(conn *websocket.Conn, live *streamQuery, cancel context.CancelFunc) {
+	defer cancel()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return // Connection closed or error
+		}
+
+		var message struct {
+			Type    string       `json:"type"`
+			Payload streamFilter `json:"payload"`
+		}
+		if err := json.Unmarshal(data, &message); err != nil {
+			log.Printf("Ignoring invalid control message: %v", err)
+			continue
+		}
+
+		switch message.Type {
+		case "filter":
+			live.applyFilter(message.Payload)
+			log.Printf("Updated stream filters: %+v", live.snapshot())
+		default:
+			log.Printf("Ignoring unknown control message type: %s", message.Type)
+		}
+	}
+}
+
+// eventRecordFunc returns the record sent to a stream for a published event,
+// or false if the event is of another kind or doesn't match the query
+type eventRecordFunc func(event processor.Event, query *models.QueryParams) (map[string]interface{}, bool)
+
+// streamLogs streams logs to a WebSocket connection until ctx is done
+func (s *Server) streamLogs(ctx context.Context, conn *websocket.Conn, query *models.QueryParams) {
+	s.stream(ctx, conn, query, "logs", s.processor.QueryLogs, logEventRecord)
+}
+
+// streamMetrics streams metrics to a WebSocket connection until ctx is done
+func (s *Server) streamMetrics(ctx context.Context, conn *websocket.Conn, query *models.QueryParams) {
+	s.stream(ctx, conn, query, "metrics", s.processor.QueryMetrics, metricEventRecord)
+}
+
+// streamTraces streams traces to a WebSocket connection until ctx is done
+func (s *Server) streamTraces(ctx context.Context, conn *websocket.Conn, query *models.QueryParams) {
+	s.stream(ctx, conn, query, "traces", s.processor.QueryTraces, traceEventRecord)
+}
+
+// stream sends the result of an initial query to a WebSocket connection and
+// then forwards the matching items published to the hub until the client
+// disconnects or ctx is done, as when the server stops. Messages have the
+// shape of the initial query result, with the records under kind.
+//
+// The control message reader runs until the connection is closed, which the
+// caller does once stream returns, so no goroutine outlives the connection.
+//
+// The time range has the same meaning as for one-shot queries: Since and
+// Until are inclusive and a zero Until is open-ended. The initial query thus
+// covers everything stored up to now, and each later message the items
+// processed since the previous one, i.e. the window (last message, now].
+// Items timestamped outside an explicit time range are not forwarded.
+func (s *Server) stream(ctx context.Context, conn *websocket.Conn, query *models.QueryParams, kind string,
+	backfill func(query *models.QueryParams) (map[string]interface{}, error), record eventRecordFunc) {
+	log.Printf("Starting %s streaming with query: %+v", kind, query)
+
+	// Subscribe before the initial query so that nothing stored while it
+	// runs is missed
+	events, unsubscribe := s.hub.Subscribe(streamBufferSize)
+	defer unsubscribe()
+
+	// The stream's context is also cancelled when the client disconnects
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	live := newStreamQuery(query)
+	go readControlMessages(conn, live, cancel)
+
+	// Initial query
+	start := time.Now()
+	result, err := backfill(live.snapshot())
+	s.observeQuery(kind, start)
+	if err == nil {
+		message := WSMessage{
+			Type:    kind,
+			Payload: result,
+		}
+		if err := conn.WriteJSON(message); err != nil {
+			log.Printf("Error sending initial %s: %v", kind, err)
+			return
+		}
+	} else {
+		log.Printf("Error in initial %s query: %v", kind, err)
+	}
+
+	// Send items as they are processed, batching those already queued
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			query := live.snapshot()
+			records := []map[string]interface{}{}
+			for _, event := range pendingEvents(event, events) {
+				if r, ok := record(event, query); ok {
+					records = append(records, r)
+				}
+			}
+			if len(records) == 0 {
+				continue
+			}
+
+			message := WSMessage{
+				Type:    kind,
+				Payload: map[string]interface{}{kind: records},
+			}
+			if err := conn.WriteJSON(message); err != nil {
+				log.Printf("Error sending %s: %v", kind, err)
+				return
+			}
+		}
+	}
+}
+
+// pendingEvents returns first followed by the events already queued on events
+func pendingEvents(first processor.Event, events <-chan processor.Event) []processor.Event {
+	batch := []processor.Event{first}
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+}
+
+// logEventRecord returns the record of a published log matching query, in
+// the shape returned by QueryLogs
+func logEventRecord(event processor.Event, query *models.QueryParams) (map[string]interface{}, bool) {
+	entry := event.Log
+	if entry == nil {
+		return nil, false
+	}
+
+	if query.Service != "" && entry.Service != query.Service {
+		return nil, false
+	}
+	if !storage.InTimeRange(entry.Timestamp, query) {
+		return nil, false
+	}
+	if query.Level != "" && string(entry.Level) != query.Level {
+		return nil, false
+	}
+	if query.MinLevel != "" && !entry.Level.AtLeast(models.LogLevel(query.MinLevel)) {
+		return nil, false
+	}
+	if query.TraceID != "" && entry.TraceID != query.TraceID {
+		return nil, false
+	}
+	if query.Search != "" && !storage.ContainsFold(query.Search, entry.Message, entry.Service) {
+		return nil, false
+	}
+	if !matchesTags(entry.Tags, query.Filters) || !hasTagKeys(entry.Tags, query) {
+		return nil, false
+	}
+
+	record := map[string]interface{}{
+		"id":        entry.ID,
+		"timestamp": entry.Timestamp.Format(time.RFC3339),
+		"service":   entry.Service,
+		"level":     entry.Level,
+		"message":   entry.Message,
+	}
+	if len(entry.Tags) > 0 {
+		record["tags"] = entry.Tags
+	}
+	for key, value := range map[string]string{
+		"trace_id": entry.TraceID,
+		"span_id":  entry.SpanID,
+		"env":      entry.Env,
+		"host":     entry.Host,
+		"source":   entry.Source,
+	} {
+		if value != "" {
+			record[key] = value
+		}
+	}
+	return record, true
+}
+
+// metricEventRecord returns the record of a published metric matching
+// query, in the shape returned by QueryMetrics
+func metricEventRecord(event processor.Event, query *models.QueryParams) (map[string]interface{}, bool) {
+	metric := event.Metric
+	if metric == nil {
+		return nil, false
+	}
+
+	if query.Service != "" && metric.Service != query.Service {
+		return nil, false
+	}
+	if !storage.InTimeRange(metric.Timestamp, query) {
+		return nil, false
+	}
+	if query.Name != "" && metric.Name != query.Name {
+		return nil, false
+	}
+	if query.Search != "" && !storage.ContainsFold(query.Search, metric.Name, metric.Service) {
+		return nil, false
+	}
+	if !matchesTags(metric.Tags, query.Filters) || !hasTagKeys(metric.Tags, query) {
+		return nil, false
+	}
+
+	record := map[string]interface{}{
+		"id":        metric.ID,
+		"timestamp": metric.Timestamp.Format(time.RFC3339),
+		"service":   metric.Service,
+		"name":      metric.Name,
+		"value":     metric.Value,
+		"type":      metric.Type,
+	}
+	if len(metric.Tags) > 0 {
+		record["tags"] = metric.Tags
+	}
+	return record, true
+}
+
+// traceEventRecord returns the record of a published trace matching query,
+// in the shape returned by QueryTraces. Root spans published on their own
+// are streamed as traces too; as their other spans are unknown, they only
+// match a contains_span filter on their own name and their duration and
+// status are their own, like traces saved span by span. A trace is ERROR if
+// any of its spans failed, whatever the status of its root.
+func traceEventRecord(event processor.Event, query *models.QueryParams) (map[string]interface{}, bool) {
+	var root *models.Span
+	var duration int64
+	var status models.SpanStatus
+	spanNames := map[string]bool{}
+
+	switch {
+	case event.Trace != nil && event.Trace.Root != nil:
+		root = event.Trace.Root
+		duration = event.Trace.Duration
+		status = event.Trace.Status
+		for _, span := range event.Trace.Spans {
+			spanNames[span.Name] = true
+			if span.Status == models.SpanStatusError {
+				status = models.SpanStatusError
+			}
+		}
+	case event.Span != nil && event.Span.ParentID == "":
+		root = event.Span
+	default:
+		return nil, false
+	}
+	spanNames[root.Name] = true
+	if duration == 0 {
+		duration = root.Duration
+	}
+	if status == "" {
+		status = root.Status
+	}
+
+	if query.Service != "" && root.Service != query.Service {
+		return nil, false
+	}
+	if !storage.InTimeRange(root.StartTime, query) || !storage.InDurationRange(duration, query) {
+		return nil, false
+	}
+	if query.TraceID != "" && root.TraceID != query.TraceID {
+		return nil, false
+	}
+	if query.Search != "" && !storage.ContainsFold(query.Search, root.Name, root.Service) {
+		return nil, false
+	}
+	if query.ContainsSpan != "" && !spanNames[query.ContainsSpan] {
+		return nil, false
+	}
+
+	record := map[string]interface{}{
+		"id":          root.TraceID,
+		"start_time":  root.StartTime.Format(time.RFC3339),
+		"service":     root.Service,
+		"name":        root.Name,
+		"duration_ms": duration,
+		"status":      status,
+	}
+	if len(root.Tags) > 0 {
+		record["tags"] = root.Tags
+	}
+	return record, true
+}
+
+// matchesTags reports whether tags has every tag in filters
+func matchesTags(tags, filters map[string]string) bool {
+	for key, value := range filters {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTagKeys reports whether tags contain every key of the query's HasTags
+// and none of its MissingTags, whatever their values. Traces aren't checked:
+// the storage queries of traces don't filter on tag presence either.
+func hasTagKeys(tags map[string]string, query *models.QueryParams) bool {
+	for _, key := range query.HasTags {
+		if _, ok := tags[key]; !ok {
+			return false
+		}
+	}
+	for _, key := range query.MissingTags {
+		if _, ok := tags[key]; ok {
+			return false
+		}
+	}
+	return true
+}
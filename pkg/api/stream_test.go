@@ -0,0 +1,414 @@
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+)
+
+// countingProcessor counts the log queries made through it
+type countingProcessor struct {
+	stubProcessor
+	queries int
+}
+
+func (p *countingProcessor) QueryLogs(query *models.QueryParams) (map[string]interface{}, error) {
+	p.mu.Lock()
+	p.queries++
+	p.mu.Unlock()
+	return p.stubProcessor.QueryLogs(query)
+}
+
+// dialStream opens a WebSocket connection to ts with the given query string
+func dialStream(t *testing.T, ts *httptest.Server, rawQuery string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"?"+rawQuery, nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readRecords reads the next stream message and returns its records of kind
+func readRecords(t *testing.T, conn *websocket.Conn, kind string) []interface{} {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message struct {
+		Type    string                   `json:"type"`
+		Payload map[string][]interface{} `json:"payload"`
+	}
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("failed to read stream message: %v", err)
+	}
+	if message.Type != kind {
+		t.Fatalf("expected a %s message, got %s", kind, message.Type)
+	}
+	return message.Payload[kind]
+}
+
+func TestStreamLogs_Published(t *testing.T) {
+	proc := &countingProcessor{}
+	server := NewServer(proc, 0)
+	ts := httptest.NewServer(server.wsLogsHandler())
+	defer ts.Close()
+
+	conn := dialStream(t, ts, "service=checkout")
+
+	// The initial query backfills the stream
+	if records := readRecords(t, conn, "logs"); len(records) != 0 {
+		t.Fatalf("expected an empty backfill, got %v", records)
+	}
+
+	// Logs ingested through the API are pushed without further queries
+	postLog(server, `{"message": "ignored", "service": "search"}`)
+	postLog(server, `{"message": "card declined", "service": "checkout", "level": "error"}`)
+
+	records := readRecords(t, conn, "logs")
+	if len(records) != 1 {
+		t.Fatalf("expected 1 matching log, got %v", records)
+	}
+	record := records[0].(map[string]interface{})
+	if record["message"] != "card declined" || record["level"] != "ERROR" || record["service"] != "checkout" {
+		t.Errorf("unexpected log record: %v", record)
+	}
+
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+	if proc.queries != 1 {
+		t.Errorf("expected only the initial query, got %d queries", proc.queries)
+	}
+}
+
+func TestStreamLogs_FilterControlMessage(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+	ts := httptest.NewServer(server.wsLogsHandler())
+	defer ts.Close()
+
+	conn := dialStream(t, ts, "service=api&search=timeout")
+	readRecords(t, conn, "logs")
+
+	// Change the service and clear the search, leaving the level unset
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "filter", "payload": {"service": "checkout", "search": ""}}`)); err != nil {
+		t.Fatalf("failed to send filter: %v", err)
+	}
+
+	// The filter applies asynchronously, so publish until a checkout log
+	// comes through; from then on api logs are filtered out
+	for i := 0; i < 50; i++ {
+		postLog(server, `{"message": "request timeout", "service": "api"}`)
+		postLog(server, `{"message": "order placed", "service": "checkout"}`)
+
+		records := readRecords(t, conn, "logs")
+		var services []string
+		for _, record := range records {
+			services = append(services, record.(map[string]interface{})["service"].(string))
+		}
+		if services[len(services)-1] != "checkout" {
+			continue // published before the filter was applied
+		}
+		for _, service := range services {
+			if service != "checkout" {
+				t.Errorf("expected only checkout logs after the filter update, got %v", services)
+			}
+		}
+		return
+	}
+	t.Fatal("expected checkout logs after the filter update")
+}
+
+func TestStreamTraces_WithHub(t *testing.T) {
+	hub := processor.NewHub()
+	server := NewServer(&tracesProcessor{}, 0, WithHub(hub))
+	ts := httptest.NewServer(server.wsTracesHandler())
+	defer ts.Close()
+
+	conn := dialStream(t, ts, "contains_span=charge")
+	readRecords(t, conn, "traces")
+
+	// Items published to a shared hub are streamed whatever their ingestion path
+	root := models.NewSpan("POST /checkout", "checkout", "trace-1")
+	child := models.NewSpan("charge", "payments", root.TraceID).SetParent(root.ID)
+	hub.Publish(processor.Event{Span: child})
+	hub.Publish(processor.Event{Trace: &models.Trace{ID: "other", Root: models.NewSpan("GET /search", "search", "other")}})
+	hub.Publish(processor.Event{Trace: &models.Trace{ID: root.TraceID, Root: root, Spans: []*models.Span{root, child}}})
+
+	records := readRecords(t, conn, "traces")
+	if len(records) != 1 {
+		t.Fatalf("expected only the trace containing the span, got %v", records)
+	}
+	record := records[0].(map[string]interface{})
+	if record["id"] != root.TraceID || record["name"] != "POST /checkout" {
+		t.Errorf("unexpected trace record: %v", record)
+	}
+}
+
+// tracesProcessor returns no traces
+type tracesProcessor struct {
+	stubProcessor
+}
+
+func (p *tracesProcessor) QueryTraces(query *models.QueryParams) (map[string]interface{}, error) {
+	return map[string]interface{}{"traces": []map[string]interface{}{}}, nil
+}
+
+func TestLogEventRecord(t *testing.T) {
+	entry := models.NewLogEntry("checkout", "Payment Timeout", models.LogLevelWarning)
+	entry.AddTag("region", "eu")
+	event := processor.Event{Log: entry}
+
+	tests := []struct {
+		query models.QueryParams
+		match bool
+	}{
+		{models.QueryParams{}, true},
+		{models.QueryParams{Service: "checkout", Search: "timeout"}, true},
+		{models.QueryParams{MinLevel: "INFO"}, true},
+		{models.QueryParams{MinLevel: "ERROR"}, false},
+		{models.QueryParams{Level: "INFO"}, false},
+		{models.QueryParams{Filters: map[string]string{"region": "eu"}}, true},
+		{models.QueryParams{Filters: map[string]string{"region": "us"}}, false},
+		{models.QueryParams{Search: "declined"}, false},
+	}
+
+	for _, tt := range tests {
+		record, ok := logEventRecord(event, &tt.query)
+		if ok != tt.match {
+			t.Errorf("%+v: expected match %v, got %v", tt.query, tt.match, ok)
+		}
+		if ok && record["message"] != "Payment Timeout" {
+			t.Errorf("unexpected record: %v", record)
+		}
+	}
+
+	if _, ok := logEventRecord(processor.Event{Metric: &models.Metric{}}, &models.QueryParams{}); ok {
+		t.Errorf("expected metric events not to produce log records")
+	}
+}
+
+func TestEventRecord_TimeRange(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := models.NewLogEntry("api", "hello", models.LogLevelInfo)
+	entry.Timestamp = at
+	metric := models.NewMetric("requests", 1, models.MetricTypeCounter, "api")
+	metric.Timestamp = at
+	root := models.NewSpan("GET /", "api", "trace-1")
+	root.StartTime = at
+
+	tests := []struct {
+		name  string
+		query models.QueryParams
+		match bool
+	}{
+		{"open-ended", models.QueryParams{Since: at.Add(-time.Hour)}, true},
+		{"unbounded", models.QueryParams{}, true},
+		{"bounds included", models.QueryParams{Since: at, Until: at}, true},
+		{"before since", models.QueryParams{Since: at.Add(time.Second)}, false},
+		{"after until", models.QueryParams{Since: at.Add(-time.Hour), Until: at.Add(-time.Second)}, false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := logEventRecord(processor.Event{Log: entry}, &tt.query); ok != tt.match {
+			t.Errorf("%s: expected log match %v, got %v", tt.name, tt.match, ok)
+		}
+		if _, ok := metricEventRecord(processor.Event{Metric: metric}, &tt.query); ok != tt.match {
+			t.Errorf("%s: expected metric match %v, got %v", tt.name, tt.match, ok)
+		}
+		if _, ok := traceEventRecord(processor.Event{Span: root}, &tt.query); ok != tt.match {
+			t.Errorf("%s: expected trace match %v, got %v", tt.name, tt.match, ok)
+		}
+	}
+}
+
+func TestEventRecord_TagPresence(t *testing.T) {
+	entry := models.NewLogEntry("api", "hello", models.LogLevelInfo)
+	entry.AddTag("region", "eu")
+	metric := models.NewMetric("requests", 1, models.MetricTypeCounter, "api")
+	metric.AddTag("region", "eu")
+
+	tests := []struct {
+		name  string
+		query models.QueryParams
+		match bool
+	}{
+		{"present", models.QueryParams{HasTags: []string{"region"}}, true},
+		{"not present", models.QueryParams{HasTags: []string{"region", "zone"}}, false},
+		{"absent", models.QueryParams{MissingTags: []string{"zone"}}, true},
+		{"not absent", models.QueryParams{MissingTags: []string{"region"}}, false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := logEventRecord(processor.Event{Log: entry}, &tt.query); ok != tt.match {
+			t.Errorf("%s: expected log match %v, got %v", tt.name, tt.match, ok)
+		}
+		if _, ok := metricEventRecord(processor.Event{Metric: metric}, &tt.query); ok != tt.match {
+			t.Errorf("%s: expected metric match %v, got %v", tt.name, tt.match, ok)
+		}
+	}
+}
+
+func TestStreamLogs_TagPresence(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+	ts := httptest.NewServer(server.wsLogsHandler())
+	defer ts.Close()
+
+	conn := dialStream(t, ts, "has_tag=region&missing_tag=canary")
+	readRecords(t, conn, "logs")
+
+	postLog(server, `{"message": "untagged", "service": "api"}`)
+	postLog(server, `{"message": "canary", "service": "api", "tags": {"region": "eu", "canary": "true"}}`)
+	postLog(server, `{"message": "tagged", "service": "api", "tags": {"region": "eu"}}`)
+
+	records := readRecords(t, conn, "logs")
+	if len(records) != 1 {
+		t.Fatalf("expected only the log with the region tag and no canary tag, got %v", records)
+	}
+	if record := records[0].(map[string]interface{}); record["message"] != "tagged" {
+		t.Errorf("unexpected log record: %v", record)
+	}
+}
+
+func TestStreamTraces_DurationRange(t *testing.T) {
+	hub := processor.NewHub()
+	server := NewServer(&tracesProcessor{}, 0, WithHub(hub))
+	ts := httptest.NewServer(server.wsTracesHandler())
+	defer ts.Close()
+
+	conn := dialStream(t, ts, "min_duration_ms=100&max_duration_ms=500")
+	readRecords(t, conn, "traces")
+
+	trace := func(id string, rootDuration, duration int64) *models.Trace {
+		root := models.NewSpan("GET /"+id, "api", id)
+		root.Duration = rootDuration
+		return &models.Trace{ID: id, Root: root, Spans: []*models.Span{root}, Duration: duration}
+	}
+	fastRoot := models.NewSpan("GET /fast", "api", "fast-root")
+	fastRoot.Duration = 20
+
+	// Traces are filtered on their total duration, not their root's
+	hub.Publish(processor.Event{Span: fastRoot})
+	hub.Publish(processor.Event{Trace: trace("fast", 50, 50)})
+	hub.Publish(processor.Event{Trace: trace("slow", 50, 900)})
+	hub.Publish(processor.Event{Trace: trace("match", 50, 300)})
+
+	records := readRecords(t, conn, "traces")
+	if len(records) != 1 {
+		t.Fatalf("expected only the trace within the duration range, got %v", records)
+	}
+	if record := records[0].(map[string]interface{}); record["id"] != "match" || record["duration_ms"] != float64(300) {
+		t.Errorf("unexpected trace record: %v", record)
+	}
+}
+
+func TestTraceEventRecord_Status(t *testing.T) {
+	root := models.NewSpan("GET /", "api", "trace-1")
+	root.Status = models.SpanStatusOK
+	child := models.NewSpan("charge", "billing", "trace-1")
+	child.ParentID = root.ID
+	child.Status = models.SpanStatusError
+
+	// A failed child fails the whole trace, whatever its root's status
+	record, ok := traceEventRecord(processor.Event{Trace: &models.Trace{ID: "trace-1", Root: root, Spans: []*models.Span{root, child}}}, &models.QueryParams{})
+	if !ok || record["status"] != models.SpanStatusError {
+		t.Errorf("expected an ERROR trace, got %v", record)
+	}
+
+	record, ok = traceEventRecord(processor.Event{Span: root}, &models.QueryParams{})
+	if !ok || record["status"] != models.SpanStatusOK {
+		t.Errorf("expected a root span alone to keep its status, got %v", record)
+	}
+}
+
+func TestStreamQuery_ApplyFilter(t *testing.T) {
+	live := newStreamQuery(&models.QueryParams{Service: "api", Level: "ERROR", Limit: 50})
+
+	level := "WARNING"
+	live.applyFilter(streamFilter{Level: &level})
+
+	query := live.snapshot()
+	if query.Service != "api" || query.Level != "WARNING" || query.Limit != 50 {
+		t.Errorf("expected only the level to change, got %+v", query)
+	}
+
+	// Snapshots are copies
+	query.Service = "changed"
+	if live.snapshot().Service != "api" {
+		t.Errorf("expected snapshot changes not to affect the stream query")
+	}
+}
+
+func TestStop_ClosesWebSocketsGracefully(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+	baseURL := startTestServer(t, server)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(baseURL, "http")+"/ws/logs", nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	readRecords(t, conn, "logs")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := server.Stop(ctx); err != nil {
+		t.Fatalf("expected no error stopping the server, got: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("expected a going away close frame, got: %v", err)
+	}
+
+	// The stream stops and its connection is unregistered
+	waitForConnections(t, server, map[string]int{"logs": 0, "metrics": 0, "traces": 0})
+}
+
+func TestStream_NoGoroutineLeak(t *testing.T) {
+	server := NewServer(&tracesProcessor{}, 0)
+	logs := httptest.NewServer(server.wsLogsHandler())
+	defer logs.Close()
+	traces := httptest.NewServer(server.wsTracesHandler())
+	defer traces.Close()
+
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < 50; i++ {
+		ts, kind := logs, "logs"
+		if i%2 == 1 {
+			ts, kind = traces, "traces"
+		}
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		readRecords(t, conn, kind)
+
+		// Half the clients say goodbye, the others just drop the connection
+		if i%4 < 2 {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		}
+		conn.Close()
+	}
+
+	// The streams, their control message readers and hub subscriptions all
+	// end once the clients are gone
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		counts := server.connectionCounts()
+		goroutines := runtime.NumGoroutine()
+		if goroutines <= baseline && counts["logs"] == 0 && counts["traces"] == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected goroutines back to %d and no connections, got %d goroutines and connections %v",
+				baseline, goroutines, counts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -2,13 +2,15 @@ package api
 
 import (
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
 )
 
 // SpanRequest represents the expected request format for submitting a span
@@ -24,6 +26,7 @@ type SpanRequest struct {
 	Status     string            `json:"status,omitempty"`      // Status of the operation
 	Tags       map[string]string `json:"tags,omitempty"`        // Additional metadata as key-value pairs
 	Logs       []SpanLogRequest  `json:"logs,omitempty"`        // Time-stamped logs attached to this span
+	Links      []models.SpanLink `json:"links,omitempty"`       // Related spans that are not the parent
 	Env        string            `json:"env,omitempty"`         // Environment (prod, dev, staging, etc.)
 	Host       string            `json:"host,omitempty"`        // Hostname where the span was generated
 	IsFinished bool              `json:"is_finished,omitempty"` // Whether the span has been completed
@@ -50,6 +53,14 @@ type SpanResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+// SpanBatchResponse represents the API response for batch span submission
+type SpanBatchResponse struct {
+	Status    string         `json:"status"`
+	Processed int            `json:"processed"`
+	Failed    int            `json:"failed"`
+	Results   []SpanResponse `json:"results"`
+}
+
 // TraceResponse represents the API response for trace submission
 type TraceResponse struct {
 	Status  string `json:"status"`
@@ -70,13 +81,16 @@ func (s *Server) tracesHandler() http.HandlerFunc {
 		traceCtx := ExtractTraceContext(r)
 
 		// Read the request body
-		body, err := io.ReadAll(io.LimitReader(r.Body, 1048576)) // 1MB limit
-		if err != nil {
-			log.Printf("Error reading request body: %v", err)
-			http.Error(w, "Error reading request", http.StatusBadRequest)
+		body, ok := readBody(w, r, s.maxBodySize)
+		if !ok {
+			return
+		}
+
+		// Collectors may send OTLP trace data as protobuf rather than JSON
+		if isOTLPProtobuf(r.Header.Get("Content-Type")) {
+			s.processProtobufTraces(w, body)
 			return
 		}
-		defer r.Body.Close()
 
 		// Parse the request
 		var traceReq TraceRequest
@@ -105,10 +119,12 @@ func (s *Server) tracesHandler() http.HandlerFunc {
 			return
 		}
 
+		traceCtx.ApplySampling(trace.Spans...)
+
 		// Save the trace
 		if err := s.processor.ProcessTrace(trace); err != nil {
 			log.Printf("Error saving trace: %v", err)
-			http.Error(w, "Error processing trace", http.StatusInternalServerError)
+			http.Error(w, "Error processing trace", processErrorStatus(err))
 			return
 		}
 
@@ -126,6 +142,42 @@ func (s *Server) tracesHandler() http.HandlerFunc {
 	}
 }
 
+// processProtobufTraces processes the traces of an OTLP TracesData protobuf
+// payload sent to the trace ingestion endpoint
+func (s *Server) processProtobufTraces(w http.ResponseWriter, body []byte) {
+	traces, err := decodeOTLPTraces(body, true)
+	if err != nil {
+		log.Printf("Error parsing protobuf traces: %v", err)
+		http.Error(w, "Invalid protobuf format", http.StatusBadRequest)
+		return
+	}
+	if len(traces) == 0 {
+		http.Error(w, "At least one span is required", http.StatusBadRequest)
+		return
+	}
+
+	response := TraceResponse{Status: "ok"}
+	for _, trace := range traces {
+		if err := s.processor.ProcessTrace(trace); err != nil {
+			log.Printf("Error saving trace %s: %v", trace.ID, err)
+			http.Error(w, "Error processing trace", processErrorStatus(err))
+			return
+		}
+		response.Spans += len(trace.Spans)
+	}
+
+	if len(traces) == 1 {
+		response.ID = traces[0].ID
+		response.Message = "Trace received and processed"
+	} else {
+		response.Message = fmt.Sprintf("%d traces received and processed", len(traces))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
 // spansHandler returns a handler for individual span submission
 func (s *Server) spansHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -138,13 +190,10 @@ func (s *Server) spansHandler() http.HandlerFunc {
 		traceCtx := ExtractTraceContext(r)
 
 		// Read the request body
-		body, err := io.ReadAll(io.LimitReader(r.Body, 1048576)) // 1MB limit
-		if err != nil {
-			log.Printf("Error reading request body: %v", err)
-			http.Error(w, "Error reading request", http.StatusBadRequest)
+		body, ok := readBody(w, r, s.maxBodySize)
+		if !ok {
 			return
 		}
-		defer r.Body.Close()
 
 		// Parse the request
 		var spanReq SpanRequest
@@ -183,10 +232,12 @@ func (s *Server) spansHandler() http.HandlerFunc {
 			return
 		}
 
+		traceCtx.ApplySampling(span)
+
 		// Save the span
 		if err := s.processor.ProcessSpan(span); err != nil {
 			log.Printf("Error saving span: %v", err)
-			http.Error(w, "Error processing span", http.StatusInternalServerError)
+			http.Error(w, "Error processing span", processErrorStatus(err))
 			return
 		}
 
@@ -204,6 +255,186 @@ func (s *Server) spansHandler() http.HandlerFunc {
 	}
 }
 
+// spansBatchHandler returns a handler for submitting many spans at once.
+// Spans may belong to different traces; the valid spans of each trace are
+// processed together as one trace, so that its duration and status cover
+// them all, and every span is reported with its own status.
+func (s *Server) spansBatchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Extract trace context from headers (to be used if not in the request body)
+		traceCtx := ExtractTraceContext(r)
+
+		// Read the request body
+		body, ok := readBody(w, r, s.maxBodySize)
+		if !ok {
+			return
+		}
+
+		// Parse the request
+		var spanReqs []SpanRequest
+		if err := json.Unmarshal(body, &spanReqs); err != nil {
+			log.Printf("Error parsing JSON: %v", err)
+			http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+
+		if len(spanReqs) == 0 {
+			http.Error(w, "At least one span is required", http.StatusBadRequest)
+			return
+		}
+
+		assignBatchTraceIDs(spanReqs, traceCtx)
+
+		response := SpanBatchResponse{
+			Status:  "ok",
+			Results: make([]SpanResponse, len(spanReqs)),
+		}
+
+		// Valid spans are grouped into a trace per trace ID, in the order
+		// the traces first appear, with the positions of their results
+		var traces []*models.Trace
+		byID := make(map[string]*models.Trace)
+		results := make(map[*models.Trace][]int)
+
+		for i, spanReq := range spanReqs {
+			result := &response.Results[i]
+			result.ID = spanReq.ID
+			result.TraceID = spanReq.TraceID
+
+			// Validate required fields
+			if spanReq.Name == "" {
+				result.Status, result.Message = "error", "Span name is required"
+				response.Failed++
+				continue
+			}
+			if spanReq.Service == "" {
+				result.Status, result.Message = "error", "Service name is required"
+				response.Failed++
+				continue
+			}
+
+			span, traceID, err := s.processSpanRequest(spanReq)
+			if err != nil {
+				result.Status, result.Message = "error", err.Error()
+				response.Failed++
+				continue
+			}
+
+			result.ID = span.ID
+			result.TraceID = traceID
+			traceCtx.ApplySampling(span)
+
+			trace, ok := byID[traceID]
+			if !ok {
+				trace = &models.Trace{ID: traceID}
+				byID[traceID] = trace
+				traces = append(traces, trace)
+			}
+			trace.Spans = append(trace.Spans, span)
+			results[trace] = append(results[trace], i)
+		}
+
+		// Each trace is processed whole so that its stored duration and
+		// status cover all its spans; the spans of a trace that fails all
+		// fail with it
+		for _, trace := range traces {
+			completeBatchTrace(trace)
+
+			status, message := "ok", ""
+			if err := s.processor.ProcessTrace(trace); err != nil {
+				log.Printf("Error saving trace %s: %v", trace.ID, err)
+				status, message = "error", "Error processing span"
+				if errors.Is(err, processor.ErrRateLimited) {
+					message = err.Error()
+				}
+			}
+
+			for _, i := range results[trace] {
+				response.Results[i].Status, response.Results[i].Message = status, message
+				if status == "ok" {
+					response.Processed++
+				} else {
+					response.Failed++
+				}
+			}
+		}
+
+		if response.Failed > 0 {
+			response.Status = "partial"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// completeBatchTrace sets the root, status and duration of a trace made of
+// the spans of a batch. A batch may hold only some spans of a trace; its root
+// is left unset unless the batch holds the span without a parent.
+func completeBatchTrace(trace *models.Trace) {
+	for _, span := range trace.Spans {
+		if span.ParentID == "" {
+			trace.Root = span
+			break
+		}
+	}
+	trace.ComputeStatus()
+	trace.ComputeDuration()
+}
+
+// assignBatchTraceIDs fills in missing trace IDs so that spans of the same
+// trace stay together. A span without a trace ID inherits it from its parent
+// when the parent is part of the batch; otherwise the header trace context is
+// used, and failing that a new trace ID is generated.
+func assignBatchTraceIDs(reqs []SpanRequest, traceCtx *TraceContext) {
+	byID := make(map[string]int, len(reqs))
+	for i, req := range reqs {
+		if req.ID != "" {
+			byID[req.ID] = i
+		}
+	}
+
+	visiting := make(map[int]bool)
+	var resolve func(i int) string
+	resolve = func(i int) string {
+		req := &reqs[i]
+		if req.TraceID != "" {
+			return req.TraceID
+		}
+
+		// Guard against parent cycles within the batch
+		if !visiting[i] {
+			visiting[i] = true
+			if parent, ok := byID[req.ParentID]; ok && req.ParentID != "" && parent != i {
+				req.TraceID = resolve(parent)
+			}
+		}
+
+		if req.TraceID == "" {
+			if traceCtx != nil && traceCtx.TraceID != "" {
+				req.TraceID = traceCtx.TraceID
+				if req.ParentID == "" && traceCtx.SpanID != "" {
+					req.ParentID = traceCtx.SpanID
+				}
+			} else {
+				req.TraceID = models.GenerateID()
+			}
+		}
+
+		return req.TraceID
+	}
+
+	for i := range reqs {
+		resolve(i)
+	}
+}
+
 // processTraceRequest converts a TraceRequest into a Trace model
 func (s *Server) processTraceRequest(req TraceRequest) (*models.Trace, error) {
 	// Generate trace ID if not provided
@@ -261,6 +492,11 @@ func (s *Server) processTraceRequest(req TraceRequest) (*models.Trace, error) {
 		trace.Status = rootSpans[0].Status
 	}
 
+	// A failed span fails the whole trace, and the trace lasts from its
+	// first span start to its last span end
+	trace.ComputeStatus()
+	trace.ComputeDuration()
+
 	return trace, nil
 }
 
@@ -316,6 +552,10 @@ func (s *Server) processSpanRequest(req SpanRequest) (*models.Span, string, erro
 		span.IsFinished = true
 	}
 
+	if err := s.checkSpanTimestamps(span); err != nil {
+		return nil, "", err
+	}
+
 	if req.Status != "" {
 		switch strings.ToUpper(req.Status) {
 		case "OK":
@@ -328,9 +568,7 @@ func (s *Server) processSpanRequest(req SpanRequest) (*models.Span, string, erro
 	}
 
 	if req.Tags != nil {
-		for k, v := range req.Tags {
-			span.AddTag(k, v)
-		}
+		span.AddTags(req.Tags)
 	}
 
 	if req.Logs != nil {
@@ -356,6 +594,13 @@ func (s *Server) processSpanRequest(req SpanRequest) (*models.Span, string, erro
 		}
 	}
 
+	for _, link := range req.Links {
+		if link.TraceID == "" || link.SpanID == "" {
+			return nil, "", errors.New("span links require trace_id and span_id")
+		}
+		span.AddLink(link.TraceID, link.SpanID, link.Attributes)
+	}
+
 	if req.Env != "" {
 		span.WithEnv(req.Env)
 	}
@@ -0,0 +1,526 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSpansBatchHandler_MixedTraces(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	// Two traces: trace-a is explicit, the second is inherited by the child
+	// from its in-batch parent. The last span is invalid.
+	body := `[
+		{"id": "a-root", "trace_id": "trace-a", "name": "GET /checkout", "service": "gateway", "duration_ms": 20},
+		{"id": "a-child", "trace_id": "trace-a", "parent_id": "a-root", "name": "charge", "service": "payments", "duration_ms": 5},
+		{"id": "b-child", "parent_id": "b-root", "name": "query", "service": "db", "duration_ms": 2},
+		{"id": "b-root", "name": "GET /users", "service": "gateway", "duration_ms": 10},
+		{"id": "broken", "trace_id": "trace-a", "service": "gateway"}
+	]`
+
+	req := httptest.NewRequest(http.MethodPost, "/spans/batch", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.spansBatchHandler()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp SpanBatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Status != "partial" || resp.Processed != 4 || resp.Failed != 1 {
+		t.Errorf("expected partial with 4 processed and 1 failed, got %s/%d/%d", resp.Status, resp.Processed, resp.Failed)
+	}
+	if len(resp.Results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(resp.Results))
+	}
+
+	// Per-span statuses line up with the request order
+	for i, status := range []string{"ok", "ok", "ok", "ok", "error"} {
+		if resp.Results[i].Status != status {
+			t.Errorf("result %d: expected status %s, got %s", i, status, resp.Results[i].Status)
+		}
+	}
+
+	// Spans of each trace share a trace ID
+	if resp.Results[0].TraceID != "trace-a" || resp.Results[1].TraceID != "trace-a" {
+		t.Errorf("expected trace-a spans to keep their trace ID, got %s and %s", resp.Results[0].TraceID, resp.Results[1].TraceID)
+	}
+	bTrace := resp.Results[3].TraceID
+	if bTrace == "" || bTrace == "trace-a" {
+		t.Errorf("expected a new trace ID for b-root, got %q", bTrace)
+	}
+	if resp.Results[2].TraceID != bTrace {
+		t.Errorf("expected b-child to inherit trace %s, got %s", bTrace, resp.Results[2].TraceID)
+	}
+
+	// Each trace is processed whole
+	if len(proc.traces) != 2 {
+		t.Fatalf("expected 2 processed traces, got %d", len(proc.traces))
+	}
+	for _, trace := range proc.traces {
+		if len(trace.Spans) != 2 || trace.Root == nil || trace.Root.ParentID != "" {
+			t.Errorf("expected trace %s with a root and 2 spans, got %+v", trace.ID, trace)
+		}
+		for _, span := range trace.Spans {
+			if span.TraceID != trace.ID {
+				t.Errorf("expected stored %s in trace %s, got %s", span.ID, trace.ID, span.TraceID)
+			}
+		}
+	}
+	if proc.traces[1].ID != bTrace {
+		t.Errorf("expected b-child in trace %s, got %s", bTrace, proc.traces[1].ID)
+	}
+}
+
+func TestSpansBatchHandler_MixedTraces_Duration(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	// The failed child ends after its root, 35ms after the trace started
+	start := time.Now().UTC().Add(-time.Minute)
+	body := fmt.Sprintf(`[
+		{"id": "a-root", "trace_id": "trace-a", "name": "GET /checkout", "service": "gateway", "start_time": %q, "duration_ms": 20, "status": "OK"},
+		{"id": "a-child", "trace_id": "trace-a", "parent_id": "a-root", "name": "charge", "service": "payments", "start_time": %q, "duration_ms": 25, "status": "ERROR"}
+	]`, start.Format(time.RFC3339Nano), start.Add(10*time.Millisecond).Format(time.RFC3339Nano))
+	rec := httptest.NewRecorder()
+	server.spansBatchHandler()(rec, httptest.NewRequest(http.MethodPost, "/spans/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	server.apiTracesHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/traces", nil))
+	var resp struct {
+		Traces []struct {
+			ID       string `json:"id"`
+			Duration int64  `json:"duration_ms"`
+			Status   string `json:"status"`
+		} `json:"traces"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Traces) != 1 || resp.Traces[0].Duration != 35 || resp.Traces[0].Status != "ERROR" {
+		t.Errorf("expected trace-a to last 35ms and fail, got %+v", resp.Traces)
+	}
+}
+
+func TestSpansBatchHandler_Validation(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+
+	for _, body := range []string{`[]`, `{"name": "not-an-array"}`} {
+		rec := httptest.NewRecorder()
+		server.spansBatchHandler()(rec, httptest.NewRequest(http.MethodPost, "/spans/batch", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestProcessTraceRequest_DurationAndStatus(t *testing.T) {
+	server := NewServer(&stubProcessor{}, 0)
+
+	trace, err := server.processTraceRequest(TraceRequest{
+		ID: "trace-1",
+		Spans: []SpanRequest{
+			{ID: "root", Name: "GET /checkout", Service: "gateway", StartTime: "2024-01-01T12:00:00Z", Duration: 100, Status: "OK"},
+			{ID: "charge", ParentID: "root", Name: "charge", Service: "payments", StartTime: "2024-01-01T12:00:00.050Z", Duration: 150, Status: "ERROR"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// The failed child fails the trace, which lasts until the child ends
+	if trace.Status != models.SpanStatusError {
+		t.Errorf("expected status ERROR, got %s", trace.Status)
+	}
+	if trace.Duration != 200 {
+		t.Errorf("expected a duration of 200ms, got %d", trace.Duration)
+	}
+}
+
+func TestTracesHandler_Protobuf(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	checkoutID := []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	searchID := []byte{0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c}
+	data := &tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "checkout"}}},
+			{Key: "deployment.environment", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "prod"}}},
+		}},
+		ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{
+			{
+				TraceId:           checkoutID,
+				SpanId:            []byte{0, 0, 0, 0, 0, 0, 0, 2},
+				ParentSpanId:      []byte{0, 0, 0, 0, 0, 0, 0, 1},
+				Name:              "charge",
+				StartTimeUnixNano: 1700000000010000000,
+				EndTimeUnixNano:   1700000000090000000,
+				Status:            &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "card declined"},
+			},
+			{
+				TraceId:           checkoutID,
+				SpanId:            []byte{0, 0, 0, 0, 0, 0, 0, 1},
+				Name:              "POST /checkout",
+				Kind:              tracepb.Span_SPAN_KIND_SERVER,
+				StartTimeUnixNano: 1700000000000000000,
+				EndTimeUnixNano:   1700000000120000000,
+			},
+			{
+				TraceId:           searchID,
+				SpanId:            []byte{0, 0, 0, 0, 0, 0, 0, 3},
+				Name:              "GET /search",
+				StartTimeUnixNano: 1700000000000000000,
+				EndTimeUnixNano:   1700000000005000000,
+			},
+		}}},
+	}}}
+	payload, err := proto.Marshal(data)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/traces", strings.NewReader(string(payload)))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+	server.tracesHandler()(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp TraceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "ok" || resp.Spans != 3 {
+		t.Errorf("expected 3 spans processed, got %+v", resp)
+	}
+
+	if len(proc.traces) != 2 {
+		t.Fatalf("expected 2 traces, got %d", len(proc.traces))
+	}
+	checkout := proc.traces[0]
+	if checkout.ID != "4bf92f3577b34da6a3ce929d0e0e4736" || len(checkout.Spans) != 2 {
+		t.Fatalf("expected the checkout trace with 2 spans, got %s with %d", checkout.ID, len(checkout.Spans))
+	}
+	if checkout.Root.Name != "POST /checkout" || checkout.Root.Duration != 120 || checkout.Root.Tags["span.kind"] != "server" {
+		t.Errorf("unexpected root span: %+v", checkout.Root)
+	}
+	charge := checkout.Spans[0]
+	if charge.ParentID != "0000000000000001" || charge.Status != models.SpanStatusError || charge.Tags["otel.status_description"] != "card declined" {
+		t.Errorf("unexpected child span: %+v", charge)
+	}
+	if charge.Service != "checkout" || charge.Env != "prod" {
+		t.Errorf("expected resource attributes to be applied, got service %s env %s", charge.Service, charge.Env)
+	}
+	if search := proc.traces[1]; search.ID != "0af7651916cd43dd8448eb211c80319c" || search.Root.Duration != 5 {
+		t.Errorf("unexpected search trace: %+v", search)
+	}
+
+	for _, body := range []string{"\xff\xff\xff", ""} {
+		req := httptest.NewRequest(http.MethodPost, "/traces", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		rec := httptest.NewRecorder()
+		server.tracesHandler()(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %q, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestSpansHandler_Links(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	body := `{"id": "consumer", "trace_id": "trace-c", "name": "process batch", "service": "worker",
+		"links": [
+			{"trace_id": "trace-a", "span_id": "producer-a", "attributes": {"messaging.message.id": "m-1"}},
+			{"trace_id": "trace-b", "span_id": "producer-b"}
+		]}`
+	rec := httptest.NewRecorder()
+	server.spansHandler()(rec, httptest.NewRequest(http.MethodPost, "/spans", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(proc.spans) != 1 {
+		t.Fatalf("expected 1 processed span, got %d", len(proc.spans))
+	}
+	links := proc.spans[0].Links
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %d", len(links))
+	}
+	if links[0].TraceID != "trace-a" || links[0].SpanID != "producer-a" || links[0].Attributes["messaging.message.id"] != "m-1" {
+		t.Errorf("unexpected first link: %+v", links[0])
+	}
+	if links[1].TraceID != "trace-b" || links[1].SpanID != "producer-b" {
+		t.Errorf("unexpected second link: %+v", links[1])
+	}
+
+	// Links must identify the span they point to
+	body = `{"name": "process batch", "service": "worker", "links": [{"trace_id": "trace-a"}]}`
+	rec = httptest.NewRecorder()
+	server.spansHandler()(rec, httptest.NewRequest(http.MethodPost, "/spans", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a link without span_id, got %d", rec.Code)
+	}
+	if len(proc.spans) != 1 {
+		t.Errorf("expected the invalid span not to be processed, got %d spans", len(proc.spans))
+	}
+}
+
+func TestSlowTracesHandler(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	// 100 api traces lasting 1ms to 100ms, and a slower trace of another service
+	for i := 1; i <= 100; i++ {
+		span := models.NewSpan("GET /users", "api", fmt.Sprintf("trace-%d", i))
+		span.Duration = int64(i)
+		if err := store.SaveSpan(span); err != nil {
+			t.Fatalf("failed to save span: %v", err)
+		}
+	}
+	slow := models.NewSpan("POST /charge", "billing", "trace-billing")
+	slow.Duration = 5000
+	if err := store.SaveSpan(slow); err != nil {
+		t.Fatalf("failed to save span: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.apiSlowTracesHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/traces/slow-percentile?service=api&percentile=95", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Percentile  float64 `json:"percentile"`
+		ThresholdMs int64   `json:"threshold_ms"`
+		Traces      []struct {
+			ID       string `json:"id"`
+			Duration int64  `json:"duration_ms"`
+		} `json:"traces"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Percentile != 95 || resp.ThresholdMs != 95 {
+		t.Errorf("expected the 95th percentile at 95ms, got %+v", resp)
+	}
+	var ids []string
+	for _, trace := range resp.Traces {
+		ids = append(ids, trace.ID)
+	}
+	if strings.Join(ids, ",") != "trace-100,trace-99,trace-98,trace-97,trace-96" {
+		t.Errorf("expected the 5 slowest api traces, slowest first, got %v", ids)
+	}
+
+	for _, percentile := range []string{"0", "100", "abc"} {
+		rec := httptest.NewRecorder()
+		server.apiSlowTracesHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/traces/slow-percentile?percentile="+percentile, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for percentile %s, got %d", percentile, rec.Code)
+		}
+	}
+}
+
+func TestTraceIssuesHandler(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+	baseURL := startTestServer(t, server)
+
+	// A request querying the items of each of 8 orders one at a time
+	root := models.NewSpan("GET /orders", "api", "trace-n1")
+	root.Duration = 400
+	spans := []*models.Span{root}
+	for i := 0; i < 8; i++ {
+		items := models.NewSpan("SELECT items", "api", root.TraceID).SetParent(root.ID)
+		items.Duration = 20
+		items.AddTag("db.statement", "SELECT * FROM items WHERE order_id = ?")
+		spans = append(spans, items)
+	}
+	if err := store.SaveTrace(&models.Trace{ID: root.TraceID, Root: root, Spans: spans}); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+
+	resp, err := http.Get(baseURL + "/api/traces/trace-n1/issues")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Spans  int                    `json:"spans"`
+		Issues []processor.TraceIssue `json:"issues"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.Spans != 9 || len(body.Issues) != 1 {
+		t.Fatalf("expected 1 issue in 9 spans, got %+v", body)
+	}
+	issue := body.Issues[0]
+	if issue.Type != processor.IssueNPlusOne || issue.ParentID != root.ID || issue.Count != 8 || issue.TotalDuration != 160 {
+		t.Errorf("expected an n+1 of 8 spans under the root, got %+v", issue)
+	}
+
+	for path, status := range map[string]int{
+		"/api/traces/trace-n1/issues?min_repeats=10": http.StatusOK,
+		"/api/traces/trace-n1/issues?min_repeats=1":  http.StatusBadRequest,
+		"/api/traces/missing/issues":                 http.StatusNotFound,
+		"/api/traces/trace-n1/unknown":               http.StatusNotFound,
+	} {
+		resp, err := http.Get(baseURL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected status %d, got %d", path, status, resp.StatusCode)
+		}
+	}
+}
+
+func TestTraceHandler(t *testing.T) {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	baseURL := startTestServer(t, NewServer(processor.NewStorageProcessor(store), 0))
+
+	root := models.NewSpan("GET /checkout", "gateway", "trace-detail")
+	root.Duration = 80
+	charge := models.NewSpan("charge", "payments", root.TraceID).SetParent(root.ID)
+	charge.StartTime = root.StartTime.Add(10 * time.Millisecond)
+	charge.Duration = 40
+	charge.AddTag("card", "visa")
+	charge.AddLog(map[string]string{"event": "authorized"})
+	for _, span := range []*models.Span{charge, root} {
+		if err := store.SaveSpan(span); err != nil {
+			t.Fatalf("failed to save span: %v", err)
+		}
+	}
+
+	resp, err := http.Get(baseURL + "/api/traces/trace-detail")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	var trace models.Trace
+	if err := json.NewDecoder(resp.Body).Decode(&trace); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if trace.ID != "trace-detail" || trace.Root == nil || trace.Root.ID != root.ID || len(trace.Spans) != 2 {
+		t.Fatalf("expected the trace rooted at %s with 2 spans, got %+v", root.ID, trace)
+	}
+	child := trace.Spans[1]
+	if child.ParentID != root.ID || child.Tags["card"] != "visa" || len(child.Logs) != 1 || child.Logs[0].Fields["event"] != "authorized" {
+		t.Errorf("expected the charge span with its tags and logs, got %+v", child)
+	}
+
+	resp, err = http.Get(baseURL + "/api/traces/missing")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown trace, got %d", resp.StatusCode)
+	}
+}
+
+func TestTraceHandler_SpanLimit(t *testing.T) {
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	server := NewServer(processor.NewStorageProcessor(store), 0)
+
+	root := models.NewSpan("GET /export", "api", "trace-big")
+	spans := []*models.Span{root}
+	for i := 0; i < maxAnalyzedTraceSpans; i++ {
+		span := models.NewSpan("SELECT rows", "db", root.TraceID).SetParent(root.ID)
+		span.ID = fmt.Sprintf("span-%d", i)
+		spans = append(spans, span)
+	}
+	if err := store.SaveSpans(spans); err != nil {
+		t.Fatalf("failed to save spans: %v", err)
+	}
+
+	for _, path := range []string{"/api/traces/trace-big", "/api/traces/trace-big/issues"} {
+		rec := httptest.NewRecorder()
+		server.apiTraceHandler()(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected status 422, got %d", path, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "/api/spans?trace_id=trace-big") {
+			t.Errorf("%s: expected the error to point to the spans endpoint, got %s", path, rec.Body.String())
+		}
+	}
+}
+
+func TestSpansHandler_UpstreamSampling(t *testing.T) {
+	proc := &stubProcessor{}
+	server := NewServer(proc, 0)
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	for _, flags := range []string{"00", "01"} {
+		req := httptest.NewRequest(http.MethodPost, "/spans", strings.NewReader(`{"name": "charge", "service": "payments"}`))
+		req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-"+flags)
+		rec := httptest.NewRecorder()
+		server.spansHandler()(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	if len(proc.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(proc.spans))
+	}
+	for i, priority := range []string{"0", "1"} {
+		if got := proc.spans[i].Tags[models.SamplingPriorityTag]; got != priority {
+			t.Errorf("span %d: expected sampling priority %s, got %q", i, priority, got)
+		}
+	}
+}
@@ -2,9 +2,13 @@ package cli
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
@@ -14,6 +18,9 @@ import (
 const (
 	// Default config file name
 	defaultConfigFile = ".pulse.yaml"
+
+	// Prefix of the environment variables that override config settings
+	envPrefix = "PULSE"
 )
 
 // Configuration settings
@@ -21,6 +28,7 @@ type Config struct {
 	ServerURL      string            `mapstructure:"server_url"`
 	DefaultService string            `mapstructure:"default_service"`
 	Tags           map[string]string `mapstructure:"tags"`
+	APIKey         string            `mapstructure:"api_key"`
 }
 
 // NewConfigCommand creates a new config command
@@ -28,13 +36,21 @@ func NewConfigCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "config",
 		Short: "Manage Pulse configuration",
-		Long:  `View and modify Pulse CLI configuration settings.`,
+		Long: `View and modify Pulse CLI configuration settings.
+
+Settings are read from ~/.pulse.yaml and can be overridden with PULSE_SERVER_URL,
+PULSE_DEFAULT_SERVICE, PULSE_TAGS (a comma separated key=value list) and
+PULSE_API_KEY. The api_key is sent with every request to servers started
+with --api-key.
+Command line flags take precedence over environment variables, which take
+precedence over the config file, which takes precedence over the defaults.`,
 	}
 
 	// Add subcommands
 	cmd.AddCommand(newConfigViewCommand())
 	cmd.AddCommand(newConfigSetCommand())
 	cmd.AddCommand(newConfigInitCommand())
+	cmd.AddCommand(newConfigValidateCommand())
 
 	return cmd
 }
@@ -54,6 +70,9 @@ func newConfigViewCommand() *cobra.Command {
 			fmt.Println("Current Configuration:")
 			fmt.Printf("Server URL: %s\n", cfg.ServerURL)
 			fmt.Printf("Default Service: %s\n", cfg.DefaultService)
+			if cfg.APIKey != "" {
+				fmt.Printf("API Key: %s\n", maskAPIKey(cfg.APIKey))
+			}
 
 			fmt.Println("\nDefault Tags:")
 			if len(cfg.Tags) == 0 {
@@ -91,44 +110,23 @@ func newConfigSetCommand() *cobra.Command {
   pulse config set default_service my-app
 
   # Set a default tag
-  pulse config set tags.environment production`,
+  pulse config set tags.environment production
+
+  # Authenticate to a server started with --api-key
+  pulse config set api_key my-secret-key`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key = args[0]
 			value = args[1]
 
-			// Load existing config
-			cfg, err := loadConfig()
+			configFile, err := setConfigValue(key, value)
 			if err != nil {
-				return fmt.Errorf("error loading config: %w", err)
-			}
-
-			// Set the value based on the key
-			if strings.HasPrefix(key, "tags.") {
-				tagName := strings.TrimPrefix(key, "tags.")
-				if cfg.Tags == nil {
-					cfg.Tags = make(map[string]string)
-				}
-				cfg.Tags[tagName] = value
-				viper.Set("tags", cfg.Tags)
-			} else {
-				viper.Set(key, value)
-			}
-
-			// Save the updated config
-			configFile := viper.ConfigFileUsed()
-			if configFile == "" {
-				home, err := homedir.Dir()
-				if err != nil {
-					return fmt.Errorf("error finding home directory: %w", err)
-				}
-				configFile = filepath.Join(home, defaultConfigFile)
+				return err
 			}
 
-			if err := viper.WriteConfigAs(configFile); err != nil {
-				return fmt.Errorf("error writing config file: %w", err)
+			if key == "api_key" {
+				value = maskAPIKey(value)
 			}
-
 			fmt.Printf("Set %s to %s in %s\n", key, value, configFile)
 			return nil
 		},
@@ -137,6 +135,48 @@ func newConfigSetCommand() *cobra.Command {
 	return cmd
 }
 
+// setConfigValue sets key to value in the config file and returns its path.
+// Only the file's own settings are written back, so values coming from the
+// environment or the defaults are never saved to it.
+func setConfigValue(key, value string) (string, error) {
+	// Load the config to find the file in use and check that it parses
+	if _, err := loadConfig(); err != nil {
+		return "", fmt.Errorf("error loading config: %w", err)
+	}
+
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", fmt.Errorf("error finding home directory: %w", err)
+		}
+		configFile = filepath.Join(home, defaultConfigFile)
+	}
+
+	file := viper.New()
+	file.SetConfigFile(configFile)
+	file.SetConfigType("yaml")
+	if _, err := os.Stat(configFile); err == nil {
+		if err := file.ReadInConfig(); err != nil {
+			return "", fmt.Errorf("error reading config file: %w", err)
+		}
+	}
+
+	// Set the value based on the key
+	if strings.HasPrefix(key, "tags.") {
+		tags := file.GetStringMapString("tags")
+		tags[strings.TrimPrefix(key, "tags.")] = value
+		file.Set("tags", tags)
+	} else {
+		file.Set(key, value)
+	}
+
+	if err := file.WriteConfigAs(configFile); err != nil {
+		return "", fmt.Errorf("error writing config file: %w", err)
+	}
+	return configFile, nil
+}
+
 // newConfigInitCommand creates a command to initialize a config file
 func newConfigInitCommand() *cobra.Command {
 	var force bool
@@ -182,6 +222,84 @@ func newConfigInitCommand() *cobra.Command {
 	return cmd
 }
 
+// newConfigValidateCommand creates a command to validate the config
+func newConfigValidateCommand() *cobra.Command {
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration",
+		Long: `Check that the config file parses, has no unknown keys and points at a
+well-formed, reachable Pulse server.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checks := validateConfig(&http.Client{Timeout: timeout})
+			if failed := printDoctorReport(cmd.OutOrStdout(), checks); failed > 0 {
+				return fmt.Errorf("config is invalid: %d check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "Timeout for the server reachability check")
+
+	return cmd
+}
+
+// validateConfig loads the config and checks its keys and server URL
+func validateConfig(client *http.Client) []doctorCheck {
+	check := doctorCheck{Name: "Config file"}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		check.Status = checkFail
+		check.Detail = err.Error()
+		return []doctorCheck{check}
+	}
+	withAPIKey(client, cfg.APIKey)
+
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		check.Status = checkPass
+		check.Detail = configFile
+	} else {
+		check.Status = checkWarn
+		check.Detail = "no config file found, using defaults (run 'pulse config init' to create one)"
+	}
+	checks := []doctorCheck{check}
+
+	for _, key := range unknownConfigKeys(viper.AllKeys()) {
+		checks = append(checks, doctorCheck{
+			Name:   "Unknown key",
+			Status: checkWarn,
+			Detail: fmt.Sprintf("%s is not a Pulse setting and will be ignored", key),
+		})
+	}
+
+	urlCheck := checkServerURL(cfg.ServerURL)
+	checks = append(checks, urlCheck)
+	if urlCheck.Status == checkFail {
+		return checks
+	}
+
+	healthCheck, _ := checkHealth(client, strings.TrimRight(cfg.ServerURL, "/"))
+	return append(checks, healthCheck)
+}
+
+// unknownConfigKeys returns the keys that don't correspond to a Config field,
+// in sorted order
+func unknownConfigKeys(keys []string) []string {
+	var unknown []string
+	for _, key := range keys {
+		switch {
+		case key == "server_url", key == "default_service", key == "tags", key == "api_key":
+		case strings.HasPrefix(key, "tags."):
+		default:
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
 // loadConfig loads the configuration from file or defaults
 func loadConfig() (*Config, error) {
 	// Find home directory
@@ -199,16 +317,109 @@ func loadConfig() (*Config, error) {
 	viper.SetDefault("server_url", "http://localhost:8080")
 	viper.SetDefault("default_service", "default")
 	viper.SetDefault("tags", map[string]string{})
-
-	// Read in config
-	viper.ReadInConfig()
-	// Errors are ignored on purpose - if no config file exists, we'll use defaults
+	viper.SetDefault("api_key", "")
+
+	// Environment variables such as PULSE_SERVER_URL override the config file
+	viper.SetEnvPrefix(envPrefix)
+	viper.AutomaticEnv()
+
+	// Read in config. A missing config file is fine since the defaults
+	// apply, but one that exists must parse.
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+	}
 
 	// Parse config
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
+	if err := viper.Unmarshal(&cfg, viper.DecodeHook(tagListHook)); err != nil {
 		return nil, fmt.Errorf("error parsing config: %w", err)
 	}
 
 	return &cfg, nil
 }
+
+// configuredServerURL returns serverURL, or the configured server_url if it
+// is empty because --server was not given
+func configuredServerURL(serverURL string) (string, error) {
+	if serverURL != "" {
+		return serverURL, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", fmt.Errorf("error loading config: %w", err)
+	}
+	return strings.TrimRight(cfg.ServerURL, "/"), nil
+}
+
+// newHTTPClient returns a client for the Pulse server that gives up after
+// timeout and sends the configured api_key, if any, with every request
+func newHTTPClient(timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if cfg, err := loadConfig(); err == nil {
+		withAPIKey(client, cfg.APIKey)
+	}
+	return client
+}
+
+// withAPIKey makes client send key in the X-API-Key header of its requests,
+// unless key is empty
+func withAPIKey(client *http.Client, key string) *http.Client {
+	if key == "" {
+		return client
+	}
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = apiKeyTransport{key: key, next: next}
+	return client
+}
+
+// apiKeyTransport adds an API key to the requests it sends through next
+type apiKeyTransport struct {
+	key  string
+	next http.RoundTripper
+}
+
+func (t apiKeyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("X-API-Key", t.key)
+	return t.next.RoundTrip(r)
+}
+
+// apiKeyHeader returns the headers carrying the configured api_key, for
+// requests such as WebSocket handshakes that aren't sent by newHTTPClient
+func apiKeyHeader() http.Header {
+	header := http.Header{}
+	if cfg, err := loadConfig(); err == nil && cfg.APIKey != "" {
+		header.Set("X-API-Key", cfg.APIKey)
+	}
+	return header
+}
+
+// maskAPIKey hides all but the last four characters of an API key
+func maskAPIKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
+}
+
+// tagListHook decodes tags given as a string, as they are when set through
+// PULSE_TAGS, from a comma separated key=value list
+func tagListHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(map[string]string{}) {
+		return data, nil
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(data.(string), ",") {
+		parts := strings.SplitN(strings.TrimSpace(tag), "=", 2)
+		if len(parts) == 2 {
+			tags[parts[0]] = parts[1]
+		}
+	}
+	return tags, nil
+}
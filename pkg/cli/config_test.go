@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+)
+
+// useHome points the home directory at a temporary directory holding config
+// as the config file, or no config file if config is empty
+func useHome(t *testing.T, config string) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	homedir.DisableCache = true
+	viper.Reset()
+	t.Cleanup(func() {
+		homedir.DisableCache = false
+		viper.Reset()
+	})
+
+	if config == "" {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(home, defaultConfigFile), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+func TestValidateConfig_Valid(t *testing.T) {
+	server := newDoctorServer(t, time.Now())
+	useHome(t, "server_url: "+server.URL+"\ndefault_service: api\ntags:\n  env: dev\n")
+
+	checks := validateConfig(server.Client())
+	for _, check := range checks {
+		if check.Status != checkPass {
+			t.Errorf("expected %s to pass, got %s: %s", check.Name, check.Status, check.Detail)
+		}
+	}
+	if statuses := checkStatuses(checks); statuses["Server health"] != checkPass {
+		t.Errorf("expected the server to be checked, got %v", statuses)
+	}
+}
+
+func TestValidateConfig_UnknownKeys(t *testing.T) {
+	server := newDoctorServer(t, time.Now())
+	useHome(t, "server_url: "+server.URL+"\nserver: http://typo:8080\ndefault_servce: api\n")
+
+	var unknown []string
+	for _, check := range validateConfig(server.Client()) {
+		if check.Name == "Unknown key" {
+			if check.Status != checkWarn {
+				t.Errorf("expected unknown keys to warn, got %s", check.Status)
+			}
+			unknown = append(unknown, check.Detail)
+		}
+	}
+	if len(unknown) != 2 {
+		t.Errorf("expected 2 unknown keys, got %v", unknown)
+	}
+}
+
+func TestValidateConfig_Malformed(t *testing.T) {
+	useHome(t, "server_url: [http://localhost:8080\n")
+
+	checks := validateConfig(http.DefaultClient)
+	if len(checks) != 1 || checks[0].Status != checkFail {
+		t.Fatalf("expected only a failed config file check, got %+v", checks)
+	}
+	if _, err := loadConfig(); err == nil {
+		t.Errorf("expected loadConfig to reject a malformed config file")
+	}
+}
+
+func TestValidateConfig_BadServerURL(t *testing.T) {
+	useHome(t, "server_url: localhost:8080\n")
+
+	statuses := checkStatuses(validateConfig(http.DefaultClient))
+	if statuses["Server URL"] != checkFail {
+		t.Errorf("expected a server URL without a scheme to fail, got %v", statuses)
+	}
+	if _, ok := statuses["Server health"]; ok {
+		t.Errorf("expected no reachability check for a malformed URL")
+	}
+}
+
+func TestValidateConfig_Defaults(t *testing.T) {
+	useHome(t, "")
+
+	statuses := checkStatuses(validateConfig(&http.Client{Timeout: time.Second}))
+	if statuses["Config file"] != checkWarn {
+		t.Errorf("expected a missing config file to warn, got %v", statuses)
+	}
+	if statuses["Server URL"] != checkPass {
+		t.Errorf("expected the default server URL to be well-formed, got %v", statuses)
+	}
+}
+
+func TestUnknownConfigKeys(t *testing.T) {
+	keys := []string{"tags.env", "server_url", "colour", "default_service", "api_key", "apikey"}
+	if unknown := unknownConfigKeys(keys); !reflect.DeepEqual(unknown, []string{"apikey", "colour"}) {
+		t.Errorf("expected apikey and colour, got %v", unknown)
+	}
+}
+
+func TestLoadConfig_EnvOverrides(t *testing.T) {
+	useHome(t, "server_url: http://file:8080\ndefault_service: file-service\ntags:\n  env: dev\n")
+
+	// Without overrides the file wins over the defaults
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.ServerURL != "http://file:8080" || cfg.DefaultService != "file-service" || cfg.Tags["env"] != "dev" {
+		t.Errorf("expected settings from the file, got %+v", cfg)
+	}
+
+	t.Setenv("PULSE_SERVER_URL", "http://ci:8080")
+	t.Setenv("PULSE_DEFAULT_SERVICE", "ci-service")
+	t.Setenv("PULSE_TAGS", "env=ci, team=infra,malformed")
+
+	cfg, err = loadConfig()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.ServerURL != "http://ci:8080" || cfg.DefaultService != "ci-service" {
+		t.Errorf("expected environment variables to win over the file, got %+v", cfg)
+	}
+	expected := map[string]string{"env": "ci", "team": "infra"}
+	if !reflect.DeepEqual(cfg.Tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, cfg.Tags)
+	}
+}
+
+func TestLoadConfig_EnvWithoutFile(t *testing.T) {
+	useHome(t, "")
+	t.Setenv("PULSE_SERVER_URL", "http://ci:8080")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.ServerURL != "http://ci:8080" || cfg.DefaultService != "default" {
+		t.Errorf("expected the environment over the defaults, got %+v", cfg)
+	}
+}
+
+func TestNewHTTPClient_APIKey(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("X-API-Key"))
+	}))
+	defer server.Close()
+
+	get := func() {
+		resp, err := newHTTPClient(time.Second).Get(server.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	useHome(t, "server_url: "+server.URL+"\napi_key: file-key\n")
+	get()
+	t.Setenv("PULSE_API_KEY", "env-key")
+	get()
+	useHome(t, "")
+	t.Setenv("PULSE_API_KEY", "")
+	get()
+
+	expected := []string{"file-key", "env-key", ""}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected API keys %v, got %v", expected, received)
+	}
+}
+
+func TestSetConfigValue_KeepsEnvOutOfFile(t *testing.T) {
+	useHome(t, "server_url: http://file:8080\ntags:\n  env: dev\n")
+	t.Setenv("PULSE_SERVER_URL", "http://ci:8080")
+	t.Setenv("PULSE_TAGS", "env=ci,team=infra")
+
+	configFile, err := setConfigValue("tags.region", "eu")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// Only the file's settings and the new tag are written, without the
+	// environment overrides or the defaults
+	file := viper.New()
+	file.SetConfigFile(configFile)
+	if err := file.ReadInConfig(); err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if url := file.GetString("server_url"); url != "http://file:8080" {
+		t.Errorf("expected server_url http://file:8080, got %q", url)
+	}
+	expected := map[string]string{"env": "dev", "region": "eu"}
+	if tags := file.GetStringMapString("tags"); !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, tags)
+	}
+	if file.IsSet("default_service") || file.IsSet("api_key") {
+		t.Errorf("expected no defaults in the file, got keys %v", file.AllKeys())
+	}
+}
+
+func TestSetConfigValue_CreatesFile(t *testing.T) {
+	useHome(t, "")
+	t.Setenv("PULSE_DEFAULT_SERVICE", "ci-service")
+
+	configFile, err := setConfigValue("server_url", "http://pulse:8080")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	file := viper.New()
+	file.SetConfigFile(configFile)
+	if err := file.ReadInConfig(); err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if keys := file.AllKeys(); !reflect.DeepEqual(keys, []string{"server_url"}) {
+		t.Errorf("expected only server_url in the file, got %v", keys)
+	}
+}
@@ -33,11 +33,15 @@ func NewDashboardCommand() *cobra.Command {
   # Start the dashboard without opening a browser
   pulse dashboard --no-open`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			serverURL, err := configuredServerURL(serverURL)
+			if err != nil {
+				return err
+			}
 			return runDashboard(serverURL, port, noOpen)
 		},
 	}
 
-	cmd.Flags().StringVar(&serverURL, "server", "http://localhost:8080", "Pulse server URL")
+	cmd.Flags().StringVar(&serverURL, "server", "", "Pulse server URL (defaults to the configured server_url)")
 	cmd.Flags().IntVar(&port, "port", 9000, "Port to serve the dashboard on")
 	cmd.Flags().BoolVar(&noOpen, "no-open", false, "Don't open browser automatically")
 
@@ -134,7 +138,7 @@ func proxyRequest(w http.ResponseWriter, r *http.Request, serverURL string) {
 	}
 
 	// Execute the request
-	client := &http.Client{}
+	client := newHTTPClient(0)
 	resp, err := client.Do(proxyReq)
 	if err != nil {
 		http.Error(w, "Error proxying request: "+err.Error(), http.StatusBadGateway)
@@ -0,0 +1,280 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// maxClockSkew is how far the server clock may drift from the local clock
+// before doctor warns about it
+const maxClockSkew = time.Minute
+
+// Doctor check outcomes
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+)
+
+// doctorCheck is the outcome of a single diagnostic check
+type doctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+// NewDoctorCommand creates a command that diagnoses common setup problems
+func NewDoctorCommand() *cobra.Command {
+	var (
+		serverURL  string
+		configFile string
+		timeout    time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose problems with the Pulse setup",
+		Long: `Check the CLI configuration file, connectivity to the Pulse server and
+the state of its storage, reporting each check as PASS, WARN or FAIL.`,
+		Example: `  # Check the configured server
+  pulse doctor
+
+  # Check a specific server
+  pulse doctor --server http://pulse-server:8080`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configFile == "" {
+				home, err := homedir.Dir()
+				if err != nil {
+					return fmt.Errorf("error finding home directory: %w", err)
+				}
+				configFile = filepath.Join(home, defaultConfigFile)
+			}
+
+			client := &http.Client{Timeout: timeout}
+			checks := runDoctor(client, serverURL, configFile)
+			if failed := printDoctorReport(cmd.OutOrStdout(), checks); failed > 0 {
+				return fmt.Errorf("%d check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&serverURL, "server", "", "Pulse server URL (defaults to the configured server_url)")
+	cmd.Flags().StringVar(&configFile, "config", "", "Config file to check (defaults to ~/"+defaultConfigFile+")")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "Timeout for each request to the server")
+
+	return cmd
+}
+
+// runDoctor runs every check in order. The server URL falls back to the one
+// in the config file, then to the default local server.
+func runDoctor(client *http.Client, serverURL, configFile string) []doctorCheck {
+	configCheck, cfg := checkConfigFile(configFile)
+	checks := []doctorCheck{configCheck}
+
+	if serverURL == "" && cfg != nil {
+		serverURL = cfg.ServerURL
+	}
+	if key := os.Getenv(envPrefix + "_API_KEY"); key != "" {
+		withAPIKey(client, key)
+	} else if cfg != nil {
+		withAPIKey(client, cfg.APIKey)
+	}
+	if serverURL == "" {
+		serverURL = "http://localhost:8080"
+	}
+
+	urlCheck := checkServerURL(serverURL)
+	checks = append(checks, urlCheck)
+	if urlCheck.Status == checkFail {
+		return checks
+	}
+	serverURL = strings.TrimRight(serverURL, "/")
+
+	healthCheck, serverTime := checkHealth(client, serverURL)
+	checks = append(checks, healthCheck)
+	if healthCheck.Status == checkFail {
+		return checks
+	}
+
+	checks = append(checks, checkClockSkew(serverTime, time.Now()))
+	checks = append(checks, checkStorage(client, serverURL))
+
+	return checks
+}
+
+// checkConfigFile reports whether the config file exists and parses. A
+// missing file is not an error since the CLI falls back to defaults.
+func checkConfigFile(path string) (doctorCheck, *Config) {
+	check := doctorCheck{Name: "Config file"}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		check.Status = checkWarn
+		check.Detail = fmt.Sprintf("%s not found, using defaults (run 'pulse config init' to create one)", path)
+		return check, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("%s could not be read: %v", path, err)
+		return check, nil
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("%s is invalid: %v", path, err)
+		return check, nil
+	}
+
+	check.Status = checkPass
+	check.Detail = path
+	if cfg.DefaultService == "" {
+		check.Status = checkWarn
+		check.Detail = fmt.Sprintf("%s has no default_service", path)
+	}
+	return check, &cfg
+}
+
+// checkServerURL catches server URLs that can't be requested
+func checkServerURL(serverURL string) doctorCheck {
+	check := doctorCheck{Name: "Server URL"}
+
+	u, err := url.Parse(serverURL)
+	switch {
+	case err != nil:
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("%q is not a valid URL: %v", serverURL, err)
+	case u.Scheme != "http" && u.Scheme != "https":
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("%q must start with http:// or https://", serverURL)
+	case u.Host == "":
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("%q has no host", serverURL)
+	case strings.Trim(u.Path, "/") != "":
+		check.Status = checkWarn
+		check.Detail = fmt.Sprintf("%q has a path; API paths are appended to it", serverURL)
+	default:
+		check.Status = checkPass
+		check.Detail = serverURL
+	}
+
+	return check
+}
+
+// checkHealth calls the server's /health endpoint and reports its version,
+// returning the server time for the clock check
+func checkHealth(client *http.Client, serverURL string) (doctorCheck, time.Time) {
+	check := doctorCheck{Name: "Server health"}
+
+	var health struct {
+		Status  string    `json:"status"`
+		Time    time.Time `json:"time"`
+		Version string    `json:"version"`
+	}
+	if err := getJSON(client, serverURL+"/health", &health); err != nil {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("%s is unreachable: %v", serverURL, err)
+		return check, time.Time{}
+	}
+
+	if health.Status != "ok" {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("server reported status %q", health.Status)
+		return check, health.Time
+	}
+
+	check.Status = checkPass
+	check.Detail = fmt.Sprintf("server version %s", health.Version)
+	return check, health.Time
+}
+
+// checkClockSkew warns when the server clock differs noticeably from the
+// local one, which skews relative time queries such as --since 1h
+func checkClockSkew(serverTime, now time.Time) doctorCheck {
+	check := doctorCheck{Name: "Clock"}
+
+	if serverTime.IsZero() {
+		check.Status = checkWarn
+		check.Detail = "server did not report its time"
+		return check
+	}
+
+	skew := serverTime.Sub(now)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxClockSkew {
+		check.Status = checkWarn
+		check.Detail = fmt.Sprintf("server clock is %s off the local clock", skew.Round(time.Second))
+		return check
+	}
+
+	check.Status = checkPass
+	check.Detail = "server and local clocks agree"
+	return check
+}
+
+// checkStorage reports the number of stored records from the stats endpoint
+func checkStorage(client *http.Client, serverURL string) doctorCheck {
+	check := doctorCheck{Name: "Storage"}
+
+	var stats models.Stats
+	if err := getJSON(client, serverURL+"/api/stats", &stats); err != nil {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("could not read storage stats: %v", err)
+		return check
+	}
+
+	check.Status = checkPass
+	check.Detail = fmt.Sprintf("%d logs, %d metrics, %d traces",
+		stats.Logs.Total, stats.Metrics.Total, stats.Traces.Total)
+	return check
+}
+
+// getJSON fetches url and decodes its JSON body into v
+func getJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// printDoctorReport writes one line per check and returns the number of failures
+func printDoctorReport(w io.Writer, checks []doctorCheck) int {
+	failed := 0
+	for _, check := range checks {
+		fmt.Fprintf(w, "[%s] %-14s %s\n", check.Status, check.Name, check.Detail)
+		if check.Status == checkFail {
+			failed++
+		}
+	}
+	return failed
+}
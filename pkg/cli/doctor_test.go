@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newDoctorServer(t *testing.T, serverTime time.Time) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "ok",
+			"time":    serverTime,
+			"version": "0.1.0",
+		})
+	})
+	mux.HandleFunc("/api/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"logs": {"total": 12}, "metrics": {"total": 3}, "traces": {"total": 4}}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), defaultConfigFile)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func checkStatuses(checks []doctorCheck) map[string]string {
+	statuses := make(map[string]string, len(checks))
+	for _, check := range checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestRunDoctor_Healthy(t *testing.T) {
+	server := newDoctorServer(t, time.Now().UTC())
+	config := writeConfig(t, "server_url: "+server.URL+"\ndefault_service: api\n")
+
+	// The server URL comes from the config file
+	checks := runDoctor(server.Client(), "", config)
+
+	expected := map[string]string{
+		"Config file":   checkPass,
+		"Server URL":    checkPass,
+		"Server health": checkPass,
+		"Clock":         checkPass,
+		"Storage":       checkPass,
+	}
+	statuses := checkStatuses(checks)
+	for name, status := range expected {
+		if statuses[name] != status {
+			t.Errorf("expected %s to be %s, got %s", name, status, statuses[name])
+		}
+	}
+
+	var out bytes.Buffer
+	if failed := printDoctorReport(&out, checks); failed != 0 {
+		t.Errorf("expected no failures, got %d", failed)
+	}
+	report := out.String()
+	if !strings.Contains(report, "server version 0.1.0") {
+		t.Errorf("expected the server version in the report, got:\n%s", report)
+	}
+	if !strings.Contains(report, "12 logs, 3 metrics, 4 traces") {
+		t.Errorf("expected storage stats in the report, got:\n%s", report)
+	}
+}
+
+func TestRunDoctor_Misconfigured(t *testing.T) {
+	server := newDoctorServer(t, time.Now().Add(-10*time.Minute))
+	missing := filepath.Join(t.TempDir(), defaultConfigFile)
+
+	checks := runDoctor(server.Client(), server.URL, missing)
+	statuses := checkStatuses(checks)
+
+	if statuses["Config file"] != checkWarn {
+		t.Errorf("expected a missing config file to warn, got %s", statuses["Config file"])
+	}
+	if statuses["Clock"] != checkWarn {
+		t.Errorf("expected clock skew to warn, got %s", statuses["Clock"])
+	}
+	if statuses["Server health"] != checkPass {
+		t.Errorf("expected the server to be healthy, got %s", statuses["Server health"])
+	}
+
+	// Warnings are not failures
+	if failed := printDoctorReport(&bytes.Buffer{}, checks); failed != 0 {
+		t.Errorf("expected no failures, got %d", failed)
+	}
+}
+
+func TestRunDoctor_Failures(t *testing.T) {
+	// An invalid config file and a server URL without a scheme
+	config := writeConfig(t, "server_url: [not yaml\n")
+	checks := runDoctor(http.DefaultClient, "localhost:8080", config)
+	statuses := checkStatuses(checks)
+	if statuses["Config file"] != checkFail || statuses["Server URL"] != checkFail {
+		t.Errorf("expected config and URL checks to fail, got %v", statuses)
+	}
+	if _, ok := statuses["Server health"]; ok {
+		t.Errorf("expected no health check for an invalid server URL")
+	}
+
+	// A server that is down
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	checks = runDoctor(http.DefaultClient, down.URL, filepath.Join(t.TempDir(), "missing.yaml"))
+	if status := checkStatuses(checks)["Server health"]; status != checkFail {
+		t.Errorf("expected unreachable server to fail, got %s", status)
+	}
+
+	// A server that is up but unhealthy
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+	checks = runDoctor(unhealthy.Client(), unhealthy.URL, filepath.Join(t.TempDir(), "missing.yaml"))
+
+	var out bytes.Buffer
+	if failed := printDoctorReport(&out, checks); failed != 1 {
+		t.Errorf("expected 1 failure, got %d", failed)
+	}
+	if !strings.Contains(out.String(), "[FAIL] Server health") || !strings.Contains(out.String(), "storage unavailable") {
+		t.Errorf("expected the health failure in the report, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxFollowSeenIDs bounds the IDs remembered to skip records sent again
+// after a reconnect
+const maxFollowSeenIDs = 10000
+
+// followMessage is a message of the server's WebSocket streams
+type followMessage struct {
+	Type    string                     `json:"type"`
+	Payload map[string]json.RawMessage `json:"payload"`
+}
+
+// followURL returns the WebSocket URL streaming dataType from serverURL. The
+// filters of the query command are translated into the stream's query
+// parameters: key=value filters on service, level, name and trace_id set
+// them directly, other keys filter on tags, and key:*value* filters search
+// the message.
+func followURL(serverURL, dataType, service string, limit int, since string, filter []string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("invalid server URL %q: expected http or https", serverURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ws/" + dataType
+
+	params := url.Values{}
+	if service != "" {
+		params.Set("service", service)
+	}
+	if limit > 0 {
+		params.Set("limit", fmt.Sprintf("%d", limit))
+	}
+	if since != "" {
+		params.Set("time_range", since)
+	}
+	if err := addFilterParams(params, filter); err != nil {
+		return "", err
+	}
+	u.RawQuery = params.Encode()
+	return u.String(), nil
+}
+
+// runFollow prints the records of dataType streamed by the server as they
+// arrive, until ctx is done. Dropped connections are reconnected with
+// backoff, skipping the records already printed; a server that can't be
+// reached on the first attempt is an error.
+func runFollow(ctx context.Context, dialer *websocket.Dialer, header http.Header, retry retryPolicy, out, errOut io.Writer, dataType, streamURL, format string) error {
+	seen := make(map[string]bool)
+	wait := retry.backoff
+	connected := false
+
+	// CSV output has a single header row, whatever the reconnects
+	if format == "csv" {
+		writer := csv.NewWriter(out)
+		writer.Write(recordFields(dataType))
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		err := followOnce(ctx, dialer, header, out, dataType, streamURL, format, seen, func() {
+			connected = true
+			wait = retry.backoff
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !connected {
+			return fmt.Errorf("error following %s: %w", dataType, err)
+		}
+
+		fmt.Fprintf(errOut, "Connection lost (%v), reconnecting in %s\n", err, wait)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+		wait *= 2
+		if wait > maxRetryBackoff {
+			wait = maxRetryBackoff
+		}
+	}
+}
+
+// followOnce connects to the stream and prints its records until the
+// connection drops or ctx is done. onConnect is called once connected.
+func followOnce(ctx context.Context, dialer *websocket.Dialer, header http.Header, out io.Writer, dataType, streamURL, format string, seen map[string]bool, onConnect func()) error {
+	conn, _, err := dialer.DialContext(ctx, streamURL, header)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	onConnect()
+
+	// Unblock the read below when the command is interrupted
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	first := true
+	for {
+		var message followMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return err
+		}
+		if message.Type != dataType {
+			continue
+		}
+
+		var records []map[string]interface{}
+		if raw, ok := message.Payload[dataType]; ok {
+			if err := json.Unmarshal(raw, &records); err != nil {
+				return fmt.Errorf("error parsing %s: %w", dataType, err)
+			}
+		}
+
+		// The first message is the recent history, newest first
+		if first {
+			sort.SliceStable(records, func(i, j int) bool {
+				return recordTimestamp(records[i]) < recordTimestamp(records[j])
+			})
+			first = false
+		}
+
+		if err := writeRecords(out, unseenRecords(records, seen), dataType, format); err != nil {
+			return err
+		}
+	}
+}
+
+// unseenRecords returns the records whose ID is not in seen and adds them
+func unseenRecords(records []map[string]interface{}, seen map[string]bool) []map[string]interface{} {
+	var unseen []map[string]interface{}
+	for _, record := range records {
+		id, _ := record["id"].(string)
+		if id != "" {
+			if seen[id] {
+				continue
+			}
+			if len(seen) >= maxFollowSeenIDs {
+				for key := range seen {
+					delete(seen, key)
+				}
+			}
+			seen[id] = true
+		}
+		unseen = append(unseen, record)
+	}
+	return unseen
+}
+
+// recordTimestamp returns the timestamp, or start time, of a record
+func recordTimestamp(record map[string]interface{}) string {
+	if timestamp, ok := record["timestamp"].(string); ok {
+		return timestamp
+	}
+	startTime, _ := record["start_time"].(string)
+	return startTime
+}
+
+// writeRecords writes records to out in format: one JSON object, CSV row or
+// line of text per record, or a table per batch
+func writeRecords(out io.Writer, records []map[string]interface{}, dataType, format string) error {
+	if len(records) == 0 {
+		return nil
+	}
+	switch format {
+	case "json", "jsonl":
+		encoder := json.NewEncoder(out)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+	case "csv":
+		fields := recordFields(dataType)
+		writer := csv.NewWriter(out)
+		for _, record := range records {
+			writer.Write(recordRow(record, fields))
+		}
+		writer.Flush()
+		return writer.Error()
+	case "text":
+		for _, record := range records {
+			if _, err := fmt.Fprintln(out, formatItem(record, dataType)); err != nil {
+				return err
+			}
+		}
+	case "table":
+		writeTable(out, records, dataType)
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+	return nil
+}
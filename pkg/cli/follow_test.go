@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestFollowURL(t *testing.T) {
+	streamURL, err := followURL("https://pulse.example.com/", "logs", "checkout", 50, "30m",
+		[]string{"level=ERROR", "message:*timeout*", "region=eu"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	if u.Scheme != "wss" || u.Host != "pulse.example.com" || u.Path != "/ws/logs" {
+		t.Errorf("unexpected stream URL: %s", streamURL)
+	}
+	expected := url.Values{
+		"service":       {"checkout"},
+		"limit":         {"50"},
+		"time_range":    {"30m"},
+		"level":         {"ERROR"},
+		"search":        {"timeout"},
+		"filter.region": {"eu"},
+	}
+	if got := u.Query(); got.Encode() != expected.Encode() {
+		t.Errorf("expected params %v, got %v", expected, got)
+	}
+
+	for _, invalid := range []struct{ server, filter string }{
+		{"ftp://pulse", "level=ERROR"},
+		{"http://pulse", "level"},
+	} {
+		if _, err := followURL(invalid.server, "logs", "", 0, "", []string{invalid.filter}); err == nil {
+			t.Errorf("expected error for %s with filter %q", invalid.server, invalid.filter)
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for a writer and a concurrent reader
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunFollow_Reconnects(t *testing.T) {
+	logs := func(ids ...string) map[string]interface{} {
+		var records []map[string]interface{}
+		for _, id := range ids {
+			records = append(records, map[string]interface{}{"id": id, "timestamp": "2024-01-01T00:00:0" + id + "Z"})
+		}
+		return map[string]interface{}{"type": "logs", "payload": map[string]interface{}{"logs": records}}
+	}
+
+	// The first connection sends the history newest first and a live log,
+	// then drops; the second sends the history again with a new log
+	connections := [][]map[string]interface{}{
+		{logs("2", "1"), logs("3")},
+		{logs("4", "3", "2")},
+	}
+	upgrader := websocket.Upgrader{}
+	served := make(chan struct{}, len(connections))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws/logs" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if len(connections) == 0 {
+			// Hold the last connection open until the client leaves
+			served <- struct{}{}
+			conn.ReadMessage()
+			return
+		}
+		messages := connections[0]
+		connections = connections[1:]
+		for _, message := range messages {
+			conn.WriteJSON(message)
+		}
+	}))
+	defer server.Close()
+
+	streamURL, err := followURL(server.URL, "logs", "", 0, "", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out, errOut := &syncBuffer{}, &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- runFollow(ctx, websocket.DefaultDialer, nil, retryPolicy{backoff: time.Millisecond}, out, errOut, "logs", streamURL, "json")
+	}()
+
+	// Wait for a third connection, after the second one dropped as well
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the client to reconnect")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("expected no error once interrupted, got: %v", err)
+	}
+
+	var ids []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("failed to decode %q: %v", line, err)
+		}
+		ids = append(ids, record["id"].(string))
+	}
+	if strings.Join(ids, ",") != "1,2,3,4" {
+		t.Errorf("expected each log once, oldest first, got %v", ids)
+	}
+	if !strings.Contains(errOut.String(), "reconnecting") {
+		t.Errorf("expected reconnects to be reported, got %q", errOut.String())
+	}
+}
+
+func TestRunFollow_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	streamURL, _ := followURL(server.URL, "logs", "", 0, "", nil)
+	server.Close()
+
+	err := runFollow(context.Background(), websocket.DefaultDialer, nil, retryPolicy{}, &bytes.Buffer{}, &bytes.Buffer{}, "logs", streamURL, "text")
+	if err == nil || !strings.Contains(err.Error(), "error following logs") {
+		t.Errorf("expected an error for an unreachable server, got: %v", err)
+	}
+}
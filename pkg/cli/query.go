@@ -2,14 +2,23 @@ package cli
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +36,10 @@ func NewQueryCommand() *cobra.Command {
 		filter     []string
 		orderBy    string
 		descending bool
+		timeout    time.Duration
+		retries    int
+		backoff    time.Duration
+		follow     bool
 	)
 
 	cmd := &cobra.Command{
@@ -44,7 +57,16 @@ Supports various filters and output formats.`,
   pulse query traces --service user-service --since 1h
 
   # Query with custom filters
-  pulse query logs --filter "level=ERROR" --filter "message:*timeout*"`,
+  pulse query logs --filter "level=ERROR" --filter "message:*timeout*"
+
+  # Pipe logs into jq, one compact JSON object per line
+  pulse query --type logs --format jsonl | jq .message
+
+  # Export metrics as CSV
+  pulse query --type metrics --format csv > metrics.csv
+
+  # Follow new error logs as they arrive, like tail -f
+  pulse query --type logs --filter "level=ERROR" --follow`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Validate data type
 			dataType = strings.ToLower(dataType)
@@ -54,158 +76,344 @@ Supports various filters and output formats.`,
 
 			// Validate format
 			format = strings.ToLower(format)
-			if format != "table" && format != "json" && format != "text" {
-				return fmt.Errorf("invalid format: %s. Must be one of: table, json, text", format)
+			switch format {
+			case "table", "json", "jsonl", "csv", "text":
+			default:
+				return fmt.Errorf("invalid format: %s. Must be one of: table, json, jsonl, csv, text", format)
+			}
+
+			if retries < 0 {
+				return fmt.Errorf("invalid retries: %d. Must not be negative", retries)
+			}
+
+			serverURL, err := configuredServerURL(serverURL)
+			if err != nil {
+				return err
 			}
 
-			return runQuery(dataType, serverURL, service, limit, format, since, until, filter, orderBy, descending)
+			retry := retryPolicy{retries: retries, backoff: backoff}
+			if follow {
+				streamURL, err := followURL(serverURL, dataType, service, limit, since, filter)
+				if err != nil {
+					return err
+				}
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+				defer stop()
+				dialer := &websocket.Dialer{HandshakeTimeout: timeout}
+				return runFollow(ctx, dialer, apiKeyHeader(), retry, cmd.OutOrStdout(), cmd.ErrOrStderr(), dataType, streamURL, format)
+			}
+
+			client := newHTTPClient(timeout)
+			return runQuery(client, retry, dataType, serverURL, service, limit, format, since, until, filter, orderBy, descending)
 		},
 	}
 
-	cmd.Flags().StringVar(&serverURL, "server", "http://localhost:8080", "Pulse server URL")
+	cmd.Flags().StringVar(&serverURL, "server", "", "Pulse server URL (defaults to the configured server_url)")
 	cmd.Flags().StringVar(&dataType, "type", "logs", "Data type to query: logs, metrics, or traces")
 	cmd.Flags().StringVar(&service, "service", "", "Filter by service name")
 	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum number of results to return")
-	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, json, or text")
-	cmd.Flags().StringVar(&since, "since", "1h", "Show data since this time (e.g. 30m, 2h, 1d)")
-	cmd.Flags().StringVar(&until, "until", "", "Show data until this time (e.g. 10m, 1h)")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, json, jsonl, csv, or text")
+	cmd.Flags().StringVar(&since, "since", "1h", "Show data since this long ago or this RFC 3339 time (e.g. 30m, 2h, 7d)")
+	cmd.Flags().StringVar(&until, "until", "", "Show data until this long ago or this RFC 3339 time (e.g. 10m, 1h)")
 	cmd.Flags().StringArrayVar(&filter, "filter", []string{}, "Filter expressions (format: key=value or key:*value*)")
 	cmd.Flags().StringVar(&orderBy, "order-by", "timestamp", "Field to order results by")
 	cmd.Flags().BoolVar(&descending, "desc", true, "Order results in descending order")
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultRequestTimeout, "Give up on the server after this long (0 waits forever)")
+	cmd.Flags().IntVar(&retries, "retries", 2, "Retry the query this many times on connection errors and 5xx responses")
+	cmd.Flags().DurationVar(&backoff, "retry-backoff", 500*time.Millisecond, "Wait before the first retry, doubling for each further retry")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Keep printing new data as it arrives until interrupted, reconnecting when the connection drops")
 
 	return cmd
 }
 
-func runQuery(dataType, serverURL, service string, limit int, format, since, until string, filter []string, orderBy string, descending bool) error {
-	// Build query URL
+// defaultRequestTimeout bounds how long query and stream wait for the server
+const defaultRequestTimeout = 30 * time.Second
+
+// requestError wraps the error of a request to the server made with client,
+// explaining timeouts
+func requestError(action string, client *http.Client, err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%s: the server did not respond within %s (see --timeout): %w", action, client.Timeout, err)
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
+
+// maxRetryBackoff caps the wait between two retries
+const maxRetryBackoff = 10 * time.Second
+
+// retryPolicy controls how often and how fast transient failures are retried
+type retryPolicy struct {
+	retries int           // Retries after the first attempt
+	backoff time.Duration // Wait before the first retry, doubled for each further one
+}
+
+// getWithRetry GETs url, retrying connection errors and 5xx responses
+// according to retry. Other responses are returned as is; after the last
+// attempt fails the error of that attempt is returned.
+func getWithRetry(client *http.Client, retry retryPolicy, url string) (*http.Response, error) {
+	attempts := retry.retries + 1
+	wait := retry.backoff
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(wait)
+			wait *= 2
+			if wait > maxRetryBackoff {
+				wait = maxRetryBackoff
+			}
+		}
+
+		resp, err := client.Get(url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode < http.StatusInternalServerError {
+			return resp, nil
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		lastErr = fmt.Errorf("server error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if attempts > 1 {
+		return nil, fmt.Errorf("giving up after %d attempts: %w", attempts, lastErr)
+	}
+	return nil, lastErr
+}
+
+func runQuery(client *http.Client, retry retryPolicy, dataType, serverURL, service string, limit int, format, since, until string, filter []string, orderBy string, descending bool) error {
+	queryURL, err := buildQueryURL(serverURL, dataType, service, limit, since, until, filter, orderBy, descending)
+	if err != nil {
+		return err
+	}
+
+	// Execute HTTP request
+	resp, err := getWithRetry(client, retry, queryURL)
+	if err != nil {
+		return requestError("error querying data", client, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server error (status %d): %s", resp.StatusCode, body)
+	}
+
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return requestError("error reading response", client, err)
+	}
+
+	return writeQueryResult(os.Stdout, body, dataType, format)
+}
+
+// buildQueryURL returns the URL of the /api endpoint of dataType, with the
+// query parameters the server parses. A relative since is sent as the
+// time_range and a relative until as the RFC 3339 time it stands for, while
+// RFC 3339 times are sent as they are.
+func buildQueryURL(serverURL, dataType, service string, limit int, since, until string, filter []string, orderBy string, descending bool) (string, error) {
 	params := url.Values{}
 	if service != "" {
 		params.Add("service", service)
 	}
 	params.Add("limit", fmt.Sprintf("%d", limit))
 	if since != "" {
-		params.Add("since", since)
+		if _, err := time.Parse(time.RFC3339, since); err == nil {
+			params.Add("since", since)
+		} else if _, err := parseRelativeDuration(since); err == nil {
+			params.Add("time_range", since)
+		} else {
+			return "", fmt.Errorf("invalid --since %q: expected a duration such as 30m, 2h or 7d, or an RFC 3339 time", since)
+		}
 	}
 	if until != "" {
-		params.Add("until", until)
+		if _, err := time.Parse(time.RFC3339, until); err == nil {
+			params.Add("until", until)
+		} else if ago, err := parseRelativeDuration(until); err == nil {
+			params.Add("until", time.Now().UTC().Add(-ago).Format(time.RFC3339))
+		} else {
+			return "", fmt.Errorf("invalid --until %q: expected a duration such as 10m or 1h, or an RFC 3339 time", until)
+		}
 	}
 	if orderBy != "" {
 		params.Add("order_by", orderBy)
 	}
-	if descending {
-		params.Add("order", "desc")
-	} else {
-		params.Add("order", "asc")
-	}
+	params.Add("order_desc", strconv.FormatBool(descending))
 
-	// Add filters
-	for _, f := range filter {
-		params.Add("filter", f)
+	if err := addFilterParams(params, filter); err != nil {
+		return "", err
 	}
 
-	// Construct URL
-	queryURL := fmt.Sprintf("%s/api/%s?%s", serverURL, dataType, params.Encode())
+	return fmt.Sprintf("%s/api/%s?%s", serverURL, dataType, params.Encode()), nil
+}
 
-	// Execute HTTP request
-	resp, err := http.Get(queryURL)
+// parseRelativeDuration parses a positive duration back from now such as
+// 30m, 2h or 7d, as the server parses time_range
+func parseRelativeDuration(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if strings.HasSuffix(s, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(s, "d"))
+		d = time.Duration(days) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
 	if err != nil {
-		return fmt.Errorf("error querying data: %w", err)
+		return 0, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server error (status %d): %s", resp.StatusCode, body)
+	if d <= 0 {
+		return 0, fmt.Errorf("duration %q is not positive", s)
 	}
+	return d, nil
+}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("error reading response: %w", err)
+// addFilterParams translates --filter expressions into query parameters:
+// key:*value* searches for value, key=value sets the dedicated parameter of
+// the fields the server filters on directly and filter.key otherwise
+func addFilterParams(params url.Values, filter []string) error {
+	for _, f := range filter {
+		if key, value, ok := strings.Cut(f, ":"); ok && !strings.Contains(key, "=") {
+			params.Set("search", strings.Trim(value, "*"))
+			continue
+		}
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid filter %q: expected key=value or key:*value*", f)
+		}
+		switch key {
+		case "level", "name", "trace_id", "service":
+			params.Set(key, value)
+		default:
+			params.Set("filter."+key, value)
+		}
 	}
+	return nil
+}
 
-	// Process based on format
-	switch format {
-	case "json":
+// writeQueryResult writes the records of a query response to out in format
+func writeQueryResult(out io.Writer, body []byte, dataType, format string) error {
+	if format == "json" {
 		// Pretty print JSON
 		var prettyJSON bytes.Buffer
 		if err := json.Indent(&prettyJSON, body, "", "  "); err != nil {
-			fmt.Println(string(body))
+			fmt.Fprintln(out, string(body))
 		} else {
-			fmt.Println(prettyJSON.String())
+			fmt.Fprintln(out, prettyJSON.String())
 		}
+		return nil
+	}
 
-	case "text":
-		// Print as text
-		var data []map[string]interface{}
-		if err := json.Unmarshal(body, &data); err != nil {
-			return fmt.Errorf("error parsing response: %w", err)
+	data, err := decodeRecords(body, dataType)
+	if err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+
+	switch format {
+	case "jsonl":
+		// One compact object per line
+		encoder := json.NewEncoder(out)
+		encoder.SetEscapeHTML(false)
+		for _, item := range data {
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
 		}
 
+	case "csv":
+		// A header row of field names, then a row per record
+		fields := recordFields(dataType)
+		writer := csv.NewWriter(out)
+		writer.Write(fields)
 		for _, item := range data {
-			fmt.Println(formatItem(item, dataType))
+			writer.Write(recordRow(item, fields))
 		}
+		writer.Flush()
+		return writer.Error()
 
-	case "table":
-		// Print as table
-		var data []map[string]interface{}
-		if err := json.Unmarshal(body, &data); err != nil {
-			return fmt.Errorf("error parsing response: %w", err)
+	case "text":
+		for _, item := range data {
+			fmt.Fprintln(out, formatItem(item, dataType))
 		}
 
+	case "table":
 		if len(data) == 0 {
-			fmt.Println("No results found.")
+			fmt.Fprintln(out, "No results found.")
 			return nil
 		}
+		writeTable(out, data, dataType)
+	}
 
-		// Create table
-		table := tablewriter.NewWriter(os.Stdout)
-
-		// Set headers based on data type
-		switch dataType {
-		case "logs":
-			table.SetHeader([]string{"Timestamp", "Service", "Level", "Message"})
-			for _, item := range data {
-				row := []string{
-					fmt.Sprintf("%v", item["timestamp"]),
-					fmt.Sprintf("%v", item["service"]),
-					fmt.Sprintf("%v", item["level"]),
-					fmt.Sprintf("%v", item["message"]),
-				}
-				table.Append(row)
-			}
+	return nil
+}
 
-		case "metrics":
-			table.SetHeader([]string{"Timestamp", "Service", "Name", "Value", "Type"})
-			for _, item := range data {
-				row := []string{
-					fmt.Sprintf("%v", item["timestamp"]),
-					fmt.Sprintf("%v", item["service"]),
-					fmt.Sprintf("%v", item["name"]),
-					fmt.Sprintf("%v", item["value"]),
-					fmt.Sprintf("%v", item["type"]),
-				}
-				table.Append(row)
-			}
+// recordFields returns the fields of records of dataType shown by the table
+// and CSV output, in column order
+func recordFields(dataType string) []string {
+	switch dataType {
+	case "logs":
+		return []string{"timestamp", "service", "level", "message"}
+	case "metrics":
+		return []string{"timestamp", "service", "name", "value", "type"}
+	case "traces":
+		return []string{"start_time", "service", "name", "duration_ms", "status"}
+	}
+	return nil
+}
 
-		case "traces":
-			table.SetHeader([]string{"Timestamp", "Service", "Name", "Duration (ms)", "Status"})
-			for _, item := range data {
-				row := []string{
-					fmt.Sprintf("%v", item["start_time"]),
-					fmt.Sprintf("%v", item["service"]),
-					fmt.Sprintf("%v", item["name"]),
-					fmt.Sprintf("%v", item["duration_ms"]),
-					fmt.Sprintf("%v", item["status"]),
-				}
-				table.Append(row)
-			}
+// recordRow returns the values of fields in item, empty for missing fields
+func recordRow(item map[string]interface{}, fields []string) []string {
+	row := make([]string, len(fields))
+	for i, field := range fields {
+		if value, ok := item[field]; ok && value != nil {
+			row[i] = fmt.Sprintf("%v", value)
 		}
+	}
+	return row
+}
+
+// writeTable writes records of dataType to out as a table
+func writeTable(out io.Writer, data []map[string]interface{}, dataType string) {
+	// Create table
+	table := tablewriter.NewWriter(out)
 
-		table.Render()
+	// Set headers based on data type
+	switch dataType {
+	case "logs":
+		table.SetHeader([]string{"Timestamp", "Service", "Level", "Message"})
+	case "metrics":
+		table.SetHeader([]string{"Timestamp", "Service", "Name", "Value", "Type"})
+	case "traces":
+		table.SetHeader([]string{"Timestamp", "Service", "Name", "Duration (ms)", "Status"})
 	}
 
-	return nil
+	fields := recordFields(dataType)
+	for _, item := range data {
+		table.Append(recordRow(item, fields))
+	}
+
+	table.Render()
+}
+
+// decodeRecords extracts the records from a paginated query response
+func decodeRecords(body []byte, dataType string) ([]map[string]interface{}, error) {
+	var page map[string]json.RawMessage
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+
+	var records []map[string]interface{}
+	if raw, ok := page[dataType]; ok {
+		if err := json.Unmarshal(raw, &records); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
 }
 
 func formatItem(item map[string]interface{}, dataType string) string {
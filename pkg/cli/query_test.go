@@ -0,0 +1,290 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/api"
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/processor"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+// newSlowServer returns a server that holds every request until the client
+// gives up or the test ends
+func newSlowServer(t *testing.T) *httptest.Server {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+	return server
+}
+
+func TestRunQuery_Timeout(t *testing.T) {
+	server := newSlowServer(t)
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	err := runQuery(client, retryPolicy{}, "logs", server.URL, "", 10, "json", "1h", "", nil, "", true)
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the query to give up quickly, took %s", elapsed)
+	}
+	if !strings.Contains(err.Error(), "did not respond within 50ms") {
+		t.Errorf("expected the error to explain the timeout, got: %v", err)
+	}
+}
+
+// newFlakyServer returns a server that answers the first failures requests
+// with status and the following ones with an empty page of logs. It counts
+// the requests it receives.
+func newFlakyServer(t *testing.T, failures int, status int) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(requests.Add(1)) <= failures {
+			http.Error(w, "storage unavailable", status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"logs":[]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRunQuery_RetriesTransientFailures(t *testing.T) {
+	server, requests := newFlakyServer(t, 2, http.StatusServiceUnavailable)
+	retry := retryPolicy{retries: 2, backoff: time.Millisecond}
+
+	if err := runQuery(server.Client(), retry, "logs", server.URL, "", 10, "json", "1h", "", nil, "", true); err != nil {
+		t.Fatalf("expected the query to succeed on the third attempt, got: %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+}
+
+func TestRunQuery_RetriesExhausted(t *testing.T) {
+	server, requests := newFlakyServer(t, 5, http.StatusBadGateway)
+	retry := retryPolicy{retries: 2, backoff: time.Millisecond}
+
+	err := runQuery(server.Client(), retry, "logs", server.URL, "", 10, "json", "1h", "", nil, "", true)
+	if err == nil {
+		t.Fatal("expected an error after the retries are exhausted")
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+	if !strings.Contains(err.Error(), "giving up after 3 attempts") || !strings.Contains(err.Error(), "status 502") {
+		t.Errorf("expected the error to report the attempts and last status, got: %v", err)
+	}
+}
+
+func TestRunQuery_DoesNotRetryClientErrors(t *testing.T) {
+	server, requests := newFlakyServer(t, 1, http.StatusBadRequest)
+	retry := retryPolicy{retries: 2, backoff: time.Millisecond}
+
+	if err := runQuery(server.Client(), retry, "logs", server.URL, "", 10, "json", "1h", "", nil, "", true); err == nil {
+		t.Fatal("expected the 400 to fail the query")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected 1 request, got %d", got)
+	}
+}
+
+func TestRunQuery_RetriesConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	serverURL := server.URL
+	server.Close()
+	retry := retryPolicy{retries: 1, backoff: time.Millisecond}
+
+	err := runQuery(&http.Client{}, retry, "logs", serverURL, "", 10, "json", "1h", "", nil, "", true)
+	if err == nil || !strings.Contains(err.Error(), "giving up after 2 attempts") {
+		t.Errorf("expected the connection error after 2 attempts, got: %v", err)
+	}
+}
+
+func TestBuildQueryURL(t *testing.T) {
+	queryURL, err := buildQueryURL("http://pulse:8080", "logs", "checkout", 20, "", "", []string{
+		"level=ERROR", "message:*timeout*", "region=eu",
+	}, "level", false)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	u, err := url.Parse(queryURL)
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	if u.Path != "/api/logs" {
+		t.Errorf("unexpected query URL: %s", queryURL)
+	}
+	// The names parseQueryParams reads
+	expected := url.Values{
+		"service":       {"checkout"},
+		"limit":         {"20"},
+		"order_by":      {"level"},
+		"order_desc":    {"false"},
+		"level":         {"ERROR"},
+		"search":        {"timeout"},
+		"filter.region": {"eu"},
+	}
+	if got := u.Query(); got.Encode() != expected.Encode() {
+		t.Errorf("expected params %v, got %v", expected, got)
+	}
+
+	queryURL, _ = buildQueryURL("http://pulse:8080", "logs", "", 20, "", "", nil, "", true)
+	if u, _ := url.Parse(queryURL); u.Query().Get("order_desc") != "true" {
+		t.Errorf("expected order_desc=true for --desc, got %s", queryURL)
+	}
+
+	if _, err := buildQueryURL("http://pulse:8080", "logs", "", 20, "", "", []string{"region"}, "", true); err == nil {
+		t.Error("expected an error for a filter without a value")
+	}
+	for _, bounds := range [][2]string{{"soon", ""}, {"", "-1h"}} {
+		if _, err := buildQueryURL("http://pulse:8080", "logs", "", 20, bounds[0], bounds[1], nil, "", true); err == nil {
+			t.Errorf("expected an error for since %q and until %q", bounds[0], bounds[1])
+		}
+	}
+}
+
+func TestBuildQueryURL_TimeRange(t *testing.T) {
+	store, err := storage.NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	server := api.NewServer(processor.NewStorageProcessor(store), 0)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Stop(ctx)
+	})
+
+	now := time.Now().UTC()
+	for message, age := range map[string]time.Duration{"too old": 8 * 24 * time.Hour, "in range": 3 * 24 * time.Hour, "too new": time.Minute} {
+		entry := models.NewLogEntry("checkout", message, models.LogLevelInfo)
+		entry.Timestamp = now.Add(-age)
+		store.SaveLog(entry)
+	}
+
+	// The server reads --since 7d and --until 10m rather than falling back
+	// to its default window
+	for _, bounds := range [][2]string{
+		{"7d", "10m"},
+		{now.Add(-7 * 24 * time.Hour).Format(time.RFC3339), now.Add(-10 * time.Minute).Format(time.RFC3339)},
+	} {
+		queryURL, err := buildQueryURL("http://"+listener.Addr().String(), "logs", "", 20, bounds[0], bounds[1], nil, "", true)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		resp, err := http.Get(queryURL)
+		if err != nil {
+			t.Fatalf("failed to query logs: %v", err)
+		}
+		var result struct {
+			Logs []models.LogEntry `json:"logs"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(result.Logs) != 1 || result.Logs[0].Message != "in range" {
+			t.Errorf("since %s until %s: expected only the log in range, got %+v", bounds[0], bounds[1], result.Logs)
+		}
+	}
+}
+
+func TestQueryCommand_ConfiguredServer(t *testing.T) {
+	configured, configuredRequests := newFlakyServer(t, 0, 0)
+	flag, flagRequests := newFlakyServer(t, 0, 0)
+	useHome(t, "server_url: "+configured.URL+"/\n")
+
+	for _, args := range [][]string{
+		{"--format", "json"},
+		{"--format", "json", "--server", flag.URL},
+	} {
+		cmd := NewQueryCommand()
+		cmd.SetArgs(args)
+		cmd.SetOut(&strings.Builder{})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v: expected no error, got: %v", args, err)
+		}
+	}
+	if configuredRequests.Load() != 1 || flagRequests.Load() != 1 {
+		t.Errorf("expected one query to the configured server and one to --server, got %d and %d",
+			configuredRequests.Load(), flagRequests.Load())
+	}
+}
+
+func TestWriteQueryResult_JSONLines(t *testing.T) {
+	body := []byte(`{"logs":[
+		{"id":"1","service":"api","message":"a <b>"},
+		{"id":"2","service":"api","message":"c"}
+	],"pagination":{"total_items":2}}`)
+
+	var out strings.Builder
+	if err := writeQueryResult(&out, body, "logs", "jsonl"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := `{"id":"1","message":"a <b>","service":"api"}` + "\n" + `{"id":"2","message":"c","service":"api"}` + "\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestWriteQueryResult_CSV(t *testing.T) {
+	tests := []struct {
+		dataType string
+		body     string
+		expected string
+	}{
+		{
+			"logs",
+			`{"logs":[{"timestamp":"2024-01-01T00:00:00Z","service":"api","level":"ERROR","message":"failed, \"retrying\"\nnow"}]}`,
+			"timestamp,service,level,message\n2024-01-01T00:00:00Z,api,ERROR,\"failed, \"\"retrying\"\"\nnow\"\n",
+		},
+		{
+			"metrics",
+			`{"metrics":[{"timestamp":"2024-01-01T00:00:00Z","service":"api","name":"http.requests","value":42,"type":"counter"}]}`,
+			"timestamp,service,name,value,type\n2024-01-01T00:00:00Z,api,http.requests,42,counter\n",
+		},
+		{
+			"traces",
+			`{"traces":[{"start_time":"2024-01-01T00:00:00Z","service":"api","name":"GET /","duration_ms":12}]}`,
+			"start_time,service,name,duration_ms,status\n2024-01-01T00:00:00Z,api,GET /,12,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		if err := writeQueryResult(&out, []byte(tt.body), tt.dataType, "csv"); err != nil {
+			t.Fatalf("%s: expected no error, got: %v", tt.dataType, err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.dataType, tt.expected, out.String())
+		}
+	}
+}
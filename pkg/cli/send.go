@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// sendTarget is the server data is sent to and the service and tags it is
+// attributed to, resolved from the flags and the config
+type sendTarget struct {
+	serverURL string
+	service   string
+	tags      map[string]string
+}
+
+// NewSendCommand creates a command that submits metrics and traces
+func NewSendCommand() *cobra.Command {
+	var (
+		serverURL string
+		service   string
+		tags      []string
+		timeout   time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "send",
+		Short: "Send metrics and traces to Pulse",
+		Long: `Submit a single metric or a trace to Pulse, for scripted instrumentation
+and testing. The server URL, service and tags default to the configured
+server_url, default_service and tags; --tag adds to or overrides the
+configured tags.`,
+	}
+
+	cmd.PersistentFlags().StringVar(&serverURL, "server", "", "Pulse server URL (defaults to the configured server_url)")
+	cmd.PersistentFlags().StringVar(&service, "service", "", "Service name (defaults to the configured default_service)")
+	cmd.PersistentFlags().StringArrayVar(&tags, "tag", []string{}, "Tags to add (format: key=value)")
+	cmd.PersistentFlags().DurationVar(&timeout, "timeout", defaultRequestTimeout, "Give up on the server after this long (0 waits forever)")
+
+	target := func() (sendTarget, error) {
+		cfg, err := loadConfig()
+		if err != nil {
+			return sendTarget{}, fmt.Errorf("error loading config: %w", err)
+		}
+		return resolveSendTarget(cfg, serverURL, service, tags)
+	}
+
+	cmd.AddCommand(newSendMetricCommand(target, &timeout))
+	cmd.AddCommand(newSendTraceCommand(target, &timeout))
+
+	return cmd
+}
+
+// newSendMetricCommand creates a command that submits a metric
+func newSendMetricCommand(target func() (sendTarget, error), timeout *time.Duration) *cobra.Command {
+	var (
+		name       string
+		value      float64
+		metricType string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "metric",
+		Short: "Send a metric",
+		Example: `  # Count a request
+  pulse send metric --name http.requests --value 1 --type counter --service api --tag endpoint=/x
+
+  # Record a gauge with the configured service
+  pulse send metric --name queue.depth --value 42`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			t, err := target()
+			if err != nil {
+				return err
+			}
+			client := newHTTPClient(*timeout)
+			return runSendMetric(client, cmd.OutOrStdout(), t, name, value, metricType)
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Metric name (required)")
+	cmd.Flags().Float64Var(&value, "value", 0, "Metric value")
+	cmd.Flags().StringVar(&metricType, "type", "gauge", "Metric type: counter, gauge, histogram or info")
+
+	return cmd
+}
+
+// newSendTraceCommand creates a command that submits a trace from a file
+func newSendTraceCommand(target func() (sendTarget, error), timeout *time.Duration) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "trace",
+		Short: "Send a trace",
+		Long: `Send a trace read from a JSON file in the format accepted by POST /traces.
+Spans without a service get the default service, and the tags are added to
+each span unless it sets them itself.`,
+		Example: `  # Send a trace from a file
+  pulse send trace --file trace.json
+
+  # Send a trace from stdin
+  generate-trace | pulse send trace --file -`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			t, err := target()
+			if err != nil {
+				return err
+			}
+
+			input := cmd.InOrStdin()
+			if file != "-" {
+				f, err := os.Open(file)
+				if err != nil {
+					return fmt.Errorf("error opening trace file: %w", err)
+				}
+				defer f.Close()
+				input = f
+			}
+
+			client := newHTTPClient(*timeout)
+			return runSendTrace(client, cmd.OutOrStdout(), t, input)
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "JSON file holding the trace, or - for stdin (required)")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// resolveSendTarget applies the flags over the config: a server URL or
+// service given as a flag replaces the configured one, and tags given as
+// flags are merged over the configured tags
+func resolveSendTarget(cfg *Config, serverURL, service string, tags []string) (sendTarget, error) {
+	target := sendTarget{
+		serverURL: cfg.ServerURL,
+		service:   cfg.DefaultService,
+		tags:      make(map[string]string),
+	}
+	if serverURL != "" {
+		target.serverURL = serverURL
+	}
+	if service != "" {
+		target.service = service
+	}
+	target.serverURL = strings.TrimRight(target.serverURL, "/")
+
+	for k, v := range cfg.Tags {
+		target.tags[k] = v
+	}
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok || key == "" {
+			return sendTarget{}, fmt.Errorf("invalid tag %q: expected key=value", tag)
+		}
+		target.tags[key] = value
+	}
+
+	return target, nil
+}
+
+// runSendMetric submits a metric to the target server
+func runSendMetric(client *http.Client, out io.Writer, target sendTarget, name string, value float64, metricType string) error {
+	if name == "" {
+		return fmt.Errorf("a metric name is required (see --name)")
+	}
+	metricType = strings.ToLower(metricType)
+	switch metricType {
+	case "counter", "gauge", "histogram", "info":
+	default:
+		return fmt.Errorf("invalid metric type: %s. Must be one of: counter, gauge, histogram, info", metricType)
+	}
+	if target.service == "" {
+		return fmt.Errorf("a service is required (see --service)")
+	}
+
+	metric := map[string]interface{}{
+		"name":      name,
+		"value":     value,
+		"type":      metricType,
+		"service":   target.service,
+		"timestamp": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if len(target.tags) > 0 {
+		metric["tags"] = target.tags
+	}
+
+	var response struct {
+		ID string `json:"id"`
+	}
+	if err := postJSON(client, target.serverURL+"/metrics", metric, &response, "error sending metric"); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Sent metric %s (id %s)\n", name, response.ID)
+	return nil
+}
+
+// runSendTrace submits the trace read from input to the target server,
+// filling in the service and tags of its spans
+func runSendTrace(client *http.Client, out io.Writer, target sendTarget, input io.Reader) error {
+	var trace map[string]interface{}
+	if err := json.NewDecoder(input).Decode(&trace); err != nil {
+		return fmt.Errorf("error parsing trace: %w", err)
+	}
+
+	spans, _ := trace["spans"].([]interface{})
+	if len(spans) == 0 {
+		return fmt.Errorf("invalid trace: at least one span is required")
+	}
+	for i, s := range spans {
+		span, ok := s.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid trace: span %d is not an object", i)
+		}
+		if service, _ := span["service"].(string); service == "" && target.service != "" {
+			span["service"] = target.service
+		}
+		if len(target.tags) == 0 {
+			continue
+		}
+		spanTags, _ := span["tags"].(map[string]interface{})
+		if spanTags == nil {
+			spanTags = make(map[string]interface{})
+		}
+		for k, v := range target.tags {
+			if _, ok := spanTags[k]; !ok {
+				spanTags[k] = v
+			}
+		}
+		span["tags"] = spanTags
+	}
+
+	var response struct {
+		ID    string `json:"id"`
+		Spans int    `json:"spans"`
+	}
+	if err := postJSON(client, target.serverURL+"/traces", trace, &response, "error sending trace"); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Sent trace %s with %d span(s)\n", response.ID, response.Spans)
+	return nil
+}
+
+// postJSON POSTs payload as JSON to url and decodes the response into
+// response, failing on any status other than 200
+func postJSON(client *http.Client, url string, payload, response interface{}, action string) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return requestError(action, client, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+	return nil
+}
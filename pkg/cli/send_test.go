@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newSendServer returns a server recording the path and JSON body of each
+// request and answering with response
+func newSendServer(t *testing.T, response string) (*httptest.Server, *map[string]map[string]interface{}) {
+	received := make(map[string]map[string]interface{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		received[r.URL.Path] = body
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+func TestResolveSendTarget(t *testing.T) {
+	cfg := &Config{
+		ServerURL:      "http://configured:8080/",
+		DefaultService: "configured",
+		Tags:           map[string]string{"env": "dev", "team": "core"},
+	}
+
+	target, err := resolveSendTarget(cfg, "", "", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if target.serverURL != "http://configured:8080" || target.service != "configured" || len(target.tags) != 2 {
+		t.Errorf("expected the configured defaults, got %+v", target)
+	}
+
+	target, err = resolveSendTarget(cfg, "http://flag:9090", "api", []string{"env=prod", "endpoint=/x"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if target.serverURL != "http://flag:9090" || target.service != "api" {
+		t.Errorf("expected the flags to override the config, got %+v", target)
+	}
+	if target.tags["env"] != "prod" || target.tags["team"] != "core" || target.tags["endpoint"] != "/x" {
+		t.Errorf("expected flag tags merged over the configured tags, got %v", target.tags)
+	}
+
+	if _, err := resolveSendTarget(cfg, "", "", []string{"env"}); err == nil {
+		t.Error("expected an error for a tag without a value")
+	}
+}
+
+func TestRunSendMetric(t *testing.T) {
+	server, received := newSendServer(t, `{"status":"ok","id":"m-1"}`)
+	target := sendTarget{serverURL: server.URL, service: "api", tags: map[string]string{"endpoint": "/x"}}
+
+	var out bytes.Buffer
+	if err := runSendMetric(server.Client(), &out, target, "http.requests", 1, "Counter"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	metric := (*received)["/metrics"]
+	if metric["name"] != "http.requests" || metric["value"] != 1.0 || metric["type"] != "counter" || metric["service"] != "api" {
+		t.Errorf("unexpected metric: %v", metric)
+	}
+	if tags, _ := metric["tags"].(map[string]interface{}); tags["endpoint"] != "/x" {
+		t.Errorf("expected the tags to be sent, got %v", metric["tags"])
+	}
+	if !strings.Contains(out.String(), "m-1") {
+		t.Errorf("expected the metric ID to be reported, got %q", out.String())
+	}
+
+	for _, invalid := range []struct{ name, metricType string }{
+		{"", "counter"},
+		{"http.requests", "summary"},
+	} {
+		if err := runSendMetric(server.Client(), &out, target, invalid.name, 1, invalid.metricType); err == nil {
+			t.Errorf("expected an error for name %q and type %q", invalid.name, invalid.metricType)
+		}
+	}
+}
+
+func TestRunSendTrace(t *testing.T) {
+	server, received := newSendServer(t, `{"status":"ok","id":"t-1","spans":2}`)
+	target := sendTarget{serverURL: server.URL, service: "api", tags: map[string]string{"env": "dev"}}
+
+	input := strings.NewReader(`{"id":"t-1","spans":[
+		{"name":"GET /x"},
+		{"name":"query","service":"db","tags":{"env":"prod"}}
+	]}`)
+	var out bytes.Buffer
+	if err := runSendTrace(server.Client(), &out, target, input); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	spans, _ := (*received)["/traces"]["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %v", (*received)["/traces"])
+	}
+	first, second := spans[0].(map[string]interface{}), spans[1].(map[string]interface{})
+	if first["service"] != "api" || first["tags"].(map[string]interface{})["env"] != "dev" {
+		t.Errorf("expected the defaults to fill in the first span, got %v", first)
+	}
+	if second["service"] != "db" || second["tags"].(map[string]interface{})["env"] != "prod" {
+		t.Errorf("expected the second span to keep its own service and tags, got %v", second)
+	}
+	if !strings.Contains(out.String(), "Sent trace t-1 with 2 span(s)") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	if err := runSendTrace(server.Client(), &out, target, strings.NewReader(`{"spans":[]}`)); err == nil {
+		t.Error("expected an error for a trace without spans")
+	}
+}
+
+func TestRunSendMetric_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Service name is required", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := runSendMetric(server.Client(), &bytes.Buffer{}, sendTarget{serverURL: server.URL, service: "api"}, "up", 1, "gauge")
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Errorf("expected the server error to be reported, got: %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"strings"
@@ -23,8 +24,10 @@ func NewStreamCommand() *cobra.Command {
 		level      string
 		format     string
 		tags       []string
+		mapping    []string
 		follow     bool
 		bufferSize int
+		timeout    time.Duration
 	)
 
 	cmd := &cobra.Command{
@@ -39,26 +42,54 @@ It supports both plain text and JSON log formats.`,
   my-app | pulse stream --service my-app --level info --follow
   
   # Stream JSON logs
-  cat json-logs.log | pulse stream --format json`,
+  cat json-logs.log | pulse stream --format json
+
+  # Stream JSON logs keeping their message in a non-standard field
+  my-app | pulse stream --format json --json-mapping text=message`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runStream(cmd.InOrStdin(), serverURL, service, level, format, tags, follow, bufferSize)
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			if serverURL == "" {
+				serverURL = strings.TrimRight(cfg.ServerURL, "/")
+			}
+			if service == "" {
+				service = cfg.DefaultService
+			}
+
+			jsonMapping, err := parseJSONMapping(mapping)
+			if err != nil {
+				return err
+			}
+
+			client := withAPIKey(&http.Client{Timeout: timeout}, cfg.APIKey)
+			return runStream(client, cmd.InOrStdin(), serverURL, service, level, format, jsonMapping, cfg.Tags, tags, follow, bufferSize)
 		},
 	}
 
-	cmd.Flags().StringVar(&serverURL, "server", "http://localhost:8080", "Pulse server URL")
-	cmd.Flags().StringVar(&service, "service", "default", "Service name to tag logs with")
+	cmd.Flags().StringVar(&serverURL, "server", "", "Pulse server URL (defaults to the configured server_url)")
+	cmd.Flags().StringVar(&service, "service", "", "Service name to tag logs with (defaults to the configured default_service)")
 	cmd.Flags().StringVar(&level, "level", "INFO", "Default log level if not provided in the log")
 	cmd.Flags().StringVar(&format, "format", "text", "Log format: 'text' or 'json'")
-	cmd.Flags().StringArrayVar(&tags, "tag", []string{}, "Tags to add to logs (format: key=value)")
+	cmd.Flags().StringArrayVar(&tags, "tag", []string{}, "Tags to add to logs, over the configured tags (format: key=value)")
+	cmd.Flags().StringArrayVar(&mapping, "json-mapping", []string{}, "Map a JSON log field, nested ones by dotted path, to a log field, over the defaults (format: field=message|level|timestamp|service|trace_id|span_id|env|host|source)")
 	cmd.Flags().BoolVar(&follow, "follow", false, "Keep the connection open and follow log input")
 	cmd.Flags().IntVar(&bufferSize, "buffer", 100, "Number of log lines to buffer before sending")
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultRequestTimeout, "Give up on each request to the server after this long (0 waits forever)")
 
 	return cmd
 }
 
-func runStream(input io.Reader, serverURL, service, level, format string, tags []string, _ bool, bufferSize int) error {
+// runStream sends the lines of input to the server as logs, tagged with
+// defaultTags and tags; tags given as key=value override defaultTags. JSON
+// lines are read with mapping, as returned by parseJSONMapping.
+func runStream(client *http.Client, input io.Reader, serverURL, service, level, format string, mapping []jsonFieldMapping, defaultTags map[string]string, tags []string, _ bool, bufferSize int) error {
 	// Parse tags into a map
 	tagMap := make(map[string]string)
+	for k, v := range defaultTags {
+		tagMap[k] = v
+	}
 	for _, tag := range tags {
 		parts := strings.SplitN(tag, "=", 2)
 		if len(parts) == 2 {
@@ -86,9 +117,9 @@ func runStream(input io.Reader, serverURL, service, level, format string, tags [
 			return fmt.Errorf("error marshaling logs: %w", err)
 		}
 
-		resp, err := http.Post(serverURL+"/logs/batch", "application/json", bytes.NewBuffer(jsonData))
+		resp, err := client.Post(serverURL+"/logs/batch", "application/json", bytes.NewBuffer(jsonData))
 		if err != nil {
-			return fmt.Errorf("error sending logs: %w", err)
+			return requestError("error sending logs", client, err)
 		}
 		defer resp.Body.Close()
 
@@ -123,7 +154,8 @@ func runStream(input io.Reader, serverURL, service, level, format string, tags [
 
 		// Parse the log based on format
 		if format == "json" {
-			if err := json.Unmarshal([]byte(line), &logEntry); err != nil {
+			var err error
+			if logEntry, err = parseJSONLog(line, mapping, service, logLevel, tagMap); err != nil {
 				// If parsing fails, treat it as a regular message
 				logEntry = models.LogEntry{
 					Message:   line,
@@ -155,15 +187,268 @@ func runStream(input io.Reader, serverURL, service, level, format string, tags [
 		}
 	}
 
-	// Final flush of buffer
+	// Final flush of buffer. Unlike the periodic sends its failure fails the
+	// command, since the logs are not retried.
 	close(done)
-	if err := sendLogs(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error sending logs: %v\n", err)
-	}
+	flushErr := sendLogs()
 
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("error reading input: %w", err)
 	}
 
-	return nil
+	return flushErr
+}
+
+// jsonFieldMapping maps a JSON log field, or a dotted path to a nested one,
+// to a log field
+type jsonFieldMapping struct {
+	field  string
+	target string
+}
+
+// defaultJSONMapping maps the field names used by common JSON loggers (zap,
+// logrus, bunyan, pino and the Elastic Common Schema) to log fields, in
+// order of precedence
+var defaultJSONMapping = []jsonFieldMapping{
+	{"message", "message"}, {"msg", "message"},
+	{"level", "level"}, {"severity", "level"}, {"lvl", "level"}, {"log.level", "level"},
+	{"timestamp", "timestamp"}, {"@timestamp", "timestamp"}, {"time", "timestamp"}, {"ts", "timestamp"},
+	{"service", "service"}, {"service.name", "service"},
+	{"trace_id", "trace_id"}, {"traceId", "trace_id"}, {"trace.id", "trace_id"},
+	{"span_id", "span_id"}, {"spanId", "span_id"}, {"span.id", "span_id"},
+	{"env", "env"}, {"environment", "env"},
+	{"host", "host"}, {"hostname", "host"},
+	{"source", "source"}, {"caller", "source"},
+}
+
+// jsonLogFields lists the log fields JSON fields can be mapped to
+var jsonLogFields = map[string]bool{
+	"message": true, "level": true, "timestamp": true, "service": true,
+	"trace_id": true, "span_id": true, "env": true, "host": true, "source": true,
+}
+
+// parseJSONMapping returns the mappings given as field=log_field, in order,
+// followed by the default mappings of other fields
+func parseJSONMapping(mappings []string) ([]jsonFieldMapping, error) {
+	var mapping []jsonFieldMapping
+	given := make(map[string]bool)
+	for _, m := range mappings {
+		field, target, ok := strings.Cut(m, "=")
+		if !ok || field == "" {
+			return nil, fmt.Errorf("invalid JSON mapping %q (format: field=log_field)", m)
+		}
+		if !jsonLogFields[target] {
+			return nil, fmt.Errorf("invalid JSON mapping %q: unknown log field %q", m, target)
+		}
+		mapping = append(mapping, jsonFieldMapping{field, target})
+		given[field] = true
+	}
+	for _, m := range defaultJSONMapping {
+		if !given[m.field] {
+			mapping = append(mapping, m)
+		}
+	}
+	return mapping, nil
+}
+
+// parseJSONLog parses a JSON log line, taking each log field from the first
+// field mapped to it that holds a usable value. Every field left, including
+// levels and timestamps that can't be parsed, becomes a tag over tags, as do
+// the entries of a "tags" object; values that aren't strings are rendered as
+// JSON. A line without a message keeps the whole line as its message.
+func parseJSONLog(line string, mapping []jsonFieldMapping, service string, level models.LogLevel, tags map[string]string) (models.LogEntry, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return models.LogEntry{}, err
+	}
+	if fields == nil {
+		return models.LogEntry{}, fmt.Errorf("not a JSON object")
+	}
+
+	entry := models.LogEntry{
+		Timestamp: time.Now().UTC(),
+		Service:   service,
+		Level:     level,
+		Tags:      make(map[string]string, len(tags)+len(fields)),
+	}
+	for k, v := range tags {
+		entry.Tags[k] = v
+	}
+	explicitTags, _ := fields["tags"].(map[string]interface{})
+	delete(fields, "tags")
+
+	targets := map[string]*string{
+		"message":  &entry.Message,
+		"service":  &entry.Service,
+		"trace_id": &entry.TraceID,
+		"span_id":  &entry.SpanID,
+		"env":      &entry.Env,
+		"host":     &entry.Host,
+		"source":   &entry.Source,
+	}
+
+	mapped := make(map[string]bool)
+	for _, m := range mapping {
+		if mapped[m.target] {
+			continue
+		}
+		value, ok := lookupJSONField(fields, m.field)
+		if !ok {
+			continue
+		}
+
+		switch m.target {
+		case "level":
+			var parsed models.LogLevel
+			if parsed, ok = parseJSONLogLevel(value); ok {
+				entry.Level = parsed
+			}
+		case "timestamp":
+			var parsed time.Time
+			if parsed, ok = parseJSONTimestamp(value); ok {
+				entry.Timestamp = parsed
+			}
+		default:
+			// Objects and arrays are left as tags
+			switch value.(type) {
+			case string, json.Number, bool:
+				str := jsonString(value)
+				if ok = str != ""; ok {
+					*targets[m.target] = str
+				}
+			default:
+				ok = false
+			}
+		}
+		if ok {
+			mapped[m.target] = true
+			removeJSONField(fields, m.field)
+		}
+	}
+
+	for k, v := range fields {
+		entry.Tags[k] = jsonString(v)
+	}
+	for k, v := range explicitTags {
+		entry.Tags[k] = jsonString(v)
+	}
+	if entry.Message == "" {
+		entry.Message = line
+	}
+	return entry, nil
+}
+
+// lookupJSONField returns the value of a field, given as its name or as a
+// dotted path into nested objects
+func lookupJSONField(fields map[string]interface{}, field string) (interface{}, bool) {
+	if value, ok := fields[field]; ok {
+		return value, true
+	}
+	parent, rest, ok := strings.Cut(field, ".")
+	if !ok {
+		return nil, false
+	}
+	nested, ok := fields[parent].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookupJSONField(nested, rest)
+}
+
+// removeJSONField removes a field found by lookupJSONField, along with the
+// nested objects it leaves empty
+func removeJSONField(fields map[string]interface{}, field string) {
+	if _, ok := fields[field]; ok {
+		delete(fields, field)
+		return
+	}
+	parent, rest, _ := strings.Cut(field, ".")
+	nested := fields[parent].(map[string]interface{})
+	removeJSONField(nested, rest)
+	if len(nested) == 0 {
+		delete(fields, parent)
+	}
+}
+
+// parseJSONLogLevel parses the level names of common loggers, in any case,
+// and bunyan and pino numeric levels (10 trace to 60 fatal)
+func parseJSONLogLevel(value interface{}) (models.LogLevel, bool) {
+	if number, ok := value.(json.Number); ok {
+		n, err := number.Int64()
+		switch {
+		case err != nil:
+			return "", false
+		case n < 30:
+			return models.LogLevelDebug, true
+		case n < 40:
+			return models.LogLevelInfo, true
+		case n < 50:
+			return models.LogLevelWarning, true
+		case n < 60:
+			return models.LogLevelError, true
+		default:
+			return models.LogLevelFatal, true
+		}
+	}
+
+	name, ok := value.(string)
+	if !ok {
+		return "", false
+	}
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "TRACE", "DEBUG":
+		return models.LogLevelDebug, true
+	case "INFO", "INFORMATION", "NOTICE":
+		return models.LogLevelInfo, true
+	case "WARN", "WARNING":
+		return models.LogLevelWarning, true
+	case "ERR", "ERROR":
+		return models.LogLevelError, true
+	case "FATAL", "PANIC", "DPANIC", "CRIT", "CRITICAL", "ALERT", "EMERG", "EMERGENCY":
+		return models.LogLevelFatal, true
+	}
+	return "", false
+}
+
+// parseJSONTimestamp parses RFC 3339 timestamps and Unix timestamps in
+// seconds (as zap writes them), milliseconds (pino) or nanoseconds
+func parseJSONTimestamp(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		ts, err := time.Parse(time.RFC3339Nano, v)
+		return ts.UTC(), err == nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		switch {
+		case f >= 1e17:
+			return time.Unix(0, int64(f)).UTC(), true
+		case f >= 1e11:
+			return time.UnixMilli(int64(f)).UTC(), true
+		default:
+			sec, frac := math.Modf(f)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// jsonString renders a decoded JSON value as a string
+func jsonString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	default:
+		b, _ := json.Marshal(value)
+		return string(b)
+	}
 }
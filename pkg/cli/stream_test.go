@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func TestRunStream_Timeout(t *testing.T) {
+	server := newSlowServer(t)
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+
+	err := runStream(client, strings.NewReader("first line\nsecond line\n"), server.URL, "api", "INFO", "text", nil, nil, nil, false, 100)
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if !strings.Contains(err.Error(), "error sending logs: the server did not respond within 50ms") {
+		t.Errorf("expected the error to explain the timeout, got: %v", err)
+	}
+}
+
+func TestStreamCommand_ConfigDefaults(t *testing.T) {
+	var logs []models.LogEntry
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []models.LogEntry
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("failed to decode logs: %v", err)
+		}
+		logs = append(logs, batch...)
+	}))
+	defer server.Close()
+	useHome(t, "server_url: "+server.URL+"\ndefault_service: checkout\ntags:\n  env: dev\n  team: payments\n")
+
+	cmd := NewStreamCommand()
+	cmd.SetArgs([]string{"--tag", "team=core"})
+	cmd.SetIn(strings.NewReader("hello\n"))
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 log, got %d", len(logs))
+	}
+	if logs[0].Service != "checkout" {
+		t.Errorf("expected the configured default service, got %q", logs[0].Service)
+	}
+	expected := map[string]string{"env": "dev", "team": "core"}
+	if !reflect.DeepEqual(logs[0].Tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, logs[0].Tags)
+	}
+}
+
+func TestParseJSONLog(t *testing.T) {
+	mapping, err := parseJSONMapping([]string{"text=message"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defaults := map[string]string{"env": "dev"}
+
+	tests := []struct {
+		name string
+		line string
+		want models.LogEntry
+	}{
+		{
+			name: "zap",
+			line: `{"level":"warn","ts":1700000000.5,"caller":"main.go:12","msg":"slow request","path":"/x"}`,
+			want: models.LogEntry{
+				Timestamp: time.Unix(1700000000, 5e8).UTC(), Service: "api", Level: models.LogLevelWarning,
+				Message: "slow request", Source: "main.go:12", Tags: map[string]string{"env": "dev", "path": "/x"},
+			},
+		},
+		{
+			name: "bunyan",
+			line: `{"name":"checkout","hostname":"web-1","level":50,"msg":"payment failed","time":"2024-01-02T03:04:05.5Z","v":0}`,
+			want: models.LogEntry{
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 5e8, time.UTC), Service: "api", Level: models.LogLevelError,
+				Message: "payment failed", Host: "web-1", Tags: map[string]string{"env": "dev", "name": "checkout", "v": "0"},
+			},
+		},
+		{
+			name: "ecs",
+			line: `{"@timestamp":"2024-01-02T03:04:05Z","log":{"level":"CRITICAL","logger":"db"},"message":"disk full","service":{"name":"storage"},"trace":{"id":"t-1"}}`,
+			want: models.LogEntry{
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Service: "storage", Level: models.LogLevelFatal,
+				Message: "disk full", TraceID: "t-1", Tags: map[string]string{"env": "dev", "log": `{"logger":"db"}`},
+			},
+		},
+		{
+			name: "pulse schema",
+			line: `{"timestamp":"2024-01-02T03:04:05Z","service":"billing","level":"DEBUG","message":"hi","env":"prod","tags":{"region":"eu"}}`,
+			want: models.LogEntry{
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Service: "billing", Level: models.LogLevelDebug,
+				Message: "hi", Env: "prod", Tags: map[string]string{"env": "dev", "region": "eu"},
+			},
+		},
+		{
+			name: "custom mapping and unknown level",
+			line: `{"text":"custom","msg":"fallback","severity":"verbose","ts":1700000000000}`,
+			want: models.LogEntry{
+				Timestamp: time.UnixMilli(1700000000000).UTC(), Service: "api", Level: models.LogLevelInfo,
+				Message: "custom", Tags: map[string]string{"env": "dev", "msg": "fallback", "severity": "verbose"},
+			},
+		},
+		{
+			name: "no message",
+			line: `{"level":"info"}`,
+			want: models.LogEntry{Service: "api", Level: models.LogLevelInfo, Message: `{"level":"info"}`, Tags: map[string]string{"env": "dev"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := parseJSONLog(tt.line, mapping, "api", models.LogLevelInfo, defaults)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if tt.want.Timestamp.IsZero() {
+				tt.want.Timestamp = entry.Timestamp
+			}
+			if !reflect.DeepEqual(entry, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, entry)
+			}
+		})
+	}
+
+	for _, line := range []string{"plain text", "[1, 2]", "null"} {
+		if _, err := parseJSONLog(line, mapping, "api", models.LogLevelInfo, defaults); err == nil {
+			t.Errorf("expected an error for %q", line)
+		}
+	}
+}
+
+func TestParseJSONMapping(t *testing.T) {
+	mapping, err := parseJSONMapping([]string{"msg=source", "event.text=message"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(mapping) != len(defaultJSONMapping)+1 {
+		t.Fatalf("expected the default mappings of other fields after the given ones, got %v", mapping)
+	}
+	if mapping[0] != (jsonFieldMapping{"msg", "source"}) || mapping[1] != (jsonFieldMapping{"event.text", "message"}) {
+		t.Errorf("expected the given mappings first, got %v", mapping[:2])
+	}
+	for _, m := range mapping[2:] {
+		if m.field == "msg" {
+			t.Errorf("expected the default msg mapping to be overridden, got %v", mapping)
+		}
+	}
+
+	for _, invalid := range []string{"msg", "=message", "msg=body"} {
+		if _, err := parseJSONMapping([]string{invalid}); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
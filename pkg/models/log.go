@@ -16,6 +16,38 @@ const (
 	LogLevelFatal   LogLevel = "FATAL"
 )
 
+// logLevels lists the standard log levels from least to most severe
+var logLevels = []LogLevel{LogLevelDebug, LogLevelInfo, LogLevelWarning, LogLevelError, LogLevelFatal}
+
+// Severity ranks the level from 1 (DEBUG) to 5 (FATAL). Unknown levels rank 0.
+func (l LogLevel) Severity() int {
+	for i, level := range logLevels {
+		if level == l {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether l is at least as severe as min. Levels without a
+// severity ranking only match themselves.
+func (l LogLevel) AtLeast(min LogLevel) bool {
+	if min.Severity() == 0 {
+		return l == min
+	}
+	return l.Severity() >= min.Severity()
+}
+
+// LevelsAtOrAbove returns the standard levels at least as severe as min, or
+// nil if min is not a standard level
+func LevelsAtOrAbove(min LogLevel) []LogLevel {
+	severity := min.Severity()
+	if severity == 0 {
+		return nil
+	}
+	return append([]LogLevel(nil), logLevels[severity-1:]...)
+}
+
 // LogEntry represents a single log message with metadata
 type LogEntry struct {
 	ID        string            `json:"id,omitempty"`       // Unique identifier for the log entry
@@ -42,12 +74,16 @@ func NewLogEntry(service, message string, level LogLevel) *LogEntry {
 	}
 }
 
-// AddTag adds a tag to the log entry
+// AddTag adds a tag to the log entry. New keys past the SetMaxTags limit are
+// ignored.
 func (l *LogEntry) AddTag(key, value string) *LogEntry {
-	if l.Tags == nil {
-		l.Tags = make(map[string]string)
-	}
-	l.Tags[key] = value
+	setTag(&l.Tags, key, value)
+	return l
+}
+
+// AddTags adds tags to the log entry like AddTag, in sorted key order
+func (l *LogEntry) AddTags(tags map[string]string) *LogEntry {
+	setTags(&l.Tags, tags)
 	return l
 }
 
@@ -123,3 +123,30 @@ func TestLogEntry_WithHost(t *testing.T) {
 		t.Errorf("expected Host %s, got %s", host, log.Host)
 	}
 }
+
+func TestLogLevel_Severity(t *testing.T) {
+	ordered := []LogLevel{LogLevelDebug, LogLevelInfo, LogLevelWarning, LogLevelError, LogLevelFatal}
+	for i := 1; i < len(ordered); i++ {
+		if ordered[i].Severity() <= ordered[i-1].Severity() {
+			t.Errorf("expected %s to be more severe than %s", ordered[i], ordered[i-1])
+		}
+	}
+	if severity := LogLevel("TRACE").Severity(); severity != 0 {
+		t.Errorf("expected unknown level to have severity 0, got %d", severity)
+	}
+
+	if !LogLevelError.AtLeast(LogLevelWarning) || LogLevelInfo.AtLeast(LogLevelWarning) {
+		t.Errorf("expected only levels at or above WARNING to match")
+	}
+	if !LogLevel("AUDIT").AtLeast("AUDIT") || LogLevelFatal.AtLeast("AUDIT") {
+		t.Errorf("expected unknown levels to match only themselves")
+	}
+
+	levels := LevelsAtOrAbove(LogLevelWarning)
+	if len(levels) != 3 || levels[0] != LogLevelWarning || levels[2] != LogLevelFatal {
+		t.Errorf("expected WARNING, ERROR and FATAL, got %v", levels)
+	}
+	if levels := LevelsAtOrAbove("warn"); levels != nil {
+		t.Errorf("expected no levels for an unknown level, got %v", levels)
+	}
+}
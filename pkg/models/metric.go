@@ -1,6 +1,11 @@
 package models
 
 import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,8 +18,25 @@ const (
 	MetricTypeGauge     MetricType = "gauge"     // Value that can go up and down
 	MetricTypeHistogram MetricType = "histogram" // Distribution of values
 	MetricTypeSummary   MetricType = "summary"   // Similar to histogram but with calculated quantiles
+	MetricTypeInfo      MetricType = "info"      // Constant 1 whose tags carry the payload (e.g. build info)
+
+	MetricTypeExponentialHistogram MetricType = "exponential_histogram" // Distribution with exponential buckets
 )
 
+// IsValid reports whether t is one of the standard metric types
+func (t MetricType) IsValid() bool {
+	switch t {
+	case MetricTypeCounter, MetricTypeGauge, MetricTypeHistogram, MetricTypeSummary,
+		MetricTypeInfo, MetricTypeExponentialHistogram:
+		return true
+	}
+	return false
+}
+
+// DefaultHistogramBuckets holds the bucket boundaries used for histograms that
+// are created without explicit buckets. It can be overridden at startup.
+var DefaultHistogramBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
 // Metric represents a single measurement with metadata
 type Metric struct {
 	ID        string            `json:"id,omitempty"`       // Unique identifier for the metric
@@ -44,6 +66,27 @@ type HistogramMetric struct {
 	Percentile map[float64]float64 `json:"percentile,omitempty"` // Optional pre-calculated percentiles
 }
 
+// ExponentialBuckets holds a contiguous run of exponential histogram bucket
+// counts. BucketCounts[i] is the count of bucket index Offset+i.
+type ExponentialBuckets struct {
+	Offset       int32    `json:"offset"`        // Index of the first bucket
+	BucketCounts []uint64 `json:"bucket_counts"` // Counts of consecutive buckets
+}
+
+// ExponentialHistogram is an OpenTelemetry exponential (native) histogram.
+// Bucket boundaries are powers of base = 2^(2^-Scale): bucket index i holds
+// values in (base^i, base^(i+1)]. Negative values are bucketed by magnitude
+// and exact zeros are counted separately.
+type ExponentialHistogram struct {
+	Metric
+	Scale     int32              `json:"scale"`      // Resolution; higher scales give narrower buckets
+	ZeroCount uint64             `json:"zero_count"` // Number of observations equal to zero
+	Positive  ExponentialBuckets `json:"positive"`   // Buckets for positive values
+	Negative  ExponentialBuckets `json:"negative"`   // Buckets for negative values, by magnitude
+	Sum       float64            `json:"sum"`        // Sum of all observed values
+	Count     uint64             `json:"count"`      // Count of observations
+}
+
 // NewMetric creates a new metric with the current timestamp
 func NewMetric(name string, value float64, metricType MetricType, service string) *Metric {
 	return &Metric{
@@ -56,12 +99,46 @@ func NewMetric(name string, value float64, metricType MetricType, service string
 	}
 }
 
-// AddTag adds a tag to the metric
-func (m *Metric) AddTag(key, value string) *Metric {
-	if m.Tags == nil {
-		m.Tags = make(map[string]string)
+// NewInfoMetric creates an info-style metric (the Prometheus "info" pattern).
+// Its value is always 1 and the labels are the information being recorded,
+// e.g. version=1.2.3 for a build_info metric.
+func NewInfoMetric(name string, service string, labels map[string]string) (*Metric, error) {
+	metric := NewMetric(name, 1, MetricTypeInfo, service).AddTags(labels)
+
+	if err := metric.ValidateInfo(); err != nil {
+		return nil, err
+	}
+
+	return metric, nil
+}
+
+// ValidateInfo checks that an info metric has the value 1 and a non-empty
+// tag set, since the tags are its payload
+func (m *Metric) ValidateInfo() error {
+	if m.Value != 1 {
+		return fmt.Errorf("info metric %q must have value 1, got %v", m.Name, m.Value)
+	}
+	if len(m.Tags) == 0 {
+		return fmt.Errorf("info metric %q requires at least one tag", m.Name)
+	}
+	for k := range m.Tags {
+		if k == "" {
+			return fmt.Errorf("info metric %q has an empty tag name", m.Name)
+		}
 	}
-	m.Tags[key] = value
+	return nil
+}
+
+// AddTag adds a tag to the metric. New keys past the SetMaxTags limit are
+// ignored.
+func (m *Metric) AddTag(key, value string) *Metric {
+	setTag(&m.Tags, key, value)
+	return m
+}
+
+// AddTags adds tags to the metric like AddTag, in sorted key order
+func (m *Metric) AddTags(tags map[string]string) *Metric {
+	setTags(&m.Tags, tags)
 	return m
 }
 
@@ -83,10 +160,18 @@ func (m *Metric) WithHost(host string) *Metric {
 	return m
 }
 
-// NewHistogramMetric creates a new histogram metric
-func NewHistogramMetric(name string, service string, buckets []float64) *HistogramMetric {
-	histogramBuckets := make([]HistogramBucket, len(buckets))
-	for i, bound := range buckets {
+// NewHistogramMetric creates a new histogram metric with the given bucket
+// upper bounds, sorted into increasing order. Observe and the percentile
+// estimates rely on strictly increasing bounds, so it fails if there are no
+// bounds or a bound is NaN or repeated.
+func NewHistogramMetric(name string, service string, buckets []float64) (*HistogramMetric, error) {
+	bounds, err := sortBucketBounds(buckets)
+	if err != nil {
+		return nil, fmt.Errorf("histogram %q: %w", name, err)
+	}
+
+	histogramBuckets := make([]HistogramBucket, len(bounds))
+	for i, bound := range bounds {
 		histogramBuckets[i] = HistogramBucket{
 			UpperBound: bound,
 			Count:      0,
@@ -105,7 +190,7 @@ func NewHistogramMetric(name string, service string, buckets []float64) *Histogr
 		Sum:        0,
 		Count:      0,
 		Percentile: make(map[float64]float64),
-	}
+	}, nil
 }
 
 // Observe adds a single observation to the histogram
@@ -120,3 +205,131 @@ func (h *HistogramMetric) Observe(value float64) {
 		}
 	}
 }
+
+// NewExponentialHistogram creates a new exponential histogram with the given scale
+func NewExponentialHistogram(name string, service string, scale int32) *ExponentialHistogram {
+	return &ExponentialHistogram{
+		Metric: Metric{
+			Name:      name,
+			Timestamp: time.Now().UTC(),
+			Type:      MetricTypeExponentialHistogram,
+			Service:   service,
+			Tags:      make(map[string]string),
+		},
+		Scale: scale,
+	}
+}
+
+// Observe adds a single observation to the exponential histogram
+func (h *ExponentialHistogram) Observe(value float64) {
+	h.Sum += value
+	h.Count++
+
+	switch {
+	case value > 0:
+		h.Positive.increment(h.BucketIndex(value))
+	case value < 0:
+		h.Negative.increment(h.BucketIndex(-value))
+	default:
+		h.ZeroCount++
+	}
+}
+
+// BucketIndex returns the index of the bucket holding the positive value v
+func (h *ExponentialHistogram) BucketIndex(v float64) int32 {
+	return int32(math.Ceil(math.Log2(v)*math.Ldexp(1, int(h.Scale)))) - 1
+}
+
+// BucketBounds returns the lower (exclusive) and upper (inclusive) boundaries
+// of the bucket with the given index
+func (h *ExponentialHistogram) BucketBounds(index int32) (lower, upper float64) {
+	width := math.Ldexp(1, -int(h.Scale)) // log2 of the base
+	return math.Exp2(float64(index) * width), math.Exp2(float64(index+1) * width)
+}
+
+// increment adds one to the bucket with the given index, growing the run as needed
+func (b *ExponentialBuckets) increment(index int32) {
+	switch {
+	case len(b.BucketCounts) == 0:
+		b.Offset = index
+		b.BucketCounts = []uint64{0}
+	case index < b.Offset:
+		grown := make([]uint64, int(b.Offset-index)+len(b.BucketCounts))
+		copy(grown[b.Offset-index:], b.BucketCounts)
+		b.BucketCounts = grown
+		b.Offset = index
+	case int(index-b.Offset) >= len(b.BucketCounts):
+		b.BucketCounts = append(b.BucketCounts, make([]uint64, int(index-b.Offset)-len(b.BucketCounts)+1)...)
+	}
+	b.BucketCounts[index-b.Offset]++
+}
+
+// ValidateBuckets checks that a pre-aggregated histogram is well formed:
+// bucket bounds must be strictly increasing and bucket counts cumulative,
+// i.e. non-decreasing and never more than the total Count
+func (h *HistogramMetric) ValidateBuckets() error {
+	if len(h.Buckets) == 0 {
+		return fmt.Errorf("histogram %q has no buckets", h.Name)
+	}
+	for i, bucket := range h.Buckets {
+		if math.IsNaN(bucket.UpperBound) {
+			return fmt.Errorf("histogram %q bucket %d has an invalid upper bound", h.Name, i)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := h.Buckets[i-1]
+		if bucket.UpperBound <= prev.UpperBound {
+			return fmt.Errorf("histogram %q bucket bounds must be strictly increasing, got %v after %v",
+				h.Name, bucket.UpperBound, prev.UpperBound)
+		}
+		if bucket.Count < prev.Count {
+			return fmt.Errorf("histogram %q bucket counts must be cumulative, got %d after %d",
+				h.Name, bucket.Count, prev.Count)
+		}
+	}
+	if last := h.Buckets[len(h.Buckets)-1].Count; last > h.Count {
+		return fmt.Errorf("histogram %q count %d is less than its largest bucket count %d", h.Name, h.Count, last)
+	}
+	return nil
+}
+
+// ParseHistogramBuckets parses a comma-separated list of distinct bucket
+// boundaries (e.g. "5,10,25,50,100") into a sorted slice
+func ParseHistogramBuckets(s string) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	buckets := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bound, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket boundary %q: %w", part, err)
+		}
+		buckets = append(buckets, bound)
+	}
+
+	return sortBucketBounds(buckets)
+}
+
+// sortBucketBounds returns a sorted copy of histogram bucket bounds, or an
+// error if there are none or they are not distinct numbers
+func sortBucketBounds(bounds []float64) ([]float64, error) {
+	if len(bounds) == 0 {
+		return nil, fmt.Errorf("no bucket boundaries provided")
+	}
+
+	sorted := append([]float64(nil), bounds...)
+	sort.Float64s(sorted)
+	for i, bound := range sorted {
+		if math.IsNaN(bound) {
+			return nil, fmt.Errorf("invalid bucket boundary %v", bound)
+		}
+		if i > 0 && bound == sorted[i-1] {
+			return nil, fmt.Errorf("duplicate bucket boundary %v", bound)
+		}
+	}
+	return sorted, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"testing"
 	"time"
 )
@@ -63,6 +64,19 @@ func TestNewMetric(t *testing.T) {
 	}
 }
 
+func TestMetricType_IsValid(t *testing.T) {
+	for _, metricType := range []MetricType{MetricTypeCounter, MetricTypeGauge, MetricTypeHistogram, MetricTypeSummary, MetricTypeInfo} {
+		if !metricType.IsValid() {
+			t.Errorf("expected %s to be valid", metricType)
+		}
+	}
+	for _, metricType := range []MetricType{"", "guage", "Counter"} {
+		if metricType.IsValid() {
+			t.Errorf("expected %q to be invalid", metricType)
+		}
+	}
+}
+
 func TestMetric_AddTag(t *testing.T) {
 	metric := NewMetric("test_metric", 100, MetricTypeGauge, "test-service")
 
@@ -87,13 +101,47 @@ func TestMetric_AddTag(t *testing.T) {
 	}
 }
 
+func TestNewInfoMetric(t *testing.T) {
+	metric, err := NewInfoMetric("build_info", "api", map[string]string{"version": "1.2.3", "commit": "abc123"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if metric.Value != 1 {
+		t.Errorf("expected value 1, got %v", metric.Value)
+	}
+	if metric.Type != MetricTypeInfo {
+		t.Errorf("expected type %s, got %s", MetricTypeInfo, metric.Type)
+	}
+	if metric.Tags["version"] != "1.2.3" {
+		t.Errorf("expected version tag 1.2.3, got %s", metric.Tags["version"])
+	}
+
+	// The tag set is the payload, so it can't be empty
+	if _, err := NewInfoMetric("build_info", "api", nil); err == nil {
+		t.Error("expected error for info metric without tags")
+	}
+	if _, err := NewInfoMetric("build_info", "api", map[string]string{"": "x"}); err == nil {
+		t.Error("expected error for empty tag name")
+	}
+
+	// Info metrics are constant 1
+	metric.Value = 2
+	if err := metric.ValidateInfo(); err == nil {
+		t.Error("expected error for info metric with value 2")
+	}
+}
+
 func TestHistogramMetric(t *testing.T) {
 	name := "http_request_duration"
 	service := "web-service"
 	buckets := []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0}
 
 	// Create a histogram metric
-	metric := NewHistogramMetric(name, service, buckets)
+	metric, err := NewHistogramMetric(name, service, buckets)
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
 
 	// Test buckets
 	if len(metric.Buckets) != len(buckets) {
@@ -133,3 +181,154 @@ func TestHistogramMetric(t *testing.T) {
 		}
 	}
 }
+
+func TestHistogramMetric_ValidateBuckets(t *testing.T) {
+	tests := []struct {
+		name    string
+		buckets []HistogramBucket
+		count   uint64
+		valid   bool
+	}{
+		{"cumulative", []HistogramBucket{{0.1, 2}, {0.5, 5}, {1, 5}}, 6, true},
+		{"no buckets", nil, 0, false},
+		{"unsorted bounds", []HistogramBucket{{0.5, 2}, {0.1, 5}}, 5, false},
+		{"duplicate bounds", []HistogramBucket{{0.1, 2}, {0.1, 5}}, 5, false},
+		{"decreasing counts", []HistogramBucket{{0.1, 5}, {0.5, 2}}, 5, false},
+		{"count below buckets", []HistogramBucket{{0.1, 2}, {0.5, 5}}, 4, false},
+	}
+
+	for _, tt := range tests {
+		metric, err := NewHistogramMetric("latency", "api", []float64{1})
+		if err != nil {
+			t.Fatalf("failed to create histogram: %v", err)
+		}
+		metric.Buckets = tt.buckets
+		metric.Count = tt.count
+
+		err = metric.ValidateBuckets()
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected no error, got: %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+
+	// Histograms built with Observe are always valid
+	observed, err := NewHistogramMetric("latency", "api", []float64{0.1, 0.5, 1})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	observed.Observe(0.05)
+	observed.Observe(0.7)
+	observed.Observe(3)
+	if err := observed.ValidateBuckets(); err != nil {
+		t.Errorf("expected observed histogram to be valid, got: %v", err)
+	}
+}
+
+func TestNewHistogramMetric_Buckets(t *testing.T) {
+	input := []float64{100, 5, 25}
+	metric, err := NewHistogramMetric("latency", "api", input)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := []float64{5, 25, 100}
+	for i, bound := range expected {
+		if metric.Buckets[i].UpperBound != bound {
+			t.Errorf("expected bucket[%d] to be %f, got %f", i, bound, metric.Buckets[i].UpperBound)
+		}
+	}
+	if input[0] != 100 {
+		t.Errorf("expected the given bounds to be left unsorted, got %v", input)
+	}
+
+	metric.Observe(10)
+	if metric.Buckets[0].Count != 0 || metric.Buckets[1].Count != 1 || metric.Buckets[2].Count != 1 {
+		t.Errorf("expected the observation counted from the 25 bucket up, got %+v", metric.Buckets)
+	}
+
+	for name, buckets := range map[string][]float64{
+		"nil":       nil,
+		"empty":     {},
+		"duplicate": {5, 25, 5},
+		"NaN":       {5, math.NaN()},
+	} {
+		if _, err := NewHistogramMetric("latency", "api", buckets); err == nil {
+			t.Errorf("expected error for %s buckets", name)
+		}
+	}
+}
+
+func TestParseHistogramBuckets(t *testing.T) {
+	buckets, err := ParseHistogramBuckets("100, 5,25")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := []float64{5, 25, 100}
+	if len(buckets) != len(expected) {
+		t.Fatalf("expected %d buckets, got %d", len(expected), len(buckets))
+	}
+	for i, bound := range expected {
+		if buckets[i] != bound {
+			t.Errorf("expected bucket[%d] to be %f, got %f", i, bound, buckets[i])
+		}
+	}
+
+	// Invalid input
+	if _, err := ParseHistogramBuckets("5,abc"); err == nil {
+		t.Errorf("expected error for non-numeric bucket")
+	}
+	if _, err := ParseHistogramBuckets(" , "); err == nil {
+		t.Errorf("expected error for empty bucket list")
+	}
+	if _, err := ParseHistogramBuckets("5,10,5"); err == nil {
+		t.Errorf("expected error for duplicate buckets")
+	}
+}
+
+func TestExponentialHistogram_Observe(t *testing.T) {
+	// Scale 0 has base 2: bucket i holds (2^i, 2^(i+1)]
+	h := NewExponentialHistogram("latency", "api", 0)
+
+	for _, v := range []float64{3, 4, 0.75, 20, 0, -3} {
+		h.Observe(v)
+	}
+
+	if h.Count != 6 || h.Sum != 3+4+0.75+20-3 {
+		t.Errorf("expected count 6 and sum 24.75, got %d and %v", h.Count, h.Sum)
+	}
+	if h.ZeroCount != 1 {
+		t.Errorf("expected zero count 1, got %d", h.ZeroCount)
+	}
+
+	// 0.75 -> -1, 3 and 4 -> 1, 20 -> 4
+	if h.Positive.Offset != -1 {
+		t.Errorf("expected positive offset -1, got %d", h.Positive.Offset)
+	}
+	expected := []uint64{1, 0, 2, 0, 0, 1}
+	if len(h.Positive.BucketCounts) != len(expected) {
+		t.Fatalf("expected %d positive buckets, got %v", len(expected), h.Positive.BucketCounts)
+	}
+	for i, count := range expected {
+		if h.Positive.BucketCounts[i] != count {
+			t.Errorf("expected positive bucket %d count %d, got %d", i, count, h.Positive.BucketCounts[i])
+		}
+	}
+
+	if h.Negative.Offset != 1 || len(h.Negative.BucketCounts) != 1 || h.Negative.BucketCounts[0] != 1 {
+		t.Errorf("expected -3 in negative bucket 1, got %+v", h.Negative)
+	}
+
+	if lower, upper := h.BucketBounds(1); lower != 2 || upper != 4 {
+		t.Errorf("expected bucket 1 bounds (2, 4], got (%v, %v]", lower, upper)
+	}
+
+	// Higher scales split each power of two into 2^scale buckets
+	fine := NewExponentialHistogram("latency", "api", 2)
+	if idx := fine.BucketIndex(3); idx != 6 {
+		t.Errorf("expected 3 to land in bucket 6 at scale 2, got %d", idx)
+	}
+}
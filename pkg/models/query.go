@@ -1,20 +1,66 @@
 package models
 
 import (
+	"encoding/base64"
+	"errors"
+	"strings"
 	"time"
 )
 
 // QueryParams represents the parameters for querying data
 type QueryParams struct {
 	Service   string            // Service name to filter by
+	Name      string            // Metric name to filter by (for metrics)
 	Level     string            // Log level to filter by (for logs)
+	MinLevel  string            // Lowest log level to return (for logs)
 	TraceID   string            // Trace ID to filter by
+	SpanID    string            // Span ID to filter by (for spans)
 	Search    string            // Free text search query
 	Limit     int               // Maximum number of results
-	Since     time.Time         // Start time for the query
-	Until     time.Time         // End time for the query
+	Since     time.Time         // Start time for the query, inclusive; zero for no lower bound
+	Until     time.Time         // End time for the query, inclusive; zero for no upper bound
 	Filters   map[string]string // Additional filters
 	OrderBy   string            // Field to order by
 	OrderDesc bool              // True for descending order
 	Offset    int               // For pagination
+	Cursor    string            // Opaque position to continue after, replacing Offset (for logs)
+
+	MinDuration int64 // Minimum duration in milliseconds (for traces and spans)
+	MaxDuration int64 // Maximum duration in milliseconds, 0 for none (for traces and spans)
+
+	HasTags     []string // Tag keys that must be present, whatever their value (for logs, metrics and spans)
+	MissingTags []string // Tag keys that must be absent (for logs, metrics and spans)
+
+	ContainsSpan string // Only return traces with at least one span of this name
+}
+
+// LogCursor is the position of a log in the newest first order of log
+// queries, ties broken by descending ID. A query with the encoded cursor
+// continues with the logs after it, so logs ingested between pages do not
+// shift the pages the way they do with offsets.
+type LogCursor struct {
+	Timestamp time.Time
+	ID        string
+}
+
+// Encode returns the opaque form of the cursor used in query parameters
+func (c LogCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Timestamp.UTC().Format(time.RFC3339Nano) + "," + c.ID))
+}
+
+// ParseLogCursor decodes a cursor produced by LogCursor.Encode
+func ParseLogCursor(s string) (*LogCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	timestamp, id, ok := strings.Cut(string(b), ",")
+	if !ok || id == "" {
+		return nil, errors.New("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	return &LogCursor{Timestamp: t.UTC(), ID: id}, nil
 }
@@ -0,0 +1,58 @@
+package models
+
+// Stats summarizes the stored telemetry, as served by /api/stats
+type Stats struct {
+	Logs    LogStats    `json:"logs"`
+	Metrics MetricStats `json:"metrics"`
+	Traces  TraceStats  `json:"traces"`
+
+	// Set when ingestion is asynchronous; its fields are reported at the
+	// top level
+	*AsyncStats
+
+	// Set when drop rules are configured; its fields are reported at the top
+	// level
+	*DropRuleStats
+}
+
+// LogStats counts the stored logs
+type LogStats struct {
+	Total   int64              `json:"total"`    // Number of logs
+	ByLevel map[LogLevel]int64 `json:"by_level"` // Number of logs of each standard level
+}
+
+// MetricStats counts the stored metrics
+type MetricStats struct {
+	Total  int64                `json:"total"`   // Number of metric points
+	ByType map[MetricType]int64 `json:"by_type"` // Number of metric points of each type
+}
+
+// TraceStats summarizes the stored traces
+type TraceStats struct {
+	Total         int64   `json:"total"`           // Number of traces
+	AvgDurationMs float64 `json:"avg_duration_ms"` // Mean trace duration in milliseconds, 0 without traces
+}
+
+// AsyncStats reports the state of the asynchronous ingestion queue
+type AsyncStats struct {
+	Pending int    `json:"async_pending"` // Items queued or being written
+	Dropped uint64 `json:"async_dropped"` // Items dropped because the queue was full
+	Failed  uint64 `json:"async_failed"`  // Items the storage failed to write
+}
+
+// DropRuleStats reports the records discarded by drop rules
+type DropRuleStats struct {
+	RuleDropped uint64 `json:"rule_dropped"` // Logs, metrics, spans and traces matching a drop rule
+}
+
+// NewStats creates empty statistics with every standard log level present
+func NewStats() *Stats {
+	stats := &Stats{
+		Logs:    LogStats{ByLevel: make(map[LogLevel]int64)},
+		Metrics: MetricStats{ByType: make(map[MetricType]int64)},
+	}
+	for _, level := range logLevels {
+		stats.Logs.ByLevel[level] = 0
+	}
+	return stats
+}
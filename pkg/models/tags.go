@@ -0,0 +1,88 @@
+package models
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// maxTags is the most tags AddTag keeps on a log entry, metric or span. Zero
+// means unlimited.
+var maxTags atomic.Int64
+
+// SetMaxTags limits the number of tags AddTag keeps on a log entry, metric or
+// span, whatever the ingestion path, and returns a function restoring the
+// previous limit. Once an item has max tags, AddTag ignores new keys but
+// still updates existing ones. A max of zero or less removes the limit.
+func SetMaxTags(max int) (restore func()) {
+	if max < 0 {
+		max = 0
+	}
+	previous := maxTags.Swap(int64(max))
+	return func() {
+		maxTags.Store(previous)
+	}
+}
+
+// MaxTags returns the tag limit set by SetMaxTags, or zero if unlimited
+func MaxTags() int {
+	return int(maxTags.Load())
+}
+
+// setTag sets key to value in *tags, creating the map if needed, unless key
+// is new and the tags are at the limit. It reports whether the tag was set.
+func setTag(tags *map[string]string, key, value string) bool {
+	if *tags == nil {
+		*tags = make(map[string]string)
+	}
+	if _, ok := (*tags)[key]; !ok {
+		if max := MaxTags(); max > 0 && len(*tags) >= max {
+			return false
+		}
+	}
+	(*tags)[key] = value
+	return true
+}
+
+// setTags sets the tags of src in *tags in sorted key order, so that the
+// keys kept at the limit don't depend on map iteration order
+func setTags(tags *map[string]string, src map[string]string) {
+	for _, key := range sortedKeys(src) {
+		setTag(tags, key, src[key])
+	}
+}
+
+// LimitTags removes the keys of tags past the SetMaxTags limit, keeping the
+// first ones in sorted key order, and returns tags. It normalizes items whose
+// tags were set without AddTag, such as decoded ones.
+func LimitTags(tags map[string]string) map[string]string {
+	max := MaxTags()
+	if max <= 0 || len(tags) <= max {
+		return tags
+	}
+	for _, key := range sortedKeys(tags)[max:] {
+		delete(tags, key)
+	}
+	return tags
+}
+
+// MergeTags returns a new map holding tags updated with updates, set like
+// AddTag in sorted key order: existing keys are overwritten and new keys past
+// the SetMaxTags limit are ignored
+func MergeTags(tags, updates map[string]string) map[string]string {
+	merged := make(map[string]string, len(tags)+len(updates))
+	for k, v := range tags {
+		merged[k] = v
+	}
+	setTags(&merged, updates)
+	return merged
+}
+
+// sortedKeys returns the keys of tags in sorted order
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
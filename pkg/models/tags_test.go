@@ -0,0 +1,66 @@
+package models
+
+import "testing"
+
+func TestSetMaxTags(t *testing.T) {
+	restore := SetMaxTags(2)
+	defer restore()
+
+	entry := NewLogEntry("api", "message", LogLevelInfo)
+	metric := NewMetric("requests", 1, MetricTypeCounter, "api")
+	span := NewSpan("GET /", "api", "trace-1")
+	addTags := map[string]func(key, value string) map[string]string{
+		"log":    func(key, value string) map[string]string { return entry.AddTag(key, value).Tags },
+		"metric": func(key, value string) map[string]string { return metric.AddTag(key, value).Tags },
+		"span":   func(key, value string) map[string]string { return span.AddTag(key, value).Tags },
+	}
+
+	for name, addTag := range addTags {
+		addTag("region", "eu")
+		addTag("zone", "a")
+		tags := addTag("host", "web-1")
+		if len(tags) != 2 || tags["host"] != "" {
+			t.Errorf("%s: expected the third tag to be dropped, got %v", name, tags)
+		}
+
+		// Existing keys can still be updated at the limit
+		if tags := addTag("zone", "b"); tags["zone"] != "b" {
+			t.Errorf("%s: expected zone to be updated, got %v", name, tags)
+		}
+	}
+
+	restore()
+	if MaxTags() != 0 {
+		t.Fatalf("expected restore to remove the limit, got %d", MaxTags())
+	}
+	span = NewSpan("GET /", "api", "trace-1")
+	for _, key := range []string{"a", "b", "c", "d"} {
+		span.AddTag(key, "1")
+	}
+	if len(span.Tags) != 4 {
+		t.Errorf("expected no limit by default, got %v", span.Tags)
+	}
+}
+
+func TestSetMaxTags_SortedKeys(t *testing.T) {
+	defer SetMaxTags(2)()
+
+	// Whatever the map order, the first keys in sorted order are kept
+	tags := map[string]string{"d": "1", "c": "1", "b": "1", "a": "1"}
+	if added := NewLogEntry("api", "message", LogLevelInfo).AddTags(tags).Tags; len(added) != 2 || added["a"] == "" || added["b"] == "" {
+		t.Errorf("expected AddTags to keep a and b, got %v", added)
+	}
+	if limited := LimitTags(map[string]string{"d": "1", "c": "1", "b": "1", "a": "1"}); len(limited) != 2 || limited["a"] == "" || limited["b"] == "" {
+		t.Errorf("expected LimitTags to keep a and b, got %v", limited)
+	}
+
+	// Merging updates existing keys and adds new ones while there is room
+	stored := map[string]string{"c": "1"}
+	merged := MergeTags(stored, map[string]string{"d": "2", "c": "2", "b": "2"})
+	if len(merged) != 2 || merged["b"] != "2" || merged["c"] != "2" {
+		t.Errorf("expected b and c to be merged, got %v", merged)
+	}
+	if len(stored) != 1 || stored["c"] != "1" {
+		t.Errorf("expected MergeTags to leave its input alone, got %v", stored)
+	}
+}
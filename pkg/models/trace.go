@@ -1,6 +1,13 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -27,6 +34,7 @@ type Span struct {
 	Status     SpanStatus        `json:"status,omitempty"`      // Status of the operation
 	Tags       map[string]string `json:"tags,omitempty"`        // Additional metadata as key-value pairs
 	Logs       []SpanLog         `json:"logs,omitempty"`        // Time-stamped logs attached to this span
+	Links      []SpanLink        `json:"links,omitempty"`       // Related spans that are not the parent
 	Env        string            `json:"env,omitempty"`         // Environment (prod, dev, staging, etc.)
 	Host       string            `json:"host,omitempty"`        // Hostname where the span was generated
 	IsFinished bool              `json:"is_finished,omitempty"` // Whether the span has been completed
@@ -38,12 +46,30 @@ type SpanLog struct {
 	Fields    map[string]string `json:"fields"`    // Log data as key-value pairs
 }
 
+// SpanLogsDroppedEvent is the event of the marker log that CapLogs puts in
+// place of the logs it drops
+const SpanLogsDroppedEvent = "logs_dropped"
+
+// SamplingPriorityTag records an upstream sampling decision on a span, as in
+// OpenTracing: 0 asks for the trace to be dropped and a positive value for it
+// to be kept
+const SamplingPriorityTag = "sampling.priority"
+
+// SpanLink points from a span to a related span that is not its parent,
+// possibly in another trace, e.g. the messages consumed by a batch job
+type SpanLink struct {
+	TraceID    string            `json:"trace_id"`             // ID of the trace of the linked span
+	SpanID     string            `json:"span_id"`              // ID of the linked span
+	Attributes map[string]string `json:"attributes,omitempty"` // Metadata describing the link
+}
+
 // Trace represents a collection of spans that make up an end-to-end transaction
 type Trace struct {
-	ID     string     `json:"id"`               // Unique identifier for the trace
-	Spans  []*Span    `json:"spans"`            // Collection of spans in this trace
-	Root   *Span      `json:"root"`             // Root span (entry point)
-	Status SpanStatus `json:"status,omitempty"` // Overall status of the trace
+	ID       string     `json:"id"`                    // Unique identifier for the trace
+	Spans    []*Span    `json:"spans"`                 // Collection of spans in this trace
+	Root     *Span      `json:"root"`                  // Root span (entry point)
+	Status   SpanStatus `json:"status,omitempty"`      // Overall status of the trace
+	Duration int64      `json:"duration_ms,omitempty"` // Total duration across all spans in milliseconds
 }
 
 // NewSpan creates a new span with the current timestamp as start time
@@ -79,12 +105,16 @@ func (s *Span) SetStatus(status SpanStatus) *Span {
 	return s
 }
 
-// AddTag adds a tag to the span
+// AddTag adds a tag to the span. New keys past the SetMaxTags limit are
+// ignored.
 func (s *Span) AddTag(key, value string) *Span {
-	if s.Tags == nil {
-		s.Tags = make(map[string]string)
-	}
-	s.Tags[key] = value
+	setTag(&s.Tags, key, value)
+	return s
+}
+
+// AddTags adds tags to the span like AddTag, in sorted key order
+func (s *Span) AddTags(tags map[string]string) *Span {
+	setTags(&s.Tags, tags)
 	return s
 }
 
@@ -98,6 +128,48 @@ func (s *Span) AddLog(fields map[string]string) *Span {
 	return s
 }
 
+// CapLogs keeps the first head and last tail logs of the span and replaces
+// the logs in between with a single marker log whose dropped_count field
+// records how many were removed. It returns the number of logs dropped.
+func (s *Span) CapLogs(head, tail int) int {
+	if head < 0 {
+		head = 0
+	}
+	if tail < 0 {
+		tail = 0
+	}
+	if len(s.Logs) <= head+tail {
+		return 0
+	}
+
+	dropped := len(s.Logs) - head - tail
+	marker := SpanLog{
+		Timestamp: s.Logs[head].Timestamp,
+		Fields: map[string]string{
+			"event":         SpanLogsDroppedEvent,
+			"dropped_count": strconv.Itoa(dropped),
+		},
+	}
+
+	logs := make([]SpanLog, 0, head+tail+1)
+	logs = append(logs, s.Logs[:head]...)
+	logs = append(logs, marker)
+	logs = append(logs, s.Logs[len(s.Logs)-tail:]...)
+	s.Logs = logs
+
+	return dropped
+}
+
+// AddLink links the span to another span
+func (s *Span) AddLink(traceID, spanID string, attributes map[string]string) *Span {
+	s.Links = append(s.Links, SpanLink{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		Attributes: attributes,
+	})
+	return s
+}
+
 // WithEnv sets the environment for the span
 func (s *Span) WithEnv(env string) *Span {
 	s.Env = env
@@ -110,6 +182,20 @@ func (s *Span) WithHost(host string) *Span {
 	return s
 }
 
+// SamplingDecision returns the upstream sampling decision recorded in the
+// span's SamplingPriorityTag. ok is false if the span carries none.
+func (s *Span) SamplingDecision() (sampled bool, ok bool) {
+	value, found := s.Tags[SamplingPriorityTag]
+	if !found {
+		return false, false
+	}
+	priority, err := strconv.Atoi(value)
+	if err != nil || priority < 0 {
+		return false, false
+	}
+	return priority > 0, true
+}
+
 // NewTrace creates a new trace with a root span
 func NewTrace(rootSpanName string, service string) (*Trace, *Span) {
 	traceID := generateID()
@@ -135,15 +221,168 @@ func (t *Trace) AddSpan(span *Span) *Trace {
 	return t
 }
 
+// ComputeDuration sets the duration of the trace to the time between the
+// earliest span start and the latest span end, and returns it. Spans without
+// an end time end after their own duration.
+func (t *Trace) ComputeDuration() int64 {
+	var start, end time.Time
+	for _, span := range t.Spans {
+		spanEnd := span.EndTime
+		if spanEnd.IsZero() {
+			spanEnd = span.StartTime.Add(time.Duration(span.Duration) * time.Millisecond)
+		}
+		if start.IsZero() || span.StartTime.Before(start) {
+			start = span.StartTime
+		}
+		if spanEnd.After(end) {
+			end = spanEnd
+		}
+	}
+
+	t.Duration = 0
+	if end.After(start) {
+		t.Duration = end.Sub(start).Milliseconds()
+	}
+	return t.Duration
+}
+
+// ComputeStatus marks the trace as ERROR if any of its spans failed,
+// whatever the status of the root span, and returns the resulting status.
+// Otherwise a trace without a status takes the status of its root span.
+func (t *Trace) ComputeStatus() SpanStatus {
+	for _, span := range t.Spans {
+		if span.Status == SpanStatusError {
+			t.Status = SpanStatusError
+			return t.Status
+		}
+	}
+
+	if t.Status == "" && t.Root != nil {
+		t.Status = t.Root.Status
+	}
+	return t.Status
+}
+
+// AssembleTrace builds the trace made of spans. The root is the earliest
+// span without a parent, or the earliest span whose parent is missing from
+// the trace. Spans are ordered depth first from the root, each span
+// followed by its children in start time order, with spans whose parent is
+// missing and their descendants after the root's tree. The status and
+// duration are computed from all spans.
+func AssembleTrace(traceID string, spans []*Span) *Trace {
+	trace := &Trace{ID: traceID, Spans: make([]*Span, 0, len(spans))}
+	if len(spans) == 0 {
+		return trace
+	}
+
+	sorted := make([]*Span, len(spans))
+	copy(sorted, spans)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartTime.Before(sorted[j].StartTime)
+	})
+
+	ids := make(map[string]bool, len(sorted))
+	for _, span := range sorted {
+		ids[span.ID] = true
+	}
+
+	// Spans without a parent in the trace start a tree, true roots first
+	children := make(map[string][]*Span)
+	var roots, orphans []*Span
+	for _, span := range sorted {
+		switch {
+		case span.ParentID == "":
+			roots = append(roots, span)
+		case !ids[span.ParentID] || span.ParentID == span.ID:
+			orphans = append(orphans, span)
+		default:
+			children[span.ParentID] = append(children[span.ParentID], span)
+		}
+	}
+	roots = append(roots, orphans...)
+
+	visited := make(map[*Span]bool, len(sorted))
+	var visit func(span *Span)
+	visit = func(span *Span) {
+		if visited[span] {
+			return
+		}
+		visited[span] = true
+		trace.Spans = append(trace.Spans, span)
+		for _, child := range children[span.ID] {
+			visit(child)
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+
+	// Spans in parent cycles are unreachable from any root
+	for _, span := range sorted {
+		visit(span)
+	}
+
+	trace.Root = trace.Spans[0]
+	trace.ComputeStatus()
+	trace.ComputeDuration()
+	return trace
+}
+
 // GenerateID creates a unique ID for spans and traces
 // This is a public function that can be used by external packages
 func GenerateID() string {
 	return generateID()
 }
 
-// generateID is a private function that generates a simple unique ID for spans and traces
+// idGenerator supplies the random bits of generated IDs
+type idGenerator struct {
+	r      io.Reader
+	locked bool       // Whether reads of r must hold mu
+	mu     sync.Mutex // Guards sources such as math/rand.Rand, which are not safe for concurrent use
+}
+
+// read fills b with random bits from the generator's source
+func (g *idGenerator) read(b []byte) error {
+	if g.locked {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+	}
+	_, err := io.ReadFull(g.r, b)
+	return err
+}
+
+// idSource is the ID generator set with SetIDSource, if any. The default
+// reads crypto/rand, which is safe for concurrent use, so generating IDs
+// takes no lock.
+var (
+	idSource        atomic.Pointer[idGenerator]
+	defaultIDSource = &idGenerator{r: rand.Reader}
+)
+
+// SetIDSource makes generated IDs read their random bits from r instead of
+// crypto/rand and returns a function restoring the previous source. Tests
+// can pass a math/rand.Rand with a fixed seed to get reproducible IDs. Reads
+// of r are serialized, so r needn't be safe for concurrent use.
+func SetIDSource(r io.Reader) (restore func()) {
+	previous := idSource.Swap(&idGenerator{r: r, locked: true})
+	return func() {
+		idSource.Store(previous)
+	}
+}
+
+// generateID is a private function that generates a unique ID for spans and traces.
+// IDs are 128 random bits from crypto/rand by default, hex encoded, so spans
+// created in the same instant never collide.
 func generateID() string {
-	// Simple generation for demo purposes
-	// In production, use a proper UUID library
-	return time.Now().Format("20060102150405") + "-" + time.Now().Format("999999999")[0:6]
+	generator := idSource.Load()
+	if generator == nil {
+		generator = defaultIDSource
+	}
+
+	var b [16]byte
+	if err := generator.read(b[:]); err != nil {
+		// crypto/rand only fails if the OS entropy source is unavailable
+		panic("models: failed to generate ID: " + err.Error())
+	}
+	return hex.EncodeToString(b[:])
 }
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -121,6 +125,48 @@ func TestSpan_Finish(t *testing.T) {
 	}
 }
 
+func TestSpan_CapLogs(t *testing.T) {
+	span := NewSpan("batch", "worker", "trace-1")
+	base := time.Now().UTC()
+	for i := 0; i < 100; i++ {
+		span.Logs = append(span.Logs, SpanLog{
+			Timestamp: base.Add(time.Duration(i) * time.Millisecond),
+			Fields:    map[string]string{"event": "item", "index": strconv.Itoa(i)},
+		})
+	}
+
+	if dropped := span.CapLogs(3, 2); dropped != 95 {
+		t.Errorf("expected 95 logs dropped, got %d", dropped)
+	}
+	if len(span.Logs) != 6 {
+		t.Fatalf("expected 3 head, 1 marker and 2 tail logs, got %d", len(span.Logs))
+	}
+
+	for i, index := range map[int]string{0: "0", 2: "2", 4: "98", 5: "99"} {
+		if span.Logs[i].Fields["index"] != index {
+			t.Errorf("expected log %d to be item %s, got %v", i, index, span.Logs[i].Fields)
+		}
+	}
+
+	marker := span.Logs[3]
+	if marker.Fields["event"] != SpanLogsDroppedEvent || marker.Fields["dropped_count"] != "95" {
+		t.Errorf("unexpected marker: %v", marker.Fields)
+	}
+	if !marker.Timestamp.Equal(base.Add(3 * time.Millisecond)) {
+		t.Errorf("expected marker at the first dropped log, got %v", marker.Timestamp)
+	}
+
+	// Spans within the cap are left alone
+	if dropped := span.CapLogs(3, 3); dropped != 0 || len(span.Logs) != 6 {
+		t.Errorf("expected no change within the cap, dropped %d and kept %d", dropped, len(span.Logs))
+	}
+
+	// Keeping only the head
+	if dropped := span.CapLogs(1, 0); dropped != 5 || len(span.Logs) != 2 || span.Logs[1].Fields["dropped_count"] != "5" {
+		t.Errorf("expected the first log and a marker, got %v", span.Logs)
+	}
+}
+
 func TestNewTrace(t *testing.T) {
 	// Create a new trace with a root span
 	rootSpanName := "root_span"
@@ -196,3 +242,156 @@ func TestTrace_AddSpan(t *testing.T) {
 		t.Errorf("child span not found in trace spans")
 	}
 }
+
+func TestTrace_ComputeDuration(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	trace, root := NewTrace("GET /orders", "api")
+	root.StartTime = start.Add(10 * time.Millisecond)
+	root.EndTime = start.Add(100 * time.Millisecond)
+
+	// A child starting before the root and one still running past its end
+	early := NewSpan("auth", "gateway", trace.ID)
+	early.StartTime = start
+	early.EndTime = start.Add(20 * time.Millisecond)
+	late := NewSpan("publish", "queue", trace.ID)
+	late.StartTime = start.Add(90 * time.Millisecond)
+	late.Duration = 60
+	trace.AddSpan(early).AddSpan(late)
+
+	if d := trace.ComputeDuration(); d != 150 || trace.Duration != 150 {
+		t.Errorf("expected a duration of 150ms, got %d (%d)", d, trace.Duration)
+	}
+
+	if d := (&Trace{}).ComputeDuration(); d != 0 {
+		t.Errorf("expected no duration without spans, got %d", d)
+	}
+}
+
+func TestTrace_ComputeStatus(t *testing.T) {
+	trace, root := NewTrace("GET /orders", "api")
+	trace.Status = ""
+	child := NewSpan("SELECT orders", "db", trace.ID).SetParent(root.ID)
+	trace.AddSpan(child)
+
+	if status := trace.ComputeStatus(); status != SpanStatusOK {
+		t.Errorf("expected the root status OK, got %s", status)
+	}
+
+	// A failed child fails the trace even though the root succeeded
+	child.SetStatus(SpanStatusError)
+	trace.Status = SpanStatusCanceled
+	if status := trace.ComputeStatus(); status != SpanStatusError || trace.Status != SpanStatusError {
+		t.Errorf("expected ERROR, got %s", trace.Status)
+	}
+}
+
+func TestAssembleTrace(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	span := func(id, parentID string, offset, duration int64) *Span {
+		return &Span{ID: id, TraceID: "trace-1", ParentID: parentID, StartTime: start.Add(time.Duration(offset) * time.Millisecond), Duration: duration, Status: SpanStatusOK}
+	}
+
+	// Shuffled spans: a root with two children, a grandchild and a span
+	// whose parent was never received
+	failed := span("db", "auth", 15, 5).SetStatus(SpanStatusError)
+	trace := AssembleTrace("trace-1", []*Span{
+		span("render", "root", 50, 20),
+		span("orphan", "missing", 5, 10),
+		failed,
+		span("root", "", 0, 100),
+		span("auth", "root", 10, 30),
+	})
+
+	if trace.ID != "trace-1" || trace.Root == nil || trace.Root.ID != "root" {
+		t.Fatalf("expected trace-1 rooted at root, got %+v", trace)
+	}
+
+	var order []string
+	for _, s := range trace.Spans {
+		order = append(order, s.ID)
+	}
+	expected := []string{"root", "auth", "db", "render", "orphan"}
+	if strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected spans in order %v, got %v", expected, order)
+	}
+
+	if trace.Status != SpanStatusError || trace.Duration != 100 {
+		t.Errorf("expected an ERROR trace of 100ms, got %s and %d", trace.Status, trace.Duration)
+	}
+
+	// Without a span lacking a parent, the earliest orphan is the root
+	partial := AssembleTrace("trace-1", []*Span{span("late", "gone", 20, 5), span("early", "gone", 10, 5)})
+	if partial.Root.ID != "early" || len(partial.Spans) != 2 {
+		t.Errorf("expected early to be the root of 2 spans, got %+v", partial)
+	}
+
+	if empty := AssembleTrace("trace-1", nil); empty.Root != nil || len(empty.Spans) != 0 {
+		t.Errorf("expected an empty trace, got %+v", empty)
+	}
+}
+
+func TestGenerateID_Unique(t *testing.T) {
+	const (
+		workers = 8
+		perWork = 12500 // 100k IDs in total
+	)
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]struct{}, workers*perWork)
+		wg   sync.WaitGroup
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]string, perWork)
+			for i := range ids {
+				ids[i] = GenerateID()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range ids {
+				seen[id] = struct{}{}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != workers*perWork {
+		t.Errorf("expected %d unique IDs, got %d", workers*perWork, len(seen))
+	}
+
+	// IDs are 128-bit hex strings
+	if id := GenerateID(); len(id) != 32 {
+		t.Errorf("expected 32 hex characters, got %q", id)
+	}
+}
+
+func TestSetIDSource_Reproducible(t *testing.T) {
+	generate := func() []string {
+		restore := SetIDSource(rand.New(rand.NewSource(42)))
+		defer restore()
+
+		span := NewSpan("GET /", "api", GenerateID())
+		return []string{span.TraceID, span.ID, GenerateID()}
+	}
+
+	first, second := generate(), generate()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("expected the same IDs with the same seed, got %v and %v", first, second)
+			break
+		}
+	}
+	if first[0] == first[1] || first[1] == first[2] {
+		t.Errorf("expected distinct IDs from one seeded source, got %v", first)
+	}
+
+	// Restoring goes back to crypto/rand
+	if id := GenerateID(); id == first[0] || len(id) != 32 {
+		t.Errorf("expected a random ID after restoring the source, got %q", id)
+	}
+}
@@ -0,0 +1,667 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// Sources of the items an alert rule selects
+const (
+	AlertSourceLogs    = "logs"
+	AlertSourceMetrics = "metrics"
+)
+
+// AlertRule fires when an aggregation of the logs or metrics it selects over
+// a sliding window compares to its threshold, e.g. more than 10 ERROR logs
+// of a service within a minute, or a p99 latency above 250ms over 5 minutes.
+type AlertRule struct {
+	Name string // Identifies the rule in notifications
+
+	Source  string            // AlertSourceLogs or AlertSourceMetrics
+	Metric  string            // Metric name to match, for metric rules
+	Service string            // Service to match; empty matches any service
+	Level   models.LogLevel   // Log level to match, for log rules; empty matches any level
+	Tags    map[string]string // Tags that must all be present with these values
+
+	Aggregation string        // count, sum, avg, min, max or a percentile such as p99
+	Window      time.Duration // Sliding window the aggregation covers
+	Comparison  string        // >, >=, <, <=, == or !=
+	Threshold   float64       // Value the aggregation is compared to
+}
+
+// Validate checks that the rule is complete and consistent
+func (r AlertRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("alert rule name is required")
+	}
+
+	switch r.Source {
+	case AlertSourceLogs:
+		if r.Aggregation != "count" {
+			return fmt.Errorf("alert rule %q: log rules only support count", r.Name)
+		}
+	case AlertSourceMetrics:
+		if r.Metric == "" {
+			return fmt.Errorf("alert rule %q: metric name is required", r.Name)
+		}
+		if r.Level != "" {
+			return fmt.Errorf("alert rule %q: level only applies to log rules", r.Name)
+		}
+	default:
+		return fmt.Errorf("alert rule %q: unknown source %q", r.Name, r.Source)
+	}
+
+	switch r.Aggregation {
+	case "count", "sum", "avg", "min", "max":
+	default:
+		if _, ok := alertPercentile(r.Aggregation); !ok {
+			return fmt.Errorf("alert rule %q: unknown aggregation %q", r.Name, r.Aggregation)
+		}
+	}
+
+	if r.Window <= 0 {
+		return fmt.Errorf("alert rule %q: window must be positive", r.Name)
+	}
+	if _, ok := alertComparisons[r.Comparison]; !ok {
+		return fmt.Errorf("alert rule %q: unknown comparison %q", r.Name, r.Comparison)
+	}
+
+	return nil
+}
+
+// ParseAlertRule parses a rule of the form
+//
+//	name: aggregation(selector) over window comparison threshold
+//
+// where the selector is "logs" or a metric name followed by space separated
+// key=value matches. service and, for logs, level are matched against those
+// fields and any other key against the tags. For example:
+//
+//	checkout-errors: count(logs service=checkout level=ERROR) over 1m > 10
+//	slow-api: p99(http.request.duration service=api) over 5m > 250
+func ParseAlertRule(s string) (AlertRule, error) {
+	var rule AlertRule
+
+	colon := strings.Index(s, ":")
+	open := strings.Index(s, "(")
+	end := strings.LastIndex(s, ")")
+	if colon < 0 || open < colon || end < open {
+		return AlertRule{}, fmt.Errorf("invalid alert rule %q, expected name: aggregation(selector) over window comparison threshold", s)
+	}
+	rule.Name = strings.TrimSpace(s[:colon])
+	rule.Aggregation = strings.ToLower(strings.TrimSpace(s[colon+1 : open]))
+
+	// Selector
+	selector := strings.Fields(s[open+1 : end])
+	if len(selector) == 0 {
+		return AlertRule{}, fmt.Errorf("alert rule %q selects nothing", rule.Name)
+	}
+	if selector[0] == AlertSourceLogs {
+		rule.Source = AlertSourceLogs
+	} else {
+		rule.Source, rule.Metric = AlertSourceMetrics, selector[0]
+	}
+	for _, match := range selector[1:] {
+		kv := strings.SplitN(match, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return AlertRule{}, fmt.Errorf("alert rule %q: invalid match %q, expected key=value", rule.Name, match)
+		}
+		switch {
+		case kv[0] == "service":
+			rule.Service = kv[1]
+		case kv[0] == "level" && rule.Source == AlertSourceLogs:
+			rule.Level = models.LogLevel(strings.ToUpper(kv[1]))
+		default:
+			if rule.Tags == nil {
+				rule.Tags = make(map[string]string)
+			}
+			rule.Tags[kv[0]] = kv[1]
+		}
+	}
+
+	// Condition
+	condition := strings.Fields(s[end+1:])
+	if len(condition) != 4 || condition[0] != "over" {
+		return AlertRule{}, fmt.Errorf("alert rule %q: expected \"over window comparison threshold\" after the selector", rule.Name)
+	}
+	window, err := time.ParseDuration(condition[1])
+	if err != nil {
+		return AlertRule{}, fmt.Errorf("alert rule %q: invalid window %q", rule.Name, condition[1])
+	}
+	threshold, err := strconv.ParseFloat(condition[3], 64)
+	if err != nil {
+		return AlertRule{}, fmt.Errorf("alert rule %q: invalid threshold %q", rule.Name, condition[3])
+	}
+	rule.Window, rule.Comparison, rule.Threshold = window, condition[2], threshold
+
+	if err := rule.Validate(); err != nil {
+		return AlertRule{}, err
+	}
+	return rule, nil
+}
+
+// matches reports whether an item with the given fields is selected by the rule
+func (r AlertRule) matches(source, metric, service string, level models.LogLevel, tags map[string]string) bool {
+	if r.Source != source || (r.Metric != "" && r.Metric != metric) {
+		return false
+	}
+	if r.Service != "" && r.Service != service {
+		return false
+	}
+	if r.Level != "" && r.Level != level {
+		return false
+	}
+	for k, v := range r.Tags {
+		if tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// alertComparisons maps the comparisons of alert rules to their operators
+var alertComparisons = map[string]func(value, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+	"==": func(v, t float64) bool { return v == t },
+	"!=": func(v, t float64) bool { return v != t },
+}
+
+// alertPercentile parses percentile aggregations such as "p99" or "p99.9"
+func alertPercentile(aggregation string) (float64, bool) {
+	if !strings.HasPrefix(aggregation, "p") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(aggregation[1:], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, false
+	}
+	return p, true
+}
+
+// AlertState is the state an alert rule transitioned to
+type AlertState string
+
+// Alert states
+const (
+	AlertStateFiring   AlertState = "firing"
+	AlertStateResolved AlertState = "resolved"
+)
+
+// Alert is the notification sent when a rule starts firing or resolves
+type Alert struct {
+	Rule        string     `json:"rule"`        // Name of the rule
+	State       AlertState `json:"state"`       // firing or resolved
+	Value       float64    `json:"value"`       // Aggregated value at the transition
+	Aggregation string     `json:"aggregation"` // Aggregation of the rule
+	Comparison  string     `json:"comparison"`  // Comparison of the rule
+	Threshold   float64    `json:"threshold"`   // Threshold of the rule
+	Window      string     `json:"window"`      // Window of the rule, e.g. 1m0s
+	Message     string     `json:"message"`     // Human readable summary
+	Timestamp   time.Time  `json:"timestamp"`   // When the transition happened
+}
+
+// Notifier delivers alert notifications
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface
+type NotifierFunc func(alert Alert) error
+
+// Notify calls f(alert)
+func (f NotifierFunc) Notify(alert Alert) error {
+	return f(alert)
+}
+
+// WebhookNotifier posts alerts as JSON to a URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting alerts to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify posts the alert and fails unless the webhook answers with a 2xx status
+func (n *WebhookNotifier) Notify(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	resp, err := n.Client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// alertQueueSize is how many notifications wait for delivery before new
+// ones are dropped
+const alertQueueSize = 100
+
+// alertWindowBuckets is how many buckets the window of a count, sum, avg, min
+// or max rule is divided into; the values observed within a bucket are
+// aggregated together
+const alertWindowBuckets = 100
+
+// maxAlertSamples caps the values a percentile rule keeps, dropping the
+// oldest ones first
+const maxAlertSamples = 10000
+
+// alertSample aggregates the values observed for a rule from at on
+type alertSample struct {
+	at    time.Time
+	count int
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// ruleState tracks the samples in a rule's window, their running count and
+// sum, and whether the rule is firing
+type ruleState struct {
+	rule    AlertRule
+	samples []alertSample
+	count   int
+	sum     float64
+	firing  bool
+
+	// resolution is how close to the last sample a value must be observed
+	// to join it, or zero to keep every value of percentile rules
+	resolution time.Duration
+}
+
+// newRuleState creates the state of a rule without samples
+func newRuleState(rule AlertRule) *ruleState {
+	state := &ruleState{rule: rule}
+	if _, ok := alertPercentile(rule.Aggregation); !ok {
+		state.resolution = rule.Window / alertWindowBuckets
+	}
+	return state
+}
+
+// AlertProcessor evaluates alert rules against the logs and metrics passed
+// to the next processor and notifies when a rule starts firing or resolves.
+// Rules are evaluated as matching items are processed and, once Start is
+// called, periodically so that rules also resolve when items stop arriving.
+// A rule notifies only on transitions, never twice in the same state.
+// Notifications are queued in the order of the transitions and sent by a
+// background worker, so a slow notifier doesn't hold up processing; when the
+// queue is full new notifications are dropped and logged.
+//
+// Windows slide over the time items are processed rather than their
+// timestamps, so late or skewed items count as current. Values observed
+// within a hundredth of the window of each other are aggregated together and
+// leave the window with the first of them, and percentile rules aggregate at
+// most the latest 10000 values.
+type AlertProcessor struct {
+	Processor
+
+	notifier Notifier
+
+	mu    sync.Mutex
+	rules []*ruleState
+	now   func() time.Time
+
+	// queue holds the notifications to send, in the order of the
+	// transitions. queueMu guards closed and sending to the queue, so that
+	// Close doesn't close the queue under a sender.
+	queue   chan func()
+	queueMu sync.RWMutex
+	closed  bool
+	worker  sync.WaitGroup
+	dropped atomic.Uint64
+
+	stop func() // Stops the periodic evaluation
+}
+
+// NewAlertProcessor creates an alert processor wrapping next that sends the
+// transitions of rules to notifier. Rules must be valid.
+func NewAlertProcessor(next Processor, rules []AlertRule, notifier Notifier) *AlertProcessor {
+	p := &AlertProcessor{
+		Processor: next,
+		notifier:  notifier,
+		now:       time.Now,
+		queue:     make(chan func(), alertQueueSize),
+	}
+	for _, rule := range rules {
+		p.rules = append(p.rules, newRuleState(rule))
+	}
+
+	p.worker.Add(1)
+	go p.work()
+	return p
+}
+
+// Dropped returns the number of notifications dropped because the queue was
+// full
+func (p *AlertProcessor) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Flush waits until every notification queued so far is sent or ctx is done
+func (p *AlertProcessor) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+
+	// Unlike notifications, the marker waits for room in the queue
+	p.queueMu.RLock()
+	if p.closed {
+		p.queueMu.RUnlock()
+		return nil
+	}
+	select {
+	case p.queue <- func() { close(done) }:
+		p.queueMu.RUnlock()
+	case <-ctx.Done():
+		p.queueMu.RUnlock()
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Start evaluates every rule each interval until Close is called
+func (p *AlertProcessor) Start(interval time.Duration) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.Evaluate()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	p.mu.Lock()
+	p.stop = func() { close(done) }
+	p.mu.Unlock()
+}
+
+// Evaluate evaluates every rule against its current window and notifies the
+// transitions
+func (p *AlertProcessor) Evaluate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	for _, state := range p.rules {
+		if alert, ok := state.evaluate(now); ok {
+			p.notifyLocked(alert)
+		}
+	}
+}
+
+// ProcessLog passes the log downstream and evaluates the rules selecting it
+func (p *AlertProcessor) ProcessLog(log *models.LogEntry) error {
+	if err := p.Processor.ProcessLog(log); err != nil {
+		return err
+	}
+	p.observe(AlertSourceLogs, "", log.Service, log.Level, log.Tags, 1)
+	return nil
+}
+
+// ProcessLogs passes the batch downstream and evaluates the rules selecting its logs
+func (p *AlertProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	if err := p.Processor.ProcessLogs(logs); err != nil {
+		return err
+	}
+	for _, log := range logs {
+		p.observe(AlertSourceLogs, "", log.Service, log.Level, log.Tags, 1)
+	}
+	return nil
+}
+
+// ProcessMetric passes the metric downstream and evaluates the rules selecting it
+func (p *AlertProcessor) ProcessMetric(metric *models.Metric) error {
+	if err := p.Processor.ProcessMetric(metric); err != nil {
+		return err
+	}
+	p.observe(AlertSourceMetrics, metric.Name, metric.Service, "", metric.Tags, metric.Value)
+	return nil
+}
+
+// ProcessMetrics passes the batch downstream and evaluates the rules selecting its metrics
+func (p *AlertProcessor) ProcessMetrics(metrics []*models.Metric) error {
+	if err := p.Processor.ProcessMetrics(metrics); err != nil {
+		return err
+	}
+	for _, metric := range metrics {
+		p.observe(AlertSourceMetrics, metric.Name, metric.Service, "", metric.Tags, metric.Value)
+	}
+	return nil
+}
+
+// ProcessHistogram passes the histogram downstream and evaluates the rules
+// selecting it with its value
+func (p *AlertProcessor) ProcessHistogram(histogram *models.HistogramMetric) error {
+	if err := p.Processor.ProcessHistogram(histogram); err != nil {
+		return err
+	}
+	p.observe(AlertSourceMetrics, histogram.Name, histogram.Service, "", histogram.Tags, histogram.Value)
+	return nil
+}
+
+// Close stops the periodic evaluation, waits for the queued notifications to
+// be sent and closes the next processor
+func (p *AlertProcessor) Close() error {
+	p.mu.Lock()
+	if p.stop != nil {
+		p.stop()
+		p.stop = nil
+	}
+	p.mu.Unlock()
+
+	p.queueMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.queueMu.Unlock()
+
+	p.worker.Wait()
+	return p.Processor.Close()
+}
+
+// observe records a value for every rule selecting the item and evaluates them
+func (p *AlertProcessor) observe(source, metric, service string, level models.LogLevel, tags map[string]string, value float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	for _, state := range p.rules {
+		if !state.rule.matches(source, metric, service, level, tags) {
+			continue
+		}
+		state.add(now, value)
+		if alert, ok := state.evaluate(now); ok {
+			p.notifyLocked(alert)
+		}
+	}
+}
+
+// notifyLocked queues the alert for the worker without waiting, so that the
+// notifier is never called under p.mu. The caller must hold p.mu, which
+// keeps the queue in the order of the transitions.
+func (p *AlertProcessor) notifyLocked(alert Alert) {
+	if p.notifier == nil {
+		return
+	}
+
+	p.queueMu.RLock()
+	defer p.queueMu.RUnlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- func() { p.send(alert) }:
+	default:
+		p.dropped.Add(1)
+		log.Printf("Alert queue full, dropping alert %s (%s)", alert.Rule, alert.State)
+	}
+}
+
+// send delivers an alert, logging failures
+func (p *AlertProcessor) send(alert Alert) {
+	if err := p.notifier.Notify(alert); err != nil {
+		log.Printf("Error sending alert %s (%s): %v", alert.Rule, alert.State, err)
+	}
+}
+
+// work runs the queued notifications until the queue is closed
+func (p *AlertProcessor) work() {
+	defer p.worker.Done()
+
+	for notify := range p.queue {
+		notify()
+	}
+}
+
+// add records a value observed at now, joining the last sample if it is
+// within the resolution
+func (s *ruleState) add(now time.Time, value float64) {
+	s.count++
+	s.sum += value
+
+	if n := len(s.samples); n > 0 && s.resolution > 0 && now.Sub(s.samples[n-1].at) < s.resolution {
+		last := &s.samples[n-1]
+		last.count++
+		last.sum += value
+		last.min = math.Min(last.min, value)
+		last.max = math.Max(last.max, value)
+		return
+	}
+
+	s.samples = append(s.samples, alertSample{at: now, count: 1, sum: value, min: value, max: value})
+	if len(s.samples) > maxAlertSamples {
+		s.dropOldest()
+	}
+}
+
+// dropOldest removes the oldest sample from the window
+func (s *ruleState) dropOldest() {
+	s.count -= s.samples[0].count
+	s.sum -= s.samples[0].sum
+	s.samples = s.samples[1:]
+	if s.count == 0 {
+		s.sum = 0 // Don't carry rounding errors into the next values
+	}
+}
+
+// evaluate drops the samples that left the window, aggregates the rest and
+// returns an alert if the rule changed state
+func (s *ruleState) evaluate(now time.Time) (Alert, bool) {
+	cutoff := now.Add(-s.rule.Window)
+	for len(s.samples) > 0 && !s.samples[0].at.After(cutoff) {
+		s.dropOldest()
+	}
+
+	// Rules without data never fire, except on counts and sums of zero
+	value, ok := s.aggregate()
+	firing := ok && alertComparisons[s.rule.Comparison](value, s.rule.Threshold)
+	if firing == s.firing {
+		return Alert{}, false
+	}
+	s.firing = firing
+
+	alert := Alert{
+		Rule:        s.rule.Name,
+		State:       AlertStateResolved,
+		Value:       value,
+		Aggregation: s.rule.Aggregation,
+		Comparison:  s.rule.Comparison,
+		Threshold:   s.rule.Threshold,
+		Window:      s.rule.Window.String(),
+		Timestamp:   now,
+	}
+	if firing {
+		alert.State = AlertStateFiring
+	}
+	alert.Message = fmt.Sprintf("%s is %s: %s over %s is %g (%s %g)",
+		s.rule.Name, alert.State, s.rule.Aggregation, s.rule.Window, value, s.rule.Comparison, s.rule.Threshold)
+
+	return alert, true
+}
+
+// aggregate applies the rule's aggregation to the samples in the window. It
+// reports false when the aggregation is undefined without samples.
+func (s *ruleState) aggregate() (float64, bool) {
+	switch s.rule.Aggregation {
+	case "count":
+		return float64(s.count), true
+	case "sum":
+		return s.sum, true
+	}
+
+	if s.count == 0 {
+		return 0, false
+	}
+
+	switch s.rule.Aggregation {
+	case "avg":
+		return s.sum / float64(s.count), true
+	case "min":
+		min := s.samples[0].min
+		for _, sample := range s.samples[1:] {
+			min = math.Min(min, sample.min)
+		}
+		return min, true
+	case "max":
+		max := s.samples[0].max
+		for _, sample := range s.samples[1:] {
+			max = math.Max(max, sample.max)
+		}
+		return max, true
+	}
+
+	// Percentile rules keep one value per sample
+	values := make([]float64, len(s.samples))
+	for i, sample := range s.samples {
+		values[i] = sample.sum
+	}
+	sort.Float64s(values)
+
+	// Nearest-rank percentile
+	percentile, _ := alertPercentile(s.rule.Aggregation)
+	rank := int(math.Ceil(percentile/100*float64(len(values)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return values[rank], true
+}
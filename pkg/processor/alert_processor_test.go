@@ -0,0 +1,287 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// newTestAlertProcessor returns an alert processor with a controllable clock
+// that records its notifications
+func newTestAlertProcessor(rules ...AlertRule) (*AlertProcessor, *[]Alert, *time.Time) {
+	var alerts []Alert
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := NewAlertProcessor(&recordingProcessor{}, rules, NotifierFunc(func(alert Alert) error {
+		alerts = append(alerts, alert)
+		return nil
+	}))
+	p.now = func() time.Time { return now }
+	return p, &alerts, &now
+}
+
+// flushAlerts waits for the notifications queued by p to be sent
+func flushAlerts(t *testing.T, p *AlertProcessor) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Flush(ctx); err != nil {
+		t.Fatalf("failed to flush alerts: %v", err)
+	}
+}
+
+func TestParseAlertRule(t *testing.T) {
+	rule, err := ParseAlertRule("checkout-errors: count(logs service=checkout level=error region=eu) over 1m > 10")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if rule.Name != "checkout-errors" || rule.Source != AlertSourceLogs || rule.Service != "checkout" || rule.Level != models.LogLevelError {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+	if rule.Tags["region"] != "eu" || rule.Aggregation != "count" || rule.Window != time.Minute || rule.Comparison != ">" || rule.Threshold != 10 {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+
+	rule, err = ParseAlertRule("slow-api: P99(http.request.duration service=api) over 5m >= 250.5")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if rule.Source != AlertSourceMetrics || rule.Metric != "http.request.duration" || rule.Aggregation != "p99" || rule.Threshold != 250.5 {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"no-selector: count over 1m > 1",
+		"empty: count() over 1m > 1",
+		"sum-logs: sum(logs) over 1m > 1",
+		"bad-agg: median(latency) over 1m > 1",
+		"bad-window: count(logs) over soon > 1",
+		"bad-op: count(logs) over 1m ~ 1",
+		"bad-threshold: count(logs) over 1m > many",
+		"bad-match: count(logs service) over 1m > 1",
+		": count(logs) over 1m > 1",
+	} {
+		if _, err := ParseAlertRule(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestAlertProcessor_LogCount(t *testing.T) {
+	p, alerts, now := newTestAlertProcessor(AlertRule{
+		Name: "checkout-errors", Source: AlertSourceLogs, Service: "checkout", Level: models.LogLevelError,
+		Aggregation: "count", Window: time.Minute, Comparison: ">", Threshold: 2,
+	})
+
+	// Logs of other services and levels are not counted
+	for _, log := range []*models.LogEntry{
+		models.NewLogEntry("checkout", "card declined", models.LogLevelError),
+		models.NewLogEntry("checkout", "order placed", models.LogLevelInfo),
+		models.NewLogEntry("billing", "timeout", models.LogLevelError),
+		models.NewLogEntry("checkout", "card declined", models.LogLevelError),
+	} {
+		if err := p.ProcessLog(log); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	flushAlerts(t, p)
+	if len(*alerts) != 0 {
+		t.Fatalf("expected no alerts at 2 errors, got %+v", *alerts)
+	}
+
+	// The third error fires the rule, further errors do not fire it again
+	for i := 0; i < 3; i++ {
+		p.ProcessLog(models.NewLogEntry("checkout", "card declined", models.LogLevelError))
+	}
+	flushAlerts(t, p)
+	if len(*alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %+v", *alerts)
+	}
+	firing := (*alerts)[0]
+	if firing.Rule != "checkout-errors" || firing.State != AlertStateFiring || firing.Value != 3 || firing.Threshold != 2 {
+		t.Errorf("unexpected alert: %+v", firing)
+	}
+
+	// Once the errors leave the window the rule resolves on evaluation
+	*now = now.Add(2 * time.Minute)
+	p.Evaluate()
+	p.Evaluate()
+	flushAlerts(t, p)
+	if len(*alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %+v", *alerts)
+	}
+	if resolved := (*alerts)[1]; resolved.State != AlertStateResolved || resolved.Value != 0 {
+		t.Errorf("expected the rule to resolve at 0, got %+v", resolved)
+	}
+}
+
+func TestAlertProcessor_MetricPercentile(t *testing.T) {
+	p, alerts, now := newTestAlertProcessor(AlertRule{
+		Name: "slow-api", Source: AlertSourceMetrics, Metric: "http.request.duration", Service: "api",
+		Tags: map[string]string{"route": "/checkout"}, Aggregation: "p90", Window: 5 * time.Minute, Comparison: ">", Threshold: 250,
+	})
+
+	metric := func(value float64, route string) *models.Metric {
+		m := models.NewMetric("http.request.duration", value, models.MetricTypeGauge, "api")
+		m.AddTag("route", route)
+		return m
+	}
+
+	batch := []*models.Metric{metric(1000, "/health")}
+	for i := 1; i <= 10; i++ {
+		batch = append(batch, metric(float64(i*20), "/checkout"))
+	}
+	if err := p.ProcessMetrics(batch); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	flushAlerts(t, p)
+	if len(*alerts) != 0 {
+		t.Fatalf("expected no alerts with a p90 of 180, got %+v", *alerts)
+	}
+
+	p.ProcessMetric(metric(900, "/checkout"))
+	p.ProcessMetric(metric(950, "/checkout"))
+	flushAlerts(t, p)
+	if len(*alerts) != 1 || (*alerts)[0].State != AlertStateFiring || (*alerts)[0].Value != 900 {
+		t.Fatalf("expected the rule to fire at 900, got %+v", *alerts)
+	}
+
+	// Without samples a percentile is undefined and the rule resolves
+	*now = now.Add(10 * time.Minute)
+	p.Evaluate()
+	flushAlerts(t, p)
+	if len(*alerts) != 2 || (*alerts)[1].State != AlertStateResolved {
+		t.Errorf("expected the rule to resolve, got %+v", *alerts)
+	}
+}
+
+func TestAlertProcessor_SkipsFailedItems(t *testing.T) {
+	var alerts []Alert
+	p := NewAlertProcessor(&failingProcessor{}, []AlertRule{{
+		Name: "errors", Source: AlertSourceLogs, Aggregation: "count", Window: time.Minute, Comparison: ">=", Threshold: 1,
+	}}, NotifierFunc(func(alert Alert) error {
+		alerts = append(alerts, alert)
+		return nil
+	}))
+
+	if err := p.ProcessLog(models.NewLogEntry("api", "boom", models.LogLevelError)); err == nil {
+		t.Fatal("expected the downstream error")
+	}
+	flushAlerts(t, p)
+	if len(alerts) != 0 {
+		t.Errorf("expected logs that failed downstream not to count, got %+v", alerts)
+	}
+}
+
+func TestAlertProcessor_SlowNotifier(t *testing.T) {
+	release := make(chan struct{})
+	sent := make(chan Alert, 10)
+	p := NewAlertProcessor(&recordingProcessor{}, []AlertRule{{
+		Name: "errors", Source: AlertSourceLogs, Aggregation: "count", Window: time.Minute, Comparison: ">=", Threshold: 1,
+	}}, NotifierFunc(func(alert Alert) error {
+		<-release
+		sent <- alert
+		return nil
+	}))
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	// Processing goes on while the notifier is stuck
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.ProcessLog(models.NewLogEntry("api", "boom", models.LogLevelError))
+		now = now.Add(2 * time.Minute)
+		p.Evaluate()
+		p.ProcessLog(models.NewLogEntry("api", "boom", models.LogLevelError))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected processing not to wait for the notifier")
+	}
+
+	// The notifications are sent in the order of the transitions
+	close(release)
+	if err := p.Close(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	close(sent)
+	var states []AlertState
+	for alert := range sent {
+		states = append(states, alert.State)
+	}
+	if len(states) != 3 || states[0] != AlertStateFiring || states[1] != AlertStateResolved || states[2] != AlertStateFiring {
+		t.Errorf("expected firing, resolved and firing, got %v", states)
+	}
+}
+
+func TestAlertProcessor_BoundedWindow(t *testing.T) {
+	p, alerts, now := newTestAlertProcessor(
+		AlertRule{Name: "errors", Source: AlertSourceLogs, Aggregation: "count", Window: 30 * time.Second, Comparison: ">", Threshold: 50000},
+		AlertRule{Name: "slow", Source: AlertSourceMetrics, Metric: "latency", Aggregation: "p50", Window: time.Minute, Comparison: ">", Threshold: 1e9},
+	)
+
+	// A log and a metric every millisecond for 30 seconds
+	for i := 0; i < 30000; i++ {
+		p.ProcessLog(models.NewLogEntry("api", "boom", models.LogLevelError))
+		p.ProcessMetric(models.NewMetric("latency", float64(i), models.MetricTypeGauge, "api"))
+		*now = now.Add(time.Millisecond)
+	}
+
+	count, percentile := p.rules[0], p.rules[1]
+	if len(count.samples) > alertWindowBuckets+1 || count.count != 30000 {
+		t.Errorf("expected 30000 logs in at most %d samples, got %d in %d", alertWindowBuckets+1, count.count, len(count.samples))
+	}
+	if len(percentile.samples) != maxAlertSamples {
+		t.Errorf("expected the latest %d values, got %d", maxAlertSamples, len(percentile.samples))
+	}
+	if value, _ := percentile.aggregate(); value != 24999 {
+		t.Errorf("expected the median of the latest values to be 24999, got %g", value)
+	}
+
+	// Another 30 seconds later the first logs left the window
+	for i := 0; i < 30000; i++ {
+		p.ProcessLog(models.NewLogEntry("api", "boom", models.LogLevelError))
+		*now = now.Add(time.Millisecond)
+	}
+	p.Evaluate()
+	if count.count < 30000-30000/alertWindowBuckets || count.count > 30000 {
+		t.Errorf("expected about 30000 logs in the window, got %d", count.count)
+	}
+	flushAlerts(t, p)
+	if len(*alerts) != 0 {
+		t.Errorf("expected no alerts, got %+v", *alerts)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("failed to decode alert: %v", err)
+		}
+		received <- alert
+		if alert.State == AlertStateResolved {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL)
+	if err := notifier.Notify(Alert{Rule: "errors", State: AlertStateFiring, Value: 3}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if alert := <-received; alert.Rule != "errors" || alert.State != AlertStateFiring || alert.Value != 3 {
+		t.Errorf("unexpected alert: %+v", alert)
+	}
+
+	if err := notifier.Notify(Alert{Rule: "errors", State: AlertStateResolved}); err == nil {
+		t.Error("expected an error for a failing webhook")
+	}
+}
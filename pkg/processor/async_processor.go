@@ -0,0 +1,224 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// ErrProcessorClosed is returned for items submitted to a closed processor
+var ErrProcessorClosed = errors.New("processor closed")
+
+// AsyncConfig configures an AsyncProcessor
+type AsyncConfig struct {
+	Workers      int  // Goroutines writing items downstream, at least 1
+	QueueSize    int  // Items buffered before the queue is full
+	DropWhenFull bool // Drop items when the queue is full instead of blocking
+}
+
+// AsyncProcessor decouples ingestion from the next processor: items are
+// queued and written downstream by a pool of workers, so ProcessX returns as
+// soon as an item is queued. When the queue is full ProcessX blocks until
+// there is room, or drops the item if DropWhenFull is set. Errors of the next
+// processor are logged and counted since nobody is waiting for them.
+//
+// Queries pass through and don't see queued items. Flush waits for the
+// queue to drain, and Close drains it before closing the next processor.
+type AsyncProcessor struct {
+	Processor
+
+	dropWhenFull bool
+	queue        chan func() error
+	workers      sync.WaitGroup
+
+	// mu guards closed and sending to the queue, so that Close doesn't close
+	// the queue under a sender
+	mu     sync.RWMutex
+	closed bool
+
+	// pendingMu guards pending, the number of items queued or being written,
+	// and idle, which is closed when pending drops to zero
+	pendingMu sync.Mutex
+	pending   int
+	idle      chan struct{}
+
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// NewAsyncProcessor creates an asynchronous processor writing to next and
+// starts its workers
+func NewAsyncProcessor(next Processor, config AsyncConfig) *AsyncProcessor {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.QueueSize < 0 {
+		config.QueueSize = 0
+	}
+
+	p := &AsyncProcessor{
+		Processor:    next,
+		dropWhenFull: config.DropWhenFull,
+		queue:        make(chan func() error, config.QueueSize),
+		idle:         make(chan struct{}),
+	}
+	close(p.idle)
+
+	for i := 0; i < config.Workers; i++ {
+		p.workers.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Dropped returns the number of items dropped because the queue was full
+func (p *AsyncProcessor) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Failed returns the number of items the next processor failed to process
+func (p *AsyncProcessor) Failed() uint64 {
+	return p.failed.Load()
+}
+
+// Pending returns the number of items queued or being written downstream
+func (p *AsyncProcessor) Pending() int {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+	return p.pending
+}
+
+// ProcessLog queues the log
+func (p *AsyncProcessor) ProcessLog(log *models.LogEntry) error {
+	return p.enqueue(func() error { return p.Processor.ProcessLog(log) })
+}
+
+// ProcessLogs queues the batch, which is written downstream as a whole
+func (p *AsyncProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	return p.enqueue(func() error { return p.Processor.ProcessLogs(logs) })
+}
+
+// ProcessMetric queues the metric
+func (p *AsyncProcessor) ProcessMetric(metric *models.Metric) error {
+	return p.enqueue(func() error { return p.Processor.ProcessMetric(metric) })
+}
+
+// ProcessMetrics queues the batch, which is written downstream as a whole
+func (p *AsyncProcessor) ProcessMetrics(metrics []*models.Metric) error {
+	return p.enqueue(func() error { return p.Processor.ProcessMetrics(metrics) })
+}
+
+// ProcessHistogram queues the histogram
+func (p *AsyncProcessor) ProcessHistogram(histogram *models.HistogramMetric) error {
+	return p.enqueue(func() error { return p.Processor.ProcessHistogram(histogram) })
+}
+
+// ProcessSpan queues the span
+func (p *AsyncProcessor) ProcessSpan(span *models.Span) error {
+	return p.enqueue(func() error { return p.Processor.ProcessSpan(span) })
+}
+
+// ProcessTrace queues the trace
+func (p *AsyncProcessor) ProcessTrace(trace *models.Trace) error {
+	return p.enqueue(func() error { return p.Processor.ProcessTrace(trace) })
+}
+
+// GetStats adds the queue length and the dropped and failed items to the
+// statistics of the next processor
+func (p *AsyncProcessor) GetStats(query *models.QueryParams) (*models.Stats, error) {
+	stats, err := p.Processor.GetStats(query)
+	if err != nil {
+		return nil, err
+	}
+	stats.AsyncStats = &models.AsyncStats{
+		Pending: p.Pending(),
+		Dropped: p.Dropped(),
+		Failed:  p.Failed(),
+	}
+	return stats, nil
+}
+
+// Flush waits until every item queued so far is written downstream or ctx
+// is done
+func (p *AsyncProcessor) Flush(ctx context.Context) error {
+	p.pendingMu.Lock()
+	idle := p.idle
+	p.pendingMu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting items, waits for the queued ones to be written and
+// closes the next processor
+func (p *AsyncProcessor) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+
+	p.workers.Wait()
+	return p.Processor.Close()
+}
+
+// enqueue queues an item, blocking or dropping it when the queue is full
+func (p *AsyncProcessor) enqueue(item func() error) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrProcessorClosed
+	}
+
+	p.addPending(1)
+	if p.dropWhenFull {
+		select {
+		case p.queue <- item:
+		default:
+			p.addPending(-1)
+			p.dropped.Add(1)
+		}
+		return nil
+	}
+	p.queue <- item
+	return nil
+}
+
+// work writes queued items downstream until the queue is closed
+func (p *AsyncProcessor) work() {
+	defer p.workers.Done()
+
+	for item := range p.queue {
+		if err := item(); err != nil {
+			p.failed.Add(1)
+			log.Printf("Asynchronous write failed: %v", err)
+		}
+		p.addPending(-1)
+	}
+}
+
+// addPending adjusts the number of pending items by delta
+func (p *AsyncProcessor) addPending(delta int) {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+
+	if p.pending == 0 && delta > 0 {
+		p.idle = make(chan struct{})
+	}
+	p.pending += delta
+	if p.pending == 0 {
+		close(p.idle)
+	}
+}
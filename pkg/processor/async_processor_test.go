@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// gatedProcessor records logs once the gate lets them through, signalling
+// on started when it begins processing one
+type gatedProcessor struct {
+	recordingProcessor
+
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func newGatedProcessor() *gatedProcessor {
+	return &gatedProcessor{started: make(chan struct{}, 100), gate: make(chan struct{})}
+}
+
+func (p *gatedProcessor) ProcessLog(log *models.LogEntry) error {
+	p.started <- struct{}{}
+	<-p.gate
+	return p.recordingProcessor.ProcessLog(log)
+}
+
+func (p *gatedProcessor) GetStats(query *models.QueryParams) (*models.Stats, error) {
+	stats := models.NewStats()
+	stats.Logs.Total = int64(len(p.logs))
+	return stats, nil
+}
+
+func TestAsyncProcessor_WritesInBackground(t *testing.T) {
+	next := newGatedProcessor()
+	p := NewAsyncProcessor(next, AsyncConfig{Workers: 2, QueueSize: 10})
+
+	// Ingestion returns while the downstream write is still blocked
+	for i := 0; i < 3; i++ {
+		if err := p.ProcessLog(models.NewLogEntry("api", "hello", models.LogLevelInfo)); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	if p.Pending() != 3 {
+		t.Errorf("expected 3 pending items, got %d", p.Pending())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the flush to hit the deadline, got: %v", err)
+	}
+
+	close(next.gate)
+	if err := p.Flush(context.Background()); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(next.logs) != 3 || p.Pending() != 0 {
+		t.Errorf("expected 3 logs written and none pending, got %d and %d", len(next.logs), p.Pending())
+	}
+
+	stats, err := p.GetStats(&models.QueryParams{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if stats.Logs.Total != 3 || stats.AsyncStats == nil || stats.Pending != 0 || stats.Dropped != 0 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+}
+
+func TestAsyncProcessor_DropWhenFull(t *testing.T) {
+	next := newGatedProcessor()
+	p := NewAsyncProcessor(next, AsyncConfig{Workers: 1, QueueSize: 1, DropWhenFull: true})
+
+	// The worker holds the first log and the queue the second
+	p.ProcessLog(models.NewLogEntry("api", "first", models.LogLevelInfo))
+	<-next.started
+	p.ProcessLog(models.NewLogEntry("api", "second", models.LogLevelInfo))
+
+	if err := p.ProcessLog(models.NewLogEntry("api", "third", models.LogLevelInfo)); err != nil {
+		t.Fatalf("expected dropped items not to fail ingestion, got: %v", err)
+	}
+	if p.Dropped() != 1 {
+		t.Errorf("expected 1 dropped item, got %d", p.Dropped())
+	}
+
+	close(next.gate)
+	if err := p.Close(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(next.logs) != 2 || next.logs[0].Message != "first" || next.logs[1].Message != "second" {
+		t.Errorf("expected the first two logs to be written, got %d", len(next.logs))
+	}
+}
+
+func TestAsyncProcessor_BlocksWhenFull(t *testing.T) {
+	next := newGatedProcessor()
+	p := NewAsyncProcessor(next, AsyncConfig{Workers: 1, QueueSize: 1})
+
+	p.ProcessLog(models.NewLogEntry("api", "first", models.LogLevelInfo))
+	<-next.started
+	p.ProcessLog(models.NewLogEntry("api", "second", models.LogLevelInfo))
+
+	done := make(chan struct{})
+	go func() {
+		p.ProcessLog(models.NewLogEntry("api", "third", models.LogLevelInfo))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected ingestion to block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(next.gate)
+	<-done
+	p.Close()
+	if len(next.logs) != 3 || p.Dropped() != 0 {
+		t.Errorf("expected every log to be written, got %d with %d dropped", len(next.logs), p.Dropped())
+	}
+}
+
+func TestAsyncProcessor_Close(t *testing.T) {
+	p := NewAsyncProcessor(&failingProcessor{}, AsyncConfig{Workers: 1, QueueSize: 10})
+
+	// Downstream errors are counted rather than returned
+	if err := p.ProcessLog(models.NewLogEntry("api", "hello", models.LogLevelInfo)); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if p.Failed() != 1 {
+		t.Errorf("expected 1 failed item, got %d", p.Failed())
+	}
+
+	if err := p.ProcessLog(models.NewLogEntry("api", "late", models.LogLevelInfo)); !errors.Is(err, ErrProcessorClosed) {
+		t.Errorf("expected ErrProcessorClosed after Close, got: %v", err)
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("expected closing twice to succeed, got: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package processor
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// Event is an item that was processed successfully. Exactly one of its
+// fields is set.
+type Event struct {
+	Log    *models.LogEntry
+	Metric *models.Metric
+	Span   *models.Span
+	Trace  *models.Trace
+}
+
+// Hub fans processed items out to its subscribers. Publishing never blocks
+// ingestion: a subscriber whose buffer is full misses the event.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+	dropped     atomic.Uint64
+}
+
+// NewHub creates a hub without subscribers
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving every event published from now on,
+// buffering up to buffer events, and a function that unsubscribes and closes
+// the channel
+func (h *Hub) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Publish sends event to every subscriber with room in its buffer
+func (h *Hub) Publish(event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			h.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribers returns the number of current subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+// Dropped returns the number of events subscribers missed because their
+// buffer was full
+func (h *Hub) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// BroadcastProcessor publishes items to a Hub once the wrapped processor has
+// processed them successfully, so subscribers see exactly what was stored
+type BroadcastProcessor struct {
+	Processor
+
+	hub *Hub
+}
+
+// NewBroadcastProcessor creates a processor wrapping next that publishes
+// processed logs, metrics, spans and traces to hub
+func NewBroadcastProcessor(next Processor, hub *Hub) *BroadcastProcessor {
+	return &BroadcastProcessor{
+		Processor: next,
+		hub:       hub,
+	}
+}
+
+// ProcessLog processes the log and publishes it
+func (p *BroadcastProcessor) ProcessLog(log *models.LogEntry) error {
+	if err := p.Processor.ProcessLog(log); err != nil {
+		return err
+	}
+	p.hub.Publish(Event{Log: log})
+	return nil
+}
+
+// ProcessLogs processes the batch and publishes each of its logs
+func (p *BroadcastProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	if err := p.Processor.ProcessLogs(logs); err != nil {
+		return err
+	}
+	for _, log := range logs {
+		p.hub.Publish(Event{Log: log})
+	}
+	return nil
+}
+
+// ProcessMetric processes the metric and publishes it
+func (p *BroadcastProcessor) ProcessMetric(metric *models.Metric) error {
+	if err := p.Processor.ProcessMetric(metric); err != nil {
+		return err
+	}
+	p.hub.Publish(Event{Metric: metric})
+	return nil
+}
+
+// ProcessMetrics processes the batch and publishes each of its metrics
+func (p *BroadcastProcessor) ProcessMetrics(metrics []*models.Metric) error {
+	if err := p.Processor.ProcessMetrics(metrics); err != nil {
+		return err
+	}
+	for _, metric := range metrics {
+		p.hub.Publish(Event{Metric: metric})
+	}
+	return nil
+}
+
+// ProcessHistogram processes the histogram and publishes it as a metric
+func (p *BroadcastProcessor) ProcessHistogram(histogram *models.HistogramMetric) error {
+	if err := p.Processor.ProcessHistogram(histogram); err != nil {
+		return err
+	}
+	p.hub.Publish(Event{Metric: &histogram.Metric})
+	return nil
+}
+
+// ProcessSpan processes the span and publishes it
+func (p *BroadcastProcessor) ProcessSpan(span *models.Span) error {
+	if err := p.Processor.ProcessSpan(span); err != nil {
+		return err
+	}
+	p.hub.Publish(Event{Span: span})
+	return nil
+}
+
+// ProcessTrace processes the trace and publishes it
+func (p *BroadcastProcessor) ProcessTrace(trace *models.Trace) error {
+	if err := p.Processor.ProcessTrace(trace); err != nil {
+		return err
+	}
+	p.hub.Publish(Event{Trace: trace})
+	return nil
+}
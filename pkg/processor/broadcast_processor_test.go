@@ -0,0 +1,121 @@
+package processor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// failingProcessor rejects every log
+type failingProcessor struct {
+	recordingProcessor
+}
+
+func (p *failingProcessor) ProcessLog(log *models.LogEntry) error {
+	return errors.New("storage unavailable")
+}
+
+func TestHub_PublishSubscribe(t *testing.T) {
+	hub := NewHub()
+	first, unsubscribeFirst := hub.Subscribe(1)
+	second, unsubscribeSecond := hub.Subscribe(1)
+	defer unsubscribeSecond()
+
+	if hub.Subscribers() != 2 {
+		t.Fatalf("expected 2 subscribers, got %d", hub.Subscribers())
+	}
+
+	log := models.NewLogEntry("api", "hello", models.LogLevelInfo)
+	hub.Publish(Event{Log: log})
+	for _, ch := range []<-chan Event{first, second} {
+		if event := <-ch; event.Log != log {
+			t.Errorf("expected every subscriber to receive the log, got %+v", event)
+		}
+	}
+
+	// Unsubscribing closes the channel and is safe to repeat
+	unsubscribeFirst()
+	unsubscribeFirst()
+	if _, ok := <-first; ok {
+		t.Errorf("expected the channel to be closed")
+	}
+	if hub.Subscribers() != 1 {
+		t.Errorf("expected 1 subscriber, got %d", hub.Subscribers())
+	}
+
+	// A full subscriber misses events instead of blocking the publisher
+	hub.Publish(Event{Log: log})
+	hub.Publish(Event{Log: log})
+	if hub.Dropped() != 1 {
+		t.Errorf("expected 1 dropped event, got %d", hub.Dropped())
+	}
+}
+
+func TestBroadcastProcessor(t *testing.T) {
+	hub := NewHub()
+	events, unsubscribe := hub.Subscribe(10)
+	defer unsubscribe()
+
+	next := &recordingProcessor{}
+	p := NewBroadcastProcessor(next, hub)
+
+	metrics := []*models.Metric{
+		models.NewMetric("cpu", 1, models.MetricTypeGauge, "api"),
+		models.NewMetric("mem", 2, models.MetricTypeGauge, "api"),
+	}
+	if err := p.ProcessMetrics(metrics); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	span := models.NewSpan("GET /", "api", "trace-1")
+	if err := p.ProcessSpan(span); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if event := <-events; event.Metric != metrics[0] {
+		t.Errorf("expected the first metric, got %+v", event)
+	}
+	<-events
+	if event := <-events; event.Span != span {
+		t.Errorf("expected the span, got %+v", event)
+	}
+
+	// Items the wrapped processor rejects are not published
+	failing := NewBroadcastProcessor(&failingProcessor{}, hub)
+	if err := failing.ProcessLog(models.NewLogEntry("api", "hello", models.LogLevelInfo)); err == nil {
+		t.Errorf("expected the processing error to be returned")
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no event for a failed log, got %d", len(events))
+	}
+}
+
+func TestBroadcastProcessor_Histogram(t *testing.T) {
+	hub := NewHub()
+	events, unsubscribe := hub.Subscribe(10)
+	defer unsubscribe()
+
+	next := &recordingProcessor{}
+	p := NewBroadcastProcessor(next, hub)
+
+	histogram, err := models.NewHistogramMetric("latency", "api", []float64{1, 5})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	if err := p.ProcessHistogram(histogram); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(next.histograms) != 1 || next.histograms[0] != histogram {
+		t.Errorf("expected the histogram to be passed downstream, got %v", next.histograms)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if event := <-events; event.Metric != &histogram.Metric {
+		t.Errorf("expected the histogram to be published as a metric, got %+v", event)
+	}
+}
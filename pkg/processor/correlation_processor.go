@@ -0,0 +1,215 @@
+package processor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// spanWindow records when a span of a trace was active for a service
+type spanWindow struct {
+	traceID string
+	spanID  string
+	start   time.Time
+	end     time.Time
+	seenAt  time.Time
+}
+
+// spanQueue holds the spans of a service in the order they were seen.
+// Expired spans are dropped from the front by advancing head, so pruning
+// costs nothing for spans that are kept.
+type spanQueue struct {
+	windows []spanWindow
+	head    int
+}
+
+// live returns the spans that haven't been pruned
+func (q *spanQueue) live() []spanWindow {
+	return q.windows[q.head:]
+}
+
+// prune drops the spans seen at or before cutoff from the front of the queue,
+// compacting it once at least half of it has expired
+func (q *spanQueue) prune(cutoff time.Time) {
+	for q.head < len(q.windows) && !q.windows[q.head].seenAt.After(cutoff) {
+		q.windows[q.head] = spanWindow{}
+		q.head++
+	}
+	if q.head > 0 && q.head*2 >= len(q.windows) {
+		q.windows = append(q.windows[:0], q.windows[q.head:]...)
+		q.head = 0
+	}
+}
+
+// CorrelationProcessor attaches trace context to logs that arrive without a
+// trace ID by matching them against recently seen spans of the same service.
+// Matching is best-effort: correlated logs are tagged with "trace.correlated".
+type CorrelationProcessor struct {
+	Processor
+
+	window time.Duration // Slack allowed around a span's start and end
+	ttl    time.Duration // How long spans are kept in the index
+
+	mu        sync.Mutex
+	recent    map[string]*spanQueue // Recently seen spans keyed by service
+	lastSweep time.Time             // When every service was last pruned
+	now       func() time.Time
+}
+
+// NewCorrelationProcessor creates a correlation processor wrapping next.
+// window is the slack around a span's lifetime within which a log is still
+// considered part of it; ttl bounds how long spans stay in the index.
+func NewCorrelationProcessor(next Processor, window, ttl time.Duration) *CorrelationProcessor {
+	return &CorrelationProcessor{
+		Processor: next,
+		window:    window,
+		ttl:       ttl,
+		recent:    make(map[string]*spanQueue),
+		now:       time.Now,
+	}
+}
+
+// ProcessLog correlates orphan logs before passing them downstream
+func (p *CorrelationProcessor) ProcessLog(log *models.LogEntry) error {
+	if log.TraceID == "" {
+		if match, ok := p.match(log.Service, log.Timestamp); ok {
+			log.WithTrace(match.traceID, match.spanID)
+			log.AddTag("trace.correlated", "true")
+		}
+	}
+	return p.Processor.ProcessLog(log)
+}
+
+// ProcessLogs correlates the orphan logs of the batch before passing it
+// downstream
+func (p *CorrelationProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	for _, log := range logs {
+		if log.TraceID != "" {
+			continue
+		}
+		if match, ok := p.match(log.Service, log.Timestamp); ok {
+			log.WithTrace(match.traceID, match.spanID)
+			log.AddTag("trace.correlated", "true")
+		}
+	}
+	return p.Processor.ProcessLogs(logs)
+}
+
+// ProcessSpan indexes the span and passes it downstream
+func (p *CorrelationProcessor) ProcessSpan(span *models.Span) error {
+	p.index(span)
+	return p.Processor.ProcessSpan(span)
+}
+
+// ProcessTrace indexes every span of the trace and passes it downstream
+func (p *CorrelationProcessor) ProcessTrace(trace *models.Trace) error {
+	for _, span := range trace.Spans {
+		p.index(span)
+	}
+	return p.Processor.ProcessTrace(trace)
+}
+
+// index adds a span to the in-memory index
+func (p *CorrelationProcessor) index(span *models.Span) {
+	if span == nil || span.TraceID == "" || span.Service == "" {
+		return
+	}
+
+	end := span.EndTime
+	if end.IsZero() {
+		end = span.StartTime
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	queue := p.pruneLocked(span.Service, now)
+	if queue == nil {
+		queue = &spanQueue{}
+		p.recent[span.Service] = queue
+	}
+	queue.windows = append(queue.windows, spanWindow{
+		traceID: span.TraceID,
+		spanID:  span.ID,
+		start:   span.StartTime,
+		end:     end,
+		seenAt:  now,
+	})
+}
+
+// match finds the most likely span for a log emitted by service at ts.
+// A span whose lifetime contains ts wins, preferring the shortest (innermost)
+// one; otherwise the nearest span within the window is used.
+func (p *CorrelationProcessor) match(service string, ts time.Time) (spanWindow, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	queue := p.pruneLocked(service, p.now())
+	if queue == nil {
+		return spanWindow{}, false
+	}
+
+	var (
+		best         spanWindow
+		bestContains bool
+		bestScore    time.Duration
+		found        bool
+	)
+
+	for _, w := range queue.live() {
+		contains := !ts.Before(w.start) && !ts.After(w.end)
+
+		var score time.Duration
+		if contains {
+			score = w.end.Sub(w.start)
+		} else {
+			// Distance from the span's lifetime
+			if ts.Before(w.start) {
+				score = w.start.Sub(ts)
+			} else {
+				score = ts.Sub(w.end)
+			}
+			if score > p.window {
+				continue
+			}
+		}
+
+		if !found || (contains && !bestContains) || (contains == bestContains && score < bestScore) {
+			best, bestContains, bestScore, found = w, contains, score, true
+		}
+	}
+
+	return best, found
+}
+
+// pruneLocked drops the spans of service older than the TTL and returns its
+// queue, or nil if it has none left. Every other service is pruned once per
+// TTL so that services that went quiet don't keep their spans. The caller
+// must hold p.mu.
+func (p *CorrelationProcessor) pruneLocked(service string, now time.Time) *spanQueue {
+	cutoff := now.Add(-p.ttl)
+
+	if now.Sub(p.lastSweep) >= p.ttl {
+		p.lastSweep = now
+		for other, queue := range p.recent {
+			queue.prune(cutoff)
+			if len(queue.live()) == 0 {
+				delete(p.recent, other)
+			}
+		}
+		return p.recent[service]
+	}
+
+	queue := p.recent[service]
+	if queue == nil {
+		return nil
+	}
+	queue.prune(cutoff)
+	if len(queue.live()) == 0 {
+		delete(p.recent, service)
+		return nil
+	}
+	return queue
+}
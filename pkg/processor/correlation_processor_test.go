@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func newTestSpan(id, traceID, service string, start time.Time, duration time.Duration) *models.Span {
+	return &models.Span{
+		ID:        id,
+		TraceID:   traceID,
+		Name:      "op",
+		Service:   service,
+		StartTime: start,
+		EndTime:   start.Add(duration),
+		Duration:  duration.Milliseconds(),
+		Status:    models.SpanStatusOK,
+	}
+}
+
+func TestCorrelationProcessor_AttachesActiveTrace(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewCorrelationProcessor(next, 100*time.Millisecond, time.Minute)
+
+	base := time.Now().UTC()
+	root := newTestSpan("root", "trace-1", "api", base, time.Second)
+	child := newTestSpan("child", "trace-1", "api", base.Add(200*time.Millisecond), 100*time.Millisecond)
+	trace := &models.Trace{ID: "trace-1", Spans: []*models.Span{root, child}, Root: root}
+	if err := p.ProcessTrace(trace); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// Log inside the child span's lifetime
+	log := models.NewLogEntry("api", "querying db", models.LogLevelInfo)
+	log.Timestamp = base.Add(250 * time.Millisecond)
+	if err := p.ProcessLog(log); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if log.TraceID != "trace-1" {
+		t.Errorf("expected trace ID trace-1, got %q", log.TraceID)
+	}
+	if log.SpanID != "child" {
+		t.Errorf("expected innermost span child, got %q", log.SpanID)
+	}
+	if log.Tags["trace.correlated"] != "true" {
+		t.Errorf("expected correlated tag, got %v", log.Tags)
+	}
+	if len(next.logs) != 1 {
+		t.Errorf("expected log to be passed downstream")
+	}
+}
+
+func TestCorrelationProcessor_WithinWindow(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewCorrelationProcessor(next, 100*time.Millisecond, time.Minute)
+
+	base := time.Now().UTC()
+	p.ProcessSpan(newTestSpan("s1", "trace-1", "api", base, 100*time.Millisecond))
+
+	// Just after the span ended, still within the window
+	log := models.NewLogEntry("api", "done", models.LogLevelInfo)
+	log.Timestamp = base.Add(150 * time.Millisecond)
+	p.ProcessLog(log)
+
+	if log.TraceID != "trace-1" {
+		t.Errorf("expected trace ID trace-1, got %q", log.TraceID)
+	}
+}
+
+func TestCorrelationProcessor_NoCorrelation(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewCorrelationProcessor(next, 100*time.Millisecond, time.Minute)
+
+	base := time.Now().UTC()
+	p.ProcessSpan(newTestSpan("s1", "trace-1", "api", base, 100*time.Millisecond))
+
+	testCases := []struct {
+		name string
+		log  *models.LogEntry
+	}{
+		{
+			name: "different service",
+			log:  &models.LogEntry{Service: "billing", Message: "m", Timestamp: base.Add(50 * time.Millisecond)},
+		},
+		{
+			name: "outside window",
+			log:  &models.LogEntry{Service: "api", Message: "m", Timestamp: base.Add(5 * time.Second)},
+		},
+		{
+			name: "already has trace",
+			log:  &models.LogEntry{Service: "api", Message: "m", TraceID: "other", Timestamp: base.Add(50 * time.Millisecond)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			originalTrace := tc.log.TraceID
+			p.ProcessLog(tc.log)
+			if tc.log.TraceID != originalTrace {
+				t.Errorf("expected trace ID %q, got %q", originalTrace, tc.log.TraceID)
+			}
+			if _, ok := tc.log.Tags["trace.correlated"]; ok {
+				t.Errorf("expected no correlated tag")
+			}
+		})
+	}
+}
+
+func TestCorrelationProcessor_ExpiredSpans(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewCorrelationProcessor(next, 100*time.Millisecond, time.Minute)
+
+	current := time.Now()
+	p.now = func() time.Time { return current }
+
+	base := time.Now().UTC()
+	p.ProcessSpan(newTestSpan("s1", "trace-1", "api", base, time.Second))
+
+	// Advance past the TTL
+	current = current.Add(2 * time.Minute)
+
+	log := &models.LogEntry{Service: "api", Message: "late", Timestamp: base.Add(500 * time.Millisecond)}
+	p.ProcessLog(log)
+
+	if log.TraceID != "" {
+		t.Errorf("expected expired span not to correlate, got %q", log.TraceID)
+	}
+}
+
+func TestCorrelationProcessor_PrunesFromFront(t *testing.T) {
+	p := NewCorrelationProcessor(&recordingProcessor{}, 100*time.Millisecond, time.Minute)
+
+	current := time.Now()
+	p.now = func() time.Time { return current }
+
+	base := time.Now().UTC()
+	p.ProcessSpan(newTestSpan("s1", "trace-1", "api", base, time.Second))
+	p.ProcessSpan(newTestSpan("s2", "trace-2", "worker", base, time.Second))
+
+	current = current.Add(30 * time.Second)
+	p.ProcessSpan(newTestSpan("s3", "trace-3", "api", base.Add(time.Hour), time.Second))
+
+	// Only the span seen before the TTL is dropped from the api queue, and the
+	// worker, which went quiet, is swept once the TTL has passed
+	current = current.Add(45 * time.Second)
+	log := &models.LogEntry{Service: "api", Message: "late", Timestamp: base.Add(time.Hour)}
+	p.ProcessLog(log)
+
+	if log.TraceID != "trace-3" {
+		t.Errorf("expected trace-3, got %q", log.TraceID)
+	}
+	if queue := p.recent["api"]; queue == nil || len(queue.live()) != 1 || queue.head != 0 {
+		t.Errorf("expected the api queue to hold only s3 after compaction, got %+v", queue)
+	}
+	if _, ok := p.recent["worker"]; ok {
+		t.Errorf("expected the quiet worker service to be swept")
+	}
+}
@@ -0,0 +1,177 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// DropRule matches records that should be discarded at ingestion.
+// A record matches when its service equals Service (if set) and it carries
+// every tag in Tags with the same value. A tag with an empty value only
+// matches records carrying the tag with an empty value.
+type DropRule struct {
+	Service string            // Service to match; empty matches any service
+	Tags    map[string]string // Tags that must all be present with these values
+}
+
+// ParseDropRule parses a rule of the form "[service:]key=value[,key=value...]",
+// e.g. "gateway:http.url=/health"
+func ParseDropRule(s string) (DropRule, error) {
+	var rule DropRule
+
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, ":"); i >= 0 && !strings.Contains(s[:i], "=") {
+		rule.Service = strings.TrimSpace(s[:i])
+		s = s[i+1:]
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return DropRule{}, fmt.Errorf("invalid tag match %q, expected key=value", part)
+		}
+		if rule.Tags == nil {
+			rule.Tags = make(map[string]string)
+		}
+		rule.Tags[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	if rule.Service == "" && len(rule.Tags) == 0 {
+		return DropRule{}, fmt.Errorf("drop rule %q matches everything", s)
+	}
+
+	return rule, nil
+}
+
+// Matches reports whether a record with the given service and tags matches the rule
+func (r DropRule) Matches(service string, tags map[string]string) bool {
+	if r.Service != "" && r.Service != service {
+		return false
+	}
+	for k, v := range r.Tags {
+		if value, ok := tags[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// DropRuleProcessor discards logs, metrics and spans matching any of its
+// rules before they reach the next processor. Traces are dropped when their
+// root span matches.
+type DropRuleProcessor struct {
+	Processor
+
+	rules   []DropRule
+	dropped atomic.Uint64
+}
+
+// NewDropRuleProcessor creates a drop rule processor wrapping next
+func NewDropRuleProcessor(next Processor, rules []DropRule) *DropRuleProcessor {
+	return &DropRuleProcessor{
+		Processor: next,
+		rules:     rules,
+	}
+}
+
+// Dropped returns the number of records discarded so far
+func (p *DropRuleProcessor) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// GetStats adds the records discarded so far to the statistics of the next
+// processor
+func (p *DropRuleProcessor) GetStats(query *models.QueryParams) (*models.Stats, error) {
+	stats, err := p.Processor.GetStats(query)
+	if err != nil {
+		return nil, err
+	}
+	stats.DropRuleStats = &models.DropRuleStats{RuleDropped: p.Dropped()}
+	return stats, nil
+}
+
+// ProcessLog drops matching logs and passes the rest downstream
+func (p *DropRuleProcessor) ProcessLog(log *models.LogEntry) error {
+	if p.drop(log.Service, log.Tags) {
+		return nil
+	}
+	return p.Processor.ProcessLog(log)
+}
+
+// ProcessLogs drops matching logs from the batch and passes the rest downstream
+func (p *DropRuleProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	kept := make([]*models.LogEntry, 0, len(logs))
+	for _, log := range logs {
+		if !p.drop(log.Service, log.Tags) {
+			kept = append(kept, log)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return p.Processor.ProcessLogs(kept)
+}
+
+// ProcessMetric drops matching metrics and passes the rest downstream
+func (p *DropRuleProcessor) ProcessMetric(metric *models.Metric) error {
+	if p.drop(metric.Service, metric.Tags) {
+		return nil
+	}
+	return p.Processor.ProcessMetric(metric)
+}
+
+// ProcessMetrics drops matching metrics from the batch and passes the rest downstream
+func (p *DropRuleProcessor) ProcessMetrics(metrics []*models.Metric) error {
+	kept := make([]*models.Metric, 0, len(metrics))
+	for _, metric := range metrics {
+		if !p.drop(metric.Service, metric.Tags) {
+			kept = append(kept, metric)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return p.Processor.ProcessMetrics(kept)
+}
+
+// ProcessHistogram drops matching histograms and passes the rest downstream
+func (p *DropRuleProcessor) ProcessHistogram(histogram *models.HistogramMetric) error {
+	if p.drop(histogram.Service, histogram.Tags) {
+		return nil
+	}
+	return p.Processor.ProcessHistogram(histogram)
+}
+
+// ProcessSpan drops matching spans and passes the rest downstream
+func (p *DropRuleProcessor) ProcessSpan(span *models.Span) error {
+	if p.drop(span.Service, span.Tags) {
+		return nil
+	}
+	return p.Processor.ProcessSpan(span)
+}
+
+// ProcessTrace drops traces whose root span matches and passes the rest downstream
+func (p *DropRuleProcessor) ProcessTrace(trace *models.Trace) error {
+	if trace.Root != nil && p.drop(trace.Root.Service, trace.Root.Tags) {
+		return nil
+	}
+	return p.Processor.ProcessTrace(trace)
+}
+
+// drop reports whether a record matches a rule, counting it if so
+func (p *DropRuleProcessor) drop(service string, tags map[string]string) bool {
+	for _, rule := range p.rules {
+		if rule.Matches(service, tags) {
+			p.dropped.Add(1)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func TestParseDropRule(t *testing.T) {
+	rule, err := ParseDropRule("gateway:http.url=/health,http.status_code=200")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if rule.Service != "gateway" {
+		t.Errorf("expected service gateway, got %q", rule.Service)
+	}
+	if rule.Tags["http.url"] != "/health" || rule.Tags["http.status_code"] != "200" {
+		t.Errorf("unexpected tags: %v", rule.Tags)
+	}
+
+	// Tag values may contain colons when no service is given
+	rule, err = ParseDropRule("http.url=http://lb:8080/health")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if rule.Service != "" || rule.Tags["http.url"] != "http://lb:8080/health" {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+
+	for _, invalid := range []string{"", ":", "http.url", "=x"} {
+		if _, err := ParseDropRule(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestDropRuleProcessor(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewDropRuleProcessor(next, []DropRule{
+		{Service: "gateway", Tags: map[string]string{"http.url": "/health"}},
+	})
+
+	health := models.NewSpan("GET /health", "gateway", "trace-1")
+	health.AddTag("http.url", "/health")
+	checkout := models.NewSpan("GET /checkout", "gateway", "trace-2")
+	checkout.AddTag("http.url", "/checkout")
+	otherService := models.NewSpan("GET /health", "billing", "trace-3")
+	otherService.AddTag("http.url", "/health")
+
+	for _, span := range []*models.Span{health, checkout, otherService} {
+		if err := p.ProcessSpan(span); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+
+	healthLog := models.NewLogEntry("gateway", "GET /health 200", models.LogLevelInfo)
+	healthLog.AddTag("http.url", "/health")
+	if err := p.ProcessLog(healthLog); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	metric := models.NewMetric("http.requests", 1, models.MetricTypeCounter, "gateway")
+	metric.AddTag("http.url", "/health")
+	if err := p.ProcessMetric(metric); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// Batches are filtered item by item
+	kept := models.NewMetric("http.requests", 1, models.MetricTypeCounter, "gateway")
+	kept.AddTag("http.url", "/checkout")
+	if err := p.ProcessMetrics([]*models.Metric{metric, kept}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	trace := &models.Trace{ID: "trace-1", Spans: []*models.Span{health}, Root: health}
+	if err := p.ProcessTrace(trace); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// Only non-matching spans are kept
+	if len(next.spans) != 2 || next.spans[0] != checkout || next.spans[1] != otherService {
+		t.Errorf("expected checkout and billing spans to be kept, got %d spans", len(next.spans))
+	}
+	if len(next.metrics) != 1 || next.metrics[0] != kept {
+		t.Errorf("expected only the checkout metric to be kept, got %d metrics", len(next.metrics))
+	}
+	if len(next.logs) != 0 || len(next.traces) != 0 {
+		t.Errorf("expected matching log and trace to be dropped")
+	}
+	if p.Dropped() != 5 {
+		t.Errorf("expected 5 dropped records, got %d", p.Dropped())
+	}
+}
+
+func TestDropRuleProcessor_ProcessLogs(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewDropRuleProcessor(next, []DropRule{{Service: "gateway"}})
+
+	dropped := models.NewLogEntry("gateway", "GET /health 200", models.LogLevelInfo)
+	kept := models.NewLogEntry("billing", "invoice sent", models.LogLevelInfo)
+	if err := p.ProcessLogs([]*models.LogEntry{dropped, kept}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(next.logs) != 1 || next.logs[0] != kept {
+		t.Errorf("expected only the billing log to be kept, got %d logs", len(next.logs))
+	}
+
+	// A batch dropped entirely isn't passed downstream
+	if err := p.ProcessLogs([]*models.LogEntry{dropped}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(next.logs) != 1 || p.Dropped() != 2 {
+		t.Errorf("expected 1 log kept and 2 dropped, got %d and %d", len(next.logs), p.Dropped())
+	}
+}
+
+func TestDropRuleProcessor_MissingTag(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewDropRuleProcessor(next, []DropRule{{Tags: map[string]string{"synthetic": ""}}})
+
+	untagged := models.NewLogEntry("gateway", "GET /checkout 200", models.LogLevelInfo)
+	tagged := models.NewLogEntry("gateway", "GET /health 200", models.LogLevelInfo)
+	tagged.AddTag("synthetic", "")
+	if err := p.ProcessLogs([]*models.LogEntry{untagged, tagged}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// An empty rule value doesn't match a log without the tag
+	if len(next.logs) != 1 || next.logs[0] != untagged {
+		t.Errorf("expected only the untagged log to be kept, got %d logs", len(next.logs))
+	}
+}
+
+func TestDropRuleProcessor_GetStats(t *testing.T) {
+	next := newGatedProcessor()
+	p := NewDropRuleProcessor(next, []DropRule{{Service: "gateway"}})
+
+	log := models.NewLogEntry("gateway", "GET /health 200", models.LogLevelInfo)
+	if err := p.ProcessLogs([]*models.LogEntry{log}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	stats, err := p.GetStats(&models.QueryParams{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if stats.DropRuleStats == nil || stats.RuleDropped != 1 {
+		t.Errorf("expected 1 dropped record in stats, got %+v", stats.DropRuleStats)
+	}
+}
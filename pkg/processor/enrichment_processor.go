@@ -0,0 +1,170 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// TagMapping derives a tag from another one. Items whose From tag has one of
+// the Values keys get To set to the mapped value, e.g. region eu-west-1 to
+// cloud aws. Without Values the From value is copied as is.
+type TagMapping struct {
+	From   string            // Tag to derive from
+	To     string            // Tag to set
+	Values map[string]string // Value of To for each value of From
+}
+
+// ParseTagMapping parses a mapping of the form "from>to[:value=mapped,...]",
+// e.g. "region>cloud:eu-west-1=aws,europe-west1=gcp"
+func ParseTagMapping(s string) (TagMapping, error) {
+	var mapping TagMapping
+
+	spec, values, hasValues := strings.Cut(strings.TrimSpace(s), ":")
+	from, to, ok := strings.Cut(spec, ">")
+	mapping.From, mapping.To = strings.TrimSpace(from), strings.TrimSpace(to)
+	if !ok || mapping.From == "" || mapping.To == "" {
+		return TagMapping{}, fmt.Errorf("invalid tag mapping %q, expected from>to[:value=mapped,...]", s)
+	}
+
+	if hasValues {
+		for _, part := range strings.Split(values, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			value, mapped, ok := strings.Cut(part, "=")
+			if !ok || strings.TrimSpace(value) == "" {
+				return TagMapping{}, fmt.Errorf("invalid value mapping %q, expected value=mapped", part)
+			}
+			if mapping.Values == nil {
+				mapping.Values = make(map[string]string)
+			}
+			mapping.Values[strings.TrimSpace(value)] = strings.TrimSpace(mapped)
+		}
+	}
+
+	return mapping, nil
+}
+
+// EnrichmentConfig holds the defaults an EnrichmentProcessor fills in
+type EnrichmentConfig struct {
+	Env      string            // Env of items without one
+	Host     string            // Host of items without one
+	Tags     map[string]string // Tags added to items that don't have them
+	Mappings []TagMapping      // Tags derived from the other tags of items
+}
+
+// EnrichmentProcessor fills in default env, host and tags on the logs,
+// metrics and spans it passes downstream, and derives tags from existing
+// ones. Values already set on an item are never overwritten. Mappings are
+// applied after the default tags, so they can derive from them.
+type EnrichmentProcessor struct {
+	Processor
+
+	config EnrichmentConfig
+}
+
+// NewEnrichmentProcessor creates an enrichment processor wrapping next
+func NewEnrichmentProcessor(next Processor, config EnrichmentConfig) *EnrichmentProcessor {
+	return &EnrichmentProcessor{
+		Processor: next,
+		config:    config,
+	}
+}
+
+// ProcessLog enriches the log and passes it downstream
+func (p *EnrichmentProcessor) ProcessLog(log *models.LogEntry) error {
+	p.enrichLog(log)
+	return p.Processor.ProcessLog(log)
+}
+
+// ProcessLogs enriches every log of the batch and passes it downstream
+func (p *EnrichmentProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	for _, log := range logs {
+		p.enrichLog(log)
+	}
+	return p.Processor.ProcessLogs(logs)
+}
+
+// ProcessMetric enriches the metric and passes it downstream
+func (p *EnrichmentProcessor) ProcessMetric(metric *models.Metric) error {
+	p.enrichMetric(metric)
+	return p.Processor.ProcessMetric(metric)
+}
+
+// ProcessMetrics enriches every metric of the batch and passes it downstream
+func (p *EnrichmentProcessor) ProcessMetrics(metrics []*models.Metric) error {
+	for _, metric := range metrics {
+		p.enrichMetric(metric)
+	}
+	return p.Processor.ProcessMetrics(metrics)
+}
+
+// ProcessHistogram enriches the histogram and passes it downstream
+func (p *EnrichmentProcessor) ProcessHistogram(histogram *models.HistogramMetric) error {
+	p.enrichMetric(&histogram.Metric)
+	return p.Processor.ProcessHistogram(histogram)
+}
+
+// ProcessSpan enriches the span and passes it downstream
+func (p *EnrichmentProcessor) ProcessSpan(span *models.Span) error {
+	p.enrichSpan(span)
+	return p.Processor.ProcessSpan(span)
+}
+
+// ProcessTrace enriches every span of the trace and passes it downstream
+func (p *EnrichmentProcessor) ProcessTrace(trace *models.Trace) error {
+	for _, span := range trace.Spans {
+		p.enrichSpan(span)
+	}
+	return p.Processor.ProcessTrace(trace)
+}
+
+// enrichLog fills in the defaults of a log entry
+func (p *EnrichmentProcessor) enrichLog(log *models.LogEntry) {
+	p.enrich(&log.Env, &log.Host, func() map[string]string { return log.Tags }, func(k, v string) { log.AddTag(k, v) })
+}
+
+// enrichMetric fills in the defaults of a metric
+func (p *EnrichmentProcessor) enrichMetric(metric *models.Metric) {
+	p.enrich(&metric.Env, &metric.Host, func() map[string]string { return metric.Tags }, func(k, v string) { metric.AddTag(k, v) })
+}
+
+// enrichSpan fills in the defaults of a span
+func (p *EnrichmentProcessor) enrichSpan(span *models.Span) {
+	p.enrich(&span.Env, &span.Host, func() map[string]string { return span.Tags }, func(k, v string) { span.AddTag(k, v) })
+}
+
+// enrich fills in the blank env and host of an item and its missing tags.
+// tags returns the current tags of the item and addTag sets one of them.
+func (p *EnrichmentProcessor) enrich(env, host *string, tags func() map[string]string, addTag func(key, value string)) {
+	if *env == "" {
+		*env = p.config.Env
+	}
+	if *host == "" {
+		*host = p.config.Host
+	}
+
+	for key, value := range p.config.Tags {
+		if _, ok := tags()[key]; !ok {
+			addTag(key, value)
+		}
+	}
+
+	for _, mapping := range p.config.Mappings {
+		if _, ok := tags()[mapping.To]; ok {
+			continue
+		}
+		from, ok := tags()[mapping.From]
+		if !ok {
+			continue
+		}
+		if mapping.Values == nil {
+			addTag(mapping.To, from)
+		} else if mapped, ok := mapping.Values[from]; ok {
+			addTag(mapping.To, mapped)
+		}
+	}
+}
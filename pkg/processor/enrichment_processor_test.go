@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func TestParseTagMapping(t *testing.T) {
+	mapping, err := ParseTagMapping("region>cloud:eu-west-1=aws, europe-west1=gcp")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := TagMapping{From: "region", To: "cloud", Values: map[string]string{"eu-west-1": "aws", "europe-west1": "gcp"}}
+	if !reflect.DeepEqual(mapping, expected) {
+		t.Errorf("expected %+v, got %+v", expected, mapping)
+	}
+
+	// Without values the tag is copied
+	mapping, err = ParseTagMapping("k8s.namespace>team")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if mapping.From != "k8s.namespace" || mapping.To != "team" || mapping.Values != nil {
+		t.Errorf("unexpected mapping: %+v", mapping)
+	}
+
+	for _, invalid := range []string{"", "region", ">cloud", "region>", "region>cloud:aws"} {
+		if _, err := ParseTagMapping(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestEnrichmentProcessor(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewEnrichmentProcessor(next, EnrichmentConfig{
+		Env:  "prod",
+		Host: "node-1",
+		Tags: map[string]string{"team": "core", "region": "eu-west-1"},
+		Mappings: []TagMapping{
+			{From: "region", To: "cloud", Values: map[string]string{"eu-west-1": "aws", "europe-west1": "gcp"}},
+			{From: "pod", To: "instance"},
+		},
+	})
+
+	// Blank fields are filled in and tags derived, including from defaults
+	bare := models.NewLogEntry("api", "hello", models.LogLevelInfo)
+	if err := p.ProcessLog(bare); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if bare.Env != "prod" || bare.Host != "node-1" {
+		t.Errorf("expected env prod and host node-1, got %q and %q", bare.Env, bare.Host)
+	}
+	expected := map[string]string{"team": "core", "region": "eu-west-1", "cloud": "aws"}
+	if !reflect.DeepEqual(bare.Tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, bare.Tags)
+	}
+
+	// Values set on the item win over the defaults
+	metric := models.NewMetric("requests", 1, models.MetricTypeCounter, "api")
+	metric.Env, metric.Host = "staging", "node-2"
+	metric.AddTag("region", "europe-west1").AddTag("team", "payments").AddTag("pod", "api-7f9")
+	if err := p.ProcessMetrics([]*models.Metric{metric}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if metric.Env != "staging" || metric.Host != "node-2" {
+		t.Errorf("expected env and host to be kept, got %q and %q", metric.Env, metric.Host)
+	}
+	expected = map[string]string{"team": "payments", "region": "europe-west1", "cloud": "gcp", "pod": "api-7f9", "instance": "api-7f9"}
+	if !reflect.DeepEqual(metric.Tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, metric.Tags)
+	}
+
+	// Unknown values are not mapped and derived tags are not overwritten
+	span := models.NewSpan("GET /", "api", "trace-1")
+	span.AddTag("region", "on-prem")
+	other := models.NewSpan("query", "db", "trace-1")
+	other.AddTag("cloud", "azure")
+	trace := &models.Trace{ID: "trace-1", Root: span, Spans: []*models.Span{span, other}}
+	if err := p.ProcessTrace(trace); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, ok := span.Tags["cloud"]; ok || span.Env != "prod" {
+		t.Errorf("expected no cloud tag and env prod, got %v and %q", span.Tags, span.Env)
+	}
+	if other.Tags["cloud"] != "azure" {
+		t.Errorf("expected the cloud tag to be kept, got %q", other.Tags["cloud"])
+	}
+
+	if len(next.logs) != 1 || len(next.metrics) != 1 || len(next.traces) != 1 {
+		t.Errorf("expected every item to be passed downstream, got %d logs, %d metrics and %d traces", len(next.logs), len(next.metrics), len(next.traces))
+	}
+}
+
+func TestEnrichmentProcessor_InChain(t *testing.T) {
+	first, second := &recordingProcessor{}, &recordingProcessor{}
+	chain := Chain{NewEnrichmentProcessor(first, EnrichmentConfig{Env: "prod"}), second}
+
+	log := models.NewLogEntry("api", "hello", models.LogLevelInfo)
+	if err := chain.ProcessLog(log); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// Later processors of the chain see the enriched item
+	if len(second.logs) != 1 || second.logs[0].Env != "prod" {
+		t.Errorf("expected the enriched log further down the chain, got %+v", second.logs)
+	}
+}
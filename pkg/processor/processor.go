@@ -1,9 +1,14 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/storage"
 )
 
 // Processor defines the interface for processing observability data
@@ -11,9 +16,18 @@ type Processor interface {
 	// ProcessLog processes a log entry
 	ProcessLog(log *models.LogEntry) error
 
+	// ProcessLogs processes a batch of logs, persisting them together
+	ProcessLogs(logs []*models.LogEntry) error
+
 	// ProcessMetric processes a metric
 	ProcessMetric(metric *models.Metric) error
 
+	// ProcessMetrics processes a batch of metrics, persisting them together
+	ProcessMetrics(metrics []*models.Metric) error
+
+	// ProcessHistogram processes a pre-aggregated histogram metric
+	ProcessHistogram(histogram *models.HistogramMetric) error
+
 	// ProcessSpan processes a span
 	ProcessSpan(span *models.Span) error
 
@@ -23,26 +37,84 @@ type Processor interface {
 	// QueryLogs queries logs based on parameters
 	QueryLogs(query *models.QueryParams) (map[string]interface{}, error)
 
+	// StreamLogs calls fn for each matching log without buffering the result set
+	StreamLogs(query *models.QueryParams, fn func(log *models.LogEntry) error) error
+
+	// UpdateLogTags merges tags into the logs matching the query and returns
+	// the number of logs updated
+	UpdateLogTags(query *models.QueryParams, tags map[string]string) (int64, error)
+
 	// QueryMetrics queries metrics based on parameters
-	QueryMetrics(query *models.QueryParams) ([]map[string]interface{}, error)
+	QueryMetrics(query *models.QueryParams) (map[string]interface{}, error)
+
+	// StreamMetrics calls fn for each matching metric without buffering the result set
+	StreamMetrics(query *models.QueryParams, fn func(metric *models.Metric) error) error
+
+	// QueryHistograms returns histogram metrics with their bucket data
+	QueryHistograms(query *models.QueryParams) ([]*models.HistogramMetric, error)
 
 	// QueryTraces queries traces based on parameters
-	QueryTraces(query *models.QueryParams) ([]map[string]interface{}, error)
+	QueryTraces(query *models.QueryParams) (map[string]interface{}, error)
+
+	// QueryTraceIDs queries the IDs and start times of traces, newest first
+	QueryTraceIDs(query *models.QueryParams) (map[string]interface{}, error)
+
+	// QueryTraceSamples queries the duration of traces and whether any of
+	// their spans failed, newest first
+	QueryTraceSamples(query *models.QueryParams) (map[string]interface{}, error)
+
+	// QuerySlowTraces queries the traces slower than a duration percentile
+	QuerySlowTraces(query *models.QueryParams, percentile float64) (map[string]interface{}, error)
 
 	// QuerySpans queries spans based on parameters
-	QuerySpans(query *models.QueryParams) ([]map[string]interface{}, error)
+	QuerySpans(query *models.QueryParams) (map[string]interface{}, error)
+
+	// StreamSpans calls fn for each matching span without buffering the result set
+	StreamSpans(query *models.QueryParams, fn func(span *models.Span) error) error
+
+	// CountSpanErrorsByType counts ERROR spans grouped by their error.type tag
+	CountSpanErrorsByType(query *models.QueryParams) ([]map[string]interface{}, error)
+
+	// GetTrace returns a trace with all of its spans
+	GetTrace(traceID string) (*models.Trace, error)
+
+	// GetSpanAncestors returns the ancestors of a span, its parent first and
+	// the root last
+	GetSpanAncestors(spanID string) ([]*models.Span, error)
+
+	// AggregateMetrics aggregates metrics into time series
+	AggregateMetrics(query storage.MetricQuery) ([]storage.MetricAggregation, error)
 
 	// GetServices returns a list of available services
 	GetServices() ([]string, error)
 
+	// GetActiveServices returns the services ordered by their most recent
+	// activity, newest first, at most limit of them (all if limit is 0)
+	GetActiveServices(limit int) ([]string, error)
+
+	// DeleteByService removes all stored telemetry of a service and returns
+	// the number of rows deleted
+	DeleteByService(service string) (int64, error)
+
+	// ClearAll removes all stored telemetry and returns the number of rows deleted
+	ClearAll() (int64, error)
+
 	// GetStats returns summary statistics
-	GetStats(query *models.QueryParams) (map[string]interface{}, error)
+	GetStats(query *models.QueryParams) (*models.Stats, error)
 
-	// Close closes any resources held by the processor
+	// Ping checks that the storage behind the processor is reachable
+	Ping() error
+
+	// Close flushes any buffered data downstream and closes resources held
+	// by the processor
 	Close() error
 }
 
-// Chain creates a processor chain from multiple processors
+// Chain creates a processor chain from multiple processors. Items are
+// processed by every processor in turn. Queries fan out to every processor
+// and merge their results, and tag updates and deletions are applied to
+// every processor, so a chain can front several storage backends;
+// aggregations and statistics go through the first processor.
 type Chain []Processor
 
 // ProcessLog processes a log entry through all processors in the chain
@@ -55,6 +127,16 @@ func (c Chain) ProcessLog(log *models.LogEntry) error {
 	return nil
 }
 
+// ProcessLogs processes a batch of logs through all processors in the chain
+func (c Chain) ProcessLogs(logs []*models.LogEntry) error {
+	for _, processor := range c {
+		if err := processor.ProcessLogs(logs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ProcessMetric processes a metric through all processors in the chain
 func (c Chain) ProcessMetric(metric *models.Metric) error {
 	for _, processor := range c {
@@ -65,6 +147,26 @@ func (c Chain) ProcessMetric(metric *models.Metric) error {
 	return nil
 }
 
+// ProcessMetrics processes a batch of metrics through all processors in the chain
+func (c Chain) ProcessMetrics(metrics []*models.Metric) error {
+	for _, processor := range c {
+		if err := processor.ProcessMetrics(metrics); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessHistogram processes a histogram metric through all processors in the chain
+func (c Chain) ProcessHistogram(histogram *models.HistogramMetric) error {
+	for _, processor := range c {
+		if err := processor.ProcessHistogram(histogram); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ProcessSpan processes a span through all processors in the chain
 func (c Chain) ProcessSpan(span *models.Span) error {
 	for _, processor := range c {
@@ -85,54 +187,372 @@ func (c Chain) ProcessTrace(trace *models.Trace) error {
 	return nil
 }
 
-// QueryLogs queries logs through the first processor in the chain
+// QueryLogs queries logs from every processor in the chain and merges them
 func (c Chain) QueryLogs(query *models.QueryParams) (map[string]interface{}, error) {
+	return c.queryMerged("logs", query, logMergeOrder(query), func(p Processor, query *models.QueryParams) (map[string]interface{}, error) {
+		return p.QueryLogs(query)
+	})
+}
+
+// QueryMetrics queries metrics from every processor in the chain and merges them
+func (c Chain) QueryMetrics(query *models.QueryParams) (map[string]interface{}, error) {
+	return c.queryMerged("metrics", query, mergeOrder{}, func(p Processor, query *models.QueryParams) (map[string]interface{}, error) {
+		return p.QueryMetrics(query)
+	})
+}
+
+// StreamMetrics streams the metrics of every processor in the chain in turn,
+// skipping metrics already streamed from an earlier one
+func (c Chain) StreamMetrics(query *models.QueryParams, fn func(metric *models.Metric) error) error {
+	if len(c) == 0 {
+		return fmt.Errorf("no processors in chain")
+	}
+	seen := make(map[string]bool)
+	for _, processor := range c {
+		err := processor.StreamMetrics(query, func(metric *models.Metric) error {
+			if metric.ID != "" {
+				if seen[metric.ID] {
+					return nil
+				}
+				seen[metric.ID] = true
+			}
+			return fn(metric)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamLogs streams the logs of every processor in the chain in turn,
+// skipping logs already streamed from an earlier one
+func (c Chain) StreamLogs(query *models.QueryParams, fn func(log *models.LogEntry) error) error {
+	if len(c) == 0 {
+		return fmt.Errorf("no processors in chain")
+	}
+	seen := make(map[string]bool)
+	for _, processor := range c {
+		err := processor.StreamLogs(query, func(log *models.LogEntry) error {
+			if log.ID != "" {
+				if seen[log.ID] {
+					return nil
+				}
+				seen[log.ID] = true
+			}
+			return fn(log)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamSpans streams the spans of every processor in the chain in turn,
+// skipping spans already streamed from an earlier one
+func (c Chain) StreamSpans(query *models.QueryParams, fn func(span *models.Span) error) error {
+	if len(c) == 0 {
+		return fmt.Errorf("no processors in chain")
+	}
+	seen := make(map[string]bool)
+	for _, processor := range c {
+		err := processor.StreamSpans(query, func(span *models.Span) error {
+			if span.ID != "" {
+				if seen[span.ID] {
+					return nil
+				}
+				seen[span.ID] = true
+			}
+			return fn(span)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryHistograms queries histograms from every processor in the chain and
+// merges them, oldest first, up to the query limit
+func (c Chain) QueryHistograms(query *models.QueryParams) ([]*models.HistogramMetric, error) {
+	if len(c) == 0 {
+		return nil, fmt.Errorf("no processors in chain")
+	}
+	var merged []*models.HistogramMetric
+	seen := make(map[string]bool)
+	for _, processor := range c {
+		histograms, err := processor.QueryHistograms(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, histogram := range histograms {
+			if histogram.ID != "" {
+				if seen[histogram.ID] {
+					continue
+				}
+				seen[histogram.ID] = true
+			}
+			merged = append(merged, histogram)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+	if query.Limit > 0 && len(merged) > query.Limit {
+		merged = merged[:query.Limit]
+	}
+	return merged, nil
+}
+
+// QueryTraces queries traces from every processor in the chain and merges them
+func (c Chain) QueryTraces(query *models.QueryParams) (map[string]interface{}, error) {
+	return c.queryMerged("traces", query, traceMergeOrder(query), func(p Processor, query *models.QueryParams) (map[string]interface{}, error) {
+		return p.QueryTraces(query)
+	})
+}
+
+// QueryTraceIDs queries trace IDs from every processor in the chain and
+// merges them
+func (c Chain) QueryTraceIDs(query *models.QueryParams) (map[string]interface{}, error) {
+	return c.queryMerged("traces", query, mergeOrder{}, func(p Processor, query *models.QueryParams) (map[string]interface{}, error) {
+		return p.QueryTraceIDs(query)
+	})
+}
+
+// QueryTraceSamples queries trace samples from every processor in the chain
+// and merges them
+func (c Chain) QueryTraceSamples(query *models.QueryParams) (map[string]interface{}, error) {
+	return c.queryMerged("traces", query, mergeOrder{}, func(p Processor, query *models.QueryParams) (map[string]interface{}, error) {
+		return p.QueryTraceSamples(query)
+	})
+}
+
+// QuerySlowTraces queries slow traces from every processor in the chain and
+// merges them, slowest first. Each processor applies its own percentile
+// threshold.
+func (c Chain) QuerySlowTraces(query *models.QueryParams, percentile float64) (map[string]interface{}, error) {
+	return c.queryMerged("traces", query, mergeOrder{field: "duration", desc: true}, func(p Processor, query *models.QueryParams) (map[string]interface{}, error) {
+		return p.QuerySlowTraces(query, percentile)
+	})
+}
+
+// QuerySpans queries spans from every processor in the chain and merges them
+func (c Chain) QuerySpans(query *models.QueryParams) (map[string]interface{}, error) {
+	return c.queryMerged("spans", query, mergeOrder{}, func(p Processor, query *models.QueryParams) (map[string]interface{}, error) {
+		return p.QuerySpans(query)
+	})
+}
+
+// CountSpanErrorsByType counts span errors of every processor in the chain,
+// adding up the counts of each error type
+func (c Chain) CountSpanErrorsByType(query *models.QueryParams) ([]map[string]interface{}, error) {
 	if len(c) == 0 {
 		return nil, fmt.Errorf("no processors in chain")
 	}
-	return c[0].QueryLogs(query)
+	counts := make(map[string]int)
+	for _, processor := range c {
+		results, err := processor.CountSpanErrorsByType(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			errorType, _ := result["error_type"].(string)
+			count, _ := result["count"].(int)
+			counts[errorType] += count
+		}
+	}
+
+	merged := make([]map[string]interface{}, 0, len(counts))
+	for errorType, count := range counts {
+		merged = append(merged, map[string]interface{}{
+			"error_type": errorType,
+			"count":      count,
+		})
+	}
+	// Most frequent first, like the storage backends
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i]["count"].(int) != merged[j]["count"].(int) {
+			return merged[i]["count"].(int) > merged[j]["count"].(int)
+		}
+		return merged[i]["error_type"].(string) < merged[j]["error_type"].(string)
+	})
+	return merged, nil
 }
 
-// QueryMetrics queries metrics through the first processor in the chain
-func (c Chain) QueryMetrics(query *models.QueryParams) ([]map[string]interface{}, error) {
+// AggregateMetrics aggregates metrics through the first processor in the chain
+func (c Chain) AggregateMetrics(query storage.MetricQuery) ([]storage.MetricAggregation, error) {
 	if len(c) == 0 {
 		return nil, fmt.Errorf("no processors in chain")
 	}
-	return c[0].QueryMetrics(query)
+	return c[0].AggregateMetrics(query)
 }
 
-// QueryTraces queries traces through the first processor in the chain
-func (c Chain) QueryTraces(query *models.QueryParams) ([]map[string]interface{}, error) {
+// GetTrace returns a trace assembled from the spans every processor in the
+// chain has of it. Processors without the trace are skipped; if none has it
+// the error of the first processor is returned.
+func (c Chain) GetTrace(traceID string) (*models.Trace, error) {
 	if len(c) == 0 {
 		return nil, fmt.Errorf("no processors in chain")
 	}
-	return c[0].QueryTraces(query)
+	var (
+		spans    []*models.Span
+		firstErr error
+	)
+	seen := make(map[string]bool)
+	for _, processor := range c {
+		trace, err := processor.GetTrace(traceID)
+		if errors.Is(err, storage.ErrTraceNotFound) {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, span := range trace.Spans {
+			if !seen[span.ID] {
+				seen[span.ID] = true
+				spans = append(spans, span)
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return nil, firstErr
+	}
+	return models.AssembleTrace(traceID, spans), nil
 }
 
-// QuerySpans queries spans through the first processor in the chain
-func (c Chain) QuerySpans(query *models.QueryParams) ([]map[string]interface{}, error) {
+// GetSpanAncestors returns the ancestors of a span from the first processor
+// in the chain that stores the span
+func (c Chain) GetSpanAncestors(spanID string) ([]*models.Span, error) {
 	if len(c) == 0 {
 		return nil, fmt.Errorf("no processors in chain")
 	}
-	return c[0].QuerySpans(query)
+	for _, processor := range c {
+		ancestors, err := processor.GetSpanAncestors(spanID)
+		if !errors.Is(err, storage.ErrSpanNotFound) {
+			return ancestors, err
+		}
+	}
+	return nil, storage.ErrSpanNotFound
 }
 
-// GetServices returns available services through the first processor in the chain
+// GetServices returns the union of the services of every processor in the
+// chain, sorted by name
 func (c Chain) GetServices() ([]string, error) {
 	if len(c) == 0 {
 		return nil, fmt.Errorf("no processors in chain")
 	}
-	return c[0].GetServices()
+	var services []string
+	seen := make(map[string]bool)
+	for _, processor := range c {
+		names, err := processor.GetServices()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				services = append(services, name)
+			}
+		}
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// GetActiveServices returns the most recently active services of every
+// processor in the chain, those of earlier processors first, at most limit
+// of them
+func (c Chain) GetActiveServices(limit int) ([]string, error) {
+	if len(c) == 0 {
+		return nil, fmt.Errorf("no processors in chain")
+	}
+	var services []string
+	seen := make(map[string]bool)
+	for _, processor := range c {
+		names, err := processor.GetActiveServices(limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				services = append(services, name)
+			}
+		}
+	}
+	if limit > 0 && len(services) > limit {
+		services = services[:limit]
+	}
+	return services, nil
+}
+
+// UpdateLogTags tags logs through every processor in the chain and returns
+// the largest number of logs a processor updated
+func (c Chain) UpdateLogTags(query *models.QueryParams, tags map[string]string) (int64, error) {
+	return c.writeAll(func(p Processor) (int64, error) {
+		return p.UpdateLogTags(query, tags)
+	})
+}
+
+// DeleteByService deletes a service's telemetry through every processor in
+// the chain and returns the largest number of items a processor deleted
+func (c Chain) DeleteByService(service string) (int64, error) {
+	return c.writeAll(func(p Processor) (int64, error) {
+		return p.DeleteByService(service)
+	})
+}
+
+// ClearAll clears stored telemetry through every processor in the chain and
+// returns the largest number of items a processor deleted
+func (c Chain) ClearAll() (int64, error) {
+	return c.writeAll(func(p Processor) (int64, error) {
+		return p.ClearAll()
+	})
+}
+
+// writeAll runs write against every processor in the chain, stopping at the
+// first error, and returns the largest count. Processors of a chain hold the
+// same items, so counts aren't added up.
+func (c Chain) writeAll(write func(p Processor) (int64, error)) (int64, error) {
+	if len(c) == 0 {
+		return 0, fmt.Errorf("no processors in chain")
+	}
+	var largest int64
+	for _, processor := range c {
+		n, err := write(processor)
+		if err != nil {
+			return largest, err
+		}
+		if n > largest {
+			largest = n
+		}
+	}
+	return largest, nil
 }
 
 // GetStats returns statistics through the first processor in the chain
-func (c Chain) GetStats(query *models.QueryParams) (map[string]interface{}, error) {
+func (c Chain) GetStats(query *models.QueryParams) (*models.Stats, error) {
 	if len(c) == 0 {
 		return nil, fmt.Errorf("no processors in chain")
 	}
 	return c[0].GetStats(query)
 }
 
+// Ping checks every processor in the chain, since items are written to all
+// of them
+func (c Chain) Ping() error {
+	for _, processor := range c {
+		if err := processor.Ping(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close closes all processors in the chain
 func (c Chain) Close() error {
 	for _, processor := range c {
@@ -142,3 +562,193 @@ func (c Chain) Close() error {
 	}
 	return nil
 }
+
+// queryMerged runs query against every processor in the chain and merges the
+// records each returns under key. Each processor is asked for the records up
+// to the end of the requested page, from offset 0, so that the page can be
+// cut from the merged records: they are deduplicated by id, sorted in order
+// and cut to the query's offset and limit. The total is the number of
+// distinct records when every processor returned all of its matches, and
+// otherwise the largest total of a processor, since the processors of a
+// chain hold the same items. Other fields are taken from the first result.
+func (c Chain) queryMerged(key string, query *models.QueryParams, order mergeOrder, run func(p Processor, query *models.QueryParams) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	if len(c) == 0 {
+		return nil, fmt.Errorf("no processors in chain")
+	}
+	// A single processor's page and pagination, including its cursor, stay valid
+	if len(c) == 1 {
+		return run(c[0], query)
+	}
+
+	pageSize := query.Limit
+	if pageSize <= 0 {
+		pageSize = 100 // Default limit
+	}
+	window := *query
+	window.Offset = 0
+	window.Limit = query.Offset + pageSize
+
+	var (
+		merged  map[string]interface{}
+		records []map[string]interface{}
+		total   int
+	)
+	seen := make(map[interface{}]bool)
+	for _, processor := range c {
+		result, err := run(processor, &window)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = make(map[string]interface{}, len(result))
+			for k, v := range result {
+				merged[k] = v
+			}
+		}
+
+		items, _ := result[key].([]map[string]interface{})
+		for _, item := range items {
+			if id, ok := item["id"]; ok && id != "" {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+			}
+			records = append(records, item)
+		}
+		if pagination, ok := result["pagination"].(map[string]interface{}); ok {
+			if totalItems, ok := pagination["total_items"].(int); ok && totalItems > total {
+				total = totalItems
+			}
+		}
+	}
+	if len(records) > total {
+		total = len(records)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return order.less(records[i], records[j])
+	})
+	if query.Offset >= len(records) {
+		records = []map[string]interface{}{}
+	} else {
+		records = records[query.Offset:]
+	}
+	if len(records) > pageSize {
+		records = records[:pageSize]
+	}
+	merged[key] = records
+
+	if _, ok := merged["pagination"]; ok {
+		merged["pagination"] = map[string]interface{}{
+			"total_items": total,
+			"total_pages": (total + pageSize - 1) / pageSize,
+			"page_size":   pageSize,
+			"offset":      query.Offset,
+		}
+	}
+	return merged, nil
+}
+
+// mergeOrder is the order of merged query records: by a field, descending or
+// not, with ties newest first, or newest first without a field
+type mergeOrder struct {
+	field string
+	desc  bool
+}
+
+// logMergeOrder returns the order of merged logs, following the order_by
+// log field of query like the storage backends do
+func logMergeOrder(query *models.QueryParams) mergeOrder {
+	switch query.OrderBy {
+	case "timestamp", "service", "level", "message":
+		return mergeOrder{field: query.OrderBy, desc: query.OrderDesc}
+	}
+	return mergeOrder{}
+}
+
+// traceMergeOrder returns the order of merged traces: by duration for
+// order_by=duration, newest first otherwise
+func traceMergeOrder(query *models.QueryParams) mergeOrder {
+	if query.OrderBy == "duration" {
+		return mergeOrder{field: "duration", desc: query.OrderDesc}
+	}
+	return mergeOrder{}
+}
+
+// less reports whether record a sorts before record b. Levels are ordered
+// by severity and durations numerically; ties are broken newest first, then
+// by id.
+func (o mergeOrder) less(a, b map[string]interface{}) bool {
+	cmp := 0
+	switch o.field {
+	case "level":
+		cmp = recordSeverity(a) - recordSeverity(b)
+	case "duration":
+		da, db := recordDuration(a), recordDuration(b)
+		if da < db {
+			cmp = -1
+		} else if da > db {
+			cmp = 1
+		}
+	case "timestamp":
+		cmp = compareTimes(recordTime(a), recordTime(b))
+	case "":
+	default:
+		cmp = strings.Compare(fmt.Sprint(a[o.field]), fmt.Sprint(b[o.field]))
+	}
+	if cmp != 0 {
+		return cmp < 0 != o.desc
+	}
+
+	if cmp := compareTimes(recordTime(a), recordTime(b)); cmp != 0 {
+		return cmp > 0
+	}
+	return fmt.Sprint(a["id"]) > fmt.Sprint(b["id"])
+}
+
+// compareTimes returns -1, 0 or 1 as a is before, equal to or after b
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// recordDuration returns the duration in milliseconds of a trace record
+func recordDuration(record map[string]interface{}) float64 {
+	switch v := record["duration_ms"].(type) {
+	case int64:
+		return float64(v)
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// recordSeverity returns the severity of the level of a log record
+func recordSeverity(record map[string]interface{}) int {
+	return models.LogLevel(fmt.Sprint(record["level"])).Severity()
+}
+
+// recordTime returns the timestamp, or for traces and spans the start time,
+// of a query record
+func recordTime(record map[string]interface{}) time.Time {
+	value, ok := record["timestamp"]
+	if !ok {
+		value = record["start_time"]
+	}
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case string:
+		t, _ := time.Parse(time.RFC3339, v)
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,328 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+	"github.com/karansingh/pulse/pkg/storage"
+)
+
+// recordingProcessor is a test double that records everything passed to it.
+// Methods that are not overridden panic via the nil embedded Processor.
+type recordingProcessor struct {
+	Processor
+
+	mu         sync.Mutex
+	logs       []*models.LogEntry
+	metrics    []*models.Metric
+	histograms []*models.HistogramMetric
+	spans      []*models.Span
+	traces     []*models.Trace
+}
+
+func (r *recordingProcessor) ProcessLog(log *models.LogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func (r *recordingProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, logs...)
+	return nil
+}
+
+func (r *recordingProcessor) ProcessMetric(metric *models.Metric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, metric)
+	return nil
+}
+
+func (r *recordingProcessor) ProcessMetrics(metrics []*models.Metric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, metrics...)
+	return nil
+}
+
+func (r *recordingProcessor) ProcessHistogram(histogram *models.HistogramMetric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.histograms = append(r.histograms, histogram)
+	return nil
+}
+
+func (r *recordingProcessor) ProcessSpan(span *models.Span) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+	return nil
+}
+
+func (r *recordingProcessor) ProcessTrace(trace *models.Trace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.traces = append(r.traces, trace)
+	return nil
+}
+
+func (r *recordingProcessor) Close() error {
+	return nil
+}
+
+// newMemoryChain returns a chain of two storage processors, each over its
+// own in-memory storage
+func newMemoryChain(t *testing.T) (Chain, *StorageProcessor, *StorageProcessor) {
+	t.Helper()
+	var backends []*StorageProcessor
+	for i := 0; i < 2; i++ {
+		st, err := storage.NewInMemoryStorage()
+		if err != nil {
+			t.Fatalf("failed to create storage: %v", err)
+		}
+		backends = append(backends, NewStorageProcessor(st))
+	}
+	return Chain{backends[0], backends[1]}, backends[0], backends[1]
+}
+
+func TestChain_QueryLogsMerges(t *testing.T) {
+	chain, first, second := newMemoryChain(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	log := func(message string, age time.Duration) *models.LogEntry {
+		entry := models.NewLogEntry("api", message, models.LogLevelInfo)
+		entry.ID = message
+		entry.Timestamp = now.Add(-age)
+		return entry
+	}
+
+	// The shared log is written through the chain and stored by both
+	if err := chain.ProcessLog(log("shared", 3*time.Minute)); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	first.ProcessLog(log("first", 2*time.Minute))
+	second.ProcessLog(log("second", time.Minute))
+	second.ProcessLog(log("oldest", 10*time.Minute))
+
+	result, err := chain.QueryLogs(&models.QueryParams{Since: now.Add(-time.Hour), Limit: 3})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	logs := result["logs"].([]map[string]interface{})
+	var messages []string
+	for _, entry := range logs {
+		messages = append(messages, entry["message"].(string))
+	}
+	if expected := []string{"second", "first", "shared"}; !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected %v, got %v", expected, messages)
+	}
+
+	pagination := result["pagination"].(map[string]interface{})
+	if pagination["total_items"] != 4 || pagination["page_size"] != 3 {
+		t.Errorf("expected the 4 distinct logs of both processors, got %v", pagination)
+	}
+
+	// Later pages are cut from the merged logs
+	result, err = chain.QueryLogs(&models.QueryParams{Since: now.Add(-time.Hour), Limit: 3, Offset: 3})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if logs := result["logs"].([]map[string]interface{}); len(logs) != 1 || logs[0]["message"] != "oldest" {
+		t.Errorf("expected the oldest log on the second page, got %v", logs)
+	}
+}
+
+func TestChain_QueryOrder(t *testing.T) {
+	chain, first, second := newMemoryChain(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, service := range []string{"billing", "api", "search", "checkout"} {
+		log := models.NewLogEntry(service, "hello", models.LogLevelInfo)
+		log.ID = service
+		log.Timestamp = now.Add(time.Duration(-i) * time.Minute)
+		span := models.NewSpan("GET /", service, "trace-"+service)
+		span.ID = service
+		span.StartTime = now.Add(time.Duration(-i) * time.Minute)
+		span.Duration = int64(100 * (i + 1))
+		p := first
+		if i%2 == 1 {
+			p = second
+		}
+		p.ProcessLog(log)
+		p.ProcessSpan(span)
+	}
+
+	field := func(records interface{}, key string) []string {
+		var values []string
+		for _, record := range records.([]map[string]interface{}) {
+			values = append(values, fmt.Sprint(record[key]))
+		}
+		return values
+	}
+
+	result, err := chain.QueryLogs(&models.QueryParams{OrderBy: "service", Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if services, expected := field(result["logs"], "service"), []string{"billing", "checkout"}; !reflect.DeepEqual(services, expected) {
+		t.Errorf("expected logs ordered by service %v, got %v", expected, services)
+	}
+
+	result, err = chain.QueryTraces(&models.QueryParams{OrderBy: "duration", OrderDesc: true})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if services, expected := field(result["traces"], "service"), []string{"checkout", "search", "api", "billing"}; !reflect.DeepEqual(services, expected) {
+		t.Errorf("expected traces slowest first %v, got %v", expected, services)
+	}
+}
+
+func TestChain_WritesToAll(t *testing.T) {
+	chain, first, second := newMemoryChain(t)
+
+	log := models.NewLogEntry("api", "hello", models.LogLevelInfo)
+	log.ID = "log-1"
+	chain.ProcessLog(log)
+	second.ProcessLog(models.NewLogEntry("billing", "hello", models.LogLevelInfo))
+
+	updated, err := chain.UpdateLogTags(&models.QueryParams{Service: "api"}, map[string]string{"incident": "INC-1"})
+	if err != nil || updated != 1 {
+		t.Fatalf("expected 1 log updated, got %d, %v", updated, err)
+	}
+	for _, p := range []*StorageProcessor{first, second} {
+		result, err := p.QueryLogs(&models.QueryParams{Filters: map[string]string{"incident": "INC-1"}})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if n := len(result["logs"].([]map[string]interface{})); n != 1 {
+			t.Errorf("expected the log to be tagged in every processor, got %d", n)
+		}
+	}
+
+	if _, err := chain.DeleteByService("api"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for _, p := range []*StorageProcessor{first, second} {
+		result, err := p.QueryLogs(&models.QueryParams{Service: "api"})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if n := len(result["logs"].([]map[string]interface{})); n != 0 {
+			t.Errorf("expected the service to be deleted from every processor, got %d logs", n)
+		}
+	}
+}
+
+func TestChain_QueryLogsByLevel(t *testing.T) {
+	chain, first, second := newMemoryChain(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, level := range []models.LogLevel{models.LogLevelError, models.LogLevelDebug, models.LogLevelFatal, models.LogLevelInfo} {
+		log := models.NewLogEntry("api", string(level), level)
+		log.Timestamp = now.Add(time.Duration(-i) * time.Minute)
+		if i%2 == 0 {
+			first.ProcessLog(log)
+		} else {
+			second.ProcessLog(log)
+		}
+	}
+
+	result, err := chain.QueryLogs(&models.QueryParams{OrderBy: "level", OrderDesc: true})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var levels []string
+	for _, log := range result["logs"].([]map[string]interface{}) {
+		levels = append(levels, log["message"].(string))
+	}
+	if expected := []string{"FATAL", "ERROR", "INFO", "DEBUG"}; !reflect.DeepEqual(levels, expected) {
+		t.Errorf("expected %v, got %v", expected, levels)
+	}
+}
+
+func TestChain_GetServicesAndTrace(t *testing.T) {
+	chain, first, second := newMemoryChain(t)
+
+	root := models.NewSpan("GET /checkout", "gateway", "trace-1")
+	child := models.NewSpan("charge", "billing", "trace-1")
+	child.SetParent(root.ID)
+	first.ProcessSpan(root)
+	second.ProcessSpan(child)
+	second.ProcessLog(models.NewLogEntry("audit", "hello", models.LogLevelInfo))
+
+	services, err := chain.GetServices()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if expected := []string{"audit", "billing", "gateway"}; !reflect.DeepEqual(services, expected) {
+		t.Errorf("expected %v, got %v", expected, services)
+	}
+
+	trace, err := chain.GetTrace("trace-1")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(trace.Spans) != 2 || trace.Root == nil || trace.Root.ID != root.ID {
+		t.Errorf("expected both spans under the root, got %+v", trace)
+	}
+
+	if _, err := chain.GetTrace("missing"); !errors.Is(err, storage.ErrTraceNotFound) {
+		t.Errorf("expected ErrTraceNotFound, got: %v", err)
+	}
+}
+
+func TestStorageProcessor_GetStats(t *testing.T) {
+	_, p, _ := newMemoryChain(t)
+
+	for _, level := range []models.LogLevel{models.LogLevelInfo, models.LogLevelInfo, models.LogLevelError} {
+		p.ProcessLog(models.NewLogEntry("api", "hello", level))
+	}
+	p.ProcessMetrics([]*models.Metric{
+		models.NewMetric("http.requests", 1, models.MetricTypeCounter, "api"),
+		models.NewMetric("http.requests", 2, models.MetricTypeCounter, "api"),
+		models.NewMetric("queue.depth", 7, models.MetricTypeGauge, "worker"),
+	})
+	for i, duration := range []int64{100, 300} {
+		span := models.NewSpan("GET /", "api", fmt.Sprintf("trace-%d", i))
+		span.Duration = duration
+		span.EndTime = span.StartTime.Add(time.Duration(duration) * time.Millisecond)
+		p.ProcessSpan(span)
+	}
+
+	stats, err := p.GetStats(&models.QueryParams{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if stats.Logs.Total != 3 || stats.Logs.ByLevel[models.LogLevelInfo] != 2 || stats.Logs.ByLevel[models.LogLevelError] != 1 {
+		t.Errorf("unexpected log stats: %+v", stats.Logs)
+	}
+	if _, ok := stats.Logs.ByLevel[models.LogLevelFatal]; !ok {
+		t.Errorf("expected every standard level to be reported, got %v", stats.Logs.ByLevel)
+	}
+	if stats.Metrics.Total != 3 || stats.Metrics.ByType[models.MetricTypeCounter] != 2 || stats.Metrics.ByType[models.MetricTypeGauge] != 1 {
+		t.Errorf("unexpected metric stats: %+v", stats.Metrics)
+	}
+	if stats.Traces.Total != 2 || stats.Traces.AvgDurationMs != 200 {
+		t.Errorf("expected 2 traces averaging 200ms, got %+v", stats.Traces)
+	}
+	if stats.AsyncStats != nil {
+		t.Errorf("expected no async stats for synchronous ingestion, got %+v", stats.AsyncStats)
+	}
+
+	// Filters of the query apply to every count
+	stats, err = p.GetStats(&models.QueryParams{Service: "worker"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if stats.Logs.Total != 0 || stats.Metrics.Total != 1 || stats.Traces.Total != 0 {
+		t.Errorf("expected only the worker metric, got %+v", stats)
+	}
+}
@@ -0,0 +1,300 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// ErrRateLimited is returned when a service has exhausted its ingestion budget
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimit is a token bucket budget: Rate records per second with bursts of
+// up to Burst records. A zero Rate means unlimited.
+type RateLimit struct {
+	Rate  float64 // Sustained records per second
+	Burst int     // Maximum records accepted at once
+}
+
+// RateLimitConfig holds the default budget and per-service overrides.
+// Every service gets its own bucket; services without an override use Default.
+type RateLimitConfig struct {
+	Default  RateLimit            // Budget for services without an override
+	Services map[string]RateLimit // Per-service budgets
+}
+
+// ParseServiceRateLimit parses a per-service limit of the form
+// "service=rate[:burst]", e.g. "checkout=500:1000"
+func ParseServiceRateLimit(s string) (string, RateLimit, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return "", RateLimit{}, fmt.Errorf("invalid service rate limit %q, expected service=rate[:burst]", s)
+	}
+
+	service := strings.TrimSpace(parts[0])
+	spec := strings.SplitN(strings.TrimSpace(parts[1]), ":", 2)
+
+	rate, err := strconv.ParseFloat(spec[0], 64)
+	if err != nil || rate < 0 {
+		return "", RateLimit{}, fmt.Errorf("invalid rate in %q", s)
+	}
+
+	limit := RateLimit{Rate: rate}
+	if len(spec) == 2 {
+		burst, err := strconv.Atoi(spec[1])
+		if err != nil || burst < 0 {
+			return "", RateLimit{}, fmt.Errorf("invalid burst in %q", s)
+		}
+		limit.Burst = burst
+	}
+
+	return service, limit, nil
+}
+
+// TokenBucket tracks the remaining budget of a single RateLimit. It is not
+// safe for concurrent use.
+type TokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a bucket for limit that is full as of now
+func NewTokenBucket(limit RateLimit, now time.Time) *TokenBucket {
+	b := &TokenBucket{limit: limit, last: now}
+	b.tokens = b.burst()
+	return b
+}
+
+// burst returns the bucket capacity, which defaults to one second of budget
+func (b *TokenBucket) burst() float64 {
+	if b.limit.Burst < 1 {
+		return b.limit.Rate
+	}
+	return float64(b.limit.Burst)
+}
+
+// Take removes n tokens if available, refilling for the time elapsed
+func (b *TokenBucket) Take(n int, now time.Time) bool {
+	if b.limit.Rate <= 0 {
+		return true
+	}
+
+	b.refill(now)
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+// refill adds the tokens earned since the last refill, up to the burst
+func (b *TokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if burst := b.burst(); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+}
+
+// idle reports whether the bucket would be full by now, making it the same
+// as a new bucket
+func (b *TokenBucket) idle(now time.Time) bool {
+	if b.limit.Rate <= 0 {
+		return true
+	}
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= b.burst()
+}
+
+// RetryAfter returns how long after the last Take the bucket will hold n tokens
+func (b *TokenBucket) RetryAfter(n int) time.Duration {
+	missing := float64(n) - b.tokens
+	if b.limit.Rate <= 0 || missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / b.limit.Rate * float64(time.Second))
+}
+
+// maxRateLimitedServices is how many service buckets the processor keeps
+// before it forgets the idle ones
+const maxRateLimitedServices = 10000
+
+// RateLimitProcessor enforces a per-service ingestion budget so that one
+// noisy service cannot starve the others. Records over budget are rejected
+// with ErrRateLimited.
+type RateLimitProcessor struct {
+	Processor
+
+	config RateLimitConfig
+
+	mu      sync.Mutex
+	buckets map[string]*TokenBucket
+	now     func() time.Time
+}
+
+// NewRateLimitProcessor creates a rate limiting processor wrapping next
+func NewRateLimitProcessor(next Processor, config RateLimitConfig) *RateLimitProcessor {
+	return &RateLimitProcessor{
+		Processor: next,
+		config:    config,
+		buckets:   make(map[string]*TokenBucket),
+		now:       time.Now,
+	}
+}
+
+// Config returns the rate limit configuration in effect
+func (p *RateLimitProcessor) Config() RateLimitConfig {
+	return p.config
+}
+
+// ProcessLog rate limits logs by service
+func (p *RateLimitProcessor) ProcessLog(log *models.LogEntry) error {
+	if !p.allow(log.Service, 1) {
+		return ErrRateLimited
+	}
+	return p.Processor.ProcessLog(log)
+}
+
+// ProcessLogs charges each service for its logs in the batch, at most its
+// burst so that batches larger than the burst can pass on a full bucket. The
+// batch is rejected as a whole, without charging any service, if any service
+// is over budget.
+func (p *RateLimitProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	counts := make(map[string]int)
+	for _, log := range logs {
+		counts[log.Service]++
+	}
+
+	if !p.allowBatch(counts) {
+		return ErrRateLimited
+	}
+	return p.Processor.ProcessLogs(logs)
+}
+
+// ProcessMetric rate limits metrics by service
+func (p *RateLimitProcessor) ProcessMetric(metric *models.Metric) error {
+	if !p.allow(metric.Service, 1) {
+		return ErrRateLimited
+	}
+	return p.Processor.ProcessMetric(metric)
+}
+
+// ProcessMetrics charges each service for its metrics in the batch. The
+// batch is rejected as a whole if any service is over budget.
+func (p *RateLimitProcessor) ProcessMetrics(metrics []*models.Metric) error {
+	counts := make(map[string]int)
+	var services []string
+	for _, metric := range metrics {
+		if counts[metric.Service] == 0 {
+			services = append(services, metric.Service)
+		}
+		counts[metric.Service]++
+	}
+
+	for _, service := range services {
+		if !p.allow(service, counts[service]) {
+			return ErrRateLimited
+		}
+	}
+	return p.Processor.ProcessMetrics(metrics)
+}
+
+// ProcessHistogram rate limits histograms by service
+func (p *RateLimitProcessor) ProcessHistogram(histogram *models.HistogramMetric) error {
+	if !p.allow(histogram.Service, 1) {
+		return ErrRateLimited
+	}
+	return p.Processor.ProcessHistogram(histogram)
+}
+
+// ProcessSpan rate limits spans by service
+func (p *RateLimitProcessor) ProcessSpan(span *models.Span) error {
+	if !p.allow(span.Service, 1) {
+		return ErrRateLimited
+	}
+	return p.Processor.ProcessSpan(span)
+}
+
+// ProcessTrace charges every span of the trace to the root span's service
+func (p *RateLimitProcessor) ProcessTrace(trace *models.Trace) error {
+	service := ""
+	if trace.Root != nil {
+		service = trace.Root.Service
+	}
+	if !p.allow(service, len(trace.Spans)) {
+		return ErrRateLimited
+	}
+	return p.Processor.ProcessTrace(trace)
+}
+
+// allow takes n tokens from the service's bucket
+func (p *RateLimitProcessor) allow(service string, n int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	return p.bucketLocked(service, now).Take(n, now)
+}
+
+// allowBatch takes the given number of tokens, capped at the burst, from
+// the bucket of each service, or none if any bucket falls short
+func (p *RateLimitProcessor) allowBatch(counts map[string]int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	charges := make(map[*TokenBucket]int, len(counts))
+	for service, n := range counts {
+		bucket := p.bucketLocked(service, now)
+		if bucket.limit.Rate <= 0 {
+			continue
+		}
+		if burst := bucket.burst(); burst >= 1 && float64(n) > burst {
+			n = int(burst)
+		}
+		bucket.refill(now)
+		if bucket.tokens < float64(n) {
+			return false
+		}
+		charges[bucket] = n
+	}
+
+	for bucket, n := range charges {
+		bucket.Take(n, now)
+	}
+	return true
+}
+
+// bucketLocked returns the service's bucket, creating a full one for a new
+// service. The caller must hold p.mu.
+func (p *RateLimitProcessor) bucketLocked(service string, now time.Time) *TokenBucket {
+	bucket, ok := p.buckets[service]
+	if !ok {
+		if len(p.buckets) >= maxRateLimitedServices {
+			p.forgetIdleLocked(now)
+		}
+		limit, ok := p.config.Services[service]
+		if !ok {
+			limit = p.config.Default
+		}
+		bucket = NewTokenBucket(limit, now)
+		p.buckets[service] = bucket
+	}
+	return bucket
+}
+
+// forgetIdleLocked drops the buckets of services idle long enough for them to
+// refill, as a new bucket for them would be the same. The caller must hold
+// p.mu.
+func (p *RateLimitProcessor) forgetIdleLocked(now time.Time) {
+	for service, bucket := range p.buckets {
+		if bucket.idle(now) {
+			delete(p.buckets, service)
+		}
+	}
+}
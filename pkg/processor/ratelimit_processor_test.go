@@ -0,0 +1,200 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func TestRateLimitProcessor_PerServiceBuckets(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewRateLimitProcessor(next, RateLimitConfig{
+		Default:  RateLimit{Rate: 1, Burst: 2},
+		Services: map[string]RateLimit{"checkout": {Rate: 1, Burst: 5}},
+	})
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	send := func(service string) error {
+		return p.ProcessLog(models.NewLogEntry(service, "hello", models.LogLevelInfo))
+	}
+
+	// The noisy service exhausts its default burst
+	for i := 0; i < 2; i++ {
+		if err := send("noisy"); err != nil {
+			t.Fatalf("expected log %d to be accepted, got: %v", i, err)
+		}
+	}
+	if err := send("noisy"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got: %v", err)
+	}
+
+	// Another service on the default budget has its own bucket
+	if err := send("quiet"); err != nil {
+		t.Errorf("expected quiet service to be unaffected, got: %v", err)
+	}
+
+	// The override gives checkout a larger burst
+	for i := 0; i < 5; i++ {
+		if err := send("checkout"); err != nil {
+			t.Fatalf("expected checkout log %d to be accepted, got: %v", i, err)
+		}
+	}
+	if err := send("checkout"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected checkout to hit its limit, got: %v", err)
+	}
+
+	// Tokens refill over time
+	now = now.Add(time.Second)
+	if err := send("noisy"); err != nil {
+		t.Errorf("expected noisy service to recover after refill, got: %v", err)
+	}
+
+	if len(next.logs) != 9 {
+		t.Errorf("expected 9 logs passed downstream, got %d", len(next.logs))
+	}
+}
+
+func TestRateLimitProcessor_TraceChargesAllSpans(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewRateLimitProcessor(next, RateLimitConfig{Default: RateLimit{Rate: 1, Burst: 2}})
+	p.now = func() time.Time { return time.Unix(0, 0) }
+
+	root := models.NewSpan("root", "api", "trace-1")
+	trace := &models.Trace{ID: "trace-1", Root: root, Spans: []*models.Span{
+		root,
+		models.NewSpan("a", "api", "trace-1"),
+		models.NewSpan("b", "api", "trace-1"),
+	}}
+
+	if err := p.ProcessTrace(trace); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected 3-span trace to exceed a burst of 2, got: %v", err)
+	}
+}
+
+func TestRateLimitProcessor_MetricBatch(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewRateLimitProcessor(next, RateLimitConfig{Default: RateLimit{Rate: 1, Burst: 3}})
+	p.now = func() time.Time { return time.Unix(0, 0) }
+
+	batch := func(services ...string) []*models.Metric {
+		var metrics []*models.Metric
+		for _, service := range services {
+			metrics = append(metrics, models.NewMetric("requests", 1, models.MetricTypeCounter, service))
+		}
+		return metrics
+	}
+
+	// Each service is charged for its own metrics
+	if err := p.ProcessMetrics(batch("api", "api", "worker", "worker", "worker")); err != nil {
+		t.Fatalf("expected batch within budget to be accepted, got: %v", err)
+	}
+	if len(next.metrics) != 5 {
+		t.Errorf("expected 5 metrics passed downstream, got %d", len(next.metrics))
+	}
+
+	// One service over budget rejects the whole batch
+	if err := p.ProcessMetrics(batch("api", "worker")); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got: %v", err)
+	}
+	if len(next.metrics) != 5 {
+		t.Errorf("expected rejected batch not to be passed downstream, got %d metrics", len(next.metrics))
+	}
+}
+
+func TestRateLimitProcessor_LogBatch(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewRateLimitProcessor(next, RateLimitConfig{Default: RateLimit{Rate: 1, Burst: 3}})
+	p.now = func() time.Time { return time.Unix(0, 0) }
+
+	batch := func(services ...string) []*models.LogEntry {
+		var logs []*models.LogEntry
+		for _, service := range services {
+			logs = append(logs, models.NewLogEntry(service, "hello", models.LogLevelInfo))
+		}
+		return logs
+	}
+
+	// A batch larger than the burst passes on a full bucket and empties it
+	if err := p.ProcessLogs(batch("api", "api", "api", "api", "api")); err != nil {
+		t.Fatalf("expected a batch over the burst to pass on a full bucket, got: %v", err)
+	}
+
+	// A service over budget rejects the batch without charging the others
+	if err := p.ProcessLogs(batch("worker", "worker", "api")); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got: %v", err)
+	}
+	if err := p.ProcessLogs(batch("worker", "worker", "worker")); err != nil {
+		t.Errorf("expected the worker's budget to be intact, got: %v", err)
+	}
+	if len(next.logs) != 8 {
+		t.Errorf("expected 8 logs passed downstream, got %d", len(next.logs))
+	}
+}
+
+func TestRateLimitProcessor_ForgetsIdleBuckets(t *testing.T) {
+	p := NewRateLimitProcessor(&recordingProcessor{}, RateLimitConfig{Default: RateLimit{Rate: 1, Burst: 2}})
+	now := time.Unix(0, 0)
+	p.now = func() time.Time { return now }
+
+	// Fill the map with services that have each spent their whole budget
+	for i := 0; i < maxRateLimitedServices; i++ {
+		if !p.allow(fmt.Sprintf("service-%d", i), 2) {
+			t.Fatalf("expected service-%d to be allowed", i)
+		}
+	}
+	if p.allow("service-0", 1) {
+		t.Fatal("expected service-0 to be over budget")
+	}
+
+	// Buckets still refilling are kept when a new service arrives
+	now = now.Add(time.Second)
+	p.allow("late", 2)
+	if len(p.buckets) != maxRateLimitedServices+1 {
+		t.Errorf("expected no bucket to be forgotten, got %d buckets", len(p.buckets))
+	}
+
+	// Buckets full again are forgotten
+	now = now.Add(time.Second)
+	p.allow("later", 1)
+	if len(p.buckets) != 2 {
+		t.Errorf("expected only the late and later buckets to remain, got %d", len(p.buckets))
+	}
+}
+
+func TestParseServiceRateLimit(t *testing.T) {
+	service, limit, err := ParseServiceRateLimit("checkout=500:1000")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if service != "checkout" || limit.Rate != 500 || limit.Burst != 1000 {
+		t.Errorf("unexpected limit for %s: %+v", service, limit)
+	}
+
+	for _, invalid := range []string{"checkout", "=5", "checkout=fast", "checkout=5:many"} {
+		if _, _, err := ParseServiceRateLimit(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestTokenBucket_RetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := NewTokenBucket(RateLimit{Rate: 4, Burst: 2}, now)
+
+	if retryAfter := bucket.RetryAfter(1); retryAfter != 0 {
+		t.Errorf("expected no wait with a full bucket, got %v", retryAfter)
+	}
+	if !bucket.Take(2, now) || bucket.Take(1, now) {
+		t.Fatalf("expected the burst to be spent after 2 tokens")
+	}
+	if retryAfter := bucket.RetryAfter(1); retryAfter != 250*time.Millisecond {
+		t.Errorf("expected a 250ms wait for one token, got %v", retryAfter)
+	}
+	if !bucket.Take(1, now.Add(250*time.Millisecond)) {
+		t.Errorf("expected a token after waiting")
+	}
+}
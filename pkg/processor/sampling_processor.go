@@ -0,0 +1,219 @@
+package processor
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// SamplingProcessor applies head-based sampling to traces and spans before
+// they reach the next processor. Each trace is kept with a fixed probability,
+// decided from a hash of its trace ID so that spans of the same trace
+// submitted separately share the decision. The decision is overridden:
+//
+//   - to keep traces with an ERROR span, and ERROR spans submitted alone
+//   - otherwise by an upstream decision recorded in the SamplingPriorityTag
+//     of a span, e.g. from the sampled flag of a W3C traceparent header
+//
+// Logs and metrics pass through unsampled, but logs of a trace that is
+// sampled out are tagged with TraceSampledTag set to "false" so that their
+// trace ID isn't shown as a link to a trace that was never stored. Logs
+// follow the decision taken for the spans of their trace seen so far, and
+// the probabilistic decision otherwise. Later spans of a trace follow the
+// overridden decision too.
+type SamplingProcessor struct {
+	Processor
+
+	rate    float64 // Probability of keeping a trace, from 0 to 1
+	salt    uint64  // Mixed into the trace ID hash, drawn from the source given to WithSource
+	dropped atomic.Uint64
+
+	mu        sync.Mutex
+	overrides map[string]samplingOverride // Decisions that differ from Sampled, keyed by trace ID
+	pruned    time.Time                   // When expired overrides were last removed
+	now       func() time.Time
+}
+
+// TraceSampledTag marks logs whose trace was sampled out
+const TraceSampledTag = "trace.sampled"
+
+// samplingOverrideTTL is how long a decision overriding the probabilistic
+// one is remembered for the logs of the trace
+const samplingOverrideTTL = 10 * time.Minute
+
+// samplingOverride is a remembered decision for a trace
+type samplingOverride struct {
+	keep    bool
+	expires time.Time
+}
+
+// NewSamplingProcessor creates a sampling processor wrapping next that keeps
+// traces with probability rate
+func NewSamplingProcessor(next Processor, rate float64) *SamplingProcessor {
+	if rate < 0 {
+		rate = 0
+	}
+	if rate > 1 {
+		rate = 1
+	}
+	return &SamplingProcessor{
+		Processor: next,
+		rate:      rate,
+		overrides: make(map[string]samplingOverride),
+		now:       time.Now,
+	}
+}
+
+// WithSource makes the probabilistic decisions depend on a value drawn from
+// src as well as on the trace ID, and returns p. Processors given sources of
+// the same seed decide alike, so tests can fix the seed; by default every
+// processor decides alike, also across instances. It must be called before
+// any telemetry is processed.
+func (p *SamplingProcessor) WithSource(src rand.Source) *SamplingProcessor {
+	p.salt = uint64(src.Int63())
+	return p
+}
+
+// Dropped returns the number of traces and spans sampled out so far
+func (p *SamplingProcessor) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// ProcessLog marks the log if its trace is sampled out and passes it
+// downstream
+func (p *SamplingProcessor) ProcessLog(log *models.LogEntry) error {
+	if log.TraceID != "" && !p.traceKept(log.TraceID) {
+		log.AddTag(TraceSampledTag, "false")
+	}
+	return p.Processor.ProcessLog(log)
+}
+
+// ProcessLogs marks the logs of sampled out traces and passes the batch
+// downstream
+func (p *SamplingProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	for _, log := range logs {
+		if log.TraceID != "" && !p.traceKept(log.TraceID) {
+			log.AddTag(TraceSampledTag, "false")
+		}
+	}
+	return p.Processor.ProcessLogs(logs)
+}
+
+// ProcessSpan passes the span downstream if its trace is sampled
+func (p *SamplingProcessor) ProcessSpan(span *models.Span) error {
+	if !p.keep(span.TraceID, span) {
+		p.dropped.Add(1)
+		return nil
+	}
+	return p.Processor.ProcessSpan(span)
+}
+
+// ProcessTrace passes the trace downstream if it is sampled
+func (p *SamplingProcessor) ProcessTrace(trace *models.Trace) error {
+	spans := trace.Spans
+	if len(spans) == 0 && trace.Root != nil {
+		spans = []*models.Span{trace.Root}
+	}
+	if !p.keep(trace.ID, spans...) {
+		p.dropped.Add(1)
+		return nil
+	}
+	return p.Processor.ProcessTrace(trace)
+}
+
+// keep decides whether to keep the given spans of a trace, remembering
+// the decision for the later spans and the logs of the trace when it
+// overrides Sampled
+func (p *SamplingProcessor) keep(traceID string, spans ...*models.Span) bool {
+	keep, explicit := p.decide(traceID, spans)
+	return p.remember(traceID, keep, explicit)
+}
+
+// decide applies the overrides of the spans, reporting whether one applied,
+// and the probabilistic decision otherwise
+func (p *SamplingProcessor) decide(traceID string, spans []*models.Span) (keep, explicit bool) {
+	for _, span := range spans {
+		if span.Status == models.SpanStatusError {
+			return true, true
+		}
+	}
+	for _, span := range spans {
+		if sampled, ok := span.SamplingDecision(); ok {
+			return sampled, true
+		}
+	}
+	return p.Sampled(traceID), false
+}
+
+// remember records a decision for a trace where it overrides Sampled and
+// returns the decision to apply. A trace with a kept span stays kept,
+// whatever is decided for its later spans, and later spans without an
+// override of their own follow the decision remembered for their trace.
+func (p *SamplingProcessor) remember(traceID string, keep, explicit bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if now.Sub(p.pruned) >= samplingOverrideTTL {
+		for id, override := range p.overrides {
+			if now.After(override.expires) {
+				delete(p.overrides, id)
+			}
+		}
+		p.pruned = now
+	}
+
+	if previous, ok := p.overrides[traceID]; ok && !now.After(previous.expires) {
+		if previous.keep || !explicit {
+			keep = previous.keep
+		}
+	}
+	if keep == p.Sampled(traceID) {
+		delete(p.overrides, traceID)
+		return keep
+	}
+	p.overrides[traceID] = samplingOverride{keep: keep, expires: now.Add(samplingOverrideTTL)}
+	return keep
+}
+
+// traceKept reports whether the spans of a trace are kept, as far as known
+func (p *SamplingProcessor) traceKept(traceID string) bool {
+	p.mu.Lock()
+	override, ok := p.overrides[traceID]
+	p.mu.Unlock()
+
+	if ok && !p.now().After(override.expires) {
+		return override.keep
+	}
+	return p.Sampled(traceID)
+}
+
+// Sampled reports whether the probabilistic decision keeps the trace. The
+// decision only depends on the trace ID, the rate and the source given to
+// WithSource.
+func (p *SamplingProcessor) Sampled(traceID string) bool {
+	return sampleTraceID(traceID, p.rate, p.salt)
+}
+
+// sampleTraceID keeps a trace with probability rate, deciding from a hash of
+// its ID, salted unless salt is zero, so that every span of the trace gets
+// the same decision
+func sampleTraceID(traceID string, rate float64, salt uint64) bool {
+	if rate >= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	if salt != 0 {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], salt)
+		h.Write(b[:])
+	}
+	h.Write([]byte(traceID))
+	// Map the top 53 bits of the hash to [0, 1)
+	return float64(h.Sum64()>>11)/(1<<53) < rate
+}
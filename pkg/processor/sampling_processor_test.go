@@ -0,0 +1,267 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func TestSamplingProcessor_Ratio(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewSamplingProcessor(next, 0.25)
+
+	const traces = 10000
+	for i := 0; i < traces; i++ {
+		trace, _ := models.NewTrace("GET /", "api")
+		if err := p.ProcessTrace(trace); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+
+	ratio := float64(len(next.traces)) / traces
+	if math.Abs(ratio-0.25) > 0.03 {
+		t.Errorf("expected about 25%% of traces to be kept, got %.1f%%", ratio*100)
+	}
+	if int(p.Dropped())+len(next.traces) != traces {
+		t.Errorf("expected %d dropped traces, got %d", traces-len(next.traces), p.Dropped())
+	}
+}
+
+func TestSamplingProcessor_Seed(t *testing.T) {
+	decisions := func(p *SamplingProcessor) []bool {
+		kept := make([]bool, 1000)
+		for i := range kept {
+			kept[i] = p.Sampled(fmt.Sprintf("trace-%d", i))
+		}
+		return kept
+	}
+	differ := func(a, b []bool) int {
+		n := 0
+		for i := range a {
+			if a[i] != b[i] {
+				n++
+			}
+		}
+		return n
+	}
+
+	seeded := decisions(NewSamplingProcessor(&recordingProcessor{}, 0.5).WithSource(rand.NewSource(7)))
+	if n := differ(seeded, decisions(NewSamplingProcessor(&recordingProcessor{}, 0.5).WithSource(rand.NewSource(7)))); n != 0 {
+		t.Errorf("expected the same decisions with the same seed, got %d different", n)
+	}
+	if n := differ(seeded, decisions(NewSamplingProcessor(&recordingProcessor{}, 0.5).WithSource(rand.NewSource(8)))); n == 0 {
+		t.Error("expected other decisions with another seed")
+	}
+	if n := differ(decisions(NewSamplingProcessor(&recordingProcessor{}, 0.5)), decisions(NewSamplingProcessor(&recordingProcessor{}, 0.5))); n != 0 {
+		t.Errorf("expected the same decisions without a seed, got %d different", n)
+	}
+
+	// Tail sampling keeps the same traces as head sampling with the same seed
+	tail := NewTailSamplingProcessor(&recordingProcessor{}, TailSamplingPolicy{Rate: 0.5}, time.Minute, 0).WithSource(rand.NewSource(7))
+	for i, kept := range seeded {
+		if tail.policy.Keep(TraceSample{ID: fmt.Sprintf("trace-%d", i)}) != kept {
+			t.Fatalf("expected tail sampling to decide trace-%d like head sampling", i)
+		}
+	}
+}
+
+func TestSamplingProcessor_ConsistentSpans(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewSamplingProcessor(next, 0.5)
+
+	// Spans of a trace are kept or dropped together
+	for i := 0; i < 100; i++ {
+		traceID := models.GenerateID()
+		for j := 0; j < 3; j++ {
+			p.ProcessSpan(models.NewSpan("query", "db", traceID))
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, span := range next.spans {
+		counts[span.TraceID]++
+	}
+	for traceID, count := range counts {
+		if count != 3 {
+			t.Errorf("expected all 3 spans of %s, got %d", traceID, count)
+		}
+	}
+	if len(counts) == 0 || len(counts) == 100 {
+		t.Errorf("expected some traces to be sampled out, kept %d of 100", len(counts))
+	}
+}
+
+func TestSamplingProcessor_KeepsErrors(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewSamplingProcessor(next, 0)
+
+	trace, root := models.NewTrace("GET /checkout", "api")
+	child := models.NewSpan("charge", "payments", trace.ID).SetParent(root.ID).SetStatus(models.SpanStatusError)
+	trace.Spans = append(trace.Spans, child)
+	healthy, _ := models.NewTrace("GET /health", "api")
+
+	for _, trace := range []*models.Trace{trace, healthy} {
+		if err := p.ProcessTrace(trace); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	if len(next.traces) != 1 || next.traces[0] != trace {
+		t.Errorf("expected only the trace with an error span to be kept, got %d traces", len(next.traces))
+	}
+
+	// An ERROR span outweighs an upstream decision to drop its trace, and
+	// its trace stays kept for the spans that follow
+	failed := models.NewSpan("charge", "payments", "trace-1").SetStatus(models.SpanStatusError)
+	failed.AddTag(models.SamplingPriorityTag, "0")
+	p.ProcessSpan(failed)
+	p.ProcessSpan(models.NewSpan("query", "db", "trace-1"))
+	p.ProcessSpan(models.NewSpan("query", "db", "trace-2"))
+	if len(next.spans) != 2 || next.spans[0] != failed || next.spans[1].TraceID != "trace-1" {
+		t.Errorf("expected both spans of the trace with an ERROR span to be kept, got %d spans", len(next.spans))
+	}
+}
+
+func TestSamplingProcessor_MultiSpanTrace(t *testing.T) {
+	next := &recordingProcessor{}
+	keepNone := NewSamplingProcessor(next, 0)
+	keepAll := NewSamplingProcessor(next, 1)
+
+	// Spans after the one sampled upstream follow its decision, both ways
+	sampled := models.NewSpan("GET /", "api", "trace-sampled")
+	sampled.AddTag(models.SamplingPriorityTag, "1")
+	keepNone.ProcessSpan(sampled)
+	unsampled := models.NewSpan("GET /", "api", "trace-unsampled")
+	unsampled.AddTag(models.SamplingPriorityTag, "0")
+	keepAll.ProcessSpan(unsampled)
+	for i := 0; i < 3; i++ {
+		keepNone.ProcessSpan(models.NewSpan("query", "db", "trace-sampled"))
+		keepAll.ProcessSpan(models.NewSpan("query", "db", "trace-unsampled"))
+	}
+
+	if len(next.spans) != 4 {
+		t.Fatalf("expected the 4 spans of the trace sampled upstream, got %d spans", len(next.spans))
+	}
+	for _, span := range next.spans {
+		if span.TraceID != "trace-sampled" {
+			t.Errorf("expected only spans of the sampled trace, got one of %s", span.TraceID)
+		}
+	}
+
+	// A later span with a decision of its own can still keep the trace
+	late := models.NewSpan("retry", "api", "trace-unsampled").SetStatus(models.SpanStatusError)
+	keepAll.ProcessSpan(late)
+	if last := next.spans[len(next.spans)-1]; last != late {
+		t.Errorf("expected the ERROR span to be kept, got %v", last)
+	}
+}
+
+func TestSamplingProcessor_UpstreamDecision(t *testing.T) {
+	next := &recordingProcessor{}
+	keepNone := NewSamplingProcessor(next, 0)
+	keepAll := NewSamplingProcessor(next, 1)
+
+	sampled, _ := models.NewTrace("GET /", "api")
+	sampled.Root.AddTag(models.SamplingPriorityTag, "1")
+	if err := keepNone.ProcessTrace(sampled); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(next.traces) != 1 {
+		t.Errorf("expected a trace sampled upstream to be kept, got %d traces", len(next.traces))
+	}
+
+	unsampled := models.NewSpan("GET /", "api", "trace-1")
+	unsampled.AddTag(models.SamplingPriorityTag, "0")
+	if err := keepAll.ProcessSpan(unsampled); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(next.spans) != 0 {
+		t.Errorf("expected a span sampled out upstream to be dropped, got %d spans", len(next.spans))
+	}
+}
+
+func TestSamplingProcessor_PassesLogsAndMetrics(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewSamplingProcessor(next, 0)
+
+	p.ProcessLog(models.NewLogEntry("api", "hello", models.LogLevelInfo))
+	p.ProcessMetric(models.NewMetric("requests", 1, models.MetricTypeCounter, "api"))
+	if len(next.logs) != 1 || len(next.metrics) != 1 {
+		t.Errorf("expected logs and metrics to pass through, got %d logs and %d metrics", len(next.logs), len(next.metrics))
+	}
+}
+
+func TestSamplingProcessor_MarksLogsOfSampledOutTraces(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewSamplingProcessor(next, 0.5)
+
+	kept := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		traceID := models.GenerateID()
+		p.ProcessSpan(models.NewSpan("query", "db", traceID))
+		p.ProcessLog(models.NewLogEntry("db", "querying", models.LogLevelInfo).WithTrace(traceID, ""))
+	}
+	for _, span := range next.spans {
+		kept[span.TraceID] = true
+	}
+
+	if len(next.logs) != 100 {
+		t.Fatalf("expected all 100 logs to pass through, got %d", len(next.logs))
+	}
+	for _, log := range next.logs {
+		marked := log.Tags[TraceSampledTag] == "false"
+		if marked == kept[log.TraceID] {
+			t.Errorf("expected the log of trace %s to be marked only if the trace was dropped, kept %v, marked %v",
+				log.TraceID, kept[log.TraceID], marked)
+		}
+	}
+	if len(kept) == 0 || len(kept) == 100 {
+		t.Errorf("expected some traces to be sampled out, kept %d of 100", len(kept))
+	}
+}
+
+func TestSamplingProcessor_LogsFollowOverrides(t *testing.T) {
+	next := &recordingProcessor{}
+	keepNone := NewSamplingProcessor(next, 0)
+	keepAll := NewSamplingProcessor(next, 1)
+
+	failed := models.NewSpan("charge", "payments", "trace-error").SetStatus(models.SpanStatusError)
+	keepNone.ProcessSpan(failed)
+	keepNone.ProcessSpan(models.NewSpan("query", "db", "trace-error"))
+	sampled := models.NewSpan("GET /", "api", "trace-sampled")
+	sampled.AddTag(models.SamplingPriorityTag, "1")
+	keepNone.ProcessSpan(sampled)
+	unsampled := models.NewSpan("GET /", "api", "trace-unsampled")
+	unsampled.AddTag(models.SamplingPriorityTag, "0")
+	keepAll.ProcessSpan(unsampled)
+
+	for _, c := range []struct {
+		p       *SamplingProcessor
+		traceID string
+		marked  bool
+	}{
+		{keepNone, "trace-error", false},
+		{keepNone, "trace-sampled", false},
+		{keepNone, "trace-unknown", true},
+		{keepNone, "", false},
+		{keepAll, "trace-unsampled", true},
+		{keepAll, "trace-unknown", false},
+	} {
+		log := models.NewLogEntry("api", "hello", models.LogLevelInfo).WithTrace(c.traceID, "")
+		c.p.ProcessLog(log)
+		if marked := log.Tags[TraceSampledTag] == "false"; marked != c.marked {
+			t.Errorf("expected the log of trace %q marked %v, got tags %v", c.traceID, c.marked, log.Tags)
+		}
+	}
+
+	// Overrides are forgotten after a while
+	keepNone.now = func() time.Time { return time.Now().Add(samplingOverrideTTL + time.Minute) }
+	log := models.NewLogEntry("api", "late", models.LogLevelInfo).WithTrace("trace-error", "")
+	keepNone.ProcessLog(log)
+	if log.Tags[TraceSampledTag] != "false" {
+		t.Errorf("expected an expired override to fall back to the sampling rate, got tags %v", log.Tags)
+	}
+}
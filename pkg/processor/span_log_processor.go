@@ -0,0 +1,54 @@
+package processor
+
+import (
+	"sync/atomic"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// SpanLogLimitProcessor caps the number of logs stored per span, keeping the
+// first and last logs of each span and a marker counting the ones dropped in
+// between (see models.Span.CapLogs)
+type SpanLogLimitProcessor struct {
+	Processor
+
+	head    int
+	tail    int
+	dropped atomic.Uint64
+}
+
+// NewSpanLogLimitProcessor creates a processor wrapping next that keeps the
+// first head and last tail logs of every span
+func NewSpanLogLimitProcessor(next Processor, head, tail int) *SpanLogLimitProcessor {
+	return &SpanLogLimitProcessor{
+		Processor: next,
+		head:      head,
+		tail:      tail,
+	}
+}
+
+// Dropped returns the number of span logs discarded so far
+func (p *SpanLogLimitProcessor) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// ProcessSpan caps the logs of the span and passes it downstream
+func (p *SpanLogLimitProcessor) ProcessSpan(span *models.Span) error {
+	p.capLogs(span)
+	return p.Processor.ProcessSpan(span)
+}
+
+// ProcessTrace caps the logs of every span of the trace and passes it downstream
+func (p *SpanLogLimitProcessor) ProcessTrace(trace *models.Trace) error {
+	for _, span := range trace.Spans {
+		p.capLogs(span)
+	}
+	return p.Processor.ProcessTrace(trace)
+}
+
+// capLogs caps the logs of a span, counting the logs dropped
+func (p *SpanLogLimitProcessor) capLogs(span *models.Span) {
+	if dropped := span.CapLogs(p.head, p.tail); dropped > 0 {
+		p.dropped.Add(uint64(dropped))
+	}
+}
@@ -0,0 +1,46 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func TestSpanLogLimitProcessor(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewSpanLogLimitProcessor(next, 2, 1)
+
+	chatty := models.NewSpan("import", "worker", "trace-1")
+	for i := 0; i < 50; i++ {
+		chatty.AddLog(map[string]string{"event": "row imported"})
+	}
+	quiet := models.NewSpan("notify", "worker", "trace-1")
+	quiet.AddLog(map[string]string{"event": "sent"})
+
+	if err := p.ProcessSpan(chatty); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(next.spans) != 1 || len(next.spans[0].Logs) != 4 {
+		t.Fatalf("expected the span to reach the next processor with 4 logs, got %d", len(chatty.Logs))
+	}
+	if marker := chatty.Logs[2]; marker.Fields["event"] != models.SpanLogsDroppedEvent || marker.Fields["dropped_count"] != "47" {
+		t.Errorf("unexpected marker: %v", marker.Fields)
+	}
+
+	// Every span of a trace is capped
+	big := models.NewSpan("export", "worker", "trace-2")
+	for i := 0; i < 10; i++ {
+		big.AddLog(map[string]string{"event": "chunk"})
+	}
+	trace := &models.Trace{ID: "trace-2", Spans: []*models.Span{big, quiet}, Root: big}
+	if err := p.ProcessTrace(trace); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(next.traces) != 1 || len(big.Logs) != 4 || len(quiet.Logs) != 1 {
+		t.Errorf("expected capped trace spans, got %d and %d logs", len(big.Logs), len(quiet.Logs))
+	}
+
+	if dropped := p.Dropped(); dropped != 54 {
+		t.Errorf("expected 54 dropped logs, got %d", dropped)
+	}
+}
@@ -22,11 +22,26 @@ func (p *StorageProcessor) ProcessLog(log *models.LogEntry) error {
 	return p.storage.SaveLog(log)
 }
 
+// ProcessLogs persists a batch of logs to storage in one write
+func (p *StorageProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	return p.storage.SaveLogs(logs)
+}
+
 // ProcessMetric persists a metric to storage
 func (p *StorageProcessor) ProcessMetric(metric *models.Metric) error {
 	return p.storage.SaveMetric(metric)
 }
 
+// ProcessMetrics persists a batch of metrics to storage in one write
+func (p *StorageProcessor) ProcessMetrics(metrics []*models.Metric) error {
+	return p.storage.SaveMetrics(metrics)
+}
+
+// ProcessHistogram persists a histogram metric and its buckets to storage
+func (p *StorageProcessor) ProcessHistogram(histogram *models.HistogramMetric) error {
+	return p.storage.SaveHistogramMetric(histogram)
+}
+
 // ProcessSpan persists a span to storage
 func (p *StorageProcessor) ProcessSpan(span *models.Span) error {
 	return p.storage.SaveSpan(span)
@@ -43,55 +58,131 @@ func (p *StorageProcessor) QueryLogs(query *models.QueryParams) (map[string]inte
 	return p.storage.QueryLogs(query)
 }
 
+// StreamLogs streams logs from storage
+func (p *StorageProcessor) StreamLogs(query *models.QueryParams, fn func(log *models.LogEntry) error) error {
+	// Delegate to the storage implementation
+	return p.storage.StreamLogs(query, fn)
+}
+
 // QueryMetrics queries metrics from storage
-func (p *StorageProcessor) QueryMetrics(query *models.QueryParams) ([]map[string]interface{}, error) {
+func (p *StorageProcessor) QueryMetrics(query *models.QueryParams) (map[string]interface{}, error) {
 	// Delegate to the storage implementation
 	return p.storage.QueryMetrics(query)
 }
 
+// StreamMetrics streams metrics from storage
+func (p *StorageProcessor) StreamMetrics(query *models.QueryParams, fn func(metric *models.Metric) error) error {
+	// Delegate to the storage implementation
+	return p.storage.StreamMetrics(query, fn)
+}
+
+// QueryHistograms queries histograms from storage
+func (p *StorageProcessor) QueryHistograms(query *models.QueryParams) ([]*models.HistogramMetric, error) {
+	// Delegate to the storage implementation
+	return p.storage.QueryHistograms(query)
+}
+
 // QueryTraces queries traces from storage
-func (p *StorageProcessor) QueryTraces(query *models.QueryParams) ([]map[string]interface{}, error) {
+func (p *StorageProcessor) QueryTraces(query *models.QueryParams) (map[string]interface{}, error) {
 	// Delegate to the storage implementation
 	return p.storage.QueryTraces(query)
 }
 
+// QueryTraceIDs queries trace IDs from storage
+func (p *StorageProcessor) QueryTraceIDs(query *models.QueryParams) (map[string]interface{}, error) {
+	// Delegate to the storage implementation
+	return p.storage.QueryTraceIDs(query)
+}
+
+// QueryTraceSamples queries trace samples from storage
+func (p *StorageProcessor) QueryTraceSamples(query *models.QueryParams) (map[string]interface{}, error) {
+	// Delegate to the storage implementation
+	return p.storage.QueryTraceSamples(query)
+}
+
+// QuerySlowTraces queries the traces slower than a duration percentile from storage
+func (p *StorageProcessor) QuerySlowTraces(query *models.QueryParams, percentile float64) (map[string]interface{}, error) {
+	// Delegate to the storage implementation
+	return p.storage.QuerySlowTraces(query, percentile)
+}
+
+// StreamSpans streams spans from storage
+func (p *StorageProcessor) StreamSpans(query *models.QueryParams, fn func(span *models.Span) error) error {
+	// Delegate to the storage implementation
+	return p.storage.StreamSpans(query, fn)
+}
+
 // QuerySpans queries spans from storage
-func (p *StorageProcessor) QuerySpans(query *models.QueryParams) ([]map[string]interface{}, error) {
+func (p *StorageProcessor) QuerySpans(query *models.QueryParams) (map[string]interface{}, error) {
 	// Delegate to the storage implementation
 	return p.storage.QuerySpans(query)
 }
 
+// CountSpanErrorsByType counts ERROR spans grouped by error type
+func (p *StorageProcessor) CountSpanErrorsByType(query *models.QueryParams) ([]map[string]interface{}, error) {
+	// Delegate to the storage implementation
+	return p.storage.CountSpanErrorsByType(query)
+}
+
+// AggregateMetrics aggregates metrics if the storage backend supports it
+func (p *StorageProcessor) AggregateMetrics(query storage.MetricQuery) ([]storage.MetricAggregation, error) {
+	aggregator, ok := p.storage.(storage.MetricAggregator)
+	if !ok {
+		return nil, storage.ErrAggregationUnsupported
+	}
+	return aggregator.AggregateMetrics(query)
+}
+
+// GetTrace returns a stored trace with all of its spans
+func (p *StorageProcessor) GetTrace(traceID string) (*models.Trace, error) {
+	// Delegate to the storage implementation
+	return p.storage.GetTrace(traceID)
+}
+
+// GetSpanAncestors returns the stored ancestors of a span
+func (p *StorageProcessor) GetSpanAncestors(spanID string) ([]*models.Span, error) {
+	// Delegate to the storage implementation
+	return p.storage.GetSpanAncestors(spanID)
+}
+
 // GetServices returns a list of available services
 func (p *StorageProcessor) GetServices() ([]string, error) {
 	// Delegate to the storage implementation
 	return p.storage.GetServices()
 }
 
-// GetStats returns summary statistics
-func (p *StorageProcessor) GetStats(query *models.QueryParams) (map[string]interface{}, error) {
-	// For now, return a placeholder implementation
-	return map[string]interface{}{
-		"logs": map[string]interface{}{
-			"total": 1245,
-			"by_level": map[string]int{
-				"INFO":    987,
-				"WARNING": 142,
-				"ERROR":   116,
-			},
-		},
-		"metrics": map[string]interface{}{
-			"total": 5432,
-			"by_type": map[string]int{
-				"counter":   1234,
-				"gauge":     3456,
-				"histogram": 742,
-			},
-		},
-		"traces": map[string]interface{}{
-			"total":           876,
-			"avg_duration_ms": 123.45,
-		},
-	}, nil
+// GetActiveServices returns the most recently active services
+func (p *StorageProcessor) GetActiveServices(limit int) ([]string, error) {
+	return p.storage.GetActiveServices(limit)
+}
+
+// UpdateLogTags merges tags into the stored logs matching the query
+func (p *StorageProcessor) UpdateLogTags(query *models.QueryParams, tags map[string]string) (int64, error) {
+	// Delegate to the storage implementation
+	return p.storage.UpdateLogTags(query, tags)
+}
+
+// DeleteByService removes all stored telemetry of a service
+func (p *StorageProcessor) DeleteByService(service string) (int64, error) {
+	// Delegate to the storage implementation
+	return p.storage.DeleteByService(service)
+}
+
+// ClearAll removes all stored telemetry
+func (p *StorageProcessor) ClearAll() (int64, error) {
+	// Delegate to the storage implementation
+	return p.storage.ClearAll()
+}
+
+// GetStats counts the logs, metrics and traces matching query
+func (p *StorageProcessor) GetStats(query *models.QueryParams) (*models.Stats, error) {
+	// Delegate to the storage implementation
+	return p.storage.GetStats(query)
+}
+
+// Ping checks that the storage is reachable
+func (p *StorageProcessor) Ping() error {
+	return p.storage.Ping()
 }
 
 // Close closes the processor
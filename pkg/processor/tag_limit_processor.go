@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// TagLimitProcessor enforces the models.SetMaxTags limit on items whose tags
+// were set without AddTag, such as decoded OTLP spans or imported logs, before
+// passing them on. Tags past the limit are dropped in sorted key order, so
+// the same tags are kept whatever the ingestion path.
+type TagLimitProcessor struct {
+	Processor
+}
+
+// NewTagLimitProcessor creates a processor wrapping next that limits the tags
+// of the logs, metrics, histograms and spans it processes
+func NewTagLimitProcessor(next Processor) *TagLimitProcessor {
+	return &TagLimitProcessor{Processor: next}
+}
+
+// ProcessLog limits the tags of the log and processes it
+func (p *TagLimitProcessor) ProcessLog(log *models.LogEntry) error {
+	models.LimitTags(log.Tags)
+	return p.Processor.ProcessLog(log)
+}
+
+// ProcessLogs limits the tags of each log of the batch and processes it
+func (p *TagLimitProcessor) ProcessLogs(logs []*models.LogEntry) error {
+	for _, log := range logs {
+		models.LimitTags(log.Tags)
+	}
+	return p.Processor.ProcessLogs(logs)
+}
+
+// ProcessMetric limits the tags of the metric and processes it
+func (p *TagLimitProcessor) ProcessMetric(metric *models.Metric) error {
+	models.LimitTags(metric.Tags)
+	return p.Processor.ProcessMetric(metric)
+}
+
+// ProcessMetrics limits the tags of each metric of the batch and processes it
+func (p *TagLimitProcessor) ProcessMetrics(metrics []*models.Metric) error {
+	for _, metric := range metrics {
+		models.LimitTags(metric.Tags)
+	}
+	return p.Processor.ProcessMetrics(metrics)
+}
+
+// ProcessHistogram limits the tags of the histogram and processes it
+func (p *TagLimitProcessor) ProcessHistogram(histogram *models.HistogramMetric) error {
+	models.LimitTags(histogram.Tags)
+	return p.Processor.ProcessHistogram(histogram)
+}
+
+// ProcessSpan limits the tags of the span and processes it
+func (p *TagLimitProcessor) ProcessSpan(span *models.Span) error {
+	models.LimitTags(span.Tags)
+	return p.Processor.ProcessSpan(span)
+}
+
+// ProcessTrace limits the tags of each span of the trace and processes it
+func (p *TagLimitProcessor) ProcessTrace(trace *models.Trace) error {
+	if trace.Root != nil {
+		models.LimitTags(trace.Root.Tags)
+	}
+	for _, span := range trace.Spans {
+		models.LimitTags(span.Tags)
+	}
+	return p.Processor.ProcessTrace(trace)
+}
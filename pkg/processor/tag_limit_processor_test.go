@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func TestTagLimitProcessor(t *testing.T) {
+	defer models.SetMaxTags(1)()
+
+	next := &recordingProcessor{}
+	p := NewTagLimitProcessor(next)
+
+	// Tags set directly, as decoders do, are limited in sorted key order
+	tags := func() map[string]string { return map[string]string{"zone": "a", "region": "eu"} }
+	p.ProcessLog(&models.LogEntry{Service: "api", Tags: tags()})
+	p.ProcessMetrics([]*models.Metric{{Name: "requests", Tags: tags()}})
+	root := &models.Span{Name: "GET /", Tags: tags()}
+	p.ProcessTrace(&models.Trace{Root: root, Spans: []*models.Span{root, {Name: "query", Tags: tags()}}})
+
+	var limited []map[string]string
+	for _, log := range next.logs {
+		limited = append(limited, log.Tags)
+	}
+	for _, metric := range next.metrics {
+		limited = append(limited, metric.Tags)
+	}
+	for _, trace := range next.traces {
+		for _, span := range trace.Spans {
+			limited = append(limited, span.Tags)
+		}
+	}
+	if len(limited) != 4 {
+		t.Fatalf("expected 4 processed items, got %d", len(limited))
+	}
+	for _, tags := range limited {
+		if len(tags) != 1 || tags["region"] != "eu" {
+			t.Errorf("expected only the region tag, got %v", tags)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package processor
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// DefaultTailSamplingPercentile is the latency percentile above which a
+// tail sampling report keeps traces unless configured otherwise
+const DefaultTailSamplingPercentile = 95.0
+
+// TraceSample is what tail-based sampling looks at to decide on a complete
+// trace
+type TraceSample struct {
+	ID         string
+	DurationMs int64 // Total duration of the trace
+	Error      bool  // Whether any span of the trace failed
+}
+
+// TailSamplingReport compares, over a set of stored traces, how many traces
+// tail-based sampling would keep with keeping every trace, as done without
+// sampling, and with head-based sampling at the same rate. Kept counts are
+// expected values, since the traces that are neither failed nor slow are
+// kept with probability Rate.
+type TailSamplingReport struct {
+	Traces        int     `json:"traces"`         // Traces in the window
+	ErrorTraces   int     `json:"error_traces"`   // Traces with an ERROR span, always kept
+	SlowTraces    int     `json:"slow_traces"`    // Traces without errors slower than ThresholdMs, kept by tail sampling
+	SampledTraces int     `json:"sampled_traces"` // Other traces, kept with probability Rate
+	Percentile    float64 `json:"percentile"`     // Latency percentile setting the threshold
+	ThresholdMs   int64   `json:"threshold_ms"`   // Duration at Percentile; slower traces are slow
+	Rate          float64 `json:"rate"`           // Probability of keeping the other traces
+
+	KeepAll  int     `json:"keep_all"`  // Traces kept without sampling
+	HeadKept float64 `json:"head_kept"` // Traces kept by head sampling, which keeps errors but can't see latency
+	TailKept float64 `json:"tail_kept"` // Traces kept by tail sampling
+}
+
+// ReportTailSampling computes the tail sampling report of traces: failed
+// traces and those slower than the given percentile of their durations are
+// kept, and the rest are kept with probability rate. The threshold is the
+// nearest-rank percentile, so with percentile 95 about the slowest 5% of
+// the traces count as slow.
+func ReportTailSampling(traces []TraceSample, percentile, rate float64) TailSamplingReport {
+	report := TailSamplingReport{
+		Traces:     len(traces),
+		Percentile: percentile,
+		Rate:       rate,
+		KeepAll:    len(traces),
+	}
+	if len(traces) == 0 {
+		return report
+	}
+
+	durations := make([]int64, len(traces))
+	for i, trace := range traces {
+		durations[i] = trace.DurationMs
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	rank := int(math.Ceil(percentile/100*float64(len(durations)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(durations) {
+		rank = len(durations) - 1
+	}
+	report.ThresholdMs = durations[rank]
+
+	for _, trace := range traces {
+		switch {
+		case trace.Error:
+			report.ErrorTraces++
+		case trace.DurationMs > report.ThresholdMs:
+			report.SlowTraces++
+		default:
+			report.SampledTraces++
+		}
+	}
+
+	report.HeadKept = float64(report.ErrorTraces) + rate*float64(report.Traces-report.ErrorTraces)
+	report.TailKept = float64(report.ErrorTraces+report.SlowTraces) + rate*float64(report.SampledTraces)
+	return report
+}
+
+// TailSamplingPolicy decides which complete traces tail-based sampling keeps
+type TailSamplingPolicy struct {
+	LatencyThreshold time.Duration // Traces longer than this are kept; zero keeps none for their latency
+	Rate             float64       // Probability of keeping the other traces, from 0 to 1
+
+	salt uint64 // Mixed into the trace ID hash, drawn from the source given to WithSource
+}
+
+// Keep reports whether the policy keeps a trace: always if it failed or is
+// slower than the latency threshold, and with probability Rate otherwise,
+// decided from a hash of the trace ID
+func (p TailSamplingPolicy) Keep(trace TraceSample) bool {
+	if trace.Error {
+		return true
+	}
+	if p.LatencyThreshold > 0 && trace.DurationMs > p.LatencyThreshold.Milliseconds() {
+		return true
+	}
+	return sampleTraceID(trace.ID, p.Rate, p.salt)
+}
+
+// DefaultTailSamplingTimeout is how long TailSamplingProcessor buffers a
+// trace whose root span hasn't arrived unless configured otherwise
+const DefaultTailSamplingTimeout = 30 * time.Second
+
+// DefaultTailSamplingMaxPending is how many traces TailSamplingProcessor
+// buffers at most unless configured otherwise
+const DefaultTailSamplingMaxPending = 10000
+
+// TailSamplingProcessor applies tail-based sampling to traces before they
+// reach the next processor. Unlike SamplingProcessor, which decides on each
+// span as it arrives, it buffers the spans of a trace until the trace is
+// complete and applies the policy to the whole trace, so that every failed
+// or slow trace is kept. A trace is complete when its root span, the span
+// without a parent, arrives with ProcessSpan or in a batch of ProcessTrace;
+// batches without it are buffered like single spans. Traces whose root span
+// hasn't arrived within the timeout of their first span are decided on the
+// spans buffered so far by FlushExpired, which Start calls periodically.
+// When the buffer holds the maximum number of traces, the oldest one is
+// decided on its spans so far to make room for a new one.
+//
+// Kept traces are passed downstream whole with ProcessTrace. Spans arriving
+// after their trace was decided follow the decision. Logs and metrics pass
+// through unsampled.
+type TailSamplingProcessor struct {
+	Processor
+
+	policy     TailSamplingPolicy
+	timeout    time.Duration
+	maxPending int
+	dropped    atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[string]*pendingTrace    // Spans of undecided traces, keyed by trace ID
+	order   []string                    // IDs of the pending traces, oldest first, with stale IDs of traces decided since
+	decided map[string]samplingOverride // Recent decisions, for late spans, keyed by trace ID
+	pruned  time.Time                   // When expired decisions were last removed
+	now     func() time.Time
+
+	stop func() // Stops the periodic flush
+}
+
+// pendingTrace is a trace buffered until it is complete
+type pendingTrace struct {
+	spans     []*models.Span
+	firstSeen time.Time
+}
+
+// tailDecision is a decision on a trace to pass downstream once p.mu is
+// released
+type tailDecision struct {
+	trace *models.Trace
+	keep  bool
+}
+
+// NewTailSamplingProcessor creates a tail sampling processor wrapping next
+// that applies policy to complete traces, and to incomplete ones timeout
+// after their first span or when maxPending traces are buffered. A timeout
+// or maxPending of zero takes the default.
+func NewTailSamplingProcessor(next Processor, policy TailSamplingPolicy, timeout time.Duration, maxPending int) *TailSamplingProcessor {
+	if policy.Rate < 0 {
+		policy.Rate = 0
+	}
+	if policy.Rate > 1 {
+		policy.Rate = 1
+	}
+	if timeout <= 0 {
+		timeout = DefaultTailSamplingTimeout
+	}
+	if maxPending <= 0 {
+		maxPending = DefaultTailSamplingMaxPending
+	}
+	return &TailSamplingProcessor{
+		Processor:  next,
+		policy:     policy,
+		timeout:    timeout,
+		maxPending: maxPending,
+		pending:    make(map[string]*pendingTrace),
+		decided:    make(map[string]samplingOverride),
+		now:        time.Now,
+	}
+}
+
+// WithSource makes the probabilistic decisions of the policy depend on a
+// value drawn from src as well as on the trace ID, and returns p, like
+// SamplingProcessor.WithSource. It must be called before any telemetry is
+// processed.
+func (p *TailSamplingProcessor) WithSource(src rand.Source) *TailSamplingProcessor {
+	p.policy.salt = uint64(src.Int63())
+	return p
+}
+
+// Dropped returns the number of traces and late spans sampled out so far
+func (p *TailSamplingProcessor) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Pending returns the number of traces buffered until they are complete
+func (p *TailSamplingProcessor) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.pending)
+}
+
+// ProcessSpan buffers the span until its trace is complete, and decides on
+// the trace when the span is its root
+func (p *TailSamplingProcessor) ProcessSpan(span *models.Span) error {
+	p.mu.Lock()
+	if keep, ok := p.decisionLocked(span.TraceID); ok {
+		p.mu.Unlock()
+		if !keep {
+			p.dropped.Add(1)
+			return nil
+		}
+		return p.Processor.ProcessSpan(span)
+	}
+
+	if span.ParentID != "" {
+		forced := p.bufferLocked(span.TraceID, []*models.Span{span})
+		p.mu.Unlock()
+		return p.flushAll(forced)
+	}
+
+	spans := []*models.Span{span}
+	if pending := p.pending[span.TraceID]; pending != nil {
+		delete(p.pending, span.TraceID)
+		spans = append(pending.spans, span)
+	}
+	trace := models.AssembleTrace(span.TraceID, spans)
+	keep := p.decideLocked(trace)
+	p.mu.Unlock()
+
+	return p.flush(trace, keep)
+}
+
+// ProcessTrace decides on a trace whose batch holds its root span, along
+// with any of its spans buffered before, and buffers the spans of batches
+// without it until the trace is complete
+func (p *TailSamplingProcessor) ProcessTrace(trace *models.Trace) error {
+	spans := trace.Spans
+	if len(spans) == 0 && trace.Root != nil {
+		spans = []*models.Span{trace.Root}
+	}
+
+	p.mu.Lock()
+	keep, ok := p.decisionLocked(trace.ID)
+	if !ok {
+		if !hasRootSpan(spans) {
+			forced := p.bufferLocked(trace.ID, spans)
+			p.mu.Unlock()
+			return p.flushAll(forced)
+		}
+
+		if pending := p.pending[trace.ID]; pending != nil {
+			delete(p.pending, trace.ID)
+			trace = models.AssembleTrace(trace.ID, append(pending.spans, spans...))
+		} else {
+			trace.ComputeStatus()
+			if trace.Duration == 0 {
+				trace.ComputeDuration()
+			}
+		}
+		keep = p.decideLocked(trace)
+	}
+	p.mu.Unlock()
+
+	return p.flush(trace, keep)
+}
+
+// hasRootSpan reports whether spans hold a span without a parent
+func hasRootSpan(spans []*models.Span) bool {
+	for _, span := range spans {
+		if span.ParentID == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferLocked adds spans to the pending trace with the given ID. When the
+// trace is new and the buffer is full, the oldest pending trace is decided
+// and returned to be flushed. The caller must hold p.mu.
+func (p *TailSamplingProcessor) bufferLocked(traceID string, spans []*models.Span) []tailDecision {
+	var forced []tailDecision
+	pending := p.pending[traceID]
+	if pending == nil {
+		if len(p.pending) >= p.maxPending {
+			forced = p.evictOldestLocked()
+		}
+		pending = &pendingTrace{firstSeen: p.now()}
+		p.pending[traceID] = pending
+		p.order = append(p.order, traceID)
+		if len(p.order) > 2*p.maxPending {
+			p.compactOrderLocked()
+		}
+	}
+	pending.spans = append(pending.spans, spans...)
+	return forced
+}
+
+// evictOldestLocked decides on the oldest pending trace. The caller must
+// hold p.mu.
+func (p *TailSamplingProcessor) evictOldestLocked() []tailDecision {
+	for len(p.order) > 0 {
+		traceID := p.order[0]
+		p.order = p.order[1:]
+		pending, ok := p.pending[traceID]
+		if !ok {
+			continue // Decided since it was buffered
+		}
+		delete(p.pending, traceID)
+		trace := models.AssembleTrace(traceID, pending.spans)
+		return []tailDecision{{trace, p.decideLocked(trace)}}
+	}
+	return nil
+}
+
+// compactOrderLocked drops the IDs of traces no longer pending from p.order.
+// The caller must hold p.mu.
+func (p *TailSamplingProcessor) compactOrderLocked() {
+	order := make([]string, 0, len(p.pending))
+	seen := make(map[string]bool, len(p.pending))
+	for _, traceID := range p.order {
+		if _, ok := p.pending[traceID]; ok && !seen[traceID] {
+			seen[traceID] = true
+			order = append(order, traceID)
+		}
+	}
+	p.order = order
+}
+
+// FlushExpired decides on the traces buffered for longer than the timeout,
+// as their root span may never arrive. It returns the first error of the
+// next processor.
+func (p *TailSamplingProcessor) FlushExpired() error {
+	return p.flushPending(false)
+}
+
+// Start calls FlushExpired each interval until Close is called
+func (p *TailSamplingProcessor) Start(interval time.Duration) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.FlushExpired(); err != nil {
+					log.Printf("Error flushing sampled traces: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	p.mu.Lock()
+	p.stop = func() { close(done) }
+	p.mu.Unlock()
+}
+
+// Close stops the periodic flush, decides on every buffered trace and closes
+// the next processor
+func (p *TailSamplingProcessor) Close() error {
+	p.mu.Lock()
+	if p.stop != nil {
+		p.stop()
+		p.stop = nil
+	}
+	p.mu.Unlock()
+
+	flushErr := p.flushPending(true)
+	if err := p.Processor.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// flushPending decides on the buffered traces, only those past the timeout
+// unless all is set, and returns the first error of the next processor
+func (p *TailSamplingProcessor) flushPending(all bool) error {
+	p.mu.Lock()
+	now := p.now()
+	var decisions []tailDecision
+	for traceID, pending := range p.pending {
+		if !all && now.Sub(pending.firstSeen) < p.timeout {
+			continue
+		}
+		delete(p.pending, traceID)
+		trace := models.AssembleTrace(traceID, pending.spans)
+		decisions = append(decisions, tailDecision{trace, p.decideLocked(trace)})
+	}
+	p.compactOrderLocked()
+	p.mu.Unlock()
+
+	return p.flushAll(decisions)
+}
+
+// flushAll flushes decisions and returns the first error of the next
+// processor
+func (p *TailSamplingProcessor) flushAll(decisions []tailDecision) error {
+	var firstErr error
+	for _, d := range decisions {
+		if err := p.flush(d.trace, d.keep); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// flush passes a kept trace downstream and counts a dropped one
+func (p *TailSamplingProcessor) flush(trace *models.Trace, keep bool) error {
+	if !keep {
+		p.dropped.Add(1)
+		return nil
+	}
+	return p.Processor.ProcessTrace(trace)
+}
+
+// decideLocked applies the policy to a trace with computed status and
+// duration, remembering the decision for its late spans. The caller must
+// hold p.mu.
+func (p *TailSamplingProcessor) decideLocked(trace *models.Trace) bool {
+	keep := p.policy.Keep(TraceSample{
+		ID:         trace.ID,
+		DurationMs: trace.Duration,
+		Error:      trace.Status == models.SpanStatusError,
+	})
+
+	now := p.now()
+	if now.Sub(p.pruned) >= samplingOverrideTTL {
+		for traceID, decision := range p.decided {
+			if now.After(decision.expires) {
+				delete(p.decided, traceID)
+			}
+		}
+		p.pruned = now
+	}
+	p.decided[trace.ID] = samplingOverride{keep: keep, expires: now.Add(samplingOverrideTTL)}
+	return keep
+}
+
+// decisionLocked returns the decision taken for a trace, if it is recent.
+// The caller must hold p.mu.
+func (p *TailSamplingProcessor) decisionLocked(traceID string) (keep bool, ok bool) {
+	decision, ok := p.decided[traceID]
+	if !ok || p.now().After(decision.expires) {
+		return false, false
+	}
+	return decision.keep, true
+}
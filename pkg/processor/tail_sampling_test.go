@@ -0,0 +1,239 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func TestReportTailSampling(t *testing.T) {
+	// 20 traces of 10ms to 200ms, with the 30ms and 200ms ones failed
+	var traces []TraceSample
+	for i := 1; i <= 20; i++ {
+		traces = append(traces, TraceSample{
+			ID:         fmt.Sprintf("trace-%d", i),
+			DurationMs: int64(i * 10),
+			Error:      i == 3 || i == 20,
+		})
+	}
+
+	report := ReportTailSampling(traces, 90, 0.1)
+
+	// The 90th percentile is the 18th duration; only the 190ms trace is
+	// slower without having failed
+	if report.Traces != 20 || report.KeepAll != 20 {
+		t.Errorf("expected 20 traces all kept without sampling, got %+v", report)
+	}
+	if report.ThresholdMs != 180 {
+		t.Errorf("expected a threshold of 180ms, got %d", report.ThresholdMs)
+	}
+	if report.ErrorTraces != 2 || report.SlowTraces != 1 || report.SampledTraces != 17 {
+		t.Errorf("expected 2 error, 1 slow and 17 sampled traces, got %+v", report)
+	}
+	if math.Abs(report.TailKept-4.7) > 1e-9 {
+		t.Errorf("expected tail sampling to keep 3 + 0.1 * 17 = 4.7 traces, got %v", report.TailKept)
+	}
+	if math.Abs(report.HeadKept-3.8) > 1e-9 {
+		t.Errorf("expected head sampling to keep 2 + 0.1 * 18 = 3.8 traces, got %v", report.HeadKept)
+	}
+}
+
+func TestReportTailSampling_Edges(t *testing.T) {
+	if report := ReportTailSampling(nil, 95, 0.5); report.Traces != 0 || report.TailKept != 0 || report.Percentile != 95 {
+		t.Errorf("expected an empty report, got %+v", report)
+	}
+
+	// Ties at the threshold are not slow
+	traces := []TraceSample{{ID: "a", DurationMs: 50}, {ID: "b", DurationMs: 50}, {ID: "c", DurationMs: 50}}
+	report := ReportTailSampling(traces, 50, 0)
+	if report.ThresholdMs != 50 || report.SlowTraces != 0 || report.TailKept != 0 {
+		t.Errorf("expected no slow traces among equal durations, got %+v", report)
+	}
+
+	report = ReportTailSampling(traces, 50, 1)
+	if report.TailKept != 3 || report.HeadKept != 3 {
+		t.Errorf("expected a rate of 1 to keep every trace, got %+v", report)
+	}
+}
+
+// tailSamplingSpans returns a root span of the given duration and a child
+// span, children first as they usually finish first
+func tailSamplingSpans(traceID string, duration time.Duration, status models.SpanStatus) (*models.Span, *models.Span) {
+	start := time.Unix(1700000000, 0).UTC()
+	root := models.NewSpan("GET /checkout", "api", traceID)
+	root.StartTime, root.EndTime = start, start.Add(duration)
+	root.Duration = duration.Milliseconds()
+	child := models.NewSpan("charge", "payments", traceID).SetParent(root.ID).SetStatus(status)
+	child.StartTime, child.EndTime = start, start.Add(duration/2)
+	child.Duration = (duration / 2).Milliseconds()
+	return root, child
+}
+
+func TestTailSamplingProcessor(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		status   models.SpanStatus
+		kept     bool
+	}{
+		{"error kept", 10 * time.Millisecond, models.SpanStatusError, true},
+		{"slow kept", 2 * time.Second, models.SpanStatusOK, true},
+		{"sampled dropped", 10 * time.Millisecond, models.SpanStatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingProcessor{}
+			p := NewTailSamplingProcessor(next, TailSamplingPolicy{LatencyThreshold: time.Second, Rate: 0}, time.Minute, 0)
+
+			root, child := tailSamplingSpans("trace-1", tt.duration, tt.status)
+			if err := p.ProcessSpan(child); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(next.traces) != 0 || p.Pending() != 1 {
+				t.Fatalf("expected the child span to be buffered, got %d traces and %d pending", len(next.traces), p.Pending())
+			}
+			if err := p.ProcessSpan(root); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Pending() != 0 {
+				t.Errorf("expected the trace to be decided once its root arrived, got %d pending", p.Pending())
+			}
+
+			if !tt.kept {
+				if len(next.traces) != 0 || p.Dropped() != 1 {
+					t.Errorf("expected the trace dropped, got %d traces and %d dropped", len(next.traces), p.Dropped())
+				}
+				return
+			}
+			if len(next.traces) != 1 || p.Dropped() != 0 {
+				t.Fatalf("expected the trace kept, got %d traces and %d dropped", len(next.traces), p.Dropped())
+			}
+			trace := next.traces[0]
+			if trace.ID != "trace-1" || len(trace.Spans) != 2 || trace.Root != root {
+				t.Errorf("expected the whole trace passed downstream, got %+v", trace)
+			}
+		})
+	}
+}
+
+func TestTailSamplingProcessor_TimeoutFlush(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewTailSamplingProcessor(next, TailSamplingPolicy{Rate: 0}, 30*time.Second, 0)
+	now := time.Unix(1700000000, 0)
+	p.now = func() time.Time { return now }
+
+	// The root span of the failed trace never arrives
+	_, failed := tailSamplingSpans("failed", 10*time.Millisecond, models.SpanStatusError)
+	_, ok := tailSamplingSpans("ok", 10*time.Millisecond, models.SpanStatusOK)
+	p.ProcessSpan(failed)
+	p.ProcessSpan(ok)
+
+	now = now.Add(20 * time.Second)
+	_, recent := tailSamplingSpans("recent", 10*time.Millisecond, models.SpanStatusError)
+	p.ProcessSpan(recent)
+
+	now = now.Add(15 * time.Second)
+	if err := p.FlushExpired(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(next.traces) != 1 || next.traces[0].ID != "failed" {
+		t.Fatalf("expected the expired failed trace flushed, got %d traces", len(next.traces))
+	}
+	if p.Dropped() != 1 || p.Pending() != 1 {
+		t.Errorf("expected the expired ok trace dropped and the recent trace still pending, got %d dropped and %d pending",
+			p.Dropped(), p.Pending())
+	}
+
+	// Late spans follow the decision taken on their trace
+	late := models.NewSpan("retry", "payments", "failed").SetParent(failed.ID)
+	p.ProcessSpan(late)
+	if len(next.spans) != 1 || next.spans[0] != late {
+		t.Errorf("expected the late span of the kept trace passed downstream, got %d spans", len(next.spans))
+	}
+	p.ProcessSpan(models.NewSpan("retry", "payments", "ok").SetParent(ok.ID))
+	if len(next.spans) != 1 || p.Dropped() != 2 {
+		t.Errorf("expected the late span of the dropped trace dropped, got %d spans and %d dropped", len(next.spans), p.Dropped())
+	}
+
+	// Closing flushes the traces still pending
+	if err := p.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(next.traces) != 2 || next.traces[1].ID != "recent" || p.Pending() != 0 {
+		t.Errorf("expected the pending trace flushed on close, got %d traces and %d pending", len(next.traces), p.Pending())
+	}
+}
+
+func TestTailSamplingProcessor_ProcessTrace(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewTailSamplingProcessor(next, TailSamplingPolicy{Rate: 1}, time.Minute, 0)
+
+	root, child := tailSamplingSpans("trace-1", 10*time.Millisecond, models.SpanStatusOK)
+	p.ProcessSpan(child)
+	if err := p.ProcessTrace(&models.Trace{ID: "trace-1", Spans: []*models.Span{root}, Root: root}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(next.traces) != 1 || len(next.traces[0].Spans) != 2 || p.Pending() != 0 {
+		t.Errorf("expected the buffered span merged into the submitted trace, got %d traces and %d pending",
+			len(next.traces), p.Pending())
+	}
+}
+
+func TestTailSamplingProcessor_ProcessTraceBatches(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewTailSamplingProcessor(next, TailSamplingPolicy{Rate: 0}, time.Minute, 0)
+
+	// The failed child arrives in a batch of its own, as exporters send the
+	// spans that ended first
+	root, child := tailSamplingSpans("trace-1", 10*time.Millisecond, models.SpanStatusError)
+	if err := p.ProcessTrace(&models.Trace{ID: "trace-1", Spans: []*models.Span{child}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(next.traces) != 0 || p.Pending() != 1 {
+		t.Fatalf("expected the batch without a root buffered, got %d traces and %d pending", len(next.traces), p.Pending())
+	}
+
+	// The batch with the root completes the trace, decided on both spans
+	if err := p.ProcessTrace(&models.Trace{ID: "trace-1", Spans: []*models.Span{root}, Root: root}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(next.traces) != 1 || len(next.traces[0].Spans) != 2 || p.Pending() != 0 {
+		t.Fatalf("expected the failed trace kept whole, got %d traces and %d pending", len(next.traces), p.Pending())
+	}
+	if next.traces[0].Status != models.SpanStatusError {
+		t.Errorf("expected the trace to fail, got %s", next.traces[0].Status)
+	}
+}
+
+func TestTailSamplingProcessor_MaxPending(t *testing.T) {
+	next := &recordingProcessor{}
+	p := NewTailSamplingProcessor(next, TailSamplingPolicy{Rate: 0}, time.Minute, 2)
+
+	_, first := tailSamplingSpans("first", 10*time.Millisecond, models.SpanStatusError)
+	_, second := tailSamplingSpans("second", 10*time.Millisecond, models.SpanStatusOK)
+	_, third := tailSamplingSpans("third", 10*time.Millisecond, models.SpanStatusOK)
+	p.ProcessSpan(first)
+	p.ProcessSpan(second)
+	if len(next.traces) != 0 || p.Pending() != 2 {
+		t.Fatalf("expected 2 traces buffered, got %d traces and %d pending", len(next.traces), p.Pending())
+	}
+
+	// A third trace forces a decision on the oldest
+	if err := p.ProcessTrace(&models.Trace{ID: "third", Spans: []*models.Span{third}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(next.traces) != 1 || next.traces[0].ID != "first" || p.Pending() != 2 {
+		t.Fatalf("expected the oldest trace decided early, got %d traces and %d pending", len(next.traces), p.Pending())
+	}
+
+	// More spans of a buffered trace don't evict anything
+	p.ProcessSpan(models.NewSpan("retry", "payments", "second").SetParent(second.ParentID))
+	if len(next.traces) != 1 || p.Dropped() != 0 || p.Pending() != 2 {
+		t.Errorf("expected no decision, got %d traces, %d dropped and %d pending", len(next.traces), p.Dropped(), p.Pending())
+	}
+}
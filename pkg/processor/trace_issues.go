@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// Trace issue types reported by DetectTraceIssues
+const (
+	IssueNPlusOne = "n_plus_one" // Repeated identical child spans under one parent
+	IssueSlowLeaf = "slow_leaf"  // A span without children taking most of the trace
+)
+
+// DefaultNPlusOneThreshold is how many identical sibling spans make an n+1
+// pattern unless configured otherwise
+const DefaultNPlusOneThreshold = 5
+
+// DefaultSlowLeafRatio is the share of the root span's duration a leaf span
+// must take to be reported as slow unless configured otherwise
+const DefaultSlowLeafRatio = 0.5
+
+// TraceIssueOptions configures the heuristics of DetectTraceIssues. Zero
+// values use the defaults.
+type TraceIssueOptions struct {
+	NPlusOneThreshold int     // Identical siblings needed to flag an n+1 pattern
+	SlowLeafRatio     float64 // Share of the root duration that flags a leaf span
+}
+
+// TraceIssue is a likely performance problem found in a trace
+type TraceIssue struct {
+	Type          string   `json:"type"`                // Kind of issue, e.g. n_plus_one
+	Message       string   `json:"message"`             // Human readable description
+	ParentID      string   `json:"parent_id,omitempty"` // Parent of the spans involved
+	SpanName      string   `json:"span_name"`           // Name of the spans involved
+	Statement     string   `json:"statement,omitempty"` // Repeated db.statement, if any
+	SpanIDs       []string `json:"span_ids"`            // Spans involved in the issue
+	Count         int      `json:"count"`               // Number of spans involved
+	TotalDuration int64    `json:"total_duration_ms"`   // Combined duration of the spans
+}
+
+// DetectTraceIssues looks for common performance problems in the spans of a
+// trace:
+//
+//   - n_plus_one: at least NPlusOneThreshold spans under the same parent with
+//     the same service and name, and the same db.statement if they have one,
+//     as produced by issuing one query per item of a list
+//   - slow_leaf: a span without children taking at least SlowLeafRatio of the
+//     root span's duration
+//
+// n+1 patterns are reported first, then slow leaves, each in the order their
+// first span appears in spans.
+func DetectTraceIssues(spans []*models.Span, opts TraceIssueOptions) []TraceIssue {
+	if opts.NPlusOneThreshold <= 0 {
+		opts.NPlusOneThreshold = DefaultNPlusOneThreshold
+	}
+	if opts.SlowLeafRatio <= 0 {
+		opts.SlowLeafRatio = DefaultSlowLeafRatio
+	}
+
+	issues := []TraceIssue{}
+
+	// Group siblings by what they do, keeping the groups in first seen order
+	type siblingKey struct {
+		parentID, service, name, statement string
+	}
+	var keys []siblingKey
+	groups := make(map[siblingKey][]*models.Span)
+	hasChildren := make(map[string]bool)
+	var root *models.Span
+	for _, span := range spans {
+		if span.ParentID == "" {
+			if root == nil {
+				root = span
+			}
+			continue
+		}
+		hasChildren[span.ParentID] = true
+
+		key := siblingKey{span.ParentID, span.Service, span.Name, span.Tags["db.statement"]}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], span)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < opts.NPlusOneThreshold {
+			continue
+		}
+
+		issue := TraceIssue{
+			Type:      IssueNPlusOne,
+			ParentID:  key.parentID,
+			SpanName:  key.name,
+			Statement: key.statement,
+			Count:     len(group),
+		}
+		for _, span := range group {
+			issue.SpanIDs = append(issue.SpanIDs, span.ID)
+			issue.TotalDuration += span.Duration
+		}
+		issue.Message = fmt.Sprintf("%d %q spans of %s under the same parent took %dms in total; consider batching them",
+			issue.Count, key.name, key.service, issue.TotalDuration)
+		issues = append(issues, issue)
+	}
+
+	if root != nil && root.Duration > 0 {
+		for _, span := range spans {
+			if span == root || hasChildren[span.ID] {
+				continue
+			}
+			if float64(span.Duration) < opts.SlowLeafRatio*float64(root.Duration) {
+				continue
+			}
+			issues = append(issues, TraceIssue{
+				Type:          IssueSlowLeaf,
+				Message:       fmt.Sprintf("%q of %s took %dms of the %dms trace", span.Name, span.Service, span.Duration, root.Duration),
+				ParentID:      span.ParentID,
+				SpanName:      span.Name,
+				Statement:     span.Tags["db.statement"],
+				SpanIDs:       []string{span.ID},
+				Count:         1,
+				TotalDuration: span.Duration,
+			})
+		}
+	}
+
+	return issues
+}
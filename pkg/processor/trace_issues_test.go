@@ -0,0 +1,91 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// nPlusOneTrace builds a request that loads a list of orders and then
+// queries the items of each order one at a time
+func nPlusOneTrace(orders int) []*models.Span {
+	root := models.NewSpan("GET /orders", "api", "trace-1")
+	root.ID = "root"
+	root.Duration = 200
+
+	list := models.NewSpan("SELECT orders", "api", "trace-1").SetParent(root.ID)
+	list.ID = "list"
+	list.Duration = 10
+	list.AddTag("db.statement", "SELECT * FROM orders")
+
+	spans := []*models.Span{root, list}
+	for i := 0; i < orders; i++ {
+		items := models.NewSpan("SELECT items", "api", "trace-1").SetParent(root.ID)
+		items.ID = fmt.Sprintf("items-%d", i)
+		items.Duration = 15
+		items.AddTag("db.statement", "SELECT * FROM items WHERE order_id = ?")
+		spans = append(spans, items)
+	}
+	return spans
+}
+
+func TestDetectTraceIssues_NPlusOne(t *testing.T) {
+	issues := DetectTraceIssues(nPlusOneTrace(6), TraceIssueOptions{})
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %+v", issues)
+	}
+
+	issue := issues[0]
+	if issue.Type != IssueNPlusOne || issue.ParentID != "root" || issue.SpanName != "SELECT items" {
+		t.Errorf("expected an n+1 of SELECT items under root, got %+v", issue)
+	}
+	if issue.Statement != "SELECT * FROM items WHERE order_id = ?" {
+		t.Errorf("expected the repeated statement, got %q", issue.Statement)
+	}
+	if issue.Count != 6 || len(issue.SpanIDs) != 6 || issue.SpanIDs[0] != "items-0" || issue.TotalDuration != 90 {
+		t.Errorf("expected 6 spans taking 90ms, got %+v", issue)
+	}
+
+	// Fewer repeats than the threshold are not flagged
+	if issues := DetectTraceIssues(nPlusOneTrace(4), TraceIssueOptions{}); len(issues) != 0 {
+		t.Errorf("expected no issues below the default threshold, got %+v", issues)
+	}
+	if issues := DetectTraceIssues(nPlusOneTrace(4), TraceIssueOptions{NPlusOneThreshold: 3}); len(issues) != 1 {
+		t.Errorf("expected the configured threshold to apply, got %+v", issues)
+	}
+}
+
+func TestDetectTraceIssues_DistinctSiblings(t *testing.T) {
+	spans := nPlusOneTrace(0)
+	for i := 0; i < 6; i++ {
+		// The same operation with different statements is not repetition
+		query := models.NewSpan("query", "api", "trace-1").SetParent("root")
+		query.AddTag("db.statement", fmt.Sprintf("SELECT * FROM table_%d", i))
+		spans = append(spans, query)
+
+		// Nor are identical spans under different parents
+		nested := models.NewSpan("SELECT items", "api", "trace-1").SetParent(fmt.Sprintf("parent-%d", i))
+		spans = append(spans, nested)
+	}
+
+	if issues := DetectTraceIssues(spans, TraceIssueOptions{}); len(issues) != 0 {
+		t.Errorf("expected no issues, got %+v", issues)
+	}
+}
+
+func TestDetectTraceIssues_SlowLeaf(t *testing.T) {
+	spans := nPlusOneTrace(1)
+	spans[2].Duration = 150
+
+	issues := DetectTraceIssues(spans, TraceIssueOptions{})
+	if len(issues) != 1 || issues[0].Type != IssueSlowLeaf || issues[0].SpanIDs[0] != "items-0" {
+		t.Fatalf("expected items-0 to be a slow leaf, got %+v", issues)
+	}
+
+	// Spans with children are not leaves
+	child := models.NewSpan("parse", "api", "trace-1").SetParent("items-0")
+	if issues := DetectTraceIssues(append(spans, child), TraceIssueOptions{}); len(issues) != 0 {
+		t.Errorf("expected no issues once items-0 has a child, got %+v", issues)
+	}
+}
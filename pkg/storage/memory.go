@@ -0,0 +1,1322 @@
+package storage
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// InMemoryStorage keeps all telemetry in memory and answers queries like
+// SQLiteStorage: the same filters, ordering, default page size and result
+// shapes. Saved items are kept as given and must not be modified afterwards.
+//
+// Its contents can be persisted with Snapshot and Restore, or automatically
+// with WithSnapshotFile.
+type InMemoryStorage struct {
+	mu         sync.RWMutex
+	logs       []*models.LogEntry
+	metrics    []*models.Metric
+	histograms []*models.HistogramMetric
+	spans      []*models.Span
+	spanIndex  map[string]int // Position of each span in spans by ID
+	closed     bool
+
+	// Duration and status of each trace saved whole by SaveTrace, by trace ID
+	traceDurations map[string]int64
+	traceStatuses  map[string]models.SpanStatus
+
+	snapshotFile string
+}
+
+var _ Storage = (*InMemoryStorage)(nil)
+
+// InMemoryOption configures optional behavior of an InMemoryStorage
+type InMemoryOption func(*InMemoryStorage)
+
+// WithSnapshotFile makes NewInMemoryStorage restore the snapshot in path if
+// it exists, and Close write a snapshot to it
+func WithSnapshotFile(path string) InMemoryOption {
+	return func(s *InMemoryStorage) {
+		s.snapshotFile = path
+	}
+}
+
+// NewInMemoryStorage creates an empty in-memory storage, or one restored from
+// its snapshot file
+func NewInMemoryStorage(opts ...InMemoryOption) (*InMemoryStorage, error) {
+	s := &InMemoryStorage{
+		spanIndex:      make(map[string]int),
+		traceDurations: make(map[string]int64),
+		traceStatuses:  make(map[string]models.SpanStatus),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.snapshotFile == "" {
+		return s, nil
+	}
+
+	f, err := os.Open(s.snapshotFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	if err := s.Restore(f); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// memorySnapshot is the serialized content of an InMemoryStorage. Histograms
+// are also stored as metrics and are only kept once.
+type memorySnapshot struct {
+	Logs       []*models.LogEntry
+	Metrics    []*models.Metric
+	Histograms []*models.HistogramMetric
+	Spans      []*models.Span
+
+	TraceDurations map[string]int64
+	TraceStatuses  map[string]models.SpanStatus
+}
+
+// Snapshot writes all stored telemetry to w in gob encoding
+func (s *InMemoryStorage) Snapshot(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.snapshot(w)
+}
+
+// snapshot writes all stored telemetry to w. The caller must hold the lock.
+func (s *InMemoryStorage) snapshot(w io.Writer) error {
+	// Leave out the metric rows of histograms, restored from the histograms
+	histogramMetrics := make(map[*models.Metric]bool, len(s.histograms))
+	for _, histogram := range s.histograms {
+		histogramMetrics[&histogram.Metric] = true
+	}
+	snapshot := memorySnapshot{
+		Logs:       s.logs,
+		Histograms: s.histograms,
+		Spans:      s.spans,
+
+		TraceDurations: s.traceDurations,
+		TraceStatuses:  s.traceStatuses,
+	}
+	for _, metric := range s.metrics {
+		if !histogramMetrics[metric] {
+			snapshot.Metrics = append(snapshot.Metrics, metric)
+		}
+	}
+
+	if err := gob.NewEncoder(w).Encode(&snapshot); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the stored telemetry with a snapshot read from r
+func (s *InMemoryStorage) Restore(r io.Reader) error {
+	var snapshot memorySnapshot
+	if err := gob.NewDecoder(r).Decode(&snapshot); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	s.logs = snapshot.Logs
+	s.metrics = snapshot.Metrics
+	s.histograms = nil
+	s.spans = nil
+	s.spanIndex = make(map[string]int)
+	s.traceDurations = snapshot.TraceDurations
+	if s.traceDurations == nil {
+		s.traceDurations = make(map[string]int64)
+	}
+	s.traceStatuses = snapshot.TraceStatuses
+	if s.traceStatuses == nil {
+		s.traceStatuses = make(map[string]models.SpanStatus)
+	}
+	for _, histogram := range snapshot.Histograms {
+		s.saveHistogram(histogram)
+	}
+	for _, span := range snapshot.Spans {
+		s.saveSpan(span)
+	}
+	return nil
+}
+
+// SaveLog saves a log entry
+func (s *InMemoryStorage) SaveLog(log *models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	s.logs = append(s.logs, log)
+	return nil
+}
+
+// SaveLogs saves a batch of logs
+func (s *InMemoryStorage) SaveLogs(logs []*models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	s.logs = append(s.logs, logs...)
+	return nil
+}
+
+// SaveMetric saves a metric
+func (s *InMemoryStorage) SaveMetric(metric *models.Metric) error {
+	return s.SaveMetrics([]*models.Metric{metric})
+}
+
+// SaveMetrics saves a batch of metrics
+func (s *InMemoryStorage) SaveMetrics(metrics []*models.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	s.metrics = append(s.metrics, metrics...)
+	return nil
+}
+
+// SaveHistogramMetric saves a histogram metric. Like in SQLite, its metric
+// is also returned by metric queries.
+func (s *InMemoryStorage) SaveHistogramMetric(histogram *models.HistogramMetric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	s.saveHistogram(histogram)
+	return nil
+}
+
+// saveHistogram stores a histogram and its metric. The caller must hold the
+// write lock.
+func (s *InMemoryStorage) saveHistogram(histogram *models.HistogramMetric) {
+	s.histograms = append(s.histograms, histogram)
+	s.metrics = append(s.metrics, &histogram.Metric)
+}
+
+// SaveSpan saves a span, replacing any span with the same ID
+func (s *InMemoryStorage) SaveSpan(span *models.Span) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	s.saveSpan(span)
+	return nil
+}
+
+// SaveSpans saves a batch of spans
+func (s *InMemoryStorage) SaveSpans(spans []*models.Span) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	for _, span := range spans {
+		s.saveSpan(span)
+	}
+	return nil
+}
+
+// SaveTrace saves all spans of a trace and records the duration and status
+// of the trace across all its stored spans, as the SQL backends do in the
+// traces table
+func (s *InMemoryStorage) SaveTrace(trace *models.Trace) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+
+	for _, span := range trace.Spans {
+		s.saveSpan(span)
+	}
+
+	var spans []*models.Span
+	for _, span := range s.spans {
+		if span.TraceID == trace.ID {
+			spans = append(spans, span)
+		}
+	}
+	if _, status, duration, ok := mergeTrace(trace, "", s.traceStatuses[trace.ID], spans); ok {
+		s.traceDurations[trace.ID] = duration
+		s.traceStatuses[trace.ID] = status
+	}
+	return nil
+}
+
+// traceDuration returns the total duration of the trace of a root span: the
+// duration recorded when the whole trace was saved, or the root span's own
+// duration for traces saved span by span. The caller must hold the read
+// lock.
+func (s *InMemoryStorage) traceDuration(root *models.Span) int64 {
+	if duration := s.traceDurations[root.TraceID]; duration > 0 {
+		return duration
+	}
+	return root.Duration
+}
+
+// traceStatus returns the status of the trace of a root span: the status
+// recorded when the whole trace was saved, ERROR if any of its spans failed,
+// or the root span's own status for traces saved span by span. The caller
+// must hold the read lock.
+func (s *InMemoryStorage) traceStatus(root *models.Span) models.SpanStatus {
+	if status := s.traceStatuses[root.TraceID]; status != "" {
+		return status
+	}
+	return root.Status
+}
+
+// saveSpan stores a span, replacing any span with the same ID. The caller
+// must hold the write lock.
+func (s *InMemoryStorage) saveSpan(span *models.Span) {
+	if i, ok := s.spanIndex[span.ID]; ok {
+		s.spans[i] = span
+		return
+	}
+	s.spanIndex[span.ID] = len(s.spans)
+	s.spans = append(s.spans, span)
+}
+
+// QueryLogs queries logs, newest first unless query orders them otherwise
+func (s *InMemoryStorage) QueryLogs(query *models.QueryParams) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	var matched []*models.LogEntry
+	for _, log := range s.logs {
+		if logMatches(log, query) {
+			matched = append(matched, log)
+		}
+	}
+
+	sort.SliceStable(matched, logOrder(matched, query))
+
+	// Continue after the cursor, if any
+	page := matched
+	cursor, err := queryLogCursor(query)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		page = matched[sort.Search(len(matched), func(i int) bool {
+			return logAfterCursor(matched[i], cursor)
+		}):]
+	}
+
+	logs := []map[string]interface{}{}
+	var last models.LogCursor
+	start, end := pageBounds(len(page), logPageQuery(query))
+	for _, log := range page[start:end] {
+		logMap := map[string]interface{}{
+			"id":        log.ID,
+			"timestamp": log.Timestamp.Format(time.RFC3339),
+			"service":   log.Service,
+			"level":     string(log.Level),
+			"message":   log.Message,
+		}
+		if len(log.Tags) > 0 {
+			logMap["tags"] = log.Tags
+		}
+		for key, value := range map[string]string{
+			"trace_id": log.TraceID,
+			"span_id":  log.SpanID,
+			"env":      log.Env,
+			"host":     log.Host,
+			"source":   log.Source,
+		} {
+			if value != "" {
+				logMap[key] = value
+			}
+		}
+		logs = append(logs, logMap)
+		last = models.LogCursor{Timestamp: log.Timestamp, ID: log.ID}
+	}
+
+	pagination := paginationInfo(len(matched), query)
+	setNextLogCursor(pagination, query, len(logs), last)
+	return map[string]interface{}{
+		"logs":       logs,
+		"pagination": pagination,
+	}, nil
+}
+
+// StreamLogs calls fn for each log matching the query, oldest first
+func (s *InMemoryStorage) StreamLogs(query *models.QueryParams, fn func(log *models.LogEntry) error) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrStorageClosed
+	}
+	var matched []*models.LogEntry
+	for _, log := range s.logs {
+		if logMatches(log, query) {
+			matched = append(matched, log)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].Timestamp.Equal(matched[j].Timestamp) {
+			return matched[i].Timestamp.Before(matched[j].Timestamp)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+
+	for _, log := range matched {
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logAfterCursor reports whether log comes after the cursor in the newest first
+// order of logs
+func logAfterCursor(log *models.LogEntry, cursor *models.LogCursor) bool {
+	if !log.Timestamp.Equal(cursor.Timestamp) {
+		return log.Timestamp.Before(cursor.Timestamp)
+	}
+	return log.ID < cursor.ID
+}
+
+// logMatches reports whether log matches the filters of query
+func logMatches(log *models.LogEntry, query *models.QueryParams) bool {
+	if query.Service != "" && log.Service != query.Service {
+		return false
+	}
+	if query.Level != "" && string(log.Level) != query.Level {
+		return false
+	}
+	if query.MinLevel != "" && !log.Level.AtLeast(models.LogLevel(query.MinLevel)) {
+		return false
+	}
+	if !InTimeRange(log.Timestamp, query) {
+		return false
+	}
+	if query.TraceID != "" && log.TraceID != query.TraceID {
+		return false
+	}
+	if query.Search != "" && !ContainsFold(query.Search, log.Message, log.Service) {
+		return false
+	}
+	return hasTags(log.Tags, query.Filters) && hasTagKeys(log.Tags, query)
+}
+
+// UpdateLogTags merges tags into the tags of every log matching query,
+// overwriting existing values of the same keys, and returns the number of
+// logs updated. Updated logs are replaced by copies so that results already
+// returned by queries don't change.
+func (s *InMemoryStorage) UpdateLogTags(query *models.QueryParams, tags map[string]string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrStorageClosed
+	}
+
+	var updated int64
+	for i, log := range s.logs {
+		if !logMatches(log, query) {
+			continue
+		}
+		tagged := *log
+		tagged.Tags = models.MergeTags(log.Tags, tags)
+		s.logs[i] = &tagged
+		updated++
+	}
+	return updated, nil
+}
+
+// logOrder returns the less function sorting logs by query.OrderBy, levels
+// by severity, with ties newest first, or newest first if it is not a log
+// field
+func logOrder(logs []*models.LogEntry, query *models.QueryParams) func(i, j int) bool {
+	// Ties are broken by ID so that cursors mark a unique position
+	newest := func(a, b *models.LogEntry) bool {
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.After(b.Timestamp)
+		}
+		return a.ID > b.ID
+	}
+
+	var less func(a, b *models.LogEntry) bool
+	switch query.OrderBy {
+	case "service":
+		less = func(a, b *models.LogEntry) bool { return a.Service < b.Service }
+	case "level":
+		less = func(a, b *models.LogEntry) bool { return a.Level.Severity() < b.Level.Severity() }
+	case "message":
+		less = func(a, b *models.LogEntry) bool { return a.Message < b.Message }
+	case "timestamp":
+		less = func(a, b *models.LogEntry) bool { return a.Timestamp.Before(b.Timestamp) }
+	default:
+		return func(i, j int) bool { return newest(logs[i], logs[j]) }
+	}
+
+	if query.OrderDesc {
+		ascending := less
+		less = func(a, b *models.LogEntry) bool { return ascending(b, a) }
+	}
+	return func(i, j int) bool {
+		a, b := logs[i], logs[j]
+		if less(a, b) || less(b, a) {
+			return less(a, b)
+		}
+		return newest(a, b)
+	}
+}
+
+// matchingMetrics returns the metrics matching the query's service, name and
+// time range, oldest first. The caller must hold the read lock.
+func (s *InMemoryStorage) matchingMetrics(query *models.QueryParams) []*models.Metric {
+	var matched []*models.Metric
+	for _, metric := range s.metrics {
+		if query.Service != "" && metric.Service != query.Service {
+			continue
+		}
+		if query.Name != "" && metric.Name != query.Name {
+			continue
+		}
+		if !InTimeRange(metric.Timestamp, query) {
+			continue
+		}
+		matched = append(matched, metric)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Timestamp.Before(matched[j].Timestamp)
+	})
+	return matched
+}
+
+// listedMetrics returns the matchingMetrics of query that also match its
+// search term and tag key filters, oldest first. The caller must hold the
+// read lock.
+func (s *InMemoryStorage) listedMetrics(query *models.QueryParams) []*models.Metric {
+	var matched []*models.Metric
+	for _, metric := range s.matchingMetrics(query) {
+		if query.Search != "" && !ContainsFold(query.Search, metric.Name, metric.Service) {
+			continue
+		}
+		if !hasTagKeys(metric.Tags, query) {
+			continue
+		}
+		matched = append(matched, metric)
+	}
+	return matched
+}
+
+// QueryMetrics queries metrics, newest first
+func (s *InMemoryStorage) QueryMetrics(query *models.QueryParams) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	matched := s.listedMetrics(query)
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Timestamp.After(matched[j].Timestamp)
+	})
+
+	metrics := []map[string]interface{}{}
+	start, end := pageBounds(len(matched), query)
+	for _, metric := range matched[start:end] {
+		metricMap := map[string]interface{}{
+			"id":        metric.ID,
+			"timestamp": metric.Timestamp.Format(time.RFC3339),
+			"service":   metric.Service,
+			"name":      metric.Name,
+			"value":     metric.Value,
+			"type":      string(metric.Type),
+		}
+		if len(metric.Tags) > 0 {
+			metricMap["tags"] = metric.Tags
+		}
+		metrics = append(metrics, metricMap)
+	}
+
+	return map[string]interface{}{
+		"metrics":    metrics,
+		"pagination": paginationInfo(len(matched), query),
+	}, nil
+}
+
+// StreamMetrics calls fn for each metric matching the query, oldest first
+func (s *InMemoryStorage) StreamMetrics(query *models.QueryParams, fn func(metric *models.Metric) error) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrStorageClosed
+	}
+	matched := s.matchingMetrics(query)
+	s.mu.RUnlock()
+
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+
+	for _, metric := range matched {
+		if err := fn(metric); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryHistograms returns the histogram metrics matching the query, oldest first
+func (s *InMemoryStorage) QueryHistograms(query *models.QueryParams) ([]*models.HistogramMetric, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	var matched []*models.HistogramMetric
+	for _, histogram := range s.histograms {
+		if query.Service != "" && histogram.Service != query.Service {
+			continue
+		}
+		if query.Name != "" && histogram.Name != query.Name {
+			continue
+		}
+		if !InTimeRange(histogram.Timestamp, query) {
+			continue
+		}
+		matched = append(matched, histogram)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Timestamp.Before(matched[j].Timestamp)
+	})
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+	return matched, nil
+}
+
+// matchingSpans returns the spans matching the query's service, time range,
+// trace ID, search and duration range, newest first. The caller must hold
+// the read lock.
+func (s *InMemoryStorage) matchingSpans(query *models.QueryParams) []*models.Span {
+	var matched []*models.Span
+	for _, span := range s.spans {
+		if query.Service != "" && span.Service != query.Service {
+			continue
+		}
+		if !InTimeRange(span.StartTime, query) {
+			continue
+		}
+		if query.TraceID != "" && span.TraceID != query.TraceID {
+			continue
+		}
+		if query.Search != "" && !ContainsFold(query.Search, span.Name, span.Service) {
+			continue
+		}
+		if !InDurationRange(span.Duration, query) {
+			continue
+		}
+		matched = append(matched, span)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].StartTime.After(matched[j].StartTime)
+	})
+	return matched
+}
+
+// StreamSpans calls fn for each span matching the query, oldest first
+func (s *InMemoryStorage) StreamSpans(query *models.QueryParams, fn func(span *models.Span) error) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrStorageClosed
+	}
+	var matched []*models.Span
+	for _, span := range s.matchingSpans(query) {
+		if (query.SpanID == "" || span.ID == query.SpanID) && hasTagKeys(span.Tags, query) {
+			matched = append(matched, span)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].StartTime.Equal(matched[j].StartTime) {
+			return matched[i].StartTime.Before(matched[j].StartTime)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+
+	for _, span := range matched {
+		if err := fn(span); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rootSpans returns the root span of each trace matching the query, newest
+// first; ContainsSpan matches any span of the trace. The caller must hold
+// the read lock.
+func (s *InMemoryStorage) rootSpans(query *models.QueryParams) []*models.Span {
+	var named map[string]bool
+	if query.ContainsSpan != "" {
+		named = make(map[string]bool)
+		for _, span := range s.spans {
+			if span.Name == query.ContainsSpan {
+				named[span.TraceID] = true
+			}
+		}
+	}
+
+	// Duration bounds apply to the whole trace rather than the root span
+	spanQuery := *query
+	spanQuery.MinDuration = 0
+	spanQuery.MaxDuration = 0
+
+	var roots []*models.Span
+	for _, span := range s.matchingSpans(&spanQuery) {
+		if span.ParentID != "" {
+			continue
+		}
+		if named != nil && !named[span.TraceID] {
+			continue
+		}
+		if !InDurationRange(s.traceDuration(span), query) {
+			continue
+		}
+		roots = append(roots, span)
+	}
+	return roots
+}
+
+// QueryTraces queries traces, newest first or by total duration for
+// order_by=duration. Each trace is represented by its root span.
+func (s *InMemoryStorage) QueryTraces(query *models.QueryParams) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	roots := s.rootSpans(query)
+	if query.OrderBy == "duration" {
+		sort.SliceStable(roots, func(i, j int) bool {
+			if query.OrderDesc {
+				return s.traceDuration(roots[i]) > s.traceDuration(roots[j])
+			}
+			return s.traceDuration(roots[i]) < s.traceDuration(roots[j])
+		})
+	}
+	return s.tracePage(roots, query), nil
+}
+
+// QueryTraceIDs returns the ID and root span start time of each trace
+// matching the service and time range of query, newest first with ties
+// broken by descending ID
+func (s *InMemoryStorage) QueryTraceIDs(query *models.QueryParams) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	roots := s.rootSpans(&models.QueryParams{Service: query.Service, Since: query.Since, Until: query.Until})
+	sortTraceIDs(roots)
+
+	traces := []map[string]interface{}{}
+	start, end := pageBounds(len(roots), query)
+	for _, root := range roots[start:end] {
+		traces = append(traces, traceIDRecord(root.TraceID, root.StartTime))
+	}
+
+	return map[string]interface{}{
+		"traces":     traces,
+		"pagination": paginationInfo(len(roots), query),
+	}, nil
+}
+
+// QueryTraceSamples returns the ID, root span start time, duration and
+// failure of each trace matching the service and time range of query, in the
+// order of QueryTraceIDs
+func (s *InMemoryStorage) QueryTraceSamples(query *models.QueryParams) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	roots := s.rootSpans(&models.QueryParams{Service: query.Service, Since: query.Since, Until: query.Until})
+	sortTraceIDs(roots)
+	start, end := pageBounds(len(roots), query)
+
+	return map[string]interface{}{
+		"traces":     traceSampleRecords(roots[start:end], s.spans),
+		"pagination": paginationInfo(len(roots), query),
+	}, nil
+}
+
+// traceSampleRecords returns the QueryTraceSamples record of the trace of
+// each root span, aggregating the duration and failure over its spans
+func traceSampleRecords(roots []*models.Span, spans []*models.Span) []map[string]interface{} {
+	traces := make(map[string]*models.Trace, len(roots))
+	for _, root := range roots {
+		traces[root.TraceID] = &models.Trace{ID: root.TraceID}
+	}
+	for _, span := range spans {
+		if trace, ok := traces[span.TraceID]; ok {
+			trace.Spans = append(trace.Spans, span)
+		}
+	}
+
+	records := make([]map[string]interface{}, 0, len(roots))
+	for _, root := range roots {
+		trace := traces[root.TraceID]
+		failed := false
+		for _, span := range trace.Spans {
+			if span.Status == models.SpanStatusError {
+				failed = true
+				break
+			}
+		}
+		records = append(records, traceSampleRecord(root.TraceID, root.StartTime, trace.ComputeDuration(), failed))
+	}
+	return records
+}
+
+// sortTraceIDs orders root spans newest first, ties broken by descending
+// trace ID
+func sortTraceIDs(roots []*models.Span) {
+	sort.SliceStable(roots, func(i, j int) bool {
+		if !roots[i].StartTime.Equal(roots[j].StartTime) {
+			return roots[i].StartTime.After(roots[j].StartTime)
+		}
+		return roots[i].TraceID > roots[j].TraceID
+	})
+}
+
+// QuerySlowTraces returns the traces matching query whose duration is above
+// the given percentile of their durations, slowest first
+func (s *InMemoryStorage) QuerySlowTraces(query *models.QueryParams, percentile float64) (map[string]interface{}, error) {
+	if percentile <= 0 || percentile >= 100 {
+		return nil, fmt.Errorf("percentile must be between 0 and 100, got %v", percentile)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	roots := s.rootSpans(query)
+	durations := make([]float64, len(roots))
+	for i, root := range roots {
+		durations[i] = float64(s.traceDuration(root))
+	}
+	threshold := int64(percentileOf(durations, percentile))
+
+	var slow []*models.Span
+	for _, root := range roots {
+		if s.traceDuration(root) > threshold {
+			slow = append(slow, root)
+		}
+	}
+	sort.SliceStable(slow, func(i, j int) bool {
+		return s.traceDuration(slow[i]) > s.traceDuration(slow[j])
+	})
+
+	result := s.tracePage(slow, query)
+	result["percentile"] = percentile
+	result["threshold_ms"] = threshold
+	return result, nil
+}
+
+// tracePage returns the page of traces selected by query out of roots. The
+// caller must hold the read lock.
+func (s *InMemoryStorage) tracePage(roots []*models.Span, query *models.QueryParams) map[string]interface{} {
+	traces := []map[string]interface{}{}
+	start, end := pageBounds(len(roots), query)
+	for _, root := range roots[start:end] {
+		traceMap := map[string]interface{}{
+			"id":          root.TraceID,
+			"start_time":  root.StartTime.Format(time.RFC3339),
+			"service":     root.Service,
+			"name":        root.Name,
+			"duration_ms": s.traceDuration(root),
+			"status":      string(s.traceStatus(root)),
+		}
+		if len(root.Tags) > 0 {
+			traceMap["tags"] = root.Tags
+		}
+		traces = append(traces, traceMap)
+	}
+
+	return map[string]interface{}{
+		"traces":     traces,
+		"pagination": paginationInfo(len(roots), query),
+	}
+}
+
+// GetTrace assembles every span of a trace into the trace, or returns
+// ErrTraceNotFound if the trace has no spans
+func (s *InMemoryStorage) GetTrace(traceID string) (*models.Trace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	var spans []*models.Span
+	for _, span := range s.spans {
+		if span.TraceID == traceID {
+			spans = append(spans, span)
+		}
+	}
+	if len(spans) == 0 {
+		return nil, ErrTraceNotFound
+	}
+
+	return models.AssembleTrace(traceID, spans), nil
+}
+
+// GetSpanAncestors returns the ancestors of a span, its parent first and the
+// root last
+func (s *InMemoryStorage) GetSpanAncestors(spanID string) ([]*models.Span, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	return spanAncestors(spanID, traceSpansOf(s.spans, spanID))
+}
+
+// traceSpansOf returns the spans of the trace of the span with the given ID,
+// or nil if no span has the ID
+func traceSpansOf(spans []*models.Span, spanID string) []*models.Span {
+	traceIDs := make(map[string]bool)
+	for _, span := range spans {
+		if span.ID == spanID {
+			traceIDs[span.TraceID] = true
+		}
+	}
+
+	var trace []*models.Span
+	for _, span := range spans {
+		if traceIDs[span.TraceID] {
+			trace = append(trace, span)
+		}
+	}
+	return trace
+}
+
+// QuerySpans queries spans, newest first
+func (s *InMemoryStorage) QuerySpans(query *models.QueryParams) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	var matched []*models.Span
+	for _, span := range s.matchingSpans(query) {
+		if query.SpanID != "" && span.ID != query.SpanID {
+			continue
+		}
+		if !hasTagKeys(span.Tags, query) {
+			continue
+		}
+		matched = append(matched, span)
+	}
+
+	spans := []map[string]interface{}{}
+	start, end := pageBounds(len(matched), query)
+	for _, span := range matched[start:end] {
+		spans = append(spans, spanResult(span))
+	}
+
+	return map[string]interface{}{
+		"spans":      spans,
+		"pagination": paginationInfo(len(matched), query),
+	}, nil
+}
+
+// CountSpanErrorsByType counts ERROR spans grouped by their error.type tag.
+// Spans without the tag are reported under "unknown".
+func (s *InMemoryStorage) CountSpanErrorsByType(query *models.QueryParams) ([]map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	counts := make(map[string]int)
+	for _, span := range s.spans {
+		if span.Status != models.SpanStatusError {
+			continue
+		}
+		if query.Service != "" && span.Service != query.Service {
+			continue
+		}
+		if !InTimeRange(span.StartTime, query) {
+			continue
+		}
+
+		errorType := span.Tags["error.type"]
+		if errorType == "" {
+			errorType = "unknown"
+		}
+		counts[errorType]++
+	}
+
+	results := make([]map[string]interface{}, 0, len(counts))
+	for errorType, count := range counts {
+		results = append(results, map[string]interface{}{
+			"error_type": errorType,
+			"count":      count,
+		})
+	}
+
+	// Sort by count (highest first), then by type
+	sort.Slice(results, func(i, j int) bool {
+		ci, cj := results[i]["count"].(int), results[j]["count"].(int)
+		if ci != cj {
+			return ci > cj
+		}
+		return results[i]["error_type"].(string) < results[j]["error_type"].(string)
+	})
+
+	return results, nil
+}
+
+// GetServices returns the sorted names of the services with stored logs,
+// metrics or spans
+func (s *InMemoryStorage) GetServices() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	seen := make(map[string]bool)
+	for _, log := range s.logs {
+		seen[log.Service] = true
+	}
+	for _, metric := range s.metrics {
+		seen[metric.Service] = true
+	}
+	for _, span := range s.spans {
+		seen[span.Service] = true
+	}
+
+	services := make([]string, 0, len(seen))
+	for service := range seen {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// GetActiveServices returns the names of the services with stored logs,
+// metrics or spans, the most recently active first, at most limit of them
+// (all if limit is 0 or less)
+func (s *InMemoryStorage) GetActiveServices(limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	lastSeen := make(map[string]time.Time)
+	for _, log := range s.logs {
+		seenAt(lastSeen, log.Service, log.Timestamp)
+	}
+	for _, metric := range s.metrics {
+		seenAt(lastSeen, metric.Service, metric.Timestamp)
+	}
+	for _, span := range s.spans {
+		seenAt(lastSeen, span.Service, span.StartTime)
+	}
+	return servicesByActivity(lastSeen, limit), nil
+}
+
+// seenAt records that service was active at t in lastSeen, keeping the
+// latest time
+func seenAt(lastSeen map[string]time.Time, service string, t time.Time) {
+	if service == "" {
+		return
+	}
+	if last, ok := lastSeen[service]; !ok || t.After(last) {
+		lastSeen[service] = t
+	}
+}
+
+// servicesByActivity returns the services of lastSeen ordered by their last
+// activity, newest first and then by name, at most limit of them (all if
+// limit is 0 or less)
+func servicesByActivity(lastSeen map[string]time.Time, limit int) []string {
+	services := make([]string, 0, len(lastSeen))
+	for service := range lastSeen {
+		services = append(services, service)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		ti, tj := lastSeen[services[i]], lastSeen[services[j]]
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return services[i] < services[j]
+	})
+	if limit > 0 && len(services) > limit {
+		services = services[:limit]
+	}
+	return services
+}
+
+// DeleteByService removes every log, metric, histogram and span of a service
+// and returns the number of items deleted
+func (s *InMemoryStorage) DeleteByService(service string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrStorageClosed
+	}
+
+	var deleted int64
+
+	logs := s.logs[:0]
+	for _, log := range s.logs {
+		if log.Service == service {
+			deleted++
+			continue
+		}
+		logs = append(logs, log)
+	}
+	s.logs = logs
+
+	metrics := s.metrics[:0]
+	for _, metric := range s.metrics {
+		if metric.Service == service {
+			deleted++
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	s.metrics = metrics
+
+	histograms := s.histograms[:0]
+	for _, histogram := range s.histograms {
+		if histogram.Service == service {
+			deleted++
+			continue
+		}
+		histograms = append(histograms, histogram)
+	}
+	s.histograms = histograms
+
+	spans := s.spans
+	s.spans = nil
+	s.spanIndex = make(map[string]int)
+	for _, span := range spans {
+		if span.Service == service {
+			if span.ParentID == "" {
+				delete(s.traceDurations, span.TraceID)
+				delete(s.traceStatuses, span.TraceID)
+			}
+			deleted++
+			continue
+		}
+		s.saveSpan(span)
+	}
+
+	return deleted, nil
+}
+
+// ClearAll removes all stored telemetry and returns the number of items deleted
+func (s *InMemoryStorage) ClearAll() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrStorageClosed
+	}
+
+	deleted := int64(len(s.logs) + len(s.metrics) + len(s.histograms) + len(s.spans))
+	s.logs = nil
+	s.metrics = nil
+	s.histograms = nil
+	s.spans = nil
+	s.spanIndex = make(map[string]int)
+	s.traceDurations = make(map[string]int64)
+	s.traceStatuses = make(map[string]models.SpanStatus)
+	return deleted, nil
+}
+
+// GetStats counts the logs matching query by level, the metrics by type and
+// the traces, averaging their durations
+func (s *InMemoryStorage) GetStats(query *models.QueryParams) (*models.Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	stats := models.NewStats()
+	query = statsQuery(query)
+
+	for _, log := range s.logs {
+		if logMatches(log, query) {
+			addLogCount(stats, string(log.Level), 1)
+		}
+	}
+
+	for _, metric := range s.listedMetrics(query) {
+		stats.Metrics.Total++
+		stats.Metrics.ByType[metric.Type]++
+	}
+
+	roots := s.rootSpans(query)
+	var totalDuration int64
+	for _, root := range roots {
+		totalDuration += s.traceDuration(root)
+	}
+	stats.Traces.Total = int64(len(roots))
+	if len(roots) > 0 {
+		stats.Traces.AvgDurationMs = float64(totalDuration) / float64(len(roots))
+	}
+
+	return stats, nil
+}
+
+// Ping fails once the storage is closed
+func (s *InMemoryStorage) Ping() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrStorageClosed
+	}
+	return nil
+}
+
+// Close closes the storage, writing its snapshot file if it has one
+func (s *InMemoryStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if s.snapshotFile == "" {
+		return nil
+	}
+	return s.writeSnapshotFile()
+}
+
+// writeSnapshotFile writes a snapshot to a temporary file and moves it over
+// the snapshot file, so a failed write leaves the previous snapshot intact.
+// The caller must hold the lock.
+func (s *InMemoryStorage) writeSnapshotFile() error {
+	tmp := s.snapshotFile + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	if err := s.snapshot(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, s.snapshotFile); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+// pageBounds returns the bounds of the page selected by query out of n
+// results, with the default page size of pageClause
+func pageBounds(n int, query *models.QueryParams) (int, int) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	start := query.Offset
+	if start > n {
+		start = n
+	}
+	end := start + limit
+	if end > n {
+		end = n
+	}
+	return start, end
+}
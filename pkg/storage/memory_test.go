@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func newTestInMemoryStorage(t *testing.T, opts ...InMemoryOption) *InMemoryStorage {
+	t.Helper()
+
+	st, err := NewInMemoryStorage(opts...)
+	if err != nil {
+		t.Fatalf("failed to create in-memory storage: %v", err)
+	}
+	return st
+}
+
+// saveSampleTelemetry stores logs, metrics, a histogram and a trace in st
+func saveSampleTelemetry(t *testing.T, st Storage) {
+	t.Helper()
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	for i, level := range []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo, models.LogLevelWarning, models.LogLevelError} {
+		entry := models.NewLogEntry("checkout", fmt.Sprintf("Payment step %d", i), level)
+		entry.ID = fmt.Sprintf("log-%d", i)
+		entry.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		entry.AddTag("region", []string{"eu", "us"}[i%2])
+		if err := st.SaveLog(entry); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+	other := models.NewLogEntry("search", "query parsed", models.LogLevelInfo)
+	other.ID = "log-search"
+	other.Timestamp = base.Add(10 * time.Minute)
+	if err := st.SaveLog(other); err != nil {
+		t.Fatalf("failed to save log: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		metric := models.NewMetric("http.requests", float64(i), models.MetricTypeCounter, "checkout")
+		metric.ID = fmt.Sprintf("metric-%d", i)
+		metric.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+	}
+
+	histogram, err := models.NewHistogramMetric("http.latency", "checkout", []float64{10, 100})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	histogram.ID = "histogram-0"
+	histogram.Timestamp = base.Add(5 * time.Minute)
+	histogram.Observe(42)
+	histogram.Percentile[99] = 42
+	if err := st.SaveHistogramMetric(histogram); err != nil {
+		t.Fatalf("failed to save histogram: %v", err)
+	}
+
+	root := models.NewSpan("POST /checkout", "checkout", "trace-1")
+	root.ID = "span-root"
+	root.StartTime = base
+	root.Duration = 120
+	child := models.NewSpan("charge", "payments", "trace-1").SetParent(root.ID)
+	child.ID = "span-child"
+	child.StartTime = base.Add(time.Millisecond)
+	child.Duration = 80
+	child.SetStatus(models.SpanStatusError)
+	if err := st.SaveTrace(&models.Trace{ID: "trace-1", Root: root, Spans: []*models.Span{root, child}}); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+}
+
+// resultIDs returns the IDs of the records under key in a query result
+func resultIDs(t *testing.T, result map[string]interface{}, key string) []string {
+	t.Helper()
+
+	ids := []string{}
+	for _, record := range result[key].([]map[string]interface{}) {
+		ids = append(ids, record["id"].(string))
+	}
+	return ids
+}
+
+func TestInMemoryStorage_MatchesSQLite(t *testing.T) {
+	memory := newTestInMemoryStorage(t)
+	sqlite := newTestSQLiteStorage(t)
+	saveSampleTelemetry(t, memory)
+	saveSampleTelemetry(t, sqlite)
+
+	queries := []models.QueryParams{
+		{},
+		{Service: "checkout", Limit: 2, Offset: 1},
+		{Search: "PAYMENT"},
+		{MinLevel: "WARNING"},
+		{Filters: map[string]string{"region": "eu"}},
+		{OrderBy: "message", OrderDesc: true},
+		{Since: time.Now().Add(-59 * time.Minute)},
+	}
+
+	for _, query := range queries {
+		for _, kind := range []string{"logs", "metrics", "traces", "spans"} {
+			var memoryResult, sqliteResult map[string]interface{}
+			var memoryErr, sqliteErr error
+			switch kind {
+			case "logs":
+				memoryResult, memoryErr = memory.QueryLogs(&query)
+				sqliteResult, sqliteErr = sqlite.QueryLogs(&query)
+			case "metrics":
+				memoryResult, memoryErr = memory.QueryMetrics(&query)
+				sqliteResult, sqliteErr = sqlite.QueryMetrics(&query)
+			case "traces":
+				memoryResult, memoryErr = memory.QueryTraces(&query)
+				sqliteResult, sqliteErr = sqlite.QueryTraces(&query)
+			case "spans":
+				memoryResult, memoryErr = memory.QuerySpans(&query)
+				sqliteResult, sqliteErr = sqlite.QuerySpans(&query)
+			}
+			if memoryErr != nil || sqliteErr != nil {
+				t.Fatalf("%s %+v: unexpected errors %v, %v", kind, query, memoryErr, sqliteErr)
+			}
+
+			if got, want := resultIDs(t, memoryResult, kind), resultIDs(t, sqliteResult, kind); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %+v: expected %v like SQLite, got %v", kind, query, want, got)
+			}
+			if !reflect.DeepEqual(memoryResult["pagination"], sqliteResult["pagination"]) {
+				t.Errorf("%s %+v: expected pagination %v like SQLite, got %v", kind, query, sqliteResult["pagination"], memoryResult["pagination"])
+			}
+		}
+	}
+
+	memoryServices, _ := memory.GetServices()
+	sqliteServices, _ := sqlite.GetServices()
+	if !reflect.DeepEqual(memoryServices, sqliteServices) {
+		t.Errorf("expected services %v like SQLite, got %v", sqliteServices, memoryServices)
+	}
+
+	memoryErrors, _ := memory.CountSpanErrorsByType(&models.QueryParams{})
+	sqliteErrors, _ := sqlite.CountSpanErrorsByType(&models.QueryParams{})
+	if !reflect.DeepEqual(memoryErrors, sqliteErrors) {
+		t.Errorf("expected span errors %v like SQLite, got %v", sqliteErrors, memoryErrors)
+	}
+}
+
+func TestInMemoryStorage_QueryLogs_Cursor(t *testing.T) {
+	assertLogCursorPages(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_SaveSpanReplaces(t *testing.T) {
+	st := newTestInMemoryStorage(t)
+
+	span := models.NewSpan("GET /", "api", "trace-1")
+	if err := st.SaveSpan(span); err != nil {
+		t.Fatalf("failed to save span: %v", err)
+	}
+	finished := *span
+	finished.Duration = 250
+	if err := st.SaveSpan(&finished); err != nil {
+		t.Fatalf("failed to save span: %v", err)
+	}
+
+	result, err := st.QuerySpans(&models.QueryParams{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	spans := result["spans"].([]map[string]interface{})
+	if len(spans) != 1 || spans[0]["duration_ms"] != int64(250) {
+		t.Errorf("expected the span to be replaced, got %v", spans)
+	}
+}
+
+func TestInMemoryStorage_QuerySlowTraces(t *testing.T) {
+	st := newTestInMemoryStorage(t)
+	saveTraceDurations(t, st, "api", 50, 10, 40, 20, 30)
+
+	result, err := st.QuerySlowTraces(&models.QueryParams{Service: "api"}, 50)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	assertSlowTraces(t, result, 30, "api-0", "api-2")
+}
+
+func TestInMemoryStorage_DurationFilters(t *testing.T) {
+	assertDurationFilters(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_QueryTraces_Duration(t *testing.T) {
+	assertTraceDuration(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_QueryTraces_Status(t *testing.T) {
+	assertTraceStatus(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_TimeBounds(t *testing.T) {
+	assertTimeBounds(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_TraceIDs(t *testing.T) {
+	assertTraceIDs(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_TraceSamples(t *testing.T) {
+	assertTraceSamples(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_LevelOrder(t *testing.T) {
+	assertLevelOrder(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_TagPresenceFilters(t *testing.T) {
+	assertTagPresenceFilters(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_UpdateLogTags(t *testing.T) {
+	assertUpdateLogTags(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_UpdateLogTagsMaxTags(t *testing.T) {
+	assertUpdateLogTagsMaxTags(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_GetStats(t *testing.T) {
+	assertGetStats(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_GetTrace(t *testing.T) {
+	assertGetTrace(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_QuerySpansDetails(t *testing.T) {
+	assertQuerySpansDetails(t, newTestInMemoryStorage(t))
+}
+
+func TestInMemoryStorage_SnapshotRestore(t *testing.T) {
+	st := newTestInMemoryStorage(t)
+	saveSampleTelemetry(t, st)
+
+	var buf bytes.Buffer
+	if err := st.Snapshot(&buf); err != nil {
+		t.Fatalf("failed to snapshot: %v", err)
+	}
+
+	restored := newTestInMemoryStorage(t)
+	restored.SaveLog(models.NewLogEntry("stale", "replaced by the snapshot", models.LogLevelInfo))
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	query := &models.QueryParams{}
+	for name, get := range map[string]func(s *InMemoryStorage) (interface{}, error){
+		"logs":       func(s *InMemoryStorage) (interface{}, error) { return s.QueryLogs(query) },
+		"metrics":    func(s *InMemoryStorage) (interface{}, error) { return s.QueryMetrics(query) },
+		"histograms": func(s *InMemoryStorage) (interface{}, error) { return s.QueryHistograms(query) },
+		"spans":      func(s *InMemoryStorage) (interface{}, error) { return s.QuerySpans(query) },
+		"services":   func(s *InMemoryStorage) (interface{}, error) { return s.GetServices() },
+	} {
+		want, err := get(st)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		got, err := get(restored)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v after restore, got %v", name, want, got)
+		}
+	}
+}
+
+func TestInMemoryStorage_SnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pulse.snapshot")
+
+	st := newTestInMemoryStorage(t, WithSnapshotFile(path))
+	saveSampleTelemetry(t, st)
+	if err := st.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	if err := st.SaveLog(models.NewLogEntry("api", "too late", models.LogLevelInfo)); err != ErrStorageClosed {
+		t.Errorf("expected ErrStorageClosed after close, got %v", err)
+	}
+
+	reopened := newTestInMemoryStorage(t, WithSnapshotFile(path))
+	result, err := reopened.QueryLogs(&models.QueryParams{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if ids := resultIDs(t, result, "logs"); len(ids) != 5 {
+		t.Errorf("expected the 5 logs from the snapshot, got %v", ids)
+	}
+	if deleted, _ := reopened.ClearAll(); deleted != 12 {
+		t.Errorf("expected 5 logs, 3 metrics, a histogram with its metric and 2 spans, got %d items", deleted)
+	}
+}
@@ -1,7 +1,14 @@
 package storage
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/karansingh/pulse/pkg/models"
@@ -15,30 +22,39 @@ type MetricQuery struct {
 	From          time.Time         // Start time
 	To            time.Time         // End time
 	Resolution    string            // Time resolution for aggregation (e.g., "1m", "5m", "1h")
-	Aggregation   string            // Aggregation function (e.g., "avg", "sum", "min", "max", "count", "p50", "p90", "p99")
+	Aggregation   string            // Aggregation function (e.g., "avg", "sum", "min", "max", "count", "rate", "p50", "p90", "p99")
 	IncludeLabels []string          // Labels to include in results (for grouping)
 }
 
 // MetricAggregation holds aggregated metric data
 type MetricAggregation struct {
-	Name       string                  // Metric name
-	Type       models.MetricType       // Metric type
-	TimeSeries []MetricTimeSeriesPoint // Time series data
-	Labels     map[string]string       // Labels (for grouped results)
+	Name       string                  `json:"name"`             // Metric name
+	Type       models.MetricType       `json:"type"`             // Metric type
+	TimeSeries []MetricTimeSeriesPoint `json:"time_series"`      // Time series data
+	Labels     map[string]string       `json:"labels,omitempty"` // Labels (for grouped results)
 }
 
 // MetricTimeSeriesPoint represents a single point in a time series
 type MetricTimeSeriesPoint struct {
-	Timestamp time.Time // Timestamp of the data point
-	Value     float64   // Aggregated value
-	Count     int       // Number of data points in this aggregation (useful for average calculations)
+	Timestamp time.Time `json:"timestamp"` // Timestamp of the data point
+	Value     float64   `json:"value"`     // Aggregated value
+	Count     int       `json:"count"`     // Number of data points in this aggregation (useful for average calculations)
 }
 
-// AggregateMetrics retrieves and aggregates metrics based on query parameters
-func (s *SQLiteStorage) AggregateMetrics(query MetricQuery) ([]MetricAggregation, error) {
-	// Placeholder implementation - in a real system, this would query the database
-	// and perform the requested aggregation
+// MetricAggregator is implemented by storage backends that can aggregate
+// metrics into time series
+type MetricAggregator interface {
+	AggregateMetrics(query MetricQuery) ([]MetricAggregation, error)
+}
 
+// ErrAggregationUnsupported is returned when the storage backend cannot aggregate metrics
+var ErrAggregationUnsupported = errors.New("metric aggregation is not supported by this storage")
+
+// AggregateMetrics retrieves and aggregates metrics based on query parameters.
+// Samples are bucketed by the query resolution and reduced with the requested
+// aggregation. When IncludeLabels is set, one series is returned per distinct
+// combination of those labels.
+func (s *SQLiteStorage) AggregateMetrics(query MetricQuery) ([]MetricAggregation, error) {
 	// Validate time range
 	if query.From.IsZero() {
 		query.From = time.Now().Add(-1 * time.Hour) // Default to last hour
@@ -48,72 +64,451 @@ func (s *SQLiteStorage) AggregateMetrics(query MetricQuery) ([]MetricAggregation
 		query.To = time.Now()
 	}
 
-	// Validate resolution
-	resolution := 60 * time.Second // Default to 1 minute
-	switch query.Resolution {
+	resolution := parseResolution(query.Resolution)
+
+	aggregation := strings.ToLower(query.Aggregation)
+	if aggregation == "" {
+		aggregation = "avg"
+	}
+
+	switch aggregation {
+	case "avg", "sum", "min", "max", "count":
+		return s.aggregateMetricsSQL(query, aggregation, resolution)
+	case "rate":
+		return s.aggregateMetricRates(query, resolution)
+	}
+
+	if percentile, ok := parsePercentileAggregation(aggregation); ok {
+		return s.aggregateMetricPercentiles(query, percentile, resolution)
+	}
+
+	return nil, fmt.Errorf("unsupported aggregation: %s", query.Aggregation)
+}
+
+// parseResolution converts a resolution such as "5m" into a duration,
+// defaulting to one minute
+func parseResolution(resolution string) time.Duration {
+	switch resolution {
 	case "10s":
-		resolution = 10 * time.Second
+		return 10 * time.Second
 	case "30s":
-		resolution = 30 * time.Second
+		return 30 * time.Second
 	case "1m":
-		resolution = time.Minute
+		return time.Minute
 	case "5m":
-		resolution = 5 * time.Minute
+		return 5 * time.Minute
 	case "15m":
-		resolution = 15 * time.Minute
+		return 15 * time.Minute
 	case "1h":
-		resolution = time.Hour
+		return time.Hour
 	case "6h":
-		resolution = 6 * time.Hour
+		return 6 * time.Hour
 	case "1d":
-		resolution = 24 * time.Hour
-	}
-
-	// Placeholder for SQL query - this would be the actual database query in a real implementation
-	/*
-		sql := `
-		SELECT
-		    strftime('%Y-%m-%d %H:%M:%S', datetime(timestamp / $resolution * $resolution, 'unixepoch')) as period,
-		    $aggregation_function(value) as value,
-		    count(*) as count
-		FROM
-		    metrics
-		WHERE
-		    name = $name
-		    AND service = $service
-		    AND timestamp >= $from
-		    AND timestamp <= $to
-		    $tag_filters
-		GROUP BY
-		    period
-		    $label_grouping
-		ORDER BY
-		    period
-		`
-	*/
-
-	// Generate a sample metric aggregation
-	points := make([]MetricTimeSeriesPoint, 0)
-	currentTime := query.From
-
-	for currentTime.Before(query.To) {
-		point := MetricTimeSeriesPoint{
-			Timestamp: currentTime,
-			Value:     float64(currentTime.Unix() % 100), // Generate some pattern
-			Count:     int(currentTime.Unix()%10) + 1,
-		}
-		points = append(points, point)
-		currentTime = currentTime.Add(resolution)
-	}
-
-	aggregation := MetricAggregation{
-		Name:       query.Name,
-		Type:       models.MetricTypeGauge,
-		TimeSeries: points,
-		Labels:     query.Tags,
-	}
-
-	return []MetricAggregation{aggregation}, nil
+		return 24 * time.Hour
+	}
+
+	if d, err := time.ParseDuration(resolution); err == nil && d >= time.Second {
+		return d
+	}
+
+	return time.Minute
+}
+
+// parsePercentileAggregation parses aggregations of the form "p50" or "p99.9"
+func parsePercentileAggregation(aggregation string) (float64, bool) {
+	if !strings.HasPrefix(aggregation, "p") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(aggregation[1:], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, false
+	}
+	return p, true
+}
+
+// metricAggregationSQL builds the shared SELECT columns, FROM/WHERE clause and
+// arguments of an aggregation query. The selected columns start with the
+// time bucket (in unix seconds) followed by one column per included label.
+func metricAggregationSQL(query MetricQuery, resolution time.Duration) (columns string, from string, args []interface{}) {
+	seconds := int64(resolution / time.Second)
+	columns = fmt.Sprintf("(CAST(strftime('%%s', m.timestamp) AS INTEGER) / %d) * %d AS bucket", seconds, seconds)
+
+	for i, label := range query.IncludeLabels {
+		columns += fmt.Sprintf(", COALESCE(json_extract(m.tags, ?), '') AS label_%d", i)
+		args = append(args, jsonTagPath(label))
+	}
+
+	from = `
+		FROM metrics AS m
+		WHERE m.timestamp >= ? AND m.timestamp <= ?`
+	args = append(args, query.From.UTC(), query.To.UTC())
+
+	if query.Name != "" {
+		from += " AND m.name = ?"
+		args = append(args, query.Name)
+	}
+
+	if query.Service != "" {
+		from += " AND m.service = ?"
+		args = append(args, query.Service)
+	}
+
+	// Sort tag keys so the generated SQL is stable
+	keys := make([]string, 0, len(query.Tags))
+	for k := range query.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		from += " AND json_extract(m.tags, ?) = ?"
+		args = append(args, jsonTagPath(k), query.Tags[k])
+	}
+
+	return columns, from, args
+}
+
+// jsonTagPath returns the JSON path of a tag key, quoted so that keys
+// containing dots (e.g. "http.method") are looked up literally
+func jsonTagPath(key string) string {
+	return `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
+
+// metricSeriesBuilder groups aggregated rows into one series per label set,
+// preserving the order in which label sets are first seen
+type metricSeriesBuilder struct {
+	query  MetricQuery
+	index  map[string]int
+	series []MetricAggregation
+}
+
+func newMetricSeriesBuilder(query MetricQuery) *metricSeriesBuilder {
+	return &metricSeriesBuilder{
+		query:  query,
+		index:  make(map[string]int),
+		series: []MetricAggregation{},
+	}
+}
+
+// get returns the index of the series for the given label values, creating
+// the series if needed
+func (b *metricSeriesBuilder) get(labelValues []string, metricType string) int {
+	key := strings.Join(labelValues, "\x00")
+	if i, ok := b.index[key]; ok {
+		return i
+	}
+
+	agg := MetricAggregation{
+		Name:       b.query.Name,
+		Type:       models.MetricType(metricType),
+		TimeSeries: []MetricTimeSeriesPoint{},
+		Labels:     b.query.Tags,
+	}
+	if len(b.query.IncludeLabels) > 0 {
+		agg.Labels = make(map[string]string, len(b.query.Tags)+len(labelValues))
+		for k, v := range b.query.Tags {
+			agg.Labels[k] = v
+		}
+		for i, label := range b.query.IncludeLabels {
+			agg.Labels[label] = labelValues[i]
+		}
+	}
+
+	b.index[key] = len(b.series)
+	b.series = append(b.series, agg)
+	return len(b.series) - 1
+}
+
+// aggregateMetricsSQL evaluates avg/sum/min/max/count entirely in SQL
+func (s *SQLiteStorage) aggregateMetricsSQL(query MetricQuery, aggregation string, resolution time.Duration) ([]MetricAggregation, error) {
+	columns, from, args := metricAggregationSQL(query, resolution)
+
+	groupBy := "bucket"
+	for i := range query.IncludeLabels {
+		groupBy += fmt.Sprintf(", label_%d", i)
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT %s, %s(m.value) AS value, COUNT(*) AS count, MAX(m.type) AS type
+		%s
+		GROUP BY %s
+		ORDER BY bucket`, columns, strings.ToUpper(aggregation), from, groupBy)
+
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate metrics: %w", err)
+	}
+	defer rows.Close()
+
+	builder := newMetricSeriesBuilder(query)
+	for rows.Next() {
+		var (
+			bucket      int64
+			labelValues = make([]string, len(query.IncludeLabels))
+			value       float64
+			count       int
+			metricType  string
+		)
+
+		dest := []interface{}{&bucket}
+		for i := range labelValues {
+			dest = append(dest, &labelValues[i])
+		}
+		dest = append(dest, &value, &count, &metricType)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan aggregation row: %w", err)
+		}
+
+		series := &builder.series[builder.get(labelValues, metricType)]
+		series.TimeSeries = append(series.TimeSeries, MetricTimeSeriesPoint{
+			Timestamp: time.Unix(bucket, 0).UTC(),
+			Value:     value,
+			Count:     count,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating aggregation rows: %w", err)
+	}
+
+	return builder.series, nil
+}
+
+// percentileBucket accumulates the samples of one time bucket of a series
+type percentileBucket struct {
+	timestamp time.Time
+	values    []float64
+	histogram *models.HistogramMetric
+}
+
+// aggregateMetricPercentiles computes percentiles per time bucket. Histogram
+// metrics are answered from their stored buckets in histogram_metrics; other
+// metric types use the raw sample values.
+func (s *SQLiteStorage) aggregateMetricPercentiles(query MetricQuery, percentile float64, resolution time.Duration) ([]MetricAggregation, error) {
+	columns, from, args := metricAggregationSQL(query, resolution)
+	from = strings.Replace(from, "FROM metrics AS m", "FROM metrics AS m LEFT JOIN histogram_metrics AS h ON h.metric_id = m.id", 1)
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT %s, m.value, m.type, h.buckets, h.count
+		%s
+		ORDER BY bucket`, columns, from)
+
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metric samples: %w", err)
+	}
+	defer rows.Close()
+
+	builder := newMetricSeriesBuilder(query)
+	buckets := make(map[int][]*percentileBucket) // Time buckets per series index
+
+	for rows.Next() {
+		var (
+			bucket      int64
+			labelValues = make([]string, len(query.IncludeLabels))
+			value       float64
+			metricType  string
+			histBuckets sql.NullString
+			histCount   sql.NullInt64
+		)
+
+		dest := []interface{}{&bucket}
+		for i := range labelValues {
+			dest = append(dest, &labelValues[i])
+		}
+		dest = append(dest, &value, &metricType, &histBuckets, &histCount)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan metric sample: %w", err)
+		}
+
+		series := builder.get(labelValues, metricType)
+		ts := time.Unix(bucket, 0).UTC()
+
+		// Rows arrive ordered by bucket, so only the last bucket can match
+		list := buckets[series]
+		if len(list) == 0 || !list[len(list)-1].timestamp.Equal(ts) {
+			list = append(list, &percentileBucket{timestamp: ts})
+			buckets[series] = list
+		}
+		current := list[len(list)-1]
+		current.values = append(current.values, value)
+
+		if models.MetricType(metricType) == models.MetricTypeHistogram && histBuckets.Valid {
+			var hb []models.HistogramBucket
+			if err := json.Unmarshal([]byte(histBuckets.String), &hb); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal histogram buckets: %w", err)
+			}
+			current.histogram = mergeHistogramBuckets(current.histogram, hb, uint64(histCount.Int64))
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating metric samples: %w", err)
+	}
+
+	for i := range builder.series {
+		series := &builder.series[i]
+		for _, b := range buckets[i] {
+			point := MetricTimeSeriesPoint{Timestamp: b.timestamp, Count: len(b.values)}
+			if b.histogram != nil && b.histogram.Count > 0 {
+				point.Value = CalculatePercentile(b.histogram, percentile)
+			} else {
+				point.Value = percentileOf(b.values, percentile)
+			}
+			series.TimeSeries = append(series.TimeSeries, point)
+		}
+	}
+
+	return builder.series, nil
+}
+
+// counterSample is a counter sample read for the rate aggregation
+type counterSample struct {
+	series     string   // Identity of the counter: name, service, env, host and complete tag set
+	labels     []string // Values of the included labels
+	bucket     int64    // Time bucket in unix seconds
+	value      float64
+	metricType string
+}
+
+// aggregateMetricRates computes the per-second rate of counters per time
+// bucket. Samples are read oldest first and rated by counterRates.
+func (s *SQLiteStorage) aggregateMetricRates(query MetricQuery, resolution time.Duration) ([]MetricAggregation, error) {
+	columns, from, args := metricAggregationSQL(query, resolution)
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT %s, m.name, m.service, COALESCE(m.env, ''), COALESCE(m.host, ''), COALESCE(m.tags, ''), m.value, m.type
+		%s
+		ORDER BY m.timestamp, m.id`, columns, from)
+
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metric samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []counterSample
+	for rows.Next() {
+		var (
+			sample                         = counterSample{labels: make([]string, len(query.IncludeLabels))}
+			name, service, env, host, tags string
+		)
+
+		dest := []interface{}{&sample.bucket}
+		for i := range sample.labels {
+			dest = append(dest, &sample.labels[i])
+		}
+		dest = append(dest, &name, &service, &env, &host, &tags, &sample.value, &sample.metricType)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan metric sample: %w", err)
+		}
+
+		sample.series = strings.Join([]string{name, service, env, host, tags}, "\x00")
+		samples = append(samples, sample)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating metric samples: %w", err)
+	}
+
+	return counterRates(query, resolution, samples), nil
+}
+
+// counterRates turns counter samples, oldest first, into the per-second rate
+// of each group of included label values per time bucket. Every distinct
+// counter series has its resets detected on its own before the increases of
+// the series in a group are summed, so that samples of other label sets
+// interleaved with a series never look like a reset, and a reset of one
+// series doesn't distort the others. The first sample of a series only sets
+// its starting value.
+func counterRates(query MetricQuery, resolution time.Duration, samples []counterSample) []MetricAggregation {
+	builder := newMetricSeriesBuilder(query)
+	last := make(map[string]float64)
+
+	for _, sample := range samples {
+		group := &builder.series[builder.get(sample.labels, sample.metricType)]
+
+		previous, ok := last[sample.series]
+		last[sample.series] = sample.value
+		if !ok {
+			continue
+		}
+
+		// Buckets arrive in order, so only the last point can match
+		ts := time.Unix(sample.bucket, 0).UTC()
+		points := group.TimeSeries
+		if len(points) == 0 || !points[len(points)-1].Timestamp.Equal(ts) {
+			group.TimeSeries = append(group.TimeSeries, MetricTimeSeriesPoint{Timestamp: ts})
+		}
+		point := &group.TimeSeries[len(group.TimeSeries)-1]
+		point.Value += counterIncrease(previous, sample.value) / resolution.Seconds()
+		point.Count++
+	}
+
+	return builder.series
+}
+
+// counterIncrease returns how much a counter grew from previous to current.
+// A decrease is a reset: the counter restarted from 0 and grew to current.
+func counterIncrease(previous, current float64) float64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// mergeHistogramBuckets adds the bucket counts of one stored histogram into acc
+func mergeHistogramBuckets(acc *models.HistogramMetric, buckets []models.HistogramBucket, count uint64) *models.HistogramMetric {
+	if acc == nil {
+		acc = &models.HistogramMetric{}
+	}
+
+	for _, b := range buckets {
+		merged := false
+		for i := range acc.Buckets {
+			if acc.Buckets[i].UpperBound == b.UpperBound {
+				acc.Buckets[i].Count += b.Count
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			acc.Buckets = append(acc.Buckets, b)
+		}
+	}
+
+	sort.Slice(acc.Buckets, func(i, j int) bool {
+		return acc.Buckets[i].UpperBound < acc.Buckets[j].UpperBound
+	})
+	acc.Count += count
+
+	return acc
+}
+
+// percentileOf returns the nearest-rank percentile of the values
+func percentileOf(values []float64, percentile float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return sorted[percentileRank(len(sorted), percentile)]
+}
+
+// percentileRank returns the index of the nearest-rank percentile among n
+// sorted values
+func percentileRank(n int, percentile float64) int {
+	rank := int(math.Ceil(percentile/100*float64(n))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= n {
+		rank = n - 1
+	}
+	return rank
 }
 
 // CalculatePercentile calculates the percentile value from a histogram metric
@@ -137,11 +532,10 @@ func CalculatePercentile(histogram *models.HistogramMetric, percentile float64)
 	// Find the target count for this percentile
 	targetCount := uint64(float64(histogram.Count) * p)
 
-	// Find the bucket that contains this percentile
-	var cumulativeCount uint64
+	// Bucket counts are cumulative, so the first bucket reaching the
+	// target count contains this percentile
 	for _, bucket := range histogram.Buckets {
-		cumulativeCount += bucket.Count
-		if cumulativeCount >= targetCount {
+		if bucket.Count >= targetCount {
 			// For simplicity, we'll return the bucket's upper bound
 			// A more accurate implementation would interpolate within the bucket
 			return bucket.UpperBound
@@ -152,7 +546,56 @@ func CalculatePercentile(histogram *models.HistogramMetric, percentile float64)
 	return histogram.Buckets[len(histogram.Buckets)-1].UpperBound
 }
 
-// CalculateMetricsRate calculates the rate of change for a counter metric
+// CalculateExponentialPercentile estimates a percentile (0-100) of an
+// exponential histogram. Values are interpolated exponentially within the
+// bucket holding the percentile, so the relative error is below base-1.
+func CalculateExponentialPercentile(histogram *models.ExponentialHistogram, percentile float64) float64 {
+	if percentile < 0 || percentile > 100 || histogram.Count == 0 {
+		return 0
+	}
+
+	rank := float64(histogram.Count) * percentile / 100.0
+	var seen float64
+
+	// Negative buckets hold magnitudes, so the most negative values come
+	// from the highest index
+	negative := histogram.Negative
+	for i := len(negative.BucketCounts) - 1; i >= 0; i-- {
+		count := float64(negative.BucketCounts[i])
+		if count > 0 && seen+count >= rank {
+			lower, upper := histogram.BucketBounds(negative.Offset + int32(i))
+			return -upper * math.Pow(lower/upper, (rank-seen)/count)
+		}
+		seen += count
+	}
+
+	if zeros := float64(histogram.ZeroCount); zeros > 0 && seen+zeros >= rank {
+		return 0
+	}
+	seen += float64(histogram.ZeroCount)
+
+	positive := histogram.Positive
+	for i, c := range positive.BucketCounts {
+		count := float64(c)
+		if count > 0 && seen+count >= rank {
+			lower, upper := histogram.BucketBounds(positive.Offset + int32(i))
+			return lower * math.Pow(upper/lower, (rank-seen)/count)
+		}
+		seen += count
+	}
+
+	// Counts don't add up to Count; fall back to the largest bucket boundary
+	if n := len(positive.BucketCounts); n > 0 {
+		_, upper := histogram.BucketBounds(positive.Offset + int32(n-1))
+		return upper
+	}
+	return 0
+}
+
+// CalculateMetricsRate calculates the rate of change of a single counter
+// series, ordered by time. Points of several label sets must be rated
+// separately, as the rate aggregation of AggregateMetrics does, or a lower
+// value of one series would be taken for a reset of another.
 func CalculateMetricsRate(points []MetricTimeSeriesPoint) []MetricTimeSeriesPoint {
 	if len(points) < 2 {
 		return []MetricTimeSeriesPoint{}
@@ -169,15 +612,9 @@ func CalculateMetricsRate(points []MetricTimeSeriesPoint) []MetricTimeSeriesPoin
 			continue
 		}
 
-		valueDiff := current.Value - previous.Value
-		if valueDiff < 0 {
-			// Counter reset, assume it was reset to 0
-			valueDiff = current.Value
-		}
-
 		rates[i-1] = MetricTimeSeriesPoint{
 			Timestamp: current.Timestamp,
-			Value:     valueDiff / timeDiff,
+			Value:     counterIncrease(previous.Value, current.Value) / timeDiff,
 			Count:     1,
 		}
 	}
@@ -185,39 +622,294 @@ func CalculateMetricsRate(points []MetricTimeSeriesPoint) []MetricTimeSeriesPoin
 	return rates
 }
 
-// ExportPrometheusFormat exports metrics in Prometheus format
+// AlignSeries joins two time series on their bucket timestamps so they can
+// be combined point by point. Only timestamps present in both series are
+// kept (an inner join), in the order they appear in a. Each aligned pair
+// holds the value from a followed by the value from b.
+func AlignSeries(a, b []MetricTimeSeriesPoint) (aligned [][2]float64, timestamps []time.Time) {
+	values := make(map[int64]float64, len(b))
+	for _, point := range b {
+		key := point.Timestamp.UnixNano()
+		if _, ok := values[key]; !ok {
+			values[key] = point.Value
+		}
+	}
+
+	for _, point := range a {
+		value, ok := values[point.Timestamp.UnixNano()]
+		if !ok {
+			continue
+		}
+		aligned = append(aligned, [2]float64{point.Value, value})
+		timestamps = append(timestamps, point.Timestamp)
+	}
+
+	return aligned, timestamps
+}
+
+// ApdexScore is the Apdex of a service's latency samples against a target
+// T. Samples up to T are satisfied, samples up to 4T are tolerating and
+// slower samples are frustrated. The score is (satisfied + tolerating/2) /
+// total, from 0 (all frustrated) to 1 (all satisfied).
+type ApdexScore struct {
+	Service    string  `json:"service"`
+	Satisfied  int     `json:"satisfied"`
+	Tolerating int     `json:"tolerating"`
+	Frustrated int     `json:"frustrated"`
+	Total      int     `json:"total"`
+	Score      float64 `json:"score"`
+}
+
+// ApdexCalculator classifies latency samples per service against a target
+type ApdexCalculator struct {
+	target float64
+	scores map[string]*ApdexScore
+}
+
+// NewApdexCalculator creates a calculator for the given target latency,
+// which must be in the unit of the samples
+func NewApdexCalculator(target float64) *ApdexCalculator {
+	return &ApdexCalculator{
+		target: target,
+		scores: make(map[string]*ApdexScore),
+	}
+}
+
+// Add classifies a latency sample of service
+func (c *ApdexCalculator) Add(service string, latency float64) {
+	score, ok := c.scores[service]
+	if !ok {
+		score = &ApdexScore{Service: service}
+		c.scores[service] = score
+	}
+
+	switch {
+	case latency <= c.target:
+		score.Satisfied++
+	case latency <= 4*c.target:
+		score.Tolerating++
+	default:
+		score.Frustrated++
+	}
+	score.Total++
+}
+
+// Scores returns the Apdex of each service with samples, sorted by service
+func (c *ApdexCalculator) Scores() []ApdexScore {
+	services := make([]string, 0, len(c.scores))
+	for service := range c.scores {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	scores := make([]ApdexScore, 0, len(services))
+	for _, service := range services {
+		score := *c.scores[service]
+		score.Score = (float64(score.Satisfied) + float64(score.Tolerating)/2) / float64(score.Total)
+		scores = append(scores, score)
+	}
+	return scores
+}
+
+// GroupPercentiles holds percentiles of the merged histograms of one label
+// group, keyed by the percentile as written in the query (e.g. "99")
+type GroupPercentiles struct {
+	Group       string             `json:"group"`
+	Count       uint64             `json:"count"`
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+// HistogramPercentilesByGroup merges the histograms sharing a value of the
+// groupBy tag and computes the percentiles of each group. Histograms without
+// the tag form the "" group. Groups are sorted by value.
+func HistogramPercentilesByGroup(histograms []*models.HistogramMetric, groupBy string, percentiles []float64) []GroupPercentiles {
+	merged := make(map[string]*models.HistogramMetric)
+	for _, histogram := range histograms {
+		group := histogram.Tags[groupBy]
+		merged[group] = mergeHistogramBuckets(merged[group], histogram.Buckets, histogram.Count)
+	}
+
+	groups := make([]string, 0, len(merged))
+	for group := range merged {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	results := make([]GroupPercentiles, 0, len(groups))
+	for _, group := range groups {
+		histogram := merged[group]
+		result := GroupPercentiles{
+			Group:       group,
+			Count:       histogram.Count,
+			Percentiles: make(map[string]float64, len(percentiles)),
+		}
+		for _, percentile := range percentiles {
+			result.Percentiles[strconv.FormatFloat(percentile, 'f', -1, 64)] = CalculatePercentile(histogram, percentile)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// ExportPrometheusFormat exports metrics in the Prometheus text exposition
+// format. Series sharing a name are written under a single HELP and TYPE
+// header, and names and label values are escaped as the format requires.
 func ExportPrometheusFormat(aggregations []MetricAggregation) string {
-	var result string
-
-	for _, agg := range aggregations {
-		// Add metric help and type
-		result += fmt.Sprintf("# HELP %s Pulse metric\n", agg.Name)
-		result += fmt.Sprintf("# TYPE %s %s\n", agg.Name, agg.Type)
-
-		// Add data points
-		for _, point := range agg.TimeSeries {
-			// Format labels
-			labelStr := "{"
-			for k, v := range agg.Labels {
-				if labelStr != "{" {
-					labelStr += ","
-				}
-				labelStr += fmt.Sprintf("%s=\"%s\"", k, v)
+	var b strings.Builder
+
+	names, groups := groupByPrometheusName(len(aggregations), func(i int) string { return aggregations[i].Name })
+	for _, name := range names {
+		first := aggregations[groups[name][0]]
+		writePrometheusHeader(&b, name, prometheusType(first.Type))
+
+		for _, i := range groups[name] {
+			agg := aggregations[i]
+			labels := formatPrometheusLabels(agg.Labels)
+			for _, point := range agg.TimeSeries {
+				writePrometheusSample(&b, name, labels, point.Value, point.Timestamp)
 			}
-			labelStr += "}"
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// ExportPrometheusHistograms exports histogram metrics in the Prometheus text
+// exposition format as cumulative _bucket series plus _sum and _count
+func ExportPrometheusHistograms(histograms []*models.HistogramMetric) string {
+	var b strings.Builder
+
+	names, groups := groupByPrometheusName(len(histograms), func(i int) string { return histograms[i].Name })
+	for _, name := range names {
+		writePrometheusHeader(&b, name, "histogram")
+
+		for _, i := range groups[name] {
+			histogram := histograms[i]
 
-			// Add timestamp if not empty
-			tsStr := ""
-			if !point.Timestamp.IsZero() {
-				tsStr = fmt.Sprintf(" %d", point.Timestamp.UnixNano()/1000000)
+			// Bucket counts are already cumulative; +Inf holds every observation
+			bucketLabels := make(map[string]string, len(histogram.Tags)+1)
+			for k, v := range histogram.Tags {
+				bucketLabels[k] = v
 			}
+			for _, bucket := range histogram.Buckets {
+				bucketLabels["le"] = strconv.FormatFloat(bucket.UpperBound, 'g', -1, 64)
+				writePrometheusSample(&b, name+"_bucket", formatPrometheusLabels(bucketLabels), float64(bucket.Count), histogram.Timestamp)
+			}
+			bucketLabels["le"] = "+Inf"
+			writePrometheusSample(&b, name+"_bucket", formatPrometheusLabels(bucketLabels), float64(histogram.Count), histogram.Timestamp)
 
-			// Add the data point
-			result += fmt.Sprintf("%s%s %g%s\n", agg.Name, labelStr, point.Value, tsStr)
+			labels := formatPrometheusLabels(histogram.Tags)
+			writePrometheusSample(&b, name+"_sum", labels, histogram.Sum, histogram.Timestamp)
+			writePrometheusSample(&b, name+"_count", labels, float64(histogram.Count), histogram.Timestamp)
 		}
 
-		result += "\n"
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// groupByPrometheusName groups n series by their sanitized metric name,
+// returning the names in order of first appearance
+func groupByPrometheusName(n int, nameOf func(i int) string) ([]string, map[string][]int) {
+	var names []string
+	groups := make(map[string][]int)
+	for i := 0; i < n; i++ {
+		name := prometheusName(nameOf(i))
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], i)
+	}
+	return names, groups
+}
+
+// writePrometheusHeader writes the HELP and TYPE lines of a metric family
+func writePrometheusHeader(b *strings.Builder, name, metricType string) {
+	fmt.Fprintf(b, "# HELP %s Pulse metric\n", name)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, metricType)
+}
+
+// writePrometheusSample writes a single sample line, with a millisecond
+// timestamp when one is set
+func writePrometheusSample(b *strings.Builder, name, labels string, value float64, timestamp time.Time) {
+	b.WriteString(name)
+	b.WriteString(labels)
+	b.WriteString(" ")
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	if !timestamp.IsZero() {
+		fmt.Fprintf(b, " %d", timestamp.UnixNano()/int64(time.Millisecond))
+	}
+	b.WriteString("\n")
+}
+
+// prometheusType maps a metric type onto a Prometheus metric type. Types
+// without a single-sample Prometheus equivalent are exported as untyped.
+func prometheusType(metricType models.MetricType) string {
+	switch metricType {
+	case models.MetricTypeCounter, models.MetricTypeGauge, models.MetricTypeHistogram:
+		return string(metricType)
+	default:
+		return "untyped"
+	}
+}
+
+// prometheusName replaces the characters Prometheus doesn't allow in metric
+// names, such as the dots in "api.latency", with underscores
+func prometheusName(name string) string {
+	return sanitizePrometheusName(name, true)
+}
+
+// sanitizePrometheusName keeps [a-zA-Z0-9_] (and ':' when allowColon is set)
+// and prefixes names that would start with a digit
+func sanitizePrometheusName(name string, allowColon bool) string {
+	var b strings.Builder
+	for i, r := range name {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(r >= '0' && r <= '9' && i > 0) || (r == ':' && allowColon)
+		switch {
+		case valid:
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			b.WriteString("_")
+			b.WriteRune(r)
+		default:
+			b.WriteString("_")
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// prometheusLabelEscaper escapes label values as the exposition format requires
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatPrometheusLabels renders labels sorted by name, or an empty string
+// when there are none
+func formatPrometheusLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("{")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `%s="%s"`, sanitizePrometheusName(k, false), prometheusLabelEscaper.Replace(labels[k]))
 	}
+	b.WriteString("}")
 
-	return result
+	return b.String()
 }
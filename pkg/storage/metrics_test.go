@@ -0,0 +1,613 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// saveTestMetrics stores one gauge sample per value, spaced offset apart from base
+func saveTestMetrics(t *testing.T, st *SQLiteStorage, name string, base time.Time, offset time.Duration, tags map[string]string, values ...float64) {
+	t.Helper()
+
+	for i, v := range values {
+		metric := models.NewMetric(name, v, models.MetricTypeGauge, "api")
+		metric.ID = fmt.Sprintf("%s-%v-%d-%d", name, tags, base.UnixNano(), i)
+		metric.Timestamp = base.Add(time.Duration(i) * offset)
+		for k, tv := range tags {
+			metric.AddTag(k, tv)
+		}
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+	}
+}
+
+func TestSQLiteStorage_AggregateMetrics(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	base := time.Now().UTC().Truncate(time.Minute).Add(-10 * time.Minute)
+
+	// Two samples in the first minute, one in the second
+	saveTestMetrics(t, st, "latency", base, 30*time.Second, map[string]string{"endpoint": "/users"}, 10, 30, 50)
+	// Different name and endpoint, must not be included
+	saveTestMetrics(t, st, "errors", base, time.Second, nil, 1000)
+	saveTestMetrics(t, st, "latency", base, time.Second, map[string]string{"endpoint": "/admin"}, 1000)
+
+	tests := []struct {
+		aggregation string
+		expected    []float64
+	}{
+		{"avg", []float64{20, 50}},
+		{"sum", []float64{40, 50}},
+		{"min", []float64{10, 50}},
+		{"max", []float64{30, 50}},
+		{"count", []float64{2, 1}},
+		{"p50", []float64{10, 50}},
+		{"p99", []float64{30, 50}},
+	}
+
+	for _, tt := range tests {
+		series, err := st.AggregateMetrics(MetricQuery{
+			Name:        "latency",
+			Service:     "api",
+			Tags:        map[string]string{"endpoint": "/users"},
+			From:        base,
+			To:          base.Add(5 * time.Minute),
+			Resolution:  "1m",
+			Aggregation: tt.aggregation,
+		})
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", tt.aggregation, err)
+		}
+		if len(series) != 1 {
+			t.Fatalf("%s: expected 1 series, got %d", tt.aggregation, len(series))
+		}
+
+		points := series[0].TimeSeries
+		if len(points) != len(tt.expected) {
+			t.Fatalf("%s: expected %d points, got %d", tt.aggregation, len(tt.expected), len(points))
+		}
+		for i, want := range tt.expected {
+			if points[i].Value != want {
+				t.Errorf("%s: point %d: expected %v, got %v", tt.aggregation, i, want, points[i].Value)
+			}
+		}
+
+		// Points are aligned to the resolution and carry sample counts
+		if !points[0].Timestamp.Equal(base) || !points[1].Timestamp.Equal(base.Add(time.Minute)) {
+			t.Errorf("%s: unexpected bucket timestamps %v, %v", tt.aggregation, points[0].Timestamp, points[1].Timestamp)
+		}
+		if points[0].Count != 2 || points[1].Count != 1 {
+			t.Errorf("%s: expected counts 2 and 1, got %d and %d", tt.aggregation, points[0].Count, points[1].Count)
+		}
+	}
+
+	if _, err := st.AggregateMetrics(MetricQuery{Name: "latency", Aggregation: "median"}); err == nil {
+		t.Error("expected error for unsupported aggregation")
+	}
+}
+
+func TestSQLiteStorage_AggregateMetrics_IncludeLabels(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	base := time.Now().UTC().Truncate(time.Minute).Add(-10 * time.Minute)
+
+	saveTestMetrics(t, st, "requests", base, time.Second, map[string]string{"endpoint": "/users", "region": "eu"}, 1, 2)
+	saveTestMetrics(t, st, "requests", base, time.Second, map[string]string{"endpoint": "/admin", "region": "eu"}, 5)
+
+	series, err := st.AggregateMetrics(MetricQuery{
+		Name:          "requests",
+		From:          base,
+		To:            base.Add(time.Minute),
+		Resolution:    "1m",
+		Aggregation:   "sum",
+		IncludeLabels: []string{"endpoint"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("expected one series per endpoint, got %d", len(series))
+	}
+
+	sums := map[string]float64{}
+	for _, s := range series {
+		if len(s.TimeSeries) != 1 {
+			t.Fatalf("expected 1 point for %v, got %d", s.Labels, len(s.TimeSeries))
+		}
+		sums[s.Labels["endpoint"]] = s.TimeSeries[0].Value
+	}
+	if sums["/users"] != 3 || sums["/admin"] != 5 {
+		t.Errorf("unexpected sums per endpoint: %v", sums)
+	}
+}
+
+func TestSQLiteStorage_AggregateMetrics_RatePerSeries(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	base := time.Now().UTC().Truncate(time.Minute).Add(-10 * time.Minute)
+
+	// Two counters sampled every 10s, interleaved 5s apart, each restarting
+	// once: a grows by 10+10+5+10+10 = 45 and b by 60+30+60+60+60 = 270
+	// within the minute
+	saveTestMetrics(t, st, "requests", base, 10*time.Second, map[string]string{"instance": "a"}, 100, 110, 120, 5, 15, 25)
+	saveTestMetrics(t, st, "requests", base.Add(5*time.Second), 10*time.Second, map[string]string{"instance": "b"}, 1000, 1060, 30, 90, 150, 210)
+
+	query := MetricQuery{
+		Name:          "requests",
+		From:          base,
+		To:            base.Add(time.Minute),
+		Resolution:    "1m",
+		Aggregation:   "rate",
+		IncludeLabels: []string{"instance"},
+	}
+	series, err := st.AggregateMetrics(query)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("expected one series per instance, got %d", len(series))
+	}
+	for _, s := range series {
+		expected := map[string]float64{"a": 45.0 / 60, "b": 270.0 / 60}[s.Labels["instance"]]
+		if len(s.TimeSeries) != 1 {
+			t.Fatalf("expected 1 point for %v, got %d", s.Labels, len(s.TimeSeries))
+		}
+		point := s.TimeSeries[0]
+		if math.Abs(point.Value-expected) > 1e-9 || point.Count != 5 {
+			t.Errorf("instance %s: expected a rate of %v/s over 5 increases, got %v over %d",
+				s.Labels["instance"], expected, point.Value, point.Count)
+		}
+	}
+
+	// Without the label, the rates of both counters are summed rather than
+	// rating their interleaved samples as one series
+	query.IncludeLabels = nil
+	series, err = st.AggregateMetrics(query)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(series) != 1 || len(series[0].TimeSeries) != 1 {
+		t.Fatalf("expected a single series with 1 point, got %+v", series)
+	}
+	if got := series[0].TimeSeries[0].Value; math.Abs(got-315.0/60) > 1e-9 {
+		t.Errorf("expected a total rate of %v/s, got %v", 315.0/60, got)
+	}
+}
+
+func TestCalculateMetricsRate(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC)
+	points := []MetricTimeSeriesPoint{
+		{Timestamp: base, Value: 100},
+		{Timestamp: base.Add(10 * time.Second), Value: 150},
+		{Timestamp: base.Add(20 * time.Second), Value: 20}, // Reset
+	}
+
+	rates := CalculateMetricsRate(points)
+	if len(rates) != 2 || rates[0].Value != 5 || rates[1].Value != 2 {
+		t.Errorf("expected rates of 5/s and 2/s after the reset, got %+v", rates)
+	}
+}
+
+func TestSQLiteStorage_AggregateMetrics_HistogramPercentiles(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	base := time.Now().UTC().Truncate(time.Minute).Add(-10 * time.Minute)
+
+	// Two histogram samples with cumulative buckets, merged for the minute
+	for i, counts := range [][]uint64{{8, 9, 10}, {8, 9, 10}} {
+		metric := models.NewMetric("duration", 0, models.MetricTypeHistogram, "api")
+		metric.ID = fmt.Sprintf("hist-metric-%d", i)
+		metric.Timestamp = base.Add(time.Duration(i) * time.Second)
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+
+		buckets := fmt.Sprintf(`[{"upper_bound":0.1,"count":%d},{"upper_bound":0.5,"count":%d},{"upper_bound":1,"count":%d}]`,
+			counts[0], counts[1], counts[2])
+		if _, err := st.db.Exec(`INSERT INTO histogram_metrics (id, metric_id, buckets, sum, count) VALUES (?, ?, ?, 0, 10)`,
+			fmt.Sprintf("hist-%d", i), metric.ID, buckets); err != nil {
+			t.Fatalf("failed to insert histogram data: %v", err)
+		}
+	}
+
+	for aggregation, expected := range map[string]float64{"p50": 0.1, "p90": 0.5, "p99": 1} {
+		series, err := st.AggregateMetrics(MetricQuery{
+			Name:        "duration",
+			From:        base,
+			To:          base.Add(time.Minute),
+			Resolution:  "1m",
+			Aggregation: aggregation,
+		})
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", aggregation, err)
+		}
+		if len(series) != 1 || len(series[0].TimeSeries) != 1 {
+			t.Fatalf("%s: expected a single point, got %+v", aggregation, series)
+		}
+		if series[0].Type != models.MetricTypeHistogram {
+			t.Errorf("%s: expected histogram type, got %s", aggregation, series[0].Type)
+		}
+		if got := series[0].TimeSeries[0].Value; got != expected {
+			t.Errorf("%s: expected %v, got %v", aggregation, expected, got)
+		}
+	}
+}
+
+func TestSQLiteStorage_SaveMetrics(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+
+	var metrics []*models.Metric
+	for i := 0; i < 3; i++ {
+		metric := models.NewMetric("requests", float64(i), models.MetricTypeCounter, "api")
+		metric.ID = fmt.Sprintf("batch-%d", i)
+		metrics = append(metrics, metric)
+	}
+	if err := st.SaveMetrics(metrics); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// A failing insert rolls back the whole batch
+	duplicate := models.NewMetric("requests", 9, models.MetricTypeCounter, "api")
+	duplicate.ID = "batch-0"
+	fresh := models.NewMetric("requests", 8, models.MetricTypeCounter, "api")
+	fresh.ID = "batch-3"
+	if err := st.SaveMetrics([]*models.Metric{fresh, duplicate}); err == nil {
+		t.Fatal("expected error for duplicate metric ID")
+	}
+
+	result, err := st.QueryMetrics(&models.QueryParams{Name: "requests"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if metrics := result["metrics"].([]map[string]interface{}); len(metrics) != 3 {
+		t.Errorf("expected 3 metrics after the failed batch, got %d", len(metrics))
+	}
+}
+
+func TestSQLiteStorage_SaveHistogramMetric(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	base := time.Now().UTC().Truncate(time.Minute).Add(-10 * time.Minute)
+
+	histogram, err := models.NewHistogramMetric("duration", "api", []float64{1})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	histogram.Timestamp = base
+	histogram.Buckets = []models.HistogramBucket{{UpperBound: 0.1, Count: 6}, {UpperBound: 0.5, Count: 9}, {UpperBound: 1, Count: 10}}
+	histogram.Sum = 2.5
+	histogram.Count = 10
+	if err := st.SaveHistogramMetric(histogram); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if histogram.ID == "" {
+		t.Fatal("expected an ID to be generated")
+	}
+
+	// The bucket data is stored alongside the metric row
+	var (
+		bucketsJSON string
+		sum         float64
+		count       uint64
+	)
+	if err := st.db.QueryRow(`SELECT buckets, sum, count FROM histogram_metrics WHERE metric_id = ?`, histogram.ID).
+		Scan(&bucketsJSON, &sum, &count); err != nil {
+		t.Fatalf("failed to read histogram data: %v", err)
+	}
+
+	var buckets []models.HistogramBucket
+	if err := json.Unmarshal([]byte(bucketsJSON), &buckets); err != nil {
+		t.Fatalf("failed to unmarshal buckets: %v", err)
+	}
+	if len(buckets) != 3 || buckets[1] != histogram.Buckets[1] {
+		t.Errorf("expected buckets %v, got %v", histogram.Buckets, buckets)
+	}
+	if sum != 2.5 || count != 10 {
+		t.Errorf("expected sum 2.5 and count 10, got %v and %d", sum, count)
+	}
+
+	// Percentiles are computed from the stored buckets
+	series, err := st.AggregateMetrics(MetricQuery{
+		Name:        "duration",
+		From:        base,
+		To:          base.Add(time.Minute),
+		Resolution:  "1m",
+		Aggregation: "p90",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(series) != 1 || len(series[0].TimeSeries) != 1 || series[0].TimeSeries[0].Value != 0.5 {
+		t.Errorf("expected p90 of 0.5, got %+v", series)
+	}
+}
+
+func TestSQLiteStorage_ExponentialHistogram(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+
+	// A known distribution: the integers 1..1000
+	histogram := models.NewExponentialHistogram("duration", "api", 3)
+	histogram.AddTag("route", "/users")
+	for v := 1; v <= 1000; v++ {
+		histogram.Observe(float64(v))
+	}
+	histogram.Observe(0)
+	histogram.Observe(-5)
+
+	if err := st.SaveExponentialHistogram(histogram); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	loaded, err := st.GetExponentialHistogram(histogram.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if loaded.Type != models.MetricTypeExponentialHistogram || loaded.Tags["route"] != "/users" {
+		t.Errorf("unexpected metric fields: %+v", loaded.Metric)
+	}
+	if loaded.Scale != 3 || loaded.ZeroCount != 1 || loaded.Count != 1002 || loaded.Sum != histogram.Sum {
+		t.Errorf("expected scale 3, zero count 1, count 1002 and sum %v, got %d, %d, %d and %v",
+			histogram.Sum, loaded.Scale, loaded.ZeroCount, loaded.Count, loaded.Sum)
+	}
+	if loaded.Positive.Offset != histogram.Positive.Offset || len(loaded.Positive.BucketCounts) != len(histogram.Positive.BucketCounts) {
+		t.Errorf("positive buckets did not round-trip: %+v", loaded.Positive)
+	}
+	if loaded.Negative.Offset != histogram.Negative.Offset || loaded.Negative.BucketCounts[0] != 1 {
+		t.Errorf("negative buckets did not round-trip: %+v", loaded.Negative)
+	}
+
+	// At scale 3 the base is 2^(1/8), so estimates are within ~9% of the true value
+	for percentile, expected := range map[float64]float64{50: 500, 90: 900, 99: 990} {
+		got := CalculateExponentialPercentile(loaded, percentile)
+		if math.Abs(got-expected)/expected > 0.0905 {
+			t.Errorf("p%v: expected about %v, got %v", percentile, expected, got)
+		}
+	}
+
+	// The lowest observations are the negative value and zero
+	if got := CalculateExponentialPercentile(loaded, 0.05); got >= -4 || got < -5.5 {
+		t.Errorf("expected p0.05 in the negative bucket holding -5, got %v", got)
+	}
+	if got := CalculateExponentialPercentile(loaded, 0.1); got != 0 {
+		t.Errorf("expected p0.1 to be zero, got %v", got)
+	}
+	if got := CalculateExponentialPercentile(models.NewExponentialHistogram("empty", "api", 0), 50); got != 0 {
+		t.Errorf("expected 0 for an empty histogram, got %v", got)
+	}
+}
+
+func TestSQLiteStorage_QueryHistograms(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	base := time.Now().UTC().Truncate(time.Minute).Add(-10 * time.Minute)
+
+	for i, service := range []string{"api", "api", "billing"} {
+		histogram, err := models.NewHistogramMetric("duration", service, []float64{0.1, 1})
+		if err != nil {
+			t.Fatalf("failed to create histogram: %v", err)
+		}
+		histogram.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		histogram.AddTag("route", "/checkout")
+		histogram.Observe(0.05)
+		histogram.Observe(float64(i))
+		histogram.Percentile[50] = 0.05
+		if err := st.SaveHistogramMetric(histogram); err != nil {
+			t.Fatalf("failed to save histogram: %v", err)
+		}
+	}
+
+	// Plain metrics don't show up as histograms
+	saveTestMetrics(t, st, "duration", base, time.Minute, nil, 1)
+
+	histograms, err := st.QueryHistograms(&models.QueryParams{Name: "duration", Service: "api"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(histograms) != 2 {
+		t.Fatalf("expected 2 histograms, got %d", len(histograms))
+	}
+
+	latest := histograms[1]
+	if !latest.Timestamp.Equal(base.Add(time.Minute)) {
+		t.Errorf("expected histograms oldest first, got %v last", latest.Timestamp)
+	}
+	if len(latest.Buckets) != 2 || latest.Buckets[0].Count != 1 || latest.Buckets[1].Count != 2 {
+		t.Errorf("unexpected buckets: %v", latest.Buckets)
+	}
+	if latest.Sum != 1.05 || latest.Count != 2 {
+		t.Errorf("expected sum 1.05 and count 2, got %v and %d", latest.Sum, latest.Count)
+	}
+	if latest.Percentile[50] != 0.05 || latest.Tags["route"] != "/checkout" || latest.Type != models.MetricTypeHistogram {
+		t.Errorf("unexpected histogram: %+v", latest)
+	}
+
+	histograms, err = st.QueryHistograms(&models.QueryParams{Since: base.Add(90 * time.Second)})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(histograms) != 1 || histograms[0].Service != "billing" {
+		t.Errorf("expected only the billing histogram since the cutoff, got %d", len(histograms))
+	}
+}
+
+func TestExportPrometheusFormat(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	output := ExportPrometheusFormat([]MetricAggregation{
+		{
+			Name:       "http.requests",
+			Type:       models.MetricTypeCounter,
+			TimeSeries: []MetricTimeSeriesPoint{{Timestamp: ts, Value: 42}},
+			Labels:     map[string]string{"service": "api", "path": `/a"b\c` + "\n"},
+		},
+		{
+			Name:       "cpu.usage",
+			Type:       models.MetricTypeGauge,
+			TimeSeries: []MetricTimeSeriesPoint{{Value: 0.5}},
+		},
+		{
+			Name:       "http.requests",
+			Type:       models.MetricTypeCounter,
+			TimeSeries: []MetricTimeSeriesPoint{{Timestamp: ts, Value: 7}},
+			Labels:     map[string]string{"service": "web", "content-type": "json"},
+		},
+	})
+
+	expected := `# HELP http_requests Pulse metric
+# TYPE http_requests counter
+http_requests{path="/a\"b\\c\n",service="api"} 42 1700000000000
+http_requests{content_type="json",service="web"} 7 1700000000000
+
+# HELP cpu_usage Pulse metric
+# TYPE cpu_usage gauge
+cpu_usage 0.5
+
+`
+	if output != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", output, expected)
+	}
+}
+
+func TestExportPrometheusHistograms(t *testing.T) {
+	histogram, err := models.NewHistogramMetric("request.duration", "api", []float64{0.1, 0.5})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	histogram.Timestamp = time.Time{}
+	histogram.AddTag("service", "api")
+	histogram.Observe(0.05)
+	histogram.Observe(0.3)
+	histogram.Observe(2)
+
+	expected := `# HELP request_duration Pulse metric
+# TYPE request_duration histogram
+request_duration_bucket{le="0.1",service="api"} 1
+request_duration_bucket{le="0.5",service="api"} 2
+request_duration_bucket{le="+Inf",service="api"} 3
+request_duration_sum{service="api"} 2.35
+request_duration_count{service="api"} 3
+
+`
+	if output := ExportPrometheusHistograms([]*models.HistogramMetric{histogram}); output != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", output, expected)
+	}
+}
+
+func TestAlignSeries(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+
+	// a covers minutes 0-3, b covers 2-5 with a gap at 3
+	a := []MetricTimeSeriesPoint{
+		{Timestamp: at(0), Value: 1},
+		{Timestamp: at(1), Value: 2},
+		{Timestamp: at(2), Value: 3},
+		{Timestamp: at(3), Value: 4},
+	}
+	b := []MetricTimeSeriesPoint{
+		{Timestamp: at(2), Value: 30},
+		{Timestamp: at(4), Value: 50},
+		{Timestamp: at(5), Value: 60},
+	}
+
+	aligned, timestamps := AlignSeries(a, b)
+	if len(aligned) != 1 || len(timestamps) != 1 {
+		t.Fatalf("expected 1 common point, got %d values and %d timestamps", len(aligned), len(timestamps))
+	}
+	if !timestamps[0].Equal(at(2)) || aligned[0] != [2]float64{3, 30} {
+		t.Errorf("expected (3, 30) at %v, got %v at %v", at(2), aligned[0], timestamps[0])
+	}
+
+	// Matching is on the instant, regardless of location
+	b = append(b, MetricTimeSeriesPoint{Timestamp: at(0).In(time.FixedZone("UTC+2", 2*60*60)), Value: 10})
+	aligned, timestamps = AlignSeries(a, b)
+	if len(aligned) != 2 || aligned[0] != [2]float64{1, 10} || aligned[1] != [2]float64{3, 30} {
+		t.Errorf("expected pairs (1, 10) and (3, 30) in order, got %v at %v", aligned, timestamps)
+	}
+
+	// Disjoint and empty series produce nothing
+	if aligned, timestamps := AlignSeries(a[:2], b[1:3]); len(aligned) != 0 || len(timestamps) != 0 {
+		t.Errorf("expected no points for disjoint series, got %v", aligned)
+	}
+	if aligned, _ := AlignSeries(nil, b); len(aligned) != 0 {
+		t.Errorf("expected no points for an empty series, got %v", aligned)
+	}
+}
+
+func TestApdexCalculator(t *testing.T) {
+	calculator := NewApdexCalculator(200)
+
+	// checkout: 6 satisfied (including exactly T), 3 tolerating (including
+	// exactly 4T) and 1 frustrated
+	for _, latency := range []float64{50, 80, 120, 150, 190, 200, 450, 700, 800, 801} {
+		calculator.Add("checkout", latency)
+	}
+	calculator.Add("auth", 20)
+
+	scores := calculator.Scores()
+	if len(scores) != 2 || scores[0].Service != "auth" || scores[1].Service != "checkout" {
+		t.Fatalf("expected scores for auth and checkout, got %+v", scores)
+	}
+	if scores[0].Score != 1 {
+		t.Errorf("expected auth to score 1, got %v", scores[0].Score)
+	}
+
+	checkout := scores[1]
+	if checkout.Satisfied != 6 || checkout.Tolerating != 3 || checkout.Frustrated != 1 || checkout.Total != 10 {
+		t.Errorf("unexpected classification: %+v", checkout)
+	}
+	// (6 + 3/2) / 10
+	if math.Abs(checkout.Score-0.75) > 1e-9 {
+		t.Errorf("expected score 0.75, got %v", checkout.Score)
+	}
+}
+
+func TestHistogramPercentilesByGroup(t *testing.T) {
+	bounds := []float64{10, 50, 100, 500, 1000}
+	observe := func(endpoint string, values ...float64) *models.HistogramMetric {
+		histogram, err := models.NewHistogramMetric("http.latency", "api", bounds)
+		if err != nil {
+			t.Fatalf("failed to create histogram: %v", err)
+		}
+		if endpoint != "" {
+			histogram.AddTag("endpoint", endpoint)
+		}
+		for _, value := range values {
+			histogram.Observe(value)
+		}
+		return histogram
+	}
+
+	// /users is fast apart from a slow tail split across two histograms;
+	// /search is uniformly slow
+	var histograms []*models.HistogramMetric
+	for i := 0; i < 9; i++ {
+		histograms = append(histograms, observe("/users", 5, 5, 5, 5, 5, 5, 5, 5, 5, 5))
+	}
+	histograms = append(histograms, observe("/users", 5, 5, 5, 5, 5, 5, 5, 5, 400, 400))
+	histograms = append(histograms, observe("/search", 700, 800, 900))
+	histograms = append(histograms, observe("", 20, 30))
+
+	groups := HistogramPercentilesByGroup(histograms, "endpoint", []float64{50, 99})
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %+v", groups)
+	}
+
+	expected := []struct {
+		group    string
+		count    uint64
+		p50, p99 float64
+	}{
+		{"", 2, 50, 50},
+		{"/search", 3, 1000, 1000},
+		{"/users", 100, 10, 500},
+	}
+	for i, want := range expected {
+		got := groups[i]
+		if got.Group != want.group || got.Count != want.count {
+			t.Errorf("expected group %q with %d observations, got %+v", want.group, want.count, got)
+		}
+		if got.Percentiles["50"] != want.p50 || got.Percentiles["99"] != want.p99 {
+			t.Errorf("%s: expected p50 %v and p99 %v, got %v", want.group, want.p50, want.p99, got.Percentiles)
+		}
+	}
+}
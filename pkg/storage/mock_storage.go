@@ -2,6 +2,7 @@ package storage
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"strings"
 	"sync"
@@ -20,8 +21,14 @@ type MockStorage struct {
 	traces      []*models.Trace
 	closed      bool
 	errorOnSave bool
+
+	// Duration and status of each trace saved whole by SaveTrace, by trace ID
+	traceDurations map[string]int64
+	traceStatuses  map[string]models.SpanStatus
 }
 
+var _ Storage = (*MockStorage)(nil)
+
 // NewMockStorage creates a new mock storage instance
 func NewMockStorage() *MockStorage {
 	return &MockStorage{
@@ -31,6 +38,9 @@ func NewMockStorage() *MockStorage {
 		spans:      make([]*models.Span, 0),
 		traces:     make([]*models.Trace, 0),
 		closed:     false,
+
+		traceDurations: make(map[string]int64),
+		traceStatuses:  make(map[string]models.SpanStatus),
 	}
 }
 
@@ -58,6 +68,23 @@ func (m *MockStorage) SaveLog(log *models.LogEntry) error {
 	return nil
 }
 
+// SaveLogs implements Storage.SaveLogs
+func (m *MockStorage) SaveLogs(logs []*models.LogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrStorageClosed
+	}
+
+	if m.errorOnSave {
+		return ErrSaveFailed
+	}
+
+	m.logs = append(m.logs, logs...)
+	return nil
+}
+
 // SaveMetric implements Storage.SaveMetric
 func (m *MockStorage) SaveMetric(metric *models.Metric) error {
 	m.mu.Lock()
@@ -76,6 +103,23 @@ func (m *MockStorage) SaveMetric(metric *models.Metric) error {
 	return nil
 }
 
+// SaveMetrics implements Storage.SaveMetrics
+func (m *MockStorage) SaveMetrics(metrics []*models.Metric) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrStorageClosed
+	}
+
+	if m.errorOnSave {
+		return ErrSaveFailed
+	}
+
+	m.metrics = append(m.metrics, metrics...)
+	return nil
+}
+
 // SaveHistogramMetric saves a histogram metric
 func (m *MockStorage) SaveHistogramMetric(histogram *models.HistogramMetric) error {
 	m.mu.Lock()
@@ -110,6 +154,23 @@ func (m *MockStorage) SaveSpan(span *models.Span) error {
 	return nil
 }
 
+// SaveSpans implements Storage.SaveSpans
+func (m *MockStorage) SaveSpans(spans []*models.Span) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrStorageClosed
+	}
+
+	if m.errorOnSave {
+		return ErrSaveFailed
+	}
+
+	m.spans = append(m.spans, spans...)
+	return nil
+}
+
 // SaveTrace implements Storage.SaveTrace
 func (m *MockStorage) SaveTrace(trace *models.Trace) error {
 	m.mu.Lock()
@@ -130,6 +191,30 @@ func (m *MockStorage) SaveTrace(trace *models.Trace) error {
 		m.spans = append(m.spans, span)
 	}
 
+	// Record the duration and status of the trace across all its saved
+	// spans, as the SQL backends do in the traces table
+	var spans []*models.Span
+	for _, span := range m.spans {
+		if span.TraceID == trace.ID {
+			spans = append(spans, span)
+		}
+	}
+	if _, status, duration, ok := mergeTrace(trace, "", m.traceStatuses[trace.ID], spans); ok {
+		m.traceDurations[trace.ID] = duration
+		m.traceStatuses[trace.ID] = status
+	}
+
+	return nil
+}
+
+// Ping implements Storage.Ping, failing once the mock is closed
+func (m *MockStorage) Ping() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return ErrStorageClosed
+	}
 	return nil
 }
 
@@ -193,7 +278,7 @@ func (m *MockStorage) GetTraces() []*models.Trace {
 }
 
 // QueryLogs implements the Storage.QueryLogs method for the mock storage
-func (m *MockStorage) QueryLogs(query *models.QueryParams) ([]map[string]interface{}, error) {
+func (m *MockStorage) QueryLogs(query *models.QueryParams) (map[string]interface{}, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -204,37 +289,14 @@ func (m *MockStorage) QueryLogs(query *models.QueryParams) ([]map[string]interfa
 	// Filter logs based on query parameters
 	var filteredLogs []*models.LogEntry
 	for _, log := range m.logs {
-		// Apply service filter
-		if query.Service != "" && log.Service != query.Service {
-			continue
-		}
-
-		// Apply level filter
-		if query.Level != "" && string(log.Level) != query.Level {
-			continue
-		}
-
-		// Apply trace ID filter
-		if query.TraceID != "" && log.TraceID != query.TraceID {
-			continue
-		}
-
-		// Apply time range filters
-		if !query.Since.IsZero() && log.Timestamp.Before(query.Since) {
-			continue
-		}
-		if !query.Until.IsZero() && log.Timestamp.After(query.Until) {
-			continue
-		}
-
-		// Apply search filter (simple contains check)
-		if query.Search != "" {
-			if !strings.Contains(log.Message, query.Search) && !strings.Contains(log.Service, query.Search) {
-				continue
-			}
+		if mockLogMatches(log, query) {
+			filteredLogs = append(filteredLogs, log)
 		}
+	}
 
-		filteredLogs = append(filteredLogs, log)
+	// Order logs the way the in-memory storage does when asked to
+	if query.OrderBy != "" {
+		sort.SliceStable(filteredLogs, logOrder(filteredLogs, query))
 	}
 
 	// Convert to map format
@@ -271,28 +333,37 @@ func (m *MockStorage) QueryLogs(query *models.QueryParams) ([]map[string]interfa
 		result = append(result, logMap)
 	}
 
-	// Apply limit
-	if query.Limit > 0 && len(result) > query.Limit {
-		result = result[:query.Limit]
-	}
-
-	return result, nil
+	return map[string]interface{}{
+		"logs":       pageResults(result, query),
+		"pagination": paginationInfo(len(result), query),
+	}, nil
 }
 
-// ClearAll clears all stored data
-func (m *MockStorage) ClearAll() {
+// ClearAll clears all stored data and returns the number of items removed
+func (m *MockStorage) ClearAll() (int64, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.closed {
+		return 0, ErrStorageClosed
+	}
+
+	deleted := int64(len(m.logs) + len(m.metrics) + len(m.histograms) + len(m.spans) + len(m.traces))
+
 	m.logs = make([]*models.LogEntry, 0)
 	m.metrics = make([]*models.Metric, 0)
 	m.histograms = make([]*models.HistogramMetric, 0)
 	m.spans = make([]*models.Span, 0)
 	m.traces = make([]*models.Trace, 0)
+	m.traceDurations = make(map[string]int64)
+	m.traceStatuses = make(map[string]models.SpanStatus)
+
+	return deleted, nil
 }
 
-// QueryMetrics queries metrics from storage
-func (m *MockStorage) QueryMetrics(query *models.QueryParams) ([]map[string]interface{}, error) {
+// GetStats counts the logs matching query by level, the metrics by type and
+// the traces, averaging their durations
+func (m *MockStorage) GetStats(query *models.QueryParams) (*models.Stats, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -300,7 +371,36 @@ func (m *MockStorage) QueryMetrics(query *models.QueryParams) ([]map[string]inte
 		return nil, ErrStorageClosed
 	}
 
-	// Filter metrics based on query parameters
+	stats := models.NewStats()
+	query = statsQuery(query)
+
+	for _, log := range m.logs {
+		if mockLogMatches(log, query) {
+			addLogCount(stats, string(log.Level), 1)
+		}
+	}
+
+	for _, metric := range m.matchingMetrics(query) {
+		stats.Metrics.Total++
+		stats.Metrics.ByType[metric.Type]++
+	}
+
+	traces := m.matchingTraces(query)
+	var totalDuration int64
+	for _, trace := range traces {
+		totalDuration += trace["duration_ms"].(int64)
+	}
+	stats.Traces.Total = int64(len(traces))
+	if len(traces) > 0 {
+		stats.Traces.AvgDurationMs = float64(totalDuration) / float64(len(traces))
+	}
+
+	return stats, nil
+}
+
+// matchingMetrics returns the metrics matching query. The caller must hold
+// the read lock.
+func (m *MockStorage) matchingMetrics(query *models.QueryParams) []*models.Metric {
 	var filteredMetrics []*models.Metric
 	for _, metric := range m.metrics {
 		// Apply service filter
@@ -308,6 +408,11 @@ func (m *MockStorage) QueryMetrics(query *models.QueryParams) ([]map[string]inte
 			continue
 		}
 
+		// Apply name filter
+		if query.Name != "" && metric.Name != query.Name {
+			continue
+		}
+
 		// Apply time range filters
 		if !query.Since.IsZero() && metric.Timestamp.Before(query.Since) {
 			continue
@@ -323,9 +428,28 @@ func (m *MockStorage) QueryMetrics(query *models.QueryParams) ([]map[string]inte
 			}
 		}
 
+		// Apply tag key filters
+		if !hasTagKeys(metric.Tags, query) {
+			continue
+		}
+
 		filteredMetrics = append(filteredMetrics, metric)
 	}
 
+	return filteredMetrics
+}
+
+// QueryMetrics queries metrics from storage
+func (m *MockStorage) QueryMetrics(query *models.QueryParams) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrStorageClosed
+	}
+
+	filteredMetrics := m.matchingMetrics(query)
+
 	// Convert to map format
 	result := make([]map[string]interface{}, 0, len(filteredMetrics))
 	for _, metric := range filteredMetrics {
@@ -346,16 +470,179 @@ func (m *MockStorage) QueryMetrics(query *models.QueryParams) ([]map[string]inte
 		result = append(result, metricMap)
 	}
 
-	// Apply limit
-	if query.Limit > 0 && len(result) > query.Limit {
-		result = result[:query.Limit]
+	return map[string]interface{}{
+		"metrics":    pageResults(result, query),
+		"pagination": paginationInfo(len(result), query),
+	}, nil
+}
+
+// StreamMetrics calls fn for each metric matching the query, oldest first
+func (m *MockStorage) StreamMetrics(query *models.QueryParams, fn func(metric *models.Metric) error) error {
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrStorageClosed
 	}
 
-	return result, nil
+	var matched []*models.Metric
+	for _, metric := range m.metrics {
+		if query.Service != "" && metric.Service != query.Service {
+			continue
+		}
+		if query.Name != "" && metric.Name != query.Name {
+			continue
+		}
+		if !query.Since.IsZero() && metric.Timestamp.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && metric.Timestamp.After(query.Until) {
+			continue
+		}
+		matched = append(matched, metric)
+	}
+	m.mu.RUnlock()
+
+	// Sort by timestamp (oldest first)
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Timestamp.Before(matched[j].Timestamp)
+	})
+
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+
+	for _, metric := range matched {
+		if err := fn(metric); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StreamLogs calls fn for each log matching the query, oldest first
+func (m *MockStorage) StreamLogs(query *models.QueryParams, fn func(log *models.LogEntry) error) error {
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrStorageClosed
+	}
+
+	var matched []*models.LogEntry
+	for _, log := range m.logs {
+		if mockLogMatches(log, query) {
+			matched = append(matched, log)
+		}
+	}
+	m.mu.RUnlock()
+
+	// Sort by timestamp (oldest first)
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Timestamp.Before(matched[j].Timestamp)
+	})
+
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+
+	for _, log := range matched {
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StreamSpans calls fn for each span matching the query, oldest first
+func (m *MockStorage) StreamSpans(query *models.QueryParams, fn func(span *models.Span) error) error {
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrStorageClosed
+	}
+
+	var matched []*models.Span
+	for _, span := range m.spans {
+		if query.Service != "" && span.Service != query.Service {
+			continue
+		}
+		if !query.Since.IsZero() && span.StartTime.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && span.StartTime.After(query.Until) {
+			continue
+		}
+		if query.TraceID != "" && span.TraceID != query.TraceID {
+			continue
+		}
+		if query.SpanID != "" && span.ID != query.SpanID {
+			continue
+		}
+		if !InDurationRange(span.Duration, query) {
+			continue
+		}
+		matched = append(matched, span)
+	}
+	m.mu.RUnlock()
+
+	// Sort by start time (oldest first)
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].StartTime.Before(matched[j].StartTime)
+	})
+
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+
+	for _, span := range matched {
+		if err := fn(span); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QueryHistograms returns the saved histogram metrics matching the query, oldest first
+func (m *MockStorage) QueryHistograms(query *models.QueryParams) ([]*models.HistogramMetric, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrStorageClosed
+	}
+
+	var matched []*models.HistogramMetric
+	for _, histogram := range m.histograms {
+		if query.Service != "" && histogram.Service != query.Service {
+			continue
+		}
+		if query.Name != "" && histogram.Name != query.Name {
+			continue
+		}
+		if !query.Since.IsZero() && histogram.Timestamp.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && histogram.Timestamp.After(query.Until) {
+			continue
+		}
+		matched = append(matched, histogram)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Timestamp.Before(matched[j].Timestamp)
+	})
+
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+
+	return matched, nil
 }
 
 // QueryTraces queries traces from storage
-func (m *MockStorage) QueryTraces(query *models.QueryParams) ([]map[string]interface{}, error) {
+func (m *MockStorage) QueryTraces(query *models.QueryParams) (map[string]interface{}, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -363,12 +650,147 @@ func (m *MockStorage) QueryTraces(query *models.QueryParams) ([]map[string]inter
 		return nil, ErrStorageClosed
 	}
 
+	result := m.matchingTraces(query)
+	if query.OrderBy == "duration" {
+		sort.SliceStable(result, func(i, j int) bool {
+			if query.OrderDesc {
+				return result[i]["duration_ms"].(int64) > result[j]["duration_ms"].(int64)
+			}
+			return result[i]["duration_ms"].(int64) < result[j]["duration_ms"].(int64)
+		})
+	}
+	return map[string]interface{}{
+		"traces":     pageResults(result, query),
+		"pagination": paginationInfo(len(result), query),
+	}, nil
+}
+
+// QueryTraceIDs returns the ID and root span start time of each trace
+// matching the service and time range of query, newest first
+func (m *MockStorage) QueryTraceIDs(query *models.QueryParams) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrStorageClosed
+	}
+
+	roots := m.traceIDRoots(query)
+	result := make([]map[string]interface{}, len(roots))
+	for i, root := range roots {
+		result[i] = traceIDRecord(root.TraceID, root.StartTime)
+	}
+	return map[string]interface{}{
+		"traces":     pageResults(result, query),
+		"pagination": paginationInfo(len(result), query),
+	}, nil
+}
+
+// QueryTraceSamples returns the ID, root span start time, duration and
+// failure of each trace matching the service and time range of query, newest
+// first
+func (m *MockStorage) QueryTraceSamples(query *models.QueryParams) (map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrStorageClosed
+	}
+
+	result := traceSampleRecords(m.traceIDRoots(query), m.spans)
+	return map[string]interface{}{
+		"traces":     pageResults(result, query),
+		"pagination": paginationInfo(len(result), query),
+	}, nil
+}
+
+// traceIDRoots returns the root spans matching the service and time range of
+// query, newest first. The caller must hold the read lock.
+func (m *MockStorage) traceIDRoots(query *models.QueryParams) []*models.Span {
+	var roots []*models.Span
+	for _, span := range m.spans {
+		if span.ParentID != "" {
+			continue
+		}
+		if query.Service != "" && span.Service != query.Service {
+			continue
+		}
+		if !query.Since.IsZero() && span.StartTime.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && span.StartTime.After(query.Until) {
+			continue
+		}
+		roots = append(roots, span)
+	}
+	sortTraceIDs(roots)
+	return roots
+}
+
+// QuerySlowTraces returns the traces matching query whose duration is above
+// the given percentile of their durations, slowest first
+func (m *MockStorage) QuerySlowTraces(query *models.QueryParams, percentile float64) (map[string]interface{}, error) {
+	if percentile <= 0 || percentile >= 100 {
+		return nil, fmt.Errorf("percentile must be between 0 and 100, got %v", percentile)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrStorageClosed
+	}
+
+	traces := m.matchingTraces(query)
+	durations := make([]float64, len(traces))
+	for i, trace := range traces {
+		durations[i] = float64(trace["duration_ms"].(int64))
+	}
+	threshold := int64(percentileOf(durations, percentile))
+
+	slow := []map[string]interface{}{}
+	for _, trace := range traces {
+		if trace["duration_ms"].(int64) > threshold {
+			slow = append(slow, trace)
+		}
+	}
+	sort.SliceStable(slow, func(i, j int) bool {
+		return slow[i]["duration_ms"].(int64) > slow[j]["duration_ms"].(int64)
+	})
+
+	return map[string]interface{}{
+		"traces":       pageResults(slow, query),
+		"pagination":   paginationInfo(len(slow), query),
+		"percentile":   percentile,
+		"threshold_ms": threshold,
+	}, nil
+}
+
+// matchingTraces returns the traces matching query, newest first. The
+// caller must hold the read lock.
+func (m *MockStorage) matchingTraces(query *models.QueryParams) []map[string]interface{} {
 	// Group spans by trace ID
 	traceSpans := make(map[string][]*models.Span)
 	rootSpans := make(map[string]*models.Span)
 
+	// Collect traces containing the requested span name
+	var namedTraces map[string]bool
+	if query.ContainsSpan != "" {
+		namedTraces = make(map[string]bool)
+		for _, span := range m.spans {
+			if span.Name == query.ContainsSpan {
+				namedTraces[span.TraceID] = true
+			}
+		}
+	}
+
 	// First, filter and group spans by trace ID
 	for _, span := range m.spans {
+		// Apply span name filter
+		if namedTraces != nil && !namedTraces[span.TraceID] {
+			continue
+		}
+
 		// Apply service filter
 		if query.Service != "" && span.Service != query.Service {
 			continue
@@ -406,13 +828,31 @@ func (m *MockStorage) QueryTraces(query *models.QueryParams) ([]map[string]inter
 	// Convert traces to the expected format
 	result := make([]map[string]interface{}, 0, len(rootSpans))
 	for traceID, rootSpan := range rootSpans {
+		// Whole traces last from their first span start to their last span
+		// end, traces saved span by span as long as their root span
+		duration := m.traceDurations[traceID]
+		if duration <= 0 {
+			duration = rootSpan.Duration
+		}
+
+		// Apply duration filters to the whole trace
+		if !InDurationRange(duration, query) {
+			continue
+		}
+
+		// Whole traces fail if any span failed
+		status := m.traceStatuses[traceID]
+		if status == "" {
+			status = rootSpan.Status
+		}
+
 		traceMap := map[string]interface{}{
 			"id":          traceID,
 			"start_time":  rootSpan.StartTime.Format(time.RFC3339),
 			"service":     rootSpan.Service,
 			"name":        rootSpan.Name,
-			"duration_ms": rootSpan.Duration,
-			"status":      rootSpan.Status,
+			"duration_ms": duration,
+			"status":      status,
 		}
 
 		// Add tags if present
@@ -430,16 +870,55 @@ func (m *MockStorage) QueryTraces(query *models.QueryParams) ([]map[string]inter
 		return timeI.After(timeJ)
 	})
 
-	// Apply limit
-	if query.Limit > 0 && len(result) > query.Limit {
-		result = result[:query.Limit]
+	return result
+}
+
+// GetTrace assembles the saved spans of a trace into the trace, the last
+// saved span winning for spans saved more than once, or returns
+// ErrTraceNotFound if the trace has no spans
+func (m *MockStorage) GetTrace(traceID string) (*models.Trace, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrStorageClosed
 	}
 
-	return result, nil
+	var spans []*models.Span
+	index := make(map[string]int)
+	for _, span := range m.spans {
+		if span.TraceID != traceID {
+			continue
+		}
+		if i, ok := index[span.ID]; ok {
+			spans[i] = span
+			continue
+		}
+		index[span.ID] = len(spans)
+		spans = append(spans, span)
+	}
+	if len(spans) == 0 {
+		return nil, ErrTraceNotFound
+	}
+
+	return models.AssembleTrace(traceID, spans), nil
+}
+
+// GetSpanAncestors returns the ancestors of a span, its parent first and the
+// root last
+func (m *MockStorage) GetSpanAncestors(spanID string) ([]*models.Span, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrStorageClosed
+	}
+
+	return spanAncestors(spanID, traceSpansOf(m.spans, spanID))
 }
 
 // QuerySpans queries spans from storage
-func (m *MockStorage) QuerySpans(query *models.QueryParams) ([]map[string]interface{}, error) {
+func (m *MockStorage) QuerySpans(query *models.QueryParams) (map[string]interface{}, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -468,6 +947,21 @@ func (m *MockStorage) QuerySpans(query *models.QueryParams) ([]map[string]interf
 			continue
 		}
 
+		// Apply span ID filter
+		if query.SpanID != "" && span.ID != query.SpanID {
+			continue
+		}
+
+		// Apply duration filters
+		if !InDurationRange(span.Duration, query) {
+			continue
+		}
+
+		// Apply tag key filters
+		if !hasTagKeys(span.Tags, query) {
+			continue
+		}
+
 		// Apply search filter
 		if query.Search != "" {
 			if !strings.Contains(span.Name, query.Search) && !strings.Contains(span.Service, query.Search) {
@@ -481,26 +975,7 @@ func (m *MockStorage) QuerySpans(query *models.QueryParams) ([]map[string]interf
 	// Convert to map format
 	result := make([]map[string]interface{}, 0, len(filteredSpans))
 	for _, span := range filteredSpans {
-		spanMap := map[string]interface{}{
-			"id":          span.ID,
-			"trace_id":    span.TraceID,
-			"start_time":  span.StartTime.Format(time.RFC3339),
-			"service":     span.Service,
-			"name":        span.Name,
-			"duration_ms": span.Duration,
-			"status":      span.Status,
-		}
-
-		// Add optional fields
-		if span.ParentID != "" {
-			spanMap["parent_id"] = span.ParentID
-		}
-
-		if span.Tags != nil && len(span.Tags) > 0 {
-			spanMap["tags"] = span.Tags
-		}
-
-		result = append(result, spanMap)
+		result = append(result, spanResult(span))
 	}
 
 	// Sort by start time (newest first)
@@ -510,11 +985,60 @@ func (m *MockStorage) QuerySpans(query *models.QueryParams) ([]map[string]interf
 		return timeI.After(timeJ)
 	})
 
-	// Apply limit
-	if query.Limit > 0 && len(result) > query.Limit {
-		result = result[:query.Limit]
+	return map[string]interface{}{
+		"spans":      pageResults(result, query),
+		"pagination": paginationInfo(len(result), query),
+	}, nil
+}
+
+// CountSpanErrorsByType counts ERROR spans grouped by their error.type tag
+func (m *MockStorage) CountSpanErrorsByType(query *models.QueryParams) ([]map[string]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrStorageClosed
 	}
 
+	counts := make(map[string]int)
+	for _, span := range m.spans {
+		if span.Status != models.SpanStatusError {
+			continue
+		}
+		if query.Service != "" && span.Service != query.Service {
+			continue
+		}
+		if !query.Since.IsZero() && span.StartTime.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && span.StartTime.After(query.Until) {
+			continue
+		}
+
+		errorType := span.Tags["error.type"]
+		if errorType == "" {
+			errorType = "unknown"
+		}
+		counts[errorType]++
+	}
+
+	result := make([]map[string]interface{}, 0, len(counts))
+	for errorType, count := range counts {
+		result = append(result, map[string]interface{}{
+			"error_type": errorType,
+			"count":      count,
+		})
+	}
+
+	// Sort by count (highest first), then by type
+	sort.Slice(result, func(i, j int) bool {
+		ci, cj := result[i]["count"].(int), result[j]["count"].(int)
+		if ci != cj {
+			return ci > cj
+		}
+		return result[i]["error_type"].(string) < result[j]["error_type"].(string)
+	})
+
 	return result, nil
 }
 
@@ -563,6 +1087,205 @@ func (m *MockStorage) GetServices() ([]string, error) {
 	return services, nil
 }
 
+// GetActiveServices returns the names of the services with logs, metrics or
+// spans, the most recently active first, at most limit of them
+func (m *MockStorage) GetActiveServices(limit int) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrStorageClosed
+	}
+
+	lastSeen := make(map[string]time.Time)
+	for _, log := range m.logs {
+		seenAt(lastSeen, log.Service, log.Timestamp)
+	}
+	for _, metric := range m.metrics {
+		seenAt(lastSeen, metric.Service, metric.Timestamp)
+	}
+	for _, span := range m.spans {
+		seenAt(lastSeen, span.Service, span.StartTime)
+	}
+	return servicesByActivity(lastSeen, limit), nil
+}
+
+// DeleteByService removes every log, metric, histogram, span and trace of a
+// service. Traces are removed when their root span belongs to the service.
+func (m *MockStorage) DeleteByService(service string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, ErrStorageClosed
+	}
+
+	var deleted int64
+
+	logs := m.logs[:0]
+	for _, log := range m.logs {
+		if log.Service == service {
+			deleted++
+			continue
+		}
+		logs = append(logs, log)
+	}
+	m.logs = logs
+
+	metrics := m.metrics[:0]
+	for _, metric := range m.metrics {
+		if metric.Service == service {
+			deleted++
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	m.metrics = metrics
+
+	histograms := m.histograms[:0]
+	for _, histogram := range m.histograms {
+		if histogram.Service == service {
+			deleted++
+			continue
+		}
+		histograms = append(histograms, histogram)
+	}
+	m.histograms = histograms
+
+	traces := m.traces[:0]
+	for _, trace := range m.traces {
+		if trace.Root != nil && trace.Root.Service == service {
+			delete(m.traceDurations, trace.ID)
+			delete(m.traceStatuses, trace.ID)
+			deleted++
+			continue
+		}
+		traces = append(traces, trace)
+	}
+	m.traces = traces
+
+	spans := m.spans[:0]
+	for _, span := range m.spans {
+		if span.Service == service {
+			deleted++
+			continue
+		}
+		spans = append(spans, span)
+	}
+	m.spans = spans
+
+	return deleted, nil
+}
+
+// hasTags reports whether tags contains every key in filters with the same value
+func hasTags(tags map[string]string, filters map[string]string) bool {
+	for k, v := range filters {
+		if tv, ok := tags[k]; !ok || tv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// mockLogMatches reports whether log matches the filters of query
+func mockLogMatches(log *models.LogEntry, query *models.QueryParams) bool {
+	// Apply service filter
+	if query.Service != "" && log.Service != query.Service {
+		return false
+	}
+
+	// Apply level filter
+	if query.Level != "" && string(log.Level) != query.Level {
+		return false
+	}
+
+	// Apply minimum level filter
+	if query.MinLevel != "" && !log.Level.AtLeast(models.LogLevel(query.MinLevel)) {
+		return false
+	}
+
+	// Apply trace ID filter
+	if query.TraceID != "" && log.TraceID != query.TraceID {
+		return false
+	}
+
+	// Apply time range filters
+	if !query.Since.IsZero() && log.Timestamp.Before(query.Since) {
+		return false
+	}
+	if !query.Until.IsZero() && log.Timestamp.After(query.Until) {
+		return false
+	}
+
+	// Apply search filter (simple contains check)
+	if query.Search != "" {
+		if !strings.Contains(log.Message, query.Search) && !strings.Contains(log.Service, query.Search) {
+			return false
+		}
+	}
+
+	// Apply tag filters
+	if !hasTags(log.Tags, query.Filters) || !hasTagKeys(log.Tags, query) {
+		return false
+	}
+
+	return true
+}
+
+// UpdateLogTags merges tags into the tags of every log matching query,
+// overwriting existing values of the same keys, and returns the number of
+// logs updated
+func (m *MockStorage) UpdateLogTags(query *models.QueryParams, tags map[string]string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, ErrStorageClosed
+	}
+
+	var updated int64
+	for i, log := range m.logs {
+		if !mockLogMatches(log, query) {
+			continue
+		}
+		tagged := *log
+		tagged.Tags = models.MergeTags(log.Tags, tags)
+		m.logs[i] = &tagged
+		updated++
+	}
+	return updated, nil
+}
+
+// hasTagKeys reports whether tags contain every key of the query's HasTags
+// and none of its MissingTags
+func hasTagKeys(tags map[string]string, query *models.QueryParams) bool {
+	for _, key := range query.HasTags {
+		if _, ok := tags[key]; !ok {
+			return false
+		}
+	}
+	for _, key := range query.MissingTags {
+		if _, ok := tags[key]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// pageResults applies the query's offset and limit to results
+func pageResults(results []map[string]interface{}, query *models.QueryParams) []map[string]interface{} {
+	if query.Offset > 0 {
+		if query.Offset >= len(results) {
+			return []map[string]interface{}{}
+		}
+		results = results[query.Offset:]
+	}
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	return results
+}
+
 // Error definitions for mock storage
 var (
 	ErrStorageClosed = errors.New("storage is closed")
@@ -0,0 +1,1570 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+	_ "github.com/lib/pq" // Postgres driver
+)
+
+// PostgresStorage implements the Storage interface using PostgreSQL. Unlike
+// SQLite it can be shared by several ingestion replicas.
+type PostgresStorage struct {
+	db *sql.DB
+
+	maxOpenConns    int           // Maximum open connections in the pool
+	maxIdleConns    int           // Maximum idle connections kept in the pool
+	connMaxLifetime time.Duration // Maximum time a connection is reused
+}
+
+// PostgresOption configures optional PostgresStorage behavior
+type PostgresOption func(*PostgresStorage)
+
+// WithConnectionPool sets the connection pool limits. Zero values keep the defaults.
+func WithConnectionPool(maxOpen, maxIdle int, maxLifetime time.Duration) PostgresOption {
+	return func(s *PostgresStorage) {
+		if maxOpen > 0 {
+			s.maxOpenConns = maxOpen
+		}
+		if maxIdle > 0 {
+			s.maxIdleConns = maxIdle
+		}
+		if maxLifetime > 0 {
+			s.connMaxLifetime = maxLifetime
+		}
+	}
+}
+
+// postgresSchemaLock is the advisory lock key held while creating the schema,
+// so replicas starting together do not race on CREATE TABLE
+const postgresSchemaLock = 0x70756c7365 // "pulse"
+
+// NewPostgresStorage creates a new Postgres storage for the given DSN and initializes tables
+func NewPostgresStorage(dsn string, opts ...PostgresOption) (*PostgresStorage, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Postgres database: %w", err)
+	}
+
+	storage := &PostgresStorage{
+		db:              db,
+		maxOpenConns:    20,
+		maxIdleConns:    10,
+		connMaxLifetime: 30 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(storage)
+	}
+
+	db.SetMaxOpenConns(storage.maxOpenConns)
+	db.SetMaxIdleConns(storage.maxIdleConns)
+	db.SetConnMaxLifetime(storage.connMaxLifetime)
+
+	// Test connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to Postgres database: %w", err)
+	}
+
+	// Initialize database schema
+	if err := storage.initializeSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database schema: %w", err)
+	}
+
+	return storage, nil
+}
+
+// initializeSchema creates the necessary tables and indexes if they don't exist
+func (s *PostgresStorage) initializeSchema() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, postgresSchemaLock); err != nil {
+		return fmt.Errorf("failed to acquire schema lock: %w", err)
+	}
+
+	statements := []struct {
+		name string
+		sql  string
+	}{
+		{"logs table", `
+		CREATE TABLE IF NOT EXISTS logs (
+			id TEXT PRIMARY KEY,
+			timestamp TIMESTAMPTZ NOT NULL,
+			service TEXT NOT NULL,
+			level TEXT NOT NULL,
+			message TEXT NOT NULL,
+			tags JSONB,
+			trace_id TEXT,
+			span_id TEXT,
+			env TEXT,
+			host TEXT,
+			source TEXT,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`},
+		{"metrics table", `
+		CREATE TABLE IF NOT EXISTS metrics (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			value DOUBLE PRECISION NOT NULL,
+			timestamp TIMESTAMPTZ NOT NULL,
+			type TEXT NOT NULL,
+			service TEXT NOT NULL,
+			tags JSONB,
+			trace_id TEXT,
+			env TEXT,
+			host TEXT,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`},
+		{"histogram_metrics table", `
+		CREATE TABLE IF NOT EXISTS histogram_metrics (
+			id TEXT PRIMARY KEY,
+			metric_id TEXT NOT NULL REFERENCES metrics(id),
+			buckets JSONB NOT NULL, -- array of {upper_bound, count}
+			sum DOUBLE PRECISION NOT NULL,
+			count BIGINT NOT NULL,
+			percentiles JSONB, -- object of percentile -> value
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`},
+		{"spans table", `
+		CREATE TABLE IF NOT EXISTS spans (
+			id TEXT PRIMARY KEY,
+			trace_id TEXT NOT NULL,
+			parent_id TEXT,
+			name TEXT NOT NULL,
+			service TEXT NOT NULL,
+			start_time TIMESTAMPTZ NOT NULL,
+			end_time TIMESTAMPTZ,
+			duration BIGINT,
+			status TEXT,
+			tags JSONB,
+			logs JSONB, -- array of {timestamp, fields}
+			links JSONB, -- array of {trace_id, span_id, attributes}
+			env TEXT,
+			host TEXT,
+			is_finished BOOLEAN DEFAULT FALSE,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`},
+		{"spans links column", `
+		ALTER TABLE spans ADD COLUMN IF NOT EXISTS links JSONB`},
+		{"traces table", `
+		CREATE TABLE IF NOT EXISTS traces (
+			id TEXT PRIMARY KEY,
+			root_span_id TEXT NOT NULL REFERENCES spans(id),
+			status TEXT,
+			duration BIGINT,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`},
+		{"traces duration column", `
+		ALTER TABLE traces ADD COLUMN IF NOT EXISTS duration BIGINT`},
+		{"indexes", `
+		CREATE INDEX IF NOT EXISTS idx_logs_timestamp ON logs(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_logs_service ON logs(service);
+		CREATE INDEX IF NOT EXISTS idx_logs_level ON logs(level);
+		CREATE INDEX IF NOT EXISTS idx_logs_trace_id ON logs(trace_id);
+		CREATE INDEX IF NOT EXISTS idx_logs_tags ON logs USING GIN (tags);
+
+		CREATE INDEX IF NOT EXISTS idx_metrics_timestamp ON metrics(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_metrics_name ON metrics(name);
+		CREATE INDEX IF NOT EXISTS idx_metrics_service ON metrics(service);
+		CREATE INDEX IF NOT EXISTS idx_metrics_tags ON metrics USING GIN (tags);
+		CREATE INDEX IF NOT EXISTS idx_histogram_metrics_metric_id ON histogram_metrics(metric_id);
+
+		CREATE INDEX IF NOT EXISTS idx_spans_trace_id ON spans(trace_id);
+		CREATE INDEX IF NOT EXISTS idx_spans_service ON spans(service);
+		CREATE INDEX IF NOT EXISTS idx_spans_start_time ON spans(start_time);
+		CREATE INDEX IF NOT EXISTS idx_spans_tags ON spans USING GIN (tags);
+		`},
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt.sql); err != nil {
+			return fmt.Errorf("failed to create %s: %w", stmt.name, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// rebind converts the ? placeholders used to build queries into Postgres'
+// numbered $n placeholders. Queries must not contain a literal question mark.
+func rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// unmarshalTags decodes a JSONB tag column, treating NULL as no tags
+func unmarshalTags(raw []byte) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var tags map[string]string
+	if err := json.Unmarshal(raw, &tags); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+	}
+	return tags, nil
+}
+
+// Ping checks that the database server is reachable
+func (s *PostgresStorage) Ping() error {
+	return s.db.Ping()
+}
+
+// Close closes the database connection pool
+func (s *PostgresStorage) Close() error {
+	return s.db.Close()
+}
+
+// SaveLog saves a log entry to the database
+func (s *PostgresStorage) SaveLog(log *models.LogEntry) error {
+	tagsJSON, err := canonicalJSON(log.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	// Generate ID if not provided
+	if log.ID == "" {
+		log.ID = fmt.Sprintf("log-%d", time.Now().UnixNano())
+	}
+
+	_, err = s.db.Exec(rebind(insertLogSQL),
+		log.ID, log.Timestamp, log.Service, log.Level, log.Message, string(tagsJSON),
+		log.TraceID, log.SpanID, log.Env, log.Host, log.Source)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert log: %w", err)
+	}
+
+	return nil
+}
+
+// SaveLogs saves a batch of logs in a single transaction through one
+// prepared statement. Either all logs are saved or, on error, none are.
+func (s *PostgresStorage) SaveLogs(logs []*models.LogEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(rebind(insertLogSQL))
+	if err != nil {
+		return fmt.Errorf("failed to prepare log insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, log := range logs {
+		tagsJSON, err := canonicalJSON(log.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		if log.ID == "" {
+			log.ID = fmt.Sprintf("log-%d", time.Now().UnixNano())
+		}
+
+		if _, err := stmt.Exec(log.ID, log.Timestamp, log.Service, log.Level, log.Message, string(tagsJSON),
+			log.TraceID, log.SpanID, log.Env, log.Host, log.Source); err != nil {
+			return fmt.Errorf("failed to insert log %s: %w", log.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// pgLogFilters returns the WHERE clause selecting the logs matching query
+func pgLogFilters(query *models.QueryParams) (string, []interface{}, error) {
+	where := " WHERE 1=1"
+	whereArgs := []interface{}{}
+
+	if query.Service != "" {
+		where += " AND service = ?"
+		whereArgs = append(whereArgs, query.Service)
+	}
+
+	if query.Level != "" {
+		where += " AND level = ?"
+		whereArgs = append(whereArgs, query.Level)
+	}
+
+	levelClause, levelArgs := minLevelClause(query.MinLevel)
+	where += levelClause
+	whereArgs = append(whereArgs, levelArgs...)
+
+	if !query.Since.IsZero() {
+		where += " AND timestamp >= ?"
+		whereArgs = append(whereArgs, query.Since)
+	}
+
+	if !query.Until.IsZero() {
+		where += " AND timestamp <= ?"
+		whereArgs = append(whereArgs, query.Until)
+	}
+
+	if query.TraceID != "" {
+		where += " AND trace_id = ?"
+		whereArgs = append(whereArgs, query.TraceID)
+	}
+
+	if query.Search != "" {
+		where += " AND (message ILIKE ? OR service ILIKE ?)"
+		searchTerm := "%" + query.Search + "%"
+		whereArgs = append(whereArgs, searchTerm, searchTerm)
+	}
+
+	// Tag filters use containment so the GIN index applies
+	if len(query.Filters) > 0 {
+		filtersJSON, err := canonicalJSON(query.Filters)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to marshal tag filters: %w", err)
+		}
+		where += " AND tags @> ?::jsonb"
+		whereArgs = append(whereArgs, string(filtersJSON))
+	}
+
+	presence, presenceArgs := pgTagPresenceClause(query)
+	where += presence
+	whereArgs = append(whereArgs, presenceArgs...)
+
+	return where, whereArgs, nil
+}
+
+// StreamLogs calls fn for each log matching the query, oldest first.
+// A zero Limit streams every matching row.
+func (s *PostgresStorage) StreamLogs(query *models.QueryParams, fn func(log *models.LogEntry) error) error {
+	where, args, err := pgLogFilters(query)
+	if err != nil {
+		return err
+	}
+	sqlQuery := "SELECT " + logColumns + " FROM logs" + where + " ORDER BY timestamp ASC, id ASC"
+
+	if query.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.Query(rebind(sqlQuery), args...)
+	if err != nil {
+		return fmt.Errorf("failed to query logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		log, err := scanLog(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating log rows: %w", err)
+	}
+	return nil
+}
+
+// QueryLogs queries logs from the database based on the given parameters
+func (s *PostgresStorage) QueryLogs(query *models.QueryParams) (map[string]interface{}, error) {
+	where, whereArgs, err := pgLogFilters(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalItems int
+	if err := s.db.QueryRow(rebind("SELECT COUNT(*) FROM logs"+where), whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count logs: %w", err)
+	}
+
+	// Continue after the cursor, if any
+	cursorClause, cursorArgs, err := logCursorClause(query)
+	if err != nil {
+		return nil, err
+	}
+	sqlQuery := `
+		SELECT id, timestamp, service, level, message, tags, trace_id, span_id, env, host, source
+		FROM logs` + where + cursorClause
+	args := append(whereArgs, cursorArgs...)
+
+	sqlQuery += logOrderClause(query)
+
+	page, pageArgs := pageClause(logPageQuery(query))
+	sqlQuery += page
+	args = append(args, pageArgs...)
+
+	rows, err := s.db.Query(rebind(sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []map[string]interface{}{}
+	var last models.LogCursor
+	for rows.Next() {
+		var (
+			id        string
+			timestamp time.Time
+			service   string
+			level     string
+			message   string
+			tagsJSON  []byte
+			traceID   sql.NullString
+			spanID    sql.NullString
+			env       sql.NullString
+			host      sql.NullString
+			source    sql.NullString
+		)
+
+		if err := rows.Scan(&id, &timestamp, &service, &level, &message, &tagsJSON, &traceID, &spanID, &env, &host, &source); err != nil {
+			return nil, fmt.Errorf("failed to scan log row: %w", err)
+		}
+
+		tags, err := unmarshalTags(tagsJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		logMap := map[string]interface{}{
+			"id":        id,
+			"timestamp": timestamp.Format(time.RFC3339),
+			"service":   service,
+			"level":     level,
+			"message":   message,
+		}
+
+		if len(tags) > 0 {
+			logMap["tags"] = tags
+		}
+		if traceID.Valid {
+			logMap["trace_id"] = traceID.String
+		}
+		if spanID.Valid {
+			logMap["span_id"] = spanID.String
+		}
+		if env.Valid {
+			logMap["env"] = env.String
+		}
+		if host.Valid {
+			logMap["host"] = host.String
+		}
+		if source.Valid {
+			logMap["source"] = source.String
+		}
+
+		logs = append(logs, logMap)
+		last = models.LogCursor{Timestamp: timestamp, ID: id}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating log rows: %w", err)
+	}
+
+	pagination := paginationInfo(totalItems, query)
+	setNextLogCursor(pagination, query, len(logs), last)
+	return map[string]interface{}{
+		"logs":       logs,
+		"pagination": pagination,
+	}, nil
+}
+
+// SaveMetric saves a metric to the database
+func (s *PostgresStorage) SaveMetric(metric *models.Metric) error {
+	return s.insertMetric(s.db, metric)
+}
+
+// SaveMetrics saves a batch of metrics in a single transaction
+func (s *PostgresStorage) SaveMetrics(metrics []*models.Metric) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, metric := range metrics {
+		if err := s.insertMetric(tx, metric); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// SaveHistogramMetric saves a histogram metric together with its bucket data
+func (s *PostgresStorage) SaveHistogramMetric(histogram *models.HistogramMetric) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.insertMetric(tx, &histogram.Metric); err != nil {
+		return err
+	}
+
+	bucketsJSON, err := json.Marshal(histogram.Buckets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal buckets: %w", err)
+	}
+
+	percentilesJSON, err := json.Marshal(histogram.Percentile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal percentiles: %w", err)
+	}
+
+	_, err = tx.Exec(rebind(`
+		INSERT INTO histogram_metrics (id, metric_id, buckets, sum, count, percentiles)
+		VALUES (?, ?, ?, ?, ?, ?)`),
+		"hist-"+histogram.ID, histogram.ID, string(bucketsJSON),
+		histogram.Sum, histogram.Count, string(percentilesJSON))
+
+	if err != nil {
+		return fmt.Errorf("failed to insert histogram data: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// QueryHistograms returns the histogram metrics matching the query, oldest
+// first, with their buckets, sum, count and percentiles
+func (s *PostgresStorage) QueryHistograms(query *models.QueryParams) ([]*models.HistogramMetric, error) {
+	sqlQuery := `
+		SELECT m.id, m.name, m.value, m.timestamp, m.type, m.service, m.tags, m.trace_id, m.env, m.host,
+			h.buckets, h.sum, h.count, h.percentiles
+		FROM metrics m
+		JOIN histogram_metrics h ON h.metric_id = m.id
+		WHERE 1=1`
+
+	args := []interface{}{}
+
+	if query.Service != "" {
+		sqlQuery += " AND m.service = ?"
+		args = append(args, query.Service)
+	}
+
+	if query.Name != "" {
+		sqlQuery += " AND m.name = ?"
+		args = append(args, query.Name)
+	}
+
+	if !query.Since.IsZero() {
+		sqlQuery += " AND m.timestamp >= ?"
+		args = append(args, query.Since)
+	}
+
+	if !query.Until.IsZero() {
+		sqlQuery += " AND m.timestamp <= ?"
+		args = append(args, query.Until)
+	}
+
+	sqlQuery += " ORDER BY m.timestamp ASC"
+
+	if query.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.Query(rebind(sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query histograms: %w", err)
+	}
+	defer rows.Close()
+
+	var histograms []*models.HistogramMetric
+	for rows.Next() {
+		var (
+			histogram       models.HistogramMetric
+			metricType      string
+			tagsJSON        []byte
+			traceID         sql.NullString
+			env             sql.NullString
+			host            sql.NullString
+			bucketsJSON     []byte
+			percentilesJSON []byte
+		)
+
+		if err := rows.Scan(&histogram.ID, &histogram.Name, &histogram.Value, &histogram.Timestamp, &metricType,
+			&histogram.Service, &tagsJSON, &traceID, &env, &host,
+			&bucketsJSON, &histogram.Sum, &histogram.Count, &percentilesJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan histogram row: %w", err)
+		}
+
+		if err := unmarshalHistogramData(&histogram, string(tagsJSON), string(bucketsJSON), string(percentilesJSON)); err != nil {
+			return nil, err
+		}
+
+		histogram.Type = models.MetricType(metricType)
+		histogram.TraceID = traceID.String
+		histogram.Env = env.String
+		histogram.Host = host.String
+
+		histograms = append(histograms, &histogram)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating histogram rows: %w", err)
+	}
+
+	return histograms, nil
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertMetric inserts a row into the metrics table, generating an ID if needed
+func (s *PostgresStorage) insertMetric(db execer, metric *models.Metric) error {
+	tagsJSON, err := canonicalJSON(metric.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	// Generate ID if not provided
+	if metric.ID == "" {
+		metric.ID = fmt.Sprintf("metric-%d", time.Now().UnixNano())
+	}
+
+	_, err = db.Exec(rebind(`
+		INSERT INTO metrics (id, name, value, timestamp, type, service, tags, trace_id, env, host)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		metric.ID, metric.Name, metric.Value, metric.Timestamp, metric.Type, metric.Service,
+		string(tagsJSON), metric.TraceID, metric.Env, metric.Host)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert metric: %w", err)
+	}
+
+	return nil
+}
+
+// metricFilters returns the WHERE clause shared by metric queries
+func metricFilters(query *models.QueryParams) (string, []interface{}) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+
+	if query.Service != "" {
+		where += " AND service = ?"
+		args = append(args, query.Service)
+	}
+
+	if query.Name != "" {
+		where += " AND name = ?"
+		args = append(args, query.Name)
+	}
+
+	if !query.Since.IsZero() {
+		where += " AND timestamp >= ?"
+		args = append(args, query.Since)
+	}
+
+	if !query.Until.IsZero() {
+		where += " AND timestamp <= ?"
+		args = append(args, query.Until)
+	}
+
+	presence, presenceArgs := pgTagPresenceClause(query)
+	where += presence
+	args = append(args, presenceArgs...)
+
+	return where, args
+}
+
+// UpdateLogTags merges tags into the tags of every log matching query,
+// overwriting existing values of the same keys, and returns the number of
+// logs updated. New keys past the models.SetMaxTags limit are ignored.
+func (s *PostgresStorage) UpdateLogTags(query *models.QueryParams, tags map[string]string) (int64, error) {
+	where, whereArgs, err := pgLogFilters(query)
+	if err != nil {
+		return 0, err
+	}
+	if models.MaxTags() > 0 {
+		return updateLimitedTags(s.db, rebind("SELECT id, tags FROM logs"+where+" FOR UPDATE"), rebind("UPDATE logs SET tags = ?::jsonb WHERE id = ?"), whereArgs, tags)
+	}
+
+	tagsJSON, err := canonicalJSON(tags)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal tags: %w", err)
+	}
+	args := append([]interface{}{string(tagsJSON)}, whereArgs...)
+
+	res, err := s.db.Exec(rebind("UPDATE logs SET tags = COALESCE(tags, '{}'::jsonb) || ?::jsonb"+where), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update log tags: %w", err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count updated logs: %w", err)
+	}
+	return updated, nil
+}
+
+// pgTagPresenceClause returns a condition requiring the query's HasTags keys
+// to be present in the tags and its MissingTags keys to be absent, with its
+// arguments. jsonb_exists stands in for the ? operator, which rebind would
+// take for a placeholder.
+func pgTagPresenceClause(query *models.QueryParams) (string, []interface{}) {
+	clause := ""
+	args := make([]interface{}, 0, len(query.HasTags)+len(query.MissingTags))
+
+	for _, key := range query.HasTags {
+		clause += " AND jsonb_exists(tags, ?)"
+		args = append(args, key)
+	}
+	for _, key := range query.MissingTags {
+		clause += " AND NOT COALESCE(jsonb_exists(tags, ?), FALSE)"
+		args = append(args, key)
+	}
+
+	return clause, args
+}
+
+// metricSearchFilters returns the metricFilters of query, narrowed to the
+// metrics whose name or service contains its search term
+func metricSearchFilters(query *models.QueryParams) (string, []interface{}) {
+	where, whereArgs := metricFilters(query)
+
+	if query.Search != "" {
+		where += " AND (name ILIKE ? OR service ILIKE ?)"
+		searchTerm := "%" + query.Search + "%"
+		whereArgs = append(whereArgs, searchTerm, searchTerm)
+	}
+
+	return where, whereArgs
+}
+
+// QueryMetrics queries metrics from storage
+func (s *PostgresStorage) QueryMetrics(query *models.QueryParams) (map[string]interface{}, error) {
+	where, whereArgs := metricSearchFilters(query)
+
+	var totalItems int
+	if err := s.db.QueryRow(rebind("SELECT COUNT(*) FROM metrics"+where), whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count metrics: %w", err)
+	}
+
+	sqlQuery := `
+		SELECT id, timestamp, service, name, value, type, tags
+		FROM metrics` + where + " ORDER BY timestamp DESC"
+
+	page, pageArgs := pageClause(query)
+	sqlQuery += page
+	args := append(whereArgs, pageArgs...)
+
+	rows, err := s.db.Query(rebind(sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	metrics := []map[string]interface{}{}
+	for rows.Next() {
+		var (
+			id         string
+			timestamp  time.Time
+			service    string
+			name       string
+			value      float64
+			metricType string
+			tagsJSON   []byte
+		)
+
+		if err := rows.Scan(&id, &timestamp, &service, &name, &value, &metricType, &tagsJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan metric row: %w", err)
+		}
+
+		tags, err := unmarshalTags(tagsJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		metricMap := map[string]interface{}{
+			"id":        id,
+			"timestamp": timestamp.Format(time.RFC3339),
+			"service":   service,
+			"name":      name,
+			"value":     value,
+			"type":      metricType,
+		}
+
+		if len(tags) > 0 {
+			metricMap["tags"] = tags
+		}
+
+		metrics = append(metrics, metricMap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating metric rows: %w", err)
+	}
+
+	return map[string]interface{}{
+		"metrics":    metrics,
+		"pagination": paginationInfo(totalItems, query),
+	}, nil
+}
+
+// StreamMetrics calls fn for each metric matching the query, oldest first.
+// A zero Limit streams every matching row.
+func (s *PostgresStorage) StreamMetrics(query *models.QueryParams, fn func(metric *models.Metric) error) error {
+	where, args := metricFilters(query)
+
+	sqlQuery := `
+		SELECT id, name, value, timestamp, type, service, tags, trace_id, env, host
+		FROM metrics` + where + " ORDER BY timestamp ASC"
+
+	if query.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.Query(rebind(sqlQuery), args...)
+	if err != nil {
+		return fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			metric     models.Metric
+			metricType string
+			tagsJSON   []byte
+			traceID    sql.NullString
+			env        sql.NullString
+			host       sql.NullString
+		)
+
+		if err := rows.Scan(&metric.ID, &metric.Name, &metric.Value, &metric.Timestamp, &metricType,
+			&metric.Service, &tagsJSON, &traceID, &env, &host); err != nil {
+			return fmt.Errorf("failed to scan metric row: %w", err)
+		}
+
+		tags, err := unmarshalTags(tagsJSON)
+		if err != nil {
+			return err
+		}
+
+		metric.Tags = tags
+		metric.Type = models.MetricType(metricType)
+		metric.TraceID = traceID.String
+		metric.Env = env.String
+		metric.Host = host.String
+
+		if err := fn(&metric); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating metric rows: %w", err)
+	}
+
+	return nil
+}
+
+// upsertSpanSQL inserts a span, replacing any earlier version with the same ID
+const upsertSpanSQL = `
+	INSERT INTO spans (
+		id, trace_id, parent_id, name, service, start_time, end_time,
+		duration, status, tags, logs, links, env, host, is_finished
+	)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (id) DO UPDATE SET
+		trace_id = EXCLUDED.trace_id, parent_id = EXCLUDED.parent_id, name = EXCLUDED.name,
+		service = EXCLUDED.service, start_time = EXCLUDED.start_time, end_time = EXCLUDED.end_time,
+		duration = EXCLUDED.duration, status = EXCLUDED.status, tags = EXCLUDED.tags,
+		logs = EXCLUDED.logs, links = EXCLUDED.links, env = EXCLUDED.env, host = EXCLUDED.host, is_finished = EXCLUDED.is_finished`
+
+// upsertSpan writes a single span using db
+func upsertSpan(db execer, span *models.Span) error {
+	tagsJSON, err := canonicalJSON(span.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	logsJSON, err := json.Marshal(span.Logs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal logs: %w", err)
+	}
+
+	linksJSON, err := json.Marshal(span.Links)
+	if err != nil {
+		return fmt.Errorf("failed to marshal links: %w", err)
+	}
+
+	_, err = db.Exec(rebind(upsertSpanSQL),
+		span.ID, span.TraceID, span.ParentID, span.Name, span.Service,
+		span.StartTime, span.EndTime, span.Duration, span.Status,
+		string(tagsJSON), string(logsJSON), string(linksJSON), span.Env, span.Host, span.IsFinished)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert span: %w", err)
+	}
+
+	return nil
+}
+
+// GetTrace loads every span of a trace with its tags, logs and links and
+// assembles them into the trace. It returns ErrTraceNotFound if the trace
+// has no spans.
+func (s *PostgresStorage) GetTrace(traceID string) (*models.Trace, error) {
+	rows, err := s.db.Query(rebind("SELECT "+spanColumns+" FROM spans WHERE trace_id = ?"), traceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace spans: %w", err)
+	}
+	defer rows.Close()
+
+	spans, err := scanSpans(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 {
+		return nil, ErrTraceNotFound
+	}
+
+	return models.AssembleTrace(traceID, spans), nil
+}
+
+// GetSpanAncestors returns the ancestors of a span, its parent first and the
+// root last, reading the spans of its trace in a single query
+func (s *PostgresStorage) GetSpanAncestors(spanID string) ([]*models.Span, error) {
+	rows, err := s.db.Query(rebind("SELECT "+spanColumns+" FROM spans WHERE trace_id IN (SELECT trace_id FROM spans WHERE id = ?)"), spanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace spans: %w", err)
+	}
+	defer rows.Close()
+
+	spans, err := scanSpans(rows)
+	if err != nil {
+		return nil, err
+	}
+	return spanAncestors(spanID, spans)
+}
+
+// SaveSpan saves a span to the database
+func (s *PostgresStorage) SaveSpan(span *models.Span) error {
+	return upsertSpan(s.db, span)
+}
+
+// SaveSpans saves a batch of spans in a single transaction. Either all spans
+// are saved or, on error, none are.
+func (s *PostgresStorage) SaveSpans(spans []*models.Span) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, span := range spans {
+		if err := upsertSpan(tx, span); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// SaveTrace saves a trace and all of its spans in one transaction
+func (s *PostgresStorage) SaveTrace(trace *models.Trace) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, span := range trace.Spans {
+		if err := upsertSpan(tx, span); err != nil {
+			return err
+		}
+	}
+
+	// Merge the trace record with the batches saved before
+	var storedRoot, storedStatus string
+	err = tx.QueryRow(rebind("SELECT root_span_id, COALESCE(status, '') FROM traces WHERE id = ? FOR UPDATE"), trace.ID).Scan(&storedRoot, &storedStatus)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to query trace: %w", err)
+	}
+	rows, err := tx.Query(rebind("SELECT "+spanColumns+" FROM spans WHERE trace_id = ?"), trace.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query trace spans: %w", err)
+	}
+	spans, err := scanSpans(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	if root, status, duration, ok := mergeTrace(trace, storedRoot, models.SpanStatus(storedStatus), spans); ok {
+		_, err = tx.Exec(rebind(`
+			INSERT INTO traces (id, root_span_id, status, duration)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET root_span_id = EXCLUDED.root_span_id, status = EXCLUDED.status, duration = EXCLUDED.duration`),
+			trace.ID, root, status, duration)
+		if err != nil {
+			return fmt.Errorf("failed to insert trace: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// spanFilters returns the conditions shared by span and trace queries
+func spanFilters(query *models.QueryParams) (string, []interface{}) {
+	where := ""
+	args := []interface{}{}
+
+	if query.Service != "" {
+		where += " AND service = ?"
+		args = append(args, query.Service)
+	}
+
+	if !query.Since.IsZero() {
+		where += " AND start_time >= ?"
+		args = append(args, query.Since)
+	}
+
+	if !query.Until.IsZero() {
+		where += " AND start_time <= ?"
+		args = append(args, query.Until)
+	}
+
+	if query.TraceID != "" {
+		where += " AND trace_id = ?"
+		args = append(args, query.TraceID)
+	}
+
+	if query.Search != "" {
+		where += " AND (name ILIKE ? OR service ILIKE ?)"
+		searchTerm := "%" + query.Search + "%"
+		args = append(args, searchTerm, searchTerm)
+	}
+
+	return where, args
+}
+
+// QueryTraces queries traces from the database based on the given parameters.
+// Each trace is represented by its root span; ContainsSpan matches any span of the trace.
+// Traces are newest first unless ordered by duration.
+func (s *PostgresStorage) QueryTraces(query *models.QueryParams) (map[string]interface{}, error) {
+	where, whereArgs := traceRootFilters(query)
+	return s.queryTraces(query, where, whereArgs, traceOrder(query))
+}
+
+// QuerySlowTraces returns the traces matching query whose duration is above
+// the given percentile of their durations, slowest first
+func (s *PostgresStorage) QuerySlowTraces(query *models.QueryParams, percentile float64) (map[string]interface{}, error) {
+	if percentile <= 0 || percentile >= 100 {
+		return nil, fmt.Errorf("percentile must be between 0 and 100, got %v", percentile)
+	}
+
+	where, whereArgs := traceRootFilters(query)
+
+	var count int
+	if err := s.db.QueryRow(rebind("SELECT COUNT(*) FROM spans"+where), whereArgs...).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	var threshold int64
+	if count > 0 {
+		args := append(append([]interface{}{}, whereArgs...), percentileRank(count, percentile))
+		if err := s.db.QueryRow(rebind("SELECT COALESCE("+traceDuration+", 0) FROM spans"+where+" ORDER BY "+traceDuration+" LIMIT 1 OFFSET ?"), args...).Scan(&threshold); err != nil {
+			return nil, fmt.Errorf("failed to compute the duration percentile: %w", err)
+		}
+	}
+
+	result, err := s.queryTraces(query, where+" AND "+traceDuration+" > ?", append(whereArgs, threshold), traceDuration+" DESC")
+	if err != nil {
+		return nil, err
+	}
+	result["percentile"] = percentile
+	result["threshold_ms"] = threshold
+	return result, nil
+}
+
+// traceRootFilters returns the WHERE clause selecting the root span of each
+// trace matching query; ContainsSpan matches any span of the trace
+func traceRootFilters(query *models.QueryParams) (string, []interface{}) {
+	filters, whereArgs := spanFilters(query)
+	where := " WHERE (parent_id IS NULL OR parent_id = '')" + filters
+
+	if query.ContainsSpan != "" {
+		where += " AND EXISTS (SELECT 1 FROM spans AS named WHERE named.trace_id = spans.trace_id AND named.name = ?)"
+		whereArgs = append(whereArgs, query.ContainsSpan)
+	}
+
+	durations, durationArgs := durationClause(traceDuration, query)
+	where += durations
+	whereArgs = append(whereArgs, durationArgs...)
+
+	return where, whereArgs
+}
+
+// queryTraces returns a page of the traces whose root span matches where,
+// in the given order
+func (s *PostgresStorage) queryTraces(query *models.QueryParams, where string, whereArgs []interface{}, order string) (map[string]interface{}, error) {
+	var totalItems int
+	if err := s.db.QueryRow(rebind("SELECT COUNT(DISTINCT trace_id) FROM spans"+where), whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	sqlQuery := `
+		SELECT trace_id, service, name, start_time, ` + traceDuration + `, ` + traceStatus + `, tags
+		FROM spans` + where + " ORDER BY " + order
+
+	page, pageArgs := pageClause(query)
+	sqlQuery += page
+	args := append(whereArgs, pageArgs...)
+
+	rows, err := s.db.Query(rebind(sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query traces: %w", err)
+	}
+	defer rows.Close()
+
+	traces := []map[string]interface{}{}
+	for rows.Next() {
+		var (
+			traceID   string
+			service   string
+			name      string
+			startTime time.Time
+			duration  sql.NullInt64
+			status    sql.NullString
+			tagsJSON  []byte
+		)
+
+		if err := rows.Scan(&traceID, &service, &name, &startTime, &duration, &status, &tagsJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan span row: %w", err)
+		}
+
+		tags, err := unmarshalTags(tagsJSON)
+		if err != nil {
+			return nil, err
+		}
+
+		traceMap := map[string]interface{}{
+			"id":          traceID,
+			"start_time":  startTime.Format(time.RFC3339),
+			"service":     service,
+			"name":        name,
+			"duration_ms": duration.Int64,
+			"status":      status.String,
+		}
+
+		if len(tags) > 0 {
+			traceMap["tags"] = tags
+		}
+
+		traces = append(traces, traceMap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trace rows: %w", err)
+	}
+
+	return map[string]interface{}{
+		"traces":     traces,
+		"pagination": paginationInfo(totalItems, query),
+	}, nil
+}
+
+// QueryTraceIDs returns the ID and root span start time of each trace
+// matching the service and time range of query, newest first with ties
+// broken by descending ID
+func (s *PostgresStorage) QueryTraceIDs(query *models.QueryParams) (map[string]interface{}, error) {
+	where, whereArgs := traceIDFilters(query)
+
+	var totalItems int
+	if err := s.db.QueryRow(rebind("SELECT COUNT(DISTINCT trace_id) FROM spans"+where), whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	page, pageArgs := pageClause(query)
+	rows, err := s.db.Query(rebind("SELECT trace_id, start_time FROM spans"+where+" ORDER BY start_time DESC, trace_id DESC"+page),
+		append(whereArgs, pageArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace IDs: %w", err)
+	}
+	defer rows.Close()
+
+	traces := []map[string]interface{}{}
+	for rows.Next() {
+		var (
+			traceID   string
+			startTime time.Time
+		)
+		if err := rows.Scan(&traceID, &startTime); err != nil {
+			return nil, fmt.Errorf("failed to scan trace ID row: %w", err)
+		}
+		traces = append(traces, traceIDRecord(traceID, startTime))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trace ID rows: %w", err)
+	}
+
+	return map[string]interface{}{
+		"traces":     traces,
+		"pagination": paginationInfo(totalItems, query),
+	}, nil
+}
+
+// pgTraceSampleDuration is the duration of a trace aggregated over its
+// spans, like traceSampleDuration
+const pgTraceSampleDuration = `CAST(ROUND(EXTRACT(EPOCH FROM MAX(CASE WHEN spans.end_time >= spans.start_time
+	THEN spans.end_time ELSE spans.start_time + spans.duration * INTERVAL '1 millisecond' END)
+	- MIN(spans.start_time)) * 1000) AS BIGINT)`
+
+// QueryTraceSamples returns the ID, root span start time, duration and
+// failure of each trace matching the service and time range of query, in the
+// order of QueryTraceIDs
+func (s *PostgresStorage) QueryTraceSamples(query *models.QueryParams) (map[string]interface{}, error) {
+	where, whereArgs := traceIDFilters(query)
+
+	var totalItems int
+	if err := s.db.QueryRow(rebind("SELECT COUNT(DISTINCT trace_id) FROM spans"+where), whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	page, pageArgs := pageClause(query)
+	args := append([]interface{}{models.SpanStatusError}, whereArgs...)
+	rows, err := s.db.Query(rebind(`
+		SELECT roots.trace_id, roots.start_time, `+pgTraceSampleDuration+`,
+			BOOL_OR(spans.status = ?)
+		FROM (SELECT trace_id, start_time FROM spans`+where+` ORDER BY start_time DESC, trace_id DESC`+page+`) AS roots
+		JOIN spans ON spans.trace_id = roots.trace_id
+		GROUP BY roots.trace_id, roots.start_time
+		ORDER BY roots.start_time DESC, roots.trace_id DESC`),
+		append(args, pageArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace samples: %w", err)
+	}
+	defer rows.Close()
+
+	traces := []map[string]interface{}{}
+	for rows.Next() {
+		var (
+			traceID   string
+			startTime time.Time
+			duration  int64
+			failed    bool
+		)
+		if err := rows.Scan(&traceID, &startTime, &duration, &failed); err != nil {
+			return nil, fmt.Errorf("failed to scan trace sample row: %w", err)
+		}
+		traces = append(traces, traceSampleRecord(traceID, startTime, duration, failed))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trace sample rows: %w", err)
+	}
+
+	return map[string]interface{}{
+		"traces":     traces,
+		"pagination": paginationInfo(totalItems, query),
+	}, nil
+}
+
+// QuerySpans queries spans from the database based on the given parameters
+func (s *PostgresStorage) QuerySpans(query *models.QueryParams) (map[string]interface{}, error) {
+	where, whereArgs := pgSpanQueryFilters(query)
+
+	var totalItems int
+	if err := s.db.QueryRow(rebind("SELECT COUNT(*) FROM spans"+where), whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count spans: %w", err)
+	}
+
+	sqlQuery := "SELECT " + spanColumns + " FROM spans" + where + " ORDER BY start_time DESC"
+
+	page, pageArgs := pageClause(query)
+	sqlQuery += page
+	args := append(whereArgs, pageArgs...)
+
+	rows, err := s.db.Query(rebind(sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spans: %w", err)
+	}
+	defer rows.Close()
+
+	matched, err := scanSpans(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	spans := make([]map[string]interface{}, 0, len(matched))
+	for _, span := range matched {
+		spans = append(spans, spanResult(span))
+	}
+
+	return map[string]interface{}{
+		"spans":      spans,
+		"pagination": paginationInfo(totalItems, query),
+	}, nil
+}
+
+// StreamSpans calls fn for each span matching the query, oldest first.
+// A zero Limit streams every matching row.
+func (s *PostgresStorage) StreamSpans(query *models.QueryParams, fn func(span *models.Span) error) error {
+	where, args := pgSpanQueryFilters(query)
+	sqlQuery := "SELECT " + spanColumns + " FROM spans" + where + " ORDER BY start_time ASC, id ASC"
+
+	if query.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.Query(rebind(sqlQuery), args...)
+	if err != nil {
+		return fmt.Errorf("failed to query spans: %w", err)
+	}
+	return streamSpans(rows, fn)
+}
+
+// pgSpanQueryFilters returns the WHERE clause selecting the spans matching
+// query
+func pgSpanQueryFilters(query *models.QueryParams) (string, []interface{}) {
+	filters, whereArgs := spanFilters(query)
+	where := " WHERE 1=1" + filters
+
+	if query.SpanID != "" {
+		where += " AND id = ?"
+		whereArgs = append(whereArgs, query.SpanID)
+	}
+
+	durations, durationArgs := durationClause("duration", query)
+	where += durations
+	whereArgs = append(whereArgs, durationArgs...)
+
+	presence, presenceArgs := pgTagPresenceClause(query)
+	where += presence
+	whereArgs = append(whereArgs, presenceArgs...)
+
+	return where, whereArgs
+}
+
+// CountSpanErrorsByType counts ERROR spans grouped by their error.type tag.
+// Spans without the tag are reported under "unknown".
+func (s *PostgresStorage) CountSpanErrorsByType(query *models.QueryParams) ([]map[string]interface{}, error) {
+	sqlQuery := `
+		SELECT COALESCE(tags->>'error.type', 'unknown') AS error_type, COUNT(*) AS count
+		FROM spans
+		WHERE status = ?`
+
+	args := []interface{}{models.SpanStatusError}
+
+	if query.Service != "" {
+		sqlQuery += " AND service = ?"
+		args = append(args, query.Service)
+	}
+
+	if !query.Since.IsZero() {
+		sqlQuery += " AND start_time >= ?"
+		args = append(args, query.Since)
+	}
+
+	if !query.Until.IsZero() {
+		sqlQuery += " AND start_time <= ?"
+		args = append(args, query.Until)
+	}
+
+	sqlQuery += " GROUP BY error_type ORDER BY count DESC, error_type ASC"
+
+	rows, err := s.db.Query(rebind(sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count span errors: %w", err)
+	}
+	defer rows.Close()
+
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		var (
+			errorType string
+			count     int
+		)
+		if err := rows.Scan(&errorType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan error count row: %w", err)
+		}
+		results = append(results, map[string]interface{}{
+			"error_type": errorType,
+			"count":      count,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating error count rows: %w", err)
+	}
+
+	return results, nil
+}
+
+// GetServices returns a list of all unique service names
+func (s *PostgresStorage) GetServices() ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT service FROM logs
+		UNION
+		SELECT service FROM metrics
+		UNION
+		SELECT service FROM spans
+		ORDER BY service`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query services: %w", err)
+	}
+	defer rows.Close()
+
+	services := []string{}
+	for rows.Next() {
+		var service string
+		if err := rows.Scan(&service); err != nil {
+			return nil, fmt.Errorf("failed to scan service row: %w", err)
+		}
+		services = append(services, service)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating service rows: %w", err)
+	}
+
+	return services, nil
+}
+
+// GetActiveServices returns the names of the services with stored logs,
+// metrics or spans, the most recently active first, at most limit of them
+// (all if limit is 0 or less)
+func (s *PostgresStorage) GetActiveServices(limit int) ([]string, error) {
+	var limitArg interface{} // NULL is no limit
+	if limit > 0 {
+		limitArg = limit
+	}
+	rows, err := s.db.Query(`
+		SELECT service FROM (
+			SELECT service, MAX(timestamp) AS last_seen FROM logs GROUP BY service
+			UNION ALL
+			SELECT service, MAX(timestamp) AS last_seen FROM metrics GROUP BY service
+			UNION ALL
+			SELECT service, MAX(start_time) AS last_seen FROM spans GROUP BY service
+		) activity
+		WHERE service != ''
+		GROUP BY service
+		ORDER BY MAX(last_seen) DESC, service
+		LIMIT $1`, limitArg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active services: %w", err)
+	}
+	return scanServices(rows)
+}
+
+// DeleteByService removes every log, metric and span of a service, together
+// with their dependent histogram and trace rows, in a single transaction. It
+// returns the total number of rows deleted across all tables.
+func (s *PostgresStorage) DeleteByService(service string) (int64, error) {
+	return s.deleteAll(deleteByServiceStatements, service)
+}
+
+// ClearAll removes all stored telemetry in a single transaction and returns
+// the total number of rows deleted across all tables
+func (s *PostgresStorage) ClearAll() (int64, error) {
+	return s.deleteAll(clearAllStatements)
+}
+
+// deleteAll runs delete statements with args in one transaction and returns
+// the total number of rows deleted
+func (s *PostgresStorage) deleteAll(statements []deleteStatement, args ...interface{}) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var total int64
+	for _, stmt := range statements {
+		res, err := tx.Exec(rebind(stmt.sql), args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete %s: %w", stmt.what, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count deleted %s: %w", stmt.what, err)
+		}
+		total += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return total, nil
+}
+
+// GetStats counts the logs matching query by level, the metrics by type and
+// the traces, averaging their durations, with one aggregate query each
+func (s *PostgresStorage) GetStats(query *models.QueryParams) (*models.Stats, error) {
+	stats := models.NewStats()
+	query = statsQuery(query)
+
+	where, whereArgs, err := pgLogFilters(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := countGroups(s.db, rebind("SELECT level, COUNT(*) FROM logs"+where+" GROUP BY level"), whereArgs, func(level string, count int64) {
+		addLogCount(stats, level, count)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to count logs: %w", err)
+	}
+
+	where, whereArgs = metricSearchFilters(query)
+	if err := countGroups(s.db, rebind("SELECT type, COUNT(*) FROM metrics"+where+" GROUP BY type"), whereArgs, func(kind string, count int64) {
+		stats.Metrics.Total += count
+		stats.Metrics.ByType[models.MetricType(kind)] += count
+	}); err != nil {
+		return nil, fmt.Errorf("failed to count metrics: %w", err)
+	}
+
+	where, whereArgs = traceRootFilters(query)
+	var avgDuration sql.NullFloat64
+	if err := s.db.QueryRow(rebind("SELECT COUNT(DISTINCT trace_id), AVG("+traceDuration+") FROM spans"+where), whereArgs...).Scan(&stats.Traces.Total, &avgDuration); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+	stats.Traces.AvgDurationMs = avgDuration.Float64
+
+	return stats, nil
+}
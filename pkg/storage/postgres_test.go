@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+func TestRebind(t *testing.T) {
+	got := rebind("SELECT * FROM logs WHERE service = ? AND level = ? LIMIT ?")
+	want := "SELECT * FROM logs WHERE service = $1 AND level = $2 LIMIT $3"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if got := rebind("SELECT 1"); got != "SELECT 1" {
+		t.Errorf("expected query without placeholders to be unchanged, got %q", got)
+	}
+}
+
+// newTestPostgresStorage connects to the database in PULSE_POSTGRES_DSN,
+// skipping the test when it is not set
+func newTestPostgresStorage(t *testing.T) *PostgresStorage {
+	t.Helper()
+
+	dsn := os.Getenv("PULSE_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("PULSE_POSTGRES_DSN not set")
+	}
+
+	st, err := NewPostgresStorage(dsn)
+	if err != nil {
+		t.Fatalf("failed to create Postgres storage: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	// Start from empty tables so results are predictable
+	if _, err := st.db.Exec(`TRUNCATE logs, metrics, histogram_metrics, spans, traces`); err != nil {
+		t.Fatalf("failed to truncate tables: %v", err)
+	}
+
+	return st
+}
+
+func TestPostgresStorage(t *testing.T) {
+	st := newTestPostgresStorage(t)
+	now := time.Now().UTC()
+
+	// Creating the schema again is a no-op
+	if err := st.initializeSchema(); err != nil {
+		t.Fatalf("expected schema creation to be idempotent, got: %v", err)
+	}
+
+	for i, region := range []string{"us-west", "eu", "us-west"} {
+		log := models.NewLogEntry("api", fmt.Sprintf("message %d", i), models.LogLevelInfo)
+		log.Timestamp = now.Add(time.Duration(i) * time.Second)
+		log.AddTag("region", region)
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	result, err := st.QueryLogs(&models.QueryParams{Filters: map[string]string{"region": "us-west"}, Limit: 1})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	logs := result["logs"].([]map[string]interface{})
+	if len(logs) != 1 || logs[0]["message"] != "message 2" {
+		t.Errorf("expected the newest us-west log, got %v", logs)
+	}
+	if total := result["pagination"].(map[string]interface{})["total_items"]; total != 2 {
+		t.Errorf("expected total_items 2, got %v", total)
+	}
+
+	metric := models.NewMetric("cpu", 0.5, models.MetricTypeGauge, "worker")
+	if err := st.SaveMetric(metric); err != nil {
+		t.Fatalf("failed to save metric: %v", err)
+	}
+	histogram, err := models.NewHistogramMetric("latency", "worker", []float64{0.1, 1})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	histogram.Observe(0.05)
+	if err := st.SaveHistogramMetric(histogram); err != nil {
+		t.Fatalf("failed to save histogram: %v", err)
+	}
+
+	result, err = st.QueryMetrics(&models.QueryParams{Service: "worker"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if metrics := result["metrics"].([]map[string]interface{}); len(metrics) != 2 {
+		t.Errorf("expected 2 metrics, got %d", len(metrics))
+	}
+
+	var streamed int
+	if err := st.StreamMetrics(&models.QueryParams{Name: "cpu"}, func(m *models.Metric) error {
+		streamed++
+		return nil
+	}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if streamed != 1 {
+		t.Errorf("expected 1 streamed metric, got %d", streamed)
+	}
+
+	saveTestTrace(t, st, "trace-db", now, "database_query")
+	saveTestTrace(t, st, "trace-cache", now.Add(time.Second), "cache_lookup")
+	saveErrorSpans(t, st, "billing", "timeout", "timeout", "")
+
+	result, err = st.QueryTraces(&models.QueryParams{ContainsSpan: "database_query"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if traces := result["traces"].([]map[string]interface{}); len(traces) != 1 || traces[0]["id"] != "trace-db" {
+		t.Errorf("expected only trace-db, got %v", traces)
+	}
+
+	result, err = st.QuerySpans(&models.QueryParams{TraceID: "trace-cache"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if spans := result["spans"].([]map[string]interface{}); len(spans) != 2 {
+		t.Errorf("expected 2 spans, got %d", len(spans))
+	}
+
+	counts, err := st.CountSpanErrorsByType(&models.QueryParams{Service: "billing"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(counts) != 2 || counts[0]["error_type"] != "timeout" || counts[0]["count"] != 2 {
+		t.Errorf("unexpected error counts: %v", counts)
+	}
+
+	services, err := st.GetServices()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(services) != 3 || services[0] != "api" || services[1] != "billing" || services[2] != "worker" {
+		t.Errorf("expected [api billing worker], got %v", services)
+	}
+}
+
+func TestPostgresStorage_QueryLogs_Cursor(t *testing.T) {
+	assertLogCursorPages(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_DurationFilters(t *testing.T) {
+	assertDurationFilters(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_QueryTraces_Duration(t *testing.T) {
+	assertTraceDuration(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_QueryTraces_Status(t *testing.T) {
+	assertTraceStatus(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_TimeBounds(t *testing.T) {
+	assertTimeBounds(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_TraceIDs(t *testing.T) {
+	assertTraceIDs(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_TraceSamples(t *testing.T) {
+	assertTraceSamples(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_LevelOrder(t *testing.T) {
+	assertLevelOrder(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_TagPresenceFilters(t *testing.T) {
+	assertTagPresenceFilters(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_UpdateLogTags(t *testing.T) {
+	assertUpdateLogTags(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_UpdateLogTagsMaxTags(t *testing.T) {
+	assertUpdateLogTagsMaxTags(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_GetStats(t *testing.T) {
+	assertGetStats(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_GetTrace(t *testing.T) {
+	assertGetTrace(t, newTestPostgresStorage(t))
+}
+
+func TestPostgresStorage_QuerySpansDetails(t *testing.T) {
+	assertQuerySpansDetails(t, newTestPostgresStorage(t))
+}
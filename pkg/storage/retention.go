@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// RetentionPolicy defines how long each signal type is kept.
+// A zero duration disables pruning for that signal.
+type RetentionPolicy struct {
+	Logs    time.Duration // Retention for log entries
+	Metrics time.Duration // Retention for metrics and their histogram data
+	Traces  time.Duration // Retention for spans and traces, by when the trace ended
+}
+
+// IsZero reports whether the policy prunes nothing
+func (p RetentionPolicy) IsZero() bool {
+	return p.Logs <= 0 && p.Metrics <= 0 && p.Traces <= 0
+}
+
+// RetentionResult reports how many rows were removed per signal
+type RetentionResult struct {
+	Logs    int64
+	Metrics int64
+	Traces  int64
+}
+
+// SQLiteOption configures optional behavior of a SQLiteStorage
+type SQLiteOption func(*SQLiteStorage)
+
+// WithRetention makes NewSQLiteStorage start a background job that applies
+// the policy on the given interval. The job stops when the storage is closed.
+func WithRetention(policy RetentionPolicy, interval time.Duration) SQLiteOption {
+	return func(s *SQLiteStorage) {
+		s.retention = policy
+		s.retentionInterval = interval
+	}
+}
+
+// retentionCutoffs holds the per-signal cutoffs of a prune; a zero time
+// leaves that signal untouched
+type retentionCutoffs struct {
+	logs    time.Time
+	metrics time.Time
+	traces  time.Time
+}
+
+// Prune removes every log and metric older than before and every trace that
+// ended before it, together with their dependent histogram and trace rows, in
+// a single transaction. It returns the total number of rows deleted across
+// all tables.
+func (s *SQLiteStorage) Prune(before time.Time) (int64, error) {
+	_, deleted, err := s.prune(retentionCutoffs{logs: before, metrics: before, traces: before})
+	return deleted, err
+}
+
+// DeleteLogsOlderThan removes log entries with a timestamp before cutoff
+func (s *SQLiteStorage) DeleteLogsOlderThan(cutoff time.Time) (int64, error) {
+	result, _, err := s.prune(retentionCutoffs{logs: cutoff})
+	return result.Logs, err
+}
+
+// DeleteMetricsOlderThan removes metrics (and their histogram data) with a
+// timestamp before cutoff
+func (s *SQLiteStorage) DeleteMetricsOlderThan(cutoff time.Time) (int64, error) {
+	result, _, err := s.prune(retentionCutoffs{metrics: cutoff})
+	return result.Metrics, err
+}
+
+// DeleteTracesOlderThan removes the spans and trace records of traces whose
+// last span ended before cutoff
+func (s *SQLiteStorage) DeleteTracesOlderThan(cutoff time.Time) (int64, error) {
+	result, _, err := s.prune(retentionCutoffs{traces: cutoff})
+	return result.Traces, err
+}
+
+// ApplyRetention prunes each signal according to the policy in a single
+// transaction
+func (s *SQLiteStorage) ApplyRetention(policy RetentionPolicy, now time.Time) (RetentionResult, error) {
+	var cutoffs retentionCutoffs
+	if policy.Logs > 0 {
+		cutoffs.logs = now.Add(-policy.Logs)
+	}
+	if policy.Metrics > 0 {
+		cutoffs.metrics = now.Add(-policy.Metrics)
+	}
+	if policy.Traces > 0 {
+		cutoffs.traces = now.Add(-policy.Traces)
+	}
+
+	result, _, err := s.prune(cutoffs)
+	return result, err
+}
+
+// expiredTraces selects the IDs of traces whose last span ended before the
+// cutoff, so that traces are pruned whole. Unfinished spans count by their
+// start time.
+const expiredTraces = `
+	SELECT trace_id FROM spans
+	GROUP BY trace_id
+	HAVING MAX(MAX(start_time, COALESCE(end_time, start_time))) < ?`
+
+// prune deletes rows older than the given cutoffs in one transaction.
+// Dependent rows are removed before the rows they reference so that no
+// histogram data or trace records are left orphaned. It returns the primary
+// row counts per signal and the total number of rows deleted.
+func (s *SQLiteStorage) prune(cutoffs retentionCutoffs) (RetentionResult, int64, error) {
+	var result RetentionResult
+	var total int64
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return result, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// exec runs a delete and adds the affected rows to the total
+	exec := func(what, query string, cutoff time.Time) (int64, error) {
+		res, err := tx.Exec(query, cutoff.UTC())
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete %s: %w", what, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count deleted %s: %w", what, err)
+		}
+		total += n
+		return n, nil
+	}
+
+	if !cutoffs.logs.IsZero() {
+		if result.Logs, err = exec("logs", `DELETE FROM logs WHERE timestamp < ?`, cutoffs.logs); err != nil {
+			return RetentionResult{}, 0, err
+		}
+	}
+
+	if !cutoffs.metrics.IsZero() {
+		// Remove dependent histogram rows first
+		if _, err = exec("histogram data", `
+			DELETE FROM histogram_metrics
+			WHERE metric_id IN (SELECT id FROM metrics WHERE timestamp < ?)`, cutoffs.metrics); err != nil {
+			return RetentionResult{}, 0, err
+		}
+		if _, err = exec("exponential histogram data", `
+			DELETE FROM exponential_histogram_metrics
+			WHERE metric_id IN (SELECT id FROM metrics WHERE timestamp < ?)`, cutoffs.metrics); err != nil {
+			return RetentionResult{}, 0, err
+		}
+		if result.Metrics, err = exec("metrics", `DELETE FROM metrics WHERE timestamp < ?`, cutoffs.metrics); err != nil {
+			return RetentionResult{}, 0, err
+		}
+	}
+
+	if !cutoffs.traces.IsZero() {
+		// Remove trace records first, including any left without spans
+		if _, err = exec("traces", `
+			DELETE FROM traces
+			WHERE id IN (`+expiredTraces+`) OR id NOT IN (SELECT trace_id FROM spans)`, cutoffs.traces); err != nil {
+			return RetentionResult{}, 0, err
+		}
+		if result.Traces, err = exec("spans", `DELETE FROM spans WHERE trace_id IN (`+expiredTraces+`)`, cutoffs.traces); err != nil {
+			return RetentionResult{}, 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RetentionResult{}, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, total, nil
+}
+
+// StartRetention runs ApplyRetention on the given interval until the returned
+// stop function is called. Stop waits for a prune in progress to finish.
+func (s *SQLiteStorage) StartRetention(policy RetentionPolicy, interval time.Duration) (stop func()) {
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				result, err := s.ApplyRetention(policy, time.Now())
+				if err != nil {
+					log.Printf("Error applying retention: %v", err)
+					continue
+				}
+				if result.Logs+result.Metrics+result.Traces > 0 {
+					log.Printf("Retention pruned %d logs, %d metrics, %d spans", result.Logs, result.Metrics, result.Traces)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+		<-done
+	}
+}
@@ -3,7 +3,10 @@ package storage
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/karansingh/pulse/pkg/models"
@@ -13,10 +16,14 @@ import (
 // SQLiteStorage implements the Storage interface using SQLite
 type SQLiteStorage struct {
 	db *sql.DB
+
+	retention         RetentionPolicy // Pruning policy applied in the background
+	retentionInterval time.Duration   // How often the policy is applied
+	stopRetention     func()          // Stops the background retention job
 }
 
 // NewSQLiteStorage creates a new SQLite storage with the given path and initializes tables
-func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
+func NewSQLiteStorage(dbPath string, opts ...SQLiteOption) (*SQLiteStorage, error) {
 	// Open database with WAL mode enabled
 	db, err := sql.Open("sqlite3", dbPath+"?_journal=WAL&_timeout=5000")
 	if err != nil {
@@ -29,12 +36,20 @@ func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
 	}
 
 	storage := &SQLiteStorage{db: db}
+	for _, opt := range opts {
+		opt(storage)
+	}
 
 	// Initialize database schema
 	if err := storage.initializeSchema(); err != nil {
 		return nil, fmt.Errorf("failed to initialize database schema: %w", err)
 	}
 
+	// Start pruning old data if a retention policy is configured
+	if !storage.retention.IsZero() && storage.retentionInterval > 0 {
+		storage.stopRetention = storage.StartRetention(storage.retention, storage.retentionInterval)
+	}
+
 	return storage, nil
 }
 
@@ -95,6 +110,24 @@ func (s *SQLiteStorage) initializeSchema() error {
 		return fmt.Errorf("failed to create histogram_metrics table: %w", err)
 	}
 
+	// Create exponential_histogram_metrics table for exponential histogram data
+	_, err = s.db.Exec(`
+	CREATE TABLE IF NOT EXISTS exponential_histogram_metrics (
+		id TEXT PRIMARY KEY,
+		metric_id TEXT NOT NULL,
+		scale INTEGER NOT NULL,
+		zero_count INTEGER NOT NULL,
+		positive TEXT NOT NULL, -- JSON object of {offset, bucket_counts}
+		negative TEXT NOT NULL, -- JSON object of {offset, bucket_counts}
+		sum REAL NOT NULL,
+		count INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (metric_id) REFERENCES metrics(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create exponential_histogram_metrics table: %w", err)
+	}
+
 	// Create spans table
 	_, err = s.db.Exec(`
 	CREATE TABLE IF NOT EXISTS spans (
@@ -109,6 +142,7 @@ func (s *SQLiteStorage) initializeSchema() error {
 		status TEXT,
 		tags TEXT,
 		logs TEXT, -- JSON array of {timestamp, fields}
+		links TEXT, -- JSON array of {trace_id, span_id, attributes}
 		env TEXT,
 		host TEXT,
 		is_finished BOOLEAN DEFAULT 0,
@@ -118,12 +152,18 @@ func (s *SQLiteStorage) initializeSchema() error {
 		return fmt.Errorf("failed to create spans table: %w", err)
 	}
 
+	// Databases created before span links were added lack the column
+	if err := s.addColumnIfMissing("spans", "links", "TEXT"); err != nil {
+		return err
+	}
+
 	// Create traces table
 	_, err = s.db.Exec(`
 	CREATE TABLE IF NOT EXISTS traces (
 		id TEXT PRIMARY KEY,
 		root_span_id TEXT NOT NULL,
 		status TEXT,
+		duration INTEGER,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (root_span_id) REFERENCES spans(id)
 	)`)
@@ -131,6 +171,11 @@ func (s *SQLiteStorage) initializeSchema() error {
 		return fmt.Errorf("failed to create traces table: %w", err)
 	}
 
+	// Databases created before traces recorded their total duration lack the column
+	if err := s.addColumnIfMissing("traces", "duration", "INTEGER"); err != nil {
+		return err
+	}
+
 	// Create indexes
 	_, err = s.db.Exec(`
 	CREATE INDEX IF NOT EXISTS idx_logs_timestamp ON logs(timestamp);
@@ -153,84 +198,109 @@ func (s *SQLiteStorage) initializeSchema() error {
 	return nil
 }
 
+// addColumnIfMissing adds a column to an existing table unless it is already there
+func (s *SQLiteStorage) addColumnIfMissing(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to read %s columns: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return fmt.Errorf("failed to scan %s column: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating %s columns: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s.%s column: %w", table, column, err)
+	}
+	return nil
+}
+
+// Ping checks that the database connection is usable
+func (s *SQLiteStorage) Ping() error {
+	return s.db.Ping()
+}
+
 // Close closes the database connection
 func (s *SQLiteStorage) Close() error {
+	if s.stopRetention != nil {
+		s.stopRetention()
+		s.stopRetention = nil
+	}
 	return s.db.Close()
 }
 
 // SaveLog saves a log entry to the database
 func (s *SQLiteStorage) SaveLog(log *models.LogEntry) error {
-	// Convert tags to JSON
-	tagsJSON, err := json.Marshal(log.Tags)
-	if err != nil {
-		return fmt.Errorf("failed to marshal tags: %w", err)
-	}
-
-	// Generate ID if not provided
-	if log.ID == "" {
-		log.ID = fmt.Sprintf("log-%d", time.Now().UnixNano())
-	}
+	return s.SaveLogs([]*models.LogEntry{log})
+}
 
-	// Insert into database
-	_, err = s.db.Exec(`
+// insertLogSQL inserts one log, taking the logColumns as arguments
+const insertLogSQL = `
 		INSERT INTO logs (id, timestamp, service, level, message, tags, trace_id, span_id, env, host, source)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		log.ID, log.Timestamp, log.Service, log.Level, log.Message, tagsJSON, log.TraceID, log.SpanID, log.Env, log.Host, log.Source)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
+// SaveLogs saves a batch of logs in a single transaction through one
+// prepared statement. Either all logs are saved or, on error, none are.
+func (s *SQLiteStorage) SaveLogs(logs []*models.LogEntry) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to insert log: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	return nil
-}
-
-// QueryLogs queries logs from the database based on the given parameters
-func (s *SQLiteStorage) QueryLogs(query *models.QueryParams) (map[string]interface{}, error) {
-	// Build the SQL query to count total items
-	countQuery := `
-		SELECT COUNT(*) as total
-		FROM logs
-		WHERE 1=1`
-
-	// Create args slice for parameterized query
-	countArgs := []interface{}{}
-
-	// Add filters for count query
-	if query.Service != "" {
-		countQuery += " AND service = ?"
-		countArgs = append(countArgs, query.Service)
+	stmt, err := tx.Prepare(insertLogSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare log insert: %w", err)
 	}
+	defer stmt.Close()
 
-	if query.Level != "" {
-		countQuery += " AND level = ?"
-		countArgs = append(countArgs, query.Level)
-	}
+	for _, log := range logs {
+		tagsJSON, err := canonicalJSON(log.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		if log.ID == "" {
+			log.ID = fmt.Sprintf("log-%d", time.Now().UnixNano())
+		}
 
-	if query.Since.IsZero() == false {
-		countQuery += " AND timestamp >= ?"
-		countArgs = append(countArgs, query.Since)
+		if _, err := stmt.Exec(log.ID, log.Timestamp, log.Service, log.Level, log.Message, tagsJSON, log.TraceID, log.SpanID, log.Env, log.Host, log.Source); err != nil {
+			return fmt.Errorf("failed to insert log %s: %w", log.ID, err)
+		}
 	}
 
-	if query.Until.IsZero() == false {
-		countQuery += " AND timestamp <= ?"
-		countArgs = append(countArgs, query.Until)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	if query.TraceID != "" {
-		countQuery += " AND trace_id = ?"
-		countArgs = append(countArgs, query.TraceID)
-	}
+	return nil
+}
 
-	// Add search filter if provided
-	if query.Search != "" {
-		countQuery += " AND (message LIKE ? OR service LIKE ?)"
-		searchTerm := "%" + query.Search + "%"
-		countArgs = append(countArgs, searchTerm, searchTerm)
-	}
+// QueryLogs queries logs from the database based on the given parameters
+func (s *SQLiteStorage) QueryLogs(query *models.QueryParams) (map[string]interface{}, error) {
+	// Build the filters shared by the count and data queries
+	where, whereArgs := logFilters(query)
 
 	// Execute the count query
 	var totalItems int
-	err := s.db.QueryRow(countQuery, countArgs...).Scan(&totalItems)
+	err := s.db.QueryRow("SELECT COUNT(*) as total FROM logs"+where, whereArgs...).Scan(&totalItems)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count logs: %w", err)
 	}
@@ -238,71 +308,24 @@ func (s *SQLiteStorage) QueryLogs(query *models.QueryParams) (map[string]interfa
 	// Build the SQL query for data
 	sqlQuery := `
 		SELECT id, timestamp, service, level, message, tags, trace_id, span_id, env, host, source
-		FROM logs
-		WHERE 1=1`
-
-	// Create args slice for parameterized query
-	args := []interface{}{}
-
-	// Add filters based on query parameters
-	if query.Service != "" {
-		sqlQuery += " AND service = ?"
-		args = append(args, query.Service)
-	}
-
-	if query.Level != "" {
-		sqlQuery += " AND level = ?"
-		args = append(args, query.Level)
-	}
-
-	if query.Since.IsZero() == false {
-		sqlQuery += " AND timestamp >= ?"
-		args = append(args, query.Since)
-	}
-
-	if query.Until.IsZero() == false {
-		sqlQuery += " AND timestamp <= ?"
-		args = append(args, query.Until)
-	}
+		FROM logs` + where
+	args := append([]interface{}{}, whereArgs...)
 
-	if query.TraceID != "" {
-		sqlQuery += " AND trace_id = ?"
-		args = append(args, query.TraceID)
-	}
-
-	// Add search filter if provided
-	if query.Search != "" {
-		sqlQuery += " AND (message LIKE ? OR service LIKE ?)"
-		searchTerm := "%" + query.Search + "%"
-		args = append(args, searchTerm, searchTerm)
+	// Continue after the cursor, if any
+	cursorClause, cursorArgs, err := logCursorClause(query)
+	if err != nil {
+		return nil, err
 	}
+	sqlQuery += cursorClause
+	args = append(args, cursorArgs...)
 
 	// Add order by
-	if query.OrderBy != "" {
-		sqlQuery += fmt.Sprintf(" ORDER BY %s", query.OrderBy)
-		if query.OrderDesc {
-			sqlQuery += " DESC"
-		} else {
-			sqlQuery += " ASC"
-		}
-	} else {
-		sqlQuery += " ORDER BY timestamp DESC"
-	}
-
-	// Add limit first (SQLite requires LIMIT before OFFSET)
-	if query.Limit > 0 {
-		sqlQuery += " LIMIT ?"
-		args = append(args, query.Limit)
-	} else {
-		// Default limit to prevent massive result sets
-		sqlQuery += " LIMIT 100"
-	}
+	sqlQuery += logOrderClause(query)
 
-	// Add offset for pagination after LIMIT
-	if query.Offset > 0 {
-		sqlQuery += " OFFSET ?"
-		args = append(args, query.Offset)
-	}
+	// Add limit and offset for pagination
+	page, pageArgs := pageClause(logPageQuery(query))
+	sqlQuery += page
+	args = append(args, pageArgs...)
 
 	// Execute the query
 	rows, err := s.db.Query(sqlQuery, args...)
@@ -313,6 +336,7 @@ func (s *SQLiteStorage) QueryLogs(query *models.QueryParams) (map[string]interfa
 
 	// Process the results
 	logs := []map[string]interface{}{}
+	var last models.LogCursor
 	for rows.Next() {
 		var (
 			id        string
@@ -375,6 +399,7 @@ func (s *SQLiteStorage) QueryLogs(query *models.QueryParams) (map[string]interfa
 		}
 
 		logs = append(logs, logMap)
+		last = models.LogCursor{Timestamp: timestamp, ID: id}
 	}
 
 	// Check for errors after iteration
@@ -382,222 +407,828 @@ func (s *SQLiteStorage) QueryLogs(query *models.QueryParams) (map[string]interfa
 		return nil, fmt.Errorf("error iterating log rows: %w", err)
 	}
 
-	// Calculate pagination information
-	pageSize := query.Limit
-	if pageSize <= 0 {
-		pageSize = 100 // Default limit
-	}
-
-	totalPages := (totalItems + pageSize - 1) / pageSize
-
 	// Return results with pagination info
+	pagination := paginationInfo(totalItems, query)
+	setNextLogCursor(pagination, query, len(logs), last)
 	return map[string]interface{}{
-		"logs": logs,
-		"pagination": map[string]interface{}{
-			"total_items": totalItems,
-			"total_pages": totalPages,
-			"page_size":   pageSize,
-			"offset":      query.Offset,
-		},
+		"logs":       logs,
+		"pagination": pagination,
 	}, nil
 }
 
-// SaveMetric saves a metric to the database
-func (s *SQLiteStorage) SaveMetric(metric *models.Metric) error {
-	// Convert tags to JSON
-	tagsJSON, err := json.Marshal(metric.Tags)
-	if err != nil {
-		return fmt.Errorf("failed to marshal tags: %w", err)
-	}
+// logFilters returns the WHERE clause selecting the logs matching query
+func logFilters(query *models.QueryParams) (string, []interface{}) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
 
-	// Generate ID if not provided
-	if metric.ID == "" {
-		metric.ID = fmt.Sprintf("metric-%d", time.Now().UnixNano())
+	if query.Service != "" {
+		where += " AND service = ?"
+		args = append(args, query.Service)
 	}
 
-	// Begin transaction
-	tx, err := s.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+	if query.Level != "" {
+		where += " AND level = ?"
+		args = append(args, query.Level)
 	}
-	defer tx.Rollback()
 
-	// Insert into metrics table
-	_, err = tx.Exec(`
-		INSERT INTO metrics (id, name, value, timestamp, type, service, tags, trace_id, env, host)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		metric.ID, metric.Name, metric.Value, metric.Timestamp, metric.Type, metric.Service,
-		tagsJSON, metric.TraceID, metric.Env, metric.Host)
+	levelClause, levelArgs := minLevelClause(query.MinLevel)
+	where += levelClause
+	args = append(args, levelArgs...)
 
-	if err != nil {
-		return fmt.Errorf("failed to insert metric: %w", err)
+	if query.Since.IsZero() == false {
+		where += " AND timestamp >= ?"
+		args = append(args, query.Since)
 	}
 
-	// If this is a histogram metric, save the histogram data
-	if histogram, ok := interface{}(metric).(*models.HistogramMetric); ok {
-		bucketsJSON, err := json.Marshal(histogram.Buckets)
-		if err != nil {
-			return fmt.Errorf("failed to marshal buckets: %w", err)
-		}
-
-		percentilesJSON, err := json.Marshal(histogram.Percentile)
-		if err != nil {
-			return fmt.Errorf("failed to marshal percentiles: %w", err)
-		}
-
-		// Insert into histogram_metrics table
-		_, err = tx.Exec(`
-			INSERT INTO histogram_metrics (id, metric_id, buckets, sum, count, percentiles)
-			VALUES (?, ?, ?, ?, ?, ?)`,
-			fmt.Sprintf("hist-%d", time.Now().UnixNano()), metric.ID, bucketsJSON,
-			histogram.Sum, histogram.Count, percentilesJSON)
+	if query.Until.IsZero() == false {
+		where += " AND timestamp <= ?"
+		args = append(args, query.Until)
+	}
 
-		if err != nil {
-			return fmt.Errorf("failed to insert histogram data: %w", err)
-		}
+	if query.TraceID != "" {
+		where += " AND trace_id = ?"
+		args = append(args, query.TraceID)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	// Add search filter if provided
+	if query.Search != "" {
+		where += " AND (message LIKE ? OR service LIKE ?)"
+		searchTerm := "%" + query.Search + "%"
+		args = append(args, searchTerm, searchTerm)
 	}
 
-	return nil
+	// Add tag filters
+	tagClause, tagArgs := tagFilterClause(query.Filters)
+	where += tagClause
+	args = append(args, tagArgs...)
+
+	presenceClause, presenceArgs := tagPresenceClause(query)
+	where += presenceClause
+	args = append(args, presenceArgs...)
+
+	return where, args
 }
 
-// SaveSpan saves a span to the database
-func (s *SQLiteStorage) SaveSpan(span *models.Span) error {
-	// Convert tags and logs to JSON
-	tagsJSON, err := json.Marshal(span.Tags)
-	if err != nil {
-		return fmt.Errorf("failed to marshal tags: %w", err)
+// logColumns are the log columns read by scanLog
+const logColumns = "id, timestamp, service, level, message, tags, trace_id, span_id, env, host, source"
+
+// StreamLogs calls fn for each log matching the query, oldest first.
+// Rows are read one at a time so large result sets are never buffered.
+// A zero Limit streams every matching row.
+func (s *SQLiteStorage) StreamLogs(query *models.QueryParams, fn func(log *models.LogEntry) error) error {
+	where, args := logFilters(query)
+	sqlQuery := "SELECT " + logColumns + " FROM logs" + where + " ORDER BY timestamp ASC, id ASC"
+
+	if query.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit)
 	}
 
-	logsJSON, err := json.Marshal(span.Logs)
+	rows, err := s.db.Query(sqlQuery, args...)
 	if err != nil {
-		return fmt.Errorf("failed to marshal logs: %w", err)
+		return fmt.Errorf("failed to query logs: %w", err)
 	}
+	defer rows.Close()
 
-	// Insert into database
-	_, err = s.db.Exec(`
-		INSERT OR REPLACE INTO spans (
-			id, trace_id, parent_id, name, service, start_time, end_time, 
-			duration, status, tags, logs, env, host, is_finished
-		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		span.ID, span.TraceID, span.ParentID, span.Name, span.Service,
-		span.StartTime, span.EndTime, span.Duration, span.Status,
-		tagsJSON, logsJSON, span.Env, span.Host, span.IsFinished)
-
-	if err != nil {
-		return fmt.Errorf("failed to insert span: %w", err)
+	for rows.Next() {
+		log, err := scanLog(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(log); err != nil {
+			return err
+		}
 	}
 
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating log rows: %w", err)
+	}
 	return nil
 }
 
-// SaveTrace saves a trace to the database
-func (s *SQLiteStorage) SaveTrace(trace *models.Trace) error {
-	// Begin transaction
-	tx, err := s.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+// scanLog reads the current row of logColumns into a log entry
+func scanLog(rows *sql.Rows) (*models.LogEntry, error) {
+	var (
+		log                            models.LogEntry
+		level                          string
+		tagsJSON, traceID, spanID, env sql.NullString
+		host, source                   sql.NullString
+	)
+
+	if err := rows.Scan(&log.ID, &log.Timestamp, &log.Service, &level, &log.Message, &tagsJSON,
+		&traceID, &spanID, &env, &host, &source); err != nil {
+		return nil, fmt.Errorf("failed to scan log row: %w", err)
 	}
-	defer tx.Rollback()
 
-	// Save all spans in the trace
-	for _, span := range trace.Spans {
-		// Convert tags and logs to JSON
-		tagsJSON, err := json.Marshal(span.Tags)
-		if err != nil {
-			return fmt.Errorf("failed to marshal tags: %w", err)
+	if tagsJSON.String != "" {
+		if err := json.Unmarshal([]byte(tagsJSON.String), &log.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
 		}
+	}
 
-		logsJSON, err := json.Marshal(span.Logs)
-		if err != nil {
-			return fmt.Errorf("failed to marshal logs: %w", err)
-		}
+	log.Level = models.LogLevel(level)
+	log.TraceID = traceID.String
+	log.SpanID = spanID.String
+	log.Env = env.String
+	log.Host = host.String
+	log.Source = source.String
+	return &log, nil
+}
 
-		// Insert span
-		_, err = tx.Exec(`
-			INSERT OR REPLACE INTO spans (
-				id, trace_id, parent_id, name, service, start_time, end_time, 
-				duration, status, tags, logs, env, host, is_finished
-			)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			span.ID, span.TraceID, span.ParentID, span.Name, span.Service,
-			span.StartTime, span.EndTime, span.Duration, span.Status,
-			tagsJSON, logsJSON, span.Env, span.Host, span.IsFinished)
+// UpdateLogTags merges tags into the tags of every log matching query,
+// overwriting existing values of the same keys, and returns the number of
+// logs updated. New keys past the models.SetMaxTags limit are ignored.
+func (s *SQLiteStorage) UpdateLogTags(query *models.QueryParams, tags map[string]string) (int64, error) {
+	where, whereArgs := logFilters(query)
+	if models.MaxTags() > 0 {
+		return updateLimitedTags(s.db, "SELECT id, tags FROM logs"+where, "UPDATE logs SET tags = ? WHERE id = ?", whereArgs, tags)
+	}
 
-		if err != nil {
-			return fmt.Errorf("failed to insert span: %w", err)
-		}
+	tagsJSON, err := canonicalJSON(tags)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal tags: %w", err)
 	}
+	args := append([]interface{}{string(tagsJSON)}, whereArgs...)
 
-	// Insert trace record
-	_, err = tx.Exec(`
-		INSERT OR REPLACE INTO traces (id, root_span_id, status)
-		VALUES (?, ?, ?)`,
-		trace.ID, trace.Root.ID, trace.Status)
+	res, err := s.db.Exec("UPDATE logs SET tags = json_patch(COALESCE(NULLIF(tags, ''), '{}'), ?)"+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update log tags: %w", err)
+	}
 
+	updated, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to insert trace: %w", err)
+		return 0, fmt.Errorf("failed to count updated logs: %w", err)
 	}
+	return updated, nil
+}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+// tagFilterClause returns a condition requiring each tag in filters to have
+// the given value, with its arguments. Keys are sorted so the SQL is stable.
+func tagFilterClause(filters map[string]string) (string, []interface{}) {
+	keys := make([]string, 0, len(filters))
+	for k := range filters {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	return nil
-}
+	clause := ""
+	args := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		clause += " AND json_extract(tags, ?) = ?"
+		args = append(args, jsonTagPath(k), filters[k])
+	}
 
-// QueryMetrics queries metrics from storage
-func (s *SQLiteStorage) QueryMetrics(query *models.QueryParams) ([]map[string]interface{}, error) {
-	// Build the SQL query
-	sqlQuery := `
-		SELECT id, timestamp, service, name, value, type, tags
-		FROM metrics
-		WHERE 1=1`
+	return clause, args
+}
 
-	// Create args slice for parameterized query
-	args := []interface{}{}
+// tagPresenceClause returns a condition requiring the query's HasTags keys
+// to be present in the tags and its MissingTags keys to be absent, with its
+// arguments
+func tagPresenceClause(query *models.QueryParams) (string, []interface{}) {
+	clause := ""
+	args := make([]interface{}, 0, len(query.HasTags)+len(query.MissingTags))
 
-	// Add filters based on query parameters
-	if query.Service != "" {
-		sqlQuery += " AND service = ?"
-		args = append(args, query.Service)
+	for _, key := range query.HasTags {
+		clause += " AND json_type(tags, ?) IS NOT NULL"
+		args = append(args, jsonTagPath(key))
+	}
+	for _, key := range query.MissingTags {
+		clause += " AND json_type(tags, ?) IS NULL"
+		args = append(args, jsonTagPath(key))
 	}
 
-	if query.Since.IsZero() == false {
-		sqlQuery += " AND timestamp >= ?"
-		args = append(args, query.Since)
+	return clause, args
+}
+
+// minLevelClause returns a condition matching logs at or above minLevel, with
+// its arguments. Levels without a severity ranking are matched exactly.
+func minLevelClause(minLevel string) (string, []interface{}) {
+	if minLevel == "" {
+		return "", nil
 	}
 
-	if query.Until.IsZero() == false {
-		sqlQuery += " AND timestamp <= ?"
-		args = append(args, query.Until)
+	levels := models.LevelsAtOrAbove(models.LogLevel(minLevel))
+	if levels == nil {
+		return " AND level = ?", []interface{}{minLevel}
 	}
 
-	// Add search filter if provided
-	if query.Search != "" {
-		sqlQuery += " AND (name LIKE ? OR service LIKE ?)"
-		searchTerm := "%" + query.Search + "%"
-		args = append(args, searchTerm, searchTerm)
+	placeholders := make([]string, len(levels))
+	args := make([]interface{}, len(levels))
+	for i, level := range levels {
+		placeholders[i] = "?"
+		args[i] = string(level)
 	}
+	return " AND level IN (" + strings.Join(placeholders, ", ") + ")", args
+}
 
-	// Add order by
-	sqlQuery += " ORDER BY timestamp DESC"
+// paginationInfo describes the page of results selected by query out of totalItems
+func paginationInfo(totalItems int, query *models.QueryParams) map[string]interface{} {
+	pageSize := query.Limit
+	if pageSize <= 0 {
+		pageSize = 100 // Default limit
+	}
+
+	totalPages := (totalItems + pageSize - 1) / pageSize
+
+	return map[string]interface{}{
+		"total_items": totalItems,
+		"total_pages": totalPages,
+		"page_size":   pageSize,
+		"offset":      query.Offset,
+	}
+}
+
+// logOrderColumns are the columns logs may be ordered by
+var logOrderColumns = map[string]bool{
+	"timestamp": true,
+	"service":   true,
+	"level":     true,
+	"message":   true,
+}
+
+// levelSeverity ranks the level column the way LogLevel.Severity does, from
+// 1 for DEBUG to 5 for FATAL and 0 for levels without a ranking
+var levelSeverity = func() string {
+	expr := "CASE level"
+	for _, level := range models.LevelsAtOrAbove(models.LogLevelDebug) {
+		expr += fmt.Sprintf(" WHEN '%s' THEN %d", level, level.Severity())
+	}
+	return expr + " ELSE 0 END"
+}()
+
+// logOrderClause returns the ORDER BY clause of a log query: by the
+// order_by column, levels by severity, with ties newest first, or newest
+// first if order_by is not a log column. Only known columns are
+// interpolated.
+func logOrderClause(query *models.QueryParams) string {
+	if !logOrderColumns[query.OrderBy] {
+		return " ORDER BY timestamp DESC, id DESC"
+	}
+
+	column := query.OrderBy
+	if column == "level" {
+		column = levelSeverity
+	}
+	direction := " ASC"
+	if query.OrderDesc {
+		direction = " DESC"
+	}
+	return " ORDER BY " + column + direction + ", timestamp DESC, id DESC"
+}
+
+// queryLogCursor decodes the cursor of query, or returns nil if it has none.
+// Cursors are positions in the default newest first order, so they cannot
+// be combined with another order.
+func queryLogCursor(query *models.QueryParams) (*models.LogCursor, error) {
+	if query.Cursor == "" {
+		return nil, nil
+	}
+	if query.OrderBy != "" {
+		return nil, ErrCursorOrder
+	}
+	cursor, err := models.ParseLogCursor(query.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+// logCursorClause returns the condition selecting the logs after the cursor
+// of query, with its arguments
+func logCursorClause(query *models.QueryParams) (string, []interface{}, error) {
+	cursor, err := queryLogCursor(query)
+	if cursor == nil {
+		return "", nil, err
+	}
+	return " AND (timestamp, id) < (?, ?)", []interface{}{cursor.Timestamp, cursor.ID}, nil
+}
+
+// logPageQuery returns the query selecting the page of logs: a cursor
+// replaces the offset
+func logPageQuery(query *models.QueryParams) *models.QueryParams {
+	if query.Cursor == "" {
+		return query
+	}
+	page := *query
+	page.Offset = 0
+	return &page
+}
+
+// setNextLogCursor adds the cursor of the last returned log to pagination
+// as next_cursor when the page of logs is full and more may follow
+func setNextLogCursor(pagination map[string]interface{}, query *models.QueryParams, returned int, last models.LogCursor) {
+	if query.OrderBy != "" || returned == 0 || returned < pagination["page_size"].(int) {
+		return
+	}
+	pagination["next_cursor"] = last.Encode()
+}
+
+// durationClause returns the conditions keeping column, a duration in
+// milliseconds, within the query's duration range with their arguments
+func durationClause(column string, query *models.QueryParams) (string, []interface{}) {
+	clause := ""
+	args := []interface{}{}
+
+	if query.MinDuration > 0 {
+		clause += " AND " + column + " >= ?"
+		args = append(args, query.MinDuration)
+	}
+	if query.MaxDuration > 0 {
+		clause += " AND " + column + " <= ?"
+		args = append(args, query.MaxDuration)
+	}
+
+	return clause, args
+}
+
+// pageClause returns the LIMIT and OFFSET clause for query with its arguments
+func pageClause(query *models.QueryParams) (string, []interface{}) {
+	limit := query.Limit
+	if limit <= 0 {
+		// Default limit to prevent massive result sets
+		limit = 100
+	}
+
+	// SQLite requires LIMIT before OFFSET
+	clause := " LIMIT ?"
+	args := []interface{}{limit}
+	if query.Offset > 0 {
+		clause += " OFFSET ?"
+		args = append(args, query.Offset)
+	}
+
+	return clause, args
+}
+
+// SaveMetric saves a metric to the database
+func (s *SQLiteStorage) SaveMetric(metric *models.Metric) error {
+	return s.SaveMetrics([]*models.Metric{metric})
+}
+
+// SaveMetrics saves a batch of metrics in a single transaction through one
+// prepared statement. Either all metrics are saved or, on error, none are.
+func (s *SQLiteStorage) SaveMetrics(metrics []*models.Metric) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertMetrics(tx, metrics); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// SaveHistogramMetric saves a histogram metric together with its bucket data
+func (s *SQLiteStorage) SaveHistogramMetric(histogram *models.HistogramMetric) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertMetrics(tx, []*models.Metric{&histogram.Metric}); err != nil {
+		return err
+	}
+	if err := insertHistogram(tx, histogram); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// SaveExponentialHistogram saves an exponential histogram together with its bucket data
+func (s *SQLiteStorage) SaveExponentialHistogram(histogram *models.ExponentialHistogram) error {
+	positiveJSON, err := json.Marshal(histogram.Positive)
+	if err != nil {
+		return fmt.Errorf("failed to marshal positive buckets: %w", err)
+	}
+
+	negativeJSON, err := json.Marshal(histogram.Negative)
+	if err != nil {
+		return fmt.Errorf("failed to marshal negative buckets: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertMetrics(tx, []*models.Metric{&histogram.Metric}); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO exponential_histogram_metrics (id, metric_id, scale, zero_count, positive, negative, sum, count)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		"exphist-"+histogram.ID, histogram.ID, histogram.Scale, histogram.ZeroCount,
+		positiveJSON, negativeJSON, histogram.Sum, histogram.Count)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert exponential histogram data: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetExponentialHistogram loads the exponential histogram stored for a metric ID
+func (s *SQLiteStorage) GetExponentialHistogram(metricID string) (*models.ExponentialHistogram, error) {
+	var (
+		histogram    models.ExponentialHistogram
+		metricType   string
+		tagsJSON     sql.NullString
+		traceID      sql.NullString
+		env          sql.NullString
+		host         sql.NullString
+		positiveJSON string
+		negativeJSON string
+	)
+
+	err := s.db.QueryRow(`
+		SELECT m.id, m.name, m.value, m.timestamp, m.type, m.service, m.tags, m.trace_id, m.env, m.host,
+			e.scale, e.zero_count, e.positive, e.negative, e.sum, e.count
+		FROM metrics m
+		JOIN exponential_histogram_metrics e ON e.metric_id = m.id
+		WHERE m.id = ?`, metricID).Scan(
+		&histogram.ID, &histogram.Name, &histogram.Value, &histogram.Timestamp, &metricType, &histogram.Service,
+		&tagsJSON, &traceID, &env, &host,
+		&histogram.Scale, &histogram.ZeroCount, &positiveJSON, &negativeJSON, &histogram.Sum, &histogram.Count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exponential histogram: %w", err)
+	}
+
+	if tagsJSON.Valid && tagsJSON.String != "" {
+		if err := json.Unmarshal([]byte(tagsJSON.String), &histogram.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+	}
+	if err := json.Unmarshal([]byte(positiveJSON), &histogram.Positive); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal positive buckets: %w", err)
+	}
+	if err := json.Unmarshal([]byte(negativeJSON), &histogram.Negative); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal negative buckets: %w", err)
+	}
+
+	histogram.Type = models.MetricType(metricType)
+	histogram.TraceID = traceID.String
+	histogram.Env = env.String
+	histogram.Host = host.String
+
+	return &histogram, nil
+}
+
+// QueryHistograms returns the histogram metrics matching the query, oldest
+// first, with their buckets, sum, count and percentiles
+func (s *SQLiteStorage) QueryHistograms(query *models.QueryParams) ([]*models.HistogramMetric, error) {
+	sqlQuery := `
+		SELECT m.id, m.name, m.value, m.timestamp, m.type, m.service, m.tags, m.trace_id, m.env, m.host,
+			h.buckets, h.sum, h.count, h.percentiles
+		FROM metrics m
+		JOIN histogram_metrics h ON h.metric_id = m.id
+		WHERE 1=1`
+
+	args := []interface{}{}
+
+	if query.Service != "" {
+		sqlQuery += " AND m.service = ?"
+		args = append(args, query.Service)
+	}
+
+	if query.Name != "" {
+		sqlQuery += " AND m.name = ?"
+		args = append(args, query.Name)
+	}
+
+	if !query.Since.IsZero() {
+		sqlQuery += " AND m.timestamp >= ?"
+		args = append(args, query.Since)
+	}
+
+	if !query.Until.IsZero() {
+		sqlQuery += " AND m.timestamp <= ?"
+		args = append(args, query.Until)
+	}
+
+	sqlQuery += " ORDER BY m.timestamp ASC"
 
-	// Add limit
 	if query.Limit > 0 {
 		sqlQuery += " LIMIT ?"
 		args = append(args, query.Limit)
-	} else {
-		// Default limit to prevent massive result sets
-		sqlQuery += " LIMIT 100"
 	}
 
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query histograms: %w", err)
+	}
+	defer rows.Close()
+
+	var histograms []*models.HistogramMetric
+	for rows.Next() {
+		var (
+			histogram       models.HistogramMetric
+			metricType      string
+			tagsJSON        sql.NullString
+			traceID         sql.NullString
+			env             sql.NullString
+			host            sql.NullString
+			bucketsJSON     string
+			percentilesJSON sql.NullString
+		)
+
+		if err := rows.Scan(&histogram.ID, &histogram.Name, &histogram.Value, &histogram.Timestamp, &metricType,
+			&histogram.Service, &tagsJSON, &traceID, &env, &host,
+			&bucketsJSON, &histogram.Sum, &histogram.Count, &percentilesJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan histogram row: %w", err)
+		}
+
+		if err := unmarshalHistogramData(&histogram, tagsJSON.String, bucketsJSON, percentilesJSON.String); err != nil {
+			return nil, err
+		}
+
+		histogram.Type = models.MetricType(metricType)
+		histogram.TraceID = traceID.String
+		histogram.Env = env.String
+		histogram.Host = host.String
+
+		histograms = append(histograms, &histogram)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating histogram rows: %w", err)
+	}
+
+	return histograms, nil
+}
+
+// unmarshalHistogramData decodes the JSON columns of a stored histogram
+func unmarshalHistogramData(histogram *models.HistogramMetric, tagsJSON, bucketsJSON, percentilesJSON string) error {
+	if tagsJSON != "" {
+		if err := json.Unmarshal([]byte(tagsJSON), &histogram.Tags); err != nil {
+			return fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+	}
+	if err := json.Unmarshal([]byte(bucketsJSON), &histogram.Buckets); err != nil {
+		return fmt.Errorf("failed to unmarshal buckets: %w", err)
+	}
+	if percentilesJSON != "" {
+		if err := json.Unmarshal([]byte(percentilesJSON), &histogram.Percentile); err != nil {
+			return fmt.Errorf("failed to unmarshal percentiles: %w", err)
+		}
+	}
+	return nil
+}
+
+// insertMetrics inserts rows into the metrics table through one prepared
+// statement, generating IDs if needed
+func insertMetrics(tx *sql.Tx, metrics []*models.Metric) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO metrics (id, name, value, timestamp, type, service, tags, trace_id, env, host)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare metric insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, metric := range metrics {
+		// Convert tags to JSON
+		tagsJSON, err := canonicalJSON(metric.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tags: %w", err)
+		}
+
+		// Generate ID if not provided
+		if metric.ID == "" {
+			metric.ID = fmt.Sprintf("metric-%d", time.Now().UnixNano())
+		}
+
+		_, err = stmt.Exec(metric.ID, metric.Name, metric.Value, metric.Timestamp, metric.Type, metric.Service,
+			tagsJSON, metric.TraceID, metric.Env, metric.Host)
+		if err != nil {
+			return fmt.Errorf("failed to insert metric: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// insertHistogram inserts the bucket data of a histogram whose metric row
+// has already been inserted
+func insertHistogram(tx *sql.Tx, histogram *models.HistogramMetric) error {
+	bucketsJSON, err := json.Marshal(histogram.Buckets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal buckets: %w", err)
+	}
+
+	percentilesJSON, err := json.Marshal(histogram.Percentile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal percentiles: %w", err)
+	}
+
+	// Insert into histogram_metrics table
+	_, err = tx.Exec(`
+		INSERT INTO histogram_metrics (id, metric_id, buckets, sum, count, percentiles)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		"hist-"+histogram.ID, histogram.ID, bucketsJSON,
+		histogram.Sum, histogram.Count, percentilesJSON)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert histogram data: %w", err)
+	}
+
+	return nil
+}
+
+// SaveSpan saves a span to the database
+func (s *SQLiteStorage) SaveSpan(span *models.Span) error {
+	return s.SaveSpans([]*models.Span{span})
+}
+
+// SaveSpans saves a batch of spans in a single transaction through one
+// prepared statement. Either all spans are saved or, on error, none are.
+func (s *SQLiteStorage) SaveSpans(spans []*models.Span) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertSpans(tx, spans); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// insertSpans inserts or replaces rows of the spans table through one
+// prepared statement
+func insertSpans(tx *sql.Tx, spans []*models.Span) error {
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO spans (
+			id, trace_id, parent_id, name, service, start_time, end_time,
+			duration, status, tags, logs, links, env, host, is_finished
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare span insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, span := range spans {
+		// Convert tags and logs to JSON
+		tagsJSON, err := canonicalJSON(span.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tags: %w", err)
+		}
+
+		logsJSON, err := json.Marshal(span.Logs)
+		if err != nil {
+			return fmt.Errorf("failed to marshal logs: %w", err)
+		}
+
+		linksJSON, err := json.Marshal(span.Links)
+		if err != nil {
+			return fmt.Errorf("failed to marshal links: %w", err)
+		}
+
+		_, err = stmt.Exec(span.ID, span.TraceID, span.ParentID, span.Name, span.Service,
+			span.StartTime, span.EndTime, span.Duration, span.Status,
+			tagsJSON, logsJSON, linksJSON, span.Env, span.Host, span.IsFinished)
+		if err != nil {
+			return fmt.Errorf("failed to insert span: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveTrace saves a trace to the database
+func (s *SQLiteStorage) SaveTrace(trace *models.Trace) error {
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Save all spans in the trace
+	if err := insertSpans(tx, trace.Spans); err != nil {
+		return err
+	}
+
+	// Merge the trace record with the batches saved before
+	var storedRoot, storedStatus string
+	err = tx.QueryRow("SELECT root_span_id, COALESCE(status, '') FROM traces WHERE id = ?", trace.ID).Scan(&storedRoot, &storedStatus)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to query trace: %w", err)
+	}
+	rows, err := tx.Query("SELECT "+spanColumns+" FROM spans WHERE trace_id = ?", trace.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query trace spans: %w", err)
+	}
+	spans, err := scanSpans(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	if root, status, duration, ok := mergeTrace(trace, storedRoot, models.SpanStatus(storedStatus), spans); ok {
+		_, err = tx.Exec(`
+			INSERT OR REPLACE INTO traces (id, root_span_id, status, duration)
+			VALUES (?, ?, ?, ?)`,
+			trace.ID, root, status, duration)
+		if err != nil {
+			return fmt.Errorf("failed to insert trace: %w", err)
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// metricQueryFilters returns the WHERE clause and arguments selecting the
+// metrics matching query
+func metricQueryFilters(query *models.QueryParams) (string, []interface{}) {
+	where := " WHERE 1=1"
+	whereArgs := []interface{}{}
+
+	// Add filters based on query parameters
+	if query.Service != "" {
+		where += " AND service = ?"
+		whereArgs = append(whereArgs, query.Service)
+	}
+
+	if query.Name != "" {
+		where += " AND name = ?"
+		whereArgs = append(whereArgs, query.Name)
+	}
+
+	if query.Since.IsZero() == false {
+		where += " AND timestamp >= ?"
+		whereArgs = append(whereArgs, query.Since)
+	}
+
+	if query.Until.IsZero() == false {
+		where += " AND timestamp <= ?"
+		whereArgs = append(whereArgs, query.Until)
+	}
+
+	// Add search filter if provided
+	if query.Search != "" {
+		where += " AND (name LIKE ? OR service LIKE ?)"
+		searchTerm := "%" + query.Search + "%"
+		whereArgs = append(whereArgs, searchTerm, searchTerm)
+	}
+
+	presence, presenceArgs := tagPresenceClause(query)
+	where += presence
+	whereArgs = append(whereArgs, presenceArgs...)
+
+	return where, whereArgs
+}
+
+// QueryMetrics queries metrics from storage
+func (s *SQLiteStorage) QueryMetrics(query *models.QueryParams) (map[string]interface{}, error) {
+	// Build the filters shared by the count and data queries
+	where, whereArgs := metricQueryFilters(query)
+
+	// Execute the count query
+	var totalItems int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM metrics"+where, whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count metrics: %w", err)
+	}
+
+	// Build the SQL query for data
+	sqlQuery := `
+		SELECT id, timestamp, service, name, value, type, tags
+		FROM metrics` + where + " ORDER BY timestamp DESC"
+
+	page, pageArgs := pageClause(query)
+	sqlQuery += page
+	args := append(whereArgs, pageArgs...)
+
 	// Execute the query
 	rows, err := s.db.Query(sqlQuery, args...)
 	if err != nil {
@@ -630,73 +1261,624 @@ func (s *SQLiteStorage) QueryMetrics(query *models.QueryParams) ([]map[string]in
 			}
 		}
 
-		// Create the metric map
-		metricMap := map[string]interface{}{
-			"id":        id,
-			"timestamp": timestamp.Format(time.RFC3339),
-			"service":   service,
-			"name":      name,
-			"value":     value,
-			"type":      metricType,
-		}
+		// Create the metric map
+		metricMap := map[string]interface{}{
+			"id":        id,
+			"timestamp": timestamp.Format(time.RFC3339),
+			"service":   service,
+			"name":      name,
+			"value":     value,
+			"type":      metricType,
+		}
+
+		// Add optional fields if present
+		if tags != nil && len(tags) > 0 {
+			metricMap["tags"] = tags
+		}
+
+		metrics = append(metrics, metricMap)
+	}
+
+	// Check for errors after iteration
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating metric rows: %w", err)
+	}
+
+	return map[string]interface{}{
+		"metrics":    metrics,
+		"pagination": paginationInfo(totalItems, query),
+	}, nil
+}
+
+// StreamMetrics calls fn for each metric matching the query, oldest first.
+// Rows are read one at a time so large result sets are never buffered.
+// A zero Limit streams every matching row.
+func (s *SQLiteStorage) StreamMetrics(query *models.QueryParams, fn func(metric *models.Metric) error) error {
+	sqlQuery := `
+		SELECT id, name, value, timestamp, type, service, tags, trace_id, env, host
+		FROM metrics
+		WHERE 1=1`
+
+	args := []interface{}{}
+
+	if query.Service != "" {
+		sqlQuery += " AND service = ?"
+		args = append(args, query.Service)
+	}
+
+	if query.Name != "" {
+		sqlQuery += " AND name = ?"
+		args = append(args, query.Name)
+	}
+
+	if query.Since.IsZero() == false {
+		sqlQuery += " AND timestamp >= ?"
+		args = append(args, query.Since)
+	}
+
+	if query.Until.IsZero() == false {
+		sqlQuery += " AND timestamp <= ?"
+		args = append(args, query.Until)
+	}
+
+	sqlQuery += " ORDER BY timestamp ASC"
+
+	if query.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			metric     models.Metric
+			metricType string
+			tagsJSON   sql.NullString
+			traceID    sql.NullString
+			env        sql.NullString
+			host       sql.NullString
+		)
+
+		if err := rows.Scan(&metric.ID, &metric.Name, &metric.Value, &metric.Timestamp, &metricType,
+			&metric.Service, &tagsJSON, &traceID, &env, &host); err != nil {
+			return fmt.Errorf("failed to scan metric row: %w", err)
+		}
+
+		if tagsJSON.Valid && tagsJSON.String != "" {
+			if err := json.Unmarshal([]byte(tagsJSON.String), &metric.Tags); err != nil {
+				return fmt.Errorf("failed to unmarshal tags: %w", err)
+			}
+		}
+
+		metric.Type = models.MetricType(metricType)
+		metric.TraceID = traceID.String
+		metric.Env = env.String
+		metric.Host = host.String
+
+		if err := fn(&metric); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating metric rows: %w", err)
+	}
+
+	return nil
+}
+
+// QueryTraces queries traces from the database based on the given parameters.
+// Each trace is represented by its root span; ContainsSpan matches any span of the trace.
+// Traces are newest first unless ordered by duration.
+func (s *SQLiteStorage) QueryTraces(query *models.QueryParams) (map[string]interface{}, error) {
+	where, whereArgs := traceFilters(query)
+	return s.queryTraces(query, where, whereArgs, traceOrder(query))
+}
+
+// traceDuration is the total duration of the trace of a root span: the
+// duration computed when the whole trace was saved, or the root span's own
+// duration for traces saved span by span
+const traceDuration = "COALESCE((SELECT NULLIF(traces.duration, 0) FROM traces WHERE traces.id = spans.trace_id), spans.duration)"
+
+// traceStatus is the status of the trace of a root span: the status computed
+// from all spans when the trace was saved, ERROR if any of them failed, or
+// the root span's own status for traces saved span by span
+const traceStatus = "COALESCE((SELECT NULLIF(traces.status, '') FROM traces WHERE traces.id = spans.trace_id), spans.status)"
+
+// traceOrder returns the ORDER BY expression of a trace query: by total
+// duration for order_by=duration, newest first otherwise
+func traceOrder(query *models.QueryParams) string {
+	if query.OrderBy != "duration" {
+		return "start_time DESC"
+	}
+	if query.OrderDesc {
+		return traceDuration + " DESC"
+	}
+	return traceDuration + " ASC"
+}
+
+// QuerySlowTraces returns the traces matching query whose duration is above
+// the given percentile of their durations, slowest first
+func (s *SQLiteStorage) QuerySlowTraces(query *models.QueryParams, percentile float64) (map[string]interface{}, error) {
+	if percentile <= 0 || percentile >= 100 {
+		return nil, fmt.Errorf("percentile must be between 0 and 100, got %v", percentile)
+	}
+
+	where, whereArgs := traceFilters(query)
+
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM spans"+where, whereArgs...).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	var threshold int64
+	if count > 0 {
+		args := append(append([]interface{}{}, whereArgs...), percentileRank(count, percentile))
+		if err := s.db.QueryRow("SELECT "+traceDuration+" FROM spans"+where+" ORDER BY 1 LIMIT 1 OFFSET ?", args...).Scan(&threshold); err != nil {
+			return nil, fmt.Errorf("failed to compute the duration percentile: %w", err)
+		}
+	}
+
+	result, err := s.queryTraces(query, where+" AND "+traceDuration+" > ?", append(whereArgs, threshold), traceDuration+" DESC")
+	if err != nil {
+		return nil, err
+	}
+	result["percentile"] = percentile
+	result["threshold_ms"] = threshold
+	return result, nil
+}
+
+// traceFilters returns the WHERE clause selecting the root span of each
+// trace matching query
+func traceFilters(query *models.QueryParams) (string, []interface{}) {
+	// Traces are collections of spans, so select the root span of each one
+	where := " WHERE (parent_id IS NULL OR parent_id = '')"
+	whereArgs := []interface{}{}
+
+	// Add filters based on query parameters
+	if query.Service != "" {
+		where += " AND service = ?"
+		whereArgs = append(whereArgs, query.Service)
+	}
+
+	if query.Since.IsZero() == false {
+		where += " AND start_time >= ?"
+		whereArgs = append(whereArgs, query.Since)
+	}
+
+	if query.Until.IsZero() == false {
+		where += " AND start_time <= ?"
+		whereArgs = append(whereArgs, query.Until)
+	}
+
+	if query.TraceID != "" {
+		where += " AND trace_id = ?"
+		whereArgs = append(whereArgs, query.TraceID)
+	}
+
+	// Add search filter if provided
+	if query.Search != "" {
+		where += " AND (name LIKE ? OR service LIKE ?)"
+		searchTerm := "%" + query.Search + "%"
+		whereArgs = append(whereArgs, searchTerm, searchTerm)
+	}
+
+	// Only keep traces that contain a span with the given name
+	if query.ContainsSpan != "" {
+		where += " AND EXISTS (SELECT 1 FROM spans AS named WHERE named.trace_id = spans.trace_id AND named.name = ?)"
+		whereArgs = append(whereArgs, query.ContainsSpan)
+	}
+
+	// Keep traces whose total duration is within the requested range
+	durations, durationArgs := durationClause(traceDuration, query)
+	where += durations
+	whereArgs = append(whereArgs, durationArgs...)
+
+	return where, whereArgs
+}
+
+// queryTraces returns a page of the traces whose root span matches where,
+// in the given order
+func (s *SQLiteStorage) queryTraces(query *models.QueryParams, where string, whereArgs []interface{}, order string) (map[string]interface{}, error) {
+	// Execute the count query
+	var totalItems int
+	if err := s.db.QueryRow("SELECT COUNT(DISTINCT trace_id) FROM spans"+where, whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	// Build the SQL query for data
+	sqlQuery := `
+		SELECT trace_id, service, name, start_time, ` + traceDuration + `, ` + traceStatus + `, tags
+		FROM spans` + where + " ORDER BY " + order
+
+	page, pageArgs := pageClause(query)
+	sqlQuery += page
+	args := append(whereArgs, pageArgs...)
+
+	// Execute the query
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query traces: %w", err)
+	}
+	defer rows.Close()
+
+	// Process the results
+	traces := []map[string]interface{}{}
+	for rows.Next() {
+		var (
+			traceID   string
+			service   string
+			name      string
+			startTime time.Time
+			duration  int64
+			status    string
+			tagsJSON  string
+		)
+
+		if err := rows.Scan(&traceID, &service, &name, &startTime, &duration, &status, &tagsJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan span row: %w", err)
+		}
+
+		// Parse the tags
+		var tags map[string]string
+		if tagsJSON != "" {
+			if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+			}
+		}
+
+		// Create the trace map from its root span
+		traceMap := map[string]interface{}{
+			"id":          traceID,
+			"start_time":  startTime.Format(time.RFC3339),
+			"service":     service,
+			"name":        name,
+			"duration_ms": duration,
+			"status":      status,
+		}
+
+		// Add tags to the trace
+		if tags != nil && len(tags) > 0 {
+			traceMap["tags"] = tags
+		}
+
+		traces = append(traces, traceMap)
+	}
+
+	// Check for errors after iteration
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trace rows: %w", err)
+	}
+
+	return map[string]interface{}{
+		"traces":     traces,
+		"pagination": paginationInfo(totalItems, query),
+	}, nil
+}
+
+// QueryTraceIDs returns the ID and root span start time of each trace
+// matching the service and time range of query, newest first with ties
+// broken by descending ID. Unlike QueryTraces it reads nothing else of the
+// root spans, so it is cheap enough to page through every trace in a window
+// and fetch the details of each with GetTrace. Root spans are read rather
+// than the traces table, which lacks the traces saved span by span.
+func (s *SQLiteStorage) QueryTraceIDs(query *models.QueryParams) (map[string]interface{}, error) {
+	where, whereArgs := traceIDFilters(query)
+
+	var totalItems int
+	if err := s.db.QueryRow("SELECT COUNT(DISTINCT trace_id) FROM spans"+where, whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	page, pageArgs := pageClause(query)
+	rows, err := s.db.Query("SELECT trace_id, start_time FROM spans"+where+" ORDER BY start_time DESC, trace_id DESC"+page,
+		append(whereArgs, pageArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace IDs: %w", err)
+	}
+	defer rows.Close()
+
+	traces := []map[string]interface{}{}
+	for rows.Next() {
+		var (
+			traceID   string
+			startTime time.Time
+		)
+		if err := rows.Scan(&traceID, &startTime); err != nil {
+			return nil, fmt.Errorf("failed to scan trace ID row: %w", err)
+		}
+		traces = append(traces, traceIDRecord(traceID, startTime))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trace ID rows: %w", err)
+	}
+
+	return map[string]interface{}{
+		"traces":     traces,
+		"pagination": paginationInfo(totalItems, query),
+	}, nil
+}
+
+// traceIDFilters returns the WHERE clause selecting the root span of each
+// trace in the service and time range of query
+func traceIDFilters(query *models.QueryParams) (string, []interface{}) {
+	where := " WHERE (parent_id IS NULL OR parent_id = '')"
+	whereArgs := []interface{}{}
+
+	if query.Service != "" {
+		where += " AND service = ?"
+		whereArgs = append(whereArgs, query.Service)
+	}
+	if !query.Since.IsZero() {
+		where += " AND start_time >= ?"
+		whereArgs = append(whereArgs, query.Since)
+	}
+	if !query.Until.IsZero() {
+		where += " AND start_time <= ?"
+		whereArgs = append(whereArgs, query.Until)
+	}
+
+	return where, whereArgs
+}
+
+// traceIDRecord returns the record of a trace in QueryTraceIDs results
+func traceIDRecord(traceID string, startTime time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         traceID,
+		"start_time": startTime.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// traceSampleDuration is the duration of a trace aggregated over its spans:
+// from the earliest span start to the latest span end, taking start plus
+// duration for the spans without an end time
+const traceSampleDuration = `CAST(ROUND((MAX(CASE WHEN julianday(spans.end_time) >= julianday(spans.start_time)
+	THEN julianday(spans.end_time) ELSE julianday(spans.start_time) + spans.duration / 86400000.0 END)
+	- MIN(julianday(spans.start_time))) * 86400000.0) AS INTEGER)`
+
+// QueryTraceSamples returns the ID, root span start time, duration and
+// failure of each trace matching the service and time range of query, in the
+// order of QueryTraceIDs. The duration and failure are aggregated over all
+// spans of the page's traces in one query, without loading them.
+func (s *SQLiteStorage) QueryTraceSamples(query *models.QueryParams) (map[string]interface{}, error) {
+	where, whereArgs := traceIDFilters(query)
+
+	var totalItems int
+	if err := s.db.QueryRow("SELECT COUNT(DISTINCT trace_id) FROM spans"+where, whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+
+	page, pageArgs := pageClause(query)
+	args := append([]interface{}{models.SpanStatusError}, whereArgs...)
+	rows, err := s.db.Query(`
+		SELECT roots.trace_id, roots.start_time, `+traceSampleDuration+`,
+			MAX(CASE WHEN spans.status = ? THEN 1 ELSE 0 END)
+		FROM (SELECT trace_id, start_time FROM spans`+where+` ORDER BY start_time DESC, trace_id DESC`+page+`) AS roots
+		JOIN spans ON spans.trace_id = roots.trace_id
+		GROUP BY roots.trace_id, roots.start_time
+		ORDER BY roots.start_time DESC, roots.trace_id DESC`,
+		append(args, pageArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace samples: %w", err)
+	}
+	defer rows.Close()
+
+	traces := []map[string]interface{}{}
+	for rows.Next() {
+		var (
+			traceID   string
+			startTime time.Time
+			duration  int64
+			failed    bool
+		)
+		if err := rows.Scan(&traceID, &startTime, &duration, &failed); err != nil {
+			return nil, fmt.Errorf("failed to scan trace sample row: %w", err)
+		}
+		traces = append(traces, traceSampleRecord(traceID, startTime, duration, failed))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trace sample rows: %w", err)
+	}
+
+	return map[string]interface{}{
+		"traces":     traces,
+		"pagination": paginationInfo(totalItems, query),
+	}, nil
+}
+
+// traceSampleRecord returns the record of a trace in QueryTraceSamples
+// results
+func traceSampleRecord(traceID string, startTime time.Time, duration int64, failed bool) map[string]interface{} {
+	record := traceIDRecord(traceID, startTime)
+	record["duration"] = duration
+	record["error"] = failed
+	return record
+}
+
+// GetTrace loads every span of a trace with its tags, logs and links and
+// assembles them into the trace. It returns ErrTraceNotFound if the trace
+// has no spans.
+func (s *SQLiteStorage) GetTrace(traceID string) (*models.Trace, error) {
+	rows, err := s.db.Query("SELECT "+spanColumns+" FROM spans WHERE trace_id = ?", traceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace spans: %w", err)
+	}
+	defer rows.Close()
+
+	spans, err := scanSpans(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 {
+		return nil, ErrTraceNotFound
+	}
+
+	return models.AssembleTrace(traceID, spans), nil
+}
+
+// GetSpanAncestors returns the ancestors of a span, its parent first and the
+// root last, reading the spans of its trace in a single query
+func (s *SQLiteStorage) GetSpanAncestors(spanID string) ([]*models.Span, error) {
+	rows, err := s.db.Query("SELECT "+spanColumns+" FROM spans WHERE trace_id IN (SELECT trace_id FROM spans WHERE id = ?)", spanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace spans: %w", err)
+	}
+	defer rows.Close()
+
+	spans, err := scanSpans(rows)
+	if err != nil {
+		return nil, err
+	}
+	return spanAncestors(spanID, spans)
+}
 
-		// Add optional fields if present
-		if tags != nil && len(tags) > 0 {
-			metricMap["tags"] = tags
-		}
+// spanColumns are the span columns read by scanSpans
+const spanColumns = "id, trace_id, parent_id, name, service, start_time, end_time, duration, status, tags, logs, links, env, host, is_finished"
 
-		metrics = append(metrics, metricMap)
+// scanSpans reads rows of spanColumns into complete spans
+func scanSpans(rows *sql.Rows) ([]*models.Span, error) {
+	var spans []*models.Span
+	for rows.Next() {
+		span, err := scanSpan(rows)
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, span)
 	}
 
-	// Check for errors after iteration
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating metric rows: %w", err)
+		return nil, fmt.Errorf("error iterating span rows: %w", err)
 	}
 
-	return metrics, nil
+	return spans, nil
 }
 
-// QueryTraces queries traces from the database based on the given parameters
-func (s *SQLiteStorage) QueryTraces(query *models.QueryParams) ([]map[string]interface{}, error) {
-	// Since traces are collections of spans, we'll query spans and group by trace_id
-	sqlQuery := `
-		SELECT id, trace_id, parent_id, service, name, start_time, duration, status, tags
-		FROM spans
-		WHERE 1=1`
+// scanSpan reads the current row of spanColumns into a complete span
+func scanSpan(rows *sql.Rows) (*models.Span, error) {
+	var (
+		span                          models.Span
+		parentID, status, env, host   sql.NullString
+		tagsJSON, logsJSON, linksJSON sql.NullString
+		endTime                       sql.NullTime
+		duration                      sql.NullInt64
+		isFinished                    sql.NullBool
+	)
+
+	if err := rows.Scan(&span.ID, &span.TraceID, &parentID, &span.Name, &span.Service, &span.StartTime, &endTime,
+		&duration, &status, &tagsJSON, &logsJSON, &linksJSON, &env, &host, &isFinished); err != nil {
+		return nil, fmt.Errorf("failed to scan span row: %w", err)
+	}
+
+	span.ParentID = parentID.String
+	span.Status = models.SpanStatus(status.String)
+	span.Env = env.String
+	span.Host = host.String
+	span.Duration = duration.Int64
+	span.IsFinished = isFinished.Bool
+	if endTime.Valid && !endTime.Time.IsZero() {
+		span.EndTime = endTime.Time
+	}
+
+	for _, field := range []struct {
+		name string
+		data sql.NullString
+		dest interface{}
+	}{
+		{"tags", tagsJSON, &span.Tags},
+		{"logs", logsJSON, &span.Logs},
+		{"links", linksJSON, &span.Links},
+	} {
+		if field.data.String == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(field.data.String), field.dest); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", field.name, err)
+		}
+	}
 
-	// Create args slice for parameterized query
-	args := []interface{}{}
+	return &span, nil
+}
 
-	// Add filters based on query parameters
-	if query.Service != "" {
-		sqlQuery += " AND service = ?"
-		args = append(args, query.Service)
+// streamSpans calls fn for each span read from rows of spanColumns
+func streamSpans(rows *sql.Rows, fn func(span *models.Span) error) error {
+	defer rows.Close()
+
+	for rows.Next() {
+		span, err := scanSpan(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(span); err != nil {
+			return err
+		}
 	}
 
-	if query.Since.IsZero() == false {
-		sqlQuery += " AND start_time >= ?"
-		args = append(args, query.Since)
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating span rows: %w", err)
 	}
+	return nil
+}
 
-	if query.Until.IsZero() == false {
-		sqlQuery += " AND start_time <= ?"
-		args = append(args, query.Until)
+// spanResult converts a span into the map returned by QuerySpans
+func spanResult(span *models.Span) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":          span.ID,
+		"trace_id":    span.TraceID,
+		"start_time":  span.StartTime.Format(time.RFC3339),
+		"service":     span.Service,
+		"name":        span.Name,
+		"duration_ms": span.Duration,
+		"status":      string(span.Status),
 	}
 
-	if query.TraceID != "" {
-		sqlQuery += " AND trace_id = ?"
-		args = append(args, query.TraceID)
+	// Add optional fields if present
+	if span.ParentID != "" {
+		result["parent_id"] = span.ParentID
+	}
+	if !span.EndTime.IsZero() {
+		result["end_time"] = span.EndTime.Format(time.RFC3339)
+	}
+	if span.Env != "" {
+		result["env"] = span.Env
+	}
+	if span.Host != "" {
+		result["host"] = span.Host
+	}
+	if len(span.Tags) > 0 {
+		result["tags"] = span.Tags
+	}
+	if len(span.Logs) > 0 {
+		result["logs"] = span.Logs
+	}
+	if len(span.Links) > 0 {
+		result["links"] = span.Links
 	}
 
-	// Add search filter if provided
-	if query.Search != "" {
-		sqlQuery += " AND (name LIKE ? OR service LIKE ?)"
-		searchTerm := "%" + query.Search + "%"
-		args = append(args, searchTerm, searchTerm)
+	return result
+}
+
+// QuerySpans queries spans from the database based on the given parameters
+func (s *SQLiteStorage) QuerySpans(query *models.QueryParams) (map[string]interface{}, error) {
+	// Build the filters shared by the count and data queries
+	where, whereArgs := spanQueryFilters(query)
+
+	// Execute the count query
+	var totalItems int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM spans"+where, whereArgs...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count spans: %w", err)
 	}
 
-	// Add order by
-	sqlQuery += " ORDER BY start_time DESC"
+	// Build the SQL query for data
+	sqlQuery := "SELECT " + spanColumns + " FROM spans" + where + " ORDER BY start_time DESC"
+
+	page, pageArgs := pageClause(query)
+	sqlQuery += page
+	args := append(whereArgs, pageArgs...)
 
 	// Execute the query
 	rows, err := s.db.Query(sqlQuery, args...)
@@ -705,77 +1887,100 @@ func (s *SQLiteStorage) QueryTraces(query *models.QueryParams) ([]map[string]int
 	}
 	defer rows.Close()
 
-	// Group spans by trace ID
-	traceMap := make(map[string]map[string]interface{})
-	for rows.Next() {
-		var (
-			id        string
-			traceID   string
-			parentID  sql.NullString
-			service   string
-			name      string
-			startTime time.Time
-			duration  int64
-			status    string
-			tagsJSON  string
-		)
+	matched, err := scanSpans(rows)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := rows.Scan(&id, &traceID, &parentID, &service, &name, &startTime, &duration, &status, &tagsJSON); err != nil {
-			return nil, fmt.Errorf("failed to scan span row: %w", err)
-		}
+	spans := make([]map[string]interface{}, 0, len(matched))
+	for _, span := range matched {
+		spans = append(spans, spanResult(span))
+	}
 
-		// Parse the tags
-		var tags map[string]string
-		if tagsJSON != "" {
-			if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
-			}
-		}
+	return map[string]interface{}{
+		"spans":      spans,
+		"pagination": paginationInfo(totalItems, query),
+	}, nil
+}
 
-		// If this is a root span (no parent), create a trace entry
-		if !parentID.Valid || parentID.String == "" {
-			traceMap[traceID] = map[string]interface{}{
-				"id":          traceID,
-				"start_time":  startTime.Format(time.RFC3339),
-				"service":     service,
-				"name":        name,
-				"duration_ms": duration,
-				"status":      status,
-			}
+// StreamSpans calls fn for each span matching the query, oldest first.
+// Rows are read one at a time so large result sets are never buffered.
+// A zero Limit streams every matching row.
+func (s *SQLiteStorage) StreamSpans(query *models.QueryParams, fn func(span *models.Span) error) error {
+	where, args := spanQueryFilters(query)
+	sqlQuery := "SELECT " + spanColumns + " FROM spans" + where + " ORDER BY start_time ASC, id ASC"
 
-			// Add tags to the trace
-			if tags != nil && len(tags) > 0 {
-				traceMap[traceID]["tags"] = tags
-			}
-		}
+	if query.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query spans: %w", err)
+	}
+	return streamSpans(rows, fn)
+}
+
+// spanQueryFilters returns the WHERE clause selecting the spans matching query
+func spanQueryFilters(query *models.QueryParams) (string, []interface{}) {
+	where := " WHERE 1=1"
+	whereArgs := []interface{}{}
+
+	// Add filters based on query parameters
+	if query.Service != "" {
+		where += " AND service = ?"
+		whereArgs = append(whereArgs, query.Service)
+	}
+
+	if query.Since.IsZero() == false {
+		where += " AND start_time >= ?"
+		whereArgs = append(whereArgs, query.Since)
+	}
+
+	if query.Until.IsZero() == false {
+		where += " AND start_time <= ?"
+		whereArgs = append(whereArgs, query.Until)
+	}
+
+	if query.TraceID != "" {
+		where += " AND trace_id = ?"
+		whereArgs = append(whereArgs, query.TraceID)
 	}
 
-	// Convert map to array
-	traces := make([]map[string]interface{}, 0, len(traceMap))
-	for _, trace := range traceMap {
-		traces = append(traces, trace)
+	if query.SpanID != "" {
+		where += " AND id = ?"
+		whereArgs = append(whereArgs, query.SpanID)
 	}
 
-	// Apply limit
-	if query.Limit > 0 && len(traces) > query.Limit {
-		traces = traces[:query.Limit]
+	// Add search filter if provided
+	if query.Search != "" {
+		where += " AND (name LIKE ? OR service LIKE ?)"
+		searchTerm := "%" + query.Search + "%"
+		whereArgs = append(whereArgs, searchTerm, searchTerm)
 	}
 
-	return traces, nil
+	durations, durationArgs := durationClause("duration", query)
+	where += durations
+	whereArgs = append(whereArgs, durationArgs...)
+
+	presence, presenceArgs := tagPresenceClause(query)
+	where += presence
+	whereArgs = append(whereArgs, presenceArgs...)
+
+	return where, whereArgs
 }
 
-// QuerySpans queries spans from the database based on the given parameters
-func (s *SQLiteStorage) QuerySpans(query *models.QueryParams) ([]map[string]interface{}, error) {
-	// Build the SQL query
+// CountSpanErrorsByType counts ERROR spans grouped by their error.type tag.
+// Spans without the tag are reported under "unknown".
+func (s *SQLiteStorage) CountSpanErrorsByType(query *models.QueryParams) ([]map[string]interface{}, error) {
 	sqlQuery := `
-		SELECT id, trace_id, parent_id, service, name, start_time, duration, status, tags
+		SELECT COALESCE(json_extract(tags, '$."error.type"'), 'unknown') AS error_type, COUNT(*) AS count
 		FROM spans
-		WHERE 1=1`
+		WHERE status = ?`
 
-	// Create args slice for parameterized query
-	args := []interface{}{}
+	args := []interface{}{models.SpanStatusError}
 
-	// Add filters based on query parameters
 	if query.Service != "" {
 		sqlQuery += " AND service = ?"
 		args = append(args, query.Service)
@@ -791,93 +1996,34 @@ func (s *SQLiteStorage) QuerySpans(query *models.QueryParams) ([]map[string]inte
 		args = append(args, query.Until)
 	}
 
-	if query.TraceID != "" {
-		sqlQuery += " AND trace_id = ?"
-		args = append(args, query.TraceID)
-	}
-
-	// Add search filter if provided
-	if query.Search != "" {
-		sqlQuery += " AND (name LIKE ? OR service LIKE ?)"
-		searchTerm := "%" + query.Search + "%"
-		args = append(args, searchTerm, searchTerm)
-	}
-
-	// Add order by
-	sqlQuery += " ORDER BY start_time DESC"
-
-	// Add limit
-	if query.Limit > 0 {
-		sqlQuery += " LIMIT ?"
-		args = append(args, query.Limit)
-	} else {
-		// Default limit to prevent massive result sets
-		sqlQuery += " LIMIT 100"
-	}
+	sqlQuery += " GROUP BY error_type ORDER BY count DESC, error_type ASC"
 
-	// Execute the query
 	rows, err := s.db.Query(sqlQuery, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query spans: %w", err)
+		return nil, fmt.Errorf("failed to count span errors: %w", err)
 	}
 	defer rows.Close()
 
-	// Process the results
-	spans := []map[string]interface{}{}
+	results := []map[string]interface{}{}
 	for rows.Next() {
 		var (
-			id        string
-			traceID   string
-			parentID  sql.NullString
-			service   string
-			name      string
-			startTime time.Time
-			duration  int64
-			status    string
-			tagsJSON  string
+			errorType string
+			count     int
 		)
-
-		if err := rows.Scan(&id, &traceID, &parentID, &service, &name, &startTime, &duration, &status, &tagsJSON); err != nil {
-			return nil, fmt.Errorf("failed to scan span row: %w", err)
-		}
-
-		// Parse the tags
-		var tags map[string]string
-		if tagsJSON != "" {
-			if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
-			}
-		}
-
-		// Create the span map
-		spanMap := map[string]interface{}{
-			"id":          id,
-			"trace_id":    traceID,
-			"start_time":  startTime.Format(time.RFC3339),
-			"service":     service,
-			"name":        name,
-			"duration_ms": duration,
-			"status":      status,
-		}
-
-		// Add optional fields if present
-		if parentID.Valid {
-			spanMap["parent_id"] = parentID.String
-		}
-
-		if tags != nil && len(tags) > 0 {
-			spanMap["tags"] = tags
+		if err := rows.Scan(&errorType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan error count row: %w", err)
 		}
-
-		spans = append(spans, spanMap)
+		results = append(results, map[string]interface{}{
+			"error_type": errorType,
+			"count":      count,
+		})
 	}
 
-	// Check for errors after iteration
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating span rows: %w", err)
+		return nil, fmt.Errorf("error iterating error count rows: %w", err)
 	}
 
-	return spans, nil
+	return results, nil
 }
 
 // GetServices returns a list of all unique service names
@@ -916,3 +2062,173 @@ func (s *SQLiteStorage) GetServices() ([]string, error) {
 
 	return services, nil
 }
+
+// GetActiveServices returns the names of the services with stored logs,
+// metrics or spans, the most recently active first, at most limit of them
+// (all if limit is 0 or less)
+func (s *SQLiteStorage) GetActiveServices(limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite reads a negative limit as no limit
+	}
+	rows, err := s.db.Query(`
+		SELECT service FROM (
+			SELECT service, MAX(timestamp) AS last_seen FROM logs GROUP BY service
+			UNION ALL
+			SELECT service, MAX(timestamp) AS last_seen FROM metrics GROUP BY service
+			UNION ALL
+			SELECT service, MAX(start_time) AS last_seen FROM spans GROUP BY service
+		) WHERE service != ''
+		GROUP BY service
+		ORDER BY MAX(last_seen) DESC, service
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active services: %w", err)
+	}
+	return scanServices(rows)
+}
+
+// scanServices reads the service names of rows and closes them
+func scanServices(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	services := []string{}
+	for rows.Next() {
+		var service string
+		if err := rows.Scan(&service); err != nil {
+			return nil, fmt.Errorf("failed to scan service row: %w", err)
+		}
+		services = append(services, service)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating service rows: %w", err)
+	}
+	return services, nil
+}
+
+// deleteStatement is a DELETE run as part of a multi-table delete
+type deleteStatement struct {
+	what string // What the statement deletes, for error messages
+	sql  string
+}
+
+// deleteByServiceStatements remove all telemetry of a service. Dependent
+// histogram and trace rows are deleted before the rows they reference.
+var deleteByServiceStatements = []deleteStatement{
+	{"logs", `DELETE FROM logs WHERE service = ?`},
+	{"histogram data", `
+		DELETE FROM histogram_metrics
+		WHERE metric_id IN (SELECT id FROM metrics WHERE service = ?)`},
+	{"exponential histogram data", `
+		DELETE FROM exponential_histogram_metrics
+		WHERE metric_id IN (SELECT id FROM metrics WHERE service = ?)`},
+	{"metrics", `DELETE FROM metrics WHERE service = ?`},
+	{"traces", `
+		DELETE FROM traces
+		WHERE root_span_id IN (SELECT id FROM spans WHERE service = ?)`},
+	{"spans", `DELETE FROM spans WHERE service = ?`},
+}
+
+// DeleteByService removes every log, metric and span of a service, together
+// with their dependent histogram and trace rows, in a single transaction. It
+// returns the total number of rows deleted across all tables.
+func (s *SQLiteStorage) DeleteByService(service string) (int64, error) {
+	return s.deleteAll(deleteByServiceStatements, service)
+}
+
+// clearAllStatements empty every table, dependent rows first
+var clearAllStatements = []deleteStatement{
+	{"traces", `DELETE FROM traces`},
+	{"spans", `DELETE FROM spans`},
+	{"histogram data", `DELETE FROM histogram_metrics`},
+	{"exponential histogram data", `DELETE FROM exponential_histogram_metrics`},
+	{"metrics", `DELETE FROM metrics`},
+	{"logs", `DELETE FROM logs`},
+}
+
+// ClearAll removes all stored telemetry in a single transaction and returns
+// the total number of rows deleted across all tables
+func (s *SQLiteStorage) ClearAll() (int64, error) {
+	return s.deleteAll(clearAllStatements)
+}
+
+// deleteAll runs delete statements with args in one transaction and returns
+// the total number of rows deleted
+func (s *SQLiteStorage) deleteAll(statements []deleteStatement, args ...interface{}) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var total int64
+	for _, stmt := range statements {
+		res, err := tx.Exec(stmt.sql, args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete %s: %w", stmt.what, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count deleted %s: %w", stmt.what, err)
+		}
+		total += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return total, nil
+}
+
+// GetStats counts the logs matching query by level, the metrics by type and
+// the traces, averaging their durations, with one aggregate query each
+func (s *SQLiteStorage) GetStats(query *models.QueryParams) (*models.Stats, error) {
+	stats := models.NewStats()
+	query = statsQuery(query)
+
+	where, whereArgs := logFilters(query)
+	if err := countGroups(s.db, "SELECT level, COUNT(*) FROM logs"+where+" GROUP BY level", whereArgs, func(level string, count int64) {
+		addLogCount(stats, level, count)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to count logs: %w", err)
+	}
+
+	where, whereArgs = metricQueryFilters(query)
+	if err := countGroups(s.db, "SELECT type, COUNT(*) FROM metrics"+where+" GROUP BY type", whereArgs, func(kind string, count int64) {
+		stats.Metrics.Total += count
+		stats.Metrics.ByType[models.MetricType(kind)] += count
+	}); err != nil {
+		return nil, fmt.Errorf("failed to count metrics: %w", err)
+	}
+
+	where, whereArgs = traceFilters(query)
+	var avgDuration sql.NullFloat64
+	if err := s.db.QueryRow("SELECT COUNT(DISTINCT trace_id), AVG("+traceDuration+") FROM spans"+where, whereArgs...).Scan(&stats.Traces.Total, &avgDuration); err != nil {
+		return nil, fmt.Errorf("failed to count traces: %w", err)
+	}
+	stats.Traces.AvgDurationMs = avgDuration.Float64
+
+	return stats, nil
+}
+
+// countGroups runs sqlQuery, which selects a group and its count on each
+// row, and calls fn for each row
+func countGroups(db *sql.DB, sqlQuery string, args []interface{}, fn func(group string, count int64)) error {
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			group string
+			count int64
+		)
+		if err := rows.Scan(&group, &count); err != nil {
+			return err
+		}
+		fn(group, count)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,1989 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// newTestSQLiteStorage creates a SQLite storage backed by a temporary file
+func newTestSQLiteStorage(t *testing.T) *SQLiteStorage {
+	t.Helper()
+
+	st, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"))
+	if err != nil {
+		t.Fatalf("failed to create SQLite storage: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	return st
+}
+
+// countRows returns the number of rows in a table
+func countRows(t *testing.T, st *SQLiteStorage, table string) int {
+	t.Helper()
+
+	var count int
+	if err := st.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+		t.Fatalf("failed to count %s: %v", table, err)
+	}
+	return count
+}
+
+func TestSQLiteStorage_ApplyRetention(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	now := time.Now().UTC()
+	day := 24 * time.Hour
+
+	// Logs: 1 day and 10 days old
+	for i, age := range []time.Duration{day, 10 * day} {
+		log := models.NewLogEntry("api", "message", models.LogLevelInfo)
+		log.ID = "log-" + string(rune('a'+i))
+		log.Timestamp = now.Add(-age)
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	// Metrics: 10 days and 40 days old
+	for i, age := range []time.Duration{10 * day, 40 * day} {
+		metric := models.NewMetric("cpu", 1, models.MetricTypeGauge, "api")
+		metric.ID = "metric-" + string(rune('a'+i))
+		metric.Timestamp = now.Add(-age)
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+	}
+
+	// Traces: 1 day and 3 days old
+	for i, age := range []time.Duration{day, 3 * day} {
+		root := &models.Span{
+			ID:        "span-" + string(rune('a'+i)),
+			TraceID:   "trace-" + string(rune('a'+i)),
+			Name:      "request",
+			Service:   "api",
+			StartTime: now.Add(-age),
+			Status:    models.SpanStatusOK,
+		}
+		trace := &models.Trace{ID: root.TraceID, Spans: []*models.Span{root}, Root: root}
+		if err := st.SaveTrace(trace); err != nil {
+			t.Fatalf("failed to save trace: %v", err)
+		}
+	}
+
+	policy := RetentionPolicy{
+		Logs:    7 * day,
+		Metrics: 30 * day,
+		Traces:  2 * day,
+	}
+
+	result, err := st.ApplyRetention(policy, now)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if result.Logs != 1 || result.Metrics != 1 || result.Traces != 1 {
+		t.Errorf("expected one row pruned per signal, got %+v", result)
+	}
+
+	if count := countRows(t, st, "logs"); count != 1 {
+		t.Errorf("expected 1 log to remain, got %d", count)
+	}
+	if count := countRows(t, st, "metrics"); count != 1 {
+		t.Errorf("expected 1 metric to remain, got %d", count)
+	}
+	if count := countRows(t, st, "spans"); count != 1 {
+		t.Errorf("expected 1 span to remain, got %d", count)
+	}
+	if count := countRows(t, st, "traces"); count != 1 {
+		t.Errorf("expected 1 trace to remain, got %d", count)
+	}
+}
+
+func TestSQLiteStorage_ApplyRetention_ZeroDisables(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+
+	log := models.NewLogEntry("api", "ancient", models.LogLevelInfo)
+	log.Timestamp = time.Now().UTC().Add(-365 * 24 * time.Hour)
+	if err := st.SaveLog(log); err != nil {
+		t.Fatalf("failed to save log: %v", err)
+	}
+
+	if _, err := st.ApplyRetention(RetentionPolicy{Metrics: time.Hour}, time.Now()); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if count := countRows(t, st, "logs"); count != 1 {
+		t.Errorf("expected logs to be kept when log retention is zero, got %d", count)
+	}
+}
+
+func TestSQLiteStorage_Prune(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	now := time.Now().UTC()
+	cutoff := now.Add(-time.Hour)
+
+	// One old and one fresh row per signal
+	for i, ts := range []time.Time{now.Add(-2 * time.Hour), now} {
+		log := models.NewLogEntry("api", "message", models.LogLevelInfo)
+		log.ID = fmt.Sprintf("log-%d", i)
+		log.Timestamp = ts
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+
+		metric := models.NewMetric("latency", 1, models.MetricTypeHistogram, "api")
+		metric.ID = fmt.Sprintf("metric-%d", i)
+		metric.Timestamp = ts
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+		if _, err := st.db.Exec(`INSERT INTO histogram_metrics (id, metric_id, buckets, sum, count) VALUES (?, ?, '[]', 0, 0)`,
+			fmt.Sprintf("hist-%d", i), metric.ID); err != nil {
+			t.Fatalf("failed to insert histogram data: %v", err)
+		}
+
+		saveTestTrace(t, st, fmt.Sprintf("trace-%d", i), ts, "child")
+	}
+
+	deleted, err := st.Prune(cutoff)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// log + metric + histogram + 2 spans + trace record
+	if deleted != 6 {
+		t.Errorf("expected 6 rows deleted, got %d", deleted)
+	}
+
+	for table, expected := range map[string]int{"logs": 1, "metrics": 1, "histogram_metrics": 1, "spans": 2, "traces": 1} {
+		if count := countRows(t, st, table); count != expected {
+			t.Errorf("expected %d rows in %s, got %d", expected, table, count)
+		}
+	}
+
+	// No histogram data or trace records may outlive what they reference
+	var orphans int
+	st.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM histogram_metrics WHERE metric_id NOT IN (SELECT id FROM metrics)) +
+		       (SELECT COUNT(*) FROM traces WHERE root_span_id NOT IN (SELECT id FROM spans))`).Scan(&orphans)
+	if orphans != 0 {
+		t.Errorf("expected no orphaned rows, got %d", orphans)
+	}
+}
+
+func TestSQLiteStorage_DeleteTracesOlderThan_WholeTraces(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	now := time.Now().UTC()
+	cutoff := now.Add(-time.Hour)
+
+	// A trace that started before the cutoff but whose child ended after it
+	root := &models.Span{ID: "long-root", TraceID: "trace-long", Name: "job", Service: "api",
+		StartTime: now.Add(-3 * time.Hour), EndTime: now.Add(-3 * time.Hour).Add(time.Second), Status: models.SpanStatusOK}
+	child := &models.Span{ID: "long-child", TraceID: "trace-long", ParentID: root.ID, Name: "step", Service: "api",
+		StartTime: now.Add(-2 * time.Hour), EndTime: now.Add(-30 * time.Minute), Status: models.SpanStatusOK}
+	trace := &models.Trace{ID: "trace-long", Spans: []*models.Span{root, child}, Root: root}
+	if err := st.SaveTrace(trace); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+
+	saveTestTrace(t, st, "trace-old", now.Add(-2*time.Hour), "child")
+
+	// A trace record left without spans
+	if _, err := st.db.Exec(`INSERT INTO traces (id, root_span_id, status) VALUES ('trace-gone', 'gone-root', 'ok')`); err != nil {
+		t.Fatalf("failed to insert trace record: %v", err)
+	}
+
+	deleted, err := st.DeleteTracesOlderThan(cutoff)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 spans deleted, got %d", deleted)
+	}
+
+	var traceIDs []string
+	rows, err := st.db.Query(`SELECT id FROM traces UNION SELECT DISTINCT trace_id FROM spans`)
+	if err != nil {
+		t.Fatalf("failed to query traces: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		rows.Scan(&id)
+		traceIDs = append(traceIDs, id)
+	}
+
+	// The long trace is kept whole, the others are removed entirely
+	if len(traceIDs) != 1 || traceIDs[0] != "trace-long" {
+		t.Errorf("expected only trace-long to remain, got %v", traceIDs)
+	}
+	if count := countRows(t, st, "spans"); count != 2 {
+		t.Errorf("expected 2 spans to remain, got %d", count)
+	}
+}
+
+func TestNewSQLiteStorage_WithRetention(t *testing.T) {
+	st, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "pulse-test.db"),
+		WithRetention(RetentionPolicy{Logs: time.Hour}, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create SQLite storage: %v", err)
+	}
+	defer st.Close()
+
+	log := models.NewLogEntry("api", "stale", models.LogLevelInfo)
+	log.Timestamp = time.Now().UTC().Add(-2 * time.Hour)
+	if err := st.SaveLog(log); err != nil {
+		t.Fatalf("failed to save log: %v", err)
+	}
+
+	// The background job should remove the stale log shortly
+	deadline := time.Now().Add(2 * time.Second)
+	for countRows(t, st, "logs") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected background retention to prune the stale log")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// traceSaver is implemented by both SQLite and mock storage
+type traceSaver interface {
+	SaveTrace(trace *models.Trace) error
+}
+
+// saveTestTrace stores a trace made of a root span and children with the given names
+func saveTestTrace(t *testing.T, st traceSaver, traceID string, start time.Time, childNames ...string) {
+	t.Helper()
+
+	root := &models.Span{
+		ID:        traceID + "-root",
+		TraceID:   traceID,
+		Name:      "http_request",
+		Service:   "api",
+		StartTime: start,
+		Duration:  100,
+		Status:    models.SpanStatusOK,
+	}
+	trace := &models.Trace{ID: traceID, Spans: []*models.Span{root}, Root: root, Status: models.SpanStatusOK}
+
+	for i, name := range childNames {
+		trace.AddSpan(&models.Span{
+			ID:        fmt.Sprintf("%s-child-%d", traceID, i),
+			TraceID:   traceID,
+			ParentID:  root.ID,
+			Name:      name,
+			Service:   "api",
+			StartTime: start.Add(time.Duration(i+1) * time.Millisecond),
+			Duration:  10,
+			Status:    models.SpanStatusOK,
+		})
+	}
+
+	if err := st.SaveTrace(trace); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+}
+
+func TestSQLiteStorage_QueryTraces_ContainsSpan(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	now := time.Now().UTC()
+
+	saveTestTrace(t, st, "trace-db", now, "database_query", "render")
+	saveTestTrace(t, st, "trace-cache", now.Add(time.Second), "cache_lookup")
+
+	result, err := st.QueryTraces(&models.QueryParams{ContainsSpan: "database_query"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	traces := result["traces"].([]map[string]interface{})
+
+	if len(traces) != 1 {
+		t.Fatalf("expected 1 trace, got %d", len(traces))
+	}
+	if traces[0]["id"] != "trace-db" {
+		t.Errorf("expected trace-db, got %v", traces[0]["id"])
+	}
+
+	// Without the filter both traces are returned
+	result, err = st.QueryTraces(&models.QueryParams{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	traces = result["traces"].([]map[string]interface{})
+	if len(traces) != 2 {
+		t.Errorf("expected 2 traces, got %d", len(traces))
+	}
+}
+
+// assertTraceDuration checks that trace queries order, filter and pick slow
+// traces by the total duration of whole traces, and by the root span's
+// duration for traces saved span by span
+func assertTraceDuration(t *testing.T, st Storage) {
+	t.Helper()
+	now := time.Now().UTC()
+
+	// Whole traces report their total duration rather than the root's
+	saveTestTrace(t, st, "trace-fast", now)
+	root := &models.Span{ID: "slow-root", TraceID: "trace-slow", Name: "GET /", Service: "api", StartTime: now, Duration: 50}
+	child := &models.Span{ID: "slow-child", TraceID: "trace-slow", ParentID: root.ID, Name: "publish", Service: "api", StartTime: now.Add(40 * time.Millisecond), Duration: 260}
+	slow := &models.Trace{ID: "trace-slow", Spans: []*models.Span{root, child}, Root: root}
+	slow.ComputeDuration()
+	if err := st.SaveTrace(slow); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+
+	// Traces saved span by span fall back to the root span's duration
+	saveTraceDurations(t, st, "worker", 200)
+
+	result, err := st.QueryTraces(&models.QueryParams{OrderBy: "duration", OrderDesc: true})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	traces := result["traces"].([]map[string]interface{})
+	expected := []struct {
+		id       string
+		duration int64
+	}{{"trace-slow", 300}, {"worker-0", 200}, {"trace-fast", 100}}
+	if len(traces) != len(expected) {
+		t.Fatalf("expected %d traces, got %v", len(expected), traces)
+	}
+	for i, trace := range traces {
+		if trace["id"] != expected[i].id || trace["duration_ms"] != expected[i].duration {
+			t.Errorf("expected trace %d to be %s taking %dms, got %v", i, expected[i].id, expected[i].duration, trace)
+		}
+	}
+
+	result, err = st.QueryTraces(&models.QueryParams{OrderBy: "duration"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if traces := result["traces"].([]map[string]interface{}); traces[0]["id"] != "trace-fast" {
+		t.Errorf("expected the fastest trace first, got %v", traces[0]["id"])
+	}
+
+	// The slow trace's root span alone is within the bounds
+	result, err = st.QueryTraces(&models.QueryParams{MinDuration: 250})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if traces := result["traces"].([]map[string]interface{}); len(traces) != 1 || traces[0]["id"] != "trace-slow" {
+		t.Errorf("expected only trace-slow to last 250ms or more, got %v", traces)
+	}
+	result, err = st.QueryTraces(&models.QueryParams{MaxDuration: 250})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if traces := result["traces"].([]map[string]interface{}); len(traces) != 2 {
+		t.Errorf("expected 2 traces to last 250ms or less, got %v", traces)
+	}
+
+	result, err = st.QuerySlowTraces(&models.QueryParams{}, 50)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	assertSlowTraces(t, result, 200, "trace-slow")
+}
+
+func TestSQLiteStorage_QueryTraces_Duration(t *testing.T) {
+	assertTraceDuration(t, newTestSQLiteStorage(t))
+}
+
+// assertTraceStatus checks that trace queries report a whole trace as ERROR
+// when any of its spans failed, whatever the status of its root span
+func assertTraceStatus(t *testing.T, st Storage) {
+	t.Helper()
+	now := time.Now().UTC()
+
+	root := &models.Span{ID: "failed-root", TraceID: "trace-failed", Name: "GET /", Service: "api", StartTime: now, Duration: 50, Status: models.SpanStatusOK}
+	child := &models.Span{ID: "failed-child", TraceID: "trace-failed", ParentID: root.ID, Name: "charge", Service: "billing",
+		StartTime: now.Add(10 * time.Millisecond), Duration: 20, Status: models.SpanStatusError}
+	failed := &models.Trace{ID: "trace-failed", Spans: []*models.Span{root, child}, Root: root}
+	if err := st.SaveTrace(failed); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+	saveTestTrace(t, st, "trace-ok", now.Add(-time.Second), "child")
+
+	result, err := st.QueryTraces(&models.QueryParams{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	traces := result["traces"].([]map[string]interface{})
+	if len(traces) != 2 {
+		t.Fatalf("expected 2 traces, got %v", traces)
+	}
+	for _, trace := range traces {
+		expected := "OK"
+		if trace["id"] == "trace-failed" {
+			expected = "ERROR"
+		}
+		if status := fmt.Sprint(trace["status"]); status != expected {
+			t.Errorf("expected %v to be %s, got %s", trace["id"], expected, status)
+		}
+	}
+}
+
+func TestSQLiteStorage_QueryTraces_Status(t *testing.T) {
+	assertTraceStatus(t, newTestSQLiteStorage(t))
+}
+
+func TestSQLiteStorage_SaveTrace_Batches(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	traceRow := func() (root, status string, duration int64) {
+		t.Helper()
+		if err := st.db.QueryRow("SELECT root_span_id, status, duration FROM traces WHERE id = 'trace-1'").Scan(&root, &status, &duration); err != nil {
+			t.Fatalf("failed to read trace row: %v", err)
+		}
+		return root, status, duration
+	}
+
+	// The first batch holds a failed child whose parent comes later; without
+	// a root there is no trace row yet
+	child := &models.Span{ID: "child", TraceID: "trace-1", ParentID: "root", Name: "charge", Service: "billing",
+		StartTime: start.Add(-50 * time.Millisecond), EndTime: start.Add(100 * time.Millisecond), Duration: 150, Status: models.SpanStatusError}
+	first := &models.Trace{ID: "trace-1", Spans: []*models.Span{child}}
+	first.ComputeStatus()
+	first.ComputeDuration()
+	if err := st.SaveTrace(first); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+	if rows := countRows(t, st, "traces"); rows != 0 {
+		t.Errorf("expected no trace row without a root, got %d", rows)
+	}
+
+	// The second batch brings the root, which succeeded
+	root := &models.Span{ID: "root", TraceID: "trace-1", Name: "POST /checkout", Service: "api",
+		StartTime: start, EndTime: start.Add(300 * time.Millisecond), Duration: 300, Status: models.SpanStatusOK}
+	second := &models.Trace{ID: "trace-1", Spans: []*models.Span{root}, Root: root}
+	second.ComputeStatus()
+	second.ComputeDuration()
+	if err := st.SaveTrace(second); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+	if id, status, duration := traceRow(); id != "root" || status != string(models.SpanStatusError) || duration != 350 {
+		t.Errorf("expected root root, status ERROR and 350ms, got %s, %s and %dms", id, status, duration)
+	}
+
+	// A late batch without the root keeps it and the worse status, and
+	// extends the duration
+	late := &models.Span{ID: "late", TraceID: "trace-1", ParentID: "root", Name: "notify", Service: "mailer",
+		StartTime: start.Add(200 * time.Millisecond), EndTime: start.Add(400 * time.Millisecond), Duration: 200, Status: models.SpanStatusOK}
+	third := &models.Trace{ID: "trace-1", Spans: []*models.Span{late}, Status: models.SpanStatusOK}
+	if err := st.SaveTrace(third); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+	if id, status, duration := traceRow(); id != "root" || status != string(models.SpanStatusError) || duration != 450 {
+		t.Errorf("expected root root, status ERROR and 450ms, got %s, %s and %dms", id, status, duration)
+	}
+}
+
+// assertDurationFilters checks that span and trace queries keep only the
+// records within the requested duration range
+func assertDurationFilters(t *testing.T, st Storage) {
+	t.Helper()
+
+	// Root spans of 10ms to 50ms, and a 100s child span in api-0
+	saveTraceDurations(t, st, "api", 50, 10, 40, 20, 30)
+
+	tests := []struct {
+		min, max int64
+		spans    int
+		traces   []string
+	}{
+		{35, 0, 3, []string{"api-0", "api-2"}},
+		{35, 1000, 2, []string{"api-0", "api-2"}},
+		{0, 20, 2, []string{"api-1", "api-3"}},
+		{0, 0, 6, []string{"api-0", "api-1", "api-2", "api-3", "api-4"}},
+	}
+
+	for _, tt := range tests {
+		query := &models.QueryParams{MinDuration: tt.min, MaxDuration: tt.max}
+
+		spans, err := st.QuerySpans(query)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if n := len(spans["spans"].([]map[string]interface{})); n != tt.spans {
+			t.Errorf("%d-%dms: expected %d spans, got %d", tt.min, tt.max, tt.spans, n)
+		}
+
+		traces, err := st.QueryTraces(query)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		var ids []string
+		for _, trace := range traces["traces"].([]map[string]interface{}) {
+			ids = append(ids, trace["id"].(string))
+		}
+		sort.Strings(ids)
+		if !reflect.DeepEqual(ids, tt.traces) {
+			t.Errorf("%d-%dms: expected traces %v, got %v", tt.min, tt.max, tt.traces, ids)
+		}
+	}
+}
+
+func TestSQLiteStorage_DurationFilters(t *testing.T) {
+	assertDurationFilters(t, newTestSQLiteStorage(t))
+}
+
+// assertTimeBounds checks that log, metric and span queries include both
+// bounds of their time range and that a zero Until leaves them open-ended
+func assertTimeBounds(t *testing.T, st Storage) {
+	t.Helper()
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	for i, offset := range []time.Duration{0, 10 * time.Minute, 20 * time.Minute} {
+		at := base.Add(offset)
+
+		log := models.NewLogEntry("api", fmt.Sprintf("log %d", i), models.LogLevelInfo)
+		log.Timestamp = at
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+
+		metric := models.NewMetric("requests", float64(i), models.MetricTypeGauge, "api")
+		metric.Timestamp = at
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+
+		span := models.NewSpan(fmt.Sprintf("span %d", i), "api", fmt.Sprintf("trace-%d", i))
+		span.StartTime = at
+		if err := st.SaveSpan(span); err != nil {
+			t.Fatalf("failed to save span: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		since, until time.Time
+		expected     int
+	}{
+		{"open-ended", base, time.Time{}, 3},
+		{"bounded", base, base.Add(10 * time.Minute), 2},
+		{"single instant", base.Add(20 * time.Minute), base.Add(20 * time.Minute), 1},
+		{"until only", time.Time{}, base.Add(10 * time.Minute), 2},
+	}
+	for _, tt := range tests {
+		query := &models.QueryParams{Since: tt.since, Until: tt.until, Limit: 100}
+
+		logs, err := st.QueryLogs(query)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", tt.name, err)
+		}
+		if n := len(logs["logs"].([]map[string]interface{})); n != tt.expected {
+			t.Errorf("%s: expected %d logs, got %d", tt.name, tt.expected, n)
+		}
+
+		metrics, err := st.QueryMetrics(query)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", tt.name, err)
+		}
+		if n := len(metrics["metrics"].([]map[string]interface{})); n != tt.expected {
+			t.Errorf("%s: expected %d metrics, got %d", tt.name, tt.expected, n)
+		}
+
+		spans, err := st.QuerySpans(query)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", tt.name, err)
+		}
+		if n := len(spans["spans"].([]map[string]interface{})); n != tt.expected {
+			t.Errorf("%s: expected %d spans, got %d", tt.name, tt.expected, n)
+		}
+	}
+}
+
+func TestSQLiteStorage_TimeBounds(t *testing.T) {
+	assertTimeBounds(t, newTestSQLiteStorage(t))
+}
+
+// assertTraceIDs checks that trace ID queries page through the root spans in
+// the window newest first, ties broken by descending ID, whether the traces
+// were saved whole or span by span
+func assertTraceIDs(t *testing.T, st Storage) {
+	t.Helper()
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	starts := map[string]time.Duration{
+		"trace-a": 0,
+		"trace-b": 10 * time.Minute,
+		"trace-c": 20 * time.Minute,
+		"trace-d": 20 * time.Minute,
+	}
+	for id, offset := range starts {
+		root := models.NewSpan("GET /", "api", id)
+		root.StartTime = base.Add(offset)
+		child := models.NewSpan("query", "db", id)
+		child.StartTime = root.StartTime.Add(time.Second)
+		child.SetParent(root.ID)
+		for _, span := range []*models.Span{root, child} {
+			if err := st.SaveSpan(span); err != nil {
+				t.Fatalf("failed to save span: %v", err)
+			}
+		}
+	}
+	trace, root := models.NewTrace("GET /whole", "api")
+	root.StartTime = base.Add(30 * time.Minute)
+	if err := st.SaveTrace(trace); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+
+	expected := []string{trace.ID, "trace-d", "trace-c", "trace-b", "trace-a"}
+	var ids []string
+	for offset := 0; offset < len(expected); offset += 2 {
+		result, err := st.QueryTraceIDs(&models.QueryParams{Since: base, Limit: 2, Offset: offset})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if total := result["pagination"].(map[string]interface{})["total_items"]; total != len(expected) {
+			t.Errorf("expected total_items %d, got %v", len(expected), total)
+		}
+		for _, record := range result["traces"].([]map[string]interface{}) {
+			ids = append(ids, record["id"].(string))
+			if _, err := time.Parse(time.RFC3339Nano, record["start_time"].(string)); err != nil {
+				t.Errorf("expected an RFC 3339 start time, got %v", record["start_time"])
+			}
+		}
+	}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected trace IDs %v, got %v", expected, ids)
+	}
+
+	result, err := st.QueryTraceIDs(&models.QueryParams{Since: base.Add(10 * time.Minute), Until: base.Add(20 * time.Minute), Limit: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(result["traces"].([]map[string]interface{})); n != 3 {
+		t.Errorf("expected 3 traces in the window, got %d", n)
+	}
+}
+
+func TestSQLiteStorage_TraceIDs(t *testing.T) {
+	assertTraceIDs(t, newTestSQLiteStorage(t))
+}
+
+// assertTraceSamples checks that trace sample queries page through the
+// traces in the window newest first, with the duration running from the
+// earliest span start to the latest span end and the failure of any span
+func assertTraceSamples(t *testing.T, st Storage) {
+	t.Helper()
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour).Add(123456789 * time.Nanosecond)
+
+	// A 100ms root with a child ending 30ms after it, known only by its
+	// duration, which fails
+	root := models.NewSpan("GET /checkout", "api", "trace-a")
+	root.StartTime = base
+	root.EndTime = base.Add(100 * time.Millisecond)
+	root.Duration = 100
+	child := models.NewSpan("charge", "payments", "trace-a").SetParent(root.ID)
+	child.StartTime = base.Add(50 * time.Millisecond)
+	child.Duration = 80
+	child.Status = models.SpanStatusError
+	later := models.NewSpan("GET /", "api", "trace-b")
+	later.StartTime = base.Add(time.Minute)
+	later.EndTime = later.StartTime.Add(40 * time.Millisecond)
+	later.Duration = 40
+	for _, span := range []*models.Span{root, child, later} {
+		if err := st.SaveSpan(span); err != nil {
+			t.Fatalf("failed to save span: %v", err)
+		}
+	}
+
+	expected := []map[string]interface{}{
+		{"id": "trace-b", "duration": int64(40), "error": false},
+		{"id": "trace-a", "duration": int64(130), "error": true},
+	}
+	for offset, want := range expected {
+		result, err := st.QueryTraceSamples(&models.QueryParams{Since: base, Limit: 1, Offset: offset})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if total := result["pagination"].(map[string]interface{})["total_items"]; total != len(expected) {
+			t.Errorf("expected total_items %d, got %v", len(expected), total)
+		}
+		records := result["traces"].([]map[string]interface{})
+		if len(records) != 1 {
+			t.Fatalf("expected 1 trace at offset %d, got %d", offset, len(records))
+		}
+		for key, value := range want {
+			if records[0][key] != value {
+				t.Errorf("expected %s %v at offset %d, got %v", key, value, offset, records[0][key])
+			}
+		}
+	}
+}
+
+func TestSQLiteStorage_TraceSamples(t *testing.T) {
+	assertTraceSamples(t, newTestSQLiteStorage(t))
+}
+
+// assertLevelOrder checks that logs ordered by level are ordered by
+// severity rather than alphabetically, newest first within a level
+func assertLevelOrder(t *testing.T, st Storage) {
+	t.Helper()
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	levels := []models.LogLevel{
+		models.LogLevelInfo, models.LogLevelFatal, models.LogLevelDebug,
+		models.LogLevelError, models.LogLevelWarning, models.LogLevelError,
+	}
+	for i, level := range levels {
+		log := models.NewLogEntry("api", fmt.Sprintf("log %d", i), level)
+		log.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	tests := []struct {
+		desc     bool
+		expected []string
+	}{
+		{true, []string{"log 1", "log 5", "log 3", "log 4", "log 0", "log 2"}},
+		{false, []string{"log 2", "log 0", "log 4", "log 5", "log 3", "log 1"}},
+	}
+	for _, tt := range tests {
+		result, err := st.QueryLogs(&models.QueryParams{OrderBy: "level", OrderDesc: tt.desc, Limit: 10})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		var messages []string
+		for _, log := range result["logs"].([]map[string]interface{}) {
+			messages = append(messages, log["message"].(string))
+		}
+		if !reflect.DeepEqual(messages, tt.expected) {
+			t.Errorf("desc=%v: expected %v, got %v", tt.desc, tt.expected, messages)
+		}
+	}
+}
+
+func TestSQLiteStorage_LevelOrder(t *testing.T) {
+	assertLevelOrder(t, newTestSQLiteStorage(t))
+}
+
+// assertTagPresenceFilters checks that log, metric and span queries keep
+// only the records with the HasTags keys and without the MissingTags keys,
+// whatever their values
+func assertTagPresenceFilters(t *testing.T, st Storage) {
+	t.Helper()
+
+	// One record of each signal per region tag value, and one without it
+	for i, region := range []string{"eu", "", "us"} {
+		log := models.NewLogEntry("api", fmt.Sprintf("log-%d", i), models.LogLevelInfo)
+		metric := models.NewMetric("requests", float64(i), models.MetricTypeCounter, "api")
+		span := models.NewSpan(fmt.Sprintf("span-%d", i), "api", fmt.Sprintf("trace-%d", i))
+		if region != "" {
+			log.AddTag("region", region)
+			metric.AddTag("region", region)
+			span.AddTag("region", region)
+		}
+		log.AddTag("zone", "a")
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+		if err := st.SaveSpan(span); err != nil {
+			t.Fatalf("failed to save span: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query models.QueryParams
+		count int
+	}{
+		{"has region", models.QueryParams{HasTags: []string{"region"}}, 2},
+		{"missing region", models.QueryParams{MissingTags: []string{"region"}}, 1},
+		{"has unknown", models.QueryParams{HasTags: []string{"unknown"}}, 0},
+		{"missing unknown", models.QueryParams{MissingTags: []string{"unknown"}}, 3},
+		{"has and missing region", models.QueryParams{HasTags: []string{"region"}, MissingTags: []string{"region"}}, 0},
+	}
+
+	for _, tt := range tests {
+		logs, err := st.QueryLogs(&tt.query)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", tt.name, err)
+		}
+		metrics, err := st.QueryMetrics(&tt.query)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", tt.name, err)
+		}
+		spans, err := st.QuerySpans(&tt.query)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", tt.name, err)
+		}
+
+		counts := map[string]int{
+			"logs":    len(logs["logs"].([]map[string]interface{})),
+			"metrics": len(metrics["metrics"].([]map[string]interface{})),
+			"spans":   len(spans["spans"].([]map[string]interface{})),
+		}
+		for signal, count := range counts {
+			if count != tt.count {
+				t.Errorf("%s: expected %d %s, got %d", tt.name, tt.count, signal, count)
+			}
+		}
+	}
+
+	// Logs have the zone tag, metrics and spans don't
+	logs, err := st.QueryLogs(&models.QueryParams{HasTags: []string{"zone"}, MissingTags: []string{"region"}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(logs["logs"].([]map[string]interface{})); n != 1 {
+		t.Errorf("expected 1 log with a zone but no region, got %d", n)
+	}
+}
+
+func TestSQLiteStorage_TagPresenceFilters(t *testing.T) {
+	assertTagPresenceFilters(t, newTestSQLiteStorage(t))
+}
+
+// assertUpdateLogTags checks that UpdateLogTags merges tags into the logs
+// matching its query and leaves the other logs alone
+func assertUpdateLogTags(t *testing.T, st Storage) {
+	t.Helper()
+
+	for i, service := range []string{"api", "api", "billing"} {
+		log := models.NewLogEntry(service, fmt.Sprintf("message %d", i), models.LogLevelError)
+		log.ID = fmt.Sprintf("log-%d", i)
+		log.AddTag("region", "eu")
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	updated, err := st.UpdateLogTags(&models.QueryParams{Service: "api"}, map[string]string{"incident": "INC-123", "region": "us"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("expected 2 logs updated, got %d", updated)
+	}
+
+	result, err := st.QueryLogs(&models.QueryParams{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := map[string]map[string]string{
+		"log-0": {"incident": "INC-123", "region": "us"},
+		"log-1": {"incident": "INC-123", "region": "us"},
+		"log-2": {"region": "eu"},
+	}
+	for _, log := range result["logs"].([]map[string]interface{}) {
+		id := log["id"].(string)
+		if tags := log["tags"]; !reflect.DeepEqual(tags, expected[id]) {
+			t.Errorf("expected %s to have tags %v, got %v", id, expected[id], tags)
+		}
+	}
+
+	// Updated tags can be filtered on
+	tagged, err := st.QueryLogs(&models.QueryParams{Filters: map[string]string{"incident": "INC-123"}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(tagged["logs"].([]map[string]interface{})); n != 2 {
+		t.Errorf("expected 2 logs with the incident tag, got %d", n)
+	}
+}
+
+func TestSQLiteStorage_UpdateLogTags(t *testing.T) {
+	assertUpdateLogTags(t, newTestSQLiteStorage(t))
+}
+
+// assertUpdateLogTagsMaxTags checks that UpdateLogTags keeps logs within the
+// tag limit, updating existing keys and adding new ones in sorted key order
+func assertUpdateLogTagsMaxTags(t *testing.T, st Storage) {
+	t.Helper()
+	defer models.SetMaxTags(2)()
+
+	log := models.NewLogEntry("api", "message", models.LogLevelError).AddTag("c", "1")
+	log.ID = "log-0"
+	if err := st.SaveLog(log); err != nil {
+		t.Fatalf("failed to save log: %v", err)
+	}
+
+	updated, err := st.UpdateLogTags(&models.QueryParams{Service: "api"}, map[string]string{"d": "2", "c": "2", "b": "2"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("expected 1 log updated, got %d", updated)
+	}
+
+	result, err := st.QueryLogs(&models.QueryParams{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := map[string]string{"b": "2", "c": "2"}
+	if tags := result["logs"].([]map[string]interface{})[0]["tags"]; !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, tags)
+	}
+}
+
+func TestSQLiteStorage_UpdateLogTagsMaxTags(t *testing.T) {
+	assertUpdateLogTagsMaxTags(t, newTestSQLiteStorage(t))
+}
+
+// assertGetStats checks that GetStats counts logs by level, metrics by type
+// and traces with their mean duration, ignoring the level filters of the
+// query but applying the others
+func assertGetStats(t *testing.T, st Storage) {
+	t.Helper()
+
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	for i, level := range []models.LogLevel{models.LogLevelInfo, models.LogLevelInfo, models.LogLevelError, "VERBOSE"} {
+		log := models.NewLogEntry("api", "hello", level)
+		log.ID = fmt.Sprintf("log-%d", i)
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+	for i, metric := range []*models.Metric{
+		models.NewMetric("http.requests", 1, models.MetricTypeCounter, "api"),
+		models.NewMetric("http.requests", 2, models.MetricTypeCounter, "api"),
+		models.NewMetric("queue.depth", 7, models.MetricTypeGauge, "worker"),
+	} {
+		metric.ID = fmt.Sprintf("metric-%d", i)
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+	}
+	for i, duration := range []int64{100, 300} {
+		span := &models.Span{ID: fmt.Sprintf("span-%d", i), TraceID: fmt.Sprintf("trace-%d", i), Name: "GET /", Service: "api", StartTime: start, EndTime: start.Add(time.Duration(duration) * time.Millisecond), Duration: duration, Status: models.SpanStatusOK}
+		if err := st.SaveSpan(span); err != nil {
+			t.Fatalf("failed to save span: %v", err)
+		}
+	}
+
+	stats, err := st.GetStats(&models.QueryParams{Level: string(models.LogLevelError)})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if stats.Logs.Total != 4 || stats.Logs.ByLevel[models.LogLevelInfo] != 2 || stats.Logs.ByLevel[models.LogLevelError] != 1 {
+		t.Errorf("expected 4 logs, 2 INFO and 1 ERROR, got %+v", stats.Logs)
+	}
+	if _, ok := stats.Logs.ByLevel["VERBOSE"]; ok {
+		t.Errorf("expected only standard levels to be reported, got %v", stats.Logs.ByLevel)
+	}
+	if stats.Metrics.Total != 3 || stats.Metrics.ByType[models.MetricTypeCounter] != 2 || stats.Metrics.ByType[models.MetricTypeGauge] != 1 {
+		t.Errorf("expected 2 counters and 1 gauge, got %+v", stats.Metrics)
+	}
+	if stats.Traces.Total != 2 || stats.Traces.AvgDurationMs != 200 {
+		t.Errorf("expected 2 traces averaging 200ms, got %+v", stats.Traces)
+	}
+
+	stats, err = st.GetStats(&models.QueryParams{Service: "worker"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if stats.Logs.Total != 0 || stats.Metrics.Total != 1 || stats.Traces.Total != 0 || stats.Traces.AvgDurationMs != 0 {
+		t.Errorf("expected only the worker metric, got %+v", stats)
+	}
+}
+
+func TestSQLiteStorage_GetStats(t *testing.T) {
+	assertGetStats(t, newTestSQLiteStorage(t))
+}
+
+// assertGetTrace checks that GetTrace returns every span of a trace, with
+// tags, logs and links, parents before their children
+func assertGetTrace(t *testing.T, st Storage) {
+	t.Helper()
+
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	root := &models.Span{ID: "root", TraceID: "trace-1", Name: "GET /orders", Service: "api", StartTime: start, Duration: 120, Status: models.SpanStatusOK}
+	query := &models.Span{ID: "query", TraceID: "trace-1", ParentID: "root", Name: "SELECT orders", Service: "db", StartTime: start.Add(10 * time.Millisecond), Duration: 30, Status: models.SpanStatusError}
+	query.AddTag("db.statement", "SELECT * FROM orders")
+	query.Logs = []models.SpanLog{{Timestamp: start.Add(20 * time.Millisecond), Fields: map[string]string{"event": "retry"}}}
+	render := &models.Span{ID: "render", TraceID: "trace-1", ParentID: "root", Name: "render", Service: "api", StartTime: start.Add(50 * time.Millisecond), Duration: 100, Status: models.SpanStatusOK}
+	render.AddLink("trace-2", "producer", nil)
+	other := &models.Span{ID: "other", TraceID: "trace-2", Name: "GET /", Service: "api", StartTime: start, Duration: 5}
+
+	// Children saved before their parent
+	for _, span := range []*models.Span{render, query, root, other} {
+		if err := st.SaveSpan(span); err != nil {
+			t.Fatalf("failed to save span: %v", err)
+		}
+	}
+
+	trace, err := st.GetTrace("trace-1")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if trace.ID != "trace-1" || trace.Root == nil || trace.Root.ID != "root" {
+		t.Fatalf("expected trace-1 rooted at root, got %+v", trace)
+	}
+
+	var ids []string
+	for _, span := range trace.Spans {
+		ids = append(ids, span.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"root", "query", "render"}) {
+		t.Errorf("expected spans [root query render], got %v", ids)
+	}
+	if trace.Status != models.SpanStatusError || trace.Duration != 150 {
+		t.Errorf("expected an ERROR trace of 150ms, got %s and %d", trace.Status, trace.Duration)
+	}
+
+	loaded := trace.Spans[1]
+	if loaded.ParentID != "root" || loaded.Tags["db.statement"] != "SELECT * FROM orders" || loaded.Duration != 30 {
+		t.Errorf("expected the query span with its tags, got %+v", loaded)
+	}
+	if len(loaded.Logs) != 1 || loaded.Logs[0].Fields["event"] != "retry" || !loaded.Logs[0].Timestamp.Equal(query.Logs[0].Timestamp) {
+		t.Errorf("expected the query span's log, got %+v", loaded.Logs)
+	}
+	if links := trace.Spans[2].Links; len(links) != 1 || links[0].SpanID != "producer" {
+		t.Errorf("expected the render span's link, got %+v", links)
+	}
+
+	if _, err := st.GetTrace("missing"); !errors.Is(err, ErrTraceNotFound) {
+		t.Errorf("expected ErrTraceNotFound, got %v", err)
+	}
+}
+
+func TestSQLiteStorage_GetTrace(t *testing.T) {
+	assertGetTrace(t, newTestSQLiteStorage(t))
+}
+
+// assertQuerySpansDetails checks that QuerySpans returns a span's logs, end
+// time, env and host along with its other fields
+func assertQuerySpansDetails(t *testing.T, st Storage) {
+	t.Helper()
+
+	start := time.Now().UTC().Truncate(time.Second)
+	span := &models.Span{ID: "checkout", TraceID: "trace-1", Name: "POST /checkout", Service: "api", StartTime: start,
+		EndTime: start.Add(2 * time.Second), Duration: 2000, Status: models.SpanStatusOK, Env: "prod", Host: "web-1"}
+	span.Logs = []models.SpanLog{
+		{Timestamp: start.Add(time.Second), Fields: map[string]string{"event": "cart loaded"}},
+		{Timestamp: start.Add(1500 * time.Millisecond), Fields: map[string]string{"event": "payment sent", "provider": "stripe"}},
+	}
+	if err := st.SaveSpan(span); err != nil {
+		t.Fatalf("failed to save span: %v", err)
+	}
+
+	result, err := st.QuerySpans(&models.QueryParams{SpanID: "checkout", Limit: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	spans := result["spans"].([]map[string]interface{})
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	got := spans[0]
+	if got["env"] != "prod" || got["host"] != "web-1" {
+		t.Errorf("expected env prod and host web-1, got %v and %v", got["env"], got["host"])
+	}
+	if got["end_time"] != span.EndTime.Format(time.RFC3339) {
+		t.Errorf("expected end_time %s, got %v", span.EndTime.Format(time.RFC3339), got["end_time"])
+	}
+
+	logs, ok := got["logs"].([]models.SpanLog)
+	if !ok || len(logs) != 2 {
+		t.Fatalf("expected 2 logs, got %#v", got["logs"])
+	}
+	for i, log := range logs {
+		if !log.Timestamp.Equal(span.Logs[i].Timestamp) || !reflect.DeepEqual(log.Fields, span.Logs[i].Fields) {
+			t.Errorf("log %d: expected %+v, got %+v", i, span.Logs[i], log)
+		}
+	}
+}
+
+func TestSQLiteStorage_QuerySpansDetails(t *testing.T) {
+	assertQuerySpansDetails(t, newTestSQLiteStorage(t))
+}
+
+func TestSQLiteStorage_QueryPagination(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	base := time.Now().UTC().Add(-time.Hour)
+
+	saveTestMetrics(t, st, "requests", base, time.Second, nil, 1, 2, 3, 4, 5)
+	for i := 0; i < 5; i++ {
+		saveTestTrace(t, st, fmt.Sprintf("trace-%d", i), base.Add(time.Duration(i)*time.Second), "render")
+	}
+
+	// Newest first, so the second page of two skips the two newest records
+	query := &models.QueryParams{Limit: 2, Offset: 2}
+
+	metrics, err := st.QueryMetrics(query)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	traces, err := st.QueryTraces(query)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	spans, err := st.QuerySpans(query)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	tests := []struct {
+		result     map[string]interface{}
+		key        string
+		totalItems int
+		firstField string
+		firstValue interface{}
+	}{
+		{metrics, "metrics", 5, "value", 3.0},
+		{traces, "traces", 5, "id", "trace-2"},
+		{spans, "spans", 10, "id", "trace-3-child-0"},
+	}
+
+	for _, tt := range tests {
+		records := tt.result[tt.key].([]map[string]interface{})
+		if len(records) != 2 {
+			t.Fatalf("%s: expected 2 records, got %d", tt.key, len(records))
+		}
+		if records[0][tt.firstField] != tt.firstValue {
+			t.Errorf("%s: expected first %s %v, got %v", tt.key, tt.firstField, tt.firstValue, records[0][tt.firstField])
+		}
+
+		pagination := tt.result["pagination"].(map[string]interface{})
+		if pagination["total_items"] != tt.totalItems {
+			t.Errorf("%s: expected total_items %d, got %v", tt.key, tt.totalItems, pagination["total_items"])
+		}
+		if pagination["total_pages"] != (tt.totalItems+1)/2 {
+			t.Errorf("%s: expected total_pages %d, got %v", tt.key, (tt.totalItems+1)/2, pagination["total_pages"])
+		}
+		if pagination["offset"] != 2 {
+			t.Errorf("%s: expected offset 2, got %v", tt.key, pagination["offset"])
+		}
+	}
+}
+
+func TestSQLiteStorage_QueryLogs_MinLevel(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+
+	levels := []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo, models.LogLevelWarning, models.LogLevelError, models.LogLevelFatal}
+	for i, level := range levels {
+		log := models.NewLogEntry("api", "message", level)
+		log.ID = fmt.Sprintf("log-%d", i)
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	tests := []struct {
+		minLevel string
+		count    int
+	}{
+		{"DEBUG", 5},
+		{"WARNING", 3},
+		{"FATAL", 1},
+		{"AUDIT", 0},
+	}
+
+	for _, tt := range tests {
+		result, err := st.QueryLogs(&models.QueryParams{MinLevel: tt.minLevel, Limit: 100})
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", tt.minLevel, err)
+		}
+
+		pagination := result["pagination"].(map[string]interface{})
+		if pagination["total_items"] != tt.count {
+			t.Errorf("%s: expected total_items %d, got %v", tt.minLevel, tt.count, pagination["total_items"])
+		}
+		for _, log := range result["logs"].([]map[string]interface{}) {
+			if models.LogLevel(fmt.Sprint(log["level"])).Severity() < models.LogLevel(tt.minLevel).Severity() {
+				t.Errorf("%s: unexpected %v log", tt.minLevel, log["level"])
+			}
+		}
+	}
+}
+
+// assertLogCursorPages pages through logs of st by cursor while newer logs
+// arrive, asserting each log is returned once in the newest first order
+func assertLogCursorPages(t *testing.T, st Storage) {
+	t.Helper()
+	base := time.Now().UTC().Add(-30 * time.Minute)
+
+	// log-1 and log-2 share a timestamp and are ordered by ID
+	for i, offset := range []time.Duration{0, time.Second, time.Second, 3 * time.Second, 4 * time.Second} {
+		log := models.NewLogEntry("api", "message", models.LogLevelInfo)
+		log.ID = fmt.Sprintf("log-%d", i)
+		log.Timestamp = base.Add(offset)
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	var ids []string
+	query := &models.QueryParams{Service: "api", Limit: 2}
+	for page := 0; ; page++ {
+		result, err := st.QueryLogs(query)
+		if err != nil {
+			t.Fatalf("page %d: expected no error, got: %v", page, err)
+		}
+		for _, log := range result["logs"].([]map[string]interface{}) {
+			ids = append(ids, log["id"].(string))
+		}
+
+		next, ok := result["pagination"].(map[string]interface{})["next_cursor"].(string)
+		if !ok {
+			break
+		}
+		query.Cursor = next
+
+		// Newer logs would shift the pages of offset pagination
+		log := models.NewLogEntry("api", "arrived between pages", models.LogLevelInfo)
+		log.ID = fmt.Sprintf("log-new-%d", page)
+		log.Timestamp = base.Add(10 * time.Second)
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	if expected := []string{"log-4", "log-3", "log-2", "log-1", "log-0"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected %v across the pages, got %v", expected, ids)
+	}
+
+	if _, err := st.QueryLogs(&models.QueryParams{Cursor: query.Cursor, OrderBy: "message"}); err != ErrCursorOrder {
+		t.Errorf("expected ErrCursorOrder with order_by, got %v", err)
+	}
+	if _, err := st.QueryLogs(&models.QueryParams{Cursor: "not a cursor"}); err == nil {
+		t.Errorf("expected an error for an invalid cursor")
+	}
+}
+
+func TestSQLiteStorage_QueryLogs_Cursor(t *testing.T) {
+	assertLogCursorPages(t, newTestSQLiteStorage(t))
+}
+
+func TestSQLiteStorage_QueryLogs_TagFilters(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	now := time.Now().UTC()
+
+	for i, tags := range []map[string]string{
+		{"region": "us-west", "tier": "gold"},
+		{"region": "us-west", "tier": "free"},
+		{"region": "eu", "tier": "gold"},
+		{},
+	} {
+		log := models.NewLogEntry("api", "message", models.LogLevelInfo)
+		log.ID = fmt.Sprintf("log-%d", i)
+		log.Timestamp = now.Add(-time.Duration(i) * time.Second)
+		for k, v := range tags {
+			log.AddTag(k, v)
+		}
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	tests := []struct {
+		filters map[string]string
+		ids     []string
+	}{
+		{map[string]string{"region": "us-west"}, []string{"log-0", "log-1"}},
+		{map[string]string{"region": "us-west", "tier": "gold"}, []string{"log-0"}},
+		{map[string]string{"region": "eu", "tier": "free"}, nil},
+		{map[string]string{"datacenter": "dc1"}, nil},
+		{map[string]string{"region": "' OR 1=1 --"}, nil},
+	}
+
+	for _, tt := range tests {
+		result, err := st.QueryLogs(&models.QueryParams{Filters: tt.filters, Limit: 1})
+		if err != nil {
+			t.Fatalf("%v: expected no error, got: %v", tt.filters, err)
+		}
+
+		// Pagination counts only matching logs
+		pagination := result["pagination"].(map[string]interface{})
+		if pagination["total_items"] != len(tt.ids) {
+			t.Errorf("%v: expected total_items %d, got %v", tt.filters, len(tt.ids), pagination["total_items"])
+		}
+
+		logs := result["logs"].([]map[string]interface{})
+		if len(tt.ids) == 0 {
+			if len(logs) != 0 {
+				t.Errorf("%v: expected no logs, got %d", tt.filters, len(logs))
+			}
+			continue
+		}
+		if len(logs) != 1 || logs[0]["id"] != tt.ids[0] {
+			t.Errorf("%v: expected first log %s, got %v", tt.filters, tt.ids[0], logs)
+		}
+	}
+}
+
+// spanSaver is implemented by both SQLite and mock storage
+type spanSaver interface {
+	SaveSpan(span *models.Span) error
+}
+
+// saveErrorSpans stores ERROR spans tagged with the given error types (empty means untagged)
+func saveErrorSpans(t *testing.T, st spanSaver, service string, errorTypes ...string) {
+	t.Helper()
+
+	for i, errorType := range errorTypes {
+		span := models.NewSpan("call", service, "trace-"+service)
+		span.ID = fmt.Sprintf("%s-err-%d", service, i)
+		span.SetStatus(models.SpanStatusError)
+		if errorType != "" {
+			span.AddTag("error.type", errorType)
+		}
+		if err := st.SaveSpan(span); err != nil {
+			t.Fatalf("failed to save span: %v", err)
+		}
+	}
+}
+
+// assertErrorCounts checks grouped error counts in order
+func assertErrorCounts(t *testing.T, counts []map[string]interface{}, expected [][2]interface{}) {
+	t.Helper()
+
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %d groups, got %d: %v", len(expected), len(counts), counts)
+	}
+	for i, exp := range expected {
+		if counts[i]["error_type"] != exp[0] || counts[i]["count"] != exp[1] {
+			t.Errorf("expected group %d to be %v=%v, got %v=%v", i, exp[0], exp[1], counts[i]["error_type"], counts[i]["count"])
+		}
+	}
+}
+
+// saveTraceDurations stores a root span lasting each of durations, with the
+// trace IDs service-0, service-1, ... and a slow child span in the first trace
+func saveTraceDurations(t *testing.T, st spanSaver, service string, durations ...int64) {
+	t.Helper()
+
+	for i, duration := range durations {
+		span := models.NewSpan("GET /", service, fmt.Sprintf("%s-%d", service, i))
+		span.Duration = duration
+		if err := st.SaveSpan(span); err != nil {
+			t.Fatalf("failed to save span: %v", err)
+		}
+		if i == 0 {
+			// Child spans don't count towards the trace durations
+			child := models.NewSpan("db.query", service, span.TraceID).SetParent(span.ID)
+			child.Duration = 100000
+			if err := st.SaveSpan(child); err != nil {
+				t.Fatalf("failed to save span: %v", err)
+			}
+		}
+	}
+}
+
+// assertSlowTraces checks a slow trace query result against the expected
+// threshold and trace IDs, slowest first
+func assertSlowTraces(t *testing.T, result map[string]interface{}, threshold int64, ids ...string) {
+	t.Helper()
+
+	if result["threshold_ms"] != threshold {
+		t.Errorf("expected threshold %d, got %v", threshold, result["threshold_ms"])
+	}
+	traces := result["traces"].([]map[string]interface{})
+	if len(traces) != len(ids) {
+		t.Fatalf("expected %d traces, got %v", len(ids), traces)
+	}
+	for i, trace := range traces {
+		if trace["id"] != ids[i] {
+			t.Errorf("expected trace %d to be %s, got %v", i, ids[i], trace["id"])
+		}
+	}
+}
+
+func TestSQLiteStorage_QuerySlowTraces(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+
+	// api traces last 10ms to 200ms; the billing trace is slower than all
+	var durations []int64
+	for i := int64(1); i <= 20; i++ {
+		durations = append(durations, i*10)
+	}
+	saveTraceDurations(t, st, "api", durations...)
+	saveTraceDurations(t, st, "billing", 1000)
+
+	result, err := st.QuerySlowTraces(&models.QueryParams{Service: "api"}, 90)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	assertSlowTraces(t, result, 180, "api-19", "api-18")
+
+	result, err = st.QuerySlowTraces(&models.QueryParams{}, 95)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	assertSlowTraces(t, result, 200, "billing-0")
+
+	result, err = st.QuerySlowTraces(&models.QueryParams{Service: "checkout"}, 95)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	assertSlowTraces(t, result, 0)
+
+	if _, err := st.QuerySlowTraces(&models.QueryParams{}, 100); err == nil {
+		t.Errorf("expected an error for the 100th percentile")
+	}
+}
+
+func TestSQLiteStorage_CountSpanErrorsByType(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+
+	saveErrorSpans(t, st, "api", "timeout", "timeout", "connection_refused", "timeout", "")
+	saveErrorSpans(t, st, "billing", "timeout")
+
+	// An OK span with an error.type tag must not be counted
+	ok := models.NewSpan("call", "api", "trace-ok")
+	ok.AddTag("error.type", "timeout")
+	if err := st.SaveSpan(ok); err != nil {
+		t.Fatalf("failed to save span: %v", err)
+	}
+
+	counts, err := st.CountSpanErrorsByType(&models.QueryParams{Service: "api"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	assertErrorCounts(t, counts, [][2]interface{}{
+		{"timeout", 3},
+		{"connection_refused", 1},
+		{"unknown", 1},
+	})
+}
+
+func TestSQLiteStorage_StreamMetrics(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+	base := time.Now().UTC().Add(-time.Hour)
+
+	// Saved newest first to check streaming order
+	for i := 3; i >= 0; i-- {
+		name := "api.latency"
+		if i == 2 {
+			name = "api.errors"
+		}
+		metric := models.NewMetric(name, float64(i), models.MetricTypeGauge, "payment-api")
+		metric.ID = fmt.Sprintf("metric-%d", i)
+		metric.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		metric.AddTag("region", "eu")
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+	}
+
+	var values []float64
+	err := st.StreamMetrics(&models.QueryParams{Name: "api.latency", Service: "payment-api"}, func(metric *models.Metric) error {
+		if metric.Tags["region"] != "eu" {
+			t.Errorf("expected tag region=eu, got %v", metric.Tags)
+		}
+		values = append(values, metric.Value)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to stream metrics: %v", err)
+	}
+
+	expected := []float64{0, 1, 3}
+	if fmt.Sprint(values) != fmt.Sprint(expected) {
+		t.Errorf("expected values %v, got %v", expected, values)
+	}
+
+	// Callback errors stop the stream
+	calls := 0
+	stopErr := fmt.Errorf("stop")
+	err = st.StreamMetrics(&models.QueryParams{}, func(metric *models.Metric) error {
+		calls++
+		return stopErr
+	})
+	if err != stopErr || calls != 1 {
+		t.Errorf("expected stream to stop after first row, got err=%v calls=%d", err, calls)
+	}
+}
+
+func TestSQLiteStorage_SpanLinks(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+
+	// A consumer span linked to the producer spans of two batched messages
+	consumer := models.NewSpan("process batch", "worker", "trace-consumer")
+	consumer.ID = "consumer"
+	consumer.AddLink("trace-a", "producer-a", map[string]string{"messaging.message.id": "m-1"})
+	consumer.AddLink("trace-b", "producer-b", nil)
+	if err := st.SaveSpan(consumer); err != nil {
+		t.Fatalf("failed to save span: %v", err)
+	}
+
+	// Spans saved as part of a trace keep their links too
+	root := models.NewSpan("GET /", "web", "trace-web")
+	root.ID = "web-root"
+	root.AddLink("trace-consumer", "consumer", nil)
+	if err := st.SaveTrace(&models.Trace{ID: "trace-web", Root: root, Spans: []*models.Span{root}, Status: root.Status}); err != nil {
+		t.Fatalf("failed to save trace: %v", err)
+	}
+
+	result, err := st.QuerySpans(&models.QueryParams{SpanID: "consumer"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	spans := result["spans"].([]map[string]interface{})
+	if len(spans) != 1 || spans[0]["id"] != "consumer" {
+		t.Fatalf("expected only the consumer span, got %v", spans)
+	}
+
+	links, ok := spans[0]["links"].([]models.SpanLink)
+	if !ok || len(links) != 2 {
+		t.Fatalf("expected 2 links, got %v", spans[0]["links"])
+	}
+	if links[0].TraceID != "trace-a" || links[0].SpanID != "producer-a" || links[0].Attributes["messaging.message.id"] != "m-1" {
+		t.Errorf("unexpected first link: %+v", links[0])
+	}
+	if links[1].TraceID != "trace-b" || links[1].SpanID != "producer-b" || links[1].Attributes != nil {
+		t.Errorf("unexpected second link: %+v", links[1])
+	}
+
+	result, err = st.QuerySpans(&models.QueryParams{SpanID: "web-root"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	spans = result["spans"].([]map[string]interface{})
+	if links, _ := spans[0]["links"].([]models.SpanLink); len(links) != 1 || links[0].SpanID != "consumer" {
+		t.Errorf("expected trace span to keep its link, got %v", spans[0]["links"])
+	}
+
+	// Spans without links don't report any
+	plain := models.NewSpan("plain", "worker", "trace-plain")
+	if err := st.SaveSpan(plain); err != nil {
+		t.Fatalf("failed to save span: %v", err)
+	}
+	result, _ = st.QuerySpans(&models.QueryParams{SpanID: plain.ID})
+	spans = result["spans"].([]map[string]interface{})
+	if _, ok := spans[0]["links"]; ok {
+		t.Errorf("expected no links on a plain span, got %v", spans[0]["links"])
+	}
+}
+
+func TestSQLiteStorage_AddsLinksColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pulse-old.db")
+
+	// Create a spans table as it looked before links were stored
+	st, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatalf("failed to create SQLite storage: %v", err)
+	}
+	if _, err := st.db.Exec("ALTER TABLE spans DROP COLUMN links"); err != nil {
+		t.Fatalf("failed to drop links column: %v", err)
+	}
+	st.Close()
+
+	st, err = NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatalf("failed to reopen SQLite storage: %v", err)
+	}
+	defer st.Close()
+
+	span := models.NewSpan("call", "api", "trace-1")
+	span.AddLink("trace-2", "span-2", nil)
+	if err := st.SaveSpan(span); err != nil {
+		t.Fatalf("expected links to be saved after migration, got: %v", err)
+	}
+}
+
+func TestSQLiteStorage_DeleteByService(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+
+	for _, service := range []string{"legacy", "api"} {
+		log := models.NewLogEntry(service, "message", models.LogLevelInfo)
+		log.ID = "log-" + service
+		if err := st.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+
+		metric := models.NewMetric("cpu", 1, models.MetricTypeGauge, service)
+		metric.ID = "metric-" + service
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("failed to save metric: %v", err)
+		}
+
+		histogram, err := models.NewHistogramMetric("duration", service, []float64{0.1, 1})
+		if err != nil {
+			t.Fatalf("failed to create histogram: %v", err)
+		}
+		histogram.ID = "histogram-" + service
+		histogram.Observe(0.5)
+		if err := st.SaveHistogramMetric(histogram); err != nil {
+			t.Fatalf("failed to save histogram: %v", err)
+		}
+
+		root := models.NewSpan("request", service, "trace-"+service)
+		root.ID = "root-" + service
+		child := models.NewSpan("query", service, "trace-"+service).SetParent(root.ID)
+		child.ID = "child-" + service
+		trace := &models.Trace{ID: root.TraceID, Spans: []*models.Span{root, child}, Root: root}
+		if err := st.SaveTrace(trace); err != nil {
+			t.Fatalf("failed to save trace: %v", err)
+		}
+	}
+
+	// A legacy span inside a trace rooted in another service
+	span := models.NewSpan("callback", "legacy", "trace-api")
+	span.ID = "legacy-callback"
+	if err := st.SaveSpan(span); err != nil {
+		t.Fatalf("failed to save span: %v", err)
+	}
+
+	deleted, err := st.DeleteByService("legacy")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// 1 log, 2 metric rows, 1 histogram row, 1 trace and 3 spans
+	if deleted != 8 {
+		t.Errorf("expected 8 rows deleted, got %d", deleted)
+	}
+
+	for table, expected := range map[string]int{
+		"logs": 1, "metrics": 2, "histogram_metrics": 1, "traces": 1, "spans": 2,
+	} {
+		if count := countRows(t, st, table); count != expected {
+			t.Errorf("expected %d rows to remain in %s, got %d", expected, table, count)
+		}
+	}
+
+	services, err := st.GetServices()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(services) != 1 || services[0] != "api" {
+		t.Errorf("expected only api to remain, got %v", services)
+	}
+
+	// Unknown services delete nothing
+	if deleted, err := st.DeleteByService("unknown"); err != nil || deleted != 0 {
+		t.Errorf("expected nothing deleted for an unknown service, got %d (%v)", deleted, err)
+	}
+}
+
+func TestSQLiteStorage_ClearAll(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+
+	log := models.NewLogEntry("api", "message", models.LogLevelInfo)
+	if err := st.SaveLog(log); err != nil {
+		t.Fatalf("failed to save log: %v", err)
+	}
+	histogram, err := models.NewHistogramMetric("duration", "api", []float64{1})
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	if err := st.SaveHistogramMetric(histogram); err != nil {
+		t.Fatalf("failed to save histogram: %v", err)
+	}
+	saveTestTrace(t, st, "trace-1", time.Now().UTC(), "child")
+
+	// 1 log, 1 metric row, 1 histogram row, 1 trace and 2 spans
+	deleted, err := st.ClearAll()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if deleted != 6 {
+		t.Errorf("expected 6 rows deleted, got %d", deleted)
+	}
+
+	for _, table := range []string{"logs", "metrics", "histogram_metrics", "traces", "spans"} {
+		if count := countRows(t, st, table); count != 0 {
+			t.Errorf("expected %s to be empty, got %d rows", table, count)
+		}
+	}
+
+	if deleted, err := st.ClearAll(); err != nil || deleted != 0 {
+		t.Errorf("expected nothing to clear, got %d (%v)", deleted, err)
+	}
+}
+
+func TestStorage_Ping(t *testing.T) {
+	memory, err := NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create in-memory storage: %v", err)
+	}
+	storages := map[string]Storage{
+		"sqlite": newTestSQLiteStorage(t),
+		"memory": memory,
+		"mock":   NewMockStorage(),
+	}
+	for name, st := range storages {
+		if err := st.Ping(); err != nil {
+			t.Errorf("%s: expected an open storage to answer, got: %v", name, err)
+		}
+		st.Close()
+		if err := st.Ping(); err == nil {
+			t.Errorf("%s: expected an error pinging a closed storage", name)
+		}
+	}
+}
+
+func TestStorage_GetActiveServices(t *testing.T) {
+	memory, err := NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create in-memory storage: %v", err)
+	}
+	storages := map[string]Storage{
+		"sqlite": newTestSQLiteStorage(t),
+		"memory": memory,
+		"mock":   NewMockStorage(),
+	}
+
+	// Each service was last active at a different time, through a different
+	// signal; legacy has its oldest items stored last
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	for name, st := range storages {
+		legacy := models.NewLogEntry("legacy", "still here", models.LogLevelInfo)
+		legacy.Timestamp = at(-60)
+		checkout := models.NewLogEntry("checkout", "order placed", models.LogLevelInfo)
+		checkout.Timestamp = at(-5)
+		old := models.NewLogEntry("checkout", "cart created", models.LogLevelInfo)
+		old.Timestamp = at(-90)
+		metric := models.NewMetric("queue.depth", 3, models.MetricTypeGauge, "worker")
+		metric.Timestamp = at(-1)
+		span := models.NewSpan("charge", "payments", "trace-1")
+		span.StartTime, span.EndTime = at(-10), at(-9)
+
+		for _, log := range []*models.LogEntry{legacy, checkout, old} {
+			if err := st.SaveLog(log); err != nil {
+				t.Fatalf("%s: failed to save log: %v", name, err)
+			}
+		}
+		if err := st.SaveMetric(metric); err != nil {
+			t.Fatalf("%s: failed to save metric: %v", name, err)
+		}
+		if err := st.SaveSpan(span); err != nil {
+			t.Fatalf("%s: failed to save span: %v", name, err)
+		}
+
+		services, err := st.GetActiveServices(0)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", name, err)
+		}
+		if expected := []string{"worker", "checkout", "payments", "legacy"}; !reflect.DeepEqual(services, expected) {
+			t.Errorf("%s: expected services newest first %v, got %v", name, expected, services)
+		}
+
+		services, err = st.GetActiveServices(2)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", name, err)
+		}
+		if expected := []string{"worker", "checkout"}; !reflect.DeepEqual(services, expected) {
+			t.Errorf("%s: expected the 2 newest-active services %v, got %v", name, expected, services)
+		}
+	}
+}
+
+func TestStorage_StreamLogsAndSpans(t *testing.T) {
+	memory, err := NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create in-memory storage: %v", err)
+	}
+	storages := map[string]Storage{
+		"sqlite": newTestSQLiteStorage(t),
+		"memory": memory,
+		"mock":   NewMockStorage(),
+	}
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for name, st := range storages {
+		for message, offset := range map[string]time.Duration{"second": time.Minute, "first": 0, "third": 2 * time.Minute} {
+			entry := models.NewLogEntry("checkout", message, models.LogLevelInfo).WithEnv("prod")
+			entry.ID = "log-" + message
+			entry.Timestamp = base.Add(offset)
+			entry.AddTag("region", "eu")
+			if err := st.SaveLog(entry); err != nil {
+				t.Fatalf("%s: failed to save log: %v", name, err)
+			}
+		}
+		other := models.NewLogEntry("search", "ignored", models.LogLevelInfo)
+		other.Timestamp = base
+		st.SaveLog(other)
+
+		root := models.NewSpan("GET /checkout", "checkout", "trace-1")
+		root.StartTime, root.EndTime, root.Duration = base, base.Add(time.Second), 1000
+		child := models.NewSpan("charge", "checkout", "trace-1").SetParent(root.ID)
+		child.StartTime = base.Add(100 * time.Millisecond)
+		child.AddTag("card", "visa")
+		for _, span := range []*models.Span{child, root} {
+			if err := st.SaveSpan(span); err != nil {
+				t.Fatalf("%s: failed to save span: %v", name, err)
+			}
+		}
+
+		query := &models.QueryParams{Service: "checkout"}
+		var messages []string
+		err := st.StreamLogs(query, func(log *models.LogEntry) error {
+			if log.Env != "prod" || log.Tags["region"] != "eu" {
+				t.Errorf("%s: expected the env and tags streamed, got %+v", name, log)
+			}
+			messages = append(messages, log.Message)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", name, err)
+		}
+		if expected := []string{"first", "second", "third"}; !reflect.DeepEqual(messages, expected) {
+			t.Errorf("%s: expected logs oldest first %v, got %v", name, expected, messages)
+		}
+
+		var spans []*models.Span
+		if err := st.StreamSpans(query, func(span *models.Span) error {
+			spans = append(spans, span)
+			return nil
+		}); err != nil {
+			t.Fatalf("%s: expected no error, got: %v", name, err)
+		}
+		if len(spans) != 2 || spans[0].ID != root.ID || spans[1].ParentID != root.ID || spans[1].Tags["card"] != "visa" {
+			t.Errorf("%s: expected both spans oldest first with their fields, got %+v", name, spans)
+		}
+
+		// Errors of fn stop the stream
+		stop := errors.New("stop")
+		calls := 0
+		err = st.StreamLogs(query, func(log *models.LogEntry) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("%s: expected the stream to stop at the first error, got %v after %d calls", name, err, calls)
+		}
+	}
+}
+
+func TestStorage_GetSpanAncestors(t *testing.T) {
+	memory, err := NewInMemoryStorage()
+	if err != nil {
+		t.Fatalf("failed to create in-memory storage: %v", err)
+	}
+	storages := map[string]Storage{
+		"sqlite": newTestSQLiteStorage(t),
+		"memory": memory,
+		"mock":   NewMockStorage(),
+	}
+
+	for name, st := range storages {
+		// A chain of eight spans, saved leaf first, next to a sibling branch
+		// and another trace
+		var chain []*models.Span
+		for depth := 0; depth < 8; depth++ {
+			span := models.NewSpan(fmt.Sprintf("level-%d", depth), "api", "trace-deep")
+			if depth > 0 {
+				span.SetParent(chain[depth-1].ID)
+			}
+			chain = append(chain, span)
+		}
+		sibling := models.NewSpan("sibling", "api", "trace-deep").SetParent(chain[2].ID)
+		other := models.NewSpan("other", "api", "trace-other").SetParent(chain[6].ID)
+		spans := []*models.Span{sibling, other}
+		for i := len(chain) - 1; i >= 0; i-- {
+			spans = append(spans, chain[i])
+		}
+		for _, span := range spans {
+			if err := st.SaveSpan(span); err != nil {
+				t.Fatalf("%s: failed to save span: %v", name, err)
+			}
+		}
+
+		ancestors, err := st.GetSpanAncestors(chain[7].ID)
+		if err != nil {
+			t.Fatalf("%s: failed to get ancestors: %v", name, err)
+		}
+		if len(ancestors) != 7 {
+			t.Fatalf("%s: expected 7 ancestors, got %d", name, len(ancestors))
+		}
+		for i, ancestor := range ancestors {
+			if expected := chain[6-i].Name; ancestor.Name != expected {
+				t.Errorf("%s: expected ancestor %d to be %s, got %s", name, i, expected, ancestor.Name)
+			}
+		}
+
+		ancestors, err = st.GetSpanAncestors(chain[0].ID)
+		if err != nil || len(ancestors) != 0 {
+			t.Errorf("%s: expected no ancestors for the root, got %d (%v)", name, len(ancestors), err)
+		}
+
+		// The parent of other is in another trace, so it has no ancestors
+		ancestors, err = st.GetSpanAncestors(other.ID)
+		if err != nil || len(ancestors) != 0 {
+			t.Errorf("%s: expected no ancestors across traces, got %d (%v)", name, len(ancestors), err)
+		}
+
+		if _, err := st.GetSpanAncestors("missing"); !errors.Is(err, ErrSpanNotFound) {
+			t.Errorf("%s: expected ErrSpanNotFound, got %v", name, err)
+		}
+
+		// Parent IDs forming a cycle end the walk instead of looping
+		a := models.NewSpan("a", "api", "trace-cycle")
+		b := models.NewSpan("b", "api", "trace-cycle").SetParent(a.ID)
+		c := models.NewSpan("c", "api", "trace-cycle").SetParent(b.ID)
+		a.SetParent(c.ID)
+		for _, span := range []*models.Span{a, b, c} {
+			if err := st.SaveSpan(span); err != nil {
+				t.Fatalf("%s: failed to save span: %v", name, err)
+			}
+		}
+		ancestors, err = st.GetSpanAncestors(c.ID)
+		if err != nil {
+			t.Fatalf("%s: failed to get ancestors in a cycle: %v", name, err)
+		}
+		if len(ancestors) != 2 || ancestors[0].ID != b.ID || ancestors[1].ID != a.ID {
+			t.Errorf("%s: expected ancestors b, a in a cycle, got %d ancestors", name, len(ancestors))
+		}
+	}
+}
+
+func TestSQLiteStorage_SaveBatches(t *testing.T) {
+	st := newTestSQLiteStorage(t)
+
+	var logs []*models.LogEntry
+	var metrics []*models.Metric
+	var spans []*models.Span
+	for i := 0; i < 3; i++ {
+		log := models.NewLogEntry("api", "hello", models.LogLevelInfo)
+		log.ID = fmt.Sprintf("log-%d", i)
+		logs = append(logs, log)
+		metric := models.NewMetric("requests", float64(i), models.MetricTypeCounter, "api")
+		metric.ID = fmt.Sprintf("metric-%d", i)
+		metrics = append(metrics, metric)
+		spans = append(spans, models.NewSpan("handle", "api", "trace-1"))
+	}
+
+	if err := st.SaveLogs(logs); err != nil {
+		t.Fatalf("failed to save logs: %v", err)
+	}
+	if err := st.SaveMetrics(metrics); err != nil {
+		t.Fatalf("failed to save metrics: %v", err)
+	}
+	if err := st.SaveSpans(spans); err != nil {
+		t.Fatalf("failed to save spans: %v", err)
+	}
+	for table, expected := range map[string]int{"logs": 3, "metrics": 3, "spans": 3} {
+		if count := countRows(t, st, table); count != expected {
+			t.Errorf("expected %d rows in %s, got %d", expected, table, count)
+		}
+	}
+
+	// A batch failing part way is rolled back as a whole
+	fresh := models.NewLogEntry("api", "new", models.LogLevelInfo)
+	fresh.ID = "log-new"
+	if err := st.SaveLogs([]*models.LogEntry{fresh, logs[0]}); err == nil {
+		t.Fatal("expected an error saving a duplicate log")
+	}
+	if count := countRows(t, st, "logs"); count != 3 {
+		t.Errorf("expected the failed batch to be rolled back, got %d logs", count)
+	}
+
+	// Single saves go through the same path
+	if err := st.SaveLog(fresh); err != nil {
+		t.Fatalf("failed to save log: %v", err)
+	}
+	if count := countRows(t, st, "logs"); count != 4 {
+		t.Errorf("expected 4 logs, got %d", count)
+	}
+}
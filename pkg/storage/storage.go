@@ -1,6 +1,10 @@
 package storage
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/karansingh/pulse/pkg/models"
 )
 
@@ -8,21 +12,185 @@ import (
 type Storage interface {
 	// Log operations
 	SaveLog(log *models.LogEntry) error
+	SaveLogs(logs []*models.LogEntry) error
 	QueryLogs(query *models.QueryParams) (map[string]interface{}, error)
+	StreamLogs(query *models.QueryParams, fn func(log *models.LogEntry) error) error
+	UpdateLogTags(query *models.QueryParams, tags map[string]string) (updated int64, err error)
 
 	// Metric operations
 	SaveMetric(metric *models.Metric) error
-	QueryMetrics(query *models.QueryParams) ([]map[string]interface{}, error)
+	SaveMetrics(metrics []*models.Metric) error
+	SaveHistogramMetric(histogram *models.HistogramMetric) error
+	QueryMetrics(query *models.QueryParams) (map[string]interface{}, error)
+	QueryHistograms(query *models.QueryParams) ([]*models.HistogramMetric, error)
+	StreamMetrics(query *models.QueryParams, fn func(metric *models.Metric) error) error
 
 	// Trace operations
 	SaveSpan(span *models.Span) error
+	SaveSpans(spans []*models.Span) error
 	SaveTrace(trace *models.Trace) error
-	QueryTraces(query *models.QueryParams) ([]map[string]interface{}, error)
-	QuerySpans(query *models.QueryParams) ([]map[string]interface{}, error)
+	QueryTraces(query *models.QueryParams) (map[string]interface{}, error)
+	QueryTraceIDs(query *models.QueryParams) (map[string]interface{}, error)
+	QueryTraceSamples(query *models.QueryParams) (map[string]interface{}, error)
+	QuerySlowTraces(query *models.QueryParams, percentile float64) (map[string]interface{}, error)
+	QuerySpans(query *models.QueryParams) (map[string]interface{}, error)
+	StreamSpans(query *models.QueryParams, fn func(span *models.Span) error) error
+	CountSpanErrorsByType(query *models.QueryParams) ([]map[string]interface{}, error)
+	GetTrace(traceID string) (*models.Trace, error)
+	GetSpanAncestors(spanID string) ([]*models.Span, error)
 
 	// Service operations
 	GetServices() ([]string, error)
+	GetActiveServices(limit int) ([]string, error)
+	DeleteByService(service string) (deleted int64, err error)
+
+	// GetStats counts the logs of each level, the metrics of each type and
+	// the traces matching query, and averages the trace durations
+	GetStats(query *models.QueryParams) (*models.Stats, error)
+
+	// ClearAll removes all stored telemetry
+	ClearAll() (deleted int64, err error)
+
+	// Ping checks that the storage is reachable and can serve requests
+	Ping() error
 
 	// Close closes the storage connection
 	Close() error
 }
+
+// ErrTraceNotFound is returned by GetTrace when no span of the trace is stored
+var ErrTraceNotFound = errors.New("trace not found")
+
+// ErrSpanNotFound is returned by GetSpanAncestors when the span isn't stored
+var ErrSpanNotFound = errors.New("span not found")
+
+// spanAncestors returns the ancestors of the span with the given ID among
+// the spans of its trace, its parent first and the root last. The walk ends
+// at a span without a parent or whose parent isn't stored, and at a parent
+// already visited, so that parent IDs forming a cycle can't loop forever.
+func spanAncestors(spanID string, spans []*models.Span) ([]*models.Span, error) {
+	byID := make(map[string]*models.Span, len(spans))
+	for _, span := range spans {
+		byID[span.ID] = span
+	}
+
+	span, ok := byID[spanID]
+	if !ok {
+		return nil, ErrSpanNotFound
+	}
+
+	ancestors := []*models.Span{}
+	visited := map[string]bool{span.ID: true}
+	for span.ParentID != "" && !visited[span.ParentID] {
+		parent, ok := byID[span.ParentID]
+		if !ok {
+			break
+		}
+		visited[parent.ID] = true
+		ancestors = append(ancestors, parent)
+		span = parent
+	}
+	return ancestors, nil
+}
+
+// statsQuery copies the filters of query without its level filters, so that
+// GetStats counts the logs of every level
+func statsQuery(query *models.QueryParams) *models.QueryParams {
+	q := *query
+	q.Level = ""
+	q.MinLevel = ""
+	return &q
+}
+
+// addLogCount adds count logs of the given level to stats. Logs of levels
+// other than the standard ones count towards the total only.
+func addLogCount(stats *models.Stats, level string, count int64) {
+	stats.Logs.Total += count
+	if _, ok := stats.Logs.ByLevel[models.LogLevel(level)]; ok {
+		stats.Logs.ByLevel[models.LogLevel(level)] += count
+	}
+}
+
+// statusSeverity orders span statuses from best to worst
+var statusSeverity = map[models.SpanStatus]int{
+	models.SpanStatusOK:       1,
+	models.SpanStatusCanceled: 2,
+	models.SpanStatusError:    3,
+}
+
+// mergeTrace works out the traces row of a trace whose spans may be saved
+// in several batches, from the row stored so far, if any, and all the stored
+// spans of the trace, the batch's included. The stored root is kept, else
+// the batch's root or a stored span without a parent is taken; the worse of
+// the stored and batch statuses wins, ERROR if any span failed; and the
+// duration runs from the earliest span start to the latest span end. It
+// reports false while no root span has been seen, as the row requires one.
+func mergeTrace(trace *models.Trace, storedRoot string, storedStatus models.SpanStatus, spans []*models.Span) (root string, status models.SpanStatus, duration int64, ok bool) {
+	root = storedRoot
+	if root == "" && trace.Root != nil {
+		root = trace.Root.ID
+	}
+	var rootSpan *models.Span
+	for _, span := range spans {
+		if (root == "" && span.ParentID == "") || span.ID == root {
+			root, rootSpan = span.ID, span
+		}
+	}
+	if root == "" {
+		return "", "", 0, false
+	}
+
+	status = storedStatus
+	if statusSeverity[trace.Status] > statusSeverity[status] {
+		status = trace.Status
+	}
+	all := &models.Trace{Spans: spans}
+	if all.ComputeStatus() == models.SpanStatusError {
+		status = models.SpanStatusError
+	}
+	if status == "" && rootSpan != nil {
+		status = rootSpan.Status
+	}
+
+	return root, status, all.ComputeDuration(), true
+}
+
+// ErrCursorOrder is returned when a log query combines a cursor with an order
+// other than the default newest first
+var ErrCursorOrder = errors.New("cursor pagination only supports the default order")
+
+// InTimeRange reports whether t is within the query's time range, bounds
+// included
+func InTimeRange(t time.Time, query *models.QueryParams) bool {
+	if !query.Since.IsZero() && t.Before(query.Since) {
+		return false
+	}
+	if !query.Until.IsZero() && t.After(query.Until) {
+		return false
+	}
+	return true
+}
+
+// InDurationRange reports whether duration, in milliseconds, is within the
+// query's duration range, bounds included
+func InDurationRange(duration int64, query *models.QueryParams) bool {
+	if query.MinDuration > 0 && duration < query.MinDuration {
+		return false
+	}
+	if query.MaxDuration > 0 && duration > query.MaxDuration {
+		return false
+	}
+	return true
+}
+
+// ContainsFold reports whether any of values contains search, ignoring case
+// like the LIKE search of the SQL backends
+func ContainsFold(search string, values ...string) bool {
+	search = strings.ToLower(search)
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), search) {
+			return true
+		}
+	}
+	return false
+}
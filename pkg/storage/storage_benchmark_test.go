@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -197,3 +199,61 @@ func BenchmarkConcurrentStorage(b *testing.B) {
 		}
 	})
 }
+
+// benchmarkLogs returns n logs with distinct IDs
+func benchmarkLogs(round, n int) []*models.LogEntry {
+	logs := make([]*models.LogEntry, n)
+	for i := range logs {
+		logs[i] = &models.LogEntry{
+			ID:        fmt.Sprintf("log-%d-%d", round, i),
+			Timestamp: time.Now().UTC(),
+			Service:   "benchmark-service",
+			Level:     models.LogLevelInfo,
+			Message:   "Benchmark log message",
+			Tags:      map[string]string{"env": "benchmark", "region": "us-west"},
+		}
+	}
+	return logs
+}
+
+// BenchmarkSQLiteStorage_SaveLogs compares saving 1000 logs one at a time,
+// each in its own transaction, with saving them as one batch
+func BenchmarkSQLiteStorage_SaveLogs(b *testing.B) {
+	b.Run("individual", func(b *testing.B) {
+		storage, err := NewSQLiteStorage(filepath.Join(b.TempDir(), "pulse-bench.db"))
+		if err != nil {
+			b.Fatalf("failed to create SQLite storage: %v", err)
+		}
+		defer storage.Close()
+
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			logs := benchmarkLogs(i, 1000)
+			b.StartTimer()
+
+			for _, log := range logs {
+				if err := storage.SaveLog(log); err != nil {
+					b.Fatalf("error during benchmark: %v", err)
+				}
+			}
+		}
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		storage, err := NewSQLiteStorage(filepath.Join(b.TempDir(), "pulse-bench.db"))
+		if err != nil {
+			b.Fatalf("failed to create SQLite storage: %v", err)
+		}
+		defer storage.Close()
+
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			logs := benchmarkLogs(i, 1000)
+			b.StartTimer()
+
+			if err := storage.SaveLogs(logs); err != nil {
+				b.Fatalf("error during benchmark: %v", err)
+			}
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -215,3 +216,273 @@ func TestMockStorage_Close(t *testing.T) {
 		t.Errorf("expected ErrStorageClosed, got: %v", err)
 	}
 }
+
+func TestMockStorage_QueryTraces_ContainsSpan(t *testing.T) {
+	storage := NewMockStorage()
+	now := time.Now().UTC()
+
+	saveTestTrace(t, storage, "trace-db", now, "database_query", "render")
+	saveTestTrace(t, storage, "trace-cache", now.Add(time.Second), "cache_lookup")
+
+	result, err := storage.QueryTraces(&models.QueryParams{ContainsSpan: "database_query"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	traces := result["traces"].([]map[string]interface{})
+
+	if len(traces) != 1 {
+		t.Fatalf("expected 1 trace, got %d", len(traces))
+	}
+	if traces[0]["id"] != "trace-db" {
+		t.Errorf("expected trace-db, got %v", traces[0]["id"])
+	}
+}
+
+func TestMockStorage_QueryPagination(t *testing.T) {
+	storage := NewMockStorage()
+	base := time.Now().UTC().Add(-time.Hour)
+
+	for i := 0; i < 5; i++ {
+		saveTestTrace(t, storage, fmt.Sprintf("trace-%d", i), base.Add(time.Duration(i)*time.Second))
+	}
+
+	result, err := storage.QueryTraces(&models.QueryParams{Limit: 2, Offset: 4})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	traces := result["traces"].([]map[string]interface{})
+	if len(traces) != 1 {
+		t.Fatalf("expected 1 trace on the last page, got %d", len(traces))
+	}
+	if traces[0]["id"] != "trace-0" {
+		t.Errorf("expected trace-0, got %v", traces[0]["id"])
+	}
+
+	pagination := result["pagination"].(map[string]interface{})
+	if pagination["total_items"] != 5 || pagination["total_pages"] != 3 {
+		t.Errorf("unexpected pagination: %v", pagination)
+	}
+}
+
+func TestMockStorage_QueryLogs_Pagination(t *testing.T) {
+	storage := NewMockStorage()
+
+	for i := 0; i < 5; i++ {
+		if err := storage.SaveLog(models.NewLogEntry("api", fmt.Sprintf("message %d", i), models.LogLevelInfo)); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	result, err := storage.QueryLogs(&models.QueryParams{Limit: 2, Offset: 4})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	logs := result["logs"].([]map[string]interface{})
+	if len(logs) != 1 || logs[0]["message"] != "message 4" {
+		t.Errorf("expected the last log on the last page, got %v", logs)
+	}
+
+	pagination := result["pagination"].(map[string]interface{})
+	if pagination["total_items"] != 5 || pagination["total_pages"] != 3 {
+		t.Errorf("unexpected pagination: %v", pagination)
+	}
+}
+
+func TestMockStorage_QueryLogs_TagFilters(t *testing.T) {
+	storage := NewMockStorage()
+
+	for _, region := range []string{"us-west", "eu"} {
+		log := models.NewLogEntry("api", "message", models.LogLevelInfo)
+		log.AddTag("region", region)
+		if err := storage.SaveLog(log); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	result, err := storage.QueryLogs(&models.QueryParams{Filters: map[string]string{"region": "eu"}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	logs := result["logs"].([]map[string]interface{})
+	if len(logs) != 1 || logs[0]["tags"].(map[string]string)["region"] != "eu" {
+		t.Errorf("expected only the eu log, got %v", logs)
+	}
+
+	result, err = storage.QueryLogs(&models.QueryParams{Filters: map[string]string{"zone": "a"}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	logs = result["logs"].([]map[string]interface{})
+	if len(logs) != 0 {
+		t.Errorf("expected no logs for a missing tag, got %d", len(logs))
+	}
+}
+
+func TestMockStorage_QuerySlowTraces(t *testing.T) {
+	storage := NewMockStorage()
+	saveTraceDurations(t, storage, "api", 50, 10, 40, 20, 30)
+
+	result, err := storage.QuerySlowTraces(&models.QueryParams{Service: "api"}, 50)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	assertSlowTraces(t, result, 30, "api-0", "api-2")
+}
+
+func TestMockStorage_DurationFilters(t *testing.T) {
+	assertDurationFilters(t, NewMockStorage())
+}
+
+func TestMockStorage_QueryTraces_Duration(t *testing.T) {
+	assertTraceDuration(t, NewMockStorage())
+}
+
+func TestMockStorage_QueryTraces_Status(t *testing.T) {
+	assertTraceStatus(t, NewMockStorage())
+}
+
+func TestMockStorage_TimeBounds(t *testing.T) {
+	assertTimeBounds(t, NewMockStorage())
+}
+
+func TestMockStorage_TraceIDs(t *testing.T) {
+	assertTraceIDs(t, NewMockStorage())
+}
+
+func TestMockStorage_TraceSamples(t *testing.T) {
+	assertTraceSamples(t, NewMockStorage())
+}
+
+func TestMockStorage_LevelOrder(t *testing.T) {
+	assertLevelOrder(t, NewMockStorage())
+}
+
+func TestMockStorage_TagPresenceFilters(t *testing.T) {
+	assertTagPresenceFilters(t, NewMockStorage())
+}
+
+func TestMockStorage_UpdateLogTags(t *testing.T) {
+	assertUpdateLogTags(t, NewMockStorage())
+}
+
+func TestMockStorage_UpdateLogTagsMaxTags(t *testing.T) {
+	assertUpdateLogTagsMaxTags(t, NewMockStorage())
+}
+
+func TestMockStorage_GetStats(t *testing.T) {
+	assertGetStats(t, NewMockStorage())
+}
+
+func TestMockStorage_GetTrace(t *testing.T) {
+	assertGetTrace(t, NewMockStorage())
+}
+
+func TestMockStorage_QuerySpansDetails(t *testing.T) {
+	assertQuerySpansDetails(t, NewMockStorage())
+}
+
+func TestMockStorage_CountSpanErrorsByType(t *testing.T) {
+	storage := NewMockStorage()
+
+	saveErrorSpans(t, storage, "api", "timeout", "connection_refused", "timeout", "")
+	saveErrorSpans(t, storage, "billing", "timeout")
+
+	counts, err := storage.CountSpanErrorsByType(&models.QueryParams{Service: "api"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	assertErrorCounts(t, counts, [][2]interface{}{
+		{"timeout", 2},
+		{"connection_refused", 1},
+		{"unknown", 1},
+	})
+}
+
+func TestMockStorage_QuerySpans_Links(t *testing.T) {
+	storage := NewMockStorage()
+
+	linked := models.NewSpan("process batch", "worker", "trace-consumer")
+	linked.AddLink("trace-a", "producer-a", map[string]string{"messaging.message.id": "m-1"})
+	other := models.NewSpan("other", "worker", "trace-consumer")
+	storage.SaveSpan(linked)
+	storage.SaveSpan(other)
+
+	result, err := storage.QuerySpans(&models.QueryParams{SpanID: linked.ID})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	spans := result["spans"].([]map[string]interface{})
+	if len(spans) != 1 || spans[0]["id"] != linked.ID {
+		t.Fatalf("expected only the linked span, got %v", spans)
+	}
+	links, ok := spans[0]["links"].([]models.SpanLink)
+	if !ok || len(links) != 1 || links[0].SpanID != "producer-a" || links[0].Attributes["messaging.message.id"] != "m-1" {
+		t.Errorf("unexpected links: %v", spans[0]["links"])
+	}
+}
+
+func TestMockStorage_DeleteByService(t *testing.T) {
+	storage := NewMockStorage()
+
+	for _, service := range []string{"legacy", "api"} {
+		storage.SaveLog(models.NewLogEntry(service, "message", models.LogLevelInfo))
+		storage.SaveMetric(models.NewMetric("cpu", 1, models.MetricTypeGauge, service))
+		histogram, err := models.NewHistogramMetric("duration", service, []float64{1})
+		if err != nil {
+			t.Fatalf("failed to create histogram: %v", err)
+		}
+		storage.SaveHistogramMetric(histogram)
+		root := models.NewSpan("request", service, "trace-"+service)
+		storage.SaveTrace(&models.Trace{ID: root.TraceID, Spans: []*models.Span{root}, Root: root})
+	}
+
+	deleted, err := storage.DeleteByService("legacy")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if deleted != 5 {
+		t.Errorf("expected 5 items deleted, got %d", deleted)
+	}
+
+	if len(storage.GetLogs()) != 1 || len(storage.GetMetrics()) != 1 || len(storage.GetHistograms()) != 1 ||
+		len(storage.GetSpans()) != 1 || len(storage.GetTraces()) != 1 {
+		t.Errorf("expected one item of each kind to remain")
+	}
+
+	services, _ := storage.GetServices()
+	if len(services) != 1 || services[0] != "api" {
+		t.Errorf("expected only api to remain, got %v", services)
+	}
+}
+
+func TestMockStorage_QueryLogs_MinLevel(t *testing.T) {
+	storage := NewMockStorage()
+
+	for _, level := range []models.LogLevel{models.LogLevelDebug, models.LogLevelWarning, models.LogLevelFatal, "AUDIT"} {
+		if err := storage.SaveLog(models.NewLogEntry("api", "message", level)); err != nil {
+			t.Fatalf("failed to save log: %v", err)
+		}
+	}
+
+	result, err := storage.QueryLogs(&models.QueryParams{MinLevel: string(models.LogLevelWarning)})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	logs := result["logs"].([]map[string]interface{})
+	if len(logs) != 2 {
+		t.Errorf("expected WARNING and FATAL logs, got %v", logs)
+	}
+
+	// Unranked levels match exactly
+	result, err = storage.QueryLogs(&models.QueryParams{MinLevel: "AUDIT"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	logs = result["logs"].([]map[string]interface{})
+	if len(logs) != 1 || logs[0]["level"] != models.LogLevel("AUDIT") {
+		t.Errorf("expected only the AUDIT log, got %v", logs)
+	}
+}
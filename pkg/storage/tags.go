@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/karansingh/pulse/pkg/models"
+)
+
+// canonicalJSON encodes tags as a JSON object with sorted keys and without
+// HTML escaping, so that tags with the same content always produce the same
+// bytes. No tags encode as {} whether the map is nil or empty.
+func canonicalJSON(tags map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		// Encode terminates each string with a newline, replaced by the separator
+		if err := enc.Encode(k); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(':')
+		if err := enc.Encode(tags[k]); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// updateLimitedTags merges tags into the logs returned by selectQuery, a
+// query of their id and tags, like models.MergeTags, and writes each back with
+// updateQuery, which takes the tags and the id. It replaces the single UPDATE
+// merging tags in SQL when a tag limit is set, so that updated logs keep
+// within it. It returns the number of logs updated.
+func updateLimitedTags(db *sql.DB, selectQuery, updateQuery string, args []interface{}, tags map[string]string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(selectQuery, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query logs: %w", err)
+	}
+	type taggedLog struct {
+		id   string
+		tags map[string]string
+	}
+	var logs []taggedLog
+	for rows.Next() {
+		var log taggedLog
+		var tagsJSON sql.NullString
+		if err := rows.Scan(&log.id, &tagsJSON); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan log: %w", err)
+		}
+		if tagsJSON.Valid && tagsJSON.String != "" {
+			if err := json.Unmarshal([]byte(tagsJSON.String), &log.tags); err != nil {
+				rows.Close()
+				return 0, fmt.Errorf("failed to unmarshal tags: %w", err)
+			}
+		}
+		logs = append(logs, log)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query logs: %w", err)
+	}
+
+	for _, log := range logs {
+		tagsJSON, err := canonicalJSON(models.MergeTags(log.tags, tags))
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		if _, err := tx.Exec(updateQuery, string(tagsJSON), log.id); err != nil {
+			return 0, fmt.Errorf("failed to update log tags: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return int64(len(logs)), nil
+}
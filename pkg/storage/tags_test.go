@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	// Maps built in different orders have the same content
+	a := map[string]string{}
+	b := map[string]string{}
+	for _, k := range []string{"zone", "http.method", "region", "a\"quoted\"", "<html>"} {
+		a[k] = k + "-value"
+	}
+	for _, k := range []string{"<html>", "region", "a\"quoted\"", "zone", "http.method"} {
+		b[k] = k + "-value"
+	}
+
+	aJSON, err := canonicalJSON(a)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	bJSON, err := canonicalJSON(b)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !bytes.Equal(aJSON, bJSON) {
+		t.Errorf("expected identical bytes, got %s and %s", aJSON, bJSON)
+	}
+
+	expected := `{"<html>":"<html>-value","a\"quoted\"":"a\"quoted\"-value","http.method":"http.method-value","region":"region-value","zone":"zone-value"}`
+	if string(aJSON) != expected {
+		t.Errorf("expected %s, got %s", expected, aJSON)
+	}
+
+	var decoded map[string]string
+	if err := json.Unmarshal(aJSON, &decoded); err != nil || !reflect.DeepEqual(decoded, a) {
+		t.Errorf("expected the JSON to decode to %v, got %v (%v)", a, decoded, err)
+	}
+
+	// Nil and empty maps are the same
+	for _, tags := range []map[string]string{nil, {}} {
+		if data, _ := canonicalJSON(tags); string(data) != "{}" {
+			t.Errorf("expected {} for %#v, got %s", tags, data)
+		}
+	}
+}
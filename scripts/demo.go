@@ -61,13 +61,9 @@ func randomStatusCode() int {
 	}
 }
 
-// Send a metric to the Pulse server
-func sendMetric(metric *models.Metric) error {
-	// Print message to console
-	fmt.Printf("Sending metric: %s = %.2f (%s)\n", metric.Name, metric.Value, metric.Type)
-
-	// Convert Metric to the expected MetricRequest format
-	metricRequest := struct {
+// Send a batch of metrics to the Pulse server in a single request
+func sendMetrics(metrics ...*models.Metric) error {
+	type metricRequest struct {
 		Name      string            `json:"name"`                // Metric name (e.g., "http.requests")
 		Value     float64           `json:"value"`               // The measured value
 		Type      string            `json:"type,omitempty"`      // Type of metric (counter, gauge, histogram)
@@ -77,28 +73,37 @@ func sendMetric(metric *models.Metric) error {
 		TraceID   string            `json:"trace_id,omitempty"`  // Optional trace ID for correlation
 		Env       string            `json:"env,omitempty"`       // Environment (prod, dev, staging, etc.)
 		Host      string            `json:"host,omitempty"`      // Hostname where the metric was generated
-	}{
-		Name:      metric.Name,
-		Value:     metric.Value,
-		Type:      string(metric.Type),
-		Service:   metric.Service,
-		Timestamp: metric.Timestamp.Format(time.RFC3339),
-		Tags:      metric.Tags,
-		TraceID:   metric.TraceID,
-		Env:       metric.Env,
-		Host:      metric.Host,
 	}
 
-	// Serialize the metric request to JSON
-	jsonData, err := json.Marshal(metricRequest)
+	// Convert each Metric to the expected MetricRequest format
+	requests := make([]metricRequest, len(metrics))
+	for i, metric := range metrics {
+		// Print message to console
+		fmt.Printf("Sending metric: %s = %.2f (%s)\n", metric.Name, metric.Value, metric.Type)
+
+		requests[i] = metricRequest{
+			Name:      metric.Name,
+			Value:     metric.Value,
+			Type:      string(metric.Type),
+			Service:   metric.Service,
+			Timestamp: metric.Timestamp.Format(time.RFC3339),
+			Tags:      metric.Tags,
+			TraceID:   metric.TraceID,
+			Env:       metric.Env,
+			Host:      metric.Host,
+		}
+	}
+
+	// Serialize the metric requests to JSON
+	jsonData, err := json.Marshal(requests)
 	if err != nil {
-		return fmt.Errorf("error serializing metric: %v", err)
+		return fmt.Errorf("error serializing metrics: %v", err)
 	}
 
 	// Send to server
-	resp, err := http.Post(pulseServerURL+"/metrics", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := http.Post(pulseServerURL+"/metrics/batch", "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("error sending metric: %v", err)
+		return fmt.Errorf("error sending metrics: %v", err)
 	}
 	defer resp.Body.Close()
 
@@ -220,6 +225,9 @@ func simulateRequest() {
 	// Add the status code as a tag
 	rootSpan.AddTag("http.status_code", fmt.Sprintf("%d", statusCode))
 
+	// Metrics for this request are sent together at the end
+	var metrics []*models.Metric
+
 	// If this is a product service call, add a database span
 	if service == "product-service" {
 		dbSpan := models.NewSpan("database_query", service, trace.ID)
@@ -241,7 +249,7 @@ func simulateRequest() {
 		dbMetric := models.NewMetric("database.query.duration", float64(dbDuration.Milliseconds()), models.MetricTypeGauge, service)
 		dbMetric.AddTag("db.type", "postgres")
 		dbMetric.AddTag("query.type", "select")
-		sendMetric(dbMetric)
+		metrics = append(metrics, dbMetric)
 	}
 
 	// Finish the root span
@@ -251,18 +259,20 @@ func simulateRequest() {
 	requestMetric := models.NewMetric("http.request.duration", float64(requestDuration.Milliseconds()), models.MetricTypeGauge, service)
 	requestMetric.AddTag("endpoint", endpoint)
 	requestMetric.AddTag("status_code", fmt.Sprintf("%d", statusCode))
-	sendMetric(requestMetric)
+	metrics = append(metrics, requestMetric)
 
 	// Increment request counter
 	requestCounter := models.NewMetric("http.requests.total", 1, models.MetricTypeCounter, service)
 	requestCounter.AddTag("endpoint", endpoint)
 	requestCounter.AddTag("status_code", fmt.Sprintf("%d", statusCode))
-	sendMetric(requestCounter)
+	metrics = append(metrics, requestCounter)
+	sendMetrics(metrics...)
 
 	// Create a histogram for request durations
 	histogramBuckets := []float64{10, 50, 100, 200, 500, 1000}
-	histogramMetric := models.NewHistogramMetric("http.request.duration.histogram", service, histogramBuckets)
-	histogramMetric.Observe(float64(requestDuration.Milliseconds()))
+	if histogramMetric, err := models.NewHistogramMetric("http.request.duration.histogram", service, histogramBuckets); err == nil {
+		histogramMetric.Observe(float64(requestDuration.Milliseconds()))
+	}
 
 	// Create a log entry
 	logLevel := models.LogLevelInfo
@@ -313,6 +323,21 @@ func startPulseServer() (*exec.Cmd, error) {
 	return cmd, nil
 }
 
+// clearOldData removes the data left over from previous demo runs
+func clearOldData() error {
+	resp, err := http.Post(pulseServerURL+"/api/clear", "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("failed to clear old data: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned non-OK status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 func openDashboard() error {
 	fmt.Printf("Opening dashboard at %s\n", dashboardURL)
 	var cmd *exec.Cmd
@@ -341,6 +366,11 @@ func main() {
 	}
 	defer serverCmd.Process.Kill()
 
+	// Start from an empty database so earlier runs don't show up
+	if err := clearOldData(); err != nil {
+		fmt.Printf("Failed to clear old data: %v\n", err)
+	}
+
 	// Open the dashboard in a browser
 	if err := openDashboard(); err != nil {
 		fmt.Printf("Failed to open dashboard: %v\n", err)